@@ -0,0 +1,82 @@
+// Package alerts notifies administrators of operational problems (e.g. the Drive service account
+// nearing its quota) through a webhook and/or email.
+//
+// Destinations come from the environment: ALERT_WEBHOOK_URL receives each alert as a JSON POST and
+// ALERT_EMAIL (comma-separated) receives it by email, queued in the notifier's outbox. With neither
+// set, alerts are only logged.
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/notifier"
+)
+
+// Alert is one threshold crossing.
+type Alert struct {
+	Tipo    string    `json:"tipo"` // e.g. "drive_error_rate"
+	Mensaje string    `json:"mensaje"`
+	Valor   float64   `json:"valor"`
+	Umbral  float64   `json:"umbral"`
+	Fecha   time.Time `json:"fecha"`
+}
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// Notify logs the alert, posts it to the webhook and queues its email in db.
+func Notify(ctx context.Context, db *sql.DB, a Alert) error {
+	slog.Warn(a.Mensaje, "alerta", a.Tipo, "valor", a.Valor, "umbral", a.Umbral)
+
+	var errs []error
+	if url := os.Getenv("ALERT_WEBHOOK_URL"); url != "" {
+		if err := postWebhook(url, a); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	var para []string
+	for _, to := range strings.Split(os.Getenv("ALERT_EMAIL"), ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			para = append(para, to)
+		}
+	}
+	if len(para) > 0 {
+		err := notifier.EnqueueEmail(ctx, db, models.EventoAlerta, para, notifier.Alerta{
+			Tipo:    a.Tipo,
+			Mensaje: a.Mensaje,
+			Valor:   a.Valor,
+			Umbral:  a.Umbral,
+			Fecha:   a.Fecha,
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func postWebhook(url string, a Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("error encoding alert: %w", err)
+	}
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error sending alert webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"database/sql"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+)
+
+// Tipos de alerta del monitor de Drive.
+const (
+	AlertaDriveErrores    = "drive_error_rate"
+	AlertaDriveRateLimit  = "drive_rate_limit"
+	AlertaDriveAlmacenaje = "drive_storage_quota"
+)
+
+const (
+	maxAlertasRecientes    = 20
+	defaultDriveCooldown   = time.Hour
+	defaultDriveInterval   = 5 * time.Minute
+	defaultDriveErrorRate  = 0.1
+	defaultDriveMinCalls   = 20
+	defaultDriveRateLimit  = 1
+	defaultDriveQuotaUsage = 0.9
+)
+
+// DriveThresholds are the soft limits checked by DriveMonitor on every interval.
+type DriveThresholds struct {
+	Interval    time.Duration `json:"interval"`
+	ErrorRate   float64       `json:"errorRate"`   // Errors/requests in one interval
+	MinRequests int64         `json:"minRequests"` // Below this many calls the error rate is not evaluated
+	RateLimited int64         `json:"rateLimited"` // Rate limit + quota responses in one interval
+	QuotaUsage  float64       `json:"quotaUsage"`  // Storage usage/limit
+	Cooldown    time.Duration `json:"cooldown"`    // Minimum time between two alerts of the same type
+}
+
+// MarshalJSON writes the durations as strings ("5m0s") instead of nanoseconds.
+func (t DriveThresholds) MarshalJSON() ([]byte, error) {
+	type plain DriveThresholds
+	return json.Marshal(struct {
+		plain
+		Interval string `json:"interval"`
+		Cooldown string `json:"cooldown"`
+	}{plain(t), t.Interval.String(), t.Cooldown.String()})
+}
+
+// DriveThresholdsFromEnv reads the thresholds from DRIVE_ALERT_INTERVAL, DRIVE_ALERT_ERROR_RATE,
+// DRIVE_ALERT_MIN_REQUESTS, DRIVE_ALERT_RATE_LIMITED, DRIVE_ALERT_QUOTA_USAGE and
+// DRIVE_ALERT_COOLDOWN, using the defaults for unset or invalid values.
+func DriveThresholdsFromEnv() DriveThresholds {
+	return DriveThresholds{
+		Interval:    envDuration("DRIVE_ALERT_INTERVAL", defaultDriveInterval),
+		ErrorRate:   envFloat("DRIVE_ALERT_ERROR_RATE", defaultDriveErrorRate),
+		MinRequests: envInt("DRIVE_ALERT_MIN_REQUESTS", defaultDriveMinCalls),
+		RateLimited: envInt("DRIVE_ALERT_RATE_LIMITED", defaultDriveRateLimit),
+		QuotaUsage:  envFloat("DRIVE_ALERT_QUOTA_USAGE", defaultDriveQuotaUsage),
+		Cooldown:    envDuration("DRIVE_ALERT_COOLDOWN", defaultDriveCooldown),
+	}
+}
+
+// DriveStatus is the monitor's view of the Drive service account, for GET /admin/storage/drive.
+type DriveStatus struct {
+	Stats      storage.DriveStats  `json:"stats"`
+	Quota      *storage.DriveQuota `json:"quota,omitempty"`
+	QuotaError string              `json:"quotaError,omitempty"`
+	Thresholds DriveThresholds     `json:"thresholds"`
+	Alertas    []Alert             `json:"alertas"` // Most recent first
+}
+
+// DriveMonitor periodically compares the Drive backend's counters and storage quota with the
+// thresholds and notifies when one is exceeded.
+type DriveMonitor struct {
+	db         *sql.DB // Outbox of the alert emails
+	backend    *storage.DriveBackend
+	thresholds DriveThresholds
+
+	mu      sync.Mutex
+	prev    storage.DriveStats
+	ultima  map[string]time.Time // Last notification per alert type, for the cooldown
+	alertas []Alert
+}
+
+// NewDriveMonitor creates a monitor for the given backend that queues its alert emails in db.
+func NewDriveMonitor(db *sql.DB, backend *storage.DriveBackend, thresholds DriveThresholds) *DriveMonitor {
+	return &DriveMonitor{
+		db:         db,
+		backend:    backend,
+		thresholds: thresholds,
+		prev:       backend.Stats(),
+		ultima:     map[string]time.Time{},
+	}
+}
+
+// Run checks the thresholds every interval until ctx is done.
+func (m *DriveMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.thresholds.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check(ctx)
+		}
+	}
+}
+
+// Check evaluates the counters accumulated since the previous check and the storage quota, and
+// notifies the alerts that are not in their cooldown. It returns the alerts raised.
+func (m *DriveMonitor) Check(ctx context.Context) []Alert {
+	t := m.thresholds
+	actual := m.backend.Stats()
+	m.mu.Lock()
+	delta := actual.Sub(m.prev)
+	m.prev = actual
+	m.mu.Unlock()
+
+	var candidatas []Alert
+	if delta.Requests >= t.MinRequests && delta.Requests > 0 {
+		if rate := float64(delta.Errors) / float64(delta.Requests); rate >= t.ErrorRate {
+			candidatas = append(candidatas, Alert{
+				Tipo:    AlertaDriveErrores,
+				Mensaje: fmt.Sprintf("%d de %d llamadas a Google Drive fallaron en los últimos %s", delta.Errors, delta.Requests, t.Interval),
+				Valor:   rate,
+				Umbral:  t.ErrorRate,
+			})
+		}
+	}
+	if limitadas := delta.RateLimited + delta.QuotaExceeded; t.RateLimited > 0 && limitadas >= t.RateLimited {
+		candidatas = append(candidatas, Alert{
+			Tipo:    AlertaDriveRateLimit,
+			Mensaje: fmt.Sprintf("Google Drive rechazó %d llamadas por límite de tasa o de cuota en los últimos %s", limitadas, t.Interval),
+			Valor:   float64(limitadas),
+			Umbral:  float64(t.RateLimited),
+		})
+	}
+	if quota, err := m.backend.Quota(ctx); err != nil {
+		logging.FromContext(ctx).Error("Error checking Drive storage quota", "error", err)
+	} else if uso := quota.UsageRatio(); uso >= t.QuotaUsage {
+		candidatas = append(candidatas, Alert{
+			Tipo:    AlertaDriveAlmacenaje,
+			Mensaje: fmt.Sprintf("La cuenta de servicio de Google Drive usa el %.1f%% de su almacenamiento (%d de %d bytes)", uso*100, quota.Usage, quota.Limit),
+			Valor:   uso,
+			Umbral:  t.QuotaUsage,
+		})
+	}
+
+	var enviadas []Alert
+	now := time.Now()
+	for _, a := range candidatas {
+		m.mu.Lock()
+		enCooldown := now.Sub(m.ultima[a.Tipo]) < t.Cooldown
+		if !enCooldown {
+			m.ultima[a.Tipo] = now
+			a.Fecha = now
+			m.alertas = append([]Alert{a}, m.alertas...)
+			if len(m.alertas) > maxAlertasRecientes {
+				m.alertas = m.alertas[:maxAlertasRecientes]
+			}
+		}
+		m.mu.Unlock()
+		if enCooldown {
+			continue
+		}
+		if err := Notify(ctx, m.db, a); err != nil {
+			logging.FromContext(ctx).Error("Error notifying alert", "tipo", a.Tipo, "error", err)
+		}
+		enviadas = append(enviadas, a)
+	}
+	return enviadas
+}
+
+// Status returns the current counters and quota, the thresholds and the recent alerts.
+func (m *DriveMonitor) Status(ctx context.Context) DriveStatus {
+	status := DriveStatus{Stats: m.backend.Stats(), Thresholds: m.thresholds}
+	if quota, err := m.backend.Quota(ctx); err != nil {
+		status.QuotaError = err.Error()
+	} else {
+		status.Quota = &quota
+	}
+	m.mu.Lock()
+	status.Alertas = append([]Alert{}, m.alertas...)
+	m.mu.Unlock()
+	return status
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	if v := os.Getenv(name); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		slog.Warn("Invalid value, using the default", "variable", name, "value", v, "default", def)
+	}
+	return def
+}
+
+func envFloat(name string, def float64) float64 {
+	if v := os.Getenv(name); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 {
+			return f
+		}
+		slog.Warn("Invalid value, using the default", "variable", name, "value", v, "default", def)
+	}
+	return def
+}
+
+func envInt(name string, def int64) int64 {
+	if v := os.Getenv(name); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			return n
+		}
+		slog.Warn("Invalid value, using the default", "variable", name, "value", v, "default", def)
+	}
+	return def
+}
@@ -0,0 +1,167 @@
+// Package analytics counts the requests of each client (a user, or an anonymous caller by IP) to each
+// route, for GET /admin/usage. Requests only increment counters in memory; a background loop adds
+// them to the uso_api table every minute, in hourly buckets, so the database stays out of the hot
+// path. USAGE_TRACKING=false disables it.
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/background"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// intervalo is how often the counters are flushed to the database.
+const intervalo = time.Minute
+
+// clave identifies a counter: an hour, a route and a client.
+type clave struct {
+	inicio    time.Time
+	metodo    string
+	ruta      string
+	idUsuario int // 0 for anonymous callers
+	ip        string
+}
+
+type contador struct {
+	peticiones, errores int64
+	duracion            time.Duration
+}
+
+var (
+	activo     atomic.Bool // Set by Start: without the flush loop the counters would only grow
+	mu         sync.Mutex
+	pendientes = map[clave]*contador{}
+)
+
+// Enabled reports whether usage tracking is on (USAGE_TRACKING is not false).
+func Enabled() bool {
+	return !strings.EqualFold(os.Getenv("USAGE_TRACKING"), "false")
+}
+
+// Start flushes the counters every minute until ctx is done. Call Flush after draining the
+// requests to save the last ones.
+func Start(ctx context.Context, db *sql.DB) {
+	if !Enabled() {
+		return
+	}
+	activo.Store(true)
+	background.Go(func() {
+		ticker := time.NewTicker(intervalo)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				Flush(ctx, db)
+			}
+		}
+	})
+}
+
+// Flush saves the pending counters. If the database fails they are kept for the next flush.
+func Flush(ctx context.Context, db *sql.DB) {
+	mu.Lock()
+	lote := pendientes
+	pendientes = map[clave]*contador{}
+	mu.Unlock()
+	if len(lote) == 0 {
+		return
+	}
+
+	usos := make([]models.UsoAPI, 0, len(lote))
+	for k, c := range lote {
+		u := models.UsoAPI{Inicio: k.inicio, Metodo: k.metodo, Ruta: k.ruta, IP: k.ip,
+			Peticiones: c.peticiones, Errores: c.errores, DuracionMs: c.duracion.Milliseconds()}
+		if k.idUsuario != 0 {
+			id := k.idUsuario
+			u.IDUsuario = &id
+		}
+		usos = append(usos, u)
+	}
+	if err := repository.AddUsoAPI(ctx, db, usos); err != nil {
+		logging.FromContext(ctx).Error("Error saving API usage", "counters", len(usos), "error", err)
+		mu.Lock()
+		for k, c := range lote {
+			sumar(k, c.peticiones, c.errores, c.duracion)
+		}
+		mu.Unlock()
+	}
+}
+
+// sumar adds to the counter of k. The caller holds mu.
+func sumar(k clave, peticiones, errores int64, duracion time.Duration) {
+	c, ok := pendientes[k]
+	if !ok {
+		c = &contador{}
+		pendientes[k] = c
+	}
+	c.peticiones += peticiones
+	c.errores += errores
+	c.duracion += duracion
+}
+
+// Middleware counts the requests to the route metodo ruta (its template, not the path, so the
+// counters don't grow with every ID). The caller is the user of the token, or the client IP.
+func Middleware(metodo, ruta string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !activo.Load() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		inicio := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+
+		k := clave{inicio: inicio.UTC().Truncate(time.Hour), metodo: metodo, ruta: ruta}
+		if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+			k.idUsuario = userID
+		} else {
+			k.ip = utils.ClientIP(r)
+		}
+		var errores int64
+		if sw.status >= http.StatusBadRequest {
+			errores = 1
+		}
+		mu.Lock()
+		sumar(k, 1, errores, time.Since(inicio))
+		mu.Unlock()
+	})
+}
+
+// statusWriter records the status of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+func (s *statusWriter) WriteHeader(status int) {
+	if !s.wrote {
+		s.wrote = true
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusWriter) Write(b []byte) (int, error) {
+	s.wrote = true
+	return s.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusWriter) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
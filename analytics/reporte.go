@@ -0,0 +1,136 @@
+package analytics
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// acumulado sums requests, errors and time, giving the average duration at the end.
+type acumulado struct {
+	peticiones, errores, duracionMs int64
+}
+
+func (a *acumulado) sumar(u models.UsoAPI) {
+	a.peticiones += u.Peticiones
+	a.errores += u.Errores
+	a.duracionMs += u.DuracionMs
+}
+
+func (a acumulado) estadistica() models.EstadisticaUso {
+	e := models.EstadisticaUso{Peticiones: a.peticiones, Errores: a.errores}
+	if a.peticiones > 0 {
+		e.DuracionMediaMs = float64(a.duracionMs) / float64(a.peticiones)
+	}
+	return e
+}
+
+type claveRuta struct{ metodo, ruta string }
+
+type claveCliente struct {
+	idUsuario int
+	ip        string
+}
+
+type cliente struct {
+	acumulado
+	email string
+	rutas map[claveRuta]*acumulado
+	serie map[time.Time]*acumulado
+}
+
+// Reporte aggregates the hourly usage between desde and hasta by intervalo (models.IntervaloHora
+// or models.IntervaloDia, in UTC). The series of the whole API has every bucket of the window,
+// those of the clients only the buckets with requests. Only the top busiest clients are kept.
+func Reporte(usos []models.UsoAPI, desde, hasta time.Time, intervalo string, top int) models.ReporteUso {
+	paso := time.Hour
+	if intervalo == models.IntervaloDia {
+		paso = 24 * time.Hour
+	}
+	bucket := func(t time.Time) time.Time { return t.UTC().Truncate(paso) }
+
+	var total acumulado
+	serie := map[time.Time]*acumulado{}
+	rutas := map[claveRuta]*acumulado{}
+	clientes := map[claveCliente]*cliente{}
+	for _, u := range usos {
+		total.sumar(u)
+		b := bucket(u.Inicio)
+		obtener(serie, b).sumar(u)
+		kr := claveRuta{u.Metodo, u.Ruta}
+		obtener(rutas, kr).sumar(u)
+
+		kc := claveCliente{ip: u.IP}
+		if u.IDUsuario != nil {
+			kc.idUsuario = *u.IDUsuario
+		}
+		c, ok := clientes[kc]
+		if !ok {
+			c = &cliente{email: u.Email, rutas: map[claveRuta]*acumulado{}, serie: map[time.Time]*acumulado{}}
+			clientes[kc] = c
+		}
+		c.acumulado.sumar(u)
+		obtener(c.rutas, kr).sumar(u)
+		obtener(c.serie, b).sumar(u)
+	}
+
+	r := models.ReporteUso{
+		Desde:     desde,
+		Hasta:     hasta,
+		Intervalo: intervalo,
+		Total:     total.estadistica(),
+		Serie:     []models.PuntoUso{},
+		Rutas:     listaRutas(rutas),
+		Clientes:  []models.UsoCliente{},
+	}
+	for b := bucket(desde); b.Before(hasta); b = b.Add(paso) {
+		p := models.PuntoUso{Inicio: b}
+		if a, ok := serie[b]; ok {
+			p.EstadisticaUso = a.estadistica()
+		}
+		r.Serie = append(r.Serie, p)
+	}
+	for k, c := range clientes {
+		uc := models.UsoCliente{Email: c.email, IP: k.ip, EstadisticaUso: c.estadistica(), Rutas: listaRutas(c.rutas), Serie: []models.PuntoUso{}}
+		if k.idUsuario != 0 {
+			id := k.idUsuario
+			uc.IDUsuario = &id
+		}
+		for b, a := range c.serie {
+			uc.Serie = append(uc.Serie, models.PuntoUso{Inicio: b, EstadisticaUso: a.estadistica()})
+		}
+		slices.SortFunc(uc.Serie, func(a, b models.PuntoUso) int { return a.Inicio.Compare(b.Inicio) })
+		r.Clientes = append(r.Clientes, uc)
+	}
+	slices.SortFunc(r.Clientes, func(a, b models.UsoCliente) int {
+		return cmp.Or(cmp.Compare(b.Peticiones, a.Peticiones), cmp.Compare(a.Email, b.Email), cmp.Compare(a.IP, b.IP))
+	})
+	if len(r.Clientes) > top {
+		r.Clientes = r.Clientes[:top]
+	}
+	return r
+}
+
+// obtener returns the accumulator of k in m, adding it if missing.
+func obtener[K comparable](m map[K]*acumulado, k K) *acumulado {
+	a, ok := m[k]
+	if !ok {
+		a = &acumulado{}
+		m[k] = a
+	}
+	return a
+}
+
+// listaRutas returns the usage of each route, busiest first.
+func listaRutas(m map[claveRuta]*acumulado) []models.UsoRuta {
+	rutas := make([]models.UsoRuta, 0, len(m))
+	for k, a := range m {
+		rutas = append(rutas, models.UsoRuta{Metodo: k.metodo, Ruta: k.ruta, EstadisticaUso: a.estadistica()})
+	}
+	slices.SortFunc(rutas, func(a, b models.UsoRuta) int {
+		return cmp.Or(cmp.Compare(b.Peticiones, a.Peticiones), cmp.Compare(a.Ruta, b.Ruta), cmp.Compare(a.Metodo, b.Metodo))
+	})
+	return rutas
+}
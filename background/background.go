@@ -0,0 +1,56 @@
+// Package background runs the server's background work (the notification worker, the job
+// scheduler, the usage flusher, export jobs...) under one root context, so that shutdown can cancel
+// it and wait for it to finish before the database pool is closed.
+package background
+
+import (
+	"context"
+	"sync"
+)
+
+var (
+	mu           sync.Mutex
+	root, cancel = context.WithCancel(context.Background())
+	wg           sync.WaitGroup
+)
+
+// Context returns the root context of background work, canceled by Stop. Work that outlives the
+// request that started it (e.g. an export) runs under it rather than under the request's context.
+func Context() context.Context {
+	return root
+}
+
+// Go runs f in its own goroutine and makes Stop wait for it. f should return soon after Context is
+// done. After Stop, f is not run.
+func Go(f func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	if root.Err() != nil {
+		return
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		f()
+	}()
+}
+
+// Stop cancels Context and waits for the goroutines started with Go, or until ctx is done, in which
+// case it returns ctx's error.
+func Stop(ctx context.Context) error {
+	mu.Lock()
+	cancel()
+	mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,302 @@
+// Package backup makes logical backups of the database (every table of the registry as a gzipped
+// JSON file kept in the storage layer) and restores them.
+//
+// A backup holds the rows, not the schema: it is restored into a database created with the migrate
+// command, of the same backend (PostgreSQL or SQLite) it was taken from. The stored files (group
+// documents, exports...) stay in the storage backend and are not part of it.
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/version"
+)
+
+// formato is the version of the file layout, checked before restoring.
+const formato = 1
+
+// ErrEnCurso is returned while another backup or restore of this instance is running.
+var ErrEnCurso = errors.New("ya hay una copia de seguridad o una restauración en curso")
+
+// mu allows a single backup or restore at a time in the instance.
+var mu sync.Mutex
+
+// tabla is a table included in the backups.
+type tabla struct {
+	nombre    string
+	id        string   // Serial column, whose sequence is reset after restoring ("" if none)
+	padre     string   // Column that refers to the table itself: parents are inserted first
+	generadas []string // Generated columns, computed again by the database
+}
+
+// tablas are the tables saved, in an order where each one only refers to the previous ones. Left
+// out are the notification outbox (delivered notifications must not be sent again), the state of
+// the scheduled jobs, the backups themselves, which a restore keeps, the usage counters of
+// GET /admin/usage and the change feed: a restore records its own changes, and the clients that
+// follow it must download everything again anyway.
+var tablas = []tabla{
+	{nombre: "Usuario", id: "idUsuario"},
+	{nombre: "facultad", id: "idFacultad"},
+	{nombre: "escuela_profesional", id: "idEscuela"},
+	{nombre: "Investigador", id: "idInvestigador"},
+	{nombre: "Grupo", id: "idGrupo", padre: "idGrupoPadre", generadas: []string{"busqueda"}},
+	{nombre: "Grupo_Investigador", id: "idGrupo_Investigador"},
+	{nombre: "resolucion", id: "idResolucion"},
+	{nombre: "renovacion", id: "idRenovacion"},
+	{nombre: "aviso_vencimiento"},
+	{nombre: "solicitud_grupo", id: "idSolicitud"},
+	{nombre: "grupo_archivo", id: "idArchivo"},
+	{nombre: "enlace_compartido", id: "idEnlace"},
+	{nombre: "grupo_comentario", id: "idComentario"},
+	{nombre: "grupo_favorito"},
+	{nombre: "busqueda_guardada", id: "idBusqueda"},
+	{nombre: "verificacion_email", id: "idVerificacion"},
+	{nombre: "restablecimiento_password", id: "idRestablecimiento"},
+	{nombre: "convocatoria", id: "idConvocatoria"},
+	{nombre: "grupo_convocatoria"},
+	{nombre: "convocatoria_recordatorio"},
+	{nombre: "postulacion", id: "idPostulacion"},
+	{nombre: "postulacion_historial", id: "idHistorial"},
+	{nombre: "postulacion_documento", id: "idDocumento"},
+	{nombre: "publicacion", id: "idPublicacion"},
+	{nombre: "publicacion_investigador"},
+	{nombre: "publicacion_grupo"},
+	{nombre: "investigador_cti_vitae"},
+	{nombre: "proyecto", id: "idProyecto"},
+	{nombre: "proyecto_archivo", id: "idArchivo"},
+	{nombre: "audit_log", id: "idAudit"},
+	{nombre: "migracion_archivo"},
+	{nombre: "archivo_checksum"},
+	{nombre: "export_job", id: "idExport"},
+	{nombre: "estadistica_diaria"},
+	{nombre: "linea_investigacion", id: "idLinea"},
+	{nombre: "rol_integrante", id: "idRol"},
+	{nombre: "tipo_investigacion", id: "idTipo"},
+}
+
+// esGenerada reports whether columna is a generated column of t.
+func (t tabla) esGenerada(columna string) bool {
+	for _, g := range t.generadas {
+		if strings.EqualFold(g, columna) {
+			return true
+		}
+	}
+	return false
+}
+
+// Create saves every table to a new file in backend and records the backup. Only one backup or
+// restore runs at a time in the instance: otherwise it returns ErrEnCurso.
+func Create(ctx context.Context, db *sql.DB, backend storage.Backend, origen, descripcion string, creadoPor *int) (*models.Backup, error) {
+	if !mu.TryLock() {
+		return nil, ErrEnCurso
+	}
+	defer mu.Unlock()
+	return create(ctx, db, backend, origen, descripcion, creadoPor)
+}
+
+func create(ctx context.Context, db *sql.DB, backend storage.Backend, origen, descripcion string, creadoPor *int) (*models.Backup, error) {
+	// The tables are read in one transaction, so that the backup is consistent
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("error starting backup transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	nombre := fmt.Sprintf("backup_%s.json.gz", time.Now().UTC().Format("20060102-150405"))
+	pr, pw := io.Pipe()
+	filas := map[string]int64{}
+	writeErr := make(chan error, 1)
+	go func() {
+		err := write(ctx, tx, pw, filas)
+		pw.CloseWithError(err) // A nil error ends the file with io.EOF
+		writeErr <- err
+	}()
+	sum := storage.NewChecksumReader(pr)
+	key, err := backend.Put(ctx, nombre, sum)
+	pr.CloseWithError(errors.New("backup upload finished")) // Unblocks write if Put stopped reading early
+	werr := <-writeErr
+	tx.Rollback() // Nothing was written: this just ends the snapshot before recording the backup
+	if werr != nil {
+		if err == nil {
+			backend.Delete(context.WithoutCancel(ctx), key)
+		}
+		return nil, werr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error storing backup file: %w", err)
+	}
+
+	sha256, tamano := sum.Sum()
+	b := &models.Backup{
+		Archivo:       storage.FormatRef(backend.Name(), key),
+		NombreArchivo: nombre,
+		Origen:        origen,
+		Descripcion:   descripcion,
+		Motor:         database.Driver(),
+		Tamano:        tamano,
+		Sha256:        sha256,
+		Filas:         filas,
+		CreadoPor:     creadoPor,
+	}
+	if err := repository.CreateBackup(ctx, db, b); err != nil {
+		backend.Delete(context.WithoutCancel(ctx), key)
+		return nil, err
+	}
+	return b, nil
+}
+
+// Archivo is the content of a backup file.
+type Archivo struct {
+	Formato int         `json:"formato"`
+	Motor   string      `json:"motor"`
+	Version string      `json:"version"` // Build of the API that took it
+	Creado  time.Time   `json:"creado"`
+	Tablas  []Contenido `json:"tablas"`
+}
+
+// Contenido holds the rows of a table, as arrays of values in the order of Columnas.
+type Contenido struct {
+	Nombre   string          `json:"nombre"`
+	Columnas []string        `json:"columnas"`
+	Filas    [][]interface{} `json:"filas"`
+}
+
+// write writes the backup file to w, gzipped, counting the rows of each table in filas. The rows
+// are written as they are read, so the file is never held in memory.
+func write(ctx context.Context, tx *sql.Tx, w io.Writer, filas map[string]int64) error {
+	gz := gzip.NewWriter(w)
+	cabecera, err := json.Marshal(Archivo{Formato: formato, Motor: database.Driver(), Version: version.Get().Version, Creado: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	// The header, without the closing brace, is followed by the tables
+	if _, err := fmt.Fprintf(gz, `%s,"tablas":[`, strings.TrimSuffix(string(cabecera), `,"tablas":null}`)); err != nil {
+		return err
+	}
+	for i, t := range tablas {
+		if i > 0 {
+			if _, err := io.WriteString(gz, ","); err != nil {
+				return err
+			}
+		}
+		n, err := writeTabla(ctx, tx, gz, t)
+		if err != nil {
+			return err
+		}
+		filas[t.nombre] = n
+	}
+	if _, err := io.WriteString(gz, "]}\n"); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writeTabla writes the Contenido of a table to w and returns its number of rows.
+func writeTabla(ctx context.Context, tx *sql.Tx, w io.Writer, t tabla) (int64, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT * FROM `+t.nombre+` ORDER BY 1`)
+	if err != nil {
+		return 0, fmt.Errorf("error reading %s: %w", t.nombre, err)
+	}
+	defer rows.Close()
+	columnas, err := rows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("error reading the columns of %s: %w", t.nombre, err)
+	}
+
+	var guardadas []int // Indexes of the columns saved
+	var nombres []string
+	for i, c := range columnas {
+		if !t.esGenerada(c) {
+			guardadas = append(guardadas, i)
+			nombres = append(nombres, c)
+		}
+	}
+	cabecera, err := json.Marshal(Contenido{Nombre: t.nombre, Columnas: nombres})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := fmt.Fprintf(w, `%s,"filas":[`, strings.TrimSuffix(string(cabecera), `,"filas":null}`)); err != nil {
+		return 0, err
+	}
+
+	valores := make([]interface{}, len(columnas))
+	destinos := make([]interface{}, len(columnas))
+	for i := range valores {
+		destinos[i] = &valores[i]
+	}
+	fila := make([]interface{}, len(guardadas))
+	var n int64
+	for rows.Next() {
+		if err := rows.Scan(destinos...); err != nil {
+			return 0, fmt.Errorf("error scanning %s: %w", t.nombre, err)
+		}
+		for j, i := range guardadas {
+			// JSON columns arrive as bytes: they are saved as the text they are
+			if b, ok := valores[i].([]byte); ok {
+				fila[j] = string(b)
+			} else {
+				fila[j] = valores[i]
+			}
+		}
+		raw, err := json.Marshal(fila)
+		if err != nil {
+			return 0, fmt.Errorf("error encoding a row of %s: %w", t.nombre, err)
+		}
+		if n > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := w.Write(raw); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error reading %s: %w", t.nombre, err)
+	}
+	_, err = io.WriteString(w, "]}")
+	return n, err
+}
+
+// Delete removes a backup: its file and its record.
+func Delete(ctx context.Context, db *sql.DB, b *models.Backup) error {
+	backend, key, err := storage.Resolve(b.Archivo)
+	if err != nil {
+		return err
+	}
+	if err := backend.Delete(ctx, key); err != nil {
+		return fmt.Errorf("error deleting backup file: %w", err)
+	}
+	return repository.DeleteBackup(ctx, db, b.ID)
+}
+
+// Prune deletes the backups of an origin beyond the newest conservar ones and returns how many
+// were deleted.
+func Prune(ctx context.Context, db *sql.DB, origen string, conservar int) (int, error) {
+	backups, err := repository.GetBackupsPorOrigen(ctx, db, origen)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for i := conservar; i < len(backups); i++ {
+		if err := Delete(ctx, db, &backups[i]); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
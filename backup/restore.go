@@ -0,0 +1,275 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+)
+
+// maxParametros bounds the arguments of each INSERT of a restore (PostgreSQL accepts 65535).
+const maxParametros = 5000
+
+// ErrNoRestaurable is returned (wrapped, with the reason) for files that can't be restored into
+// this database.
+var ErrNoRestaurable = errors.New("la copia de seguridad no puede restaurarse")
+
+// Read decodes a backup file and checks that it can be restored into this database.
+func Read(r io.Reader) (*Archivo, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: el archivo no es una copia de seguridad (%v)", ErrNoRestaurable, err)
+	}
+	defer gz.Close()
+	dec := json.NewDecoder(gz)
+	dec.UseNumber() // Ids and counters stay integers
+	var a Archivo
+	if err := dec.Decode(&a); err != nil {
+		return nil, fmt.Errorf("%w: el archivo no es una copia de seguridad (%v)", ErrNoRestaurable, err)
+	}
+	if a.Formato != formato {
+		return nil, fmt.Errorf("%w: formato %d no soportado", ErrNoRestaurable, a.Formato)
+	}
+	if a.Motor != database.Driver() {
+		return nil, fmt.Errorf("%w: es de una base de datos %s y esta es %s", ErrNoRestaurable, a.Motor, database.Driver())
+	}
+	for _, c := range a.Tablas {
+		if _, ok := buscarTabla(c.Nombre); !ok {
+			return nil, fmt.Errorf("%w: contiene la tabla desconocida %q", ErrNoRestaurable, c.Nombre)
+		}
+	}
+	return &a, nil
+}
+
+// Filas returns the number of rows of each table of the file.
+func (a *Archivo) Filas() map[string]int64 {
+	filas := map[string]int64{}
+	for _, c := range a.Tablas {
+		filas[c.Nombre] = int64(len(c.Filas))
+	}
+	return filas
+}
+
+func buscarTabla(nombre string) (tabla, bool) {
+	for _, t := range tablas {
+		if strings.EqualFold(t.nombre, nombre) {
+			return t, true
+		}
+	}
+	return tabla{}, false
+}
+
+// Restore replaces the content of every table with the backup b, after saving the current content
+// as a pre_restauracion backup so that the restore can be undone.
+func Restore(ctx context.Context, db *sql.DB, b *models.Backup, creadoPor *int) (*models.ResultadoRestauracion, error) {
+	if !mu.TryLock() {
+		return nil, ErrEnCurso
+	}
+	defer mu.Unlock()
+
+	backend, key, err := storage.Resolve(b.Archivo)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := backend.Open(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("error opening backup file: %w", err)
+	}
+	a, err := Read(obj.Body)
+	obj.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	return restore(ctx, db, a, fmt.Sprintf("Antes de restaurar %s", b.NombreArchivo), creadoPor, b)
+}
+
+// RestoreArchivo is Restore for a file read with Read, such as one downloaded beforehand (e.g. into
+// a new database, whose backups table is empty).
+func RestoreArchivo(ctx context.Context, db *sql.DB, a *Archivo, nombre string) (*models.ResultadoRestauracion, error) {
+	if !mu.TryLock() {
+		return nil, ErrEnCurso
+	}
+	defer mu.Unlock()
+	return restore(ctx, db, a, fmt.Sprintf("Antes de restaurar %s", nombre), nil, &models.Backup{NombreArchivo: nombre, Motor: a.Motor, Filas: a.Filas()})
+}
+
+func restore(ctx context.Context, db *sql.DB, a *Archivo, descripcion string, creadoPor *int, b *models.Backup) (*models.ResultadoRestauracion, error) {
+	backend, err := storage.Default()
+	if err != nil {
+		return nil, err
+	}
+	previo, err := create(ctx, db, backend, models.BackupPreRestauracion, descripcion, creadoPor)
+	if err != nil {
+		return nil, fmt.Errorf("error saving the current data before restoring: %w", err)
+	}
+
+	contenido := map[string]*Contenido{}
+	for i := range a.Tablas {
+		contenido[strings.ToLower(a.Tablas[i].Nombre)] = &a.Tablas[i]
+	}
+	filas := map[string]int64{}
+	err = repository.WithTx(ctx, db, func(tx *sql.Tx) error {
+		nombres := make([]string, len(tablas))
+		for i, t := range tablas {
+			nombres[i] = t.nombre
+		}
+		reactivar, err := database.PrepareRestore(ctx, tx, nombres)
+		if err != nil {
+			return err
+		}
+		// Children first, so that no ON DELETE action reaches a table already emptied
+		for i := len(tablas) - 1; i >= 0; i-- {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM `+tablas[i].nombre); err != nil {
+				return fmt.Errorf("error emptying %s: %w", tablas[i].nombre, err)
+			}
+		}
+		for _, t := range tablas {
+			// A table missing from the file (added after the backup was taken) is left empty
+			c := contenido[strings.ToLower(t.nombre)]
+			if c != nil {
+				if err := insertTabla(ctx, tx, t, c); err != nil {
+					return err
+				}
+				filas[t.nombre] = int64(len(c.Filas))
+			}
+			if t.id != "" {
+				if err := database.ResetSequence(ctx, tx, t.nombre, t.id); err != nil {
+					return err
+				}
+			}
+		}
+		return reactivar(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.ResultadoRestauracion{Backup: *b, Filas: filas, PrevioBackup: previo}, nil
+}
+
+// insertTabla inserts the rows of c into t, several per statement.
+func insertTabla(ctx context.Context, tx *sql.Tx, t tabla, c *Contenido) error {
+	// The column types of the table tell which values are times, saved as RFC 3339 text
+	rows, err := tx.QueryContext(ctx, `SELECT * FROM `+t.nombre+` WHERE 1 = 0`)
+	if err != nil {
+		return fmt.Errorf("error reading the columns of %s: %w", t.nombre, err)
+	}
+	tipos, err := rows.ColumnTypes()
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("error reading the columns of %s: %w", t.nombre, err)
+	}
+	esFecha := make([]bool, len(c.Columnas))
+	id, padre := -1, -1
+	for i, columna := range c.Columnas {
+		encontrada := false
+		for _, tipo := range tipos {
+			if strings.EqualFold(tipo.Name(), columna) {
+				nombre := strings.ToUpper(tipo.DatabaseTypeName())
+				esFecha[i] = strings.Contains(nombre, "DATE") || strings.Contains(nombre, "TIMESTAMP")
+				encontrada = true
+			}
+		}
+		if !encontrada || t.esGenerada(columna) {
+			return fmt.Errorf("%w: la columna %s.%s no existe en la base de datos", ErrNoRestaurable, t.nombre, columna)
+		}
+		if strings.EqualFold(columna, t.id) {
+			id = i
+		}
+		if strings.EqualFold(columna, t.padre) {
+			padre = i
+		}
+	}
+	if len(c.Filas) == 0 || len(c.Columnas) == 0 {
+		return nil
+	}
+	filas := c.Filas
+	if id >= 0 && padre >= 0 {
+		filas = ordenarPorPadre(filas, id, padre)
+	}
+
+	porSentencia := max(1, maxParametros/len(c.Columnas))
+	for inicio := 0; inicio < len(filas); inicio += porSentencia {
+		lote := filas[inicio:min(inicio+porSentencia, len(filas))]
+		var sb strings.Builder
+		fmt.Fprintf(&sb, `INSERT INTO %s (%s) VALUES `, t.nombre, strings.Join(c.Columnas, ", "))
+		args := make([]interface{}, 0, len(lote)*len(c.Columnas))
+		for i, fila := range lote {
+			if len(fila) != len(c.Columnas) {
+				return fmt.Errorf("una fila de %s de la copia de seguridad tiene %d valores en lugar de %d", t.nombre, len(fila), len(c.Columnas))
+			}
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString("(")
+			for j, v := range fila {
+				if j > 0 {
+					sb.WriteString(", ")
+				}
+				arg, err := valor(v, esFecha[j])
+				if err != nil {
+					return fmt.Errorf("valor inválido en %s.%s: %w", t.nombre, c.Columnas[j], err)
+				}
+				args = append(args, arg)
+				fmt.Fprintf(&sb, "$%d", len(args))
+			}
+			sb.WriteString(")")
+		}
+		if _, err := tx.ExecContext(ctx, sb.String(), args...); err != nil {
+			return fmt.Errorf("error restoring %s: %w", t.nombre, err)
+		}
+	}
+	return nil
+}
+
+// valor converts a value decoded from the file into the argument for its column.
+func valor(v interface{}, esFecha bool) (interface{}, error) {
+	switch x := v.(type) {
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return n, nil
+		}
+		return x.Float64()
+	case string:
+		if esFecha {
+			return time.Parse(time.RFC3339Nano, x)
+		}
+	case map[string]interface{}, []interface{}:
+		return nil, fmt.Errorf("valor compuesto inesperado")
+	}
+	return v, nil
+}
+
+// ordenarPorPadre orders the rows of a table that refers to itself so that each row comes after the
+// one it refers to. Rows whose parent is not in the table are left at the end, for the database to
+// reject them.
+func ordenarPorPadre(filas [][]interface{}, id, padre int) [][]interface{} {
+	ordenadas := make([][]interface{}, 0, len(filas))
+	insertadas := map[string]bool{}
+	pendientes := filas
+	for len(pendientes) > 0 {
+		var resto [][]interface{}
+		for _, f := range pendientes {
+			if f[padre] == nil || insertadas[fmt.Sprint(f[padre])] {
+				ordenadas = append(ordenadas, f)
+				insertadas[fmt.Sprint(f[id])] = true
+			} else {
+				resto = append(resto, f)
+			}
+		}
+		if len(resto) == len(pendientes) {
+			return append(ordenadas, resto...)
+		}
+		pendientes = resto
+	}
+	return ordenadas
+}
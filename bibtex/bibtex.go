@@ -0,0 +1,302 @@
+// Package bibtex reads and writes BibTeX, the format of reference managers such as Zotero, Mendeley
+// or JabRef, to import and export publicaciones.
+//
+// The parser accepts what those tools produce: entries with braced, quoted or numeric values,
+// concatenation with #, @string macros and the month abbreviations, and skips @comment and
+// @preamble. LaTeX accents and escapes in values are decoded to UTF-8.
+package bibtex
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Entrada is a BibTeX entry.
+type Entrada struct {
+	Tipo   string            // Lowercase entry type, e.g. "article"
+	Clave  string            // Citation key
+	Campos map[string]string // Decoded values by lowercase field name
+	Linea  int               // Line of the file where the entry starts
+}
+
+// Error is a syntax error in a BibTeX file.
+type Error struct {
+	Linea   int
+	Mensaje string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("línea %d: %s", e.Linea, e.Mensaje)
+}
+
+// meses are the month macros predefined by BibTeX.
+var meses = map[string]string{
+	"jan": "1", "feb": "2", "mar": "3", "apr": "4", "may": "5", "jun": "6",
+	"jul": "7", "aug": "8", "sep": "9", "oct": "10", "nov": "11", "dec": "12",
+}
+
+type parser struct {
+	src    []rune
+	pos    int
+	linea  int
+	macros map[string]string
+}
+
+// Parse reads every entry of a BibTeX file. Text outside entries is ignored, as BibTeX does. It
+// returns an *Error for malformed entries.
+func Parse(r io.Reader) ([]Entrada, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading BibTeX: %w", err)
+	}
+	p := &parser{src: []rune(strings.TrimPrefix(string(b), "\uFEFF")), linea: 1, macros: map[string]string{}}
+	entradas := []Entrada{}
+	for {
+		if !p.saltarHasta('@') {
+			return entradas, nil
+		}
+		linea := p.linea
+		p.avanzar() // @
+		p.saltarEspacios()
+		tipo := strings.ToLower(p.identificador())
+		if tipo == "" {
+			return nil, &Error{linea, "falta el tipo de la entrada"}
+		}
+		p.saltarEspacios()
+		if tipo == "comment" {
+			// @comment{...} or the rest of the line
+			if p.actual() == '{' || p.actual() == '(' {
+				if _, err := p.delimitado(); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		abre := p.actual()
+		if abre != '{' && abre != '(' {
+			return nil, &Error{p.linea, fmt.Sprintf("se esperaba { después de @%s", tipo)}
+		}
+		cierra := '}'
+		if abre == '(' {
+			cierra = ')'
+		}
+		p.avanzar()
+		switch tipo {
+		case "preamble":
+			p.retroceder()
+			if _, err := p.delimitado(); err != nil {
+				return nil, err
+			}
+		case "string":
+			campos, err := p.campos(cierra)
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range campos {
+				p.macros[k] = v
+			}
+		default:
+			p.saltarEspacios()
+			clave := strings.TrimSpace(p.hasta(',', cierra))
+			if p.actual() == ',' {
+				p.avanzar()
+			}
+			campos, err := p.campos(cierra)
+			if err != nil {
+				return nil, err
+			}
+			entradas = append(entradas, Entrada{Tipo: tipo, Clave: clave, Campos: campos, Linea: linea})
+		}
+	}
+}
+
+// campos reads "name = value" pairs separated by commas up to the closing delimiter, which it
+// consumes.
+func (p *parser) campos(cierra rune) (map[string]string, error) {
+	campos := map[string]string{}
+	for {
+		p.saltarEspacios()
+		if p.fin() {
+			return nil, &Error{p.linea, "entrada sin cerrar"}
+		}
+		if p.actual() == cierra {
+			p.avanzar()
+			return campos, nil
+		}
+		if p.actual() == ',' {
+			p.avanzar()
+			continue
+		}
+		nombre := strings.ToLower(p.identificador())
+		if nombre == "" {
+			return nil, &Error{p.linea, fmt.Sprintf("carácter inesperado %q", p.actual())}
+		}
+		p.saltarEspacios()
+		if p.actual() != '=' {
+			return nil, &Error{p.linea, fmt.Sprintf("se esperaba = después de %s", nombre)}
+		}
+		p.avanzar()
+		valor, err := p.valor()
+		if err != nil {
+			return nil, err
+		}
+		campos[nombre] = valor
+	}
+}
+
+// valor reads a field value: braced, quoted, numeric or macro parts joined with #.
+func (p *parser) valor() (string, error) {
+	var b strings.Builder
+	for {
+		p.saltarEspacios()
+		switch c := p.actual(); {
+		case c == '{':
+			s, err := p.delimitado()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		case c == '"':
+			s, err := p.entreComillas()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		default:
+			id := p.identificador()
+			if id == "" {
+				return "", &Error{p.linea, "falta el valor del campo"}
+			}
+			if v, ok := p.macros[strings.ToLower(id)]; ok {
+				b.WriteString(v)
+			} else if v, ok := meses[strings.ToLower(id)]; ok {
+				b.WriteString(v)
+			} else {
+				b.WriteString(id)
+			}
+		}
+		p.saltarEspacios()
+		if p.actual() != '#' {
+			return Decodificar(b.String()), nil
+		}
+		p.avanzar()
+	}
+}
+
+// delimitado reads a value between balanced braces (or parentheses, for @comment and @preamble),
+// returning it without the outer ones.
+func (p *parser) delimitado() (string, error) {
+	linea := p.linea
+	abre := p.actual()
+	cierra := '}'
+	if abre == '(' {
+		cierra = ')'
+	}
+	p.avanzar()
+	inicio, nivel := p.pos, 0
+	for !p.fin() {
+		switch c := p.actual(); {
+		case c == '\\':
+			p.avanzar() // An escaped brace does not count
+		case c == abre:
+			nivel++
+		case c == cierra && nivel == 0:
+			s := string(p.src[inicio:p.pos])
+			p.avanzar()
+			return s, nil
+		case c == cierra:
+			nivel--
+		}
+		p.avanzar()
+	}
+	return "", &Error{linea, "llave sin cerrar"}
+}
+
+// entreComillas reads a quoted value; quotes inside braces do not end it.
+func (p *parser) entreComillas() (string, error) {
+	linea := p.linea
+	p.avanzar()
+	inicio, nivel := p.pos, 0
+	for !p.fin() {
+		switch p.actual() {
+		case '\\':
+			p.avanzar()
+		case '{':
+			nivel++
+		case '}':
+			nivel--
+		case '"':
+			if nivel == 0 {
+				s := string(p.src[inicio:p.pos])
+				p.avanzar()
+				return s, nil
+			}
+		}
+		p.avanzar()
+	}
+	return "", &Error{linea, "comillas sin cerrar"}
+}
+
+// identificador reads an entry type, field name, number or macro name.
+func (p *parser) identificador() string {
+	inicio := p.pos
+	for !p.fin() {
+		c := p.actual()
+		if unicode.IsSpace(c) || strings.ContainsRune(`{}(),=#"@%'`, c) {
+			break
+		}
+		p.avanzar()
+	}
+	return string(p.src[inicio:p.pos])
+}
+
+// hasta reads up to (not including) either delimiter.
+func (p *parser) hasta(a, b rune) string {
+	inicio := p.pos
+	for !p.fin() && p.actual() != a && p.actual() != b {
+		p.avanzar()
+	}
+	return string(p.src[inicio:p.pos])
+}
+
+// saltarHasta advances to the next c, reporting whether there is one.
+func (p *parser) saltarHasta(c rune) bool {
+	for !p.fin() && p.actual() != c {
+		p.avanzar()
+	}
+	return !p.fin()
+}
+
+func (p *parser) saltarEspacios() {
+	for !p.fin() && unicode.IsSpace(p.actual()) {
+		p.avanzar()
+	}
+}
+
+func (p *parser) fin() bool { return p.pos >= len(p.src) }
+
+func (p *parser) actual() rune {
+	if p.fin() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) avanzar() {
+	if p.fin() {
+		return
+	}
+	if p.src[p.pos] == '\n' {
+		p.linea++
+	}
+	p.pos++
+}
+
+func (p *parser) retroceder() {
+	p.pos--
+	if p.src[p.pos] == '\n' {
+		p.linea--
+	}
+}
@@ -0,0 +1,131 @@
+package bibtex
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// acentos maps the LaTeX accent commands to the combining mark they add.
+var acentos = map[string]rune{
+	"'": '\u0301', "`": '\u0300', "^": '\u0302', `"`: '\u0308', "~": '\u0303', "=": '\u0304', ".": '\u0307',
+	"c": '\u0327', "u": '\u0306', "v": '\u030c', "H": '\u030b', "k": '\u0328', "r": '\u030a',
+}
+
+// letras maps the LaTeX commands of special letters to the letter.
+var letras = map[string]string{
+	"i": "i", "j": "j", "o": "ø", "O": "Ø", "l": "ł", "L": "Ł", "ss": "ß",
+	"ae": "æ", "AE": "Æ", "oe": "œ", "OE": "Œ", "aa": "å", "AA": "Å",
+	"textbackslash": `\`, "textasciitilde": "~", "textasciicircum": "^",
+}
+
+// Decodificar turns a raw BibTeX value into plain text: accents and escapes are decoded, grouping
+// braces and formatting commands (\emph{...}) dropped, ~ and runs of spaces turned into a single
+// space, and -- and --- into dashes.
+func Decodificar(s string) string {
+	src := []rune(s)
+	var b strings.Builder
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '\\' && i+1 < len(src):
+			i++
+			n := src[i]
+			if strings.ContainsRune(`&%$#_{}\ `, n) {
+				b.WriteRune(n)
+				continue
+			}
+			// Command name: one symbol, or letters
+			j := i
+			if unicode.IsLetter(n) {
+				for j+1 < len(src) && unicode.IsLetter(src[j+1]) {
+					j++
+				}
+			}
+			cmd := string(src[i : j+1])
+			i = j
+			if marca, ok := acentos[cmd]; ok {
+				var arg string
+				arg, i = argumento(src, i+1)
+				letra := []rune(Decodificar(arg))
+				if len(letra) == 0 {
+					continue
+				}
+				b.WriteString(norm.NFC.String(string(letra[0]) + string(marca) + string(letra[1:])))
+				continue
+			}
+			if l, ok := letras[cmd]; ok {
+				b.WriteString(l)
+				// "\ss{}" and "\o " end the command
+				if i+2 < len(src) && src[i+1] == '{' && src[i+2] == '}' {
+					i += 2
+				} else if i+1 < len(src) && src[i+1] == ' ' {
+					i++
+				}
+			}
+			// Any other command (\emph, \textit, ...) is dropped and its argument kept
+		case c == '{' || c == '}':
+		case c == '~' || unicode.IsSpace(c):
+			b.WriteRune(' ')
+		case c == '-' && i+1 < len(src) && src[i+1] == '-':
+			if i+2 < len(src) && src[i+2] == '-' {
+				b.WriteRune('—')
+				i += 2
+			} else {
+				b.WriteRune('–')
+				i++
+			}
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// argumento returns the argument of an accent command starting at src[i] (after optional spaces
+// when the command is a letter, as in \c c): a braced group or a single character, and the index
+// of its last rune.
+func argumento(src []rune, i int) (string, int) {
+	for i < len(src) && src[i] == ' ' {
+		i++
+	}
+	if i >= len(src) {
+		return "", i - 1
+	}
+	if src[i] != '{' {
+		if src[i] == '\\' && i+1 < len(src) {
+			// \'\i
+			j := i + 1
+			for j+1 < len(src) && unicode.IsLetter(src[j+1]) {
+				j++
+			}
+			return string(src[i : j+1]), j
+		}
+		return string(src[i]), i
+	}
+	nivel := 0
+	for j := i; j < len(src); j++ {
+		switch src[j] {
+		case '{':
+			nivel++
+		case '}':
+			nivel--
+			if nivel == 0 {
+				return string(src[i+1 : j]), j
+			}
+		}
+	}
+	return string(src[i+1:]), len(src) - 1
+}
+
+// Codificar escapes the characters with a meaning in BibTeX values. Other characters, accented
+// letters included, are written as they are (UTF-8).
+func Codificar(s string) string {
+	return codificador.Replace(strings.Join(strings.Fields(s), " "))
+}
+
+var codificador = strings.NewReplacer(
+	`\`, `\textbackslash{}`, "{", `\{`, "}", `\}`, "&", `\&`, "%", `\%`, "$", `\$`, "#", `\#`, "_", `\_`,
+	"~", `\textasciitilde{}`, "^", `\textasciicircum{}`,
+)
@@ -0,0 +1,200 @@
+package bibtex
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// tipos maps the BibTeX entry types to the publicacion types; the rest are otro.
+var tipos = map[string]string{
+	"article":       models.PublicacionArticulo,
+	"book":          models.PublicacionLibro,
+	"booklet":       models.PublicacionLibro,
+	"inbook":        models.PublicacionCapitulo,
+	"incollection":  models.PublicacionCapitulo,
+	"inproceedings": models.PublicacionPonencia,
+	"conference":    models.PublicacionPonencia,
+	"proceedings":   models.PublicacionPonencia,
+	"phdthesis":     models.PublicacionTesis,
+	"mastersthesis": models.PublicacionTesis,
+	"thesis":        models.PublicacionTesis,
+}
+
+// exportacion is the entry type and the field holding revista of each publicacion type.
+var exportacion = map[string][2]string{
+	models.PublicacionArticulo: {"article", "journal"},
+	models.PublicacionLibro:    {"book", "publisher"},
+	models.PublicacionCapitulo: {"incollection", "booktitle"},
+	models.PublicacionPonencia: {"inproceedings", "booktitle"},
+	models.PublicacionTesis:    {"thesis", "school"},
+	models.PublicacionOtro:     {"misc", "howpublished"},
+}
+
+// camposRevista are the fields read as revista, by preference.
+var camposRevista = []string{"journal", "journaltitle", "booktitle", "publisher", "school", "institution", "howpublished"}
+
+var anioEnFecha = regexp.MustCompile(`\b(\d{4})\b`)
+
+// Publicacion converts an entry to a publicacion, without links, and returns its authors as
+// "Nombre Apellido". An invalid DOI is left out; the result is not validated.
+func Publicacion(e Entrada) (models.Publicacion, []string) {
+	p := models.Publicacion{
+		Titulo: e.Campos["title"],
+		Tipo:   models.PublicacionOtro,
+	}
+	if t, ok := tipos[e.Tipo]; ok {
+		p.Tipo = t
+	}
+	for _, campo := range camposRevista {
+		if v := e.Campos[campo]; v != "" {
+			p.Revista = v
+			break
+		}
+	}
+	for _, campo := range []string{"year", "date"} {
+		if m := anioEnFecha.FindStringSubmatch(e.Campos[campo]); m != nil {
+			p.Anio, _ = strconv.Atoi(m[1])
+			break
+		}
+	}
+	if doi, ok := utils.NormalizarDOI(e.Campos["doi"]); ok {
+		p.DOI = &doi
+	}
+	return p, Autores(e.Campos["author"])
+}
+
+// Autores splits a BibTeX name list ("Apellido, Nombre and Nombre Apellido and ...") into names in
+// the "Nombre Apellido" order. "others" (et al.) is dropped.
+func Autores(s string) []string {
+	var autores []string
+	for _, a := range regexp.MustCompile(`(?i)\s+and\s+`).Split(strings.TrimSpace(s), -1) {
+		a = strings.TrimSpace(a)
+		if a == "" || strings.EqualFold(a, "others") {
+			continue
+		}
+		if partes := strings.Split(a, ","); len(partes) > 1 {
+			// "Apellido, Nombre" or "Apellido, Jr, Nombre"
+			a = strings.TrimSpace(partes[len(partes)-1]) + " " + strings.TrimSpace(partes[0])
+		}
+		autores = append(autores, strings.Join(strings.Fields(a), " "))
+	}
+	return autores
+}
+
+// EntradaPublicacion converts a publicacion to an entry with the given citation key.
+func EntradaPublicacion(p models.PublicacionConAutores, clave string) Entrada {
+	exp, ok := exportacion[p.Tipo]
+	if !ok {
+		exp = exportacion[models.PublicacionOtro]
+	}
+	e := Entrada{Tipo: exp[0], Clave: clave, Campos: map[string]string{
+		"title": p.Titulo,
+		"year":  strconv.Itoa(p.Anio),
+	}}
+	if len(p.Autores) > 0 {
+		e.Campos["author"] = strings.Join(p.Autores, " and ")
+	}
+	if p.Revista != "" {
+		e.Campos[exp[1]] = p.Revista
+	}
+	if p.DOI != nil {
+		e.Campos["doi"] = *p.DOI
+	}
+	return e
+}
+
+// Clave builds a citation key from the first author's surname and the year ("quispe2023"),
+// adding a, b, ... when usadas already has it, and records it there.
+func Clave(p models.PublicacionConAutores, usadas map[string]bool) string {
+	base := "publicacion"
+	if len(p.Autores) > 0 {
+		apellido, _, _ := strings.Cut(p.Autores[0], ",")
+		if f := strings.Fields(apellido); len(f) > 0 {
+			base = f[0]
+		}
+	}
+	var b strings.Builder
+	for _, r := range norm.NFD.String(base) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	if b.Len() == 0 {
+		b.WriteString("publicacion")
+	}
+	clave := b.String() + strconv.Itoa(p.Anio)
+	for i := 0; usadas[clave]; i++ {
+		clave = b.String() + strconv.Itoa(p.Anio) + sufijo(i)
+	}
+	usadas[clave] = true
+	return clave
+}
+
+// sufijo returns a, b, ..., z, aa, ab, ... for 0, 1, ...
+func sufijo(i int) string {
+	if i < 26 {
+		return string(rune('a' + i))
+	}
+	return sufijo(i/26-1) + string(rune('a'+i%26))
+}
+
+// ordenCampos is the order fields are written in; others follow alphabetically.
+var ordenCampos = []string{"author", "title", "journal", "booktitle", "publisher", "school", "howpublished", "year", "doi"}
+
+// Escribir writes entries as BibTeX.
+func Escribir(w io.Writer, entradas []Entrada) error {
+	for i, e := range entradas {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "@%s{%s,\n", e.Tipo, e.Clave); err != nil {
+			return err
+		}
+		nombres := make([]string, 0, len(e.Campos))
+		for nombre := range e.Campos {
+			nombres = append(nombres, nombre)
+		}
+		sort.Slice(nombres, func(i, j int) bool {
+			pi, pj := posicionCampo(nombres[i]), posicionCampo(nombres[j])
+			if pi != pj {
+				return pi < pj
+			}
+			return nombres[i] < nombres[j]
+		})
+		for _, nombre := range nombres {
+			valor := Codificar(e.Campos[nombre])
+			if nombre == "doi" {
+				// DOIs are written verbatim, as reference managers expect
+				valor = e.Campos[nombre]
+			}
+			if _, err := fmt.Fprintf(w, "  %s = {%s},\n", nombre, valor); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "}\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func posicionCampo(nombre string) int {
+	for i, n := range ordenCampos {
+		if n == nombre {
+			return i
+		}
+	}
+	return len(ordenCampos)
+}
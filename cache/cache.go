@@ -0,0 +1,187 @@
+// Package cache keeps the responses of hot read queries (the group and investigator directory)
+// in memory or in Redis for a short time.
+//
+// Entries are invalidated as a whole: every key includes a generation number and Invalidate bumps
+// it, so after a write no instance serves the previous entries (with Redis, the generation is
+// shared by all the instances) and the old ones simply expire.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+)
+
+const (
+	defaultTTL        = time.Minute
+	defaultMaxEntries = 1000
+	generationKey     = "generacion"
+)
+
+// Backend stores raw entries.
+type Backend interface {
+	// Name identifies the backend ("memory", "redis").
+	Name() string
+	// Get returns the value of key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Incr increments the counter at key (0 if missing) and returns its new value.
+	Incr(ctx context.Context, key string) (int64, error)
+	// Counter returns the value of the counter at key, 0 if missing.
+	Counter(ctx context.Context, key string) (int64, error)
+}
+
+// Config controls the cache.
+type Config struct {
+	Backend    string        `json:"backend"`    // memory or redis
+	TTL        time.Duration `json:"ttl"`        // How long an entry is served
+	MaxEntries int           `json:"maxEntries"` // Entries kept by the memory backend
+	RedisURL   string        `json:"-"`          // May carry credentials
+}
+
+// MarshalJSON writes the TTL as a string ("1m0s") instead of nanoseconds.
+func (c Config) MarshalJSON() ([]byte, error) {
+	type plain Config
+	return json.Marshal(struct {
+		plain
+		TTL string `json:"ttl"`
+	}{plain(c), c.TTL.String()})
+}
+
+// ConfigFromEnv reads CACHE_BACKEND (memory or redis; empty disables the cache), CACHE_TTL,
+// CACHE_MAX_ENTRIES and REDIS_URL, using the defaults for unset or invalid values. It reports false
+// when the cache is disabled.
+func ConfigFromEnv() (Config, bool) {
+	c := Config{Backend: os.Getenv("CACHE_BACKEND"), TTL: defaultTTL, MaxEntries: defaultMaxEntries, RedisURL: os.Getenv("REDIS_URL")}
+	if v, err := time.ParseDuration(os.Getenv("CACHE_TTL")); err == nil && v > 0 {
+		c.TTL = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("CACHE_MAX_ENTRIES")); err == nil && v > 0 {
+		c.MaxEntries = v
+	}
+	return c, c.Backend != ""
+}
+
+// Cache serves entries from a backend and counts hits and misses.
+type Cache struct {
+	cfg     Config
+	backend Backend
+
+	hits, misses, errors, invalidations atomic.Int64
+}
+
+// Status describes the cache, for GET /admin/cache.
+type Status struct {
+	Config        Config  `json:"config"`
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	HitRatio      float64 `json:"hitRatio"`
+	Errors        int64   `json:"errors"` // Backend failures; the query is then run uncached
+	Invalidations int64   `json:"invalidations"`
+}
+
+// New creates a cache from its configuration.
+func New(cfg Config) (*Cache, error) {
+	var backend Backend
+	switch cfg.Backend {
+	case "memory":
+		backend = NewMemory(cfg.MaxEntries)
+	case "redis":
+		if cfg.RedisURL == "" {
+			return nil, errors.New("CACHE_BACKEND=redis requires REDIS_URL")
+		}
+		var err error
+		if backend, err = NewRedis(cfg.RedisURL); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown CACHE_BACKEND %q (use memory or redis)", cfg.Backend)
+	}
+	return &Cache{cfg: cfg, backend: backend}, nil
+}
+
+// Load returns the value cached under key, or calls load, caches its result and returns it. A nil
+// cache always calls load. Backend errors are logged and counted, never returned: the cache is an
+// optimization and the query still runs.
+func Load[T any](ctx context.Context, c *Cache, key string, load func() (T, error)) (T, error) {
+	if c == nil {
+		return load()
+	}
+	fullKey, err := c.key(ctx, key)
+	if err != nil {
+		c.fail(ctx, "reading generation", err)
+		return load()
+	}
+	if raw, ok, err := c.backend.Get(ctx, fullKey); err != nil {
+		c.fail(ctx, "reading entry", err)
+	} else if ok {
+		var v T
+		if err := json.Unmarshal(raw, &v); err == nil {
+			c.hits.Add(1)
+			return v, nil
+		}
+	}
+	c.misses.Add(1)
+
+	v, err := load()
+	if err != nil {
+		return v, err
+	}
+	raw, err := json.Marshal(v)
+	if err == nil {
+		err = c.backend.Set(ctx, fullKey, raw, c.cfg.TTL)
+	}
+	if err != nil {
+		c.fail(ctx, "storing entry", err)
+	}
+	return v, nil
+}
+
+// Invalidate discards every entry. It is safe to call on a nil cache.
+func (c *Cache) Invalidate(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	if _, err := c.backend.Incr(ctx, generationKey); err != nil {
+		// The entries expire after the TTL anyway
+		c.fail(ctx, "invalidating", err)
+		return
+	}
+	c.invalidations.Add(1)
+}
+
+// Status returns the cache's counters and configuration.
+func (c *Cache) Status() Status {
+	s := Status{
+		Config:        c.cfg,
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Errors:        c.errors.Load(),
+		Invalidations: c.invalidations.Load(),
+	}
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRatio = float64(s.Hits) / float64(total)
+	}
+	return s
+}
+
+func (c *Cache) key(ctx context.Context, key string) (string, error) {
+	gen, err := c.backend.Counter(ctx, generationKey)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(gen, 10) + ":" + key, nil
+}
+
+func (c *Cache) fail(ctx context.Context, op string, err error) {
+	c.errors.Add(1)
+	logging.FromContext(ctx).Warn("Cache error", "op", op, "backend", c.backend.Name(), "error", err)
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory keeps the entries in the process. Each instance has its own copy and its own generation.
+type Memory struct {
+	max int
+
+	mu       sync.Mutex
+	entries  map[string]memoryEntry
+	counters map[string]int64
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemory creates a memory backend holding up to max entries.
+func NewMemory(max int) *Memory {
+	return &Memory{max: max, entries: map[string]memoryEntry{}, counters: map[string]int64{}}
+}
+
+// Name implements Backend.
+func (m *Memory) Name() string { return "memory" }
+
+// Get implements Backend.
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(e.expires) {
+		delete(m.entries, key)
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+// Set implements Backend. When full, expired entries are dropped first and, if that is not
+// enough, the new entry is not stored.
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.entries) >= m.max {
+		now := time.Now()
+		for k, e := range m.entries {
+			if now.After(e.expires) {
+				delete(m.entries, k)
+			}
+		}
+		if len(m.entries) >= m.max {
+			return nil
+		}
+	}
+	m.entries[key] = memoryEntry{value: value, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// Incr implements Backend. Entries of older generations can no longer be requested, so they are
+// dropped right away.
+func (m *Memory) Incr(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[key]++
+	m.entries = map[string]memoryEntry{}
+	return m.counters[key], nil
+}
+
+// Counter implements Backend.
+func (m *Memory) Counter(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counters[key], nil
+}
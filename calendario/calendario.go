@@ -0,0 +1,203 @@
+// Package calendario handles calendar dates (DATE columns) in the institution's time zone.
+//
+// A date such as a group's fechaRegistro is a day, not an instant: it is parsed from and stored as
+// 2006-01-02, and held in memory as midnight of that day in the institutional time zone
+// (INSTITUTION_TIMEZONE, America/Lima by default). Serialized as a timestamp it is therefore
+// 2023-03-15T00:00:00-05:00, which clients in that zone read as the same day; the former UTC
+// midnight (2023-03-15T00:00:00Z) showed the previous day in the frontend.
+package calendario
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	_ "time/tzdata" // The zone must load in containers without the system's zoneinfo
+)
+
+// ZonaPorDefecto is the institutional time zone unless INSTITUTION_TIMEZONE says otherwise.
+const ZonaPorDefecto = "America/Lima"
+
+// Formato is the layout of a date in requests, the database and date-only responses.
+const Formato = time.DateOnly
+
+var zona atomic.Pointer[time.Location]
+
+// Configurar loads the institutional time zone from INSTITUTION_TIMEZONE (an IANA name). An
+// unknown zone is an error.
+func Configurar() error {
+	nombre := strings.TrimSpace(os.Getenv("INSTITUTION_TIMEZONE"))
+	if nombre == "" {
+		nombre = ZonaPorDefecto
+	}
+	loc, err := time.LoadLocation(nombre)
+	if err != nil {
+		return fmt.Errorf("invalid INSTITUTION_TIMEZONE %q: %w", nombre, err)
+	}
+	zona.Store(loc)
+	return nil
+}
+
+// Zona returns the institutional time zone.
+func Zona() *time.Location {
+	if loc := zona.Load(); loc != nil {
+		return loc
+	}
+	loc, err := time.LoadLocation(ZonaPorDefecto)
+	if err != nil {
+		return time.UTC
+	}
+	zona.Store(loc)
+	return loc
+}
+
+// Fecha is a calendar date: midnight of a day in the institutional time zone. The zero value is
+// no date. In JSON it is written as a timestamp (or as 2006-01-02 with JSON_DATE_FORMAT=date, see
+// utils.Serialization) and read as 2006-01-02; in the database it is a DATE.
+type Fecha struct {
+	t time.Time
+}
+
+// De returns the day of t in the institutional time zone.
+func De(t time.Time) Fecha {
+	t = t.In(Zona())
+	return dia(t.Year(), t.Month(), t.Day())
+}
+
+// Hoy returns today's date in the institutional time zone.
+func Hoy() Fecha {
+	return De(time.Now())
+}
+
+// Parse reads a date in the 2006-01-02 format. Timestamps and other formats are an error: the day
+// of a timestamp depends on the time zone it is read in.
+func Parse(s string) (Fecha, error) {
+	t, err := time.Parse(Formato, s)
+	if err != nil {
+		return Fecha{}, fmt.Errorf("fecha inválida %q: use el formato AAAA-MM-DD", s)
+	}
+	return dia(t.Year(), t.Month(), t.Day()), nil
+}
+
+// dia returns midnight of a day in the institutional time zone.
+func dia(anio int, mes time.Month, d int) Fecha {
+	return Fecha{time.Date(anio, mes, d, 0, 0, 0, 0, Zona())}
+}
+
+// Time returns midnight of the date in the institutional time zone.
+func (f Fecha) Time() time.Time {
+	return f.t
+}
+
+// IsZero reports whether f is no date.
+func (f Fecha) IsZero() bool {
+	return f.t.IsZero()
+}
+
+// Before reports whether f is a day before g.
+func (f Fecha) Before(g Fecha) bool {
+	return f.t.Before(g.t)
+}
+
+// After reports whether f is a day after g.
+func (f Fecha) After(g Fecha) bool {
+	return f.t.After(g.t)
+}
+
+// AddDate returns the date years, months and days after f, normalized as time.Time.AddDate.
+func (f Fecha) AddDate(years, months, days int) Fecha {
+	return dia(f.t.Year()+years, f.t.Month()+time.Month(months), f.t.Day()+days)
+}
+
+// Format formats midnight of the date in the institutional time zone, as time.Time.Format.
+func (f Fecha) Format(layout string) string {
+	return f.t.Format(layout)
+}
+
+// String returns the date as 2006-01-02, or "" for the zero value.
+func (f Fecha) String() string {
+	if f.IsZero() {
+		return ""
+	}
+	return f.Format(Formato)
+}
+
+// MarshalJSON writes the date as the RFC 3339 timestamp of its midnight in the institutional
+// time zone, the format of every other date of the API.
+func (f Fecha) MarshalJSON() ([]byte, error) {
+	return f.t.MarshalJSON()
+}
+
+// UnmarshalText reads a date as 2006-01-02; "" is no date. A timestamp is only accepted at
+// midnight of its own offset, as MarshalJSON writes it (in any zone), so responses can be sent
+// back; any other is rejected with a *json.UnmarshalTypeError (see EsFechaInvalida).
+func (f *Fecha) UnmarshalText(b []byte) error {
+	s := string(b)
+	if s == "" {
+		*f = Fecha{}
+		return nil
+	}
+	if fecha, err := Parse(s); err == nil {
+		*f = fecha
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		if h, m, seg := t.Clock(); h == 0 && m == 0 && seg == 0 && t.Nanosecond() == 0 {
+			*f = dia(t.Year(), t.Month(), t.Day())
+			return nil
+		}
+	}
+	return &json.UnmarshalTypeError{Value: "string " + strconv.Quote(s), Type: reflect.TypeOf(Fecha{})}
+}
+
+// EsFechaInvalida reports whether err, returned by encoding/json, is a Fecha that was not a date.
+// encoding/json does not name the field of such an error, so the caller has to.
+func EsFechaInvalida(err error) bool {
+	var tipo *json.UnmarshalTypeError
+	return errors.As(err, &tipo) && tipo.Type == reflect.TypeOf(Fecha{})
+}
+
+// Value implements driver.Valuer: the date is sent as 2006-01-02, so no time zone conversion can
+// move it to another day.
+func (f Fecha) Value() (driver.Value, error) {
+	if f.IsZero() {
+		return nil, nil
+	}
+	return f.Format(Formato), nil
+}
+
+// Scan implements sql.Scanner. Drivers return DATE columns as time.Time at UTC midnight or as
+// text; either way the day is kept as is.
+func (f *Fecha) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*f = Fecha{}
+	case time.Time:
+		*f = dia(v.Year(), v.Month(), v.Day())
+	case string:
+		return f.scanTexto(v)
+	case []byte:
+		return f.scanTexto(string(v))
+	default:
+		return fmt.Errorf("no se puede leer una fecha de %T", src)
+	}
+	return nil
+}
+
+func (f *Fecha) scanTexto(s string) error {
+	if len(s) < len(Formato) {
+		return fmt.Errorf("fecha inválida en la base de datos: %q", s)
+	}
+	fecha, err := Parse(s[:len(Formato)])
+	if err != nil {
+		return err
+	}
+	*f = fecha
+	return nil
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// ListAuditLogs returns a page of the audit trail, optionally filtered by entidad (admin only).
+func (c *Client) ListAuditLogs(ctx context.Context, entidad string, opts PageOptions) (*Page[models.AuditLog], error) {
+	q := url.Values{}
+	if entidad != "" {
+		q.Set("entidad", entidad)
+	}
+	opts.apply(q)
+	var p Page[models.AuditLog]
+	if err := c.do(ctx, http.MethodGet, "/admin/auditoria", q, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListArchivosDuplicados returns a page of documents with identical content attached to several groups
+// (admin only). mismoGrupo also includes files repeated within one group.
+func (c *Client) ListArchivosDuplicados(ctx context.Context, mismoGrupo bool, opts PageOptions) (*Page[models.ArchivoDuplicado], error) {
+	q := url.Values{}
+	opts.apply(q)
+	if mismoGrupo {
+		q.Set("mismoGrupo", "true")
+	}
+	var p Page[models.ArchivoDuplicado]
+	if err := c.do(ctx, http.MethodGet, "/admin/archivos-duplicados", q, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetSupportBundle returns the diagnostics bundle as raw JSON (admin only).
+func (c *Client) GetSupportBundle(ctx context.Context) (json.RawMessage, error) {
+	var raw json.RawMessage
+	if err := c.do(ctx, http.MethodGet, "/admin/support-bundle", nil, nil, &raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// ArchivoInput is an attachment to upload to a group.
+type ArchivoInput struct {
+	Nombre        string
+	Tipo          string // models.TipoArchivo*; default "otro"
+	Publico       bool
+	Archivo       io.Reader
+	NombreArchivo string
+}
+
+// ListGrupoArchivos lists a group's attachments (non-public ones only when authenticated).
+func (c *Client) ListGrupoArchivos(ctx context.Context, idGrupo int) ([]models.GrupoArchivo, error) {
+	var a []models.GrupoArchivo
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/grupos/%d/archivos", idGrupo), nil, nil, &a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// CreateGrupoArchivo uploads an attachment for a group.
+func (c *Client) CreateGrupoArchivo(ctx context.Context, idGrupo int, in ArchivoInput) (*models.GrupoArchivo, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("nombre", in.Nombre)
+	mw.WriteField("tipo", in.Tipo)
+	if in.Publico {
+		mw.WriteField("publico", "true")
+	}
+	if in.Archivo != nil {
+		if err := writeFile(mw, "archivo", in.NombreArchivo, in.Archivo); err != nil {
+			return nil, fmt.Errorf("error encoding attachment form: %w", err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("error encoding attachment form: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, fmt.Sprintf("/grupos/%d/archivos", idGrupo), nil, &buf, mw.FormDataContentType())
+	if err != nil {
+		return nil, err
+	}
+	var a models.GrupoArchivo
+	if err := c.doRequest(req, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// CompartirArchivo creates a temporary share link to a non-public attachment, valid for the given hours.
+func (c *Client) CompartirArchivo(ctx context.Context, idGrupo, idArchivo, horas int) (*models.EnlaceCompartido, error) {
+	var e models.EnlaceCompartido
+	body := models.CrearEnlaceCompartidoRequest{Horas: horas}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/grupos/%d/archivos/%d/share", idGrupo, idArchivo), nil, body, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// DescargarArchivoCompartido downloads an attachment through a share token (or full share URL).
+// The caller must close the returned reader.
+func (c *Client) DescargarArchivoCompartido(ctx context.Context, token string) (io.ReadCloser, string, error) {
+	if i := strings.LastIndex(token, "/"); i >= 0 {
+		token = token[i+1:]
+	}
+	return c.download(ctx, "/compartido/"+token, nil)
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/version"
+)
+
+// Register creates a user account.
+func (c *Client) Register(ctx context.Context, email, password string) (*models.Usuario, error) {
+	var u models.Usuario
+	if err := c.do(ctx, http.MethodPost, "/register", nil, models.Credentials{Email: email, Password: password}, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Login authenticates and stores the returned JWT, which is sent on every following request.
+func (c *Client) Login(ctx context.Context, email, password string) error {
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/login", nil, models.Credentials{Email: email, Password: password}, &resp); err != nil {
+		return err
+	}
+	c.SetToken(resp.Token)
+	return nil
+}
+
+// Version returns the server's build information.
+func (c *Client) Version(ctx context.Context) (*version.Info, error) {
+	var info version.Info
+	if err := c.do(ctx, http.MethodGet, "/version", nil, nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
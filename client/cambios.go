@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// CambiosOptions selects a page of the change feed.
+type CambiosOptions struct {
+	Since     string   // NextCursor of the previous page, or a date; empty starts at the oldest change kept
+	Entidades []string // Only changes of these entities ("grupo", "investigador"...)
+	Limit     int      // Max 1000; zero uses the server default (100)
+}
+
+// GetCambios returns a page of the change feed. Pass its NextCursor as Since to get the next one;
+// an *APIError with StatusCode 410 means the cursor is older than the feed's retention and the data must
+// be downloaded again.
+func (c *Client) GetCambios(ctx context.Context, opts CambiosOptions) (*models.FeedCambios, error) {
+	q := url.Values{}
+	if opts.Since != "" {
+		q.Set("since", opts.Since)
+	}
+	if len(opts.Entidades) > 0 {
+		q.Set("entidad", strings.Join(opts.Entidades, ","))
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	var f models.FeedCambios
+	if err := c.do(ctx, http.MethodGet, "/changes", q, nil, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
@@ -0,0 +1,193 @@
+// Package client is a typed Go client for the research groups API.
+//
+// Usage:
+//
+//	c := client.New("https://api.example.com")
+//	if err := c.Login(ctx, "user@example.com", "secret"); err != nil { ... }
+//	for g, err := range c.GruposIter(ctx, client.GruposFilter{Anios: []int{2023}}) { ... }
+//
+// Errors returned by the API are reported as *APIError.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// Client calls the API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+
+	mu    sync.RWMutex
+	token string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the underlying HTTP client (default http.DefaultClient).
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithToken sets a JWT obtained elsewhere, instead of calling Login.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// New creates a client for the API at baseURL (e.g. "http://localhost:3000").
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// SetToken replaces the JWT sent in the Authorization header.
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	c.token = token
+	c.mu.Unlock()
+}
+
+// Token returns the JWT currently in use ("" if not authenticated).
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// APIError is an error response from the API.
+type APIError struct {
+	StatusCode int
+	Code       string             // Stable error code, e.g. "not_found" or "conflict"
+	Message    string             // "message" field of the JSON envelope
+	Version    string             // Server build that produced the error (X-API-Version)
+	Body       []byte             // Raw response body, e.g. to decode the duplicates of a 409
+	Fields     []utils.FieldError // Offending fields of 409/422 validation errors, if any
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api error %d: %s", e.StatusCode, e.Message)
+}
+
+// newRequest builds a request; body may be nil, an io.Reader (sent as is with contentType) or a value encoded as JSON.
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body interface{}, contentType string) (*http.Request, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding request body: %w", err)
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	if token := c.Token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
+
+// send executes req and returns the response, converting non-2xx statuses into *APIError.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	apiErr := &APIError{StatusCode: resp.StatusCode, Body: data, Message: strings.TrimSpace(string(data)), Version: resp.Header.Get("X-API-Version")}
+	var envelope struct {
+		Code    string          `json:"code"`
+		Message string          `json:"message"`
+		Error   string          `json:"error"` // Servers before the code field
+		Details json.RawMessage `json:"details"`
+	}
+	if json.Unmarshal(data, &envelope) == nil && (envelope.Message != "" || envelope.Error != "") {
+		apiErr.Code = envelope.Code
+		apiErr.Message = envelope.Message
+		if apiErr.Message == "" {
+			apiErr.Message = envelope.Error
+		}
+		// Details hold the fields of 409/422 field errors, and other data (e.g. a size limit) elsewhere
+		var fields []utils.FieldError
+		if json.Unmarshal(envelope.Details, &fields) == nil {
+			apiErr.Fields = fields
+		}
+	}
+	return nil, apiErr
+}
+
+// do sends a JSON request and decodes the JSON response into out (which may be nil).
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	req, err := c.newRequest(ctx, method, path, query, body, "")
+	if err != nil {
+		return err
+	}
+	return c.doRequest(req, out)
+}
+
+// doRequest sends a prepared request and decodes the JSON response into out (which may be nil).
+func (c *Client) doRequest(req *http.Request, out interface{}) error {
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding response of %s %s: %w", req.Method, req.URL.Path, err)
+	}
+	return nil
+}
+
+// download sends a GET request and returns the raw response body; the caller must close it.
+func (c *Client) download(ctx context.Context, path string, query url.Values) (io.ReadCloser, string, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, path, query, nil, "")
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, "", err
+	}
+	return resp.Body, resp.Header.Get("Content-Type"), nil
+}
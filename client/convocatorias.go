@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// ListConvocatorias returns a page of convocatorias, optionally filtered by estado.
+func (c *Client) ListConvocatorias(ctx context.Context, estado string, opts PageOptions) (*Page[models.Convocatoria], error) {
+	q := url.Values{}
+	if estado != "" {
+		q.Set("estado", estado)
+	}
+	opts.apply(q)
+	var p Page[models.Convocatoria]
+	if err := c.do(ctx, http.MethodGet, "/convocatorias", q, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ConvocatoriasIter iterates over every convocatoria, optionally filtered by estado.
+func (c *Client) ConvocatoriasIter(ctx context.Context, estado string) iter.Seq2[models.Convocatoria, error] {
+	return iteratePages(ctx, 0, func(ctx context.Context, opts PageOptions) (*Page[models.Convocatoria], error) {
+		return c.ListConvocatorias(ctx, estado, opts)
+	})
+}
+
+// GetConvocatoria returns a convocatoria.
+func (c *Client) GetConvocatoria(ctx context.Context, id int) (*models.Convocatoria, error) {
+	var out models.Convocatoria
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/convocatorias/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateConvocatoria creates a convocatoria (admin only).
+func (c *Client) CreateConvocatoria(ctx context.Context, conv models.Convocatoria) (*models.Convocatoria, error) {
+	var out models.Convocatoria
+	if err := c.do(ctx, http.MethodPost, "/convocatorias", nil, conv, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateConvocatoria replaces a convocatoria's fields (admin only).
+func (c *Client) UpdateConvocatoria(ctx context.Context, id int, conv models.Convocatoria) (*models.Convocatoria, error) {
+	var out models.Convocatoria
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/convocatorias/%d", id), nil, conv, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteConvocatoria deletes a convocatoria (admin only).
+func (c *Client) DeleteConvocatoria(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/convocatorias/%d", id), nil, nil, nil)
+}
+
+// ListGruposConvocatoria returns the groups participating in a convocatoria.
+func (c *Client) ListGruposConvocatoria(ctx context.Context, id int) ([]models.Grupo, error) {
+	var grupos []models.Grupo
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/convocatorias/%d/grupos", id), nil, nil, &grupos); err != nil {
+		return nil, err
+	}
+	return grupos, nil
+}
+
+// AddGrupoConvocatoria registers a group in a convocatoria.
+func (c *Client) AddGrupoConvocatoria(ctx context.Context, id, idGrupo int) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/convocatorias/%d/grupos", id), nil, models.ParticipacionConvocatoriaRequest{IDGrupo: idGrupo}, nil)
+}
+
+// RemoveGrupoConvocatoria removes a group from a convocatoria.
+func (c *Client) RemoveGrupoConvocatoria(ctx context.Context, id, idGrupo int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/convocatorias/%d/grupos/%d", id, idGrupo), nil, nil, nil)
+}
+
+// ListConvocatoriasGrupo returns the convocatorias a group participates in.
+func (c *Client) ListConvocatoriasGrupo(ctx context.Context, idGrupo int) ([]models.Convocatoria, error) {
+	var convocatorias []models.Convocatoria
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/grupos/%d/convocatorias", idGrupo), nil, nil, &convocatorias); err != nil {
+		return nil, err
+	}
+	return convocatorias, nil
+}
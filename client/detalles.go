@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// DetallesFilter holds the filters of the membership listings. Zero values mean no filter.
+type DetallesFilter struct {
+	Rol       string    // Exact role, case-insensitive
+	Nombre    string    // Partial investigator name
+	ActivosEn time.Time // Memberships whose period includes this day, i.e. the roster on that day
+}
+
+func (f DetallesFilter) query() url.Values {
+	q := url.Values{}
+	if f.Rol != "" {
+		q.Set("rol", f.Rol)
+	}
+	if f.Nombre != "" {
+		q.Set("nombre", f.Nombre)
+	}
+	if !f.ActivosEn.IsZero() {
+		q.Set("activosEn", f.ActivosEn.Format("2006-01-02"))
+	}
+	return q
+}
+
+// ListDetalles returns a page of group-investigator memberships matching the filters.
+func (c *Client) ListDetalles(ctx context.Context, f DetallesFilter, opts PageOptions) (*Page[models.DetalleGrupoInvestigador], error) {
+	return c.listDetalles(ctx, "/detalles", f, opts)
+}
+
+// DetallesIter iterates over every membership matching the filters.
+func (c *Client) DetallesIter(ctx context.Context, f DetallesFilter) iter.Seq2[models.DetalleGrupoInvestigador, error] {
+	return iteratePages(ctx, 0, func(ctx context.Context, opts PageOptions) (*Page[models.DetalleGrupoInvestigador], error) {
+		return c.ListDetalles(ctx, f, opts)
+	})
+}
+
+func (c *Client) listDetalles(ctx context.Context, path string, f DetallesFilter, opts PageOptions) (*Page[models.DetalleGrupoInvestigador], error) {
+	q := f.query()
+	opts.apply(q)
+	var p Page[models.DetalleGrupoInvestigador]
+	if err := c.do(ctx, http.MethodGet, path, q, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetDetalle returns a membership by ID.
+func (c *Client) GetDetalle(ctx context.Context, id int) (*models.DetalleGrupoInvestigador, error) {
+	var d models.DetalleGrupoInvestigador
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/detalles/%d", id), nil, nil, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListDetallesByGrupo returns a page of the memberships of a group matching the filters.
+func (c *Client) ListDetallesByGrupo(ctx context.Context, idGrupo int, f DetallesFilter, opts PageOptions) (*Page[models.DetalleGrupoInvestigador], error) {
+	return c.listDetalles(ctx, fmt.Sprintf("/grupos/%d/detalles", idGrupo), f, opts)
+}
+
+// DetallesByGrupoIter iterates over every membership of a group matching the filters.
+func (c *Client) DetallesByGrupoIter(ctx context.Context, idGrupo int, f DetallesFilter) iter.Seq2[models.DetalleGrupoInvestigador, error] {
+	return iteratePages(ctx, 0, func(ctx context.Context, opts PageOptions) (*Page[models.DetalleGrupoInvestigador], error) {
+		return c.ListDetallesByGrupo(ctx, idGrupo, f, opts)
+	})
+}
+
+// GetDetallesByInvestigador returns the memberships of an investigator with the names of their
+// groups. A non-zero activosEn keeps only the memberships current on that day.
+func (c *Client) GetDetallesByInvestigador(ctx context.Context, idInvestigador int, activosEn time.Time) ([]models.DetalleConGrupo, error) {
+	q := url.Values{}
+	if !activosEn.IsZero() {
+		q.Set("activosEn", activosEn.Format("2006-01-02"))
+	}
+	var d []models.DetalleConGrupo
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/investigadores/%d/detalles", idInvestigador), q, nil, &d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// CreateDetalle adds an investigator to a group.
+func (c *Client) CreateDetalle(ctx context.Context, d models.DetalleGrupoInvestigador) (*models.DetalleGrupoInvestigador, error) {
+	var out models.DetalleGrupoInvestigador
+	if err := c.do(ctx, http.MethodPost, "/detalles", nil, d, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateDetalles adds many investigators to groups in one transaction. If any item is invalid
+// nothing is created and the API answers 422 listing the problems per item.
+func (c *Client) CreateDetalles(ctx context.Context, detalles []models.DetalleGrupoInvestigador) ([]models.DetalleGrupoInvestigador, error) {
+	var out []models.DetalleGrupoInvestigador
+	if err := c.do(ctx, http.MethodPost, "/detalles/bulk", nil, detalles, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateDetalle updates a membership.
+func (c *Client) UpdateDetalle(ctx context.Context, id int, d models.DetalleGrupoInvestigador) (*models.DetalleGrupoInvestigador, error) {
+	var out models.DetalleGrupoInvestigador
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/detalles/%d", id), nil, d, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteDetalle removes a membership.
+func (c *Client) DeleteDetalle(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/detalles/%d", id), nil, nil, nil)
+}
+
+// AddIntegrante adds an investigator to a group with the given role ("Integrante" when empty).
+func (c *Client) AddIntegrante(ctx context.Context, idGrupo, idInvestigador int, rol string) (*models.DetalleGrupoInvestigador, error) {
+	var out models.DetalleGrupoInvestigador
+	in := models.IntegranteRequest{IDInvestigador: idInvestigador, Rol: rol}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/grupos/%d/investigadores", idGrupo), nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateIntegrante changes the role of an investigator in a group.
+func (c *Client) UpdateIntegrante(ctx context.Context, idGrupo, idInvestigador int, rol string) (*models.DetalleGrupoInvestigador, error) {
+	var out models.DetalleGrupoInvestigador
+	in := models.IntegranteRequest{Rol: rol}
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/grupos/%d/investigadores/%d", idGrupo, idInvestigador), nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemoveIntegrante removes an investigator from a group.
+func (c *Client) RemoveIntegrante(ctx context.Context, idGrupo, idInvestigador int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/grupos/%d/investigadores/%d", idGrupo, idInvestigador), nil, nil, nil)
+}
+
+// SetCoordinador makes an investigator the coordinator of a group, demoting the previous one to Integrante.
+func (c *Client) SetCoordinador(ctx context.Context, idGrupo, idInvestigador int) (*models.DetalleGrupoInvestigador, error) {
+	var out models.DetalleGrupoInvestigador
+	in := models.CambiarCoordinadorRequest{IDInvestigador: idInvestigador}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/grupos/%d/coordinador", idGrupo), nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// CreateExport starts an asynchronous export job.
+func (c *Client) CreateExport(ctx context.Context, p models.ParametrosExport) (*models.ExportJob, error) {
+	var j models.ExportJob
+	if err := c.do(ctx, http.MethodPost, "/exports", nil, p, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// GetExport returns the status of an export job.
+func (c *Client) GetExport(ctx context.Context, id int) (*models.ExportJob, error) {
+	var j models.ExportJob
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/exports/%d", id), nil, nil, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// WaitExport polls an export job every interval until it is completed or fails.
+func (c *Client) WaitExport(ctx context.Context, id int, interval time.Duration) (*models.ExportJob, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		j, err := c.GetExport(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		switch j.Estado {
+		case models.ExportCompletado:
+			return j, nil
+		case models.ExportError:
+			msg := "export failed"
+			if j.Error != nil {
+				msg = *j.Error
+			}
+			return j, fmt.Errorf("export %d: %s", id, msg)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DownloadExport streams the file of a completed export job. The caller must close the reader.
+func (c *Client) DownloadExport(ctx context.Context, id int) (io.ReadCloser, string, error) {
+	return c.download(ctx, fmt.Sprintf("/exports/%d/download", id), nil)
+}
@@ -0,0 +1,364 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// GruposFilter holds the search filters of GET /grupos. Zero values mean no filter.
+type GruposFilter struct {
+	Q                   string   // Full-text search (nombre, numeroResolucion, lineaInvestigacion), ranked
+	Grupo               string   // Partial group name
+	Investigador        string   // Partial member name
+	Anios               []int    // Years of fechaRegistro
+	LineasInvestigacion []string // Partial matches, any of them
+	TiposInvestigacion  []string // Partial matches, any of them
+	Facultades          []int    // idFacultad, any of them
+	Estado              string
+	EstadoVigencia      string // vigente, por_vencer, vencido or sin_vigencia
+	IncludeDeleted      bool   // Admin only
+}
+
+func (f GruposFilter) query() url.Values {
+	q := url.Values{}
+	if f.Q != "" {
+		q.Set("q", f.Q)
+	}
+	if f.Grupo != "" {
+		q.Set("grupo", f.Grupo)
+	}
+	if f.Investigador != "" {
+		q.Set("investigador", f.Investigador)
+	}
+	for _, a := range f.Anios {
+		q.Add("año", strconv.Itoa(a))
+	}
+	for _, l := range f.LineasInvestigacion {
+		q.Add("lineaInvestigacion", l)
+	}
+	for _, t := range f.TiposInvestigacion {
+		q.Add("tipoInvestigacion", t)
+	}
+	for _, id := range f.Facultades {
+		q.Add("facultad", strconv.Itoa(id))
+	}
+	if f.Estado != "" {
+		q.Set("estado", f.Estado)
+	}
+	if f.EstadoVigencia != "" {
+		q.Set("estadoVigencia", f.EstadoVigencia)
+	}
+	if f.IncludeDeleted {
+		q.Set("includeDeleted", "true")
+	}
+	return q
+}
+
+// GrupoInput is the data sent to create or update a group (multipart/form-data).
+type GrupoInput struct {
+	Nombre             string
+	NumeroResolucion   string
+	LineaInvestigacion string
+	TipoInvestigacion  string
+	FechaRegistro      string    // YYYY-MM-DD
+	Archivo            io.Reader // Optional file, uploaded to Drive
+	NombreArchivo      string    // File name for Archivo
+	Forzar             bool      // Create even if similar groups exist (create only)
+}
+
+// GrupoDeInvestigador is an item of GET /investigadores/{id}/grupos.
+type GrupoDeInvestigador = models.GrupoDeInvestigador
+
+// ListGrupos returns a page of groups (with members), applying the given filters.
+func (c *Client) ListGrupos(ctx context.Context, f GruposFilter, opts PageOptions) (*Page[models.GrupoWithInvestigadores], error) {
+	q := f.query()
+	opts.apply(q)
+	var p Page[models.GrupoWithInvestigadores]
+	if err := c.do(ctx, http.MethodGet, "/grupos", q, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GruposIter iterates over every group matching the filters, fetching pages as needed.
+func (c *Client) GruposIter(ctx context.Context, f GruposFilter) iter.Seq2[models.GrupoWithInvestigadores, error] {
+	return iteratePages(ctx, 0, func(ctx context.Context, opts PageOptions) (*Page[models.GrupoWithInvestigadores], error) {
+		return c.ListGrupos(ctx, f, opts)
+	})
+}
+
+// ListGruposWithDetails returns a page of GET /grupos/with-details.
+func (c *Client) ListGruposWithDetails(ctx context.Context, includeDeleted bool, opts PageOptions) (*Page[models.GrupoWithInvestigadores], error) {
+	q := url.Values{}
+	if includeDeleted {
+		q.Set("includeDeleted", "true")
+	}
+	opts.apply(q)
+	var p Page[models.GrupoWithInvestigadores]
+	if err := c.do(ctx, http.MethodGet, "/grupos/with-details", q, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetGrupo returns a group by ID.
+func (c *Client) GetGrupo(ctx context.Context, id int) (*models.Grupo, error) {
+	var g models.Grupo
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/grupos/%d", id), nil, nil, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// GetGruposByIDs returns the given groups with their investigators in one request (up to 100 IDs).
+func (c *Client) GetGruposByIDs(ctx context.Context, ids []int) (*Lote[models.GrupoWithInvestigadores], error) {
+	var l Lote[models.GrupoWithInvestigadores]
+	if err := c.do(ctx, http.MethodGet, "/grupos", idsQuery(ids), nil, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// GetGrupoVerificandoArchivo returns a group and whether its Drive file still exists.
+func (c *Client) GetGrupoVerificandoArchivo(ctx context.Context, id int) (*models.GrupoConEstadoArchivo, error) {
+	var g models.GrupoConEstadoArchivo
+	q := url.Values{"verificarArchivo": {"true"}}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/grupos/%d", id), q, nil, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// GetGrupoDetails returns a group with its members and their roles.
+func (c *Client) GetGrupoDetails(ctx context.Context, id int) (*models.GrupoWithInvestigadores, error) {
+	var g models.GrupoWithInvestigadores
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/grupos/%d/details", id), nil, nil, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// GetGrupoReport downloads the group's PDF report. The caller must close the returned reader.
+func (c *Client) GetGrupoReport(ctx context.Context, id int) (io.ReadCloser, error) {
+	body, _, err := c.download(ctx, fmt.Sprintf("/grupos/%d/report", id), nil)
+	return body, err
+}
+
+// GetGruposStats returns aggregate statistics about the groups.
+func (c *Client) GetGruposStats(ctx context.Context) (*models.EstadisticasGrupos, error) {
+	var s models.EstadisticasGrupos
+	if err := c.do(ctx, http.MethodGet, "/grupos/stats", nil, nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// FindGrupoDuplicados returns existing groups similar to a proposed one. umbral <= 0 uses the server default.
+func (c *Client) FindGrupoDuplicados(ctx context.Context, nombre, numeroResolucion string, umbral float64) ([]models.GrupoDuplicado, error) {
+	q := url.Values{}
+	if nombre != "" {
+		q.Set("nombre", nombre)
+	}
+	if numeroResolucion != "" {
+		q.Set("numeroResolucion", numeroResolucion)
+	}
+	if umbral > 0 {
+		q.Set("umbral", strconv.FormatFloat(umbral, 'f', -1, 64))
+	}
+	var d []models.GrupoDuplicado
+	if err := c.do(ctx, http.MethodGet, "/grupos/duplicates", q, nil, &d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ListGrupoDuplicadosPares returns pairs of existing groups that look duplicated.
+func (c *Client) ListGrupoDuplicadosPares(ctx context.Context, umbral float64, limit int) ([]models.ParGruposDuplicados, error) {
+	q := url.Values{}
+	if umbral > 0 {
+		q.Set("umbral", strconv.FormatFloat(umbral, 'f', -1, 64))
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var p []models.ParGruposDuplicados
+	if err := c.do(ctx, http.MethodGet, "/grupos/duplicates", q, nil, &p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// grupoForm encodes a GrupoInput as multipart/form-data.
+func grupoForm(in GrupoInput) (*bytes.Buffer, string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fields := map[string]string{
+		"nombre":             in.Nombre,
+		"numeroResolucion":   in.NumeroResolucion,
+		"lineaInvestigacion": in.LineaInvestigacion,
+		"tipoInvestigacion":  in.TipoInvestigacion,
+		"fechaRegistro":      in.FechaRegistro,
+	}
+	if in.Forzar {
+		fields["forzar"] = "true"
+	}
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			return nil, "", err
+		}
+	}
+	if in.Archivo != nil {
+		if err := writeFile(mw, "archivo", in.NombreArchivo, in.Archivo); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return &buf, mw.FormDataContentType(), nil
+}
+
+// writeFile adds a file part to a multipart form.
+func writeFile(mw *multipart.Writer, field, filename string, r io.Reader) error {
+	if filename == "" {
+		filename = field
+	}
+	part, err := mw.CreateFormFile(field, filename)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, r)
+	return err
+}
+
+// CreateGrupo creates a group. If similar groups exist and Forzar is false the API answers 409;
+// the *APIError's Body then lists them.
+func (c *Client) CreateGrupo(ctx context.Context, in GrupoInput) (*models.Grupo, error) {
+	return c.sendGrupoForm(ctx, http.MethodPost, "/grupos", in)
+}
+
+// UpdateGrupo updates a group, replacing its file if in.Archivo is set.
+func (c *Client) UpdateGrupo(ctx context.Context, id int, in GrupoInput) (*models.Grupo, error) {
+	return c.sendGrupoForm(ctx, http.MethodPut, fmt.Sprintf("/grupos/%d", id), in)
+}
+
+func (c *Client) sendGrupoForm(ctx context.Context, method, path string, in GrupoInput) (*models.Grupo, error) {
+	body, contentType, err := grupoForm(in)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding group form: %w", err)
+	}
+	req, err := c.newRequest(ctx, method, path, nil, body, contentType)
+	if err != nil {
+		return nil, err
+	}
+	var g models.Grupo
+	if err := c.doRequest(req, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// CreateGrupoWithDetails creates a group and its memberships in one request.
+func (c *Client) CreateGrupoWithDetails(ctx context.Context, in models.CreateGrupoWithDetailsRequest, forzar bool) (*models.Grupo, error) {
+	var q url.Values
+	if forzar {
+		q = url.Values{"forzar": {"true"}}
+	}
+	var g models.Grupo
+	if err := c.do(ctx, http.MethodPost, "/grupos/with-details", q, in, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// UpdateGrupoWithDetails updates a group and replaces its member list in a single transaction.
+func (c *Client) UpdateGrupoWithDetails(ctx context.Context, id int, in models.CreateGrupoWithDetailsRequest) (*models.GrupoWithInvestigadores, error) {
+	var g models.GrupoWithInvestigadores
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/grupos/%d/with-details", id), nil, in, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// DeleteGrupo soft-deletes a group.
+func (c *Client) DeleteGrupo(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/grupos/%d", id), nil, nil, nil)
+}
+
+// RestoreGrupo restores a soft-deleted group.
+func (c *Client) RestoreGrupo(ctx context.Context, id int) (*models.Grupo, error) {
+	var g models.Grupo
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/grupos/%d/restore", id), nil, nil, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// CambiarEstadoGrupo moves a group to another lifecycle state.
+func (c *Client) CambiarEstadoGrupo(ctx context.Context, id int, estado string) (*models.Grupo, error) {
+	var g models.Grupo
+	body := models.CambiarEstadoGrupoRequest{Estado: estado}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/grupos/%d/estado", id), nil, body, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// GetGruposByInvestigador returns the groups an investigator belongs to, with their members.
+func (c *Client) GetGruposByInvestigador(ctx context.Context, idInvestigador int) ([]GrupoDeInvestigador, error) {
+	var g []GrupoDeInvestigador
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/investigadores/%d/grupos", idInvestigador), nil, nil, &g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// GetEstadisticasPorFacultad returns group and investigator counts per facultad.
+func (c *Client) GetEstadisticasPorFacultad(ctx context.Context) ([]models.EstadisticasFacultad, error) {
+	var s []models.EstadisticasFacultad
+	if err := c.do(ctx, http.MethodGet, "/estadisticas/por-facultad", nil, nil, &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetGrupoPadre sets the parent of a group; nil makes it a top-level group.
+func (c *Client) SetGrupoPadre(ctx context.Context, id int, idPadre *int) (*models.Grupo, error) {
+	var g models.Grupo
+	in := models.CambiarGrupoPadreRequest{IDGrupoPadre: idPadre}
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/grupos/%d/padre", id), nil, in, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// GetSubgrupos returns a group with its whole subgroup tree.
+func (c *Client) GetSubgrupos(ctx context.Context, id int) (*models.GrupoNodo, error) {
+	var n models.GrupoNodo
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/grupos/%d/subgrupos", id), nil, nil, &n); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// GetGruposRelacionados returns up to limit groups similar to a group (0 uses the server default).
+func (c *Client) GetGruposRelacionados(ctx context.Context, id, limit int) ([]models.GrupoRelacionado, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var relacionados []models.GrupoRelacionado
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/grupos/%d/relacionados", id), query, nil, &relacionados); err != nil {
+		return nil, err
+	}
+	return relacionados, nil
+}
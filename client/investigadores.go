@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// ListInvestigadores returns a page of investigators, optionally filtered by name.
+func (c *Client) ListInvestigadores(ctx context.Context, name string, opts PageOptions) (*Page[models.Investigador], error) {
+	q := url.Values{}
+	if name != "" {
+		q.Set("name", name)
+	}
+	opts.apply(q)
+	var p Page[models.Investigador]
+	if err := c.do(ctx, http.MethodGet, "/investigadores", q, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// InvestigadoresIter iterates over every investigator matching name.
+func (c *Client) InvestigadoresIter(ctx context.Context, name string) iter.Seq2[models.Investigador, error] {
+	return iteratePages(ctx, 0, func(ctx context.Context, opts PageOptions) (*Page[models.Investigador], error) {
+		return c.ListInvestigadores(ctx, name, opts)
+	})
+}
+
+// ListInvestigadoresConResumen is ListInvestigadores with ?include=grupos (group and coordinator counts).
+func (c *Client) ListInvestigadoresConResumen(ctx context.Context, name string, opts PageOptions) (*Page[models.InvestigadorConResumen], error) {
+	q := url.Values{"include": {"grupos"}}
+	if name != "" {
+		q.Set("name", name)
+	}
+	opts.apply(q)
+	var p Page[models.InvestigadorConResumen]
+	if err := c.do(ctx, http.MethodGet, "/investigadores", q, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetInvestigadoresByIDs returns the given investigators in one request (up to 100 IDs).
+func (c *Client) GetInvestigadoresByIDs(ctx context.Context, ids []int) (*Lote[models.Investigador], error) {
+	var l Lote[models.Investigador]
+	if err := c.do(ctx, http.MethodGet, "/investigadores", idsQuery(ids), nil, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// ListAllInvestigadores returns every investigator in one call (GET /investigadores/all).
+func (c *Client) ListAllInvestigadores(ctx context.Context) ([]models.Investigador, error) {
+	var resp struct {
+		Data []models.Investigador `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/investigadores/all", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// GetInvestigador returns an investigator by ID.
+func (c *Client) GetInvestigador(ctx context.Context, id int) (*models.Investigador, error) {
+	var inv models.Investigador
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/investigadores/%d", id), nil, nil, &inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// CreateInvestigador creates an investigator.
+func (c *Client) CreateInvestigador(ctx context.Context, inv models.Investigador) (*models.Investigador, error) {
+	var out models.Investigador
+	if err := c.do(ctx, http.MethodPost, "/investigadores", nil, inv, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateInvestigador updates an investigator.
+func (c *Client) UpdateInvestigador(ctx context.Context, id int, inv models.Investigador) (*models.Investigador, error) {
+	var out models.Investigador
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/investigadores/%d", id), nil, inv, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RequestEmailVerification (re)sends the confirmation link for an investigator's email and returns
+// when it expires.
+func (c *Client) RequestEmailVerification(ctx context.Context, id int) (time.Time, error) {
+	var out struct {
+		ExpiraEn time.Time `json:"expiraEn"`
+	}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/investigadores/%d/verificar-email", id), nil, nil, &out); err != nil {
+		return time.Time{}, err
+	}
+	return out.ExpiraEn, nil
+}
+
+// DeleteInvestigador soft-deletes an investigator. If they still belong to groups and force is
+// false the API answers 409 listing the memberships; with force they are removed as well.
+func (c *Client) DeleteInvestigador(ctx context.Context, id int, force bool) error {
+	var q url.Values
+	if force {
+		q = url.Values{"force": {"true"}}
+	}
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/investigadores/%d", id), q, nil, nil)
+}
+
+// RestoreInvestigador restores a soft-deleted investigator.
+func (c *Client) RestoreInvestigador(ctx context.Context, id int) (*models.Investigador, error) {
+	var inv models.Investigador
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/investigadores/%d/restore", id), nil, nil, &inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// ExportInvestigadores downloads the investigators with their groups and roles as "csv" or "xlsx".
+// With agrupar there is one row per investigator instead of one per membership; name filters like
+// the listing. The caller must close the returned reader.
+func (c *Client) ExportInvestigadores(ctx context.Context, format, name string, agrupar bool) (io.ReadCloser, error) {
+	q := url.Values{"format": {format}}
+	if name != "" {
+		q.Set("name", name)
+	}
+	if agrupar {
+		q.Set("agrupar", "true")
+	}
+	body, _, err := c.download(ctx, "/investigadores/export", q)
+	return body, err
+}
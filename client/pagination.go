@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// Page is one page of a paginated listing.
+type Page[T any] struct {
+	Data       []T                       `json:"data"`
+	Pagination models.PaginationMetadata `json:"pagination"`
+}
+
+// HasNext reports whether there are more pages after this one.
+func (p *Page[T]) HasNext() bool {
+	return p.Pagination.CurrentPage < p.Pagination.TotalPages
+}
+
+// Lote is the response of a batched GET by IDs: the records found, in the requested order, and
+// the IDs that do not exist.
+type Lote[T any] struct {
+	Data          []T   `json:"data"`
+	NoEncontrados []int `json:"noEncontrados"`
+}
+
+// idsQuery builds ?ids=1,5,9 (at most 100 IDs).
+func idsQuery(ids []int) url.Values {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(id)
+	}
+	return url.Values{"ids": {strings.Join(s, ",")}}
+}
+
+// PageOptions selects a page. Zero values use the server defaults (page 1, 6 items).
+type PageOptions struct {
+	Page  int
+	Limit int // Max 100
+}
+
+func (o PageOptions) apply(q url.Values) {
+	if o.Page > 0 {
+		q.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+}
+
+// iteratePages walks every page returned by fetch, starting at page 1 with the given limit
+// (100 if zero), yielding each item. Iteration stops at the first error, which is yielded.
+func iteratePages[T any](ctx context.Context, limit int, fetch func(context.Context, PageOptions) (*Page[T], error)) iter.Seq2[T, error] {
+	if limit <= 0 {
+		limit = 100
+	}
+	return func(yield func(T, error) bool) {
+		for page := 1; ; page++ {
+			p, err := fetch(ctx, PageOptions{Page: page, Limit: limit})
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range p.Data {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if !p.HasNext() || len(p.Data) == 0 {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// DocumentoPostulacionInput is a document to attach to a postulacion.
+type DocumentoPostulacionInput struct {
+	Nombre        string // Defaults to Requisito
+	Requisito     string // One of the convocatoria's DocumentosRequeridos, or "" for an extra document
+	Archivo       io.Reader
+	NombreArchivo string
+}
+
+// CreatePostulacion submits a group's postulacion to an open convocatoria.
+func (c *Client) CreatePostulacion(ctx context.Context, idConvocatoria, idGrupo int) (*models.Postulacion, error) {
+	var out models.Postulacion
+	body := models.CrearPostulacionRequest{IDGrupo: idGrupo}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/convocatorias/%d/postulaciones", idConvocatoria), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPostulaciones returns a convocatoria's postulaciones, optionally filtered by estado.
+func (c *Client) ListPostulaciones(ctx context.Context, idConvocatoria int, estado string) ([]models.Postulacion, error) {
+	q := url.Values{}
+	if estado != "" {
+		q.Set("estado", estado)
+	}
+	var out []models.Postulacion
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/convocatorias/%d/postulaciones", idConvocatoria), q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetPostulacion returns a postulacion with its documents and estado history.
+func (c *Client) GetPostulacion(ctx context.Context, id int) (*models.Postulacion, error) {
+	var out models.Postulacion
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/postulaciones/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CambiarEstadoPostulacion moves a postulacion to estado (observado and aprobado require admin).
+func (c *Client) CambiarEstadoPostulacion(ctx context.Context, id int, estado, observaciones string) (*models.Postulacion, error) {
+	var out models.Postulacion
+	body := models.CambiarEstadoPostulacionRequest{Estado: estado, Observaciones: observaciones}
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/postulaciones/%d/estado", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateDocumentoPostulacion uploads a document for a postulacion.
+func (c *Client) CreateDocumentoPostulacion(ctx context.Context, id int, in DocumentoPostulacionInput) (*models.DocumentoPostulacion, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("nombre", in.Nombre)
+	mw.WriteField("requisito", in.Requisito)
+	if in.Archivo != nil {
+		if err := writeFile(mw, "archivo", in.NombreArchivo, in.Archivo); err != nil {
+			return nil, fmt.Errorf("error encoding document form: %w", err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("error encoding document form: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, fmt.Sprintf("/postulaciones/%d/documentos", id), nil, &buf, mw.FormDataContentType())
+	if err != nil {
+		return nil, err
+	}
+	var d models.DocumentoPostulacion
+	if err := c.doRequest(req, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// DeleteDocumentoPostulacion removes a document from a postulacion.
+func (c *Client) DeleteDocumentoPostulacion(ctx context.Context, id, idDocumento int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/postulaciones/%d/documentos/%d", id, idDocumento), nil, nil, nil)
+}
+
+// GetReporteConvocatoria returns the completion rates of a convocatoria's postulaciones.
+func (c *Client) GetReporteConvocatoria(ctx context.Context, idConvocatoria int) (*models.ReporteConvocatoria, error) {
+	var out models.ReporteConvocatoria
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/convocatorias/%d/reporte", idConvocatoria), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
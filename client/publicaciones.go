@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// ListPublicaciones returns a page of publicaciones matching the filters.
+func (c *Client) ListPublicaciones(ctx context.Context, f models.FiltroPublicaciones, opts PageOptions) (*Page[models.Publicacion], error) {
+	q := url.Values{}
+	if f.Q != "" {
+		q.Set("q", f.Q)
+	}
+	if f.Tipo != "" {
+		q.Set("tipo", f.Tipo)
+	}
+	if f.Anio != 0 {
+		q.Set("anio", strconv.Itoa(f.Anio))
+	}
+	if f.IDGrupo != 0 {
+		q.Set("idGrupo", strconv.Itoa(f.IDGrupo))
+	}
+	if f.IDInvestigador != 0 {
+		q.Set("idInvestigador", strconv.Itoa(f.IDInvestigador))
+	}
+	opts.apply(q)
+	var p Page[models.Publicacion]
+	if err := c.do(ctx, http.MethodGet, "/publicaciones", q, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// PublicacionesIter iterates over every publicacion matching the filters.
+func (c *Client) PublicacionesIter(ctx context.Context, f models.FiltroPublicaciones) iter.Seq2[models.Publicacion, error] {
+	return iteratePages(ctx, 0, func(ctx context.Context, opts PageOptions) (*Page[models.Publicacion], error) {
+		return c.ListPublicaciones(ctx, f, opts)
+	})
+}
+
+// GetPublicacion returns a publicacion.
+func (c *Client) GetPublicacion(ctx context.Context, id int) (*models.Publicacion, error) {
+	var out models.Publicacion
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/publicaciones/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreatePublicacion creates a publicacion linked to its investigators and groups.
+func (c *Client) CreatePublicacion(ctx context.Context, p models.Publicacion) (*models.Publicacion, error) {
+	var out models.Publicacion
+	if err := c.do(ctx, http.MethodPost, "/publicaciones", nil, p, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdatePublicacion replaces a publicacion's fields and links.
+func (c *Client) UpdatePublicacion(ctx context.Context, id int, p models.Publicacion) (*models.Publicacion, error) {
+	var out models.Publicacion
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/publicaciones/%d", id), nil, p, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeletePublicacion deletes a publicacion.
+func (c *Client) DeletePublicacion(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/publicaciones/%d", id), nil, nil, nil)
+}
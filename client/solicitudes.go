@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// CreateSolicitudGrupo submits a public group registration request.
+func (c *Client) CreateSolicitudGrupo(ctx context.Context, s models.CreateSolicitudGrupoRequest) (*models.SolicitudGrupo, error) {
+	var out models.SolicitudGrupo
+	if err := c.do(ctx, http.MethodPost, "/solicitudes-grupo", nil, s, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListSolicitudes returns a page of the moderation queue, optionally filtered by estado (admin only).
+func (c *Client) ListSolicitudes(ctx context.Context, estado string, opts PageOptions) (*Page[models.SolicitudGrupo], error) {
+	q := url.Values{}
+	if estado != "" {
+		q.Set("estado", estado)
+	}
+	opts.apply(q)
+	var p Page[models.SolicitudGrupo]
+	if err := c.do(ctx, http.MethodGet, "/admin/solicitudes", q, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SolicitudesIter iterates over the moderation queue (admin only).
+func (c *Client) SolicitudesIter(ctx context.Context, estado string) iter.Seq2[models.SolicitudGrupo, error] {
+	return iteratePages(ctx, 0, func(ctx context.Context, opts PageOptions) (*Page[models.SolicitudGrupo], error) {
+		return c.ListSolicitudes(ctx, estado, opts)
+	})
+}
+
+// GetSolicitud returns a registration request (admin only).
+func (c *Client) GetSolicitud(ctx context.Context, id int) (*models.SolicitudGrupo, error) {
+	var s models.SolicitudGrupo
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/admin/solicitudes/%d", id), nil, nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// AprobarSolicitud approves a request, creating its group (admin only).
+func (c *Client) AprobarSolicitud(ctx context.Context, id int, req models.ModerarSolicitudRequest) (*models.SolicitudGrupo, *models.Grupo, error) {
+	var resp struct {
+		Solicitud *models.SolicitudGrupo `json:"solicitud"`
+		Grupo     *models.Grupo          `json:"grupo"`
+	}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/admin/solicitudes/%d/aprobar", id), nil, req, &resp); err != nil {
+		return nil, nil, err
+	}
+	return resp.Solicitud, resp.Grupo, nil
+}
+
+// RechazarSolicitud rejects a request; comentario is required (admin only).
+func (c *Client) RechazarSolicitud(ctx context.Context, id int, comentario string) (*models.SolicitudGrupo, error) {
+	var s models.SolicitudGrupo
+	body := models.ModerarSolicitudRequest{Comentario: comentario}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/admin/solicitudes/%d/rechazar", id), nil, body, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/analytics"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/background"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/backup"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/controllers"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/grpcapi"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/jobs"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/migration"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/notifier"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/routes" // Usa gorilla/mux
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/telemetry"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/validation"
+	"github.com/rs/cors" // Importar CORS para gorilla/mux
+	"google.golang.org/grpc"
+)
+
+// runServe starts the HTTP API and drains in-flight requests on SIGTERM/SIGINT.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.Parse(args)
+
+	db, shutdownTracing, err := setup("serve")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Sin JWT_SECRET no se pueden emitir ni verificar tokens: el servidor no arranca
+	if err := middleware.CheckConfig(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// Formato de las respuestas JSON (fechas, nulos y envoltura data)
+	serializacion, err := utils.SerializationFromEnv()
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	utils.SetSerialization(serializacion)
+
+	// SIGTERM (Cloud Run, docker stop) o SIGINT (Ctrl+C) inician el apagado ordenado
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	// El trabajo en segundo plano usa la base de datos: se cancela y se espera antes de cerrar el
+	// pool (db.Close, diferido antes, corre después). El apagado ordenado lo hace más abajo; esto
+	// cubre el caso en que el servidor no llega a arrancar
+	bg := background.Context()
+	bgDetenido := false
+	defer func() {
+		if bgDetenido {
+			return
+		}
+		stopCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout())
+		defer cancel()
+		if err := background.Stop(stopCtx); err != nil {
+			slog.Error("Background work still running when closing the database", "error", err)
+		}
+	}()
+
+	// Alertas de límites blandos de la cuenta de servicio de Drive (errores, rate limit, cuota)
+	controllers.StartDriveMonitor(bg, db)
+	// Envío en segundo plano de las notificaciones (emails y webhook) de cambios en las membresías
+	notifier.Start(bg, db)
+	// Tareas programadas (limpiezas, retención de auditoría, estadísticas diarias), una vez entre todas las instancias
+	jobs.Start(bg, db)
+	// Uso de la API por cliente y ruta (GET /admin/usage), guardado cada minuto
+	analytics.Start(bg, db)
+	// Modo snapshot de los endpoints públicos (PUBLIC_SNAPSHOT=true); debe iniciarse antes de las rutas
+	controllers.StartPublicSnapshot(ctx)
+
+	// Setup routes using the routes package (gorilla/mux)
+	r := routes.SetupRoutes(db)
+
+	// --- Configuración de CORS usando rs/cors ---
+	c := cors.New(cors.Options{
+		AllowedOrigins:   []string{"http://localhost:4200"},                   // Origen permitido
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}, // Métodos permitidos
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},           // Cabeceras permitidas
+		ExposedHeaders:   []string{"X-Total-Count", "Link", "X-API-Version"},  // Legibles desde el navegador
+		AllowCredentials: true,
+		// Debug:            true, // Habilita logs de CORS si necesitas depurar
+	})
+
+	// Envolver el router 'r' con el handler CORS
+	httpHandler := telemetry.Handler(c.Handler(r))
+
+	// Determine port for HTTP service.
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "3000"
+		slog.Info("defaulting to port", "port", port)
+	}
+
+	// Start HTTP server using net/http with the CORS handler
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           httpHandler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serveErr := make(chan error, 2)
+	go func() {
+		slog.Info("listening on port", "port", port)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	// API gRPC de solo lectura para otros servicios institucionales, en un segundo puerto (GRPC_PORT)
+	var grpcServer *grpc.Server
+	if grpcPort := os.Getenv("GRPC_PORT"); grpcPort != "" {
+		lis, err := net.Listen("tcp", ":"+grpcPort)
+		if err != nil {
+			return fmt.Errorf("failed to listen on the gRPC port: %w", err)
+		}
+		grpcServer = grpcapi.NewServer(db)
+		go func() {
+			slog.Info("gRPC listening on port", "port", grpcPort)
+			serveErr <- grpcServer.Serve(lis)
+		}()
+	}
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("server failed: %w", err) // The port could not be opened
+	case <-ctx.Done():
+	}
+
+	// Deja de aceptar conexiones y espera a que terminen las peticiones en curso. Cloud Run concede
+	// 10 segundos entre SIGTERM y SIGKILL.
+	stop()
+	timeout := shutdownTimeout()
+	slog.Info("shutting down, draining in-flight requests", "timeout", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Error draining requests", "error", err)
+	}
+	if grpcServer != nil {
+		grpcapi.Shutdown(shutdownCtx, grpcServer)
+	}
+	// Cancela las exportaciones, las tareas programadas y los workers, y espera a que terminen
+	bgDetenido = true
+	if err := background.Stop(shutdownCtx); err != nil {
+		slog.Error("Error waiting for background work", "error", err)
+	}
+	if analytics.Enabled() {
+		analytics.Flush(shutdownCtx, db) // Counters of the requests since the last flush
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Error flushing traces", "error", err)
+	}
+	// db.Close (deferred) cierra el pool una vez atendidas las peticiones y terminado el trabajo en segundo plano
+	slog.Info("server stopped")
+	return nil
+}
+
+// shutdownTimeout reads SHUTDOWN_TIMEOUT (default 8s, under Cloud Run's 10 second grace period).
+func shutdownTimeout() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("SHUTDOWN_TIMEOUT")); err == nil && d > 0 {
+		return d
+	}
+	return 8 * time.Second
+}
+
+// runMigrate applies database/schema.sql.
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fs.Parse(args)
+
+	db, shutdownTracing, err := setup("migrate")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	defer shutdownTracing(context.Background())
+
+	return database.InitSchema(db)
+}
+
+// runSeed loads database/seed.sql.
+func runSeed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	fs.Parse(args)
+
+	db, shutdownTracing, err := setup("seed")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	defer shutdownTracing(context.Background())
+
+	if err := database.Seed(db); err != nil {
+		return err
+	}
+	// Un directorio en Redis compartido con los servidores no debe seguir sirviendo la versión anterior
+	controllers.DirectorioCache().Invalidate(context.Background())
+	return nil
+}
+
+// runCreateAdmin creates an administrator account, or gives the admin role to an existing user.
+// The password can be passed in ADMIN_PASSWORD to keep it out of the shell history.
+func runCreateAdmin(args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	email := fs.String("email", "", "email of the administrator")
+	password := fs.String("password", os.Getenv("ADMIN_PASSWORD"), "password for a new user (default $ADMIN_PASSWORD); ignored if the user exists")
+	fs.Parse(args)
+	if *email == "" {
+		return errors.New("--email is required")
+	}
+
+	db, shutdownTracing, err := setup("create-admin")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	defer shutdownTracing(context.Background())
+	ctx := context.Background()
+
+	existente, err := repository.GetUsuarioByEmail(ctx, db, *email)
+	if err != nil {
+		return err
+	}
+	if existente != nil {
+		if existente.Rol == models.RolAdmin {
+			slog.Info("User is already an administrator", "email", existente.Email)
+			return nil
+		}
+		if err := repository.SetUsuarioRol(ctx, db, existente.ID, models.RolAdmin); err != nil {
+			return err
+		}
+		slog.Info("User promoted to administrator", "email", existente.Email, "idUsuario", existente.ID)
+		return nil
+	}
+
+	creds := models.Credentials{Email: *email, Password: *password}
+	if errs := validation.Struct(&creds); len(errs) > 0 {
+		return fmt.Errorf("%s: %s", errs[0].Campo, errs[0].Mensaje)
+	}
+	usuario := &models.Usuario{Email: creds.Email, Password: creds.Password}
+	if err := repository.CreateUsuario(ctx, db, usuario); err != nil {
+		return err
+	}
+	if err := repository.SetUsuarioRol(ctx, db, usuario.ID, models.RolAdmin); err != nil {
+		return err
+	}
+	slog.Info("Administrator created", "email", usuario.Email, "idUsuario", usuario.ID)
+	return nil
+}
+
+// runCleanupFiles deletes the files of old exports and the expired share links.
+func runCleanupFiles(args []string) error {
+	fs := flag.NewFlagSet("cleanup-files", flag.ExitOnError)
+	olderThan := fs.Duration("older-than", 30*24*time.Hour, "delete the files of exports completed longer ago than this")
+	fs.Parse(args)
+
+	db, shutdownTracing, err := setup("cleanup-files")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	defer shutdownTracing(context.Background())
+
+	res, err := migration.CleanupFiles(context.Background(), db, time.Now().Add(-*olderThan))
+	if err != nil {
+		return err
+	}
+	slog.Info("File cleanup finished", "exports", res.Exports, "enlaces", res.Enlaces, "errores", res.Errores)
+	if res.Errores > 0 {
+		return fmt.Errorf("%d archivos no se pudieron eliminar", res.Errores)
+	}
+	return nil
+}
+
+// runMigrateFiles copies the stored files to another backend and updates the references. It can be
+// interrupted and run again: files already copied are not copied twice.
+func runMigrateFiles(args []string) error {
+	fs := flag.NewFlagSet("migrate-files", flag.ExitOnError)
+	to := fs.String("to", "", "destination storage backend (e.g. local)")
+	from := fs.String("from", storage.DriveName, "source storage backend")
+	deleteSource := fs.Bool("delete-source", false, "delete each file from the source backend once migrated")
+	fs.Parse(args)
+	if *to == "" {
+		return errors.New("--to is required")
+	}
+
+	db, shutdownTracing, err := setup("migrate-files")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	defer shutdownTracing(context.Background())
+
+	origen, err := storage.Get(*from)
+	if err != nil {
+		return fmt.Errorf("invalid --from: %w", err)
+	}
+	destino, err := storage.Get(*to)
+	if err != nil {
+		return fmt.Errorf("invalid --to: %w", err)
+	}
+	progreso, err := migration.MigrateFiles(context.Background(), db, origen, destino, migration.Options{DeleteSource: *deleteSource})
+	if err != nil {
+		return err
+	}
+	slog.Info("File migration finished", "migrados", progreso.Migrados, "omitidos", progreso.Omitidos, "errores", progreso.Errores, "total", progreso.Total)
+	if progreso.Errores > 0 {
+		return fmt.Errorf("%d archivos no se pudieron migrar", progreso.Errores)
+	}
+	return nil
+}
+
+// runMigrateUploads uploads the files left on local disk, including the archivo values saved as
+// ./uploads paths when they were served statically, to the configured storage backend and rewrites
+// their references. Like migrate-files it can be interrupted and run again.
+func runMigrateUploads(args []string) error {
+	fs := flag.NewFlagSet("migrate-uploads", flag.ExitOnError)
+	to := fs.String("to", storage.DefaultName(), "destination storage backend (default STORAGE_BACKEND)")
+	dir := fs.String("dir", "", "directory of the local files (default LOCAL_STORAGE_DIR or ./uploads)")
+	deleteSource := fs.Bool("delete-source", false, "delete each local file once uploaded")
+	fs.Parse(args)
+
+	db, shutdownTracing, err := setup("migrate-uploads")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	defer shutdownTracing(context.Background())
+
+	local, err := storage.Get(storage.LocalName)
+	if err != nil {
+		return err
+	}
+	if *dir != "" {
+		local = storage.NewLocalBackend(*dir, "")
+	}
+	destino, err := storage.Get(*to)
+	if err != nil {
+		return fmt.Errorf("invalid --to: %w", err)
+	}
+	progreso, err := migration.MigrateLegacyUploads(context.Background(), db, local, destino, migration.Options{DeleteSource: *deleteSource})
+	if err != nil {
+		return err
+	}
+	slog.Info("Local upload migration finished", "to", destino.Name(), "migrados", progreso.Migrados, "omitidos", progreso.Omitidos, "errores", progreso.Errores, "total", progreso.Total)
+	if progreso.Errores > 0 {
+		return fmt.Errorf("%d archivos no se pudieron migrar", progreso.Errores)
+	}
+	return nil
+}
+
+// runBackfillChecksums computes the checksums of older files, for duplicate detection.
+func runBackfillChecksums(args []string) error {
+	fs := flag.NewFlagSet("backfill-checksums", flag.ExitOnError)
+	fs.Parse(args)
+
+	db, shutdownTracing, err := setup("backfill-checksums")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	defer shutdownTracing(context.Background())
+
+	calculados, errores, err := migration.BackfillChecksums(context.Background(), db)
+	if err != nil {
+		return err
+	}
+	slog.Info("Checksum backfill finished", "calculados", calculados, "errores", errores)
+	if errores > 0 {
+		return fmt.Errorf("%d checksums no se pudieron calcular", errores)
+	}
+	return nil
+}
+
+// runBackup saves a backup of the database to the default storage backend.
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	descripcion := fs.String("description", "", "note stored with the backup")
+	fs.Parse(args)
+
+	db, shutdownTracing, err := setup("backup")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	defer shutdownTracing(context.Background())
+
+	backend, err := storage.Default()
+	if err != nil {
+		return err
+	}
+	b, err := backup.Create(context.Background(), db, backend, models.BackupManual, *descripcion, nil)
+	if err != nil {
+		return err
+	}
+	slog.Info("Backup created", "id", b.ID, "archivo", b.Archivo, "tamano", b.Tamano, "sha256", b.Sha256)
+	return nil
+}
+
+// runRestore replaces the content of the database with a backup, recorded (--id) or downloaded
+// (--file). Without --yes it only shows what the backup contains.
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	id := fs.Int("id", 0, "ID of a recorded backup (GET /admin/backups)")
+	file := fs.String("file", "", "backup file downloaded beforehand (e.g. into a new database)")
+	yes := fs.Bool("yes", false, "replace the data; without it the backup is only described")
+	fs.Parse(args)
+	if (*id == 0) == (*file == "") {
+		return errors.New("use either --id or --file")
+	}
+
+	db, shutdownTracing, err := setup("restore")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	defer shutdownTracing(context.Background())
+	ctx := context.Background()
+
+	var b *models.Backup
+	var archivo *backup.Archivo
+	if *id != 0 {
+		if b, err = repository.GetBackup(ctx, db, *id); err != nil {
+			return err
+		}
+		if b == nil {
+			return fmt.Errorf("backup %d not found", *id)
+		}
+		slog.Info("Backup to restore", "id", b.ID, "archivo", b.NombreArchivo, "createdAt", b.CreatedAt, "filas", b.Filas)
+	} else {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		archivo, err = backup.Read(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		slog.Info("Backup to restore", "archivo", *file, "creado", archivo.Creado, "version", archivo.Version, "filas", archivo.Filas())
+	}
+	if !*yes {
+		return errors.New("nothing was changed: run again with --yes to replace the data with this backup")
+	}
+
+	var res *models.ResultadoRestauracion
+	if b != nil {
+		res, err = backup.Restore(ctx, db, b, nil)
+	} else {
+		res, err = backup.RestoreArchivo(ctx, db, archivo, filepath.Base(*file))
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("Backup restored", "filas", res.Filas, "previo_backup", res.PrevioBackup.ID, "previo_archivo", res.PrevioBackup.Archivo)
+	return nil
+}
+
+// runRoutes prints the authorization matrix; it needs no configuration or database.
+func runRoutes(args []string) error {
+	fs := flag.NewFlagSet("routes", flag.ExitOnError)
+	fs.Parse(args)
+
+	for _, route := range routes.Routes(nil) {
+		fmt.Printf("%-7s %-55s %s\n", route.Method, route.Path, route.Access)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/alerts"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/background"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// driveMonitor watches the Drive service account's error rate and quota; nil until StartDriveMonitor.
+var driveMonitor *alerts.DriveMonitor
+
+// StartDriveMonitor starts checking the Drive backend against the soft limits configured in the
+// environment (see alerts.DriveThresholdsFromEnv) until ctx is done. Alert emails are queued in db.
+func StartDriveMonitor(ctx context.Context, db *sql.DB) {
+	b, err := storage.Get(storage.DriveName)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Drive monitor not started", "error", err)
+		return
+	}
+	backend, ok := b.(*storage.DriveBackend)
+	if !ok {
+		logging.FromContext(ctx).Warn("Drive monitor not started: unexpected backend type", "type", fmt.Sprintf("%T", b))
+		return
+	}
+	thresholds := alerts.DriveThresholdsFromEnv()
+	driveMonitor = alerts.NewDriveMonitor(db, backend, thresholds)
+	background.Go(func() { driveMonitor.Run(ctx) })
+	logging.FromContext(ctx).Info("Drive monitor started", "interval", thresholds.Interval)
+}
+
+// GetDriveStatusHandler returns the Drive API counters, storage quota, alert thresholds and recent
+// alerts (admin only).
+func GetDriveStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if driveMonitor == nil {
+		utils.RespondError(w, "El monitor de Google Drive no está activo", http.StatusServiceUnavailable)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, driveMonitor.Status(r.Context()))
+}
@@ -0,0 +1,416 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/validation"
+	"github.com/gorilla/mux"
+)
+
+const (
+	horasEnlacePorDefecto = 24 // El máximo (30 días) está en CrearEnlaceCompartidoRequest
+)
+
+// hashToken returns the hex SHA-256 of a share token; only the hash is stored in the database.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// grupoVisible reports whether the caller of ctx may see g: groups whose registration is pendiente
+// or rechazado are only shown to authenticated users.
+func grupoVisible(ctx context.Context, g *models.Grupo) bool {
+	return models.EsGrupoAprobado(g.Estado) || verNoAprobados(ctx)
+}
+
+// verNoAprobados reports whether the caller may see pending and rejected groups, and what belongs
+// to them (memberships, files): any authenticated user can.
+func verNoAprobados(ctx context.Context) bool {
+	_, autenticado := middleware.UserIDFromContext(ctx)
+	return autenticado
+}
+
+// grupoActivoOr404 loads a non-deleted group visible to the caller (see grupoVisible), writing
+// 404/500 and returning false if it can't.
+func grupoActivoOr404(ctx context.Context, w http.ResponseWriter, db *sql.DB, id int) bool {
+	grupo, err := repository.GetGrupoByID(ctx, db, id)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error getting grupo", "id_grupo", id, "error", err)
+		utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if grupo == nil || !grupoVisible(ctx, grupo) {
+		utils.RespondError(w, "Grupo not found", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// GetGrupoArchivosHandler lists a group's attachments. Anonymous callers only see public ones;
+// non-public attachments are listed for authenticated users, without their Drive link.
+func GetGrupoArchivosHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid grupo ID", http.StatusBadRequest)
+			return
+		}
+		if !grupoActivoOr404(r.Context(), w, db, id) {
+			return
+		}
+
+		_, autenticado := middleware.UserIDFromContext(r.Context())
+		archivos, err := repository.GetGrupoArchivos(r.Context(), db, id, !autenticado)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting attachments for grupo", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		for i := range archivos {
+			if !archivos[i].Publico {
+				archivos[i].Archivo = nil // Solo accesible mediante enlace compartido
+			}
+		}
+
+		utils.RespondJSON(w, http.StatusOK, archivos)
+	}
+}
+
+// CreateGrupoArchivoHandler uploads an attachment for a group.
+// Expects multipart/form-data with "archivo", "nombre", "tipo" and "publico" (true/false, default false).
+func CreateGrupoArchivoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid grupo ID", http.StatusBadRequest)
+			return
+		}
+		if !grupoActivoOr404(r.Context(), w, db, id) {
+			return
+		}
+
+		fileID, err := saveUploadedFile(db, r, "archivo")
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error subiendo adjunto para grupo", "id", id, "error", err)
+			if limite, ok := utils.BodyTooLarge(err); ok {
+				utils.RespondBodyTooLarge(w, limite)
+			} else if errors.Is(err, errTipoArchivo) {
+				respondTipoArchivoInvalido(w)
+			} else if strings.Contains(err.Error(), "parsing multipart form") {
+				utils.RespondError(w, fmt.Sprintf("Error procesando formulario: %v", err), http.StatusBadRequest)
+			} else {
+				utils.RespondError(w, "Error interno del servidor al guardar el archivo", http.StatusInternalServerError)
+			}
+			return
+		}
+		if fileID == nil {
+			utils.RespondError(w, "Falta el campo de archivo requerido: archivo", http.StatusBadRequest)
+			return
+		}
+
+		a := models.GrupoArchivo{
+			IDGrupo: id,
+			Nombre:  r.FormValue("nombre"),
+			Tipo:    r.FormValue("tipo"),
+			Archivo: fileID,
+			Publico: r.FormValue("publico") == "true",
+		}
+		if a.Tipo == "" {
+			a.Tipo = models.TipoArchivoOtro
+		}
+		if !validar(w, &a) {
+			_ = removeFile(r.Context(), fileID)
+			return
+		}
+		if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+			a.SubidoPor = &userID
+		}
+
+		if err := repository.CreateGrupoArchivo(r.Context(), db, &a); err != nil {
+			_ = removeFile(r.Context(), fileID)
+			respondRepoError(w, r, err, "Error creating attachment for grupo", "id", id)
+			return
+		}
+
+		if !a.Publico {
+			a.Archivo = nil
+		}
+		utils.RespondJSON(w, http.StatusCreated, a)
+	}
+}
+
+// CreateEnlaceCompartidoHandler creates a temporary, tokenized link to a non-public attachment so it
+// can be shared with external evaluators. The token is only returned in this response.
+func CreateEnlaceCompartidoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid grupo ID", http.StatusBadRequest)
+			return
+		}
+		fid, err := strconv.Atoi(vars["fid"])
+		if err != nil {
+			utils.RespondError(w, "Invalid archivo ID", http.StatusBadRequest)
+			return
+		}
+
+		req := models.CrearEnlaceCompartidoRequest{Horas: horasEnlacePorDefecto}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				utils.RespondDecodeError(w, err, "Invalid request body")
+				return
+			}
+		}
+		if !validar(w, &req) {
+			return
+		}
+
+		if !grupoActivoOr404(r.Context(), w, db, id) {
+			return
+		}
+		archivo, err := repository.GetGrupoArchivoByID(r.Context(), db, id, fid)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting attachment of grupo", "id_archivo", fid, "id_grupo", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if archivo == nil {
+			utils.RespondError(w, "Archivo not found", http.StatusNotFound)
+			return
+		}
+		if archivo.Publico {
+			utils.RespondError(w, "El archivo ya es público; no necesita un enlace compartido", http.StatusBadRequest)
+			return
+		}
+
+		tokenBytes := make([]byte, 32)
+		if _, err := rand.Read(tokenBytes); err != nil {
+			logging.FromContext(r.Context()).Error("Error generating share token", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		token := hex.EncodeToString(tokenBytes)
+
+		var creadoPor *int
+		if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+			creadoPor = &userID
+		}
+		enlace, err := repository.CreateEnlaceCompartido(r.Context(), db, fid, hashToken(token), req.Horas, creadoPor)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error creating share link for attachment", "id_archivo", fid, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		registrarAuditoria(db, r, models.AuditCompartirArchivo, "grupo_archivo", fid,
+			fmt.Sprintf("enlace %d válido por %d horas (hasta %s)", enlace.ID, req.Horas, enlace.ExpiraEn.Format("2006-01-02 15:04")))
+
+		enlace.Token = token
+		enlace.URL = utils.BaseURL(r) + "/compartido/" + token
+		utils.RespondJSON(w, http.StatusCreated, enlace)
+	}
+}
+
+// tiposInline are the content types the file endpoints show in the browser. Any other is sent as
+// a download: a stored HTML or SVG opened from the API's origin could run scripts there.
+var tiposInline = map[string]bool{
+	"application/pdf": true,
+	"image/png":       true,
+	"image/jpeg":      true,
+}
+
+// errTipoArchivo is returned by saveUploadedFile for an upload whose content is not accepted (see
+// verificarTipoArchivo).
+var errTipoArchivo = errors.New("el contenido del archivo no está permitido o no coincide con su extensión")
+
+// verificarTipoArchivo checks the beginning of an upload named nombre, sniffed as browsers do: HTML
+// is rejected, and a name with the extension of an inline type (.pdf, .png, .jpg) must hold that
+// type, since the backends derive the type they serve from it.
+func verificarTipoArchivo(nombre string, cabecera []byte) error {
+	detectado := tipoBase(http.DetectContentType(cabecera))
+	if detectado == "text/html" {
+		return errTipoArchivo
+	}
+	if declarado := tipoBase(mime.TypeByExtension(strings.ToLower(filepath.Ext(nombre)))); tiposInline[declarado] && declarado != detectado {
+		return errTipoArchivo
+	}
+	return nil
+}
+
+// tipoBase returns a content type without its parameters, in lowercase.
+func tipoBase(contentType string) string {
+	tipo, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(tipo))
+}
+
+// cabecerasArchivo sets the headers of a stored file sent to the client: its type, inline only
+// for tiposInline, and no sniffing nor scripts for anything the browser renders.
+func cabecerasArchivo(w http.ResponseWriter, contentType, nombre string) {
+	disposicion := "attachment"
+	if tiposInline[tipoBase(contentType)] {
+		disposicion = "inline"
+	}
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposicion, nombre))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+}
+
+// respondTipoArchivoInvalido answers an upload rejected with errTipoArchivo.
+func respondTipoArchivoInvalido(w http.ResponseWriter) {
+	utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity, validation.TipoArchivoInvalido("archivo"))
+}
+
+// openStoredFile opens a file from its storage backend, writing 503/410/502 and returning false
+// if it can't. The caller must close the returned object's Body.
+func openStoredFile(w http.ResponseWriter, r *http.Request, ref string) (*storage.Object, bool) {
+	backend, key, err := storage.Resolve(ref)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error resolviendo archivo", "ref", ref, "error", err)
+		utils.RespondError(w, "El almacenamiento del archivo no está disponible", http.StatusServiceUnavailable)
+		return nil, false
+	}
+	obj, err := backend.Open(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			utils.RespondError(w, "El archivo ya no existe", http.StatusGone)
+			return nil, false
+		}
+		logging.FromContext(r.Context()).Error("Error abriendo archivo", "ref", ref, "error", err)
+		utils.RespondError(w, "No se pudo obtener el archivo", http.StatusBadGateway)
+		return nil, false
+	}
+	return obj, true
+}
+
+// GetArchivoCompartidoHandler serves a non-public attachment through a valid share token.
+// The file is streamed from its storage backend, so it never needs to be made public.
+func GetArchivoCompartidoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		archivo, enlace, err := repository.GetArchivoByEnlaceCompartido(r.Context(), db, hashToken(mux.Vars(r)["token"]))
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error resolving share link", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if archivo == nil || archivo.Archivo == nil {
+			utils.RespondError(w, "Enlace inválido o expirado", http.StatusNotFound)
+			return
+		}
+		obj, ok := openStoredFile(w, r, *archivo.Archivo)
+		if !ok {
+			return
+		}
+		defer obj.Body.Close()
+
+		registrarAuditoria(db, r, models.AuditAccesoCompartido, "grupo_archivo", archivo.ID, fmt.Sprintf("enlace %d", enlace.ID))
+
+		cabecerasArchivo(w, obj.ContentType, archivo.Nombre)
+		w.Header().Set("Cache-Control", "private, no-store")
+		if _, err := io.Copy(w, obj.Body); err != nil {
+			logging.FromContext(r.Context()).Error("Error enviando archivo compartido", "id_archivo", archivo.ID, "error", err)
+		}
+	}
+}
+
+// maxAgeArchivoPublico is how long clients and shared caches may keep a public file. The content of
+// a ref never changes (every upload gets a new key), but the file can stop being public.
+const maxAgeArchivoPublico = 3600 // Seconds
+
+// GetFileHandler serves a stored file by its ref (GET /files/{id}) from whatever backend holds
+// it; the links of local files in the responses point here. Files of active groups, their
+// resolutions and public attachments are public; project files and postulacion documents need a
+// token; anything else referenced (non-public attachments, deleted groups, exports) is for admins.
+// Unreferenced refs are not served. Since a ref's content never changes, its ETag is derived from
+// the ref and revalidations are answered with 304 after the access check.
+func GetFileHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ref := mux.Vars(r)["id"]
+		acceso, err := repository.GetAccesoArchivo(r.Context(), db, ref)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting file access level", "ref", ref, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		_, autenticado := middleware.UserIDFromContext(r.Context())
+		switch {
+		case acceso == "":
+			utils.RespondError(w, "Archivo not found", http.StatusNotFound)
+			return
+		case acceso != models.AccesoArchivoPublico && !autenticado:
+			utils.RespondError(w, "Authorization header required", http.StatusUnauthorized)
+			return
+		case acceso == models.AccesoArchivoAdmin && !middleware.IsAdmin(r):
+			utils.RespondError(w, "Archivo not found", http.StatusNotFound)
+			return
+		}
+
+		if acceso == models.AccesoArchivoPublico {
+			w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAgeArchivoPublico))
+		} else {
+			w.Header().Set("Cache-Control", "private, no-cache")
+		}
+		etag := `"` + hashToken(ref)[:32] + `"`
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		obj, ok := openStoredFile(w, r, ref)
+		if !ok {
+			return
+		}
+		defer obj.Body.Close()
+
+		cabecerasArchivo(w, obj.ContentType, obj.Name)
+		if _, err := io.Copy(w, obj.Body); err != nil {
+			logging.FromContext(r.Context()).Error("Error enviando archivo", "ref", ref, "error", err)
+		}
+	}
+}
+
+// GetArchivosDuplicadosHandler lists documents with identical content attached to more than one group
+// (admin only), e.g. a resolution copy-pasted to the wrong group. With ?mismoGrupo=true it also
+// reports files repeated within a single group.
+func GetArchivosDuplicadosHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, limit := utils.GetPaginationParams(r)
+		offset := (page - 1) * limit
+
+		duplicados, totalItems, err := repository.GetArchivosDuplicados(r.Context(), db, r.URL.Query().Get("mismoGrupo") == "true", limit, offset)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting duplicate files", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		response := models.PaginatedResponse{
+			Data:       duplicados,
+			Pagination: paginacion(w, r, totalItems, page, limit),
+		}
+
+		utils.RespondJSON(w, http.StatusOK, response)
+	}
+}
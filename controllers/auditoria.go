@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// registrarAuditoria appends an entry to the audit trail for the current request.
+// Failures are logged but never interrupt the request.
+func registrarAuditoria(db *sql.DB, r *http.Request, accion, entidad string, idEntidad int, detalle string) {
+	entrada := models.AuditLog{
+		Accion:    accion,
+		Entidad:   entidad,
+		IDEntidad: &idEntidad,
+		Detalle:   detalle,
+		IP:        utils.ClientIP(r),
+	}
+	if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+		entrada.IDUsuario = &userID
+	}
+	// The action already happened: record it even if the client has gone away
+	if err := repository.CreateAuditLog(context.WithoutCancel(r.Context()), db, &entrada); err != nil {
+		logging.FromContext(r.Context()).Error("Error registrando auditoría", "accion", accion, "entidad", entidad, "id_entidad", idEntidad, "error", err)
+	}
+}
+
+// GetAuditLogsHandler lists audit entries (admin only), newest first, optionally filtered by ?entidad.
+func GetAuditLogsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, limit := utils.GetPaginationParams(r)
+		offset := (page - 1) * limit
+
+		entradas, totalItems, err := repository.GetAuditLogs(r.Context(), db, r.URL.Query().Get("entidad"), limit, offset)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting audit log", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		response := models.PaginatedResponse{
+			Data:       entradas,
+			Pagination: paginacion(w, r, totalItems, page, limit),
+		}
+
+		utils.RespondJSON(w, http.StatusOK, response)
+	}
+}
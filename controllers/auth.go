@@ -3,42 +3,50 @@ package controllers
 import (
 	"database/sql"
 	"encoding/json"
-	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/notifier"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
 	"github.com/golang-jwt/jwt/v5"
+	"log/slog"
 )
 
+// authClaims are the JWT claims issued on login.
+type authClaims struct {
+	Rol string `json:"rol"`
+	jwt.RegisteredClaims
+}
+
 // RegisterHandler handles user registration.
 func RegisterHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var creds models.Credentials // Use Credentials struct for input
 		if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			utils.RespondDecodeError(w, err, "Invalid request body")
 			return
 		}
 
-		// Basic validation
-		if creds.Email == "" || creds.Password == "" {
-			http.Error(w, "Email and password are required", http.StatusBadRequest)
+		if !validar(w, &creds) {
 			return
 		}
-		// Add more validation if needed (e.g., password complexity, email format)
 
 		// Check if user already exists
-		existingUser, err := repository.GetUsuarioByEmail(db, creds.Email)
+		existingUser, err := repository.GetUsuarioByEmail(r.Context(), db, creds.Email)
 		if err != nil {
-			log.Printf("Error checking for existing user: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Error("Error checking for existing user", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 		if existingUser != nil {
-			http.Error(w, "User with this email already exists", http.StatusConflict) // 409 Conflict
+			utils.RespondError(w, "User with this email already exists", http.StatusConflict) // 409 Conflict
 			return
 		}
 
@@ -49,66 +57,68 @@ func RegisterHandler(db *sql.DB) http.HandlerFunc {
 		}
 
 		// Create user in repository (handles hashing)
-		if err := repository.CreateUsuario(db, user); err != nil {
-			log.Printf("Error creating user: %v", err)
-			http.Error(w, "Failed to register user", http.StatusInternalServerError)
+		if err := repository.CreateUsuario(r.Context(), db, user); err != nil {
+			logging.FromContext(r.Context()).Error("Error creating user", "error", err)
+			utils.RespondError(w, "Failed to register user", http.StatusInternalServerError)
 			return
 		}
 
 		// Respond with created user (password hash is excluded by JSON tag in model)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(user)
+		utils.RespondJSON(w, http.StatusCreated, user)
 	}
 }
 
 // LoginHandler handles user login and JWT generation.
 func LoginHandler(db *sql.DB) http.HandlerFunc {
 	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Fatal("FATAL: JWT_SECRET environment variable not set for login handler.")
-	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
+		if jwtSecret == "" {
+			// main refuses to serve without it; this only happens in partial setups such as tests
+			utils.RespondError(w, "Authentication is not configured", http.StatusServiceUnavailable)
+			return
+		}
 		var creds models.Credentials
 		if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			utils.RespondDecodeError(w, err, "Invalid request body")
 			return
 		}
 
-		if creds.Email == "" || creds.Password == "" {
-			http.Error(w, "Email and password are required", http.StatusBadRequest)
+		if !validar(w, &creds) {
 			return
 		}
 
 		// Get user by email
-		user, err := repository.GetUsuarioByEmail(db, creds.Email)
+		user, err := repository.GetUsuarioByEmail(r.Context(), db, creds.Email)
 		if err != nil {
-			log.Printf("Error fetching user for login: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Error("Error fetching user for login", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 		if user == nil {
 			// User not found
-			http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+			utils.RespondError(w, "Invalid email or password", http.StatusUnauthorized)
 			return
 		}
 
 		// Compare the provided password with the stored hash
 		if !repository.CheckPasswordHash(creds.Password, user.Password) {
 			// Password doesn't match
-			http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+			utils.RespondError(w, "Invalid email or password", http.StatusUnauthorized)
 			return
 		}
 
 		// --- Generate JWT Token ---
 		// Set token claims
 		expirationTime := time.Now().Add(24 * time.Hour) // Token valid for 24 hours
-		claims := &jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Subject:   strconv.Itoa(user.ID), // Use user ID as subject
-			// Issuer:    "your-app-name", // Optional: Add issuer
+		claims := &authClaims{
+			Rol: user.Rol, // Application role, checked by the admin middleware
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(expirationTime),
+				IssuedAt:  jwt.NewNumericDate(time.Now()),
+				Subject:   strconv.Itoa(user.ID), // Use user ID as subject
+				// Issuer:    "your-app-name", // Optional: Add issuer
+			},
 		}
 
 		// Create token with claims
@@ -117,15 +127,130 @@ func LoginHandler(db *sql.DB) http.HandlerFunc {
 		// Generate encoded token and send it as response.
 		tokenString, err := token.SignedString([]byte(jwtSecret))
 		if err != nil {
-			log.Printf("Error signing token: %v", err)
-			http.Error(w, "Internal server error generating token", http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Error("Error signing token", "error", err)
+			utils.RespondError(w, "Internal server error generating token", http.StatusInternalServerError)
 			return
 		}
 
 		// --- Respond with the token ---
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{
+		utils.RespondJSON(w, http.StatusOK, map[string]string{
 			"token": tokenString,
 		})
 	}
 }
+
+// horasRestablecimientoPassword is how long a password reset link is valid.
+const horasRestablecimientoPassword = 1
+
+// baseRestablecimientoPassword is the page the reset link points to: PASSWORD_RESET_URL, the page of
+// the frontend that asks for the new password and sends it to POST /password/reset, or this API's
+// /password/reset under PUBLIC_BASE_URL. It is "" if neither is set; the link is never derived from
+// the request (see utils.PublicBaseURL).
+func baseRestablecimientoPassword() string {
+	if base := os.Getenv("PASSWORD_RESET_URL"); base != "" {
+		return base
+	}
+	if base := utils.PublicBaseURL(); base != "" {
+		return base + "/password/reset"
+	}
+	return ""
+}
+
+// LogEnlacesConfig warns at startup when emailed links (password reset, email verification) cannot
+// be built because neither PUBLIC_BASE_URL nor PASSWORD_RESET_URL is set.
+func LogEnlacesConfig() {
+	switch {
+	case utils.PublicBaseURL() == "" && os.Getenv("PASSWORD_RESET_URL") == "":
+		slog.Warn("PUBLIC_BASE_URL not set; password reset and email verification will be answered with 503")
+	case utils.PublicBaseURL() == "":
+		slog.Warn("PUBLIC_BASE_URL not set; email verification will be answered with 503")
+	}
+}
+
+// enlaceRestablecimientoPassword is the link of the reset email, with the token in ?token=.
+func enlaceRestablecimientoPassword(base, token string) string {
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + "token=" + url.QueryEscape(token)
+}
+
+// SolicitarRestablecimientoPasswordHandler emails a password reset link to the user with the given
+// email ({"email": "..."}). It answers 202 whether or not there is such a user, so that it cannot be
+// used to find out which emails have an account, and 503 if there is no public URL to link to.
+func SolicitarRestablecimientoPasswordHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		base := baseRestablecimientoPassword()
+		if base == "" {
+			utils.RespondError(w, "Emailed links are not configured", http.StatusServiceUnavailable)
+			return
+		}
+		var req models.SolicitudRestablecimientoPassword
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body")
+			return
+		}
+		if !validar(w, &req) {
+			return
+		}
+		user, err := repository.GetUsuarioByEmail(r.Context(), db, req.Email)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error fetching user for password reset", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if user != nil {
+			if err := enviarRestablecimientoPassword(r, db, base, user); err != nil {
+				logging.FromContext(r.Context()).Error("Error sending password reset", "id_usuario", user.ID, "error", err)
+				utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+		utils.RespondJSON(w, http.StatusAccepted, map[string]string{
+			"mensaje": "Si el email corresponde a una cuenta, recibirá un enlace para restablecer la contraseña",
+		})
+	}
+}
+
+// enviarRestablecimientoPassword creates a reset link to base for the user and queues the email with it.
+func enviarRestablecimientoPassword(r *http.Request, db *sql.DB, base string, user *models.Usuario) error {
+	token, err := nuevoToken()
+	if err != nil {
+		return err
+	}
+	if _, err := repository.CreateRestablecimientoPassword(r.Context(), db, user.ID, hashToken(token), horasRestablecimientoPassword); err != nil {
+		return err
+	}
+	return notifier.EnqueueEmail(r.Context(), db, models.EventoRestablecerPassword, []string{user.Email}, notifier.RestablecerPassword{
+		Email:  user.Email,
+		Enlace: enlaceRestablecimientoPassword(base, token),
+		Horas:  horasRestablecimientoPassword,
+	})
+}
+
+// RestablecerPasswordHandler sets a new password with the token of a reset link
+// ({"token": "...", "password": "..."}). The link can only be used once.
+func RestablecerPasswordHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.RestablecimientoPassword
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body")
+			return
+		}
+		if !validar(w, &req) {
+			return
+		}
+		restablecida, err := repository.RestablecerPassword(r.Context(), db, hashToken(req.Token), req.Password)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error resetting password", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !restablecida {
+			utils.RespondError(w, "Enlace para restablecer la contraseña inválido o expirado", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
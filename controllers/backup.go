@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/backup"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/gorilla/mux"
+)
+
+// CreateBackupHandler saves a backup of the database to the storage backend (POST /admin/backup,
+// admin only) and responds 201 with it. Body (optional): {"descripcion": "..."}.
+func CreateBackupHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body models.CreateBackupRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+			utils.RespondDecodeError(w, err, "Invalid request body")
+			return
+		}
+		if !validar(w, &body) {
+			return
+		}
+
+		backend, err := storage.Default()
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting storage backend for backup", "error", err)
+			utils.RespondError(w, "El almacenamiento de archivos no está disponible", http.StatusServiceUnavailable)
+			return
+		}
+		var creadoPor *int
+		if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+			creadoPor = &userID
+		}
+		// The backup is completed even if the client goes away
+		b, err := backup.Create(context.WithoutCancel(r.Context()), db, backend, models.BackupManual, body.Descripcion, creadoPor)
+		if errors.Is(err, backup.ErrEnCurso) {
+			utils.RespondError(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error creating backup", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		logging.FromContext(r.Context()).Info("Backup created", "id", b.ID, "archivo", b.Archivo, "tamano", b.Tamano)
+
+		w.Header().Set("Location", fmt.Sprintf("/admin/backups/%d/download", b.ID))
+		utils.RespondJSON(w, http.StatusCreated, b)
+	}
+}
+
+// GetBackupsHandler lists the backups, newest first (admin only).
+func GetBackupsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		backups, err := repository.GetBackups(r.Context(), db)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting backups", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, backups)
+	}
+}
+
+// backupOr404 loads the backup of the {id} route variable, writing 400/404/500 and returning nil if
+// it can't.
+func backupOr404(w http.ResponseWriter, r *http.Request, db *sql.DB) *models.Backup {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, "Invalid backup ID", http.StatusBadRequest)
+		return nil
+	}
+	b, err := repository.GetBackup(r.Context(), db, id)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error getting backup", "id", id, "error", err)
+		utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+		return nil
+	}
+	if b == nil {
+		utils.RespondError(w, "Backup not found", http.StatusNotFound)
+		return nil
+	}
+	return b
+}
+
+// DownloadBackupHandler streams the file of a backup (admin only), e.g. to keep a copy outside the
+// storage backend or to restore it into a new database with the restore command.
+func DownloadBackupHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b := backupOr404(w, r, db)
+		if b == nil {
+			return
+		}
+		obj, ok := openStoredFile(w, r, b.Archivo)
+		if !ok {
+			return
+		}
+		defer obj.Body.Close()
+
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", b.NombreArchivo))
+		w.Header().Set("Cache-Control", "private, no-store")
+		if _, err := io.Copy(w, obj.Body); err != nil {
+			logging.FromContext(r.Context()).Error("Error sending backup", "id", b.ID, "error", err)
+		}
+	}
+}
+
+// RestoreBackupHandler replaces the content of the database with a backup (admin only). The body
+// must repeat the backup's file name: {"confirmacion": "backup_20250101-050000.json.gz"}. The data
+// replaced is saved first as a pre_restauracion backup, returned in previoBackup.
+func RestoreBackupHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b := backupOr404(w, r, db)
+		if b == nil {
+			return
+		}
+		var body models.RestoreBackupRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+			utils.RespondDecodeError(w, err, "Invalid request body")
+			return
+		}
+		if !validar(w, &body) {
+			return
+		}
+		if body.Confirmacion != b.NombreArchivo {
+			utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity,
+				utils.FieldError{Campo: "confirmacion", Codigo: "confirmacion_incorrecta", Mensaje: "debe ser el nombreArchivo de la copia de seguridad"})
+			return
+		}
+
+		var creadoPor *int
+		if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+			creadoPor = &userID
+		}
+		// A restore that has started is finished (or rolled back) even if the client goes away
+		res, err := backup.Restore(context.WithoutCancel(r.Context()), db, b, creadoPor)
+		switch {
+		case errors.Is(err, backup.ErrEnCurso):
+			utils.RespondError(w, err.Error(), http.StatusConflict)
+			return
+		case errors.Is(err, backup.ErrNoRestaurable):
+			utils.RespondError(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		case errors.Is(err, storage.ErrNotFound):
+			utils.RespondError(w, "El archivo de la copia de seguridad ya no existe", http.StatusGone)
+			return
+		case err != nil:
+			logging.FromContext(r.Context()).Error("Error restoring backup", "id", b.ID, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		logging.FromContext(r.Context()).Warn("Backup restored", "id", b.ID, "archivo", b.Archivo, "previo", res.PrevioBackup.ID)
+		registrarAuditoria(db, r, models.AuditRestaurarBackup, "backup", b.ID,
+			fmt.Sprintf("%s restaurada; datos anteriores en la copia %d", b.NombreArchivo, res.PrevioBackup.ID))
+
+		utils.RespondJSON(w, http.StatusOK, res)
+	}
+}
+
+// DeleteBackupHandler deletes a backup and its file (admin only).
+func DeleteBackupHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b := backupOr404(w, r, db)
+		if b == nil {
+			return
+		}
+		if err := backup.Delete(r.Context(), db, b); err != nil {
+			logging.FromContext(r.Context()).Error("Error deleting backup", "id", b.ID, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
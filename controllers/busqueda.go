@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/gorilla/mux"
+)
+
+// validarBusqueda checks a saved search from a request body, writing a 422 and returning false if
+// it is not valid. At least one filter must be set.
+func validarBusqueda(w http.ResponseWriter, b *models.BusquedaGuardada) bool {
+	b.Nombre = strings.TrimSpace(b.Nombre)
+	f := &b.Filtros
+	f.Q, f.Grupo, f.Investigador = strings.TrimSpace(f.Q), strings.TrimSpace(f.Grupo), strings.TrimSpace(f.Investigador)
+	if !validar(w, b) {
+		return false
+	}
+	var errs []utils.FieldError
+	if f.Vacios() {
+		errs = append(errs, utils.FieldError{Campo: "filtros", Codigo: "obligatorio", Mensaje: "La búsqueda debe tener al menos un filtro"})
+	}
+	if f.Estado != "" && !models.EsEstadoGrupoValido(f.Estado) {
+		errs = append(errs, utils.FieldError{Campo: "filtros.estado", Codigo: "invalido", Mensaje: "Use activo, inactivo, en_renovacion, cerrado, pendiente o rechazado"})
+	}
+	if f.EstadoVigencia != "" && !models.EsEstadoVigenciaValido(f.EstadoVigencia) {
+		errs = append(errs, utils.FieldError{Campo: "filtros.estadoVigencia", Codigo: "invalido", Mensaje: "Use vigente, por_vencer, vencido o sin_vigencia"})
+	}
+	if len(errs) > 0 {
+		utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity, errs...)
+		return false
+	}
+	return true
+}
+
+// respondBusquedaError maps the repository errors of a saved search write to a response.
+func respondBusquedaError(w http.ResponseWriter, r *http.Request, err error, accion string) {
+	if errors.Is(err, repository.ErrBusquedaDuplicada) {
+		utils.RespondFieldErrors(w, "Búsqueda duplicada", http.StatusConflict, utils.FieldError{
+			Campo:   "nombre",
+			Codigo:  "busqueda_duplicada",
+			Mensaje: "Ya tiene una búsqueda guardada con ese nombre",
+		})
+		return
+	}
+	respondRepoError(w, r, err, "Error saving search", "accion", accion)
+}
+
+// GetBusquedasHandler lists the saved searches of the current user by nombre.
+func GetBusquedasHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			utils.RespondError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		busquedas, err := repository.GetBusquedasByUsuario(r.Context(), db, userID)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting saved searches", "id_usuario", userID, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, busquedas)
+	}
+}
+
+// CreateBusquedaHandler saves a named search of the current user.
+func CreateBusquedaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			utils.RespondError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var b models.BusquedaGuardada
+		if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body format")
+			return
+		}
+		if !validarBusqueda(w, &b) {
+			return
+		}
+		b.IDUsuario = userID
+		if err := repository.CreateBusqueda(r.Context(), db, &b); err != nil {
+			respondBusquedaError(w, r, err, "creating")
+			return
+		}
+		utils.RespondJSON(w, http.StatusCreated, b)
+	}
+}
+
+// UpdateBusquedaHandler replaces the nombre, filtros and alertaEmail of a saved search of the
+// current user.
+func UpdateBusquedaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid búsqueda ID", http.StatusBadRequest)
+			return
+		}
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			utils.RespondError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var b models.BusquedaGuardada
+		if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body format")
+			return
+		}
+		if !validarBusqueda(w, &b) {
+			return
+		}
+		b.ID, b.IDUsuario = id, userID
+		if err := repository.UpdateBusqueda(r.Context(), db, &b); err != nil {
+			respondBusquedaError(w, r, err, "updating")
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, b)
+	}
+}
+
+// DeleteBusquedaHandler deletes a saved search of the current user.
+func DeleteBusquedaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid búsqueda ID", http.StatusBadRequest)
+			return
+		}
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			utils.RespondError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err := repository.DeleteBusqueda(r.Context(), db, userID, id); err != nil {
+			respondBusquedaError(w, r, err, "deleting")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/cache"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// directorioCache caches the directory queries (group lists and details, investigator lists); nil
+// unless CACHE_BACKEND is set and InitCache was called.
+var directorioCache *cache.Cache
+
+// pagina is a cached page of a list with its total count.
+type pagina[T any] struct {
+	Items []T `json:"items"`
+	Total int `json:"total"`
+}
+
+// InitCache enables the directory cache when CACHE_BACKEND is set (see cache.ConfigFromEnv).
+func InitCache() error {
+	cfg, enabled := cache.ConfigFromEnv()
+	if !enabled {
+		return nil
+	}
+	c, err := cache.New(cfg)
+	if err != nil {
+		return err
+	}
+	directorioCache = c
+	slog.Info("Directory cache enabled", "backend", cfg.Backend, "ttl", cfg.TTL)
+	return nil
+}
+
+// DirectorioCache returns the directory cache, or nil when caching is disabled.
+func DirectorioCache() *cache.Cache {
+	return directorioCache
+}
+
+// InvalidarCacheDirectorio discards the cached directory after every successful request handled
+// by next. The routes that modify data are wrapped with it.
+func InvalidarCacheDirectorio(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		if sw.status < http.StatusBadRequest {
+			directorioCache.Invalidate(r.Context())
+		}
+	})
+}
+
+// statusWriter remembers the status written by a handler.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// GetCacheStatusHandler returns the directory cache's configuration and hit counters (admin only).
+func GetCacheStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if directorioCache == nil {
+		utils.RespondError(w, "La caché no está activa", http.StatusServiceUnavailable)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, directorioCache.Status())
+}
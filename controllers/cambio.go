@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/jobs"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+const (
+	defaultLimitCambios = 100
+	maxLimitCambios     = 1000
+	// margenCambios holds back the newest changes: IDs are assigned when a row is written but become
+	// visible on commit, so a slower transaction may still commit a lower ID. A transaction that
+	// takes longer than this can still be missed by a cursor that already went past its ID.
+	margenCambios = 5 * time.Second
+)
+
+// posicionCambios is where a page of the feed starts: after a change (a cursor) or from a date.
+type posicionCambios struct {
+	despuesDe int64
+	desde     time.Time
+	fecha     time.Time // Of the cursor's change, or desde; zero from the start of the feed
+}
+
+// cursorCambio returns the opaque cursor that resumes the feed after the change id recorded at fecha.
+func cursorCambio(id int64, fecha time.Time) string {
+	v := url.Values{}
+	v.Set("i", strconv.FormatInt(id, 10))
+	v.Set("t", strconv.FormatInt(fecha.Unix(), 10))
+	return base64.RawURLEncoding.EncodeToString([]byte(v.Encode()))
+}
+
+// parseSince reads the since parameter of GET /changes: a cursor, an RFC 3339 timestamp or a date.
+func parseSince(since string) (posicionCambios, bool) {
+	if since == "" {
+		return posicionCambios{}, true
+	}
+	if t, ok := parseInstante(since); ok {
+		return posicionCambios{desde: t, fecha: t}, true
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(since)
+	if err != nil {
+		return posicionCambios{}, false
+	}
+	v, err := url.ParseQuery(string(raw))
+	if err != nil {
+		return posicionCambios{}, false
+	}
+	id, err := strconv.ParseInt(v.Get("i"), 10, 64)
+	if err != nil || id < 0 {
+		return posicionCambios{}, false
+	}
+	unix, err := strconv.ParseInt(v.Get("t"), 10, 64)
+	if err != nil {
+		return posicionCambios{}, false
+	}
+	return posicionCambios{despuesDe: id, fecha: time.Unix(unix, 0).UTC()}, true
+}
+
+// GetCambiosHandler returns the feed of changes to the directory entities, oldest first, for
+// clients that mirror the data incrementally (sync scripts, Zapier or Make polling triggers).
+// ?since resumes it from the nextCursor of the previous page, or starts it at a timestamp or date;
+// ?entidad limits it to some entities. A since older than CHANGES_RETENTION gets 410 Gone: changes
+// may have been purged, so the client has to download everything again. A timestamp since is
+// echoed back as nextCursor by an empty page.
+func GetCambiosHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		since := r.URL.Query().Get("since")
+		pos, ok := parseSince(since)
+		if !ok {
+			utils.RespondError(w, "Invalid since parameter", http.StatusBadRequest)
+			return
+		}
+		entidades := utils.QueryValues(r, "entidad")
+		for _, e := range entidades {
+			if _, ok := models.EntidadesCambio[e]; !ok {
+				utils.RespondError(w, "Invalid entidad parameter", http.StatusBadRequest)
+				return
+			}
+		}
+		limit := defaultLimitCambios
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				utils.RespondError(w, "Invalid limit parameter", http.StatusBadRequest)
+				return
+			}
+			limit = min(n, maxLimitCambios)
+		}
+
+		retencion, err := jobs.RetencionCambios()
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error reading the change feed retention", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if retencion > 0 && !pos.fecha.IsZero() && pos.fecha.Before(time.Now().Add(-retencion)) {
+			utils.RespondError(w, "since is older than the retention of the change feed; download the full data again", http.StatusGone)
+			return
+		}
+
+		cambios, err := repository.GetCambios(r.Context(), db, pos.despuesDe, pos.desde, entidades, limit+1)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting changes", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		feed := models.FeedCambios{Data: []models.Cambio{}, NextCursor: since}
+		hasta := time.Now().Add(-margenCambios)
+		base := utils.BaseURL(r)
+		for i, c := range cambios {
+			// The page ends at the first recent change, so none before it is skipped
+			if i == limit || c.Fecha.After(hasta) {
+				feed.HasMore = true
+				break
+			}
+			c.Cursor = cursorCambio(c.ID, c.Fecha)
+			c.URL = base + models.EntidadesCambio[c.Entidad] + strconv.Itoa(c.IDEntidad)
+			feed.Data = append(feed.Data, c)
+			feed.NextCursor = c.Cursor
+		}
+		if len(feed.Data) == 0 && pos.desde.IsZero() {
+			// Nothing new: the cursor moves to now, so a client polling an idle feed doesn't fall
+			// behind the retention
+			feed.NextCursor = cursorCambio(pos.despuesDe, hasta)
+		}
+		utils.RespondJSON(w, http.StatusOK, feed)
+	}
+}
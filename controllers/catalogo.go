@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// GetTiposInvestigacionHandler lists the catalog of research types, to fill the forms' dropdowns
+// (GET /catalogos/tipos-investigacion).
+func GetTiposInvestigacionHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tipos, err := repository.GetTiposInvestigacion(r.Context(), db)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting research types", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, tipos)
+	}
+}
+
+// validarTipoInvestigacion checks that *tipo is in the catalog of research types and rewrites it
+// as written there ("basica" becomes "Básica"); otherwise it answers 422. A value equal to actual,
+// the one already stored, is accepted as is, so that groups registered before the catalog can
+// still be edited. It reports whether *tipo is valid.
+func validarTipoInvestigacion(w http.ResponseWriter, r *http.Request, db *sql.DB, tipo *string, actual string) bool {
+	if actual != "" && *tipo == actual {
+		return true
+	}
+	nombre, err := repository.BuscarTipoInvestigacion(r.Context(), db, *tipo)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error validating research type", "tipo", *tipo, "error", err)
+		utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if nombre == "" {
+		utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity, utils.FieldError{
+			Campo:   "tipoInvestigacion",
+			Codigo:  "tipo_invalido",
+			Mensaje: "El tipo de investigación \"" + strings.TrimSpace(*tipo) + "\" no está en el catálogo (GET /catalogos/tipos-investigacion)",
+		})
+		return false
+	}
+	*tipo = nombre
+	return true
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/gorilla/mux"
+)
+
+// GetComentariosGrupoHandler lists the comments of a group, newest first, with pagination.
+func GetComentariosGrupoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid grupo ID", http.StatusBadRequest)
+			return
+		}
+		if !grupoActivoOr404(r.Context(), w, db, id) {
+			return
+		}
+		page, limit := utils.GetPaginationParams(r)
+		offset := (page - 1) * limit
+
+		comentarios, totalItems, err := repository.GetComentariosByGrupo(r.Context(), db, id, limit, offset)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting comments of grupo", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, models.PaginatedResponse{
+			Data:       comentarios,
+			Pagination: paginacion(w, r, totalItems, page, limit),
+		})
+	}
+}
+
+// CreateComentarioGrupoHandler adds a comment ({"contenido": "..."}) to a group, signed by the
+// authenticated user.
+func CreateComentarioGrupoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid grupo ID", http.StatusBadRequest)
+			return
+		}
+		var c models.GrupoComentario
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body format")
+			return
+		}
+		c.Contenido = strings.TrimSpace(c.Contenido)
+		if !validar(w, &c) {
+			return
+		}
+		if !grupoActivoOr404(r.Context(), w, db, id) {
+			return
+		}
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			utils.RespondError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		c.IDGrupo = id
+		c.IDUsuario = &userID
+
+		if err := repository.CreateComentario(r.Context(), db, &c); err != nil {
+			respondRepoError(w, r, err, "Error creating comment for grupo", "id", id)
+			return
+		}
+		utils.RespondJSON(w, http.StatusCreated, c)
+	}
+}
+
+// DeleteComentarioGrupoHandler deletes a comment of a group. Only its author or an admin can.
+func DeleteComentarioGrupoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid grupo ID", http.StatusBadRequest)
+			return
+		}
+		cid, err := strconv.Atoi(vars["cid"])
+		if err != nil {
+			utils.RespondError(w, "Invalid comentario ID", http.StatusBadRequest)
+			return
+		}
+
+		c, err := repository.GetComentario(r.Context(), db, id, cid)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting comment of grupo", "id_comentario", cid, "id_grupo", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if c == nil {
+			utils.RespondError(w, "Comentario not found", http.StatusNotFound)
+			return
+		}
+		userID, _ := middleware.UserIDFromContext(r.Context())
+		if !middleware.IsAdmin(r) && (c.IDUsuario == nil || *c.IDUsuario != userID) {
+			utils.RespondError(w, "Only the author or an admin can delete this comment", http.StatusForbidden)
+			return
+		}
+
+		existia, err := repository.DeleteComentario(r.Context(), db, id, cid)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error deleting comment of grupo", "id_comentario", cid, "id_grupo", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !existia {
+			utils.RespondError(w, "Comentario not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
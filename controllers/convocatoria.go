@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/gorilla/mux"
+)
+
+// validarConvocatoria normalizes a convocatoria from a request body and checks it, writing a 422
+// and returning false if it is not valid. An empty estado defaults to borrador;
+// documentosRequeridos is trimmed and deduplicated.
+func validarConvocatoria(w http.ResponseWriter, c *models.Convocatoria) bool {
+	c.Nombre = strings.TrimSpace(c.Nombre)
+	if c.Estado == "" {
+		c.Estado = models.ConvocatoriaBorrador
+	}
+	documentos := []string{}
+	vistos := map[string]bool{}
+	for _, d := range c.DocumentosRequeridos {
+		d = strings.TrimSpace(d)
+		if d == "" || vistos[d] {
+			continue
+		}
+		vistos[d] = true
+		documentos = append(documentos, d)
+	}
+	c.DocumentosRequeridos = documentos
+	return validar(w, c)
+}
+
+// GetConvocatoriasHandler lists convocatorias with pagination, optionally filtered by ?estado=.
+func GetConvocatoriasHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		estado := r.URL.Query().Get("estado")
+		if estado != "" && !models.EsEstadoConvocatoriaValido(estado) {
+			utils.RespondError(w, "Invalid estado parameter", http.StatusBadRequest)
+			return
+		}
+		page, limit := utils.GetPaginationParams(r)
+		offset := (page - 1) * limit
+
+		convocatorias, totalItems, err := repository.GetConvocatorias(r.Context(), db, estado, limit, offset)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting convocatorias", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		utils.RespondJSON(w, http.StatusOK, models.PaginatedResponse{
+			Data:       convocatorias,
+			Pagination: paginacion(w, r, totalItems, page, limit),
+		})
+	}
+}
+
+// GetConvocatoriaHandler fetches a single convocatoria.
+func GetConvocatoriaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid convocatoria ID", http.StatusBadRequest)
+			return
+		}
+		c, err := repository.GetConvocatoriaByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting convocatoria by ID", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if c == nil {
+			utils.RespondError(w, "Convocatoria not found", http.StatusNotFound)
+			return
+		}
+		utils.RespondJSONWithETag(w, r, c)
+	}
+}
+
+// CreateConvocatoriaHandler creates a convocatoria (admin only).
+func CreateConvocatoriaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var c models.Convocatoria
+		if !decodeJSON(w, r, &c, "Invalid request body format") {
+			return
+		}
+		if !validarConvocatoria(w, &c) {
+			return
+		}
+		if err := repository.CreateConvocatoria(r.Context(), db, &c); err != nil {
+			respondRepoError(w, r, err, "Error creating convocatoria")
+			return
+		}
+		utils.RespondJSON(w, http.StatusCreated, c)
+	}
+}
+
+// UpdateConvocatoriaHandler replaces a convocatoria's fields (admin only).
+func UpdateConvocatoriaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid convocatoria ID", http.StatusBadRequest)
+			return
+		}
+		var c models.Convocatoria
+		if !decodeJSON(w, r, &c, "Invalid request body format") {
+			return
+		}
+		c.ID = id
+		if !validarConvocatoria(w, &c) {
+			return
+		}
+		if err := repository.UpdateConvocatoria(r.Context(), db, &c); err != nil {
+			if errors.Is(err, repository.ErrConvocatoriaNoEncontrada) {
+				utils.RespondError(w, "Convocatoria not found", http.StatusNotFound)
+				return
+			}
+			respondRepoError(w, r, err, "Error updating convocatoria", "id", id)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, c)
+	}
+}
+
+// DeleteConvocatoriaHandler deletes a convocatoria and its group links (admin only).
+func DeleteConvocatoriaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid convocatoria ID", http.StatusBadRequest)
+			return
+		}
+		eliminada, err := repository.DeleteConvocatoria(r.Context(), db, id)
+		if err != nil {
+			respondRepoError(w, r, err, "Error deleting convocatoria", "id", id)
+			return
+		}
+		if !eliminada {
+			utils.RespondError(w, "Convocatoria not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetGruposConvocatoriaHandler lists the groups participating in a convocatoria.
+func GetGruposConvocatoriaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid convocatoria ID", http.StatusBadRequest)
+			return
+		}
+		c, err := repository.GetConvocatoriaByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting convocatoria by ID", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if c == nil {
+			utils.RespondError(w, "Convocatoria not found", http.StatusNotFound)
+			return
+		}
+		grupos, err := repository.GetGruposByConvocatoria(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting groups of convocatoria", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, grupos)
+	}
+}
+
+// AddGrupoConvocatoriaHandler registers a group's participation in a convocatoria.
+// Body: {"idGrupo": 3}. Responds 409 if it already participates.
+func AddGrupoConvocatoriaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid convocatoria ID", http.StatusBadRequest)
+			return
+		}
+		var req models.ParticipacionConvocatoriaRequest
+		if !decodeJSON(w, r, &req, "Invalid request body format") {
+			return
+		}
+		if !validar(w, &req) {
+			return
+		}
+		if !grupoActivoOr404(r.Context(), w, db, req.IDGrupo) {
+			return
+		}
+
+		if err := repository.AddGrupoConvocatoria(r.Context(), db, id, req.IDGrupo); err != nil {
+			switch {
+			case errors.Is(err, repository.ErrConvocatoriaNoEncontrada):
+				utils.RespondError(w, "Convocatoria not found", http.StatusNotFound)
+			case errors.Is(err, repository.ErrGrupoNoEncontrado):
+				utils.RespondError(w, "Grupo not found", http.StatusNotFound)
+			case errors.Is(err, repository.ErrGrupoYaParticipa):
+				utils.RespondError(w, err.Error(), http.StatusConflict)
+			default:
+				logging.FromContext(r.Context()).Error("Error linking group to convocatoria", "id_grupo", req.IDGrupo, "id_convocatoria", id, "error", err)
+				utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RemoveGrupoConvocatoriaHandler removes a group from a convocatoria.
+func RemoveGrupoConvocatoriaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid convocatoria ID", http.StatusBadRequest)
+			return
+		}
+		idGrupo, err := strconv.Atoi(vars["idGrupo"])
+		if err != nil {
+			utils.RespondError(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+		eliminado, err := repository.RemoveGrupoConvocatoria(r.Context(), db, id, idGrupo)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error unlinking group from convocatoria", "id_grupo", idGrupo, "id_convocatoria", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !eliminado {
+			utils.RespondError(w, "El grupo no participa en la convocatoria", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetConvocatoriasGrupoHandler lists the convocatorias a group participates in.
+func GetConvocatoriasGrupoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+		if !grupoActivoOr404(r.Context(), w, db, id) {
+			return
+		}
+		convocatorias, err := repository.GetConvocatoriasByGrupo(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting convocatorias of group", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, convocatorias)
+	}
+}
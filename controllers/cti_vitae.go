@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/ctivitae"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/gorilla/mux"
+)
+
+// vinculoCtiVitaeOr404 loads the CTI Vitae link of the investigator in the path, answering the
+// error and returning nil if the ID is invalid or there is no link.
+func vinculoCtiVitaeOr404(w http.ResponseWriter, r *http.Request, db *sql.DB) *models.CtiVitae {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, "Invalid investigator ID", http.StatusBadRequest)
+		return nil
+	}
+	v, err := repository.GetCtiVitae(r.Context(), db, id)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error getting CTI Vitae link", "id_investigador", id, "error", err)
+		utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+		return nil
+	}
+	if v == nil {
+		utils.RespondError(w, "El investigador no está vinculado a CTI Vitae", http.StatusNotFound)
+	}
+	return v
+}
+
+// GetCtiVitaeInvestigadorHandler returns the CTI Vitae link of an investigator and the state of
+// its last synchronization.
+func GetCtiVitaeInvestigadorHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if v := vinculoCtiVitaeOr404(w, r, db); v != nil {
+			utils.RespondJSON(w, http.StatusOK, v)
+		}
+	}
+}
+
+// SetCtiVitaeInvestigadorHandler links an investigator to their CTI Vitae record
+// ({"idCtiVitae": "..."}), replacing the previous link. It does not synchronize: see
+// SyncCtiVitaeInvestigadorHandler.
+func SetCtiVitaeInvestigadorHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid investigator ID", http.StatusBadRequest)
+			return
+		}
+		var v models.CtiVitae
+		if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body format")
+			return
+		}
+		v.IDInvestigador = id
+		v.IDCtiVitae = strings.TrimSpace(v.IDCtiVitae)
+		if !validar(w, &v) {
+			return
+		}
+		inv, err := repository.GetInvestigadorByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting investigator by ID", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if inv == nil {
+			utils.RespondError(w, "Investigador not found", http.StatusNotFound)
+			return
+		}
+		if err := repository.SetCtiVitae(r.Context(), db, &v); err != nil {
+			if errors.Is(err, repository.ErrCtiVitaeDuplicado) {
+				utils.RespondFieldErrors(w, "Datos duplicados", http.StatusConflict, utils.FieldError{
+					Campo:   "idCtiVitae",
+					Codigo:  "cti_vitae_duplicado",
+					Mensaje: "El registro de CTI Vitae ya está vinculado a otro investigador",
+				})
+				return
+			}
+			respondRepoError(w, r, err, "Error linking investigator to CTI Vitae", "id", id)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, v)
+	}
+}
+
+// DeleteCtiVitaeInvestigadorHandler unlinks an investigator from CTI Vitae. What was already
+// imported is kept.
+func DeleteCtiVitaeInvestigadorHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid investigator ID", http.StatusBadRequest)
+			return
+		}
+		existia, err := repository.DeleteCtiVitae(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error unlinking investigator from CTI Vitae", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !existia {
+			utils.RespondError(w, "El investigador no está vinculado a CTI Vitae", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// SyncCtiVitaeInvestigadorHandler pulls the investigator's record from CTI Vitae now and
+// reconciles it into the local data (see ctivitae.Sincronizar), answering what changed.
+func SyncCtiVitaeInvestigadorHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v := vinculoCtiVitaeOr404(w, r, db)
+		if v == nil {
+			return
+		}
+		res, err := ctivitae.Sincronizar(r.Context(), db, *v)
+		switch {
+		case err == nil:
+			utils.RespondJSON(w, http.StatusOK, res)
+		case errors.Is(err, ctivitae.ErrNoConfigurado):
+			utils.RespondError(w, "La integración con CTI Vitae no está configurada", http.StatusServiceUnavailable)
+		case errors.Is(err, ctivitae.ErrNoEncontrado):
+			utils.RespondError(w, "El investigador no existe en CTI Vitae", http.StatusUnprocessableEntity)
+		case errors.Is(err, repository.ErrInvestigadorNoExiste):
+			utils.RespondError(w, "Investigador not found", http.StatusNotFound)
+		case errors.Is(err, ctivitae.ErrConsulta):
+			logging.FromContext(r.Context()).Error("Error querying CTI Vitae", "id", v.IDInvestigador, "error", err)
+			utils.RespondError(w, "No se pudo consultar CTI Vitae", http.StatusBadGateway)
+		default:
+			respondRepoError(w, r, err, "Error synchronizing investigator with CTI Vitae", "id", v.IDInvestigador)
+		}
+	}
+}
@@ -1,35 +1,176 @@
 package controllers
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/notifier"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/validation"
 	"github.com/gorilla/mux"
 )
 
+// MembresiaDuplicadaResponse is returned with 409 when an investigator would be added twice to a
+// group; IDGrupo and IDInvestigador identify the existing membership.
+type MembresiaDuplicadaResponse struct {
+	utils.ErrorResponse
+	IDGrupo        int `json:"idGrupo"`
+	IDInvestigador int `json:"idInvestigador"`
+}
+
+// respondMembresiaError writes the 409 response of a membership write rejected by
+// repository.ErrMembresiaDuplicada or repository.ErrCoordinadorDuplicado, returning false for any
+// other error.
+func respondMembresiaError(w http.ResponseWriter, err error) bool {
+	var dup *repository.MembresiaDuplicadaError
+	switch {
+	case errors.As(err, &dup):
+		resp := MembresiaDuplicadaResponse{
+			ErrorResponse: utils.NewErrorResponse("El investigador ya es integrante del grupo", http.StatusConflict, []utils.FieldError{{
+				Campo:   "idInvestigador",
+				Codigo:  "membresia_duplicada",
+				Mensaje: dup.Error(),
+			}}),
+			IDGrupo:        dup.IDGrupo,
+			IDInvestigador: dup.IDInvestigador,
+		}
+		utils.RespondErrorEnvelope(w, http.StatusConflict, &resp)
+		return true
+	case errors.Is(err, repository.ErrCoordinadorDuplicado):
+		utils.RespondError(w, "El grupo ya tiene un coordinador; use POST /grupos/{id}/coordinador para reemplazarlo", http.StatusConflict)
+		return true
+	}
+	return false
+}
+
+// notificarMembresia queues the email and webhook notifications of a membership change; antes is
+// nil for a new membership and despues for a deleted one. Failures are only logged, since the
+// change itself was already saved.
+func notificarMembresia(db *sql.DB, r *http.Request, evento string, antes, despues *models.DetalleGrupoInvestigador) {
+	ref := despues
+	if ref == nil {
+		ref = antes
+	}
+	cambio := models.CambioMembresia{
+		Evento:         evento,
+		Fecha:          time.Now(),
+		IDGrupo:        ref.IDGrupo,
+		IDInvestigador: ref.IDInvestigador,
+		Antes:          antes,
+		Despues:        despues,
+	}
+	if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+		cambio.IDUsuario = &userID
+	}
+	if err := notifier.EnqueueCambioMembresia(context.WithoutCancel(r.Context()), db, cambio, verificacionEmailAutomatica()); err != nil {
+		logging.FromContext(r.Context()).Error("Error queueing notifications for investigator in group", "evento", evento, "id_investigador", ref.IDInvestigador, "id_grupo", ref.IDGrupo, "error", err)
+	}
+}
+
 // CreateDetalleGrupoInvestigadorHandler handles creating a new relationship between a group and an investigator.
 func CreateDetalleGrupoInvestigadorHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var detalle models.DetalleGrupoInvestigador
-		if err := json.NewDecoder(r.Body).Decode(&detalle); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		if !decodeJSON(w, r, &detalle, "Invalid request body") {
+			return
+		}
+		if !validar(w, &detalle) {
 			return
 		}
 
-		if err := repository.CreateDetalleGrupoInvestigador(db, &detalle); err != nil {
-			log.Printf("Error creating group-investigator relationship: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		if err := repository.CreateDetalleGrupoInvestigador(r.Context(), db, &detalle); err != nil {
+			if respondMembresiaError(w, err) {
+				return
+			}
+			respondRepoError(w, r, err, "Error creating group-investigator relationship")
 			return
 		}
+		notificarMembresia(db, r, models.EventoMembresiaCreada, nil, &detalle)
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(detalle)
+		utils.RespondJSON(w, http.StatusCreated, detalle)
+	}
+}
+
+// CreateDetallesBulkHandler registers many group-investigator relations at once (POST /detalles/bulk),
+// e.g. a group's whole roster. The body is an array of {idGrupo, idInvestigador, rol}; rol defaults
+// to Integrante. The relations are inserted in one transaction: if any item is invalid nothing is
+// created and the response is 422 listing every problem, with campo "[i].field" pointing at the item.
+func CreateDetallesBulkHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var detalles []models.DetalleGrupoInvestigador
+		if !decodeJSON(w, r, &detalles, "Invalid request body (expected an array of relations)") {
+			return
+		}
+		if len(detalles) == 0 || len(detalles) > models.MaxDetallesBulk {
+			utils.RespondError(w, fmt.Sprintf("Se esperan entre 1 y %d relaciones", models.MaxDetallesBulk), http.StatusBadRequest)
+			return
+		}
+
+		// Checks that need no database: required fields, repeated items and coordinators in the batch
+		var errores []models.ErrorDetalleBulk
+		type clave struct{ idGrupo, idInvestigador int }
+		vistos := map[clave]int{}
+		coordinadores := map[int]int{}
+		for i := range detalles {
+			d := &detalles[i]
+			d.ID = 0
+			d.Rol = strings.TrimSpace(d.Rol)
+			if d.Rol == "" {
+				d.Rol = models.RolIntegrante
+			}
+			if campos := validation.Struct(d); len(campos) > 0 {
+				for _, c := range campos {
+					errores = append(errores, models.ErrorDetalleBulk{Indice: i, Campo: c.Campo, Codigo: c.Codigo, Mensaje: c.Mensaje})
+				}
+				continue
+			}
+			k := clave{d.IDGrupo, d.IDInvestigador}
+			if j, ok := vistos[k]; ok {
+				errores = append(errores, models.ErrorDetalleBulk{Indice: i, Campo: "idInvestigador", Codigo: "repetido", Mensaje: fmt.Sprintf("La relación ya aparece en la posición %d", j)})
+				continue
+			}
+			vistos[k] = i
+			if models.EsCoordinador(d.Rol) {
+				if j, ok := coordinadores[d.IDGrupo]; ok {
+					errores = append(errores, models.ErrorDetalleBulk{Indice: i, Campo: "rol", Codigo: "coordinador_duplicado", Mensaje: fmt.Sprintf("El grupo ya recibe un coordinador en la posición %d", j)})
+					continue
+				}
+				coordinadores[d.IDGrupo] = i
+			}
+		}
+		if len(errores) == 0 {
+			var err error
+			errores, err = repository.CreateDetallesGrupoInvestigador(r.Context(), db, detalles)
+			if err != nil {
+				logging.FromContext(r.Context()).Error("Error creating group-investigator relationships in bulk", "error", err)
+				utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+		if len(errores) > 0 {
+			campos := make([]utils.FieldError, len(errores))
+			for i, e := range errores {
+				campos[i] = utils.FieldError{Campo: fmt.Sprintf("[%d].%s", e.Indice, e.Campo), Codigo: e.Codigo, Mensaje: e.Mensaje}
+			}
+			utils.RespondFieldErrors(w, "Ninguna relación fue creada", http.StatusUnprocessableEntity, campos...)
+			return
+		}
+		for i := range detalles {
+			notificarMembresia(db, r, models.EventoMembresiaCreada, nil, &detalles[i])
+		}
+
+		utils.RespondJSON(w, http.StatusCreated, detalles)
 	}
 }
 
@@ -40,24 +181,33 @@ func GetDetalleGrupoInvestigadorHandler(db *sql.DB) http.HandlerFunc {
 		idStr := vars["id"]
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			http.Error(w, "Invalid detail ID", http.StatusBadRequest)
+			utils.RespondError(w, "Invalid detail ID", http.StatusBadRequest)
 			return
 		}
 
-		detalle, err := repository.GetDetalleGrupoInvestigadorByID(db, id)
+		detalle, err := repository.GetDetalleGrupoInvestigadorByID(r.Context(), db, id)
 		if err != nil {
-			log.Printf("Error getting detail by ID: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Error("Error getting detail by ID", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
 		if detalle == nil {
-			http.Error(w, "Detail not found", http.StatusNotFound)
+			utils.RespondError(w, "Detail not found", http.StatusNotFound)
+			return
+		}
+		grupo, err := repository.GetGrupoByID(r.Context(), db, detalle.IDGrupo)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting grupo of detail", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if grupo == nil || !grupoVisible(r.Context(), grupo) {
+			utils.RespondError(w, "Detail not found", http.StatusNotFound) // Deleted or unapproved group
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(detalle)
+		utils.RespondJSON(w, http.StatusOK, detalle)
 	}
 }
 
@@ -68,28 +218,41 @@ func UpdateDetalleGrupoInvestigadorHandler(db *sql.DB) http.HandlerFunc {
 		idStr := vars["id"]
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			http.Error(w, "Invalid detail ID", http.StatusBadRequest)
+			utils.RespondError(w, "Invalid detail ID", http.StatusBadRequest)
 			return
 		}
 
 		var detalle models.DetalleGrupoInvestigador
-		if err := json.NewDecoder(r.Body).Decode(&detalle); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		if !decodeJSON(w, r, &detalle, "Invalid request body") {
 			return
 		}
 
 		// Ensure the ID in the body matches the ID in the URL
 		detalle.ID = id
+		if !validar(w, &detalle) {
+			return
+		}
 
-		if err := repository.UpdateDetalleGrupoInvestigador(db, &detalle); err != nil {
-			log.Printf("Error updating detail: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		// Previous state, for the notification
+		antes, err := repository.GetDetalleGrupoInvestigadorByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting detail by ID", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := repository.UpdateDetalleGrupoInvestigador(r.Context(), db, &detalle); err != nil {
+			if respondMembresiaError(w, err) {
+				return
+			}
+			respondRepoError(w, r, err, "Error updating detail", "id", id)
 			return
 		}
+		if antes != nil {
+			notificarMembresia(db, r, models.EventoMembresiaActualizada, antes, &detalle)
+		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(detalle)
+		utils.RespondJSON(w, http.StatusOK, detalle)
 	}
 }
 
@@ -100,39 +263,370 @@ func DeleteDetalleGrupoInvestigadorHandler(db *sql.DB) http.HandlerFunc {
 		idStr := vars["id"]
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			http.Error(w, "Invalid detail ID", http.StatusBadRequest)
+			utils.RespondError(w, "Invalid detail ID", http.StatusBadRequest)
 			return
 		}
 
-		if err := repository.DeleteDetalleGrupoInvestigador(db, id); err != nil {
-			log.Printf("Error deleting detail: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		antes, err := repository.GetDetalleGrupoInvestigadorByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting detail by ID", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
+		if err := repository.DeleteDetalleGrupoInvestigador(r.Context(), db, id); err != nil {
+			respondRepoError(w, r, err, "Error deleting detail", "id", id)
+			return
+		}
+		if antes != nil {
+			notificarMembresia(db, r, models.EventoMembresiaEliminada, antes, nil)
+		}
+
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
-// GetDetallesByGrupoHandler handles fetching all relationship details for a given group ID.
+// fechaQueryParam parses the optional date query parameter nombre (YYYY-MM-DD), writing 400 and
+// returning false if it is invalid.
+func fechaQueryParam(w http.ResponseWriter, r *http.Request, nombre string) (*calendario.Fecha, bool) {
+	v := r.URL.Query().Get(nombre)
+	if v == "" {
+		return nil, true
+	}
+	fecha, err := calendario.Parse(v)
+	if err != nil {
+		utils.RespondError(w, fmt.Sprintf("Formato inválido para %s. Use %s", nombre, timeFormat), http.StatusBadRequest)
+		return nil, false
+	}
+	return &fecha, true
+}
+
+// respondDetallesPaginados answers a page of memberships of idGrupo (all groups if 0), filtered by
+// ?rol, ?nombre and ?tipo (investigator) and ?activosEn=YYYY-MM-DD.
+func respondDetallesPaginados(db *sql.DB, w http.ResponseWriter, r *http.Request, idGrupo int) {
+	page, limit := utils.GetPaginationParams(r)
+	offset := (page - 1) * limit
+	activosEn, ok := fechaQueryParam(w, r, "activosEn")
+	if !ok {
+		return
+	}
+	tipo := r.URL.Query().Get("tipo")
+	if tipo != "" && !models.EsTipoIntegranteValido(tipo) {
+		utils.RespondError(w, "Invalid tipo parameter", http.StatusBadRequest)
+		return
+	}
+	filtro := models.FiltroDetalles{
+		IDGrupo:   idGrupo,
+		Rol:       strings.TrimSpace(r.URL.Query().Get("rol")),
+		Nombre:    strings.TrimSpace(r.URL.Query().Get("nombre")),
+		Tipo:      tipo,
+		ActivosEn: activosEn,
+
+		NoAprobados: verNoAprobados(r.Context()),
+	}
+
+	detalles, totalItems, err := repository.GetAllDetallesGrupoInvestigador(r.Context(), db, filtro, limit, offset)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error getting group-investigator details", "error", err)
+		utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, models.PaginatedResponse{
+		Data:       detalles,
+		Pagination: paginacion(w, r, totalItems, page, limit),
+	})
+}
+
+// GetDetallesByGrupoHandler handles fetching a page of the relationship details of a given group ID,
+// 404 if the group does not exist or was deleted. It accepts the filters of respondDetallesPaginados; ?activosEn=YYYY-MM-DD returns the roster at that date.
 func GetDetallesByGrupoHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		grupoIDStr := vars["grupoID"]
 		grupoID, err := strconv.Atoi(grupoIDStr)
 		if err != nil {
-			http.Error(w, "Invalid group ID", http.StatusBadRequest)
+			utils.RespondError(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+		if !grupoActivoOr404(r.Context(), w, db, grupoID) {
+			return
+		}
+
+		respondDetallesPaginados(db, w, r, grupoID)
+	}
+}
+
+// GetDetallesByInvestigadorHandler lists the memberships of an investigator with the names of their
+// groups. ?activosEn=YYYY-MM-DD keeps the memberships current at that date.
+func GetDetallesByInvestigadorHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid investigator ID", http.StatusBadRequest)
+			return
+		}
+		activosEn, ok := fechaQueryParam(w, r, "activosEn")
+		if !ok {
 			return
 		}
 
-		detalles, err := repository.GetDetallesByGrupoID(db, grupoID)
+		investigador, err := repository.GetInvestigadorByID(r.Context(), db, id)
 		if err != nil {
-			log.Printf("Error getting details by group ID: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Error("Error getting investigator by ID", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if investigador == nil {
+			utils.RespondError(w, "Investigador not found", http.StatusNotFound)
+			return
+		}
+
+		detalles, err := repository.GetDetallesByInvestigadorID(r.Context(), db, id, activosEn, verNoAprobados(r.Context()))
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting details by investigator ID", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, detalles)
+	}
+}
+
+// integranteVars parses the {id} and {invId} path variables of the membership sub-resource.
+func integranteVars(w http.ResponseWriter, r *http.Request) (idGrupo, idInvestigador int, ok bool) {
+	vars := mux.Vars(r)
+	idGrupo, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.RespondError(w, "Invalid group ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	idInvestigador, err = strconv.Atoi(vars["invId"])
+	if err != nil {
+		utils.RespondError(w, "Invalid investigator ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return idGrupo, idInvestigador, true
+}
+
+// AddIntegranteHandler adds an investigator to a group (POST /grupos/{id}/investigadores).
+func AddIntegranteHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idGrupo, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+
+		var req models.IntegranteRequest
+		if !decodeJSON(w, r, &req, "Invalid request body") {
+			return
+		}
+		if strings.TrimSpace(req.Rol) == "" {
+			req.Rol = models.RolIntegrante
+		}
+		if !validar(w, &req) {
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(detalles)
+		if !grupoActivoOr404(r.Context(), w, db, idGrupo) {
+			return
+		}
+		investigador, err := repository.GetInvestigadorByID(r.Context(), db, req.IDInvestigador)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting investigator", "id_investigador", req.IDInvestigador, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if investigador == nil {
+			utils.RespondError(w, "Investigator not found", http.StatusNotFound)
+			return
+		}
+
+		existente, err := repository.GetDetalleByGrupoInvestigador(r.Context(), db, idGrupo, req.IDInvestigador)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error checking membership of investigator in group", "id_investigador", req.IDInvestigador, "id_grupo", idGrupo, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if existente != nil && existente.FechaFin == nil {
+			utils.RespondError(w, "El investigador ya es integrante del grupo", http.StatusConflict)
+			return
+		}
+
+		detalle := models.DetalleGrupoInvestigador{IDGrupo: idGrupo, IDInvestigador: req.IDInvestigador, Rol: req.Rol}
+		if existente != nil {
+			// Rejoining after a past membership: the new one starts today, and overlapping the past one is a conflict
+			hoy := calendario.Hoy()
+			detalle.FechaInicio = &hoy
+		}
+		if err := repository.CreateDetalleGrupoInvestigador(r.Context(), db, &detalle); err != nil {
+			if respondMembresiaError(w, err) {
+				return
+			}
+			logging.FromContext(r.Context()).Error("Error adding investigator to group", "id_investigador", req.IDInvestigador, "id_grupo", idGrupo, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		notificarMembresia(db, r, models.EventoMembresiaCreada, nil, &detalle)
+
+		utils.RespondJSON(w, http.StatusCreated, detalle)
+	}
+}
+
+// UpdateIntegranteHandler changes the role of a group member (PUT /grupos/{id}/investigadores/{invId}).
+func UpdateIntegranteHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idGrupo, idInvestigador, ok := integranteVars(w, r)
+		if !ok {
+			return
+		}
+
+		var req models.IntegranteRequest
+		if !decodeJSON(w, r, &req, "Invalid request body") {
+			return
+		}
+		req.IDInvestigador = idInvestigador
+		if !validar(w, &req) {
+			return
+		}
+
+		actual, err := repository.GetDetalleByGrupoInvestigador(r.Context(), db, idGrupo, idInvestigador)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting membership of investigator in group", "id_investigador", idInvestigador, "id_grupo", idGrupo, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if actual == nil || actual.FechaFin != nil {
+			utils.RespondError(w, "El investigador no es integrante del grupo", http.StatusNotFound)
+			return
+		}
+		// Demoting the coordinator would leave the group without one
+		if models.EsCoordinador(actual.Rol) && !models.EsCoordinador(req.Rol) {
+			utils.RespondError(w, "No se puede quitar el rol al coordinador; asigne otro con POST /grupos/{id}/coordinador", http.StatusConflict)
+			return
+		}
+
+		detalle, err := repository.UpdateRolGrupoInvestigador(r.Context(), db, idGrupo, idInvestigador, req.Rol)
+		if errors.Is(err, repository.ErrCoordinadorDuplicado) {
+			utils.RespondError(w, "El grupo ya tiene un coordinador; use POST /grupos/{id}/coordinador para reemplazarlo", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error updating role of investigator in group", "id_investigador", idInvestigador, "id_grupo", idGrupo, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if detalle == nil {
+			utils.RespondError(w, "El investigador no es integrante del grupo", http.StatusNotFound)
+			return
+		}
+		notificarMembresia(db, r, models.EventoMembresiaActualizada, actual, detalle)
+
+		utils.RespondJSON(w, http.StatusOK, detalle)
+	}
+}
+
+// RemoveIntegranteHandler removes an investigator from a group (DELETE /grupos/{id}/investigadores/{invId}).
+func RemoveIntegranteHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idGrupo, idInvestigador, ok := integranteVars(w, r)
+		if !ok {
+			return
+		}
+
+		// The coordinator can only leave when they are the last current member
+		detalles, err := repository.GetDetallesByGrupoID(r.Context(), db, idGrupo)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting members of group", "id_grupo", idGrupo, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		actuales := 0
+		for _, d := range detalles {
+			if d.FechaFin == nil {
+				actuales++
+			}
+		}
+		var antes *models.DetalleGrupoInvestigador
+		for i, d := range detalles {
+			if d.IDInvestigador != idInvestigador {
+				continue
+			}
+			if models.EsCoordinador(d.Rol) && d.FechaFin == nil && actuales > 1 {
+				utils.RespondError(w, "No se puede retirar al coordinador mientras haya otros integrantes; asigne otro con POST /grupos/{id}/coordinador", http.StatusConflict)
+				return
+			}
+			antes = &detalles[i]
+		}
+
+		eliminado, err := repository.DeleteGrupoInvestigador(r.Context(), db, idGrupo, idInvestigador)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error removing investigator from group", "id_investigador", idInvestigador, "id_grupo", idGrupo, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !eliminado {
+			utils.RespondError(w, "El investigador no es integrante del grupo", http.StatusNotFound)
+			return
+		}
+		if antes != nil {
+			notificarMembresia(db, r, models.EventoMembresiaEliminada, antes, nil)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// SetCoordinadorHandler makes an investigator the group's coordinator (POST /grupos/{id}/coordinador).
+// The previous coordinator stays in the group as Integrante; the investigator is added if not a member.
+func SetCoordinadorHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idGrupo, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+
+		var req models.CambiarCoordinadorRequest
+		if !decodeJSON(w, r, &req, "Invalid request body") {
+			return
+		}
+		if !validar(w, &req) {
+			return
+		}
+
+		antes, err := repository.GetDetalleByGrupoInvestigador(r.Context(), db, idGrupo, req.IDInvestigador)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting membership of investigator in group", "id_investigador", req.IDInvestigador, "id_grupo", idGrupo, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if antes != nil && antes.FechaFin != nil {
+			antes = nil // A past membership is left as it was and the investigator joins again
+		}
+
+		detalle, err := repository.SetCoordinadorGrupo(r.Context(), db, idGrupo, req.IDInvestigador)
+		switch {
+		case errors.Is(err, repository.ErrGrupoNoEncontrado):
+			utils.RespondError(w, "Grupo not found", http.StatusNotFound)
+			return
+		case errors.Is(err, repository.ErrInvestigadorNoExiste):
+			utils.RespondError(w, "Investigator not found", http.StatusNotFound)
+			return
+		case respondMembresiaError(w, err):
+			return
+		case err != nil:
+			logging.FromContext(r.Context()).Error("Error setting coordinator of group", "id_investigador", req.IDInvestigador, "id_grupo", idGrupo, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		switch {
+		case antes == nil:
+			notificarMembresia(db, r, models.EventoMembresiaCreada, nil, detalle)
+		case !models.EsCoordinador(antes.Rol):
+			notificarMembresia(db, r, models.EventoMembresiaActualizada, antes, detalle)
+		}
+
+		utils.RespondJSON(w, http.StatusOK, detalle)
 	}
 }
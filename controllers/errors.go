@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/validation"
+)
+
+// respondRepoError writes the response for an error returned by the repository: the error's own
+// message with 404, 409 or 422 for repository.ErrNotFound, ErrConflict and ErrValidation (unique
+// and foreign key violations included, see repository.ConstraintError), and a generic 500 for
+// anything else, which is logged as msg with args so no internal detail reaches the client.
+func respondRepoError(w http.ResponseWriter, r *http.Request, err error, msg string, args ...any) {
+	err = repository.ConstraintError(err)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		utils.RespondError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repository.ErrConflict):
+		utils.RespondError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, repository.ErrValidation):
+		utils.RespondError(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		logging.FromContext(r.Context()).Error(msg, append(args, "error", err)...)
+		utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// validar checks v against its `validate` tags and, if anything is wrong, answers 422 listing the
+// offending fields. It reports whether v is valid.
+func validar(w http.ResponseWriter, v interface{}) bool {
+	if campos := validation.Struct(v); len(campos) > 0 {
+		utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity, campos...)
+		return false
+	}
+	return true
+}
+
+// decodeJSON decodes the body of r into v. A body that is not valid JSON for v is answered with 400
+// and message (413 past its size limit); a date that is not YYYY-MM-DD, such as a timestamp, with
+// 422 naming the field. It reports whether v was decoded.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}, message string) bool {
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.NewDecoder(bytes.NewReader(body)).Decode(v)
+	}
+	if err == nil {
+		return true
+	}
+	if calendario.EsFechaInvalida(err) {
+		utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity, validation.FechaInvalida(validation.CampoFecha(body, v)))
+		return false
+	}
+	utils.RespondDecodeError(w, err, message)
+	return false
+}
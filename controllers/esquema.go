@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/schemas"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/gorilla/mux"
+)
+
+// GetSchemasHandler lists the published JSON Schemas (see package schemas): the webhook events
+// first, then the API models, each with the URL it is served at.
+func GetSchemasHandler(w http.ResponseWriter, r *http.Request) {
+	defs := schemas.Definiciones()
+	for i := range defs {
+		defs[i].URL = urlEsquema(r, defs[i].Nombre)
+	}
+	utils.RespondJSONWithETag(w, r, defs)
+}
+
+// GetSchemaHandler serves the JSON Schema of GET /schemas/{nombre}.json, with a strong ETag: the
+// schemas only change with the binary.
+func GetSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	nombre := mux.Vars(r)["nombre"]
+	s, ok := schemas.Get(nombre, urlEsquema(r, nombre))
+	if !ok {
+		utils.RespondError(w, "Schema not found", http.StatusNotFound)
+		return
+	}
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		logging.FromContext(r.Context()).Error("Error encoding JSON Schema", "schema", nombre, "error", err)
+		utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if utils.NotModified(w, r, utils.StrongETag(body.Bytes())) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
+
+func urlEsquema(r *http.Request, nombre string) string {
+	return utils.BaseURL(r) + "/schemas/" + nombre + ".json"
+}
@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// directorioNoModificado sets the weak ETag of a directory list (groups or investigators) and
+// answers 304 if the client's copy is still current, so clients polling the directory skip the
+// list queries and the body. The ETag varies with the query string, the caller's role and whether
+// the response is public (anonymous), which decide what the list contains. If the version cannot be read the list is served as usual.
+func directorioNoModificado(w http.ResponseWriter, r *http.Request, db *sql.DB) bool {
+	count, lastModified, err := repository.GetDirectorioVersion(r.Context(), db)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("Error reading directory version, serving without ETag", "error", err)
+		return false
+	}
+	return utils.NotModified(w, r, utils.WeakETag(count, lastModified, r.URL.RawQuery, strconv.FormatBool(middleware.IsAdmin(r)), strconv.FormatBool(utils.EsRespuestaPublica(w))))
+}
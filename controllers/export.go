@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/background"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/exports"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/gorilla/mux"
+)
+
+// CreateExportHandler starts an export job in the background (POST /exports) and responds 202 with
+// the job and a Location header to poll. Body: {"tipo": "grupos_detalles"|"reporte_grupos",
+// "anios": [2023], "includeDeleted": false}. includeDeleted is only honored for admins.
+func CreateExportHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var parametros models.ParametrosExport
+		if err := json.NewDecoder(r.Body).Decode(&parametros); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body")
+			return
+		}
+		if !validar(w, &parametros) {
+			return
+		}
+		if parametros.IncludeDeleted && !middleware.IsAdmin(r) {
+			utils.RespondError(w, "includeDeleted requires admin role", http.StatusForbidden)
+			return
+		}
+
+		backend, err := storage.Default()
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting storage backend for export", "error", err)
+			utils.RespondError(w, "El almacenamiento de archivos no está disponible", http.StatusServiceUnavailable)
+			return
+		}
+
+		var creadoPor *int
+		if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+			creadoPor = &userID
+		}
+		job, err := repository.CreateExportJob(r.Context(), db, parametros, creadoPor)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error creating export job", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		// La exportación sobrevive a la petición que la inició; el apagado del servidor la cancela
+		background.Go(func() { exports.Run(background.Context(), db, job, backend) })
+
+		w.Header().Set("Location", fmt.Sprintf("/exports/%d", job.ID))
+		utils.RespondJSON(w, http.StatusAccepted, job)
+	}
+}
+
+// exportJobOr404 loads an export job visible to the caller (its creator or an admin), writing
+// 400/404/500 and returning nil if it can't. Other users' jobs are reported as not found.
+func exportJobOr404(w http.ResponseWriter, r *http.Request, db *sql.DB) *models.ExportJob {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, "Invalid export ID", http.StatusBadRequest)
+		return nil
+	}
+	job, err := repository.GetExportJob(r.Context(), db, id)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error getting export job", "id", id, "error", err)
+		utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+		return nil
+	}
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	if job == nil || (!middleware.IsAdmin(r) && (job.CreadoPor == nil || *job.CreadoPor != userID)) {
+		utils.RespondError(w, "Export not found", http.StatusNotFound)
+		return nil
+	}
+	return job
+}
+
+// GetExportHandler reports the status of an export job (GET /exports/{id}). Completed jobs include
+// the download URL.
+func GetExportHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job := exportJobOr404(w, r, db)
+		if job == nil {
+			return
+		}
+
+		// A job that stopped saving progress was interrupted with its instance
+		enCurso := job.Estado == models.ExportPendiente || job.Estado == models.ExportEnProceso
+		if enCurso && time.Since(job.UpdatedAt) > exports.StaleAfter {
+			mensaje := "la exportación se interrumpió; créela de nuevo"
+			if err := repository.FailExportJob(r.Context(), db, job.ID, mensaje); err != nil {
+				logging.FromContext(r.Context()).Error("Error marking stale export as failed", "id", job.ID, "error", err)
+			} else {
+				job.Estado = models.ExportError
+				job.Error = &mensaje
+			}
+		}
+
+		if job.Estado == models.ExportCompletado {
+			job.URL = fmt.Sprintf("%s/exports/%d/download", utils.BaseURL(r), job.ID)
+		}
+		utils.RespondJSON(w, http.StatusOK, job)
+	}
+}
+
+// DownloadExportHandler streams the file of a completed export job (GET /exports/{id}/download).
+func DownloadExportHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job := exportJobOr404(w, r, db)
+		if job == nil {
+			return
+		}
+		if job.Estado == models.ExportExpirado {
+			utils.RespondError(w, "El archivo de la exportación ya no está disponible", http.StatusGone)
+			return
+		}
+		if job.Estado != models.ExportCompletado || job.Archivo == nil {
+			utils.RespondError(w, "La exportación aún no está lista", http.StatusConflict)
+			return
+		}
+
+		obj, ok := openStoredFile(w, r, *job.Archivo)
+		if !ok {
+			return
+		}
+		defer obj.Body.Close()
+
+		if contentType := obj.ContentType; contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", job.NombreArchivo))
+		w.Header().Set("Cache-Control", "private, no-store")
+		if _, err := io.Copy(w, obj.Body); err != nil {
+			logging.FromContext(r.Context()).Error("Error sending export", "id", job.ID, "error", err)
+		}
+	}
+}
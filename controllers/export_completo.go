@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/version"
+)
+
+const (
+	// formatoExportCompleto and versionExportCompleto identify the format of GET /admin/export/full.
+	// The version changes when a field is removed or changes meaning, not when one is added.
+	formatoExportCompleto = "apigrupos-export-completo"
+	versionExportCompleto = 1
+
+	// defaultIntervaloExportCompleto is the minimum time between two full exports of an
+	// administrator unless EXPORT_FULL_MIN_INTERVAL says otherwise.
+	defaultIntervaloExportCompleto = 5 * time.Minute
+
+	// loteFlushExport is how many rows are written between flushes.
+	loteFlushExport = 500
+)
+
+// exportCompleto limits the full exports: one at a time in the instance, and one per
+// administrator every EXPORT_FULL_MIN_INTERVAL.
+var exportCompleto struct {
+	enCurso sync.Mutex
+	mu      sync.Mutex
+	ultimo  map[int]time.Time
+}
+
+// intervaloExportCompletoFromEnv reads EXPORT_FULL_MIN_INTERVAL, using the default for unset or
+// invalid values. 0 disables the per-administrator limit.
+func intervaloExportCompletoFromEnv() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("EXPORT_FULL_MIN_INTERVAL")); err == nil && d >= 0 {
+		return d
+	}
+	return defaultIntervaloExportCompleto
+}
+
+// reservarExportCompleto records an export of userID now, or returns how long until the next one
+// is allowed.
+func reservarExportCompleto(userID int, intervalo time.Duration) (time.Duration, bool) {
+	exportCompleto.mu.Lock()
+	defer exportCompleto.mu.Unlock()
+	now := time.Now()
+	if espera := exportCompleto.ultimo[userID].Add(intervalo).Sub(now); espera > 0 {
+		return espera, false
+	}
+	if exportCompleto.ultimo == nil {
+		exportCompleto.ultimo = map[int]time.Time{}
+	}
+	exportCompleto.ultimo[userID] = now
+	return 0, true
+}
+
+// cabeceraExportCompleto opens the dump with its format, version and date.
+type cabeceraExportCompleto struct {
+	Tipo              string    `json:"tipo,omitempty"` // "cabecera" in NDJSON
+	Formato           string    `json:"formato"`
+	Version           int       `json:"version"`
+	GeneradoEn        time.Time `json:"generadoEn"`
+	APIVersion        string    `json:"apiVersion"`
+	IncluyeEliminados bool      `json:"incluyeEliminados"`
+}
+
+// GetExportFullHandler streams every group, investigator and membership with the catalogs
+// (faculties, schools and research types) as a single versioned dump, for analytics pipelines and
+// migrations (admin only). The rows are read in one transaction and written as they are read,
+// flat and by ID: ?format=json (the default) writes one object with an array per section, and
+// ?format=ndjson one line per row between a "cabecera" and a "fin" line. Both end with the totals
+// of each section; a dump without them was cut short by an error. ?includeDeleted=true adds the
+// soft-deleted investigators and groups.
+func GetExportFullHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var s exportStream
+		switch r.URL.Query().Get("format") {
+		case "", "json":
+			s = &exportStreamJSON{w: w}
+		case "ndjson":
+			s = &exportStreamNDJSON{w: w}
+		default:
+			utils.RespondError(w, "Invalid format parameter (json or ndjson)", http.StatusBadRequest)
+			return
+		}
+		incluirEliminados := r.URL.Query().Get("includeDeleted") == "true"
+
+		if !exportCompleto.enCurso.TryLock() {
+			w.Header().Set("Retry-After", "60")
+			utils.RespondError(w, "A full export is already running", http.StatusTooManyRequests)
+			return
+		}
+		defer exportCompleto.enCurso.Unlock()
+		userID, _ := middleware.UserIDFromContext(r.Context())
+		if espera, ok := reservarExportCompleto(userID, intervaloExportCompletoFromEnv()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(espera.Seconds()))))
+			utils.RespondError(w, "Too many full exports; try again later", http.StatusTooManyRequests)
+			return
+		}
+
+		cabecera := cabeceraExportCompleto{
+			Formato:           formatoExportCompleto,
+			Version:           versionExportCompleto,
+			GeneradoEn:        time.Now().UTC(),
+			APIVersion:        version.String(),
+			IncluyeEliminados: incluirEliminados,
+		}
+		// The response starts with the first section, so a failing transaction can still get a 500
+		iniciado := false
+		totales := map[string]int{}
+		var orden []string
+		flush := http.NewResponseController(w).Flush
+		n := 0
+		err := repository.ExportarDirectorio(r.Context(), db, incluirEliminados, func(seccion string) error {
+			if !iniciado {
+				iniciado = true
+				w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+					"apigrupos_"+cabecera.GeneradoEn.Format("20060102-150405")+s.extension()))
+				if err := s.iniciar(cabecera); err != nil {
+					return err
+				}
+			}
+			totales[seccion] = 0
+			orden = append(orden, seccion)
+			return s.seccion(seccion)
+		}, func(seccion string, v any) error {
+			totales[seccion]++
+			if n++; n%loteFlushExport == 0 {
+				flush()
+			}
+			return s.fila(seccion, v)
+		})
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error streaming full export", "error", err)
+			if !iniciado {
+				utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			}
+			return
+		}
+		if err := s.cerrar(orden, totales); err != nil {
+			logging.FromContext(r.Context()).Error("Error finishing full export", "error", err)
+		}
+	}
+}
+
+// exportStream writes the full export in one format.
+type exportStream interface {
+	extension() string
+	iniciar(c cabeceraExportCompleto) error // Sets the headers and writes the opening of the dump
+	seccion(nombre string) error
+	fila(seccion string, v any) error
+	cerrar(orden []string, totales map[string]int) error
+}
+
+// totalesJSON writes the totals in the order of the sections.
+func totalesJSON(orden []string, totales map[string]int) json.RawMessage {
+	b := []byte{'{'}
+	for i, s := range orden {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = strconv.AppendQuote(b, s)
+		b = append(b, ':')
+		b = strconv.AppendInt(b, int64(totales[s]), 10)
+	}
+	return append(b, '}')
+}
+
+// exportStreamJSON writes {<cabecera>, "facultades": [...], ..., "totales": {...}}.
+type exportStreamJSON struct {
+	w         http.ResponseWriter
+	enSeccion bool
+	filas     int // Of the current section
+}
+
+func (s *exportStreamJSON) extension() string { return ".json" }
+
+func (s *exportStreamJSON) iniciar(c cabeceraExportCompleto) error {
+	s.w.Header().Set("Content-Type", "application/json")
+	s.w.WriteHeader(http.StatusOK)
+	b, err := utils.MarshalRespuesta(s.w, c)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(b[:len(b)-1]) // Without the closing brace: the sections follow
+	return err
+}
+
+func (s *exportStreamJSON) seccion(nombre string) error {
+	cierre := ""
+	if s.enSeccion {
+		cierre = "]"
+	}
+	s.enSeccion, s.filas = true, 0
+	_, err := fmt.Fprintf(s.w, "%s,%q:[", cierre, nombre)
+	return err
+}
+
+func (s *exportStreamJSON) fila(_ string, v any) error {
+	b, err := utils.MarshalRespuesta(s.w, v)
+	if err != nil {
+		return err
+	}
+	if s.filas > 0 {
+		b = append([]byte{','}, b...)
+	}
+	s.filas++
+	_, err = s.w.Write(b)
+	return err
+}
+
+func (s *exportStreamJSON) cerrar(orden []string, totales map[string]int) error {
+	cierre := ""
+	if s.enSeccion {
+		cierre = "]"
+	}
+	_, err := fmt.Fprintf(s.w, "%s,\"totales\":%s}\n", cierre, totalesJSON(orden, totales))
+	return err
+}
+
+// exportStreamNDJSON writes a line per row, between a "cabecera" and a "fin" line.
+type exportStreamNDJSON struct {
+	w http.ResponseWriter
+}
+
+// lineaExport is a line of the NDJSON export.
+type lineaExport struct {
+	Tipo    string          `json:"tipo"` // "fila" or "fin"
+	Seccion string          `json:"seccion,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Totales json.RawMessage `json:"totales,omitempty"`
+}
+
+func (s *exportStreamNDJSON) extension() string { return ".ndjson" }
+
+func (s *exportStreamNDJSON) iniciar(c cabeceraExportCompleto) error {
+	s.w.Header().Set("Content-Type", "application/x-ndjson")
+	s.w.WriteHeader(http.StatusOK)
+	c.Tipo = "cabecera"
+	return s.linea(c)
+}
+
+func (s *exportStreamNDJSON) seccion(string) error { return nil }
+
+func (s *exportStreamNDJSON) fila(seccion string, v any) error {
+	b, err := utils.MarshalRespuesta(s.w, v)
+	if err != nil {
+		return err
+	}
+	return s.linea(lineaExport{Tipo: "fila", Seccion: seccion, Data: b})
+}
+
+func (s *exportStreamNDJSON) cerrar(orden []string, totales map[string]int) error {
+	return s.linea(lineaExport{Tipo: "fin", Totales: totalesJSON(orden, totales)})
+}
+
+// linea writes v as a line of the dump.
+func (s *exportStreamNDJSON) linea(v any) error {
+	b, err := utils.MarshalRespuesta(s.w, v)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(append(b, '\n'))
+	return err
+}
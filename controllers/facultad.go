@@ -0,0 +1,320 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/validation"
+	"github.com/gorilla/mux"
+)
+
+// GetFacultadesHandler lists the faculties with their professional schools (GET /facultades).
+func GetFacultadesHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		facultades, err := repository.GetFacultades(r.Context(), db)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting faculties", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, facultades)
+	}
+}
+
+// GetFacultadHandler returns a faculty with its professional schools (GET /facultades/{id}).
+func GetFacultadHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid facultad ID", http.StatusBadRequest)
+			return
+		}
+		f, err := repository.GetFacultad(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting faculty", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if f == nil {
+			utils.RespondError(w, "Facultad not found", http.StatusNotFound)
+			return
+		}
+		utils.RespondJSONWithETag(w, r, f)
+	}
+}
+
+// decodeFacultad reads and validates the body of POST and PUT /facultades. It answers 400 or 422
+// and returns ok=false if it is not valid.
+func decodeFacultad(w http.ResponseWriter, r *http.Request) (f models.Facultad, ok bool) {
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		utils.RespondDecodeError(w, err, "Invalid request body")
+		return f, false
+	}
+	f.Nombre = strings.TrimSpace(f.Nombre)
+	if f.Siglas != nil {
+		if siglas := strings.TrimSpace(*f.Siglas); siglas != "" {
+			f.Siglas = &siglas
+		} else {
+			f.Siglas = nil
+		}
+	}
+	return f, validar(w, &f)
+}
+
+// CreateFacultadHandler creates a faculty (POST /facultades, admin only).
+func CreateFacultadHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f, ok := decodeFacultad(w, r)
+		if !ok {
+			return
+		}
+		if err := repository.CreateFacultad(r.Context(), db, &f); err != nil {
+			respondRepoError(w, r, err, "Error creating faculty")
+			return
+		}
+		utils.RespondJSON(w, http.StatusCreated, f)
+	}
+}
+
+// UpdateFacultadHandler replaces the name and abbreviation of a faculty (PUT /facultades/{id},
+// admin only).
+func UpdateFacultadHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid facultad ID", http.StatusBadRequest)
+			return
+		}
+		f, ok := decodeFacultad(w, r)
+		if !ok {
+			return
+		}
+		f.ID = id
+		if err := repository.UpdateFacultad(r.Context(), db, &f); err != nil {
+			respondRepoError(w, r, err, "Error updating faculty", "id", id)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, f)
+	}
+}
+
+// DeleteFacultadHandler deletes a faculty without schools, groups or investigators; otherwise it
+// answers 409 (DELETE /facultades/{id}, admin only).
+func DeleteFacultadHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid facultad ID", http.StatusBadRequest)
+			return
+		}
+		if err := repository.DeleteFacultad(r.Context(), db, id); err != nil {
+			respondRepoError(w, r, err, "Error deleting faculty", "id", id)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetEscuelaHandler returns a professional school (GET /escuelas/{id}).
+func GetEscuelaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid escuela ID", http.StatusBadRequest)
+			return
+		}
+		e, err := repository.GetEscuela(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting professional school", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if e == nil {
+			utils.RespondError(w, "Escuela not found", http.StatusNotFound)
+			return
+		}
+		utils.RespondJSONWithETag(w, r, e)
+	}
+}
+
+// decodeEscuela reads and validates the body of POST /facultades/{id}/escuelas and PUT
+// /escuelas/{id}, whose faculty comes from the URL or does not change. It answers 400 or 422 and
+// returns ok=false if it is not valid.
+func decodeEscuela(w http.ResponseWriter, r *http.Request, idFacultad int) (e models.EscuelaProfesional, ok bool) {
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		utils.RespondDecodeError(w, err, "Invalid request body")
+		return e, false
+	}
+	e.Nombre = strings.TrimSpace(e.Nombre)
+	e.IDFacultad = idFacultad
+	return e, validar(w, &e)
+}
+
+// CreateEscuelaHandler adds a professional school to a faculty (POST /facultades/{id}/escuelas,
+// admin only).
+func CreateEscuelaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idFacultad, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid facultad ID", http.StatusBadRequest)
+			return
+		}
+		e, ok := decodeEscuela(w, r, idFacultad)
+		if !ok {
+			return
+		}
+		f, err := repository.GetFacultad(r.Context(), db, idFacultad)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting faculty", "id", idFacultad, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if f == nil {
+			utils.RespondError(w, "Facultad not found", http.StatusNotFound)
+			return
+		}
+		if err := repository.CreateEscuela(r.Context(), db, &e); err != nil {
+			respondRepoError(w, r, err, "Error creating professional school", "idFacultad", idFacultad)
+			return
+		}
+		utils.RespondJSON(w, http.StatusCreated, e)
+	}
+}
+
+// UpdateEscuelaHandler renames a professional school (PUT /escuelas/{id}, admin only). Its faculty
+// can't be changed, since its investigators belong to it.
+func UpdateEscuelaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid escuela ID", http.StatusBadRequest)
+			return
+		}
+		actual, err := repository.GetEscuela(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting professional school", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if actual == nil {
+			utils.RespondError(w, "Escuela not found", http.StatusNotFound)
+			return
+		}
+		e, ok := decodeEscuela(w, r, actual.IDFacultad)
+		if !ok {
+			return
+		}
+		e.ID = id
+		if err := repository.UpdateEscuela(r.Context(), db, &e); err != nil {
+			respondRepoError(w, r, err, "Error updating professional school", "id", id)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, e)
+	}
+}
+
+// DeleteEscuelaHandler deletes a professional school without investigators; otherwise it answers
+// 409 (DELETE /escuelas/{id}, admin only).
+func DeleteEscuelaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid escuela ID", http.StatusBadRequest)
+			return
+		}
+		if err := repository.DeleteEscuela(r.Context(), db, id); err != nil {
+			respondRepoError(w, r, err, "Error deleting professional school", "id", id)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// parseFacultadesQuery reads the ?facultad= filter of the listings: faculty IDs, repeated or
+// separated by commas. It answers 400 and returns ok=false if any is not an ID.
+func parseFacultadesQuery(w http.ResponseWriter, r *http.Request) (ids []int, ok bool) {
+	for _, v := range utils.QueryValues(r, "facultad") {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 1 {
+			utils.RespondError(w, fmt.Sprintf("Invalid facultad filter: %q is not an ID", v), http.StatusBadRequest)
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// formFacultad reads the optional idFacultad field of the group forms: nil when it is absent, and
+// 0 (also sent as an empty value) to remove the faculty. It answers 422 and returns ok=false if it
+// is not a number.
+func formFacultad(w http.ResponseWriter, r *http.Request) (id *int, ok bool) {
+	v := strings.TrimSpace(r.FormValue("idFacultad"))
+	if _, enviado := r.Form["idFacultad"]; !enviado {
+		return nil, true
+	}
+	n := 0
+	if v != "" {
+		var err error
+		if n, err = strconv.Atoi(v); err != nil || n < 0 {
+			utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity, validation.NumeroInvalido("idFacultad"))
+			return nil, false
+		}
+	}
+	return &n, true
+}
+
+// sinCero turns the 0 sent to remove a faculty or school into nil.
+func sinCero(id *int) *int {
+	if id == nil || *id == 0 {
+		return nil
+	}
+	return id
+}
+
+// validarFacultadInvestigador resolves the faculty and school of inv against the ones it has now
+// (actual, nil when creating): an omitted one is kept and a 0 removes it. A school must be of the
+// investigator's faculty; when the school is sent without the faculty, the faculty is taken from
+// it. It answers 422 (or 500) and returns false if they don't match.
+func validarFacultadInvestigador(w http.ResponseWriter, r *http.Request, db *sql.DB, inv *models.Investigador, actual *models.Investigador) bool {
+	facultadEnviada, escuelaEnviada := inv.IDFacultad != nil, inv.IDEscuela != nil
+	if actual != nil {
+		if !facultadEnviada {
+			inv.IDFacultad = actual.IDFacultad
+		}
+		if !escuelaEnviada {
+			inv.IDEscuela = actual.IDEscuela
+		}
+	}
+	inv.IDFacultad, inv.IDEscuela = sinCero(inv.IDFacultad), sinCero(inv.IDEscuela)
+	if inv.IDEscuela == nil {
+		return true
+	}
+	escuela, err := repository.GetEscuela(r.Context(), db, *inv.IDEscuela)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error getting professional school", "id", *inv.IDEscuela, "error", err)
+		utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if escuela == nil {
+		utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity,
+			utils.NewFieldError("idEscuela", "escuela_inexistente", "escuela_inexistente", *inv.IDEscuela))
+		return false
+	}
+	if escuelaEnviada && !facultadEnviada {
+		inv.IDFacultad = &escuela.IDFacultad
+	}
+	if inv.IDFacultad == nil || *inv.IDFacultad != escuela.IDFacultad {
+		utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity,
+			utils.NewFieldError("idEscuela", "escuela_de_otra_facultad", "escuela_de_otra_facultad", escuela.ID))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/gorilla/mux"
+)
+
+// GetFavoritosHandler lists the groups pinned by the current user, most recently pinned first,
+// with pagination.
+func GetFavoritosHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			utils.RespondError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		page, limit := utils.GetPaginationParams(r)
+		offset := (page - 1) * limit
+
+		favoritos, totalItems, err := repository.GetFavoritosByUsuario(r.Context(), db, userID, limit, offset)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting favorite groups", "id_usuario", userID, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, models.PaginatedResponse{
+			Data:       favoritos,
+			Pagination: paginacion(w, r, totalItems, page, limit),
+		})
+	}
+}
+
+// AddFavoritoHandler pins a group for the current user. Pinning it again is not an error.
+func AddFavoritoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid grupo ID", http.StatusBadRequest)
+			return
+		}
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			utils.RespondError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !grupoActivoOr404(r.Context(), w, db, id) {
+			return
+		}
+		if err := repository.AddFavorito(r.Context(), db, userID, id); err != nil {
+			respondRepoError(w, r, err, "Error adding favorite group", "id", id)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RemoveFavoritoHandler unpins a group for the current user, answering 404 if it was not pinned.
+func RemoveFavoritoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid grupo ID", http.StatusBadRequest)
+			return
+		}
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			utils.RespondError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		eliminado, err := repository.RemoveFavorito(r.Context(), db, userID, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error removing favorite group", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !eliminado {
+			utils.RespondError(w, "Favorito not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -1,24 +1,32 @@
 package controllers
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"math"
+	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"time"
 
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/cache"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/reports"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/telemetry"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/validation"
 	"github.com/gorilla/mux"
-	"github.com/joho/godotenv"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/drive/v3"
@@ -36,57 +44,59 @@ var (
 	driveFolderID string
 )
 
-// init se ejecuta una vez al iniciar el paquete
-func init() {
-	// Cargar variables de entorno desde .env
-	err := godotenv.Load() // Asume .env en el directorio de ejecución
-	if err != nil {
-		log.Println("Advertencia: No se pudo cargar el archivo .env, se intentará usar variables de entorno del sistema:", err)
+// InitStorage registra los backends de almacenamiento: disco local (servido en /files/) y, si
+// está configurado (GOOGLE_APPLICATION_CREDENTIALS o GOOGLE_CREDENTIALS_JSON, y
+// GOOGLE_DRIVE_FOLDER_ID), Google Drive. Sin esas
+// variables el servidor arranca igual y las operaciones sobre Drive responden que el backend no está
+// disponible; unas credenciales ilegibles o inválidas sí son un error de arranque.
+func InitStorage(ctx context.Context) error {
+	// Backend local y enlaces de los archivos en las respuestas JSON (exponen el enlace de
+	// visualización de cada archivo, no su referencia interna)
+	localDir := os.Getenv("LOCAL_STORAGE_DIR")
+	if localDir == "" {
+		localDir = "./uploads"
 	}
+	storage.Register(storage.NewLocalBackend(localDir, strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/")+"/files/"))
+	utils.RegisterLinkRewriter(utils.LinkFile, storage.URL)
 
+	// La clave JSON puede venir en GOOGLE_CREDENTIALS_JSON (p. ej. desde Secret Manager, ver el
+	// paquete secrets) o en el archivo de GOOGLE_APPLICATION_CREDENTIALS
+	credentialsJSON := os.Getenv("GOOGLE_CREDENTIALS_JSON")
 	credentialsPath := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
-	driveFolderID = os.Getenv("GOOGLE_DRIVE_FOLDER_ID")
-
-	if credentialsPath == "" {
-		log.Fatal("La variable de entorno GOOGLE_APPLICATION_CREDENTIALS no está configurada. Debe ser la ruta a su archivo JSON de credenciales.")
+	folderID := os.Getenv("GOOGLE_DRIVE_FOLDER_ID")
+	if (credentialsJSON == "" && credentialsPath == "") || folderID == "" {
+		slog.Warn("Google Drive no configurado (GOOGLE_APPLICATION_CREDENTIALS o GOOGLE_CREDENTIALS_JSON, y GOOGLE_DRIVE_FOLDER_ID); los archivos en Drive no estarán disponibles", "storage_backend", storage.DefaultName())
+		return nil
 	}
-	if driveFolderID == "" {
-		log.Fatal("La variable de entorno GOOGLE_DRIVE_FOLDER_ID no está configurada.")
-	}
-
-	ctx := context.Background()
 
-	// Leer el contenido del archivo de credenciales JSON
-	credsBytes, err := os.ReadFile(credentialsPath)
-	if err != nil {
-		log.Fatalf("No se pudo leer el archivo de credenciales JSON desde la ruta especificada en GOOGLE_APPLICATION_CREDENTIALS (%s): %v", credentialsPath, err)
+	credsBytes := []byte(credentialsJSON)
+	if credentialsJSON == "" {
+		// Leer el contenido del archivo de credenciales JSON
+		var err error
+		if credsBytes, err = os.ReadFile(credentialsPath); err != nil {
+			return fmt.Errorf("reading GOOGLE_APPLICATION_CREDENTIALS %q: %w", credentialsPath, err)
+		}
 	}
 
 	// Crear credenciales a partir del contenido del archivo JSON
 	creds, err := google.CredentialsFromJSON(ctx, credsBytes, drive.DriveFileScope)
 	if err != nil {
-		log.Fatalf("No se pudieron crear las credenciales de Google a partir del archivo JSON. Asegúrese de que el archivo sea válido y contenga una clave privada PEM correcta: %v", err)
+		return fmt.Errorf("parsing Google credentials (must be a valid JSON key with a PEM private key): %w", err)
 	}
 
 	// Crear el cliente HTTP con las credenciales
 	client := oauth2.NewClient(ctx, creds.TokenSource)
+	// Las llamadas a Drive aparecen como spans dentro de la traza de cada petición
+	client.Transport = telemetry.Transport(client.Transport)
 
 	// Crear el servicio de Drive
-	driveService, err = drive.NewService(ctx, option.WithHTTPClient(client))
+	service, err := drive.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
-		log.Fatalf("No se pudo crear el servicio de Drive: %v", err)
+		return fmt.Errorf("creating Drive service: %w", err)
 	}
-	log.Println("Servicio de Google Drive inicializado correctamente.")
-}
-
-// constructDriveLink genera el enlace web de visualización para un ID de archivo de Drive
-func constructDriveLink(fileID *string) *string {
-	if fileID != nil && *fileID != "" {
-		// Usar https://drive.google.com/file/d/FILE_ID/view como formato estándar
-		link := fmt.Sprintf("https://drive.google.com/file/d/%s/view", *fileID)
-		return &link
-	}
-	// Si no hay fileID, devuelve nil
+	driveService, driveFolderID = service, folderID
+	storage.Register(storage.NewDriveBackend(driveService, driveFolderID))
+	slog.Info("Servicio de Google Drive inicializado correctamente")
 	return nil
 }
 
@@ -111,18 +121,20 @@ func oauth2ConfigFromCredentials(creds *google.Credentials) *oauth2.Config {
 	}
 }
 
-// Helper function to save uploaded file to Google Drive
-func saveUploadedFile(r *http.Request, formKey string) (*string, error) {
-	// Asegurarse de que el servicio de Drive esté inicializado
-	if driveService == nil {
-		return nil, fmt.Errorf("el servicio de Google Drive no está inicializado")
+// Helper function to save uploaded file to the default storage backend (Google Drive unless STORAGE_BACKEND says otherwise).
+// Returns the file's storage ref, or nil if no file was uploaded. The file's checksum is stored
+// to detect the same document uploaded to several groups.
+func saveUploadedFile(db *sql.DB, r *http.Request, formKey string) (*string, error) {
+	backend, err := storage.Default()
+	if err != nil {
+		return nil, fmt.Errorf("el almacenamiento de archivos no está inicializado: %w", err)
 	}
 
-	err := r.ParseMultipartForm(maxUploadSize)
+	err = r.ParseMultipartForm(maxUploadSize)
 	if err != nil {
 		// Si no es multipart o falta el archivo, devolvemos nil, nil como antes
 		if err == http.ErrNotMultipart || err == http.ErrMissingFile {
-			log.Printf("Formulario no es multipart o falta archivo '%s'", formKey)
+			logging.FromContext(r.Context()).Info("Formulario no es multipart o falta archivo", "form_key", formKey)
 			return nil, nil // Indica que no se subió archivo, no es un error fatal aquí.
 		}
 		return nil, fmt.Errorf("error parsing multipart form: %w", err)
@@ -132,7 +144,7 @@ func saveUploadedFile(r *http.Request, formKey string) (*string, error) {
 	if err != nil {
 		// Si el archivo específico no está, devolvemos nil, nil
 		if err == http.ErrMissingFile {
-			log.Printf("Campo de archivo '%s' no encontrado en el formulario", formKey)
+			logging.FromContext(r.Context()).Info("Campo de archivo no encontrado en el formulario", "form_key", formKey)
 			return nil, nil // Indica que no se subió archivo para este campo.
 		}
 		return nil, fmt.Errorf("error retrieving file '%s': %w", formKey, err)
@@ -140,68 +152,225 @@ func saveUploadedFile(r *http.Request, formKey string) (*string, error) {
 	defer file.Close()
 
 	originalFilename := filepath.Base(handler.Filename)
-	// Podríamos querer sanitizar el nombre aquí también si se usa en Drive
-	uniqueFilename := fmt.Sprintf("%d_%s", time.Now().UnixNano(), originalFilename)
-
-	// Crear metadatos del archivo para Google Drive
-	driveFile := &drive.File{
-		Name:    uniqueFilename,
-		Parents: []string{driveFolderID}, // ID de la carpeta donde guardar
+	// El tipo se comprueba por el contenido, no por el Content-Type que envía el cliente
+	cabecera := make([]byte, 512)
+	n, err := io.ReadFull(file, cabecera)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("error reading file '%s': %w", formKey, err)
 	}
-
-	// Subir el archivo
-	createdFile, err := driveService.Files.Create(driveFile).Media(file).Do()
+	if err := verificarTipoArchivo(originalFilename, cabecera[:n]); err != nil {
+		return nil, err
+	}
+	checksum := storage.NewChecksumReader(io.MultiReader(bytes.NewReader(cabecera[:n]), file))
+	key, err := backend.Put(r.Context(), originalFilename, checksum)
 	if err != nil {
 		// Intentar obtener más detalles del error si es posible
-		googleErr, ok := err.(*googleapi.Error)
-		if ok {
-			log.Printf("Error detallado de Google API al subir archivo: Código=%d, Mensaje=%s, Errores=%v", googleErr.Code, googleErr.Message, googleErr.Errors)
+		var googleErr *googleapi.Error
+		if errors.As(err, &googleErr) {
+			logging.FromContext(r.Context()).Error("Error detallado de Google API al subir archivo", "code", googleErr.Code, "message", googleErr.Message, "errors", googleErr.Errors)
 		}
-		return nil, fmt.Errorf("no se pudo crear el archivo en Google Drive: %w", err)
+		return nil, err
 	}
 
-	log.Printf("Archivo subido a Google Drive con ID: %s", createdFile.Id)
-	// Devolver el ID del archivo de Drive en lugar de la ruta local
-	return &createdFile.Id, nil
+	ref := storage.FormatRef(backend.Name(), key)
+	logging.FromContext(r.Context()).Info("Archivo subido al almacenamiento con referencia", "backend", backend.Name(), "ref", ref)
+	// Sin checksum el archivo solo queda fuera de la detección de duplicados: no es un error de la subida
+	sum, size := checksum.Sum()
+	if err := repository.SaveArchivoChecksum(r.Context(), db, ref, sum, size); err != nil {
+		logging.FromContext(r.Context()).Error("Error guardando checksum", "ref", ref, "error", err)
+	}
+	return &ref, nil
 }
 
-// removeFile elimina un archivo de Google Drive usando su ID
-func removeFile(fileID *string) error {
-	if fileID == nil || *fileID == "" {
-		log.Println("No se proporcionó fileID para eliminar, omitiendo.")
+// removeFile elimina un archivo de su backend de almacenamiento usando su referencia
+func removeFile(ctx context.Context, fileRef *string) error {
+	if fileRef == nil || *fileRef == "" {
+		logging.FromContext(ctx).Info("No se proporcionó archivo para eliminar, omitiendo")
 		return nil // No hay nada que eliminar
 	}
-	// Asegurarse de que el servicio de Drive esté inicializado
-	if driveService == nil {
-		return fmt.Errorf("el servicio de Google Drive no está inicializado para eliminar archivo")
+	backend, key, err := storage.Resolve(*fileRef)
+	if err != nil {
+		return fmt.Errorf("no se puede eliminar archivo '%s': %w", *fileRef, err)
+	}
+	// Los backends tratan un archivo inexistente como eliminado
+	if err := backend.Delete(ctx, key); err != nil {
+		logging.FromContext(ctx).Error("Error al eliminar archivo", "file_ref", *fileRef, "error", err)
+		return err
+	}
+
+	logging.FromContext(ctx).Info("Archivo eliminado correctamente", "file_ref", *fileRef)
+	return nil
+}
+
+// parseIncludeDeleted reads the admin-only ?includeDeleted=true flag.
+// It writes a 403 response and returns ok=false if a non-admin requests it.
+func parseIncludeDeleted(w http.ResponseWriter, r *http.Request) (includeDeleted bool, ok bool) {
+	if r.URL.Query().Get("includeDeleted") != "true" {
+		return false, true
+	}
+	if !middleware.IsAdmin(r) {
+		utils.RespondError(w, "includeDeleted requires admin role", http.StatusForbidden)
+		return false, false
 	}
+	return true, true
+}
+
+// umbralDuplicadoPorDefecto is the minimum name similarity (0..1) for two groups to be flagged as duplicates.
+const umbralDuplicadoPorDefecto = 0.6
 
-	err := driveService.Files.Delete(*fileID).Do()
+// DuplicadosConflictResponse is returned with 409 when a new group looks like an existing one.
+// Resend the request with forzar=true to create it anyway.
+type DuplicadosConflictResponse struct {
+	utils.ErrorResponse
+	Duplicados []models.GrupoDuplicado `json:"duplicados"`
+}
+
+// checkGrupoDuplicados looks for existing groups similar to g. Unless forzar is set, it writes a 409
+// response listing them and returns false; it also returns false after writing a 500 on query errors.
+func checkGrupoDuplicados(ctx context.Context, w http.ResponseWriter, db *sql.DB, g *models.Grupo, forzar bool) bool {
+	if forzar {
+		return true
+	}
+	duplicados, err := repository.FindGrupoDuplicateCandidates(ctx, db, g.Nombre, g.NumeroResolucion, umbralDuplicadoPorDefecto, 0)
 	if err != nil {
-		// Podríamos querer verificar si el error es "not found" y tratarlo como éxito
-		googleErr, ok := err.(*googleapi.Error)
-		if ok && googleErr.Code == 404 {
-			log.Printf("El archivo con ID '%s' no fue encontrado en Drive (quizás ya fue eliminado), considerando la operación exitosa.", *fileID)
-			return nil // El archivo no existe, objetivo cumplido.
+		logging.FromContext(ctx).Error("Error buscando grupos duplicados", "error", err)
+		utils.RespondError(w, "Error interno del servidor verificando duplicados", http.StatusInternalServerError)
+		return false
+	}
+	if len(duplicados) == 0 {
+		return true
+	}
+	resp := DuplicadosConflictResponse{
+		ErrorResponse: utils.NewErrorResponse("Existen grupos similares; envíe forzar=true para crearlo de todas formas", http.StatusConflict, nil),
+		Duplicados:    duplicados,
+	}
+	utils.RespondErrorEnvelope(w, http.StatusConflict, &resp)
+	return false
+}
+
+// verificarArchivo checks whether a group's stored file still exists.
+// It returns one of models.ArchivoDisponible, models.ArchivoRoto or models.ArchivoPendiente.
+func verificarArchivo(ctx context.Context, fileRef *string) string {
+	if fileRef == nil || *fileRef == "" {
+		return models.ArchivoPendiente
+	}
+	backend, key, err := storage.Resolve(*fileRef)
+	if err != nil {
+		logging.FromContext(ctx).Warn("No se puede verificar archivo", "file_ref", *fileRef, "error", err)
+		return models.ArchivoPendiente
+	}
+
+	existe, err := backend.Exists(ctx, key)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error verificando archivo", "file_ref", *fileRef, "error", err)
+		return models.ArchivoPendiente
+	}
+	if !existe {
+		return models.ArchivoRoto
+	}
+	return models.ArchivoDisponible
+}
+
+// filtrosGrupos are the search filters of GET /grupos.
+type filtrosGrupos struct {
+	q                   string // Búsqueda de texto completo (nombre, resolución, línea)
+	grupo               string
+	investigador        string
+	anios               []int
+	lineasInvestigacion []string
+	tiposInvestigacion  []string
+	facultades          []int
+	estado              string
+	estadoVigencia      string
+}
+
+// parseFiltrosGrupos reads the search filters of GET /grupos, answering 400 and returning ok=false
+// if any is invalid. Filtering by the unapproved estados (pendiente, rechazado) requires
+// authentication and is answered with 403 otherwise.
+func parseFiltrosGrupos(w http.ResponseWriter, r *http.Request) (filtrosGrupos, bool) {
+	f := filtrosGrupos{
+		q:            strings.TrimSpace(r.URL.Query().Get("q")),
+		grupo:        r.URL.Query().Get("grupo"),
+		investigador: r.URL.Query().Get("investigador"),
+		// año, lineaInvestigacion, tipoInvestigacion y facultad aceptan varios valores: ?año=2022,2023 o ?año=2022&año=2023
+		lineasInvestigacion: utils.QueryValues(r, "lineaInvestigacion"),
+		tiposInvestigacion:  utils.QueryValues(r, "tipoInvestigacion"),
+		estado:              r.URL.Query().Get("estado"),
+		estadoVigencia:      r.URL.Query().Get("estadoVigencia"),
+	}
+	for _, y := range utils.QueryValues(r, "año") {
+		year, err := strconv.Atoi(y)
+		if err != nil {
+			utils.RespondError(w, fmt.Sprintf("Invalid año filter: %q is not a year", y), http.StatusBadRequest)
+			return f, false
 		}
-		log.Printf("Error al eliminar archivo de Google Drive (ID: %s): %v", *fileID, err)
-		return fmt.Errorf("error eliminando archivo '%s' de Google Drive: %w", *fileID, err)
+		f.anios = append(f.anios, year)
+	}
+	var ok bool
+	if f.facultades, ok = parseFacultadesQuery(w, r); !ok {
+		return f, false
+	}
+	if f.estado != "" && !models.EsEstadoGrupoValido(f.estado) {
+		utils.RespondError(w, "Invalid estado filter: use activo, inactivo, en_renovacion, cerrado, pendiente or rechazado", http.StatusBadRequest)
+		return f, false
+	}
+	if _, autenticado := middleware.UserIDFromContext(r.Context()); f.estado != "" && !models.EsGrupoAprobado(f.estado) && !autenticado {
+		utils.RespondError(w, "Filtering by estado pendiente or rechazado requires authentication", http.StatusForbidden)
+		return f, false
 	}
+	if f.estadoVigencia != "" && !models.EsEstadoVigenciaValido(f.estadoVigencia) {
+		utils.RespondError(w, "Invalid estadoVigencia filter: use vigente, por_vencer, vencido or sin_vigencia", http.StatusBadRequest)
+		return f, false
+	}
+	return f, true
+}
 
-	log.Printf("Archivo con ID '%s' eliminado de Google Drive correctamente.", *fileID)
-	return nil
+// activos reports whether any filter is set.
+func (f filtrosGrupos) activos() bool {
+	return f.q != "" || f.grupo != "" || f.investigador != "" || len(f.anios) > 0 || len(f.lineasInvestigacion) > 0 || len(f.tiposInvestigacion) > 0 || len(f.facultades) > 0 || f.estado != "" || f.estadoVigencia != ""
+}
+
+// buscar runs the search for a page of groups.
+func (f filtrosGrupos) buscar(ctx context.Context, db *sql.DB, includeDeleted bool, limit, offset int) ([]models.GrupoWithInvestigadores, int, error) {
+	return repository.SearchGrupos(ctx, db, f.q, f.grupo, f.investigador, f.anios, f.lineasInvestigacion, f.tiposInvestigacion, f.facultades, f.estado, f.estadoVigencia, includeDeleted, limit, offset)
 }
 
 // GetGruposHandler handles fetching all groups or searching based on criteria with pagination.
 // It *always* returns groups with their associated investigators.
 func GetGruposHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Read search params
-		groupName := r.URL.Query().Get("grupo")
-		investigatorName := r.URL.Query().Get("investigador")
-		year := r.URL.Query().Get("año")
-		lineaInvestigacion := r.URL.Query().Get("lineaInvestigacion")
-		tipoInvestigacion := r.URL.Query().Get("tipoInvestigacion")
+		f, ok := parseFiltrosGrupos(w, r)
+		if !ok {
+			return
+		}
+		includeDeleted, ok := parseIncludeDeleted(w, r)
+		if !ok {
+			return
+		}
+		vista, ok := parseVistaGrupo(w, r, "investigadores")
+		if !ok {
+			return
+		}
+		if directorioNoModificado(w, r, db) {
+			return
+		}
+		if r.URL.Query().Has("ids") {
+			getGruposLote(w, r, db, includeDeleted, vista)
+			return
+		}
+
+		// Check if *any* search parameter is provided
+		isSearch := f.activos()
+
+		if limitAll(r) {
+			streamGrupos(w, r, vista, func(limit, offset int) ([]models.GrupoWithInvestigadores, int, error) {
+				if isSearch {
+					return f.buscar(r.Context(), db, includeDeleted, limit, offset)
+				}
+				return repository.GetAllGruposWithDetails(r.Context(), db, includeDeleted, limit, offset)
+			})
+			return
+		}
 
 		// Read pagination params
 		page, limit := utils.GetPaginationParams(r)
@@ -212,49 +381,90 @@ func GetGruposHandler(db *sql.DB) http.HandlerFunc {
 		var totalItems int
 		var err error
 
-		// Check if *any* search parameter is provided
-		isSearch := groupName != "" || investigatorName != "" || year != "" || lineaInvestigacion != "" || tipoInvestigacion != ""
-
 		if isSearch {
 			// Perform search: returns groups with investigators and roles
-			gruposConDetalles, totalItems, err = repository.SearchGrupos(db, groupName, investigatorName, year, lineaInvestigacion, tipoInvestigacion, limit, offset)
+			gruposConDetalles, totalItems, err = f.buscar(r.Context(), db, includeDeleted, limit, offset)
 		} else {
 			// Get all groups *with details* when no search parameters are present
-			gruposConDetalles, totalItems, err = repository.GetAllGruposWithDetails(db, limit, offset)
+			gruposConDetalles, totalItems, err = gruposConDetallesCached(r, db, includeDeleted, limit, offset)
 		}
 
 		if err != nil {
-			log.Printf("Error getting/searching groups with details: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Error("Error getting/searching groups with details", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
-
-		// Construir enlaces para los archivos ANTES de enviar la respuesta
-		for i := range gruposConDetalles {
-			// Asumiendo que GrupoWithInvestigadores tiene un campo Grupo (models.Grupo) que contiene Archivo
-			gruposConDetalles[i].Grupo.Archivo = constructDriveLink(gruposConDetalles[i].Grupo.Archivo)
+		data, err := vista.datos(gruposConDetalles)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error selecting group fields", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
 		}
 
-		// Calculate pagination metadata
-		totalPages := 0
-		if totalItems > 0 {
-			totalPages = int(math.Ceil(float64(totalItems) / float64(limit)))
-		}
-		pagination := models.PaginationMetadata{
-			TotalItems:  totalItems,
-			TotalPages:  totalPages,
-			CurrentPage: page,
-			Limit:       limit,
-		}
+		pagination := paginacion(w, r, totalItems, page, limit)
 
 		// Create paginated response with the detailed data
 		response := models.PaginatedResponse{
-			Data:       gruposConDetalles,
+			Data:       data,
 			Pagination: pagination,
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		utils.RespondJSON(w, http.StatusOK, response)
+	}
+}
+
+// getGruposLote answers GET /grupos?ids=1,5,9 with the requested groups and their investigators,
+// loaded in one query, so clients resolving memberships don't request them one by one.
+func getGruposLote(w http.ResponseWriter, r *http.Request, db *sql.DB, includeDeleted bool, vista vistaGrupo) {
+	ids, ok := parseIDsLote(w, r)
+	if !ok {
+		return
+	}
+	grupos, err := repository.GetGruposWithDetailsByIDs(r.Context(), db, ids, includeDeleted)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error getting groups by IDs", "error", err)
+		utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	data, err := vista.datos(grupos)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error selecting group fields", "error", err)
+		utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	encontrados := make(map[int]bool, len(grupos))
+	for _, g := range grupos {
+		encontrados[g.Grupo.ID] = true
+	}
+	utils.RespondJSON(w, http.StatusOK, models.LoteResponse{Data: data, NoEncontrados: idsNoEncontrados(ids, encontrados)})
+}
+
+// ExplainBusquedaGruposHandler reports the execution plan of the group search (GET
+// /admin/explain/grupos, admin only) for the filters and page of GET /grupos, with the indexes it
+// uses. ?analyze=true runs the query (EXPLAIN ANALYZE) to include actual row counts and times.
+func ExplainBusquedaGruposHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f, ok := parseFiltrosGrupos(w, r)
+		if !ok {
+			return
+		}
+		includeDeleted, ok := parseIncludeDeleted(w, r)
+		if !ok {
+			return
+		}
+		page, limit := utils.GetPaginationParams(r)
+
+		plan, err := repository.ExplainSearchGrupos(r.Context(), db, f.q, f.grupo, f.investigador, f.anios, f.lineasInvestigacion, f.tiposInvestigacion, f.facultades, f.estado, f.estadoVigencia, includeDeleted, limit, (page-1)*limit, r.URL.Query().Get("analyze") == "true")
+		if errors.Is(err, repository.ErrNoSoportado) {
+			utils.RespondError(w, "Not available with the SQLite backend", http.StatusNotImplemented)
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error explaining group search", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, plan)
 	}
 }
 
@@ -265,27 +475,71 @@ func GetGrupoHandler(db *sql.DB) http.HandlerFunc {
 		idStr := vars["id"]
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			http.Error(w, "Invalid group ID", http.StatusBadRequest)
+			utils.RespondError(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+		includeDeleted, ok := parseIncludeDeleted(w, r)
+		if !ok {
+			return
+		}
+		vista, ok := parseVistaGrupo(w, r)
+		if !ok {
 			return
 		}
 
-		grupo, err := repository.GetGrupoByID(db, id)
+		var grupo *models.Grupo
+		if includeDeleted {
+			grupo, err = repository.GetGrupoByIDIncludingDeleted(r.Context(), db, id)
+		} else {
+			grupo, err = repository.GetGrupoByID(r.Context(), db, id)
+		}
 		if err != nil {
-			log.Printf("Error getting group by ID: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Error("Error getting group by ID", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
-		if grupo == nil {
-			http.Error(w, "Grupo not found", http.StatusNotFound)
+		if grupo == nil || !grupoVisible(r.Context(), grupo) {
+			utils.RespondError(w, "Grupo not found", http.StatusNotFound)
 			return
 		}
 
-		// Construir el enlace antes de enviar
-		grupo.Archivo = constructDriveLink(grupo.Archivo)
+		// Opcional: verificar que el archivo siga existiendo en Drive. Cada verificación es una
+		// llamada al backend, por eso solo para usuarios autenticados
+		if r.URL.Query().Get("verificarArchivo") == "true" {
+			if _, ok := middleware.UserIDFromContext(r.Context()); !ok {
+				utils.RespondError(w, "verificarArchivo requires authentication", http.StatusUnauthorized)
+				return
+			}
+			respuesta := models.GrupoConEstadoArchivo{
+				Grupo:         *grupo,
+				ArchivoEstado: verificarArchivo(r.Context(), grupo.Archivo),
+			}
+			utils.RespondJSON(w, http.StatusOK, respuesta)
+			return
+		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(grupo)
+		if vista.activa {
+			respondVistaGrupo(w, r, vista, models.GrupoWithInvestigadores{Grupo: *grupo})
+			return
+		}
+		utils.RespondJSONWithETag(w, r, grupo)
+	}
+}
+
+// estadoRegistroGrupo returns the state a group registered by the caller starts in: activo for
+// admins and pendiente, awaiting approval (see AprobarGrupoHandler), for everyone else.
+func estadoRegistroGrupo(r *http.Request) string {
+	if middleware.IsAdmin(r) {
+		return models.EstadoGrupoActivo
+	}
+	return models.EstadoGrupoPendiente
+}
+
+// auditarRegistroGrupo records in the audit log that a new group was sent for approval.
+func auditarRegistroGrupo(db *sql.DB, r *http.Request, g *models.Grupo) {
+	if g.Estado == models.EstadoGrupoPendiente {
+		registrarAuditoria(db, r, models.AuditEnviarGrupo, "grupo", g.ID, g.Nombre)
 	}
 }
 
@@ -294,18 +548,22 @@ func GetGrupoHandler(db *sql.DB) http.HandlerFunc {
 func CreateGrupoHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Llama a la nueva función saveUploadedFile que usa Drive
-		fileID, err := saveUploadedFile(r, "archivo") // Ahora devuelve fileID o nil
+		fileID, err := saveUploadedFile(db, r, "archivo") // Ahora devuelve fileID o nil
 		if err != nil {
-			log.Printf("Error subiendo archivo a Drive durante creación de grupo: %v", err)
+			logging.FromContext(r.Context()).Error("Error subiendo archivo a Drive durante creación de grupo", "error", err)
 			// Distinguir errores de subida vs. errores de formulario
-			if strings.Contains(err.Error(), "parsing multipart form") || strings.Contains(err.Error(), "request body too large") {
-				http.Error(w, fmt.Sprintf("Error procesando formulario: %v", err), http.StatusBadRequest)
+			if limite, ok := utils.BodyTooLarge(err); ok {
+				utils.RespondBodyTooLarge(w, limite)
+			} else if errors.Is(err, errTipoArchivo) {
+				respondTipoArchivoInvalido(w)
+			} else if strings.Contains(err.Error(), "parsing multipart form") {
+				utils.RespondError(w, fmt.Sprintf("Error procesando formulario: %v", err), http.StatusBadRequest)
 			} else if strings.Contains(err.Error(), "Google Drive") {
 				// Error específico de Drive
-				http.Error(w, "Error interno del servidor al subir archivo a Google Drive", http.StatusInternalServerError)
+				utils.RespondError(w, "Error interno del servidor al subir archivo a Google Drive", http.StatusInternalServerError)
 			} else {
 				// Otro error inesperado durante saveUploadedFile
-				http.Error(w, "Error interno del servidor procesando el archivo", http.StatusInternalServerError)
+				utils.RespondError(w, "Error interno del servidor procesando el archivo", http.StatusInternalServerError)
 			}
 			return // Detener ejecución si hubo error en saveUploadedFile
 		}
@@ -321,43 +579,46 @@ func CreateGrupoHandler(db *sql.DB) http.HandlerFunc {
 
 		fechaStr := r.FormValue("fechaRegistro")
 		if fechaStr != "" {
-			parsedDate, err := time.Parse(timeFormat, fechaStr)
+			parsedDate, err := calendario.Parse(fechaStr)
 			if err != nil {
-				_ = removeFile(fileID) // Intentar eliminar el archivo de Drive si ya se subió
-				http.Error(w, fmt.Sprintf("Formato inválido para fechaRegistro. Use %s", timeFormat), http.StatusBadRequest)
+				_ = removeFile(r.Context(), fileID) // Intentar eliminar el archivo de Drive si ya se subió
+				utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity, validation.FechaInvalida("fechaRegistro"))
 				return
 			}
 			g.FechaRegistro = parsedDate
 		}
+		idFacultad, ok := formFacultad(w, r)
+		if !ok {
+			_ = removeFile(r.Context(), fileID)
+			return
+		}
+		g.IDFacultad = sinCero(idFacultad)
 
-		if g.Nombre == "" || g.NumeroResolucion == "" || g.LineaInvestigacion == "" || g.TipoInvestigacion == "" {
-			_ = removeFile(fileID) // Intentar eliminar el archivo de Drive si ya se subió
-			http.Error(w, "Faltan campos de texto requeridos: nombre, numeroResolucion, lineaInvestigacion, tipoInvestigacion", http.StatusBadRequest)
+		if !validar(w, &g) || !validarTipoInvestigacion(w, r, db, &g.TipoInvestigacion, "") {
+			_ = removeFile(r.Context(), fileID) // Intentar eliminar el archivo de Drive si ya se subió
 			return
 		}
-		if g.FechaRegistro.IsZero() {
-			_ = removeFile(fileID) // Intentar eliminar el archivo de Drive si ya se subió
-			http.Error(w, fmt.Sprintf("Falta campo requerido o inválido: fechaRegistro (use formato %s)", timeFormat), http.StatusBadRequest)
+
+		// Evitar registrar dos veces el mismo grupo (nombre parecido o misma resolución)
+		if !checkGrupoDuplicados(r.Context(), w, db, &g, r.FormValue("forzar") == "true") {
+			_ = removeFile(r.Context(), fileID) // Intentar eliminar el archivo de Drive si ya se subió
 			return
 		}
 
 		// Asignar el fileID (puede ser nil) al campo Archivo del grupo
 		g.Archivo = fileID
+		g.Estado = estadoRegistroGrupo(r)
 
 		// Intentar crear el grupo en la BD
-		if err := repository.CreateGrupo(db, &g); err != nil {
-			log.Printf("Error creando grupo en repositorio: %v", err)
-			_ = removeFile(fileID) // Si falla la BD, intentar eliminar el archivo de Drive
-			http.Error(w, "Error interno del servidor guardando grupo", http.StatusInternalServerError)
+		if err := repository.CreateGrupo(r.Context(), db, &g); err != nil {
+			_ = removeFile(r.Context(), fileID) // Si falla la BD, intentar eliminar el archivo de Drive
+			respondRepoError(w, r, err, "Error creando grupo en repositorio")
 			return
 		}
+		auditarRegistroGrupo(db, r, &g)
 
 		// Si todo fue bien:
-		// Construir el enlace ANTES de enviar la respuesta
-		g.Archivo = constructDriveLink(g.Archivo)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(g) // Devolver el grupo con el enlace (o nil)
+		utils.RespondJSON(w, http.StatusCreated, g) // Devolver el grupo con el enlace (o nil)
 	}
 }
 
@@ -369,34 +630,38 @@ func UpdateGrupoHandler(db *sql.DB) http.HandlerFunc {
 		idStr := vars["id"]
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			http.Error(w, "ID de grupo inválido", http.StatusBadRequest)
+			utils.RespondError(w, "ID de grupo inválido", http.StatusBadRequest)
 			return
 		}
 
 		// 1. Obtener el grupo existente para saber el ID del archivo antiguo (si existe)
-		existingGrupo, err := repository.GetGrupoByID(db, id)
+		existingGrupo, err := repository.GetGrupoByID(r.Context(), db, id)
 		if err != nil {
-			log.Printf("Error obteniendo grupo por ID para actualizar: %v", err)
-			http.Error(w, "Error interno del servidor", http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Error("Error obteniendo grupo por ID para actualizar", "error", err)
+			utils.RespondError(w, "Error interno del servidor", http.StatusInternalServerError)
 			return
 		}
 		if existingGrupo == nil {
-			http.Error(w, "Grupo no encontrado para actualizar", http.StatusNotFound)
+			utils.RespondError(w, "Grupo no encontrado para actualizar", http.StatusNotFound)
 			return
 		}
 		oldFileID := existingGrupo.Archivo // Guardamos el ID del archivo antiguo (puede ser nil)
 
 		// 2. Intentar subir un nuevo archivo (usando la función modificada)
-		newFileID, err := saveUploadedFile(r, "archivo") // Devuelve el nuevo ID de Drive o nil
+		newFileID, err := saveUploadedFile(db, r, "archivo") // Devuelve el nuevo ID de Drive o nil
 		if err != nil {
-			log.Printf("Error subiendo archivo a Drive durante actualización de grupo: %v", err)
+			logging.FromContext(r.Context()).Error("Error subiendo archivo a Drive durante actualización de grupo", "error", err)
 			// Manejar errores de subida como en CreateGrupoHandler
-			if strings.Contains(err.Error(), "parsing multipart form") || strings.Contains(err.Error(), "request body too large") {
-				http.Error(w, fmt.Sprintf("Error procesando formulario: %v", err), http.StatusBadRequest)
+			if limite, ok := utils.BodyTooLarge(err); ok {
+				utils.RespondBodyTooLarge(w, limite)
+			} else if errors.Is(err, errTipoArchivo) {
+				respondTipoArchivoInvalido(w)
+			} else if strings.Contains(err.Error(), "parsing multipart form") {
+				utils.RespondError(w, fmt.Sprintf("Error procesando formulario: %v", err), http.StatusBadRequest)
 			} else if strings.Contains(err.Error(), "Google Drive") {
-				http.Error(w, "Error interno del servidor al subir archivo a Google Drive", http.StatusInternalServerError)
+				utils.RespondError(w, "Error interno del servidor al subir archivo a Google Drive", http.StatusInternalServerError)
 			} else {
-				http.Error(w, "Error interno del servidor procesando el archivo", http.StatusInternalServerError)
+				utils.RespondError(w, "Error interno del servidor procesando el archivo", http.StatusInternalServerError)
 			}
 			return // Detener si la subida falló
 		}
@@ -412,10 +677,10 @@ func UpdateGrupoHandler(db *sql.DB) http.HandlerFunc {
 
 		fechaStr := r.FormValue("fechaRegistro")
 		if fechaStr != "" {
-			parsedDate, err := time.Parse(timeFormat, fechaStr)
+			parsedDate, err := calendario.Parse(fechaStr)
 			if err != nil {
-				_ = removeFile(newFileID) // Si hubo error de fecha, eliminar el nuevo archivo si se subió
-				http.Error(w, fmt.Sprintf("Formato inválido para fechaRegistro. Use %s", timeFormat), http.StatusBadRequest)
+				_ = removeFile(r.Context(), newFileID) // Si hubo error de fecha, eliminar el nuevo archivo si se subió
+				utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity, validation.FechaInvalida("fechaRegistro"))
 				return
 			}
 			updatedGrupo.FechaRegistro = parsedDate
@@ -437,6 +702,20 @@ func UpdateGrupoHandler(db *sql.DB) http.HandlerFunc {
 		if updatedGrupo.TipoInvestigacion == "" {
 			updatedGrupo.TipoInvestigacion = existingGrupo.TipoInvestigacion
 		}
+		idFacultad, ok := formFacultad(w, r)
+		if !ok {
+			_ = removeFile(r.Context(), newFileID)
+			return
+		}
+		updatedGrupo.IDFacultad = existingGrupo.IDFacultad
+		if idFacultad != nil {
+			updatedGrupo.IDFacultad = sinCero(idFacultad)
+		}
+		updatedGrupo.FechaVencimiento, updatedGrupo.EstadoVigencia = existingGrupo.FechaVencimiento, existingGrupo.EstadoVigencia
+		if !validar(w, &updatedGrupo) || !validarTipoInvestigacion(w, r, db, &updatedGrupo.TipoInvestigacion, existingGrupo.TipoInvestigacion) {
+			_ = removeFile(r.Context(), newFileID)
+			return
+		}
 
 		// 4. Determinar el ID del archivo final y si hay que borrar el antiguo
 		var fileIDToDelete *string = nil
@@ -455,130 +734,311 @@ func UpdateGrupoHandler(db *sql.DB) http.HandlerFunc {
 		// Si se quisiera eso, se necesitaría un campo adicional en el form, ej: "eliminarArchivo=true".
 
 		// 5. Actualizar el grupo en la base de datos
-		if err := repository.UpdateGrupo(db, &updatedGrupo); err != nil {
-			log.Printf("Error actualizando grupo en repositorio: %v", err)
-			// Si falla la BD, NO borrar el archivo antiguo, pero SÍ borrar el nuevo si se subió uno.
-			_ = removeFile(newFileID)
-			http.Error(w, "Error interno del servidor actualizando grupo", http.StatusInternalServerError)
+		if err := repository.UpdateGrupo(r.Context(), db, &updatedGrupo); err != nil {
+			// Si falla la BD (o el grupo se eliminó entretanto), NO borrar el archivo antiguo, pero SÍ
+			// borrar el nuevo si se subió uno.
+			_ = removeFile(r.Context(), newFileID)
+			respondRepoError(w, r, err, "Error actualizando grupo en repositorio", "id", id)
 			return
 		}
 
-		// 6. Si la actualización de la BD fue exitosa, borrar el archivo antiguo (si aplica)
+		// 6. Si la actualización de la BD fue exitosa, borrar el archivo antiguo (si aplica), salvo que
+		// siga siendo el PDF de una de sus resoluciones
+		if fileIDToDelete != nil {
+			if enUso, err := repository.ResolucionUsaArchivo(r.Context(), db, *fileIDToDelete); err != nil || enUso {
+				fileIDToDelete = nil
+			}
+		}
 		if fileIDToDelete != nil {
-			err := removeFile(fileIDToDelete) // Usar la función modificada
+			err := removeFile(r.Context(), fileIDToDelete) // Usar la función modificada
 			if err != nil {
 				// Solo registrar advertencia, la actualización principal fue exitosa.
-				log.Printf("Advertencia: Error eliminando archivo antiguo de Drive '%s' después de actualizar grupo: %v", *fileIDToDelete, err)
+				logging.FromContext(r.Context()).Warn("Error eliminando archivo antiguo de Drive después de actualizar grupo", "file_id_to_delete", *fileIDToDelete, "error", err)
 			}
 		}
 
 		// 7. Enviar respuesta exitosa
-		// Construir el enlace ANTES de enviar la respuesta
-		updatedGrupo.Archivo = constructDriveLink(updatedGrupo.Archivo)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(updatedGrupo) // Devolver el grupo actualizado con el enlace correcto
+		utils.RespondJSON(w, http.StatusOK, updatedGrupo) // Devolver el grupo actualizado con el enlace correcto
 	}
 }
 
-// DeleteGrupoHandler handles deleting a group by ID.
-func DeleteGrupoHandler(db *sql.DB) http.HandlerFunc {
+// CambiarEstadoGrupoHandler moves a group to another lifecycle state, validating the transition.
+func CambiarEstadoGrupoHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		idStr := vars["id"]
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			http.Error(w, "ID de grupo inválido", http.StatusBadRequest)
+			utils.RespondError(w, "ID de grupo inválido", http.StatusBadRequest)
 			return
 		}
 
-		// ANTES de eliminar el grupo de la BD, obtener su info para saber qué archivo borrar
-		grupo, err := repository.GetGrupoByID(db, id)
+		var body models.CambiarEstadoGrupoRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body")
+			return
+		}
+		if !validar(w, &body) {
+			return
+		}
+
+		grupo, err := repository.GetGrupoByID(r.Context(), db, id)
 		if err != nil {
-			// Si no se puede obtener el grupo, podría no existir o haber otro error
-			log.Printf("Error obteniendo grupo %d antes de eliminar: %v", id, err)
-			// Decidir si continuar o no. Si el grupo no existe, DeleteGrupo probablemente falle igual.
-			// Podríamos devolver un error aquí o dejar que DeleteGrupo maneje el not found.
-			// Por seguridad, si no podemos obtener la info, no intentamos borrar archivo de Drive.
-			// Dejemos que DeleteGrupo maneje la lógica de la BD.
+			logging.FromContext(r.Context()).Error("Error obteniendo grupo para cambiar estado", "id", id, "error", err)
+			utils.RespondError(w, "Error interno del servidor", http.StatusInternalServerError)
+			return
+		}
+		if grupo == nil {
+			utils.RespondError(w, "Grupo no encontrado", http.StatusNotFound)
+			return
+		}
+		if !models.PuedeTransicionarEstadoGrupo(grupo.Estado, body.Estado) {
+			utils.RespondError(w, fmt.Sprintf("Transición de estado no permitida: %s -> %s", grupo.Estado, body.Estado), http.StatusConflict)
+			return
 		}
 
-		// Intentar eliminar el grupo de la base de datos
-		if err := repository.DeleteGrupo(db, id); err != nil {
-			// Comprobar si el error es porque no se encontró el grupo
-			// (Esta comprobación depende de cómo DeleteGrupo señale "not found")
-			// if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "not found") {
-			//	 http.Error(w, "Grupo no encontrado", http.StatusNotFound)
-			//	 return
-			// }
-			// Si es otro error:
-			log.Printf("Error eliminando grupo %d de la BD: %v", id, err)
-			http.Error(w, "Error interno del servidor al eliminar grupo", http.StatusInternalServerError)
+		if err := repository.UpdateGrupoEstado(r.Context(), db, id, body.Estado); err != nil {
+			respondRepoError(w, r, err, "Error cambiando estado del grupo", "id", id)
 			return
 		}
+		grupo.Estado = body.Estado
 
-		// Si la eliminación de la BD fue exitosa Y pudimos obtener la info del grupo antes:
-		if grupo != nil && grupo.Archivo != nil && *grupo.Archivo != "" {
-			log.Printf("Grupo %d eliminado de la BD, intentando eliminar archivo de Drive con ID: %s", id, *grupo.Archivo)
-			err := removeFile(grupo.Archivo) // Usar la función modificada
-			if err != nil {
-				// Solo registrar advertencia, la eliminación del grupo fue exitosa.
-				log.Printf("Advertencia: Error eliminando archivo de Drive '%s' después de eliminar grupo %d: %v", *grupo.Archivo, id, err)
-			}
-		} else if grupo != nil {
-			log.Printf("Grupo %d eliminado de la BD, no tenía archivo asociado en Drive.", id)
-		} else {
-			log.Printf("Grupo %d eliminado de la BD, no se pudo obtener info previa para eliminar archivo de Drive asociado.", id)
+		utils.RespondJSON(w, http.StatusOK, grupo)
+	}
+}
+
+// AprobarGrupoHandler approves the registration of a pendiente group (admin only), which becomes
+// activo and public. The optional "comentario" is kept in the audit log.
+func AprobarGrupoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+
+		var body models.RevisarGrupoRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+			utils.RespondDecodeError(w, err, "Invalid request body")
+			return
+		}
+		if !validar(w, &body) {
+			return
+		}
+
+		grupo, err := repository.RevisarRegistroGrupo(r.Context(), db, id, models.EstadoGrupoActivo)
+		if err != nil {
+			respondRepoError(w, r, err, "Error approving group", "id", id)
+			return
+		}
+		registrarAuditoria(db, r, models.AuditAprobarGrupo, "grupo", id, strings.TrimSpace(body.Comentario))
+		utils.RespondJSON(w, http.StatusOK, grupo)
+	}
+}
+
+// RechazarGrupoHandler rejects the registration of a pendiente group (admin only). The
+// "comentario" explaining why is required and kept in the audit log.
+func RechazarGrupoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+
+		var body models.RevisarGrupoRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body")
+			return
+		}
+		body.Comentario = strings.TrimSpace(body.Comentario)
+		if body.Comentario == "" {
+			utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity, utils.FieldError{
+				Campo:   "comentario",
+				Codigo:  "obligatorio",
+				Mensaje: "comentario es obligatorio para rechazar un grupo",
+			})
+			return
+		}
+		if !validar(w, &body) {
+			return
+		}
+
+		grupo, err := repository.RevisarRegistroGrupo(r.Context(), db, id, models.EstadoGrupoRechazado)
+		if err != nil {
+			respondRepoError(w, r, err, "Error rejecting group", "id", id)
+			return
+		}
+		registrarAuditoria(db, r, models.AuditRechazarGrupo, "grupo", id, body.Comentario)
+		utils.RespondJSON(w, http.StatusOK, grupo)
+	}
+}
+
+// DeleteGrupoHandler handles soft-deleting a group by ID.
+// The group's Drive file and memberships are kept so it can be restored later.
+func DeleteGrupoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		idStr := vars["id"]
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			utils.RespondError(w, "ID de grupo inválido", http.StatusBadRequest)
+			return
+		}
+
+		grupo, err := repository.GetGrupoByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error obteniendo grupo antes de eliminar", "id", id, "error", err)
+			utils.RespondError(w, "Error interno del servidor al eliminar grupo", http.StatusInternalServerError)
+			return
+		}
+		if grupo == nil {
+			utils.RespondError(w, "Grupo no encontrado", http.StatusNotFound)
+			return
 		}
 
+		if err := repository.DeleteGrupo(r.Context(), db, id); err != nil {
+			respondRepoError(w, r, err, "Error eliminando grupo de la BD", "id", id)
+			return
+		}
+		logging.FromContext(r.Context()).Info("Grupo eliminado (soft delete); su archivo de Drive se conserva para poder restaurarlo", "id", id)
+
 		w.WriteHeader(http.StatusNoContent) // Éxito
 	}
 }
 
-// GetGrupoDetailsHandler retrieves a group's details along with its associated investigators.
-func GetGrupoDetailsHandler(db *sql.DB) http.HandlerFunc {
+// RestoreGrupoHandler restores a soft-deleted group.
+func RestoreGrupoHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		idStr := vars["id"]
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			http.Error(w, "Invalid group ID", http.StatusBadRequest)
+			utils.RespondError(w, "ID de grupo inválido", http.StatusBadRequest)
 			return
 		}
 
-		grupoWithInvestigadores, err := repository.GetGrupoDetails(db, id)
+		grupo, err := repository.GetGrupoByIDIncludingDeleted(r.Context(), db, id)
 		if err != nil {
-			log.Printf("Error getting group details from repository: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Error("Error obteniendo grupo para restaurar", "id", id, "error", err)
+			utils.RespondError(w, "Error interno del servidor", http.StatusInternalServerError)
+			return
+		}
+		if grupo == nil {
+			utils.RespondError(w, "Grupo no encontrado", http.StatusNotFound)
+			return
+		}
+		if grupo.DeletedAt == nil {
+			utils.RespondError(w, "El grupo no está eliminado", http.StatusConflict)
 			return
 		}
 
-		if grupoWithInvestigadores == nil {
-			http.Error(w, "Grupo not found", http.StatusNotFound)
+		if err := repository.RestoreGrupo(r.Context(), db, id); err != nil {
+			respondRepoError(w, r, err, "Error restaurando grupo", "id", id)
 			return
 		}
 
-		// Construir el enlace antes de enviar
-		if grupoWithInvestigadores != nil {
-			// Asumiendo que GrupoWithInvestigadores tiene un campo Grupo (models.Grupo) que contiene Archivo
-			grupoWithInvestigadores.Grupo.Archivo = constructDriveLink(grupoWithInvestigadores.Grupo.Archivo)
+		grupo, err = repository.GetGrupoByID(r.Context(), db, id)
+		if err != nil || grupo == nil {
+			logging.FromContext(r.Context()).Error("Error obteniendo grupo después de restaurar", "id", id, "error", err)
+			utils.RespondError(w, "Error interno del servidor", http.StatusInternalServerError)
+			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(grupoWithInvestigadores)
+		utils.RespondJSON(w, http.StatusOK, grupo)
 	}
 }
 
-// Struct to represent the investigator relationship in the combined creation request
-type InvestigatorRelationshipRequest struct {
-	IDInvestigador int    `json:"idInvestigador"`
-	TipoRelacion   string `json:"tipoRelacion"`
+// GetGrupoDetailsHandler retrieves a group's details along with its associated investigators and
+// how many there are of each tipo. ?tipo= lists only the investigators of that tipo; the counts
+// still cover all of them.
+func GetGrupoDetailsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		idStr := vars["id"]
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			utils.RespondError(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+		vista, ok := parseVistaGrupo(w, r, "investigadores", "publicaciones", "proyectos")
+		if !ok {
+			return
+		}
+		tipo := r.URL.Query().Get("tipo")
+		if tipo != "" && !models.EsTipoIntegranteValido(tipo) {
+			utils.RespondError(w, "Invalid tipo parameter", http.StatusBadRequest)
+			return
+		}
+
+		grupoWithInvestigadores, err := cache.Load(r.Context(), directorioCache, fmt.Sprintf("grupo:%d", id), func() (*models.GrupoWithInvestigadores, error) {
+			return repository.GetGrupoDetails(r.Context(), db, id)
+		})
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting group details from repository", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if grupoWithInvestigadores == nil || !grupoVisible(r.Context(), &grupoWithInvestigadores.Grupo) {
+			utils.RespondError(w, "Grupo not found", http.StatusNotFound)
+			return
+		}
+		if tipo != "" {
+			// Filter a copy: the cached details are shared between requests
+			filtrado := *grupoWithInvestigadores
+			filtrado.Investigadores = []models.InvestigadorConRol{}
+			for _, inv := range grupoWithInvestigadores.Investigadores {
+				if inv.Tipo == tipo {
+					filtrado.Investigadores = append(filtrado.Investigadores, inv)
+				}
+			}
+			grupoWithInvestigadores = &filtrado
+		}
+
+		if vista.activa {
+			respondVistaGrupo(w, r, vista, *grupoWithInvestigadores)
+			return
+		}
+		utils.RespondJSONWithETag(w, r, grupoWithInvestigadores)
+	}
 }
 
-// Struct to represent the combined group and details creation request body
-type CreateGrupoWithDetailsRequest struct {
-	models.Grupo   `json:"grupo"`
-	Investigadores []InvestigatorRelationshipRequest `json:"investigadores"`
+// GetGrupoReportHandler generates a printable PDF report of a group with its member roster.
+func GetGrupoReportHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		idStr := vars["id"]
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			utils.RespondError(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+
+		grupoWithInvestigadores, err := repository.GetGrupoDetails(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting group details for report", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if grupoWithInvestigadores == nil || !grupoVisible(r.Context(), &grupoWithInvestigadores.Grupo) {
+			utils.RespondError(w, "Grupo not found", http.StatusNotFound)
+			return
+		}
+
+		// Render to a buffer first so a PDF error can still produce a proper HTTP error
+		var buf bytes.Buffer
+		if err := reports.WriteGrupoPDF(&buf, grupoWithInvestigadores); err != nil {
+			logging.FromContext(r.Context()).Error("Error generating PDF report for group", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error generating report", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"grupo_%d.pdf\"", id))
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		w.Write(buf.Bytes())
+	}
 }
 
 // Handler for creating a group with associated investigator details
@@ -586,83 +1046,146 @@ type CreateGrupoWithDetailsRequest struct {
 // La subida de archivos debería hacerse ANTES con CreateGrupoHandler
 // y luego pasar el ID del archivo (o nil) en requestBody.Grupo.Archivo.
 // La lógica actual de este handler NO interactúa con saveUploadedFile.
+// El grupo y sus integrantes se crean en una sola transacción (repository.CreateGrupoWithDetails).
 func CreateGrupoWithDetailsHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var requestBody CreateGrupoWithDetailsRequest
-		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		var requestBody models.CreateGrupoWithDetailsRequest
+		if !decodeJSON(w, r, &requestBody, "Invalid request body") {
+			return
+		}
+		vistos := make(map[int]bool, len(requestBody.Investigadores))
+		for i, inv := range requestBody.Investigadores {
+			if vistos[inv.IDInvestigador] {
+				utils.RespondError(w, fmt.Sprintf("El investigador %d aparece más de una vez", inv.IDInvestigador), http.StatusBadRequest)
+				return
+			}
+			vistos[inv.IDInvestigador] = true
+			if strings.TrimSpace(inv.TipoRelacion) == "" {
+				requestBody.Investigadores[i].TipoRelacion = models.RolIntegrante
+			}
+		}
+		if !validar(w, &requestBody) || !validarTipoInvestigacion(w, r, db, &requestBody.TipoInvestigacion, "") {
+			return
+		}
+		if err := models.ValidarCoordinadorUnico(requestBody.Investigadores); err != nil {
+			utils.RespondError(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		// Start a transaction
-		tx, err := db.Begin()
-		if err != nil {
-			log.Printf("Error starting transaction: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		if !checkGrupoDuplicados(r.Context(), w, db, &requestBody.Grupo, r.URL.Query().Get("forzar") == "true") {
 			return
 		}
-		// Use a deferred function for commit/rollback based on error
-		defer func() {
-			if p := recover(); p != nil {
-				tx.Rollback()
-				panic(p) // Re-panic after rollback
-			} else if err != nil {
-				// Log the error that caused the rollback
-				log.Printf("Rolling back transaction due to error: %v", err)
-				tx.Rollback() // Rollback on any error
-			} else {
-				err = tx.Commit() // Commit otherwise
-				if err != nil {
-					log.Printf("Error committing transaction: %v", err)
-					// Don't send HTTP error here as response might have already been written
-				}
-			}
-		}()
-
-		// Create the group within the transaction using QueryRow with RETURNING
-		grupoToCreate := requestBody.Grupo // Ya debería incluir el ID de Drive si se subió antes
-		// Use lowercase snake_case names and $n placeholders
-		groupInsertQuery := `INSERT INTO grupo (nombre, numeroResolucion, lineaInvestigacion, tipoInvestigacion, fechaRegistro, archivo) VALUES ($1, $2, $3, $4, $5, $6) RETURNING idGrupo`
-		var grupoID int64 // Use int64 for Scan with RETURNING
-
-		// Asegurarse de pasar nil si Archivo es nil o el valor si existe
-		var archivoID interface{}
-		if grupoToCreate.Archivo != nil {
-			archivoID = *grupoToCreate.Archivo
-		} else {
-			archivoID = nil
+
+		grupo := requestBody.Grupo // Ya debería incluir el ID de Drive si se subió antes
+		grupo.IDFacultad = sinCero(grupo.IDFacultad)
+		grupo.Estado = estadoRegistroGrupo(r)
+		err := repository.WithTx(r.Context(), db, func(tx *sql.Tx) error {
+			return repository.CreateGrupoWithDetails(r.Context(), tx, &grupo, requestBody.Investigadores)
+		})
+		switch {
+		case errors.Is(err, repository.ErrInvestigadorNoExiste):
+			utils.RespondError(w, err.Error(), http.StatusBadRequest)
+			return
+		case respondMembresiaError(w, err):
+			return
+		case err != nil:
+			respondRepoError(w, r, err, "Error creating group with details")
+			return
 		}
+		auditarRegistroGrupo(db, r, &grupo)
+
+		utils.RespondJSON(w, http.StatusCreated, grupo)
+	}
+}
 
-		err = tx.QueryRow(groupInsertQuery, grupoToCreate.Nombre, grupoToCreate.NumeroResolucion, grupoToCreate.LineaInvestigacion, grupoToCreate.TipoInvestigacion, grupoToCreate.FechaRegistro, archivoID).Scan(&grupoID)
+// UpdateGrupoWithDetailsHandler updates a group and replaces its whole member list atomically.
+// Empty group fields keep their current value; the group's file is not touched (use PUT /grupos/{id}).
+func UpdateGrupoWithDetailsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
 		if err != nil {
-			// Error is logged and transaction rolled back by defer
-			log.Printf("Error inserting group in transaction: %v", err)
-			http.Error(w, "Internal server error during group creation", http.StatusInternalServerError)
+			utils.RespondError(w, "Invalid group ID", http.StatusBadRequest)
 			return
 		}
 
-		// Create the detailed relationships within the transaction using Exec
-		// Use lowercase snake_case names and $n placeholders
-		detailInsertQuery := `INSERT INTO Grupo_Investigador (idGrupo, idInvestigador, tipo_relacion) VALUES ($1, $2, $3)`
-		for _, invRel := range requestBody.Investigadores {
-			_, err = tx.Exec(detailInsertQuery, grupoID, invRel.IDInvestigador, invRel.TipoRelacion)
-			if err != nil {
-				// Error is logged and transaction rolled back by defer
-				log.Printf("Error inserting group-investigator detail in transaction: %v", err)
-				http.Error(w, "Internal server error during detail creation", http.StatusInternalServerError)
+		var requestBody models.CreateGrupoWithDetailsRequest
+		if !decodeJSON(w, r, &requestBody, "Invalid request body") {
+			return
+		}
+
+		vistos := make(map[int]bool, len(requestBody.Investigadores))
+		for i, inv := range requestBody.Investigadores {
+			if vistos[inv.IDInvestigador] {
+				utils.RespondError(w, fmt.Sprintf("El investigador %d aparece más de una vez", inv.IDInvestigador), http.StatusBadRequest)
 				return
 			}
+			vistos[inv.IDInvestigador] = true
+			if strings.TrimSpace(inv.TipoRelacion) == "" {
+				requestBody.Investigadores[i].TipoRelacion = models.RolIntegrante
+			}
+		}
+		if err := models.ValidarCoordinadorUnico(requestBody.Investigadores); err != nil {
+			utils.RespondError(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
-		// If we reach here without error, the defer func will handle the commit.
+		existingGrupo, err := repository.GetGrupoByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting group for update", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if existingGrupo == nil {
+			utils.RespondError(w, "Group not found", http.StatusNotFound)
+			return
+		}
 
-		// Prepare the response
-		grupoToCreate.ID = int(grupoID) // Convert int64 back to int for the response model
-		// Construir el enlace ANTES de enviar la respuesta
-		grupoToCreate.Archivo = constructDriveLink(grupoToCreate.Archivo)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(grupoToCreate)
+		grupo := *existingGrupo
+		if requestBody.Nombre != "" {
+			grupo.Nombre = requestBody.Nombre
+		}
+		if requestBody.NumeroResolucion != "" {
+			grupo.NumeroResolucion = requestBody.NumeroResolucion
+		}
+		if requestBody.LineaInvestigacion != "" {
+			grupo.LineaInvestigacion = requestBody.LineaInvestigacion
+		}
+		if requestBody.TipoInvestigacion != "" {
+			grupo.TipoInvestigacion = requestBody.TipoInvestigacion
+		}
+		if !requestBody.FechaRegistro.IsZero() {
+			grupo.FechaRegistro = requestBody.FechaRegistro
+		}
+		if requestBody.IDFacultad != nil {
+			grupo.IDFacultad = sinCero(requestBody.IDFacultad)
+		}
+		requestBody.Grupo = grupo
+		if !validar(w, &requestBody) || !validarTipoInvestigacion(w, r, db, &requestBody.TipoInvestigacion, existingGrupo.TipoInvestigacion) {
+			return
+		}
+
+		err = repository.UpdateGrupoWithDetails(r.Context(), db, &requestBody.Grupo, requestBody.Investigadores)
+		switch {
+		case errors.Is(err, repository.ErrGrupoNoEncontrado):
+			utils.RespondError(w, "Group not found", http.StatusNotFound)
+			return
+		case errors.Is(err, repository.ErrInvestigadorNoExiste):
+			utils.RespondError(w, err.Error(), http.StatusBadRequest)
+			return
+		case respondMembresiaError(w, err):
+			return
+		case err != nil:
+			respondRepoError(w, r, err, "Error updating group with details", "id", id)
+			return
+		}
+
+		detalles, err := repository.GetGrupoDetails(r.Context(), db, id)
+		if err != nil || detalles == nil {
+			logging.FromContext(r.Context()).Error("Error getting details of updated group", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, detalles)
 	}
 }
 
@@ -673,122 +1196,295 @@ func GetGruposByInvestigadorHandler(db *sql.DB) http.HandlerFunc {
 		idStr := vars["idInvestigador"]
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			http.Error(w, "ID de investigador inválido", http.StatusBadRequest)
+			utils.RespondError(w, "ID de investigador inválido", http.StatusBadRequest)
 			return
 		}
 
-		gruposConIntegrantes, err := repository.GetGruposByInvestigadorID(db, id)
+		gruposConIntegrantes, err := repository.GetGruposByInvestigadorID(r.Context(), db, id)
 		if err != nil {
-			log.Printf("Error obteniendo grupos por investigador: %v", err)
-			http.Error(w, "Error interno del servidor", http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Error("Error obteniendo grupos por investigador", "error", err)
+			utils.RespondError(w, "Error interno del servidor", http.StatusInternalServerError)
 			return
 		}
 
-		// Enriquecer la respuesta para incluir los integrantes con su rol Y CONSTRUIR ENLACES
-		var respuesta []map[string]interface{}
-		for _, grupoConInt := range gruposConIntegrantes {
-			// Asumiendo que 'grupoConInt["grupo"]' es un tipo que tiene un campo 'Archivo'
-			// Necesitamos hacer type assertion y modificar el campo.
-			if grupoData, ok := grupoConInt["grupo"].(models.Grupo); ok { // Ajusta models.Grupo si es otro tipo
-				grupoData.Archivo = constructDriveLink(grupoData.Archivo)
-				grupoConInt["grupo"] = grupoData // Reasignar el grupo modificado al mapa
-			} else if grupoDataPtr, ok := grupoConInt["grupo"].(*models.Grupo); ok && grupoDataPtr != nil { // Caso puntero
-				grupoDataPtr.Archivo = constructDriveLink(grupoDataPtr.Archivo)
-				// No es necesario reasignar porque modificamos el puntero
-			} else {
-				// Manejar el caso en que la aserción falle o el tipo sea inesperado
-				log.Printf("Advertencia: No se pudo convertir grupo a tipo esperado para construir enlace en GetGruposByInvestigadorHandler: %T", grupoConInt["grupo"])
-			}
-
-			respuesta = append(respuesta, map[string]interface{}{
-				"grupo":       grupoConInt["grupo"], // Ya tiene el enlace construido
-				"integrantes": grupoConInt["integrantes"],
-			})
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(respuesta)
+		utils.RespondJSON(w, http.StatusOK, gruposConIntegrantes)
 	}
 }
 
 // GetAllGruposWithDetailsHandler retrieves all groups with their associated investigators and roles, paginated.
 func GetAllGruposWithDetailsHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		includeDeleted, ok := parseIncludeDeleted(w, r)
+		if !ok {
+			return
+		}
+		vista, ok := parseVistaGrupo(w, r, "investigadores")
+		if !ok {
+			return
+		}
+		if directorioNoModificado(w, r, db) {
+			return
+		}
+		if limitAll(r) {
+			streamGrupos(w, r, vista, func(limit, offset int) ([]models.GrupoWithInvestigadores, int, error) {
+				return repository.GetAllGruposWithDetails(r.Context(), db, includeDeleted, limit, offset)
+			})
+			return
+		}
+
 		// Read pagination params
 		page, limit := utils.GetPaginationParams(r)
 		offset := (page - 1) * limit
 
 		// Call the repository function to get all groups with details
-		gruposConDetalles, totalItems, err := repository.GetAllGruposWithDetails(db, limit, offset)
+		gruposConDetalles, totalItems, err := gruposConDetallesCached(r, db, includeDeleted, limit, offset)
 		if err != nil {
-			log.Printf("Error getting all groups with details: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Error("Error getting all groups with details", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
-
-		// Construir enlaces para los archivos ANTES de enviar la respuesta
-		for i := range gruposConDetalles {
-			// Asumiendo que GrupoWithInvestigadores tiene un campo Grupo (models.Grupo) que contiene Archivo
-			gruposConDetalles[i].Grupo.Archivo = constructDriveLink(gruposConDetalles[i].Grupo.Archivo)
+		data, err := vista.datos(gruposConDetalles)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error selecting group fields", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
 		}
 
-		// Calculate pagination metadata
-		totalPages := 0
-		if totalItems > 0 {
-			totalPages = int(math.Ceil(float64(totalItems) / float64(limit)))
-		}
-		pagination := models.PaginationMetadata{
-			TotalItems:  totalItems,
-			TotalPages:  totalPages,
-			CurrentPage: page,
-			Limit:       limit,
-		}
+		pagination := paginacion(w, r, totalItems, page, limit)
 
 		// Create paginated response
 		response := models.PaginatedResponse{
-			Data:       gruposConDetalles, // Data is []GrupoWithInvestigadores
+			Data:       data, // []GrupoWithInvestigadores, or the groups in the shape of ?fields/?expand
 			Pagination: pagination,
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		utils.RespondJSON(w, http.StatusOK, response)
 	}
 }
 
 // GetAllDetallesGrupoInvestigadorHandler retrieves all group-investigator relationships with pagination.
+// It accepts ?rol, ?nombre (investigator) and ?activosEn=YYYY-MM-DD as filters.
 func GetAllDetallesGrupoInvestigadorHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Read pagination params
-		page, limit := utils.GetPaginationParams(r)
-		offset := (page - 1) * limit
+		respondDetallesPaginados(db, w, r, 0)
+	}
+}
+
+// GetGrupoDuplicadosHandler lists likely duplicate groups.
+// With ?nombre and/or ?numeroResolucion it returns the existing groups similar to that proposal
+// (useful before creating one); otherwise it returns pairs of existing groups that look duplicated.
+// ?umbral sets the minimum name similarity (0..1, default 0.6).
+func GetGrupoDuplicadosHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		umbral := umbralDuplicadoPorDefecto
+		if umbralStr := query.Get("umbral"); umbralStr != "" {
+			parsed, err := strconv.ParseFloat(umbralStr, 64)
+			if err != nil || parsed <= 0 || parsed > 1 {
+				utils.RespondError(w, "umbral debe ser un número entre 0 y 1", http.StatusBadRequest)
+				return
+			}
+			umbral = parsed
+		}
+
+		nombre := query.Get("nombre")
+		numeroResolucion := query.Get("numeroResolucion")
+		w.Header().Set("Content-Type", "application/json")
+
+		if nombre != "" || numeroResolucion != "" {
+			excludeID := 0
+			if excludeStr := query.Get("excluir"); excludeStr != "" {
+				parsed, err := strconv.Atoi(excludeStr)
+				if err != nil {
+					utils.RespondError(w, "Invalid excluir parameter", http.StatusBadRequest)
+					return
+				}
+				excludeID = parsed
+			}
+			candidatos, err := repository.FindGrupoDuplicateCandidates(r.Context(), db, nombre, numeroResolucion, umbral, excludeID)
+			if err != nil {
+				logging.FromContext(r.Context()).Error("Error buscando grupos duplicados", "error", err)
+				utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			utils.RespondJSON(w, http.StatusOK, candidatos)
+			return
+		}
 
-		// Call the repository function to get all details
-		detalles, totalItems, err := repository.GetAllDetallesGrupoInvestigador(db, limit, offset)
+		limit := 50
+		if limitStr := query.Get("limit"); limitStr != "" {
+			parsed, err := strconv.Atoi(limitStr)
+			if err != nil || parsed <= 0 {
+				utils.RespondError(w, "Invalid limit parameter", http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+		pares, err := repository.FindDuplicateGrupoPairs(r.Context(), db, umbral, limit)
 		if err != nil {
-			log.Printf("Error getting all group-investigator details: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Error("Error buscando pares de grupos duplicados", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
+		utils.RespondJSON(w, http.StatusOK, pares)
+	}
+}
 
-		// Calculate pagination metadata
-		totalPages := 0
-		if totalItems > 0 {
-			totalPages = int(math.Ceil(float64(totalItems) / float64(limit)))
+// GetGruposStatsHandler returns aggregate statistics (by year, line and type, plus member totals) for dashboards.
+func GetGruposStatsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := repository.GetEstadisticasGrupos(r.Context(), db)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting group statistics", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
 		}
-		pagination := models.PaginationMetadata{
-			TotalItems:  totalItems,
-			TotalPages:  totalPages,
-			CurrentPage: page,
-			Limit:       limit,
+		utils.RespondJSON(w, http.StatusOK, stats)
+	}
+}
+
+// GetEstadisticasPorFacultadHandler returns group counts, investigator counts and average members
+// per group broken down by facultad (GET /estadisticas/por-facultad).
+func GetEstadisticasPorFacultadHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := repository.GetEstadisticasPorFacultad(r.Context(), db)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting statistics by facultad", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
 		}
+		utils.RespondJSON(w, http.StatusOK, stats)
+	}
+}
 
-		// Create paginated response
-		response := models.PaginatedResponse{
-			Data:       detalles,
-			Pagination: pagination,
+// GetEstadisticasHistorialHandler returns the statistics materialized every night by the
+// estadisticas job, optionally between ?desde and ?hasta (YYYY-MM-DD), oldest first
+// (GET /estadisticas/historial).
+func GetEstadisticasHistorialHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		desde, ok := fechaQueryParam(w, r, "desde")
+		if !ok {
+			return
+		}
+		hasta, ok := fechaQueryParam(w, r, "hasta")
+		if !ok {
+			return
+		}
+		estadisticas, err := repository.GetEstadisticasDiarias(r.Context(), db, desde, hasta)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting daily statistics", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
 		}
+		utils.RespondJSON(w, http.StatusOK, estadisticas)
+	}
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+// SetGrupoPadreHandler sets or clears the parent of a group (PUT /grupos/{id}/padre).
+func SetGrupoPadreHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+
+		var req models.CambiarGrupoPadreRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body")
+			return
+		}
+
+		grupo, err := repository.SetGrupoPadre(r.Context(), db, id, req.IDGrupoPadre)
+		switch {
+		case errors.Is(err, repository.ErrGrupoNoEncontrado):
+			utils.RespondError(w, "Grupo not found", http.StatusNotFound)
+			return
+		case errors.Is(err, repository.ErrGrupoPadreNoEncontrado):
+			utils.RespondError(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, repository.ErrCicloJerarquia):
+			utils.RespondError(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			respondRepoError(w, r, err, "Error setting parent of group", "id", id)
+			return
+		}
+
+		utils.RespondJSON(w, http.StatusOK, grupo)
+	}
+}
+
+// GetSubgruposHandler returns a group with its whole subgroup tree (GET /grupos/{id}/subgrupos).
+func GetSubgruposHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+
+		arbol, err := repository.GetSubgruposTree(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting subgroups of group", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if arbol == nil || !grupoVisible(r.Context(), &arbol.Grupo) {
+			utils.RespondError(w, "Grupo not found", http.StatusNotFound)
+			return
+		}
+
+		utils.RespondJSON(w, http.StatusOK, arbol)
 	}
 }
+
+// GetGruposRelacionadosHandler returns the groups most similar to a group by línea, keywords and
+// shared members (GET /grupos/{id}/relacionados?limit=10, at most 50).
+func GetGruposRelacionadosHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid group ID", http.StatusBadRequest)
+			return
+		}
+		limit := 10
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			parsed, err := strconv.Atoi(limitStr)
+			if err != nil || parsed <= 0 || parsed > 50 {
+				utils.RespondError(w, "Invalid limit parameter (1-50)", http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+
+		grupo, err := repository.GetGrupoByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting group by ID", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if grupo == nil || !grupoVisible(r.Context(), grupo) {
+			utils.RespondError(w, "Grupo not found", http.StatusNotFound)
+			return
+		}
+
+		relacionados, err := repository.GetGruposRelacionados(r.Context(), db, id, limit)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting groups related to group", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, relacionados)
+	}
+}
+
+// gruposConDetallesCached returns a page of every group with its investigators, from the directory
+// cache when enabled.
+func gruposConDetallesCached(r *http.Request, db *sql.DB, includeDeleted bool, limit, offset int) ([]models.GrupoWithInvestigadores, int, error) {
+	p, err := cache.Load(r.Context(), directorioCache, fmt.Sprintf("grupos:%t:%d:%d", includeDeleted, limit, offset), func() (pagina[models.GrupoWithInvestigadores], error) {
+		grupos, total, err := repository.GetAllGruposWithDetails(r.Context(), db, includeDeleted, limit, offset)
+		return pagina[models.GrupoWithInvestigadores]{Items: grupos, Total: total}, err
+	})
+	return p.Items, p.Total, err
+}
@@ -1,23 +1,51 @@
 package controllers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
-	"log"
-	"math"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/background"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/cache"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/reports"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils/querybuilder"
 	"github.com/gorilla/mux"
 )
 
-// GetInvestigadoresHandler handles fetching all investigators or searching by name with pagination.
+// GetInvestigadoresHandler handles fetching all investigators or searching by name (?name=),
+// faculty (?facultad=) and member tipo (?tipo=) with pagination, sorted by ?sort= (e.g.
+// -createdAt,apellido).
+// With ?include=grupos each investigator also carries its group and coordinator counts.
 func GetInvestigadoresHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if directorioNoModificado(w, r, db) {
+			return
+		}
+		if r.URL.Query().Has("ids") {
+			getInvestigadoresLote(w, r, db)
+			return
+		}
 		name := r.URL.Query().Get("name")
+		facultades, ok := parseFacultadesQuery(w, r)
+		if !ok {
+			return
+		}
+		tipo := r.URL.Query().Get("tipo")
+		if tipo != "" && !models.EsTipoIntegranteValido(tipo) {
+			utils.RespondError(w, "Invalid tipo parameter", http.StatusBadRequest)
+			return
+		}
+		orden := r.URL.Query().Get("sort")
 		page, limit := utils.GetPaginationParams(r)
 		offset := (page - 1) * limit
 
@@ -25,39 +53,89 @@ func GetInvestigadoresHandler(db *sql.DB) http.HandlerFunc {
 		var totalItems int
 		var err error
 
-		if name != "" {
-			investigadores, totalItems, err = repository.SearchInvestigadores(db, name, limit, offset)
+		if name != "" || len(facultades) > 0 || tipo != "" || orden != "" {
+			investigadores, totalItems, err = repository.SearchInvestigadores(r.Context(), db, name, facultades, tipo, orden, limit, offset)
 		} else {
-			investigadores, totalItems, err = repository.GetAllInvestigadores(db, limit, offset)
+			var p pagina[models.Investigador]
+			p, err = cache.Load(r.Context(), directorioCache, fmt.Sprintf("investigadores:%d:%d", limit, offset), func() (pagina[models.Investigador], error) {
+				investigadores, total, err := repository.GetAllInvestigadores(r.Context(), db, limit, offset)
+				return pagina[models.Investigador]{Items: investigadores, Total: total}, err
+			})
+			investigadores, totalItems = p.Items, p.Total
 		}
 
+		if errors.Is(err, querybuilder.ErrClaveNoPermitida) {
+			utils.RespondError(w, "Invalid sort parameter", http.StatusBadRequest)
+			return
+		}
 		if err != nil {
-			log.Printf("Error getting/searching investigators: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Error("Error getting/searching investigators", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
-		// Calculate pagination metadata
-		totalPages := 0
-		if totalItems > 0 {
-			totalPages = int(math.Ceil(float64(totalItems) / float64(limit)))
-		}
-		pagination := models.PaginationMetadata{
-			TotalItems:  totalItems,
-			TotalPages:  totalPages,
-			CurrentPage: page,
-			Limit:       limit,
+		data, ok := conResumenGrupos(w, r, db, investigadores)
+		if !ok {
+			return
 		}
 
+		pagination := paginacion(w, r, totalItems, page, limit)
+
 		// Create paginated response
 		response := models.PaginatedResponse{
-			Data:       investigadores,
+			Data:       data,
 			Pagination: pagination,
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		utils.RespondJSON(w, http.StatusOK, response)
+	}
+}
+
+// conResumenGrupos returns the investigators to send, with their group and coordinator counts when
+// the request asks for ?include=grupos. It answers 500 and returns ok=false if the counts fail.
+func conResumenGrupos(w http.ResponseWriter, r *http.Request, db *sql.DB, investigadores []models.Investigador) (data interface{}, ok bool) {
+	if r.URL.Query().Get("include") != "grupos" {
+		return investigadores, true
+	}
+	ids := make([]int, len(investigadores))
+	for i, inv := range investigadores {
+		ids[i] = inv.ID
+	}
+	resumen, err := repository.GetResumenGruposInvestigadores(r.Context(), db, ids)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error getting investigator group summary", "error", err)
+		utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	conResumen := make([]models.InvestigadorConResumen, len(investigadores))
+	for i, inv := range investigadores {
+		conResumen[i] = models.InvestigadorConResumen{Investigador: inv, ResumenGruposInvestigador: resumen[inv.ID]}
+	}
+	return conResumen, true
+}
+
+// getInvestigadoresLote answers GET /investigadores?ids=1,5,9 with the requested investigators,
+// loaded in one query. ?include=grupos works as in the paginated list.
+func getInvestigadoresLote(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+	ids, ok := parseIDsLote(w, r)
+	if !ok {
+		return
+	}
+	investigadores, err := repository.GetInvestigadoresByIDs(r.Context(), db, ids)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error getting investigators by IDs", "error", err)
+		utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	data, ok := conResumenGrupos(w, r, db, investigadores)
+	if !ok {
+		return
+	}
+	encontrados := make(map[int]bool, len(investigadores))
+	for _, inv := range investigadores {
+		encontrados[inv.ID] = true
 	}
+	utils.RespondJSON(w, http.StatusOK, models.LoteResponse{Data: data, NoEncontrados: idsNoEncontrados(ids, encontrados)})
 }
 
 // GetInvestigadorHandler handles fetching a single investigator by ID.
@@ -67,24 +145,23 @@ func GetInvestigadorHandler(db *sql.DB) http.HandlerFunc {
 		idStr := vars["id"]
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			http.Error(w, "Invalid investigator ID", http.StatusBadRequest)
+			utils.RespondError(w, "Invalid investigator ID", http.StatusBadRequest)
 			return
 		}
 
-		investigador, err := repository.GetInvestigadorByID(db, id)
+		investigador, err := repository.GetInvestigadorByID(r.Context(), db, id)
 		if err != nil {
-			log.Printf("Error getting investigator by ID: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Error("Error getting investigator by ID", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
 		if investigador == nil {
-			http.Error(w, "Investigador not found", http.StatusNotFound)
+			utils.RespondError(w, "Investigador not found", http.StatusNotFound)
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(investigador)
+		utils.RespondJSONWithETag(w, r, investigador)
 	}
 }
 
@@ -94,27 +171,33 @@ func CreateInvestigadorHandler(db *sql.DB) http.HandlerFunc {
 		var inv models.Investigador
 		if err := json.NewDecoder(r.Body).Decode(&inv); err != nil {
 			// Consider logging the actual error for debugging
-			// log.Printf("Error decoding investigator JSON: %v", err)
-			http.Error(w, "Invalid request body format", http.StatusBadRequest)
+			// logging.FromContext(r.Context()).Error("Error decoding investigator JSON", "error", err)
+			utils.RespondDecodeError(w, err, "Invalid request body format")
 			return
 		}
 
-		// --- VALIDACIÓN ---
-		if inv.Nombre == "" || inv.Apellido == "" {
-			http.Error(w, "Missing required fields: nombre and apellido", http.StatusBadRequest)
+		if !validar(w, &inv) || !validarEmailInvestigador(w, &inv) || !validarFacultadInvestigador(w, r, db, &inv, nil) ||
+			!validarTipoInvestigador(w, &inv, nil) {
 			return
 		}
-		// --- FIN VALIDACIÓN ---
 
-		if err := repository.CreateInvestigador(db, &inv); err != nil {
-			log.Printf("Error creating investigator: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		if err := repository.CreateInvestigador(r.Context(), db, &inv); err != nil {
+			if errors.Is(err, repository.ErrEmailDuplicado) {
+				respondEmailDuplicado(w)
+				return
+			}
+			if errors.Is(err, repository.ErrCodigoMatriculaDuplicado) {
+				respondCodigoMatriculaDuplicado(w)
+				return
+			}
+			respondRepoError(w, r, err, "Error creating investigator")
 			return
 		}
+		if inv.Email != nil && verificacionEmailAutomatica() {
+			background.Go(func() { enviarVerificacionEmailAsync(context.WithoutCancel(r.Context()), db, inv) })
+		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(inv)
+		utils.RespondJSON(w, http.StatusCreated, inv)
 	}
 }
 
@@ -125,59 +208,221 @@ func UpdateInvestigadorHandler(db *sql.DB) http.HandlerFunc {
 		idStr := vars["id"]
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			http.Error(w, "Invalid investigator ID", http.StatusBadRequest)
+			utils.RespondError(w, "Invalid investigator ID", http.StatusBadRequest)
 			return
 		}
 
 		var inv models.Investigador
 		if err := json.NewDecoder(r.Body).Decode(&inv); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			utils.RespondDecodeError(w, err, "Invalid request body")
 			return
 		}
 
 		// Ensure the ID in the body matches the ID in the URL
 		inv.ID = id
+		if !validar(w, &inv) || !validarEmailInvestigador(w, &inv) {
+			return
+		}
+		actual, err := repository.GetInvestigadorByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting investigator for update", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if actual == nil {
+			utils.RespondError(w, "Investigador not found", http.StatusNotFound)
+			return
+		}
+		if !validarFacultadInvestigador(w, r, db, &inv, actual) || !validarTipoInvestigador(w, &inv, actual) {
+			return
+		}
+		emailEnviado := inv.Email != nil && *inv.Email != ""
 
-		if err := repository.UpdateInvestigador(db, &inv); err != nil {
-			log.Printf("Error updating investigator: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		if err := repository.UpdateInvestigador(r.Context(), db, &inv); err != nil {
+			if errors.Is(err, repository.ErrInvestigadorNoExiste) {
+				utils.RespondError(w, "Investigador not found", http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, repository.ErrEmailDuplicado) {
+				respondEmailDuplicado(w)
+				return
+			}
+			if errors.Is(err, repository.ErrCodigoMatriculaDuplicado) {
+				respondCodigoMatriculaDuplicado(w)
+				return
+			}
+			respondRepoError(w, r, err, "Error updating investigator", "id", id)
 			return
 		}
+		// Nuevo email (o el mismo aún sin verificar): enviar el enlace de confirmación
+		if emailEnviado && !inv.EmailVerificado && verificacionEmailAutomatica() {
+			background.Go(func() { enviarVerificacionEmailAsync(context.WithoutCancel(r.Context()), db, inv) })
+		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(inv)
+		utils.RespondJSON(w, http.StatusOK, inv)
 	}
 }
 
-// DeleteInvestigadorHandler handles deleting an investigator by ID.
+// validarTipoInvestigador completes and checks the member tipo of inv, writing a 422 and returning
+// false if it is not valid. On update (actual != nil) an omitted tipo keeps the current one, and an
+// omitted codigoMatricula too while the investigator stays a student. Students need a
+// codigoMatricula and an escuela; other tipos cannot have a codigoMatricula.
+func validarTipoInvestigador(w http.ResponseWriter, inv *models.Investigador, actual *models.Investigador) bool {
+	if actual != nil {
+		if inv.Tipo == "" {
+			inv.Tipo = actual.Tipo
+		}
+		if inv.CodigoMatricula == nil && inv.Tipo == models.TipoEstudiante {
+			inv.CodigoMatricula = actual.CodigoMatricula
+		}
+	}
+	if inv.Tipo == "" {
+		inv.Tipo = models.TipoDocente
+	}
+	if inv.CodigoMatricula != nil {
+		codigo := strings.TrimSpace(*inv.CodigoMatricula)
+		inv.CodigoMatricula = &codigo
+		if codigo == "" {
+			inv.CodigoMatricula = nil
+		}
+	}
+
+	var errs []utils.FieldError
+	if inv.Tipo != models.TipoEstudiante {
+		if inv.CodigoMatricula != nil {
+			errs = append(errs, utils.FieldError{
+				Campo:   "codigoMatricula",
+				Codigo:  "solo_estudiantes",
+				Mensaje: "Solo los integrantes de tipo estudiante tienen código de matrícula",
+			})
+		}
+	} else {
+		if inv.CodigoMatricula == nil {
+			errs = append(errs, utils.FieldError{Campo: "codigoMatricula", Codigo: "obligatorio", Mensaje: "Los estudiantes deben tener código de matrícula"})
+		}
+		if inv.IDEscuela == nil {
+			errs = append(errs, utils.FieldError{Campo: "idEscuela", Codigo: "obligatorio", Mensaje: "Los estudiantes deben tener escuela profesional"})
+		}
+	}
+	if len(errs) > 0 {
+		utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity, errs...)
+		return false
+	}
+	return true
+}
+
+// respondCodigoMatriculaDuplicado answers 409 for a codigoMatricula already used by another investigator.
+func respondCodigoMatriculaDuplicado(w http.ResponseWriter) {
+	utils.RespondFieldErrors(w, "Datos duplicados", http.StatusConflict, utils.FieldError{
+		Campo:   "codigoMatricula",
+		Codigo:  "codigo_matricula_duplicado",
+		Mensaje: "El código de matrícula ya está registrado para otro investigador",
+	})
+}
+
+// RelacionesConflictResponse is returned with 409 when deleting an investigator who still belongs
+// to groups. Resend the request with ?force=true to remove those memberships and delete it anyway.
+type RelacionesConflictResponse struct {
+	utils.ErrorResponse
+	Relaciones []models.RelacionGrupoInvestigador `json:"relaciones"`
+}
+
+// DeleteInvestigadorHandler handles soft-deleting an investigator by ID. An investigator who still
+// belongs to groups is rejected with 409 listing them, unless ?force=true, which also removes the
+// memberships in the same transaction.
 func DeleteInvestigadorHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		idStr := vars["id"]
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			http.Error(w, "Invalid investigator ID", http.StatusBadRequest)
+			utils.RespondError(w, "Invalid investigator ID", http.StatusBadRequest)
 			return
 		}
+		force := r.URL.Query().Get("force") == "true"
 
-		if err := repository.DeleteInvestigador(db, id); err != nil {
-			log.Printf("Error deleting investigator: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		eliminadas, err := repository.DeleteInvestigador(r.Context(), db, id, force)
+		switch {
+		case errors.Is(err, repository.ErrInvestigadorNoExiste):
+			utils.RespondError(w, "Investigador not found", http.StatusNotFound)
+			return
+		case errors.Is(err, repository.ErrInvestigadorConRelaciones):
+			relaciones, err := repository.GetRelacionesInvestigador(r.Context(), db, id)
+			if err != nil {
+				logging.FromContext(r.Context()).Error("Error getting relations of investigator", "id", id, "error", err)
+				utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			resp := RelacionesConflictResponse{
+				ErrorResponse: utils.NewErrorResponse("El investigador aún pertenece a grupos; use ?force=true para retirarlo de ellos y eliminarlo", http.StatusConflict, nil),
+				Relaciones:    relaciones,
+			}
+			utils.RespondErrorEnvelope(w, http.StatusConflict, &resp)
 			return
+		case err != nil:
+			respondRepoError(w, r, err, "Error deleting investigator", "id", id)
+			return
+		}
+		if eliminadas > 0 {
+			registrarAuditoria(db, r, models.AuditEliminarForzado, "investigador", id, fmt.Sprintf("%d membresías eliminadas", eliminadas))
 		}
 
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
+// RestoreInvestigadorHandler restores a soft-deleted investigator. Group memberships removed by a
+// forced deletion have to be added again.
+func RestoreInvestigadorHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid investigator ID", http.StatusBadRequest)
+			return
+		}
+
+		inv, err := repository.GetInvestigadorByIDIncludingDeleted(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting investigator to restore", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if inv == nil {
+			utils.RespondError(w, "Investigador not found", http.StatusNotFound)
+			return
+		}
+		if inv.DeletedAt == nil {
+			utils.RespondError(w, "El investigador no está eliminado", http.StatusConflict)
+			return
+		}
+
+		if err := repository.RestoreInvestigador(r.Context(), db, id); err != nil {
+			respondRepoError(w, r, err, "Error restoring investigator", "id", id)
+			return
+		}
+		inv, err = repository.GetInvestigadorByID(r.Context(), db, id)
+		if err != nil || inv == nil {
+			logging.FromContext(r.Context()).Error("Error getting investigator after restoring", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		utils.RespondJSON(w, http.StatusOK, inv)
+	}
+}
+
 // GetAllInvestigadoresNoPaginationHandler handles fetching ALL investigators without pagination.
 func GetAllInvestigadoresNoPaginationHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		investigadores, err := repository.GetAllInvestigadoresNoPagination(db)
+		if directorioNoModificado(w, r, db) {
+			return
+		}
+		investigadores, err := cache.Load(r.Context(), directorioCache, "investigadores:all", func() ([]models.Investigador, error) {
+			return repository.GetAllInvestigadoresNoPagination(r.Context(), db)
+		})
 		if err != nil {
-			log.Printf("Error getting all investigators (no pagination): %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Error("Error getting all investigators (no pagination)", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
@@ -186,7 +431,84 @@ func GetAllInvestigadoresNoPaginationHandler(db *sql.DB) http.HandlerFunc {
 			"data": investigadores,
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response) // Encode the map
+		utils.RespondJSON(w, http.StatusOK, response)
+	}
+}
+
+// ExportInvestigadoresHandler streams the investigators with their groups and roles as a CSV or
+// XLSX file (?format=csv|xlsx, csv by default). By default there is one row per membership; with
+// ?agrupar=true one row per investigator lists all their groups. ?name= filters like the listing.
+func ExportInvestigadoresHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		formato := q.Get("format")
+		if formato == "" {
+			formato = reports.FormatoCSV
+		}
+		if formato != reports.FormatoCSV && formato != reports.FormatoXLSX {
+			utils.RespondError(w, "Invalid format parameter (csv or xlsx)", http.StatusBadRequest)
+			return
+		}
+		agrupado := q.Get("agrupar") == "true"
+
+		encabezado := []string{"idInvestigador", "nombre", "apellido", "email", "idGrupo", "grupo", "rol"}
+		if agrupado {
+			encabezado = []string{"idInvestigador", "nombre", "apellido", "email", "totalGrupos", "grupos"}
+		}
+
+		// The file is started with the first row, so a failing query can still be answered with 500
+		var tabla reports.TablaWriter
+		iniciar := func() error {
+			t, contentType, err := reports.NewTablaWriter(w, formato, "Investigadores")
+			if err != nil {
+				return err
+			}
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("investigadores_%s.%s", time.Now().Format("20060102"), formato)))
+			tabla = t
+			return tabla.WriteRow(encabezado)
+		}
+
+		err := repository.ExportInvestigadores(r.Context(), db, q.Get("name"), agrupado, func(f models.FilaExportInvestigador) error {
+			if tabla == nil {
+				if err := iniciar(); err != nil {
+					return err
+				}
+			}
+			email := ""
+			if f.Email != nil {
+				email = *f.Email
+			}
+			fila := []string{strconv.Itoa(f.ID), f.Nombre, f.Apellido, email}
+			if agrupado {
+				fila = append(fila, strconv.Itoa(f.TotalGrupos), f.Grupos)
+			} else {
+				idGrupo := ""
+				if f.IDGrupo != nil {
+					idGrupo = strconv.Itoa(*f.IDGrupo)
+				}
+				fila = append(fila, idGrupo, f.Grupo, f.Rol)
+			}
+			return tabla.WriteRow(fila)
+		})
+		if err != nil {
+			if tabla == nil {
+				logging.FromContext(r.Context()).Error("Error exporting investigators", "error", err)
+				utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			// Headers are already sent; the client will get a truncated file
+			logging.FromContext(r.Context()).Error("Error streaming investigator export", "error", err)
+			return
+		}
+		if tabla == nil {
+			if err := iniciar(); err != nil {
+				logging.FromContext(r.Context()).Error("Error starting investigator export", "error", err)
+				return
+			}
+		}
+		if err := tabla.Close(); err != nil {
+			logging.FromContext(r.Context()).Error("Error finishing investigator export", "error", err)
+		}
 	}
 }
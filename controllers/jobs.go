@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/jobs"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// GetJobsHandler lists the scheduled jobs with their schedule, next run and the result of the
+// last one (admin only).
+func GetJobsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, err := jobs.Status(r.Context(), db)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting scheduled jobs", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, status)
+	}
+}
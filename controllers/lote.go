@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// maxIDsLote is the maximum number of IDs in a batched GET (?ids=...).
+const maxIDsLote = 100
+
+// parseIDsLote reads ?ids=1,5,9 (or repeated ids parameters), without duplicates and in the
+// requested order. It answers 400 and returns ok=false if an ID is invalid or there are too many.
+func parseIDsLote(w http.ResponseWriter, r *http.Request) (ids []int, ok bool) {
+	vistos := map[int]bool{}
+	for _, v := range utils.QueryValues(r, "ids") {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 1 {
+			utils.RespondError(w, fmt.Sprintf("Invalid ids: %q is not an ID", v), http.StatusBadRequest)
+			return nil, false
+		}
+		if !vistos[id] {
+			vistos[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		utils.RespondError(w, "ids requires at least one ID", http.StatusBadRequest)
+		return nil, false
+	}
+	if len(ids) > maxIDsLote {
+		utils.RespondError(w, fmt.Sprintf("ids accepts at most %d IDs", maxIDsLote), http.StatusBadRequest)
+		return nil, false
+	}
+	return ids, true
+}
+
+// idsNoEncontrados returns the requested IDs missing from encontrados.
+func idsNoEncontrados(ids []int, encontrados map[int]bool) []int {
+	faltan := []int{}
+	for _, id := range ids {
+		if !encontrados[id] {
+			faltan = append(faltan, id)
+		}
+	}
+	return faltan
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database"
+)
+
+// metrica is one sample of the metrics endpoint, in the Prometheus text format.
+type metrica struct {
+	nombre string
+	tipo   string // gauge or counter
+	ayuda  string
+	valor  float64
+}
+
+// GetMetricsHandler returns the connection pool statistics (sql.DBStats) and the slow query count
+// in the Prometheus text format, for capacity planning: a growing wait count means requests queue
+// for a connection and DB_MAX_OPEN_CONNS (or the database's max_connections) falls short. Admin
+// only; the scraper authenticates with a Bearer token.
+func GetMetricsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := db.Stats()
+		metricas := []metrica{
+			{"apigrupos_db_max_open_connections", "gauge", "Maximum number of open connections to the database.", float64(stats.MaxOpenConnections)},
+			{"apigrupos_db_open_connections", "gauge", "Established connections, in use and idle.", float64(stats.OpenConnections)},
+			{"apigrupos_db_in_use_connections", "gauge", "Connections currently in use.", float64(stats.InUse)},
+			{"apigrupos_db_idle_connections", "gauge", "Idle connections.", float64(stats.Idle)},
+			{"apigrupos_db_wait_count_total", "counter", "Times a request waited for a free connection.", float64(stats.WaitCount)},
+			{"apigrupos_db_wait_duration_seconds_total", "counter", "Total time spent waiting for a free connection.", stats.WaitDuration.Seconds()},
+			{"apigrupos_db_max_idle_closed_total", "counter", "Connections closed because of DB_MAX_IDLE_CONNS.", float64(stats.MaxIdleClosed)},
+			{"apigrupos_db_max_idle_time_closed_total", "counter", "Connections closed because they were idle for too long.", float64(stats.MaxIdleTimeClosed)},
+			{"apigrupos_db_max_lifetime_closed_total", "counter", "Connections closed because of DB_CONN_MAX_LIFETIME.", float64(stats.MaxLifetimeClosed)},
+			{"apigrupos_db_slow_queries_total", "counter", "Statements slower than DB_SLOW_QUERY_THRESHOLD.", float64(database.SlowQueries())},
+		}
+
+		var b strings.Builder
+		for _, m := range metricas {
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", m.nombre, m.ayuda, m.nombre, m.tipo, m.nombre, m.valor)
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(b.String()))
+	}
+}
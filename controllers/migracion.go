@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/background"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/migration"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// Estado de la última migración de archivos lanzada desde la API (solo una a la vez).
+var (
+	migracionMu       sync.Mutex
+	migracionProgreso *models.ProgresoMigracion
+)
+
+// StartFileMigrationHandler starts migrating every stored file to another storage backend in the
+// background (admin only). Query params: destino (required), origen (default "drive") and
+// eliminarOrigen=true to delete the source files. Responds 202 with the initial progress.
+func StartFileMigrationHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		origenName := query.Get("origen")
+		if origenName == "" {
+			origenName = storage.DriveName
+		}
+		from, err := storage.Get(origenName)
+		if err != nil {
+			utils.RespondError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		to, err := storage.Get(query.Get("destino"))
+		if err != nil {
+			utils.RespondError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if from.Name() == to.Name() {
+			utils.RespondError(w, "origen y destino deben ser backends distintos", http.StatusBadRequest)
+			return
+		}
+
+		migracionMu.Lock()
+		if migracionProgreso != nil && migracionProgreso.EnCurso {
+			migracionMu.Unlock()
+			utils.RespondError(w, "Ya hay una migración de archivos en curso", http.StatusConflict)
+			return
+		}
+		inicial := models.ProgresoMigracion{Origen: from.Name(), Destino: to.Name(), EnCurso: true}
+		migracionProgreso = &inicial
+		migracionMu.Unlock()
+
+		opts := migration.Options{
+			DeleteSource: query.Get("eliminarOrigen") == "true",
+			Progress: func(p models.ProgresoMigracion) {
+				migracionMu.Lock()
+				migracionProgreso = &p
+				migracionMu.Unlock()
+			},
+		}
+		background.Go(func() {
+			// La migración sobrevive a la petición que la inició; el apagado del servidor la cancela
+			if _, err := migration.MigrateFiles(background.Context(), db, from, to, opts); err != nil {
+				logging.FromContext(r.Context()).Error("Migración de archivos terminó con error", "from", from.Name(), "to", to.Name(), "error", err)
+				migracionMu.Lock()
+				migracionProgreso.UltimoError = fmt.Sprintf("%v", err)
+				migracionMu.Unlock()
+			}
+		})
+
+		utils.RespondJSON(w, http.StatusAccepted, inicial)
+	}
+}
+
+// GetFileMigrationHandler returns the progress of the last file migration started from the API (admin only).
+func GetFileMigrationHandler(w http.ResponseWriter, r *http.Request) {
+	migracionMu.Lock()
+	var progreso *models.ProgresoMigracion
+	if migracionProgreso != nil {
+		p := *migracionProgreso
+		progreso = &p
+	}
+	migracionMu.Unlock()
+
+	if progreso == nil {
+		utils.RespondError(w, "No se ha iniciado ninguna migración de archivos", http.StatusNotFound)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, progreso)
+}
@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/oai"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// OAIHandler is the OAI-PMH 2.0 provider for harvesting groups and publicaciones in Dublin Core
+// (see package oai). It accepts GET and POST (application/x-www-form-urlencoded) requests; as the
+// protocol requires, protocol errors are answered with 200 and an error element.
+func OAIHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body")
+			return
+		}
+		args := r.Form
+		apiURL := utils.BaseURL(r)
+		baseURL := apiURL + "/oai"
+		cfg := oai.ConfigFromEnv(apiURL)
+
+		resp, err := responderOAI(r.Context(), db, cfg, apiURL, baseURL, args)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error answering OAI-PMH request", "verb", args.Get("verb"), "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(xml.Header))
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		enc.Encode(resp)
+	}
+}
+
+// responderOAI builds the response to an OAI-PMH request.
+func responderOAI(ctx context.Context, db *sql.DB, cfg oai.Config, apiURL, baseURL string, args url.Values) (*oai.Response, error) {
+	fallo := func(e *oai.Error) (*oai.Response, error) {
+		resp := oai.NewResponse(baseURL, args, e.Code != oai.BadVerb && e.Code != oai.BadArgument)
+		resp.Errors = []oai.Error{*e}
+		return resp, nil
+	}
+	verbs := args["verb"]
+	if len(verbs) != 1 {
+		return fallo(&oai.Error{Code: oai.BadVerb, Message: "Falta el argumento verb o está repetido"})
+	}
+	resp := oai.NewResponse(baseURL, args, true)
+
+	switch verbs[0] {
+	case oai.VerbIdentify:
+		if e := oai.CheckArgs(args, nil, nil); e != nil {
+			return fallo(e)
+		}
+		earliest, err := repository.GetEarliestDatestampOAI(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		resp.Identify = oai.NewIdentify(cfg, baseURL, earliest)
+
+	case oai.VerbListMetadataFormats:
+		if e := oai.CheckArgs(args, nil, []string{"identifier"}); e != nil {
+			return fallo(e)
+		}
+		if id := resp.Request.Identifier; id != "" {
+			rec, err := registroOAI(ctx, db, cfg, apiURL, id)
+			if err != nil {
+				return nil, err
+			}
+			if rec == nil {
+				return fallo(&oai.Error{Code: oai.IDDoesNotExist, Message: "El identificador no existe"})
+			}
+		}
+		resp.ListMetadataFormats = oai.Formats
+
+	case oai.VerbListSets:
+		if e := oai.CheckArgs(args, nil, []string{"resumptionToken"}); e != nil {
+			return fallo(e)
+		}
+		if resp.Request.ResumptionToken != "" {
+			return fallo(&oai.Error{Code: oai.BadResumptionToken, Message: "La lista de sets está completa"})
+		}
+		resp.ListSets = oai.SetList
+
+	case oai.VerbGetRecord:
+		identifier, e := oai.ParseGetRecord(args)
+		if e != nil {
+			return fallo(e)
+		}
+		rec, err := registroOAI(ctx, db, cfg, apiURL, identifier)
+		if err != nil {
+			return nil, err
+		}
+		if rec == nil {
+			return fallo(&oai.Error{Code: oai.IDDoesNotExist, Message: "El identificador no existe"})
+		}
+		resp.GetRecord = &oai.GetRecord{Record: *rec}
+
+	case oai.VerbListIdentifiers, oai.VerbListRecords:
+		lista, e := oai.ParseLista(args)
+		if e != nil {
+			return fallo(e)
+		}
+		conMetadata := verbs[0] == oai.VerbListRecords
+		records, token, err := paginaOAI(ctx, db, cfg, apiURL, lista, conMetadata)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			return fallo(&oai.Error{Code: oai.NoRecordsMatch, Message: "Ningún registro coincide con la selección"})
+		}
+		// The last page of a list that took several requests carries an empty token
+		if token == nil && resp.Request.ResumptionToken != "" {
+			token = &oai.ResumptionToken{Cursor: lista.Cursor}
+		}
+		if conMetadata {
+			resp.ListRecords = &oai.ListRecords{Records: records, ResumptionToken: token}
+		} else {
+			headers := make([]oai.Header, len(records))
+			for i, rec := range records {
+				headers[i] = rec.Header
+			}
+			resp.ListIdentifiers = &oai.ListIdentifiers{Headers: headers, ResumptionToken: token}
+		}
+
+	default:
+		return fallo(&oai.Error{Code: oai.BadVerb, Message: "Verbo desconocido"})
+	}
+	return resp, nil
+}
+
+// registroOAI returns the record with the given OAI identifier, or nil if there is none.
+func registroOAI(ctx context.Context, db *sql.DB, cfg oai.Config, apiURL, identifier string) (*oai.Record, error) {
+	set, id, ok := cfg.ParseIdentifier(identifier)
+	if !ok {
+		return nil, nil
+	}
+	switch set {
+	case oai.SetGrupo:
+		g, err := repository.GetGrupoOAI(ctx, db, id)
+		if err != nil || g == nil {
+			return nil, err
+		}
+		rec := cfg.GrupoRecord(*g, apiURL, true)
+		return &rec, nil
+	default:
+		p, err := repository.GetPublicacionOAI(ctx, db, id)
+		if err != nil || p == nil {
+			return nil, err
+		}
+		rec := cfg.PublicacionRecord(*p, apiURL, true)
+		return &rec, nil
+	}
+}
+
+// paginaOAI returns the page of records of lista, up to oai.PageSize across its sets, and the
+// token of the next page (nil if it is the last one).
+func paginaOAI(ctx context.Context, db *sql.DB, cfg oai.Config, apiURL string, lista oai.Lista, conMetadata bool) ([]oai.Record, *oai.ResumptionToken, error) {
+	records := []oai.Record{}
+	despuesDe := lista.DespuesDe
+	for _, set := range lista.SetsPendientes() {
+		if lista.Set != "" && set != lista.Set {
+			break
+		}
+		// One more than needed tells whether the set has more records after this page
+		falta := oai.PageSize - len(records)
+		f := models.FiltroOAI{Desde: lista.Desde, Hasta: lista.Hasta, DespuesDe: despuesDe}
+		var pagina []oai.Record
+		var ids []int
+		switch set {
+		case oai.SetGrupo:
+			grupos, err := repository.GetGruposOAI(ctx, db, f, falta+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, g := range grupos {
+				pagina = append(pagina, cfg.GrupoRecord(g, apiURL, conMetadata))
+				ids = append(ids, g.ID)
+			}
+		case oai.SetPublicacion:
+			publicaciones, err := repository.GetPublicacionesOAI(ctx, db, f, falta+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, p := range publicaciones {
+				pagina = append(pagina, cfg.PublicacionRecord(p, apiURL, conMetadata))
+				ids = append(ids, p.ID)
+			}
+		}
+		if len(pagina) > falta {
+			records = append(records, pagina[:falta]...)
+			cursor := lista.Cursor + len(records)
+			ultimo := 0
+			if falta > 0 {
+				ultimo = ids[falta-1]
+			}
+			return records, &oai.ResumptionToken{Cursor: lista.Cursor, Value: lista.Token(set, ultimo, cursor)}, nil
+		}
+		records = append(records, pagina...)
+		despuesDe = 0
+	}
+	return records, nil, nil
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// paginacion builds the pagination metadata of a page of a listing, with the numbers and links of
+// the next and previous pages, and sets the X-Total-Count and Link (rel="next", rel="prev")
+// headers, which carry the pagination when JSON_ENVELOPE=none. It must be called before the
+// response is written.
+func paginacion(w http.ResponseWriter, r *http.Request, totalItems, page, limit int) models.PaginationMetadata {
+	totalPages := 0
+	if totalItems > 0 {
+		totalPages = int(math.Ceil(float64(totalItems) / float64(limit)))
+	}
+	p := models.PaginationMetadata{
+		TotalItems:  totalItems,
+		TotalPages:  totalPages,
+		CurrentPage: page,
+		Limit:       limit,
+	}
+	if page < totalPages {
+		next := page + 1
+		p.NextPage = &next
+		p.Links.Next = enlacePagina(r, next)
+	}
+	// A page past the end still links back to the last one
+	if page > 1 && totalPages > 0 {
+		prev := min(page-1, totalPages)
+		p.PrevPage = &prev
+		p.Links.Prev = enlacePagina(r, prev)
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(totalItems))
+	enlaces := []string{}
+	if p.Links.Next != nil {
+		enlaces = append(enlaces, fmt.Sprintf(`<%s>; rel="next"`, *p.Links.Next))
+	}
+	if p.Links.Prev != nil {
+		enlaces = append(enlaces, fmt.Sprintf(`<%s>; rel="prev"`, *p.Links.Prev))
+	}
+	if len(enlaces) > 0 {
+		w.Header().Set("Link", strings.Join(enlaces, ", "))
+	}
+	return p
+}
+
+// enlacePagina returns the absolute URL of the request with page replaced.
+func enlacePagina(r *http.Request, page int) *string {
+	q := r.URL.Query()
+	q.Set("page", strconv.Itoa(page))
+	link := utils.BaseURL(r) + r.URL.Path + "?" + q.Encode()
+	return &link
+}
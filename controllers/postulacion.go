@@ -0,0 +1,347 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/gorilla/mux"
+)
+
+// postulacionOr404 loads a postulacion, writing a 404 (or 500) and returning nil if it can't.
+func postulacionOr404(ctx context.Context, w http.ResponseWriter, db *sql.DB, id int) *models.Postulacion {
+	p, err := repository.GetPostulacionByID(ctx, db, id)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error getting postulacion", "id", id, "error", err)
+		utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+		return nil
+	}
+	if p == nil {
+		utils.RespondError(w, "Postulación not found", http.StatusNotFound)
+		return nil
+	}
+	return p
+}
+
+// convocatoriaAbiertaHoy reports whether a convocatoria accepts postulaciones today: it is abierta
+// and its deadline (inclusive) has not passed.
+func convocatoriaAbiertaHoy(c *models.Convocatoria) bool {
+	return c.Estado == models.ConvocatoriaAbierta && !c.FechaCierre.Before(calendario.Hoy())
+}
+
+// CreatePostulacionHandler registers a group's postulacion to an open convocatoria, in estado
+// presentado. The group is linked to the convocatoria if it was not participating yet.
+// Body: {"idGrupo": 3}. Responds 409 if the convocatoria is closed or the group already submitted.
+func CreatePostulacionHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid convocatoria ID", http.StatusBadRequest)
+			return
+		}
+		var req models.CrearPostulacionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body format")
+			return
+		}
+		if !validar(w, &req) {
+			return
+		}
+		c, err := repository.GetConvocatoriaByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting convocatoria by ID", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if c == nil {
+			utils.RespondError(w, "Convocatoria not found", http.StatusNotFound)
+			return
+		}
+		if !convocatoriaAbiertaHoy(c) {
+			utils.RespondError(w, "La convocatoria no está abierta a postulaciones", http.StatusConflict)
+			return
+		}
+		if !grupoActivoOr404(r.Context(), w, db, req.IDGrupo) {
+			return
+		}
+
+		var presentadoPor *int
+		if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+			presentadoPor = &userID
+		}
+		p, err := repository.CreatePostulacion(r.Context(), db, id, req.IDGrupo, presentadoPor)
+		if err != nil {
+			switch {
+			case errors.Is(err, repository.ErrConvocatoriaNoEncontrada):
+				utils.RespondError(w, "Convocatoria not found", http.StatusNotFound)
+			case errors.Is(err, repository.ErrGrupoNoEncontrado):
+				utils.RespondError(w, "Grupo not found", http.StatusNotFound)
+			case errors.Is(err, repository.ErrPostulacionDuplicada):
+				utils.RespondError(w, err.Error(), http.StatusConflict)
+			default:
+				logging.FromContext(r.Context()).Error("Error creating postulacion of group to convocatoria", "id_grupo", req.IDGrupo, "id_convocatoria", id, "error", err)
+				utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			}
+			return
+		}
+		utils.RespondJSON(w, http.StatusCreated, p)
+	}
+}
+
+// GetPostulacionesConvocatoriaHandler lists a convocatoria's postulaciones, optionally filtered by ?estado=.
+func GetPostulacionesConvocatoriaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid convocatoria ID", http.StatusBadRequest)
+			return
+		}
+		estado := r.URL.Query().Get("estado")
+		if estado != "" && !models.EsEstadoPostulacionValido(estado) {
+			utils.RespondError(w, "Invalid estado parameter", http.StatusBadRequest)
+			return
+		}
+		c, err := repository.GetConvocatoriaByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting convocatoria by ID", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if c == nil {
+			utils.RespondError(w, "Convocatoria not found", http.StatusNotFound)
+			return
+		}
+		postulaciones, err := repository.GetPostulacionesByConvocatoria(r.Context(), db, id, estado)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting postulaciones of convocatoria", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, postulaciones)
+	}
+}
+
+// GetPostulacionHandler fetches a postulacion with its documents and estado history.
+func GetPostulacionHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid postulación ID", http.StatusBadRequest)
+			return
+		}
+		p := postulacionOr404(r.Context(), w, db, id)
+		if p == nil {
+			return
+		}
+		if p.Documentos, err = repository.GetDocumentosPostulacion(r.Context(), db, id); err != nil {
+			logging.FromContext(r.Context()).Error("Error getting documents of postulacion", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if p.Historial, err = repository.GetHistorialPostulacion(r.Context(), db, id); err != nil {
+			logging.FromContext(r.Context()).Error("Error getting history of postulacion", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, p)
+	}
+}
+
+// CambiarEstadoPostulacionHandler moves a postulacion to a new estado. Reviewing it (observado or
+// aprobado) is reserved to admins, and observaciones are required when observing it; the group
+// marks it subsanado once it has addressed the observations.
+// Body: {"estado": "observado", "observaciones": "..."}. Responds 409 if the change is not allowed
+// from the current estado.
+func CambiarEstadoPostulacionHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid postulación ID", http.StatusBadRequest)
+			return
+		}
+		var req models.CambiarEstadoPostulacionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body format")
+			return
+		}
+		req.Observaciones = strings.TrimSpace(req.Observaciones)
+		if !validar(w, &req) {
+			return
+		}
+		if req.Estado != models.PostulacionSubsanado && !middleware.IsAdmin(r) {
+			utils.RespondError(w, "Admin role required", http.StatusForbidden)
+			return
+		}
+
+		var idUsuario *int
+		if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+			idUsuario = &userID
+		}
+		p, err := repository.CambiarEstadoPostulacion(r.Context(), db, id, req.Estado, req.Observaciones, idUsuario)
+		if err != nil {
+			if errors.Is(err, repository.ErrTransicionPostulacion) {
+				utils.RespondError(w, err.Error(), http.StatusConflict)
+				return
+			}
+			logging.FromContext(r.Context()).Error("Error changing estado of postulacion", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if p == nil {
+			utils.RespondError(w, "Postulación not found", http.StatusNotFound)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, p)
+	}
+}
+
+// CreateDocumentoPostulacionHandler attaches a document to a postulacion (multipart: archivo, nombre
+// and optionally requisito, which must be one of the convocatoria's documentosRequeridos). Approved
+// postulaciones can no longer be changed.
+func CreateDocumentoPostulacionHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid postulación ID", http.StatusBadRequest)
+			return
+		}
+		p := postulacionOr404(r.Context(), w, db, id)
+		if p == nil {
+			return
+		}
+		if p.Estado == models.PostulacionAprobado {
+			utils.RespondError(w, "La postulación ya fue aprobada", http.StatusConflict)
+			return
+		}
+		c, err := repository.GetConvocatoriaByID(r.Context(), db, p.IDConvocatoria)
+		if err != nil || c == nil {
+			logging.FromContext(r.Context()).Error("Error getting convocatoria of postulacion", "id_convocatoria", p.IDConvocatoria, "id_postulacion", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		fileID, err := saveUploadedFile(db, r, "archivo")
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error subiendo documento para postulación", "id", id, "error", err)
+			if limite, ok := utils.BodyTooLarge(err); ok {
+				utils.RespondBodyTooLarge(w, limite)
+			} else if errors.Is(err, errTipoArchivo) {
+				respondTipoArchivoInvalido(w)
+			} else if strings.Contains(err.Error(), "parsing multipart form") {
+				utils.RespondError(w, fmt.Sprintf("Error procesando formulario: %v", err), http.StatusBadRequest)
+			} else {
+				utils.RespondError(w, "Error interno del servidor al guardar el archivo", http.StatusInternalServerError)
+			}
+			return
+		}
+		if fileID == nil {
+			utils.RespondError(w, "Falta el campo de archivo requerido: archivo", http.StatusBadRequest)
+			return
+		}
+
+		d := models.DocumentoPostulacion{
+			IDPostulacion: id,
+			Requisito:     strings.TrimSpace(r.FormValue("requisito")),
+			Nombre:        strings.TrimSpace(r.FormValue("nombre")),
+			Archivo:       fileID,
+		}
+		if d.Nombre == "" {
+			d.Nombre = d.Requisito
+		}
+		if !validar(w, &d) {
+			_ = removeFile(r.Context(), fileID)
+			return
+		}
+		if d.Requisito != "" && !slices.Contains(c.DocumentosRequeridos, d.Requisito) {
+			_ = removeFile(r.Context(), fileID)
+			utils.RespondError(w, fmt.Sprintf("requisito debe ser uno de los documentos requeridos: %s", strings.Join(c.DocumentosRequeridos, ", ")), http.StatusBadRequest)
+			return
+		}
+		if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+			d.SubidoPor = &userID
+		}
+
+		if err := repository.CreateDocumentoPostulacion(r.Context(), db, &d); err != nil {
+			logging.FromContext(r.Context()).Error("Error creating document for postulacion", "id", id, "error", err)
+			_ = removeFile(r.Context(), fileID)
+			utils.RespondError(w, "Error interno del servidor guardando archivo", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusCreated, d)
+	}
+}
+
+// DeleteDocumentoPostulacionHandler removes a document from a postulacion that is not approved yet.
+func DeleteDocumentoPostulacionHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid postulación ID", http.StatusBadRequest)
+			return
+		}
+		did, err := strconv.Atoi(vars["did"])
+		if err != nil {
+			utils.RespondError(w, "Invalid documento ID", http.StatusBadRequest)
+			return
+		}
+		p := postulacionOr404(r.Context(), w, db, id)
+		if p == nil {
+			return
+		}
+		if p.Estado == models.PostulacionAprobado {
+			utils.RespondError(w, "La postulación ya fue aprobada", http.StatusConflict)
+			return
+		}
+
+		d, err := repository.DeleteDocumentoPostulacion(r.Context(), db, id, did)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error deleting document of postulacion", "id_documento", did, "id_postulacion", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if d == nil {
+			utils.RespondError(w, "Documento not found", http.StatusNotFound)
+			return
+		}
+		if err := removeFile(r.Context(), d.Archivo); err != nil {
+			logging.FromContext(r.Context()).Error("Error removing file of document", "id_documento", did, "error", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetReporteConvocatoriaHandler reports a convocatoria's completion rates: how many participating
+// groups submitted, how many postulaciones have every required document and how many were approved.
+func GetReporteConvocatoriaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid convocatoria ID", http.StatusBadRequest)
+			return
+		}
+		rep, err := repository.GetReporteConvocatoria(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting report of convocatoria", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if rep == nil {
+			utils.RespondError(w, "Convocatoria not found", http.StatusNotFound)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, rep)
+	}
+}
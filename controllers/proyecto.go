@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/gorilla/mux"
+)
+
+// validarProyecto checks a proyecto from a request body, writing a 422 and returning false if it is
+// not valid. An empty estado defaults to en_ejecucion and an empty codigo to none.
+func validarProyecto(w http.ResponseWriter, p *models.Proyecto) bool {
+	p.Titulo = strings.TrimSpace(p.Titulo)
+	p.FuenteFinanciamiento = strings.TrimSpace(p.FuenteFinanciamiento)
+	if p.Codigo != nil {
+		codigo := strings.TrimSpace(*p.Codigo)
+		p.Codigo = &codigo
+		if codigo == "" {
+			p.Codigo = nil
+		}
+	}
+	if p.Estado == "" {
+		p.Estado = models.ProyectoEnEjecucion
+	}
+	return validar(w, p)
+}
+
+// respondProyectoError maps the repository errors of a proyecto write to a response.
+func respondProyectoError(w http.ResponseWriter, r *http.Request, err error, accion string) {
+	switch {
+	case errors.Is(err, repository.ErrProyectoNoEncontrado):
+		utils.RespondError(w, "Proyecto not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrCodigoProyectoDuplicado):
+		utils.RespondFieldErrors(w, "Proyecto duplicado", http.StatusConflict, utils.FieldError{
+			Campo:   "codigo",
+			Codigo:  "codigo_duplicado",
+			Mensaje: "Ya existe un proyecto con el mismo código",
+		})
+	case errors.Is(err, repository.ErrGrupoNoEncontrado):
+		utils.RespondError(w, "idGrupo no corresponde a un grupo existente", http.StatusBadRequest)
+	default:
+		respondRepoError(w, r, err, "Error saving proyecto", "accion", accion)
+	}
+}
+
+// GetProyectosHandler lists proyectos with pagination. Filters: ?q= (titulo, codigo or fuente de
+// financiamiento), ?idGrupo= and ?estado=.
+func GetProyectosHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		f := models.FiltroProyectos{Q: strings.TrimSpace(q.Get("q")), Estado: q.Get("estado")}
+		if f.Estado != "" && !models.EsEstadoProyectoValido(f.Estado) {
+			utils.RespondError(w, "Invalid estado parameter", http.StatusBadRequest)
+			return
+		}
+		if v := q.Get("idGrupo"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				utils.RespondError(w, "Invalid idGrupo parameter", http.StatusBadRequest)
+				return
+			}
+			f.IDGrupo = n
+		}
+		page, limit := utils.GetPaginationParams(r)
+		offset := (page - 1) * limit
+
+		proyectos, totalItems, err := repository.GetProyectos(r.Context(), db, f, limit, offset)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting proyectos", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		utils.RespondJSON(w, http.StatusOK, models.PaginatedResponse{
+			Data:       proyectos,
+			Pagination: paginacion(w, r, totalItems, page, limit),
+		})
+	}
+}
+
+// GetProyectoHandler fetches a single proyecto with its files. Anonymous callers get the files
+// without their link.
+func GetProyectoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid proyecto ID", http.StatusBadRequest)
+			return
+		}
+		p, err := repository.GetProyectoByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting proyecto by ID", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if p == nil {
+			utils.RespondError(w, "Proyecto not found", http.StatusNotFound)
+			return
+		}
+		utils.RespondJSONWithETag(w, r, p)
+	}
+}
+
+// CreateProyectoHandler creates a proyecto for a group.
+func CreateProyectoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var p models.Proyecto
+		if !decodeJSON(w, r, &p, "Invalid request body format") {
+			return
+		}
+		if !validarProyecto(w, &p) {
+			return
+		}
+		if err := repository.CreateProyecto(r.Context(), db, &p); err != nil {
+			respondProyectoError(w, r, err, "creating")
+			return
+		}
+		utils.RespondJSON(w, http.StatusCreated, p)
+	}
+}
+
+// UpdateProyectoHandler replaces a proyecto's fields; its files are kept.
+func UpdateProyectoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid proyecto ID", http.StatusBadRequest)
+			return
+		}
+		var p models.Proyecto
+		if !decodeJSON(w, r, &p, "Invalid request body format") {
+			return
+		}
+		p.ID = id
+		if !validarProyecto(w, &p) {
+			return
+		}
+		if err := repository.UpdateProyecto(r.Context(), db, &p); err != nil {
+			respondProyectoError(w, r, err, "updating")
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, p)
+	}
+}
+
+// DeleteProyectoHandler deletes a proyecto and its files.
+func DeleteProyectoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid proyecto ID", http.StatusBadRequest)
+			return
+		}
+		refs, err := repository.DeleteProyecto(r.Context(), db, id)
+		if err != nil {
+			respondProyectoError(w, r, err, "deleting")
+			return
+		}
+		for _, ref := range refs {
+			if err := removeFile(r.Context(), &ref); err != nil {
+				logging.FromContext(r.Context()).Error("Error removing file of proyecto", "id_proyecto", id, "error", err)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// CreateProyectoArchivoHandler attaches a file to a proyecto (multipart: archivo and optionally
+// nombre, which defaults to the file name).
+func CreateProyectoArchivoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid proyecto ID", http.StatusBadRequest)
+			return
+		}
+		p, err := repository.GetProyectoByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting proyecto by ID", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if p == nil {
+			utils.RespondError(w, "Proyecto not found", http.StatusNotFound)
+			return
+		}
+
+		fileID, err := saveUploadedFile(db, r, "archivo")
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error subiendo archivo para proyecto", "id", id, "error", err)
+			if limite, ok := utils.BodyTooLarge(err); ok {
+				utils.RespondBodyTooLarge(w, limite)
+			} else if errors.Is(err, errTipoArchivo) {
+				respondTipoArchivoInvalido(w)
+			} else if strings.Contains(err.Error(), "parsing multipart form") {
+				utils.RespondError(w, fmt.Sprintf("Error procesando formulario: %v", err), http.StatusBadRequest)
+			} else {
+				utils.RespondError(w, "Error interno del servidor al guardar el archivo", http.StatusInternalServerError)
+			}
+			return
+		}
+		if fileID == nil {
+			utils.RespondError(w, "Falta el campo de archivo requerido: archivo", http.StatusBadRequest)
+			return
+		}
+
+		a := models.ProyectoArchivo{
+			IDProyecto: id,
+			Nombre:     strings.TrimSpace(r.FormValue("nombre")),
+			Archivo:    fileID,
+		}
+		if a.Nombre == "" {
+			if _, header, err := r.FormFile("archivo"); err == nil {
+				a.Nombre = header.Filename
+			}
+		}
+		if !validar(w, &a) {
+			_ = removeFile(r.Context(), fileID)
+			return
+		}
+		if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+			a.SubidoPor = &userID
+		}
+
+		if err := repository.CreateProyectoArchivo(r.Context(), db, &a); err != nil {
+			_ = removeFile(r.Context(), fileID)
+			respondProyectoError(w, r, err, "attaching file")
+			return
+		}
+		utils.RespondJSON(w, http.StatusCreated, a)
+	}
+}
+
+// DeleteProyectoArchivoHandler removes a file from a proyecto.
+func DeleteProyectoArchivoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid proyecto ID", http.StatusBadRequest)
+			return
+		}
+		aid, err := strconv.Atoi(vars["aid"])
+		if err != nil {
+			utils.RespondError(w, "Invalid archivo ID", http.StatusBadRequest)
+			return
+		}
+
+		a, err := repository.DeleteProyectoArchivo(r.Context(), db, id, aid)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error deleting file of proyecto", "id_archivo", aid, "id_proyecto", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if a == nil {
+			utils.RespondError(w, "Archivo not found", http.StatusNotFound)
+			return
+		}
+		if err := removeFile(r.Context(), a.Archivo); err != nil {
+			logging.FromContext(r.Context()).Error("Error removing file of proyecto", "id_archivo", aid, "error", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
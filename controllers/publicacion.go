@@ -0,0 +1,359 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/bibtex"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/validation"
+	"github.com/gorilla/mux"
+)
+
+// validarPublicacion checks a publicacion from a request body, writing a 422 and returning false if
+// it is not valid. An empty tipo defaults to articulo and an empty DOI to none.
+func validarPublicacion(w http.ResponseWriter, p *models.Publicacion) bool {
+	p.Titulo = strings.TrimSpace(p.Titulo)
+	p.Revista = strings.TrimSpace(p.Revista)
+	if p.Tipo == "" {
+		p.Tipo = models.PublicacionArticulo
+	}
+	if !validar(w, p) {
+		return false
+	}
+	if p.DOI != nil && strings.TrimSpace(*p.DOI) == "" {
+		p.DOI = nil
+	}
+	if p.DOI != nil {
+		doi, ok := utils.NormalizarDOI(*p.DOI)
+		if !ok {
+			utils.RespondFieldErrors(w, "Datos de la publicación inválidos", http.StatusUnprocessableEntity, utils.FieldError{
+				Campo:   "doi",
+				Codigo:  "doi_invalido",
+				Mensaje: "El DOI debe tener la forma 10.xxxx/sufijo",
+			})
+			return false
+		}
+		p.DOI = &doi
+	}
+	return true
+}
+
+// respondPublicacionError maps the repository errors of a publicacion write to a response.
+func respondPublicacionError(w http.ResponseWriter, r *http.Request, err error, accion string) {
+	switch {
+	case errors.Is(err, repository.ErrPublicacionNoEncontrada):
+		utils.RespondError(w, "Publicación not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrDOIDuplicado):
+		utils.RespondFieldErrors(w, "Publicación duplicada", http.StatusConflict, utils.FieldError{
+			Campo:   "doi",
+			Codigo:  "doi_duplicado",
+			Mensaje: "Ya existe una publicación con el mismo DOI",
+		})
+	case errors.Is(err, repository.ErrInvestigadorNoExiste):
+		utils.RespondError(w, "idInvestigadores contiene un investigador que no existe", http.StatusBadRequest)
+	case errors.Is(err, repository.ErrGrupoNoEncontrado):
+		utils.RespondError(w, "idGrupos contiene un grupo que no existe", http.StatusBadRequest)
+	default:
+		respondRepoError(w, r, err, "Error saving publicacion", "accion", accion)
+	}
+}
+
+// GetPublicacionesHandler lists publicaciones with pagination. Filters: ?q= (titulo or revista),
+// ?anio=, ?tipo=, ?idGrupo= and ?idInvestigador=.
+func GetPublicacionesHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		f := models.FiltroPublicaciones{Q: strings.TrimSpace(q.Get("q")), Tipo: q.Get("tipo")}
+		if f.Tipo != "" && !models.EsTipoPublicacionValido(f.Tipo) {
+			utils.RespondError(w, "Invalid tipo parameter", http.StatusBadRequest)
+			return
+		}
+		for _, p := range []struct {
+			name string
+			dest *int
+		}{{"anio", &f.Anio}, {"idGrupo", &f.IDGrupo}, {"idInvestigador", &f.IDInvestigador}} {
+			if v := q.Get(p.name); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n <= 0 {
+					utils.RespondError(w, "Invalid "+p.name+" parameter", http.StatusBadRequest)
+					return
+				}
+				*p.dest = n
+			}
+		}
+		page, limit := utils.GetPaginationParams(r)
+		offset := (page - 1) * limit
+
+		publicaciones, totalItems, err := repository.GetPublicaciones(r.Context(), db, f, limit, offset)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting publicaciones", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		utils.RespondJSON(w, http.StatusOK, models.PaginatedResponse{
+			Data:       publicaciones,
+			Pagination: paginacion(w, r, totalItems, page, limit),
+		})
+	}
+}
+
+// GetPublicacionHandler fetches a single publicacion.
+func GetPublicacionHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid publicación ID", http.StatusBadRequest)
+			return
+		}
+		p, err := repository.GetPublicacionByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting publicacion by ID", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if p == nil {
+			utils.RespondError(w, "Publicación not found", http.StatusNotFound)
+			return
+		}
+		utils.RespondJSONWithETag(w, r, p)
+	}
+}
+
+// CreatePublicacionHandler creates a publicacion linked to the given investigators and groups.
+func CreatePublicacionHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var p models.Publicacion
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body format")
+			return
+		}
+		if !validarPublicacion(w, &p) {
+			return
+		}
+		if err := repository.CreatePublicacion(r.Context(), db, &p); err != nil {
+			respondPublicacionError(w, r, err, "creating")
+			return
+		}
+		utils.RespondJSON(w, http.StatusCreated, p)
+	}
+}
+
+// UpdatePublicacionHandler replaces a publicacion's fields and its investigator and group links.
+func UpdatePublicacionHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid publicación ID", http.StatusBadRequest)
+			return
+		}
+		var p models.Publicacion
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body format")
+			return
+		}
+		p.ID = id
+		if !validarPublicacion(w, &p) {
+			return
+		}
+		if err := repository.UpdatePublicacion(r.Context(), db, &p); err != nil {
+			respondPublicacionError(w, r, err, "updating")
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, p)
+	}
+}
+
+// DeletePublicacionHandler deletes a publicacion and its links.
+func DeletePublicacionHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid publicación ID", http.StatusBadRequest)
+			return
+		}
+		eliminada, err := repository.DeletePublicacion(r.Context(), db, id)
+		if err != nil {
+			respondRepoError(w, r, err, "Error deleting publicacion", "id", id)
+			return
+		}
+		if !eliminada {
+			utils.RespondError(w, "Publicación not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ImportPublicacionesBibtexHandler imports publicaciones from a BibTeX file, sent in the multipart
+// field "archivo" or as the request body. Each entry is merged with repository.ReconciliarPublicaciones,
+// so one already registered (same DOI, or same titulo and anio) is reported as duplicada and only
+// gets the new links. Authors are linked to the investigator with the same name, if exactly one;
+// ?idInvestigador= and ?idGrupo= (or form fields) credit every entry to that investigator and
+// group. Entries that are not valid publicaciones are omitted with the reason.
+func ImportPublicacionesBibtexHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var src io.Reader = r.Body
+		valores := r.URL.Query()
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+				if limite, ok := utils.BodyTooLarge(err); ok {
+					utils.RespondBodyTooLarge(w, limite)
+					return
+				}
+				utils.RespondError(w, fmt.Sprintf("Error procesando formulario: %v", err), http.StatusBadRequest)
+				return
+			}
+			file, _, err := r.FormFile("archivo")
+			if err != nil {
+				utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity, utils.FieldError{
+					Campo:   "archivo",
+					Codigo:  "archivo_bibtex_requerido",
+					Mensaje: "Adjunte el archivo BibTeX",
+				})
+				return
+			}
+			defer file.Close()
+			src = file
+			valores = r.Form
+		}
+		var vinculos struct{ idGrupo, idInvestigador int }
+		for _, p := range []struct {
+			name string
+			dest *int
+		}{{"idGrupo", &vinculos.idGrupo}, {"idInvestigador", &vinculos.idInvestigador}} {
+			if v := valores.Get(p.name); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n <= 0 {
+					utils.RespondError(w, "Invalid "+p.name+" parameter", http.StatusBadRequest)
+					return
+				}
+				*p.dest = n
+			}
+		}
+
+		entradas, err := bibtex.Parse(src)
+		if err != nil {
+			var errBib *bibtex.Error
+			switch {
+			case errors.As(err, &errBib):
+				utils.RespondError(w, fmt.Sprintf("BibTeX inválido: %v", err), http.StatusUnprocessableEntity)
+			default:
+				if limite, ok := utils.BodyTooLarge(err); ok {
+					utils.RespondBodyTooLarge(w, limite)
+					return
+				}
+				utils.RespondError(w, "Error leyendo el archivo BibTeX", http.StatusBadRequest)
+			}
+			return
+		}
+		if len(entradas) == 0 {
+			utils.RespondError(w, "El archivo no contiene entradas BibTeX", http.StatusUnprocessableEntity)
+			return
+		}
+
+		res := models.ResultadoImportPublicaciones{Entradas: make([]models.EntradaImportada, len(entradas))}
+		var pubs []models.Publicacion
+		var indices []int // Entry of each publicacion in pubs
+		for i, e := range entradas {
+			p, autores := bibtex.Publicacion(e)
+			ei := &res.Entradas[i]
+			*ei = models.EntradaImportada{Clave: e.Clave, Linea: e.Linea, Titulo: p.Titulo}
+			if campos := validation.Struct(&p); len(campos) > 0 {
+				ei.Estado = models.EntradaOmitida
+				ei.Motivo = campos[0].Campo + ": " + campos[0].Mensaje
+				res.Omitidas++
+				continue
+			}
+			vistos := map[int]bool{}
+			for _, autor := range autores {
+				id, err := repository.BuscarInvestigadorPorNombre(r.Context(), db, autor)
+				if err != nil {
+					respondRepoError(w, r, err, "Error matching BibTeX authors")
+					return
+				}
+				if id != 0 && !vistos[id] {
+					vistos[id] = true
+					ei.IDInvestigadores = append(ei.IDInvestigadores, id)
+				}
+			}
+			p.IDInvestigadores = ei.IDInvestigadores
+			if vinculos.idInvestigador != 0 && !vistos[vinculos.idInvestigador] {
+				p.IDInvestigadores = append(append([]int{}, p.IDInvestigadores...), vinculos.idInvestigador)
+			}
+			if vinculos.idGrupo != 0 {
+				p.IDGrupos = []int{vinculos.idGrupo}
+			}
+			pubs = append(pubs, p)
+			indices = append(indices, i)
+		}
+
+		reconciliadas, err := repository.ReconciliarPublicaciones(r.Context(), db, pubs)
+		if err != nil {
+			switch {
+			case errors.Is(err, repository.ErrInvestigadorNoExiste):
+				utils.RespondError(w, "idInvestigador no corresponde a un investigador existente", http.StatusBadRequest)
+			case errors.Is(err, repository.ErrGrupoNoEncontrado):
+				utils.RespondError(w, "idGrupo no corresponde a un grupo existente", http.StatusBadRequest)
+			default:
+				respondRepoError(w, r, err, "Error importing BibTeX publicaciones")
+			}
+			return
+		}
+		for j, rp := range reconciliadas {
+			ei := &res.Entradas[indices[j]]
+			id := rp.ID
+			ei.IDPublicacion = &id
+			if rp.Creada {
+				ei.Estado = models.EntradaCreada
+				res.Creadas++
+			} else {
+				ei.Estado = models.EntradaDuplicada
+				res.Duplicadas++
+			}
+		}
+		logging.FromContext(r.Context()).Info("BibTeX import", "creadas", res.Creadas, "duplicadas", res.Duplicadas, "omitidas", res.Omitidas)
+		utils.RespondJSON(w, http.StatusOK, res)
+	}
+}
+
+// ExportPublicacionesBibtexHandler downloads the publicaciones credited to a group as a BibTeX file,
+// with citation keys built from the first author's surname and the year.
+func ExportPublicacionesBibtexHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid grupo ID", http.StatusBadRequest)
+			return
+		}
+		if !grupoActivoOr404(r.Context(), w, db, id) {
+			return
+		}
+		pubs, err := repository.GetPublicacionesConAutoresByGrupo(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting publicaciones of grupo", "id_grupo", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		usadas := map[string]bool{}
+		entradas := make([]bibtex.Entrada, len(pubs))
+		for i, p := range pubs {
+			entradas[i] = bibtex.EntradaPublicacion(p, bibtex.Clave(p, usadas))
+		}
+		w.Header().Set("Content-Type", "application/x-bibtex; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"grupo_%d.bib\"", id))
+		if err := bibtex.Escribir(w, entradas); err != nil {
+			logging.FromContext(r.Context()).Error("Error writing BibTeX", "id_grupo", id, "error", err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/gorilla/mux"
+)
+
+// GetRenovacionesHandler lists the renovaciones of all groups, oldest first. Filter: ?estado=
+// (pendiente, aprobada or rechazada).
+func GetRenovacionesHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		estado := r.URL.Query().Get("estado")
+		switch estado {
+		case "", models.RenovacionPendiente, models.RenovacionAprobada, models.RenovacionRechazada:
+		default:
+			utils.RespondError(w, "Invalid estado parameter: use pendiente, aprobada or rechazada", http.StatusBadRequest)
+			return
+		}
+		renovaciones, err := repository.GetRenovaciones(r.Context(), db, estado)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting renovaciones", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, renovaciones)
+	}
+}
+
+// GetRenovacionesGrupoHandler lists the renovaciones of a group, newest first.
+func GetRenovacionesGrupoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid grupo ID", http.StatusBadRequest)
+			return
+		}
+		if !grupoActivoOr404(r.Context(), w, db, id) {
+			return
+		}
+		renovaciones, err := repository.GetRenovacionesByGrupo(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting renovaciones of grupo", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, renovaciones)
+	}
+}
+
+// SolicitarRenovacionHandler submits the renovacion of a group's vigencia, which moves the group
+// to en_renovacion until an admin reviews it.
+func SolicitarRenovacionHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid grupo ID", http.StatusBadRequest)
+			return
+		}
+		var body models.SolicitarRenovacionRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body format")
+			return
+		}
+		if !validar(w, &body) {
+			return
+		}
+
+		v := models.Renovacion{IDGrupo: id, Motivo: strings.TrimSpace(body.Motivo)}
+		if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+			v.SolicitadoPor = &userID
+		}
+		if err := repository.CreateRenovacion(r.Context(), db, &v); err != nil {
+			respondRepoError(w, r, err, "Error creating renovacion for grupo", "id", id)
+			return
+		}
+		utils.RespondJSON(w, http.StatusCreated, v)
+	}
+}
+
+// AprobarRenovacionHandler approves a pendiente renovacion with the renewal resolution: multipart
+// with "numero", "fechaEmision", optionally "fechaVencimiento" (default fechaEmision +
+// GRUPO_VIGENCIA_ANIOS), "descripcion", "observaciones" and the PDF in "archivo". The group gets
+// the new fechaVencimiento and is activo again. Responds with the renovacion and the resolucion.
+func AprobarRenovacionHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid renovación ID", http.StatusBadRequest)
+			return
+		}
+
+		fileID, ok := guardarPDFResolucion(w, r, db)
+		if !ok {
+			return
+		}
+		if fileID == nil {
+			utils.RespondError(w, "Falta el campo de archivo requerido: archivo", http.StatusBadRequest)
+			return
+		}
+		res := models.Resolucion{Tipo: models.ResolucionRenovacion, Archivo: fileID}
+		if fe := resolucionDesdeFormulario(r, &res); fe != nil {
+			_ = removeFile(r.Context(), fileID)
+			utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity, *fe)
+			return
+		}
+		if !validar(w, &res) {
+			_ = removeFile(r.Context(), fileID)
+			return
+		}
+		var revisadoPor *int
+		if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+			revisadoPor = &userID
+			res.SubidoPor = &userID
+		}
+
+		v, err := repository.AprobarRenovacion(r.Context(), db, id, &res, strings.TrimSpace(r.FormValue("observaciones")), revisadoPor)
+		if err != nil {
+			_ = removeFile(r.Context(), fileID)
+			respondResolucionError(w, r, err, "Error approving renovacion", "id", id)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, struct {
+			Renovacion *models.Renovacion `json:"renovacion"`
+			Resolucion models.Resolucion  `json:"resolucion"`
+		}{v, res})
+	}
+}
+
+// RechazarRenovacionHandler rejects a pendiente renovacion, putting the group back in the estado it
+// had when the renovacion was submitted.
+func RechazarRenovacionHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid renovación ID", http.StatusBadRequest)
+			return
+		}
+		var body models.RechazarRenovacionRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body format")
+			return
+		}
+		if !validar(w, &body) {
+			return
+		}
+		var revisadoPor *int
+		if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+			revisadoPor = &userID
+		}
+
+		v, err := repository.RechazarRenovacion(r.Context(), db, id, strings.TrimSpace(body.Observaciones), revisadoPor)
+		if err != nil {
+			respondRepoError(w, r, err, "Error rejecting renovacion", "id", id)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, v)
+	}
+}
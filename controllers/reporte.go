@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/reports"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// GetReporteGruposHandler generates the aggregated report of the approved groups grouped by
+// ?groupBy=facultad|linea|año (anio is also accepted), with their member counts per tipo and the
+// totals, as ?format=json (default), xlsx or pdf.
+func GetReporteGruposHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		groupBy := q.Get("groupBy")
+		if groupBy == "anio" {
+			groupBy = models.ReportePorAnio
+		}
+		if !models.EsAgrupacionReporteValida(groupBy) {
+			utils.RespondError(w, "Invalid groupBy parameter (facultad, linea or año)", http.StatusBadRequest)
+			return
+		}
+		formato := q.Get("format")
+		if formato == "" {
+			formato = "json"
+		}
+		if formato != "json" && formato != reports.FormatoXLSX && formato != "pdf" {
+			utils.RespondError(w, "Invalid format parameter (json, xlsx or pdf)", http.StatusBadRequest)
+			return
+		}
+
+		reporte, err := repository.GetReporteGrupos(r.Context(), db, groupBy)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting group report", "groupBy", groupBy, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if formato == "json" {
+			utils.RespondJSON(w, http.StatusOK, reporte)
+			return
+		}
+
+		// Render to a buffer first so an error can still produce a proper HTTP error
+		var buf bytes.Buffer
+		contentType := "application/pdf"
+		if formato == "pdf" {
+			err = reports.WriteReporteGruposPDF(&buf, reporte)
+		} else {
+			var tabla reports.TablaWriter
+			tabla, contentType, err = reports.NewTablaWriter(&buf, formato, "Reporte")
+			for _, fila := range reports.TablaReporteGrupos(reporte) {
+				if err != nil {
+					break
+				}
+				err = tabla.WriteRow(fila)
+			}
+			if err == nil {
+				err = tabla.Close()
+			}
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error generating group report", "groupBy", groupBy, "format", formato, "error", err)
+			utils.RespondError(w, "Internal server error generating report", http.StatusInternalServerError)
+			return
+		}
+
+		nombre := fmt.Sprintf("reporte_grupos_%s_%s.%s", reporte.GeneradoEn.Format("20060102"), slugAgrupacion(groupBy), formato)
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", nombre))
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		w.Write(buf.Bytes())
+	}
+}
+
+// slugAgrupacion is groupBy as used in file names, without the ñ.
+func slugAgrupacion(groupBy string) string {
+	if groupBy == models.ReportePorAnio {
+		return "anio"
+	}
+	return groupBy
+}
@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/validation"
+	"github.com/gorilla/mux"
+)
+
+// esPDF reports whether the uploaded file formKey, if any, is a PDF (by its content, not its
+// name). A missing file counts as valid.
+func esPDF(r *http.Request, formKey string) (bool, error) {
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil && err != http.ErrNotMultipart {
+		return false, fmt.Errorf("error parsing multipart form: %w", err)
+	}
+	file, _, err := r.FormFile(formKey)
+	if err != nil {
+		return true, nil
+	}
+	defer file.Close()
+	cabecera := make([]byte, 5)
+	if _, err := io.ReadFull(file, cabecera); err != nil {
+		return false, nil
+	}
+	return bytes.Equal(cabecera, []byte("%PDF-")), nil
+}
+
+// guardarPDFResolucion stores the PDF uploaded in "archivo", if any, answering the error and
+// returning ok=false if it is not a PDF or cannot be stored.
+func guardarPDFResolucion(w http.ResponseWriter, r *http.Request, db *sql.DB) (fileID *string, ok bool) {
+	pdf, err := esPDF(r, "archivo")
+	if err == nil && !pdf {
+		utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity, utils.FieldError{
+			Campo:   "archivo",
+			Codigo:  "archivo_no_pdf",
+			Mensaje: "La resolución debe adjuntarse en PDF",
+		})
+		return nil, false
+	}
+	if err == nil {
+		fileID, err = saveUploadedFile(db, r, "archivo")
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error subiendo resolución", "path", r.URL.Path, "error", err)
+		if limite, ok := utils.BodyTooLarge(err); ok {
+			utils.RespondBodyTooLarge(w, limite)
+		} else if errors.Is(err, errTipoArchivo) {
+			respondTipoArchivoInvalido(w)
+		} else if strings.Contains(err.Error(), "parsing multipart form") {
+			utils.RespondError(w, fmt.Sprintf("Error procesando formulario: %v", err), http.StatusBadRequest)
+		} else {
+			utils.RespondError(w, "Error interno del servidor al guardar el archivo", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return fileID, true
+}
+
+// respondResolucionError maps the repository errors of a resolucion write to a response.
+func respondResolucionError(w http.ResponseWriter, r *http.Request, err error, msg string, args ...any) {
+	if errors.Is(err, repository.ErrResolucionDuplicada) {
+		utils.RespondFieldErrors(w, "Resolución duplicada", http.StatusConflict, utils.FieldError{
+			Campo:   "numero",
+			Codigo:  "resolucion_duplicada",
+			Mensaje: "El grupo ya tiene una resolución con ese número",
+		})
+		return
+	}
+	respondRepoError(w, r, err, msg, args...)
+}
+
+// GetResolucionesGrupoHandler lists the resoluciones of a group, oldest first.
+func GetResolucionesGrupoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid grupo ID", http.StatusBadRequest)
+			return
+		}
+		if !grupoActivoOr404(r.Context(), w, db, id) {
+			return
+		}
+		resoluciones, err := repository.GetResolucionesByGrupo(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting resolutions of grupo", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, resoluciones)
+	}
+}
+
+// resolucionDesdeFormulario reads the fields of a resolucion from a multipart form: "numero",
+// "fechaEmision" and "fechaVencimiento" (YYYY-MM-DD), "descripcion" and, unless res.Tipo is already
+// set, "tipo" (default otro). A creacion or renovacion without fechaVencimiento gets fechaEmision +
+// models.VigenciaAnios. It returns the field error of an invalid date.
+func resolucionDesdeFormulario(r *http.Request, res *models.Resolucion) *utils.FieldError {
+	res.Numero = strings.TrimSpace(r.FormValue("numero"))
+	res.Descripcion = strings.TrimSpace(r.FormValue("descripcion"))
+	if res.Tipo == "" {
+		res.Tipo = r.FormValue("tipo")
+	}
+	if res.Tipo == "" {
+		res.Tipo = models.ResolucionOtro
+	}
+	if fecha := r.FormValue("fechaEmision"); fecha != "" {
+		f, err := calendario.Parse(fecha)
+		if err != nil {
+			fe := validation.FechaInvalida("fechaEmision")
+			return &fe
+		}
+		res.FechaEmision = f
+	}
+	if fecha := r.FormValue("fechaVencimiento"); fecha != "" {
+		f, err := calendario.Parse(fecha)
+		if err != nil {
+			fe := validation.FechaInvalida("fechaVencimiento")
+			return &fe
+		}
+		res.FechaVencimiento = &f
+	} else if (res.Tipo == models.ResolucionCreacion || res.Tipo == models.ResolucionRenovacion) && !res.FechaEmision.IsZero() {
+		f := res.FechaEmision.AddDate(models.VigenciaAnios(), 0, 0)
+		res.FechaVencimiento = &f
+	}
+	return nil
+}
+
+// CreateResolucionGrupoHandler attaches a resolucion to a group. Expects multipart/form-data with
+// "numero", "fechaEmision" (YYYY-MM-DD), "tipo" (default otro), "descripcion", optionally
+// "fechaVencimiento" (see resolucionDesdeFormulario) and optionally the PDF in "archivo".
+func CreateResolucionGrupoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid grupo ID", http.StatusBadRequest)
+			return
+		}
+		if !grupoActivoOr404(r.Context(), w, db, id) {
+			return
+		}
+
+		fileID, ok := guardarPDFResolucion(w, r, db)
+		if !ok {
+			return
+		}
+
+		res := models.Resolucion{IDGrupo: id, Archivo: fileID}
+		if fe := resolucionDesdeFormulario(r, &res); fe != nil {
+			_ = removeFile(r.Context(), fileID)
+			utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity, *fe)
+			return
+		}
+		if !validar(w, &res) {
+			_ = removeFile(r.Context(), fileID)
+			return
+		}
+		if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+			res.SubidoPor = &userID
+		}
+
+		if err := repository.CreateResolucion(r.Context(), db, &res); err != nil {
+			_ = removeFile(r.Context(), fileID)
+			respondResolucionError(w, r, err, "Error creating resolution for grupo", "id", id)
+			return
+		}
+		utils.RespondJSON(w, http.StatusCreated, res)
+	}
+}
+
+// DeleteResolucionGrupoHandler removes a resolucion from a group, with its PDF unless it is also
+// the group's own file.
+func DeleteResolucionGrupoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid grupo ID", http.StatusBadRequest)
+			return
+		}
+		rid, err := strconv.Atoi(vars["rid"])
+		if err != nil {
+			utils.RespondError(w, "Invalid resolución ID", http.StatusBadRequest)
+			return
+		}
+		grupo, err := repository.GetGrupoByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting grupo", "id_grupo", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if grupo == nil {
+			utils.RespondError(w, "Grupo not found", http.StatusNotFound)
+			return
+		}
+
+		res, err := repository.DeleteResolucion(r.Context(), db, id, rid)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error deleting resolution of grupo", "id_resolucion", rid, "id_grupo", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if res == nil {
+			utils.RespondError(w, "Resolución not found", http.StatusNotFound)
+			return
+		}
+		// The creation resolution of groups registered before resoluciones shares the group's file
+		if res.Archivo != nil && (grupo.Archivo == nil || *grupo.Archivo != *res.Archivo) {
+			if err := removeFile(r.Context(), res.Archivo); err != nil {
+				logging.FromContext(r.Context()).Error("Error removing file of resolution", "id_resolucion", rid, "error", err)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/snapshot"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// publicSnapshot serves the public endpoints from periodically refreshed responses; nil unless
+// PUBLIC_SNAPSHOT=true and StartPublicSnapshot was called.
+var publicSnapshot *snapshot.Store
+
+// StartPublicSnapshot enables snapshot mode when PUBLIC_SNAPSHOT=true (see snapshot.ConfigFromEnv),
+// refreshing the stored responses until ctx is done. It must run before the routes are set up.
+func StartPublicSnapshot(ctx context.Context) {
+	cfg, enabled := snapshot.ConfigFromEnv()
+	if !enabled {
+		return
+	}
+	publicSnapshot = snapshot.New(cfg)
+	go publicSnapshot.Run(ctx)
+	logging.FromContext(ctx).Info("Public snapshot mode enabled", "interval", cfg.Interval)
+}
+
+// PublicSnapshot returns the snapshot store, or nil when snapshot mode is disabled.
+func PublicSnapshot() *snapshot.Store {
+	return publicSnapshot
+}
+
+// GetSnapshotStatusHandler returns the snapshot store's size, hit counters and last refresh (admin only).
+func GetSnapshotStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if publicSnapshot == nil {
+		utils.RespondError(w, "El modo snapshot no está activo", http.StatusServiceUnavailable)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, publicSnapshot.Status())
+}
+
+// RefreshSnapshotHandler re-renders every stored response now, e.g. after an import (admin only).
+func RefreshSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	if publicSnapshot == nil {
+		utils.RespondError(w, "El modo snapshot no está activo", http.StatusServiceUnavailable)
+		return
+	}
+	publicSnapshot.Refresh(r.Context())
+	utils.RespondJSON(w, http.StatusOK, publicSnapshot.Status())
+}
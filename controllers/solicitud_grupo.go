@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/notifier"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/gorilla/mux"
+)
+
+// CreateSolicitudGrupoHandler handles the public group registration form.
+// The request is stored as "pendiente" until an administrator moderates it.
+func CreateSolicitudGrupoHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.CreateSolicitudGrupoRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body")
+			return
+		}
+
+		ok, err := utils.VerifyCaptcha(req.CaptchaToken, utils.ClientIP(r))
+		if errors.Is(err, utils.ErrCaptchaNoConfigurado) {
+			utils.RespondError(w, "Captcha verification is not configured", http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error verifying captcha", "error", err)
+			utils.RespondError(w, "Could not verify captcha", http.StatusBadGateway)
+			return
+		}
+		if !ok {
+			utils.RespondError(w, "Invalid captcha", http.StatusBadRequest)
+			return
+		}
+
+		s := req.SolicitudGrupo
+		for i, integrante := range s.Integrantes {
+			if integrante.Rol == "" {
+				s.Integrantes[i].Rol = models.RolIntegrante
+			}
+		}
+		if !validar(w, &s) || !validarTipoInvestigacion(w, r, db, &s.TipoInvestigacion, "") {
+			return
+		}
+		for _, integrante := range s.Integrantes {
+			// The requester becomes the coordinator on approval
+			if models.EsCoordinador(integrante.Rol) {
+				utils.RespondError(w, "El solicitante será el coordinador del grupo; los integrantes propuestos no pueden tener rol Coordinador", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := repository.CreateSolicitudGrupo(r.Context(), db, &s); err != nil {
+			respondRepoError(w, r, err, "Error creating solicitud")
+			return
+		}
+
+		utils.RespondJSON(w, http.StatusCreated, s)
+	}
+}
+
+// GetSolicitudesHandler lists the moderation queue (admin only), optionally filtered by ?estado=.
+func GetSolicitudesHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		estado := r.URL.Query().Get("estado")
+		page, limit := utils.GetPaginationParams(r)
+		offset := (page - 1) * limit
+
+		solicitudes, totalItems, err := repository.GetSolicitudesGrupo(r.Context(), db, estado, limit, offset)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting solicitudes", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		response := models.PaginatedResponse{
+			Data:       solicitudes,
+			Pagination: paginacion(w, r, totalItems, page, limit),
+		}
+
+		utils.RespondJSON(w, http.StatusOK, response)
+	}
+}
+
+// GetSolicitudHandler fetches a single request by ID (admin only).
+func GetSolicitudHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid solicitud ID", http.StatusBadRequest)
+			return
+		}
+
+		solicitud, err := repository.GetSolicitudGrupoByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting solicitud by ID", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if solicitud == nil {
+			utils.RespondError(w, "Solicitud not found", http.StatusNotFound)
+			return
+		}
+
+		utils.RespondJSON(w, http.StatusOK, solicitud)
+	}
+}
+
+// AprobarSolicitudHandler approves a pending request, creating the group and its memberships (admin only).
+func AprobarSolicitudHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid solicitud ID", http.StatusBadRequest)
+			return
+		}
+
+		var body models.ModerarSolicitudRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+			utils.RespondDecodeError(w, err, "Invalid request body")
+			return
+		}
+
+		if !validar(w, &body) {
+			return
+		}
+		fechaRegistro := calendario.Hoy()
+		if body.FechaRegistro != "" {
+			fechaRegistro, _ = calendario.Parse(body.FechaRegistro) // Validated as 2006-01-02
+		}
+
+		revisadoPor, _ := middleware.UserIDFromContext(r.Context())
+		solicitud, grupo, err := repository.ApproveSolicitudGrupo(r.Context(), db, id, body.NumeroResolucion, fechaRegistro, body.Comentario, revisadoPor)
+		if err == repository.ErrSolicitudYaRevisada {
+			utils.RespondError(w, "Solicitud already moderated", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error approving solicitud", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if solicitud == nil {
+			utils.RespondError(w, "Solicitud not found", http.StatusNotFound)
+			return
+		}
+
+		notifySolicitudModerada(context.WithoutCancel(r.Context()), db, solicitud)
+
+		utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+			"solicitud": solicitud,
+			"grupo":     grupo,
+		})
+	}
+}
+
+// RechazarSolicitudHandler rejects a pending request with the moderator's comments (admin only).
+func RechazarSolicitudHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid solicitud ID", http.StatusBadRequest)
+			return
+		}
+
+		var body models.ModerarSolicitudRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.RespondDecodeError(w, err, "Invalid request body")
+			return
+		}
+		if strings.TrimSpace(body.Comentario) == "" {
+			utils.RespondFieldErrors(w, "Datos inválidos", http.StatusUnprocessableEntity, utils.FieldError{
+				Campo:   "comentario",
+				Codigo:  "obligatorio",
+				Mensaje: "comentario es obligatorio para rechazar una solicitud",
+			})
+			return
+		}
+
+		revisadoPor, _ := middleware.UserIDFromContext(r.Context())
+		solicitud, err := repository.RejectSolicitudGrupo(r.Context(), db, id, body.Comentario, revisadoPor)
+		if err == repository.ErrSolicitudYaRevisada {
+			utils.RespondError(w, "Solicitud already moderated", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error rejecting solicitud", "id", id, "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if solicitud == nil {
+			utils.RespondError(w, "Solicitud not found", http.StatusNotFound)
+			return
+		}
+
+		notifySolicitudModerada(context.WithoutCancel(r.Context()), db, solicitud)
+
+		utils.RespondJSON(w, http.StatusOK, solicitud)
+	}
+}
+
+// notifySolicitudModerada queues the email that tells the requester the outcome of the moderation.
+// A failure is only logged: the moderation is already recorded.
+func notifySolicitudModerada(ctx context.Context, db *sql.DB, s *models.SolicitudGrupo) {
+	datos := notifier.SolicitudModerada{
+		NombreSolicitante: s.NombreSolicitante,
+		NombreGrupo:       s.Nombre,
+		Aprobada:          s.Estado == models.SolicitudAprobada,
+	}
+	if s.Comentario != nil {
+		datos.Comentario = *s.Comentario
+	}
+	if err := notifier.EnqueueEmail(ctx, db, models.EventoSolicitudModerada, []string{s.EmailSolicitante}, datos); err != nil {
+		logging.FromContext(ctx).Error("Error queuing solicitud result email", "id", s.ID, "error", err)
+	}
+}
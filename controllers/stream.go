@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/reports"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// loteStream is the number of groups loaded per query when a listing is streamed.
+const loteStream = 200
+
+// limitAll reports whether the request asks for the whole listing (?limit=all) instead of a page.
+func limitAll(r *http.Request) bool {
+	return r.URL.Query().Get("limit") == "all"
+}
+
+// cargaGrupos loads a page of a group listing: the groups and the total matching the filters.
+type cargaGrupos func(limit, offset int) ([]models.GrupoWithInvestigadores, int, error)
+
+// streamGrupos answers ?limit=all on a group listing. The groups are loaded in batches and each one
+// is written as soon as its batch arrives, flushing after every batch, so memory stays bounded
+// however many groups there are. By default the body has the shape of the paginated response (a
+// single page holding every group, in the shape of ?fields/?expand); ?format=csv or xlsx sends a
+// table with one row per membership instead.
+func streamGrupos(w http.ResponseWriter, r *http.Request, vista vistaGrupo, cargar cargaGrupos) {
+	var s grupoStream
+	switch formato := r.URL.Query().Get("format"); formato {
+	case "", "json":
+		s = &grupoStreamJSON{w: w, r: r, vista: vista}
+	case reports.FormatoCSV, reports.FormatoXLSX:
+		s = &grupoStreamTabla{w: w, formato: formato}
+	default:
+		utils.RespondError(w, "Invalid format parameter (json, csv or xlsx)", http.StatusBadRequest)
+		return
+	}
+
+	// The response is started with the first batch, so a failing query can still be answered with 500
+	iniciado := false
+	flush := http.NewResponseController(w).Flush
+	err := repository.EachBatch(r.Context(), loteStream, cargar, func(lote []models.GrupoWithInvestigadores, total int) error {
+		if !iniciado {
+			if err := s.iniciar(total); err != nil {
+				return err
+			}
+			iniciado = true
+		}
+		for _, g := range lote {
+			if err := s.escribir(g); err != nil {
+				return err
+			}
+		}
+		flush() // Not every writer can flush (e.g. the snapshot recorder); the rows go out at the end
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error streaming groups", "error", err)
+		// Once the headers are sent the client can only get a truncated body
+		if !iniciado {
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+	if !iniciado {
+		if err := s.iniciar(0); err != nil {
+			logging.FromContext(r.Context()).Error("Error starting group stream", "error", err)
+			return
+		}
+	}
+	if err := s.cerrar(); err != nil {
+		logging.FromContext(r.Context()).Error("Error finishing group stream", "error", err)
+	}
+}
+
+// grupoStream writes a streamed group listing in one format.
+type grupoStream interface {
+	iniciar(total int) error // Sets the headers and writes the opening of the body
+	escribir(g models.GrupoWithInvestigadores) error
+	cerrar() error
+}
+
+// grupoStreamJSON writes the listing in the shape of the paginated response (see utils.ListaJSON),
+// each group shaped for the caller like RespondJSON does.
+type grupoStreamJSON struct {
+	w          http.ResponseWriter
+	r          *http.Request
+	vista      vistaGrupo
+	lista      *utils.ListaJSON
+	pagination models.PaginationMetadata
+}
+
+func (s *grupoStreamJSON) iniciar(total int) error {
+	s.pagination = paginacion(s.w, s.r, total, 1, total)
+	lista, err := utils.NewListaJSON(s.w)
+	s.lista = lista
+	return err
+}
+
+func (s *grupoStreamJSON) escribir(g models.GrupoWithInvestigadores) error {
+	var v interface{} = &g
+	if s.vista.activa {
+		m, err := s.vista.grupo(g)
+		if err != nil {
+			return err
+		}
+		v = m
+	}
+	return s.lista.Escribir(v)
+}
+
+func (s *grupoStreamJSON) cerrar() error {
+	return s.lista.Cerrar(s.pagination)
+}
+
+// grupoStreamTabla writes one row per membership (and one for each group without members).
+type grupoStreamTabla struct {
+	w       http.ResponseWriter
+	formato string
+	tabla   reports.TablaWriter
+}
+
+func (s *grupoStreamTabla) iniciar(total int) error {
+	t, contentType, err := reports.NewTablaWriter(s.w, s.formato, "Grupos")
+	if err != nil {
+		return err
+	}
+	s.w.Header().Set("Content-Type", contentType)
+	s.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("grupos_%s.%s", time.Now().Format("20060102"), s.formato)))
+	s.w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	s.tabla = t
+	return s.tabla.WriteRow([]string{"idGrupo", "grupo", "numeroResolucion", "lineaInvestigacion", "tipoInvestigacion", "fechaRegistro", "estado", "idInvestigador", "nombre", "apellido", "rol"})
+}
+
+func (s *grupoStreamTabla) escribir(g models.GrupoWithInvestigadores) error {
+	grupo := []string{strconv.Itoa(g.Grupo.ID), g.Grupo.Nombre, g.Grupo.NumeroResolucion, g.Grupo.LineaInvestigacion, g.Grupo.TipoInvestigacion, g.Grupo.FechaRegistro.Format("2006-01-02"), g.Grupo.Estado}
+	if len(g.Investigadores) == 0 {
+		return s.tabla.WriteRow(append(grupo, "", "", "", ""))
+	}
+	for _, inv := range g.Investigadores {
+		fila := append(append([]string{}, grupo...), strconv.Itoa(inv.ID), inv.Nombre, inv.Apellido, inv.Rol)
+		if err := s.tabla.WriteRow(fila); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *grupoStreamTabla) cerrar() error {
+	return s.tabla.Close()
+}
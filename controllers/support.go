@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/notifier"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/secrets"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/version"
+)
+
+var startedAt = time.Now()
+
+// configVars are the environment variables reported (redacted) in the support bundle.
+var configVars = []string{
+	"PORT",
+	"DEMO_MODE", "DB_DRIVER", "SQLITE_PATH", "DB_USER", "DB_PASSWORD", "DB_HOST", "DB_PORT", "DB_NAME", "DB_SSLMODE",
+	"INSTANCE_CONNECTION_NAME", "DB_IAM_AUTH", "DB_IP_TYPE",
+	"JWT_SECRET",
+	"GOOGLE_APPLICATION_CREDENTIALS", "GOOGLE_CREDENTIALS_JSON", "GOOGLE_DRIVE_FOLDER_ID", "GOOGLE_CLOUD_PROJECT",
+	"CAPTCHA_SECRET", "CAPTCHA_VERIFY_URL", "CAPTCHA_DISABLED",
+	"CTI_VITAE_API_URL", "CTI_VITAE_API_TOKEN",
+	"EMAIL_PROVIDER", "EMAIL_FROM", "SMTP_HOST", "SMTP_PORT", "SMTP_USER", "SMTP_PASSWORD", "SMTP_FROM",
+	"SENDGRID_API_KEY", "SENDGRID_API_URL", "PASSWORD_RESET_URL",
+	"ALERT_WEBHOOK_URL", "ALERT_EMAIL", "INVESTIGADOR_EMAIL_VERIFICATION", "CONVOCATORIA_RECORDATORIO_DIAS",
+	"GRUPO_VIGENCIA_ANIOS", "GRUPO_AVISO_VENCIMIENTO_DIAS",
+	"DRIVE_ALERT_INTERVAL", "DRIVE_ALERT_ERROR_RATE", "DRIVE_ALERT_MIN_REQUESTS",
+	"DRIVE_ALERT_RATE_LIMITED", "DRIVE_ALERT_QUOTA_USAGE", "DRIVE_ALERT_COOLDOWN",
+	"PUBLIC_SNAPSHOT", "PUBLIC_SNAPSHOT_INTERVAL", "PUBLIC_SNAPSHOT_MAX_ENTRIES", "PUBLIC_SNAPSHOT_MAX_BODY",
+	"MAX_BODY_SIZE", "MAX_UPLOAD_SIZE",
+	"JOBS_ENABLED", "AUDIT_LOG_RETENTION", "CHANGES_RETENTION", "USAGE_TRACKING", "USAGE_RETENTION", "BACKUP_KEEP", "EXPORT_FULL_MIN_INTERVAL",
+	"JSON_DATE_FORMAT", "JSON_OMIT_NULL", "JSON_ENVELOPE", "INSTITUTION_TIMEZONE",
+}
+
+// SelfCheck is the result of one health check included in the support bundle.
+type SelfCheck struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Detail   string `json:"detail,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// SupportBundle gathers diagnostics to attach to issue reports.
+type SupportBundle struct {
+	GeneratedAt  time.Time         `json:"generatedAt"`
+	Build        version.Info      `json:"build"`
+	Runtime      map[string]string `json:"runtime"`
+	Config       map[string]string `json:"config"`
+	SelfChecks   []SelfCheck       `json:"selfChecks"`
+	RecentErrors []string          `json:"recentErrors"`
+}
+
+// redactConfigValue hides secrets, keeping only whether they are set.
+func redactConfigValue(name, value string) string {
+	if value == "" {
+		return "(no configurado)"
+	}
+	upper := strings.ToUpper(name)
+	if strings.Contains(upper, "PASSWORD") || strings.Contains(upper, "SECRET") || strings.Contains(upper, "TOKEN") || strings.Contains(upper, "KEY") || slices.Contains(secrets.Names, upper) {
+		return "(redactado)"
+	}
+	return value
+}
+
+// runSelfChecks verifies the service's external dependencies.
+func runSelfChecks(db *sql.DB) []SelfCheck {
+	check := func(name string, fn func() (string, error)) SelfCheck {
+		start := time.Now()
+		detail, err := fn()
+		c := SelfCheck{Name: name, OK: err == nil, Detail: detail, Duration: time.Since(start).String()}
+		if err != nil {
+			c.Detail = err.Error()
+		}
+		return c
+	}
+
+	return []SelfCheck{
+		check("database", func() (string, error) {
+			if err := db.Ping(); err != nil {
+				return "", err
+			}
+			stats := db.Stats()
+			return fmt.Sprintf("open=%d inUse=%d idle=%d", stats.OpenConnections, stats.InUse, stats.Idle), nil
+		}),
+		check("googleDrive", func() (string, error) {
+			if driveService == nil {
+				return "", fmt.Errorf("Google Drive no está configurado (GOOGLE_APPLICATION_CREDENTIALS o GOOGLE_CREDENTIALS_JSON, y GOOGLE_DRIVE_FOLDER_ID)")
+			}
+			folder, err := driveService.Files.Get(driveFolderID).Fields("id", "name").Do()
+			if err != nil {
+				return "", fmt.Errorf("no se pudo acceder a la carpeta de Drive: %w", err)
+			}
+			return "carpeta: " + folder.Name, nil
+		}),
+		check("jwtSecret", func() (string, error) {
+			if os.Getenv("JWT_SECRET") == "" {
+				return "", fmt.Errorf("JWT_SECRET no configurado")
+			}
+			return "configurado", nil
+		}),
+		check("email", func() (string, error) {
+			proveedor, configurado, err := notifier.ProveedorActual()
+			switch {
+			case err != nil:
+				return "", err
+			case proveedor == notifier.ProveedorSendGrid && !configurado:
+				return "", fmt.Errorf("EMAIL_PROVIDER=sendgrid requiere SENDGRID_API_KEY")
+			case !configurado:
+				return "SMTP_HOST no configurado, los correos solo se registran en el log", nil
+			}
+			return proveedor + " configurado", nil
+		}),
+	}
+}
+
+// GetSupportBundleHandler returns diagnostics (recent errors, self-checks, redacted config and build
+// info) as JSON, or as a zip with ?format=zip (admin only).
+func GetSupportBundleHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := make(map[string]string, len(configVars))
+		for _, name := range configVars {
+			config[name] = redactConfigValue(name, os.Getenv(name))
+		}
+
+		bundle := SupportBundle{
+			GeneratedAt: time.Now(),
+			Build:       version.Get(),
+			Runtime: map[string]string{
+				"startedAt":  startedAt.Format(time.RFC3339),
+				"uptime":     time.Since(startedAt).Round(time.Second).String(),
+				"goroutines": fmt.Sprint(runtime.NumGoroutine()),
+				"os":         runtime.GOOS + "/" + runtime.GOARCH,
+			},
+			Config:       config,
+			SelfChecks:   runSelfChecks(db),
+			RecentErrors: utils.RecentLogs.ErrorLines(),
+		}
+
+		if r.URL.Query().Get("format") != "zip" {
+			utils.RespondJSON(w, http.StatusOK, bundle)
+			return
+		}
+
+		filename := fmt.Sprintf("support-bundle-%s.zip", bundle.GeneratedAt.Format("20060102-150405"))
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+		zw := zip.NewWriter(w)
+		bundleFile, err := zw.Create("bundle.json")
+		if err == nil {
+			enc := json.NewEncoder(bundleFile)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(bundle)
+		}
+		if err == nil {
+			logsFile, createErr := zw.Create("recent.log")
+			if err = createErr; err == nil {
+				_, err = logsFile.Write([]byte(strings.Join(utils.RecentLogs.Lines(), "\n") + "\n"))
+			}
+		}
+		if err == nil {
+			err = zw.Close()
+		}
+		if err != nil {
+			// Headers are already sent; the client will get a truncated zip
+			logging.FromContext(r.Context()).Error("Error writing support bundle zip", "error", err)
+		}
+	}
+}
+
+// VersionHandler returns the build information (version, commit and build time).
+func VersionHandler(w http.ResponseWriter, r *http.Request) {
+	utils.RespondJSON(w, http.StatusOK, version.Get())
+}
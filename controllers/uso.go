@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/analytics"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+const (
+	defaultTopUso = 20
+	maxTopUso     = 100
+)
+
+// ventanaUso is the default window of GET /admin/usage by intervalo, and maxVentanaUso the longest
+// one allowed, which bounds the number of buckets of the series.
+var (
+	ventanaUso    = map[string]time.Duration{models.IntervaloHora: 24 * time.Hour, models.IntervaloDia: 30 * 24 * time.Hour}
+	maxVentanaUso = map[string]time.Duration{models.IntervaloHora: 31 * 24 * time.Hour, models.IntervaloDia: 366 * 24 * time.Hour}
+)
+
+// parseInstante reads a query parameter that is an RFC 3339 timestamp or a date (midnight UTC).
+func parseInstante(v string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC(), true
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// GetUsageHandler returns the API usage per client (user, or anonymous caller by IP) and route
+// between ?desde and ?hasta (the last 24 hours by default), bucketed by ?intervalo (hora or dia),
+// so administrators can see which clients drive the load. ?ruta (a route template such as
+// /grupos, the search) and ?idUsuario narrow it; ?top limits the clients listed. The counters of each
+// instance reach the database every minute (admin only).
+func GetUsageHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		intervalo := q.Get("intervalo")
+		if intervalo == "" {
+			intervalo = models.IntervaloHora
+		}
+		if _, ok := ventanaUso[intervalo]; !ok {
+			utils.RespondError(w, "Invalid intervalo parameter", http.StatusBadRequest)
+			return
+		}
+		hasta := time.Now().UTC()
+		if v := q.Get("hasta"); v != "" {
+			t, ok := parseInstante(v)
+			if !ok {
+				utils.RespondError(w, "Invalid hasta parameter", http.StatusBadRequest)
+				return
+			}
+			hasta = t
+		}
+		desde := hasta.Add(-ventanaUso[intervalo])
+		if v := q.Get("desde"); v != "" {
+			t, ok := parseInstante(v)
+			if !ok {
+				utils.RespondError(w, "Invalid desde parameter", http.StatusBadRequest)
+				return
+			}
+			desde = t
+		}
+		if !desde.Before(hasta) {
+			utils.RespondError(w, "desde must be before hasta", http.StatusBadRequest)
+			return
+		}
+		if hasta.Sub(desde) > maxVentanaUso[intervalo] {
+			utils.RespondError(w, "The usage window is too long: up to 31 days by hour or 366 days by day", http.StatusBadRequest)
+			return
+		}
+		var idUsuario int
+		if v := q.Get("idUsuario"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				utils.RespondError(w, "Invalid idUsuario parameter", http.StatusBadRequest)
+				return
+			}
+			idUsuario = n
+		}
+		top := defaultTopUso
+		if v := q.Get("top"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				utils.RespondError(w, "Invalid top parameter", http.StatusBadRequest)
+				return
+			}
+			top = min(n, maxTopUso)
+		}
+
+		// Rows are hourly: a partial first hour counts whole, like the bucket it falls in
+		usos, err := repository.GetUsoAPI(r.Context(), db, desde.Truncate(time.Hour), hasta, q.Get("ruta"), idUsuario)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting API usage", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, analytics.Reporte(usos, desde, hasta, intervalo, top))
+	}
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/notifier"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/gorilla/mux"
+)
+
+// horasVerificacionEmail is how long an email confirmation link is valid.
+const horasVerificacionEmail = 48
+
+// verificacionEmailAutomatica reports whether a confirmation link is emailed every time an
+// investigator's email is set or changed (INVESTIGADOR_EMAIL_VERIFICATION=true). Otherwise it is
+// only sent on request (POST /investigadores/{id}/verificar-email).
+func verificacionEmailAutomatica() bool {
+	return os.Getenv("INVESTIGADOR_EMAIL_VERIFICATION") == "true"
+}
+
+// validarEmailInvestigador normalizes inv.Email, writing a 422 and returning false if it is not a
+// valid address. An empty email is accepted (no email, or removing it on update).
+func validarEmailInvestigador(w http.ResponseWriter, inv *models.Investigador) bool {
+	if inv.Email == nil || *inv.Email == "" {
+		return true
+	}
+	email, ok := utils.NormalizeEmail(*inv.Email)
+	if !ok {
+		utils.RespondFieldErrors(w, "Datos de contacto inválidos", http.StatusUnprocessableEntity, utils.FieldError{
+			Campo:   "email",
+			Codigo:  "email_invalido",
+			Mensaje: "El email no tiene un formato válido (usuario@dominio)",
+		})
+		return false
+	}
+	inv.Email = &email
+	return true
+}
+
+// respondEmailDuplicado writes the 409 returned when another investigator already uses the email.
+func respondEmailDuplicado(w http.ResponseWriter) {
+	utils.RespondFieldErrors(w, "Datos de contacto duplicados", http.StatusConflict, utils.FieldError{
+		Campo:   "email",
+		Codigo:  "email_duplicado",
+		Mensaje: "El email ya está registrado para otro investigador",
+	})
+}
+
+// nuevoToken returns a random token for an emailed link. Only its hash (hashToken) is stored.
+func nuevoToken() (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", fmt.Errorf("error generating token: %w", err)
+	}
+	return hex.EncodeToString(tokenBytes), nil
+}
+
+// enviarVerificacionEmail creates a confirmation link (under baseURL, see utils.PublicBaseURL) for the
+// investigator's current email and queues the email with it, returning the link's expiry.
+func enviarVerificacionEmail(ctx context.Context, db *sql.DB, baseURL string, inv models.Investigador) (time.Time, error) {
+	token, err := nuevoToken()
+	if err != nil {
+		return time.Time{}, err
+	}
+	expiraEn, err := repository.CreateVerificacionEmail(ctx, db, inv.ID, *inv.Email, hashToken(token), horasVerificacionEmail)
+	if err != nil {
+		return time.Time{}, err
+	}
+	err = notifier.EnqueueEmail(ctx, db, models.EventoVerificacionEmail, []string{*inv.Email}, notifier.VerificacionEmail{
+		Nombre: inv.Nombre,
+		Enlace: baseURL + "/verificacion-email/" + token,
+		Horas:  horasVerificacionEmail,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return expiraEn, nil
+}
+
+// enviarVerificacionEmailAsync is enviarVerificacionEmail for background use: errors are only logged,
+// and without PUBLIC_BASE_URL nothing is sent.
+func enviarVerificacionEmailAsync(ctx context.Context, db *sql.DB, inv models.Investigador) {
+	baseURL := utils.PublicBaseURL()
+	if baseURL == "" {
+		logging.FromContext(ctx).Warn("PUBLIC_BASE_URL not set; email verification not sent", "id", inv.ID)
+		return
+	}
+	if _, err := enviarVerificacionEmail(ctx, db, baseURL, inv); err != nil {
+		logging.FromContext(ctx).Error("Error sending email verification to investigator", "id", inv.ID, "error", err)
+	}
+}
+
+// SolicitarVerificacionEmailHandler (re)sends the confirmation link for an investigator's email.
+// Responds 202 with the address and the link's expiry, or 503 without PUBLIC_BASE_URL.
+func SolicitarVerificacionEmailHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondError(w, "Invalid investigator ID", http.StatusBadRequest)
+			return
+		}
+		inv, err := repository.GetInvestigadorByID(r.Context(), db, id)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error getting investigator by ID", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if inv == nil {
+			utils.RespondError(w, "Investigador not found", http.StatusNotFound)
+			return
+		}
+		if inv.Email == nil {
+			utils.RespondFieldErrors(w, "El investigador no tiene email", http.StatusUnprocessableEntity, utils.FieldError{
+				Campo:   "email",
+				Codigo:  "email_requerido",
+				Mensaje: "Registre un email antes de solicitar su verificación",
+			})
+			return
+		}
+		if inv.EmailVerificado {
+			utils.RespondError(w, "El email ya está verificado", http.StatusConflict)
+			return
+		}
+
+		baseURL := utils.PublicBaseURL()
+		if baseURL == "" {
+			utils.RespondError(w, "Emailed links are not configured", http.StatusServiceUnavailable)
+			return
+		}
+		expiraEn, err := enviarVerificacionEmail(r.Context(), db, baseURL, *inv)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error sending email verification to investigator", "id", id, "error", err)
+			utils.RespondError(w, "No se pudo enviar el email de verificación", http.StatusInternalServerError)
+			return
+		}
+		utils.RespondJSON(w, http.StatusAccepted, map[string]interface{}{
+			"email":    *inv.Email,
+			"expiraEn": expiraEn,
+		})
+	}
+}
+
+// ConfirmarEmailHandler consumes a confirmation link and marks the investigator's email as verified.
+func ConfirmarEmailHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inv, err := repository.ConfirmarEmailInvestigador(r.Context(), db, hashToken(mux.Vars(r)["token"]))
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error confirming investigator email", "error", err)
+			utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if inv == nil {
+			utils.RespondError(w, "Enlace de verificación inválido o expirado", http.StatusNotFound)
+			return
+		}
+		// Public route: not covered by the invalidation of authenticated writes
+		directorioCache.Invalidate(r.Context())
+		utils.RespondJSON(w, http.StatusOK, inv)
+	}
+}
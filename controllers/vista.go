@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// vistaGrupo is the shape of the groups requested with ?fields= and ?expand= on group endpoints.
+// Without either parameter the endpoints answer as usual; with any of them each group is sent as
+// a flat object with only the selected fields (all of them without ?fields=) plus the expanded
+// relations, e.g. ?fields=idGrupo,nombre for a lightweight listing without members.
+type vistaGrupo struct {
+	activa bool
+	campos []string
+	expand map[string]bool
+}
+
+// parseVistaGrupo reads ?fields= and ?expand=; expansiones lists the relations the endpoint can
+// expand. It answers 400 and returns ok=false for unknown names.
+func parseVistaGrupo(w http.ResponseWriter, r *http.Request, expansiones ...string) (vistaGrupo, bool) {
+	q := r.URL.Query()
+	v := vistaGrupo{
+		activa: q.Has("fields") || q.Has("expand"),
+		campos: utils.QueryValues(r, "fields"),
+		expand: map[string]bool{},
+	}
+	if err := utils.CheckFields(models.Grupo{}, v.campos); err != nil {
+		utils.RespondError(w, fmt.Sprintf("Invalid fields: %v", err), http.StatusBadRequest)
+		return v, false
+	}
+	for _, e := range utils.QueryValues(r, "expand") {
+		valida := false
+		for _, permitida := range expansiones {
+			valida = valida || e == permitida
+		}
+		if !valida {
+			utils.RespondError(w, fmt.Sprintf("Invalid expand %q: use %s", e, strings.Join(expansiones, ", ")), http.StatusBadRequest)
+			return v, false
+		}
+		v.expand[e] = true
+	}
+	return v, true
+}
+
+// grupo returns g in the requested shape.
+func (v vistaGrupo) grupo(g models.GrupoWithInvestigadores) (map[string]interface{}, error) {
+	campos, err := utils.SelectFields(g.Grupo, v.campos)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]interface{}, len(campos)+3)
+	for k, valor := range campos {
+		out[k] = valor
+	}
+	if v.expand["investigadores"] {
+		out["investigadores"] = g.Investigadores
+	}
+	if v.expand["publicaciones"] {
+		publicaciones := g.Publicaciones
+		if publicaciones == nil {
+			publicaciones = []models.Publicacion{}
+		}
+		out["publicaciones"] = publicaciones
+	}
+	if v.expand["proyectos"] {
+		proyectos := g.Proyectos
+		if proyectos == nil {
+			proyectos = []models.ProyectoResumen{}
+		}
+		out["proyectos"] = proyectos
+	}
+	return out, nil
+}
+
+// datos returns the groups to send as response data: unchanged when no shape was requested.
+func (v vistaGrupo) datos(gs []models.GrupoWithInvestigadores) (interface{}, error) {
+	if !v.activa {
+		return gs, nil
+	}
+	out := make([]map[string]interface{}, len(gs))
+	for i, g := range gs {
+		m, err := v.grupo(g)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = m
+	}
+	return out, nil
+}
+
+// respondVistaGrupo writes a single group in the requested shape, with its ETag.
+func respondVistaGrupo(w http.ResponseWriter, r *http.Request, v vistaGrupo, g models.GrupoWithInvestigadores) {
+	m, err := v.grupo(g)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error selecting group fields", "error", err)
+		utils.RespondError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	utils.RespondJSONWithETag(w, r, m)
+}
@@ -0,0 +1,97 @@
+// Package ctivitae synchronizes investigators with CTI Vitae, the national researcher registry of
+// CONCYTEC (Dirección de Información y Gestión del Conocimiento, DINA).
+//
+// The registry is read through the JSON service set in CTI_VITAE_API_URL, which answers
+// GET {CTI_VITAE_API_URL}/investigadores/{idCtiVitae} with the investigator's registered data and
+// publications (see Registro). CTI_VITAE_API_TOKEN, if set, is sent as a bearer token. With no URL
+// set the integration is disabled.
+package ctivitae
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNoConfigurado is returned when CTI_VITAE_API_URL is not set.
+var ErrNoConfigurado = errors.New("la integración con CTI Vitae no está configurada")
+
+// ErrNoEncontrado is returned when the registry has no investigator with the given ID.
+var ErrNoEncontrado = errors.New("el investigador no existe en CTI Vitae")
+
+// ErrConsulta wraps the failures to reach the registry or to read its answer.
+var ErrConsulta = errors.New("no se pudo consultar CTI Vitae")
+
+var ctiVitaeClient = &http.Client{Timeout: 30 * time.Second}
+
+// Registro is an investigator as the registry returns it.
+type Registro struct {
+	ID              string                `json:"id"`
+	Nombres         string                `json:"nombres"`
+	ApellidoPaterno string                `json:"apellidoPaterno"`
+	ApellidoMaterno string                `json:"apellidoMaterno"`
+	Email           string                `json:"email"`
+	Publicaciones   []PublicacionRegistro `json:"publicaciones"`
+}
+
+// Apellidos returns both surnames as they are kept locally.
+func (r Registro) Apellidos() string {
+	return strings.TrimSpace(strings.TrimSpace(r.ApellidoPaterno) + " " + strings.TrimSpace(r.ApellidoMaterno))
+}
+
+// PublicacionRegistro is a publication of the registry's scientific production.
+type PublicacionRegistro struct {
+	Titulo  string `json:"titulo"`
+	DOI     string `json:"doi"`
+	Revista string `json:"revista"` // Journal, publisher or event
+	Anio    int    `json:"anio"`
+	Tipo    string `json:"tipo"` // As the registry names it, e.g. "Artículo científico"
+}
+
+// Configurado reports whether CTI_VITAE_API_URL is set.
+func Configurado() bool {
+	return os.Getenv("CTI_VITAE_API_URL") != ""
+}
+
+// Obtener fetches an investigator from the registry. It returns ErrNoConfigurado, ErrNoEncontrado
+// or ErrConsulta.
+func Obtener(ctx context.Context, idCtiVitae string) (*Registro, error) {
+	base := strings.TrimRight(os.Getenv("CTI_VITAE_API_URL"), "/")
+	if base == "" {
+		return nil, ErrNoConfigurado
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/investigadores/"+url.PathEscape(idCtiVitae), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error building CTI Vitae request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if token := os.Getenv("CTI_VITAE_API_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := ctiVitaeClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConsulta, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNoEncontrado
+	case resp.StatusCode != http.StatusOK:
+		cuerpo, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%w: respuesta %d: %s", ErrConsulta, resp.StatusCode, strings.TrimSpace(string(cuerpo)))
+	}
+
+	var r Registro
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("%w: respuesta no válida: %v", ErrConsulta, err)
+	}
+	return &r, nil
+}
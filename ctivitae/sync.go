@@ -0,0 +1,157 @@
+package ctivitae
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/validation"
+)
+
+// maxRevista is the longest revista a publicacion can store; longer ones are cut.
+const maxRevista = 300
+
+// Sincronizar pulls an investigator's record from the registry and reconciles it into the local
+// data, recording the attempt in the link:
+//   - email is filled in (unverified) when the investigator has none; nombre, apellido and a
+//     different email are left as they are and reported in Diferencias.
+//   - the registry's publications are merged with repository.ReconciliarPublicaciones, with the
+//     investigator as author, so nothing local is removed. Those that are not valid publicaciones
+//     (no titulo, anio out of range) are omitted, and an invalid DOI is dropped.
+//
+// It returns ErrNoConfigurado, ErrNoEncontrado or repository.ErrInvestigadorNoExiste.
+func Sincronizar(ctx context.Context, db *sql.DB, v models.CtiVitae) (*models.ResultadoSyncCtiVitae, error) {
+	inv, err := repository.GetInvestigadorByID(ctx, db, v.IDInvestigador)
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil {
+		return nil, repository.ErrInvestigadorNoExiste
+	}
+	res, err := sincronizar(ctx, db, inv, v.IDCtiVitae)
+	if errors.Is(err, ErrNoConfigurado) {
+		return nil, err
+	}
+	var errMsg *string
+	if err != nil {
+		msg := err.Error()
+		errMsg = &msg
+	}
+	if errReg := repository.RegistrarSincronizacionCtiVitae(ctx, db, v.IDInvestigador, errMsg); errReg != nil && err == nil {
+		err = errReg
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func sincronizar(ctx context.Context, db *sql.DB, inv *models.Investigador, idCtiVitae string) (*models.ResultadoSyncCtiVitae, error) {
+	reg, err := Obtener(ctx, idCtiVitae)
+	if err != nil {
+		return nil, err
+	}
+	res := &models.ResultadoSyncCtiVitae{
+		IDInvestigador:        inv.ID,
+		IDCtiVitae:            idCtiVitae,
+		CamposActualizados:    []string{},
+		Diferencias:           []models.DiferenciaCtiVitae{},
+		PublicacionesOmitidas: []models.PublicacionOmitida{},
+	}
+	res.Diferencias = append(res.Diferencias, diferencia("nombre", inv.Nombre, reg.Nombres)...)
+	res.Diferencias = append(res.Diferencias, diferencia("apellido", inv.Apellido, reg.Apellidos())...)
+
+	if email := strings.TrimSpace(reg.Email); email != "" {
+		if inv.Email == nil {
+			completado, err := repository.CompletarEmailInvestigador(ctx, db, inv.ID, email)
+			switch {
+			case errors.Is(err, repository.ErrEmailDuplicado):
+				res.Diferencias = append(res.Diferencias, models.DiferenciaCtiVitae{Campo: "email", CtiVitae: email})
+			case err != nil:
+				return nil, err
+			case completado:
+				res.CamposActualizados = append(res.CamposActualizados, "email")
+			}
+		} else {
+			res.Diferencias = append(res.Diferencias, diferencia("email", *inv.Email, email)...)
+		}
+	}
+
+	var pubs []models.Publicacion
+	for _, pr := range reg.Publicaciones {
+		p, motivo := publicacion(pr)
+		if motivo != "" {
+			res.PublicacionesOmitidas = append(res.PublicacionesOmitidas, models.PublicacionOmitida{Titulo: pr.Titulo, Motivo: motivo})
+			continue
+		}
+		p.IDInvestigadores = []int{inv.ID}
+		pubs = append(pubs, p)
+	}
+	reconciliadas, err := repository.ReconciliarPublicaciones(ctx, db, pubs)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reconciliadas {
+		if r.Creada {
+			res.PublicacionesCreadas++
+		} else if r.Vinculada {
+			res.PublicacionesVinculadas++
+		}
+	}
+	res.Sincronizado = time.Now()
+	return res, nil
+}
+
+// diferencia reports campo if the registry has a value for it that differs, ignoring case and
+// surrounding spaces, from the local one.
+func diferencia(campo, local, registro string) []models.DiferenciaCtiVitae {
+	registro = strings.TrimSpace(registro)
+	if registro == "" || strings.EqualFold(strings.TrimSpace(local), registro) {
+		return nil
+	}
+	return []models.DiferenciaCtiVitae{{Campo: campo, Local: local, CtiVitae: registro}}
+}
+
+// publicacion converts a registry publication to a local one, or returns why it cannot be imported.
+func publicacion(pr PublicacionRegistro) (models.Publicacion, string) {
+	p := models.Publicacion{
+		Titulo:  strings.TrimSpace(pr.Titulo),
+		Revista: strings.TrimSpace(pr.Revista),
+		Anio:    pr.Anio,
+		Tipo:    tipoPublicacion(pr.Tipo),
+	}
+	if utf8.RuneCountInString(p.Revista) > maxRevista {
+		p.Revista = string([]rune(p.Revista)[:maxRevista])
+	}
+	if doi, ok := utils.NormalizarDOI(pr.DOI); ok {
+		p.DOI = &doi
+	}
+	if campos := validation.Struct(&p); len(campos) > 0 {
+		return p, campos[0].Mensaje
+	}
+	return p, ""
+}
+
+// tipoPublicacion maps the type names of the registry to the local tipos.
+func tipoPublicacion(tipo string) string {
+	t := strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u").Replace(strings.ToLower(tipo))
+	switch {
+	case strings.Contains(t, "articulo"):
+		return models.PublicacionArticulo
+	case strings.Contains(t, "capitulo"):
+		return models.PublicacionCapitulo
+	case strings.Contains(t, "libro"):
+		return models.PublicacionLibro
+	case strings.Contains(t, "ponencia"), strings.Contains(t, "congreso"), strings.Contains(t, "conferencia"):
+		return models.PublicacionPonencia
+	case strings.Contains(t, "tesis"):
+		return models.PublicacionTesis
+	}
+	return models.PublicacionOtro
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/cloudsqlconn"
+	"github.com/jackc/pgx/v5"
+)
+
+// cloudSQLConfig is the connection to a Cloud SQL instance through the Cloud SQL Go connector,
+// which finds the instance by its connection name and opens an mTLS tunnel to it authorized by IAM,
+// without authorized networks, the Auth Proxy or a host and port.
+type cloudSQLConfig struct {
+	instance string // project:region:instance
+	iamAuth  bool   // Log in as the IAM principal in DB_USER instead of with DB_PASSWORD
+	ipType   string // public, private or psc
+}
+
+// cloudSQLConfigFromEnv reads INSTANCE_CONNECTION_NAME, DB_IAM_AUTH and DB_IP_TYPE. It returns nil
+// when INSTANCE_CONNECTION_NAME is not set, i.e. the database is reached at DB_HOST and DB_PORT.
+func cloudSQLConfigFromEnv() (*cloudSQLConfig, error) {
+	instance := os.Getenv("INSTANCE_CONNECTION_NAME")
+	if instance == "" {
+		return nil, nil
+	}
+	if strings.Count(instance, ":") != 2 {
+		return nil, fmt.Errorf("invalid INSTANCE_CONNECTION_NAME %q: use project:region:instance", instance)
+	}
+	c := &cloudSQLConfig{instance: instance, ipType: "public"}
+	if v := os.Getenv("DB_IAM_AUTH"); v != "" {
+		iamAuth, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DB_IAM_AUTH %q: use true or false", v)
+		}
+		c.iamAuth = iamAuth
+	}
+	if v := os.Getenv("DB_IP_TYPE"); v != "" {
+		c.ipType = strings.ToLower(v)
+	}
+	switch c.ipType {
+	case "public", "private", "psc":
+	default:
+		return nil, fmt.Errorf("invalid DB_IP_TYPE %q: use public, private or psc", c.ipType)
+	}
+	return c, nil
+}
+
+// apply makes config dial the instance through the connector. The dialer refreshes the instance
+// certificates on demand (lazy refresh), as recommended on Cloud Run, where the CPU may be
+// throttled between requests; it lives as long as the process.
+func (c *cloudSQLConfig) apply(config *pgx.ConnConfig) error {
+	opts := []cloudsqlconn.Option{cloudsqlconn.WithLazyRefresh()}
+	if c.iamAuth {
+		opts = append(opts, cloudsqlconn.WithIAMAuthN())
+	}
+	switch c.ipType {
+	case "private":
+		opts = append(opts, cloudsqlconn.WithDefaultDialOptions(cloudsqlconn.WithPrivateIP()))
+	case "psc":
+		opts = append(opts, cloudsqlconn.WithDefaultDialOptions(cloudsqlconn.WithPSC()))
+	}
+	dialer, err := cloudsqlconn.NewDialer(context.Background(), opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create the Cloud SQL dialer: %w", err)
+	}
+	// El conector cifra la conexión: pgx no negocia TLS por su cuenta
+	config.TLSConfig = nil
+	config.Fallbacks = nil
+	config.DialFunc = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.Dial(ctx, c.instance)
+	}
+	slog.Info("Connecting through the Cloud SQL connector", "instance", c.instance, "iam_auth", c.iamAuth, "ip_type", c.ipType)
+	return nil
+}
@@ -2,18 +2,65 @@ package database
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"time"
 
-	// Importa el driver de PostgreSQL
-	_ "github.com/lib/pq"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/telemetry"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
-// InitDB initializes and returns a database connection.
+// InitDB initializes and returns a database connection to the backend selected by DB_DRIVER.
 func InitDB() (*sql.DB, error) {
-	log.Print("initializing postgresql database connection...")
+	driverName := Driver()
+	slog.Info("initializing database connection", "driver", driverName)
 
+	var (
+		connector driver.Connector
+		err       error
+	)
+	if driverName == DriverSQLite {
+		var path string
+		if connector, path, err = sqliteConnectorFromEnv(); err != nil {
+			return nil, err
+		}
+		slog.Warn("Using the SQLite backend, meant for local development and demos", "path", path)
+	} else if connector, err = postgresConnectorFromEnv(); err != nil {
+		return nil, err
+	}
+
+	// Las consultas más lentas que DB_SLOW_QUERY_THRESHOLD se registran en el log
+	slowQueryThreshold, err := slowQueryThresholdFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	var c driver.Connector = connector
+	if slowQueryThreshold > 0 {
+		c = slowQueryConnector{Connector: connector, threshold: slowQueryThreshold}
+	}
+	// Cada consulta genera un span si el tracing está activo
+	db := telemetry.OpenSQL(c)
+
+	pool := PoolConfigFromEnv()
+	pool.apply(db)
+
+	// Reintenta mientras la base de datos termina de arrancar
+	if err := pingWithRetry(db, pool.ConnectTimeout); err != nil {
+		db.Close() // Cierra la conexión si el ping falla
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	slog.Info("Database connection successfully established", "driver", driverName, "max_open_conns", pool.MaxOpenConns, "max_idle_conns", pool.MaxIdleConns, "slow_query_threshold", slowQueryThreshold)
+	return db, nil
+}
+
+// postgresConnectorFromEnv returns the connector to the PostgreSQL database set by the DB_*
+// environment variables, reached through the Cloud SQL connector when INSTANCE_CONNECTION_NAME is
+// set.
+func postgresConnectorFromEnv() (driver.Connector, error) {
 	// Usa los NOMBRES de las variables de entorno
 	dbUser := os.Getenv("DB_USER")         // Nombre de la variable, ej: postgres
 	dbPassword := os.Getenv("DB_PASSWORD") // Nombre de la variable, ej: 123456
@@ -22,31 +69,66 @@ func InitDB() (*sql.DB, error) {
 	dbName := os.Getenv("DB_NAME")         // Nombre de la variable, ej: db_PIUnamba
 	dbSSLMode := os.Getenv("DB_SSLMODE")   // Opcional, ej: disable
 
+	cloudSQL, err := cloudSQLConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
 	// Validaciones básicas (opcional pero recomendado)
-	if dbUser == "" || dbPassword == "" || dbHost == "" || dbPort == "" || dbName == "" {
-		log.Fatal("Database environment variables DB_USER, DB_PASSWORD, DB_HOST, DB_PORT, DB_NAME must be set")
+	if cloudSQL != nil {
+		// El conector de Cloud SQL localiza la instancia y cifra la conexión; con IAM no hay contraseña
+		if dbUser == "" || dbName == "" || (dbPassword == "" && !cloudSQL.iamAuth) {
+			return nil, fmt.Errorf("with INSTANCE_CONNECTION_NAME the database environment variables DB_USER, DB_NAME and DB_PASSWORD (unless DB_IAM_AUTH is set) must be set")
+		}
+		dbHost, dbPort, dbSSLMode = "localhost", "5432", "disable"
+	} else if dbUser == "" || dbPassword == "" || dbHost == "" || dbPort == "" || dbName == "" {
+		return nil, fmt.Errorf("database environment variables DB_USER, DB_PASSWORD, DB_HOST, DB_PORT and DB_NAME must be set")
 	}
 	if dbSSLMode == "" {
 		dbSSLMode = "disable" // Valor por defecto si no se especifica
 	}
 
-	// Construye el DSN (Data Source Name) para PostgreSQL
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		dbHost, dbPort, dbUser, dbPassword, dbName, dbSSLMode)
-
-	var err error
-	// Usa "postgres" como nombre del driver
-	db, err := sql.Open("postgres", dsn)
+	statementTimeout, err := statementTimeoutFromEnv()
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database connection: %w", err)
+		return nil, err
 	}
 
-	err = db.Ping()
+	// Construye el DSN (Data Source Name) para PostgreSQL.
+	// pgx envía los parámetros que no reconoce (statement_timeout) como configuración de la sesión.
+	dsn := fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=%s statement_timeout=%d",
+		dbHost, dbPort, dbUser, dbName, dbSSLMode, statementTimeout.Milliseconds())
+
+	config, err := pgx.ParseConfig(dsn)
 	if err != nil {
-		db.Close() // Cierra la conexión si el ping falla
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
+	config.Password = dbPassword
+	if cloudSQL != nil {
+		if err := cloudSQL.apply(config); err != nil {
+			return nil, err
+		}
+	}
+	// database/sql sobre pgx: los repositorios siguen recibiendo un *sql.DB y pueden usar la conexión
+	// pgx subyacente para enviar lotes de sentencias (ver repository.WithTx)
+	return stdlib.GetConnector(*config), nil
+}
 
-	log.Println("PostgreSQL Database connection successfully established")
-	return db, nil
+// defaultStatementTimeout limits how long a single query may run, so a slow query cannot hold a
+// connection indefinitely. Queries are also cancelled when the request that issued them is.
+const defaultStatementTimeout = 30 * time.Second
+
+// statementTimeoutFromEnv reads DB_STATEMENT_TIMEOUT (e.g. "15s"; "0" disables the limit).
+func statementTimeoutFromEnv() (time.Duration, error) {
+	v := os.Getenv("DB_STATEMENT_TIMEOUT")
+	if v == "" {
+		return defaultStatementTimeout, nil
+	}
+	if v == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid DB_STATEMENT_TIMEOUT %q: use a duration such as 30s", v)
+	}
+	return d, nil
 }
@@ -0,0 +1,182 @@
+// Package databasetest opens databases for tests. Each one is a new database of the test's own with
+// the schema and the sample data of database.Seed: three groups, five investigators and six
+// memberships.
+//
+// PostgreSQL, the production backend, is the one tests run against. Its server is the one at
+// TEST_DATABASE_URL if set (e.g. a CI service container), or else an ephemeral one started with
+// embedded-postgres on first use and stopped by Main. When neither is available the PostgreSQL tests
+// are skipped, unless TEST_POSTGRES=required makes that a failure. SQLite (the backend of demo mode,
+// see "Backend SQLite" in the README) is a second backend, tested with Run.
+package databasetest
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database"
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers the "pgx" driver used for the server's admin connection
+)
+
+// servidor is the ephemeral PostgreSQL server started by postgresURL, nil with TEST_DATABASE_URL.
+var (
+	servidor    *embeddedpostgres.EmbeddedPostgres
+	servidorDir string
+)
+
+// postgresURL returns the URL of the PostgreSQL server the test databases are created in, starting
+// the ephemeral one the first time it is needed.
+var postgresURL = sync.OnceValues(func() (*url.URL, error) {
+	if v := os.Getenv("TEST_DATABASE_URL"); v != "" {
+		return url.Parse(v)
+	}
+
+	// A free port for the server, released just before it starts
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	port := lis.Addr().(*net.TCPAddr).Port
+	lis.Close()
+
+	dir, err := os.MkdirTemp("", "apigrupos-postgres-")
+	if err != nil {
+		return nil, err
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		cache = dir
+	}
+	pg := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Version(embeddedpostgres.V16).
+		Port(uint32(port)).
+		RuntimePath(dir).
+		CachePath(filepath.Join(cache, "apigrupos-embedded-postgres")).
+		StartTimeout(time.Minute).
+		Logger(nil))
+	if err := pg.Start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("starting the ephemeral PostgreSQL server: %w", err)
+	}
+	servidor, servidorDir = pg, dir
+	return &url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword("postgres", "postgres"),
+		Host:     fmt.Sprintf("127.0.0.1:%d", port),
+		Path:     "/postgres",
+		RawQuery: "sslmode=disable",
+	}, nil
+})
+
+// Main runs the tests of a package that uses Open and then stops the ephemeral PostgreSQL server, if
+// one was started. Call it from TestMain: os.Exit(databasetest.Main(m)).
+func Main(m *testing.M) int {
+	code := m.Run()
+	if servidor != nil {
+		if err := servidor.Stop(); err != nil {
+			fmt.Fprintln(os.Stderr, "stopping the ephemeral PostgreSQL server:", err)
+		}
+		os.RemoveAll(servidorDir)
+	}
+	return code
+}
+
+// numeroBase numbers the databases created on the PostgreSQL server.
+var numeroBase atomic.Int64
+
+// Open returns a seeded PostgreSQL database of the test's own, dropped when the test ends. It sets
+// DB_DRIVER and the DB_* connection variables for the test, so tests that use it cannot run in
+// parallel.
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+	servidorURL, err := postgresURL()
+	if err != nil {
+		if os.Getenv("TEST_POSTGRES") == "required" {
+			t.Fatalf("PostgreSQL not available: %v", err)
+		}
+		t.Skipf("PostgreSQL not available (set TEST_DATABASE_URL, or TEST_POSTGRES=required to fail): %v", err)
+	}
+
+	admin, err := sql.Open("pgx", servidorURL.String())
+	if err != nil {
+		t.Fatalf("connecting to the PostgreSQL server: %v", err)
+	}
+	defer admin.Close()
+	nombre := fmt.Sprintf("apigrupos_test_%d_%d", os.Getpid(), numeroBase.Add(1))
+	if _, err := admin.Exec(`CREATE DATABASE ` + nombre); err != nil {
+		t.Fatalf("creating the test database: %v", err)
+	}
+
+	password, _ := servidorURL.User.Password()
+	t.Setenv("DB_DRIVER", database.DriverPostgres)
+	t.Setenv("DEMO_MODE", "")
+	t.Setenv("INSTANCE_CONNECTION_NAME", "")
+	t.Setenv("DB_HOST", servidorURL.Hostname())
+	t.Setenv("DB_PORT", servidorURL.Port())
+	t.Setenv("DB_USER", servidorURL.User.Username())
+	t.Setenv("DB_PASSWORD", password)
+	t.Setenv("DB_NAME", nombre)
+	t.Setenv("DB_SSLMODE", servidorURL.Query().Get("sslmode"))
+
+	db, err := database.InitDB()
+	if err != nil {
+		t.Fatalf("opening the test database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		admin, err := sql.Open("pgx", servidorURL.String())
+		if err != nil {
+			return
+		}
+		defer admin.Close()
+		if _, err := admin.Exec(`DROP DATABASE IF EXISTS ` + nombre + ` WITH (FORCE)`); err != nil {
+			t.Logf("dropping the test database: %v", err)
+		}
+	})
+	cargar(t, db)
+	return db
+}
+
+// OpenSQLite returns a seeded SQLite database of the test's own, closed and removed when the test
+// ends. It sets DB_DRIVER for the test, so tests that use it cannot run in parallel.
+func OpenSQLite(t testing.TB) *sql.DB {
+	t.Helper()
+	t.Setenv("DB_DRIVER", database.DriverSQLite)
+	t.Setenv("DEMO_MODE", "") // Demo mode shares one in-memory database across the process
+	t.Setenv("SQLITE_PATH", filepath.Join(t.TempDir(), "apigrupos.db"))
+
+	db, err := database.InitDB()
+	if err != nil {
+		t.Fatalf("opening the test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	cargar(t, db)
+	return db
+}
+
+// cargar creates the schema of a new test database and loads the sample data.
+func cargar(t testing.TB, db *sql.DB) {
+	t.Helper()
+	if err := database.InitSchema(db); err != nil {
+		t.Fatalf("creating the test schema: %v", err)
+	}
+	if err := database.Seed(db); err != nil {
+		t.Fatalf("loading the sample data: %v", err)
+	}
+}
+
+// Run runs f as a subtest on each backend, "postgres" (see Open) and "sqlite" (see OpenSQLite), with
+// a seeded database of its own.
+func Run(t *testing.T, f func(t *testing.T, db *sql.DB)) {
+	t.Helper()
+	t.Run("postgres", func(t *testing.T) { f(t, Open(t)) })
+	t.Run("sqlite", func(t *testing.T) { f(t, OpenSQLite(t)) })
+}
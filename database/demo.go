@@ -0,0 +1,15 @@
+package database
+
+import (
+	"os"
+	"strconv"
+)
+
+// DemoMode reports whether DEMO_MODE is set: the API runs on an in-memory SQLite database that is
+// created and seeded at startup and lost when the process exits, so it needs no PostgreSQL. It is
+// meant for frontend development, handler tests in CI and live demos. The repository is the same
+// as in production, not an in-memory copy of it (see "Modo demo" in the README).
+func DemoMode() bool {
+	demo, _ := strconv.ParseBool(os.Getenv("DEMO_MODE"))
+	return demo
+}
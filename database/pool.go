@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxOpenConns    = 10
+	defaultMaxIdleConns    = 5
+	defaultConnMaxLifetime = 30 * time.Minute
+	defaultConnectTimeout  = time.Minute // Total time to keep retrying the first connection
+	retryInicial           = 500 * time.Millisecond
+	retryMaximo            = 10 * time.Second
+)
+
+// PoolConfig sizes the connection pool. In Cloud Run, MaxOpenConns times the maximum number of
+// instances must stay below the database's max_connections.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration // Connections are recycled after this long, e.g. to follow a failover
+	ConnectTimeout  time.Duration
+}
+
+// PoolConfigFromEnv reads DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS, DB_CONN_MAX_LIFETIME and
+// DB_CONNECT_TIMEOUT, using the defaults for unset or invalid values.
+func PoolConfigFromEnv() PoolConfig {
+	c := PoolConfig{
+		MaxOpenConns:    envInt("DB_MAX_OPEN_CONNS", defaultMaxOpenConns),
+		MaxIdleConns:    envInt("DB_MAX_IDLE_CONNS", defaultMaxIdleConns),
+		ConnMaxLifetime: envDuration("DB_CONN_MAX_LIFETIME", defaultConnMaxLifetime),
+		ConnectTimeout:  envDuration("DB_CONNECT_TIMEOUT", defaultConnectTimeout),
+	}
+	if c.MaxIdleConns > c.MaxOpenConns {
+		c.MaxIdleConns = c.MaxOpenConns
+	}
+	return c
+}
+
+// apply configures db's pool.
+func (c PoolConfig) apply(db *sql.DB) {
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+}
+
+// pingWithRetry waits for the database to accept connections, retrying with exponential backoff
+// for up to timeout, so the API survives the database starting a little later (docker-compose,
+// Cloud SQL after a restart).
+func pingWithRetry(db *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	espera := retryInicial
+	for intento := 1; ; intento++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("database not reachable after %s (%d attempts): %w", timeout, intento, err)
+		}
+		slog.Warn("Database not ready, retrying", "attempt", intento, "error", err, "retry_in", espera)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database not reachable after %s (%d attempts): %w", timeout, intento, err)
+		case <-time.After(espera):
+		}
+		espera = min(espera*2, retryMaximo)
+	}
+}
+
+func envInt(name string, def int) int {
+	if v := os.Getenv(name); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+		slog.Warn("Invalid value, using the default", "variable", name, "value", v, "default", def)
+	}
+	return def
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	if v := os.Getenv(name); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		slog.Warn("Invalid value, using the default", "variable", name, "value", v, "default", def)
+	}
+	return def
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// PrepareRestore lets tx reload the given tables with rows that were valid when they were saved,
+// as a backup restore does: the triggers (updatedAt, memberships only of active investigators) are
+// turned off and, with SQLite, foreign keys are checked at commit instead of row by row. The
+// returned function turns the triggers back on; it must be called before committing. Nothing
+// outlives tx: if it is rolled back, so are these changes.
+//
+// With PostgreSQL the database user must own the tables (as it does after the migrate command);
+// foreign keys are still checked by each statement.
+func PrepareRestore(ctx context.Context, tx *sql.Tx, tablas []string) (func(context.Context) error, error) {
+	if Driver() == DriverSQLite {
+		return prepareRestoreSQLite(ctx, tx)
+	}
+	for _, tabla := range tablas {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s DISABLE TRIGGER USER`, tabla)); err != nil {
+			return nil, fmt.Errorf("error disabling the triggers of %s: %w", tabla, err)
+		}
+	}
+	return func(ctx context.Context) error {
+		for _, tabla := range tablas {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ENABLE TRIGGER USER`, tabla)); err != nil {
+				return fmt.Errorf("error enabling the triggers of %s: %w", tabla, err)
+			}
+		}
+		return nil
+	}, nil
+}
+
+// prepareRestoreSQLite drops the triggers, which SQLite can't disable, and recreates them from
+// their saved definitions afterwards.
+func prepareRestoreSQLite(ctx context.Context, tx *sql.Tx) (func(context.Context) error, error) {
+	if _, err := tx.ExecContext(ctx, `PRAGMA defer_foreign_keys = ON`); err != nil {
+		return nil, fmt.Errorf("error deferring foreign keys: %w", err)
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT name, sql FROM sqlite_master WHERE type = 'trigger'`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying triggers: %w", err)
+	}
+	defer rows.Close()
+	triggers := map[string]string{}
+	for rows.Next() {
+		var nombre, definicion string
+		if err := rows.Scan(&nombre, &definicion); err != nil {
+			return nil, fmt.Errorf("error scanning trigger: %w", err)
+		}
+		triggers[nombre] = definicion
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating triggers: %w", err)
+	}
+	rows.Close()
+
+	for nombre := range triggers {
+		if _, err := tx.ExecContext(ctx, `DROP TRIGGER `+nombre); err != nil {
+			return nil, fmt.Errorf("error dropping trigger %s: %w", nombre, err)
+		}
+	}
+	return func(ctx context.Context) error {
+		for nombre, definicion := range triggers {
+			if _, err := tx.ExecContext(ctx, definicion); err != nil {
+				return fmt.Errorf("error recreating trigger %s: %w", nombre, err)
+			}
+		}
+		return nil
+	}, nil
+}
+
+// ResetSequence makes the ids generated for tabla.columna continue after the largest one in the
+// table, once rows have been inserted with explicit ids. SQLite already does it on its own.
+func ResetSequence(ctx context.Context, tx *sql.Tx, tabla, columna string) error {
+	if Driver() == DriverSQLite {
+		return nil
+	}
+	// pg_get_serial_sequence takes the column name literally: unquoted identifiers are lowercase
+	query := fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%[1]s', '%[2]s'), COALESCE(MAX(%[2]s), 0) + 1, false) FROM %[1]s`, tabla, strings.ToLower(columna))
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("error resetting the sequence of %s.%s: %w", tabla, columna, err)
+	}
+	return nil
+}
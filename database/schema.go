@@ -0,0 +1,48 @@
+package database
+
+import (
+	"database/sql"
+	_ "embed" // Para embeber schema.sql en el binario
+	"fmt"
+	"log/slog"
+)
+
+// schemaSQL contiene el esquema idempotente (tablas, índices, extensiones y catálogos).
+//
+//go:embed schema.sql
+var schemaSQL string
+
+// schemaSQLiteSQL es la traducción de schema.sql para DB_DRIVER=sqlite.
+//
+//go:embed schema_sqlite.sql
+var schemaSQLiteSQL string
+
+// InitSchema crea (o completa) el esquema de la base de datos.
+// Es seguro ejecutarlo varias veces: todas las sentencias son idempotentes.
+func InitSchema(db *sql.DB) error {
+	slog.Info("initializing database schema")
+	// Sin argumentos, pgx usa el protocolo simple y acepta múltiples sentencias en un solo Exec.
+	// Se ejecuta dentro de una transacción para no dejar el esquema a medias si algo falla.
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start schema transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	schema := schemaSQLiteSQL
+	if Driver() == DriverPostgres {
+		// Building indexes on large tables may take longer than DB_STATEMENT_TIMEOUT
+		if _, err := tx.Exec(`SET LOCAL statement_timeout = 0`); err != nil {
+			return fmt.Errorf("failed to disable the statement timeout: %w", err)
+		}
+		schema = schemaSQL
+	}
+	if _, err := tx.Exec(schema); err != nil {
+		return fmt.Errorf("failed to apply schema: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit schema: %w", err)
+	}
+	slog.Info("Database schema is up to date")
+	return nil
+}
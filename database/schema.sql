@@ -1,81 +1,906 @@
+-- Esquema de la base de datos.
+-- Este script es idempotente: puede ejecutarse sobre una base vacía o sobre una ya
+-- inicializada (go run . migrate) sin perder datos.
+
+-- Extensiones
+CREATE EXTENSION IF NOT EXISTS unaccent; -- Búsquedas sin acentos
+CREATE EXTENSION IF NOT EXISTS pg_trgm;  -- Índices trigram para búsquedas ILIKE
+CREATE EXTENSION IF NOT EXISTS btree_gist; -- Restricción de exclusión de los periodos de membresía
+
+-- Collation ICU en español: ordena "Ñuñoa" entre N y O y no separa las vocales acentuadas
+-- (la collation por defecto "C"/"en_US" deja la Ñ y las tildes después de la Z).
+CREATE COLLATION IF NOT EXISTS es_icu (provider = icu, locale = 'es-ES');
+
+-- Configuración de búsqueda de texto completo en español sin acentos (usada por Grupo.busqueda).
+-- Es inmutable, a diferencia de unaccent(), por lo que puede usarse en columnas generadas.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'es_unaccent') THEN
+        CREATE TEXT SEARCH CONFIGURATION es_unaccent (COPY = spanish);
+        ALTER TEXT SEARCH CONFIGURATION es_unaccent
+            ALTER MAPPING FOR hword, hword_part, word WITH unaccent, spanish_stem;
+    END IF;
+END
+$$;
+
+-- unaccent() depende del search_path y no es inmutable, por lo que no puede usarse en índices.
+-- f_unaccent fija el diccionario y sí lo es: las búsquedas ILIKE sin acentos la usan en ambos lados
+-- para aprovechar los índices trigram (ver Índices).
+CREATE OR REPLACE FUNCTION f_unaccent(text) RETURNS text
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+    AS $$ SELECT public.unaccent('public.unaccent'::regdictionary, $1) $$;
+
 -- Table: usuario (Application Users)
-CREATE TABLE Usuario (
+CREATE TABLE IF NOT EXISTS Usuario (
     idUsuario SERIAL PRIMARY KEY,            -- Changed from id_usuario
     -- Removed supabase_user_id UUID UNIQUE NOT NULL,
     email VARCHAR(150) UNIQUE NOT NULL,
     password TEXT NOT NULL,                   -- Added password field (will store hash)
-    -- Removed rol_aplicacion
-    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP, 
+    rol VARCHAR(20) NOT NULL DEFAULT 'usuario', -- Application role: 'usuario' or 'admin'
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Table: facultad (Faculties of the university)
+CREATE TABLE IF NOT EXISTS facultad (
+    idFacultad SERIAL PRIMARY KEY,
+    nombre VARCHAR(200) COLLATE es_icu UNIQUE NOT NULL,
+    siglas VARCHAR(20),
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: escuela_profesional (Professional schools, each within a faculty)
+CREATE TABLE IF NOT EXISTS escuela_profesional (
+    idEscuela SERIAL PRIMARY KEY,
+    idFacultad INT NOT NULL REFERENCES facultad(idFacultad) ON DELETE RESTRICT,
+    nombre VARCHAR(200) COLLATE es_icu NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_escuela_profesional_nombre UNIQUE (idFacultad, nombre)
+);
+
 -- Table: Investigador (Researchers)
-CREATE TABLE Investigador (
+CREATE TABLE IF NOT EXISTS Investigador (
     idInvestigador SERIAL PRIMARY KEY, -- SERIAL is PostgreSQL's auto-incrementing integer
-    nombre VARCHAR(100) NOT NULL,
-    apellido VARCHAR(100) NOT NULL,
+    nombre VARCHAR(100) COLLATE es_icu NOT NULL,
+    apellido VARCHAR(100) COLLATE es_icu NOT NULL,
+    email VARCHAR(254), -- Contact email, unique ignoring case
+    emailVerificado BOOLEAN NOT NULL DEFAULT FALSE, -- Set when the confirmation link is opened
     createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP -- Sets timestamp on creation only
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deletedAt TIMESTAMP, -- Soft delete: NULL while the investigator is active
+    idFacultad INT REFERENCES facultad(idFacultad) ON DELETE RESTRICT, -- Faculty the investigator belongs to
+    idEscuela INT REFERENCES escuela_profesional(idEscuela) ON DELETE RESTRICT, -- School, within that faculty
+    tipo VARCHAR(20) NOT NULL DEFAULT 'docente', -- 'docente', 'estudiante' (tesistas, semilleros) or 'externo'
+    codigoMatricula VARCHAR(20) -- Student enrollment code, unique ignoring case
 );
 
 -- Table: Grupo (Research Groups)
-CREATE TABLE Grupo (
+CREATE TABLE IF NOT EXISTS Grupo (
     idGrupo SERIAL PRIMARY KEY,
-    nombre VARCHAR(150) NOT NULL,
+    nombre VARCHAR(150) COLLATE es_icu NOT NULL,
     numeroResolucion VARCHAR(100) NOT NULL,
     lineaInvestigacion VARCHAR(200) NOT NULL,
     tipoInvestigacion VARCHAR(100) NOT NULL,
     fechaRegistro DATE NOT NULL,
-    archivo VARCHAR(255), -- Assuming this stores a file path or name
+    archivo VARCHAR(255), -- Storage ref ("<backend>:<key>", or a legacy Google Drive file ID)
+    estado VARCHAR(20) NOT NULL DEFAULT 'activo', -- 'activo', 'inactivo', 'en_renovacion' or 'cerrado'
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deletedAt TIMESTAMP, -- Soft delete: NULL while the group is active
+    idGrupoPadre INT REFERENCES Grupo(idGrupo) ON DELETE SET NULL, -- Parent group (e.g. the institute of a semillero)
+    idFacultad INT REFERENCES facultad(idFacultad) ON DELETE RESTRICT, -- Faculty the group belongs to
+    fechaVencimiento DATE, -- Latest fechaVencimiento of its resoluciones, kept by the repository; NULL = sin vigencia
+    busqueda tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('es_unaccent', coalesce(nombre, '')), 'A') ||
+        setweight(to_tsvector('es_unaccent', coalesce(numeroResolucion, '')), 'B') ||
+        setweight(to_tsvector('es_unaccent', coalesce(lineaInvestigacion, '')), 'C')
+    ) STORED -- Full-text search (?q=)
+);
+
+-- Table: resolucion (Resolutions issued for a group: creation, renewal, member changes...)
+CREATE TABLE IF NOT EXISTS resolucion (
+    idResolucion SERIAL PRIMARY KEY,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    numero VARCHAR(100) NOT NULL,
+    fechaEmision DATE NOT NULL,
+    tipo VARCHAR(30) NOT NULL DEFAULT 'otro', -- 'creacion', 'renovacion', 'cambio_integrantes' or 'otro'
+    descripcion TEXT NOT NULL DEFAULT '',
+    archivo VARCHAR(255), -- Storage ref of the PDF (see Grupo.archivo)
+    fechaVencimiento DATE, -- End of the group's vigencia granted by a creacion or renovacion resolution
+    subidoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
     createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP -- Sets timestamp on creation only
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_resolucion_grupo_numero UNIQUE (idGrupo, numero)
+);
+
+-- Table: renovacion (Renewal requests of a group's vigencia, reviewed by an admin)
+CREATE TABLE IF NOT EXISTS renovacion (
+    idRenovacion SERIAL PRIMARY KEY,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente', -- 'pendiente', 'aprobada' or 'rechazada'
+    motivo TEXT NOT NULL DEFAULT '',
+    observaciones TEXT NOT NULL DEFAULT '', -- Reviewer's notes
+    estadoAnterior VARCHAR(20) NOT NULL, -- Group estado when it was submitted, restored if it is rejected
+    idResolucion INT REFERENCES resolucion(idResolucion) ON DELETE SET NULL, -- Renewal resolution, once approved
+    solicitadoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    revisadoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    fechaRevision TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: aviso_vencimiento (Expiry notices already sent, one per group and fechaVencimiento)
+CREATE TABLE IF NOT EXISTS aviso_vencimiento (
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    fechaVencimiento DATE NOT NULL,
+    enviadoEn TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (idGrupo, fechaVencimiento)
 );
 
 -- Table: Grupo_Investigador (Associative table for Groups and Researchers)
-CREATE TABLE Grupo_Investigador (
+CREATE TABLE IF NOT EXISTS Grupo_Investigador (
     idGrupo_Investigador SERIAL PRIMARY KEY,
     idGrupo INT NOT NULL,
     idInvestigador INT NOT NULL,
     rol VARCHAR(50) NOT NULL, -- e.g., 'Coordinador' or 'Integrante'
+    fechaInicio DATE, -- Membership period; NULL start = unknown, NULL end = still a member
+    fechaFin DATE,
     createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- Sets timestamp on creation only
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (idGrupo) REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
     FOREIGN KEY (idInvestigador) REFERENCES Investigador(idInvestigador) ON DELETE CASCADE
 );
 
--- Función para actualizar updatedAt
+-- Table: solicitud_grupo (Public group registration requests awaiting moderation)
+CREATE TABLE IF NOT EXISTS solicitud_grupo (
+    idSolicitud SERIAL PRIMARY KEY,
+    nombre VARCHAR(150) NOT NULL,
+    numeroResolucion VARCHAR(100) NOT NULL DEFAULT '',
+    lineaInvestigacion VARCHAR(200) NOT NULL,
+    tipoInvestigacion VARCHAR(100) NOT NULL,
+    descripcion TEXT NOT NULL DEFAULT '',
+    nombreSolicitante VARCHAR(100) NOT NULL,
+    apellidoSolicitante VARCHAR(100) NOT NULL,
+    emailSolicitante VARCHAR(150) NOT NULL,
+    integrantes JSONB NOT NULL DEFAULT '[]', -- Proposed members: [{idInvestigador?, nombre, apellido, rol}]
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente', -- 'pendiente', 'aprobada' or 'rechazada'
+    comentario TEXT, -- Moderator comments
+    idGrupo INT REFERENCES Grupo(idGrupo) ON DELETE SET NULL, -- Group created on approval
+    revisadoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    fechaRevision TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: grupo_archivo (Attachments of a group: resolutions, evaluations, internal reports...)
+CREATE TABLE IF NOT EXISTS grupo_archivo (
+    idArchivo SERIAL PRIMARY KEY,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    nombre VARCHAR(200) NOT NULL,
+    tipo VARCHAR(50) NOT NULL DEFAULT 'otro', -- 'resolucion', 'evaluacion', 'informe' or 'otro'
+    archivo VARCHAR(255) NOT NULL, -- Storage ref (see Grupo.archivo)
+    publico BOOLEAN NOT NULL DEFAULT FALSE, -- Non-public files are only reachable through share links
+    subidoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: enlace_compartido (Time-boxed share links for non-public attachments)
+CREATE TABLE IF NOT EXISTS enlace_compartido (
+    idEnlace SERIAL PRIMARY KEY,
+    idArchivo INT NOT NULL REFERENCES grupo_archivo(idArchivo) ON DELETE CASCADE,
+    tokenHash CHAR(64) UNIQUE NOT NULL, -- SHA-256 of the token; the token itself is never stored
+    expiraEn TIMESTAMP NOT NULL,
+    creadoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: grupo_comentario (Notes thread of a group: evaluation committee feedback, internal notes...)
+CREATE TABLE IF NOT EXISTS grupo_comentario (
+    idComentario SERIAL PRIMARY KEY,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    idUsuario INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL, -- Author; NULL once their account is deleted
+    contenido TEXT NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: grupo_favorito (Groups each user pinned to follow them)
+CREATE TABLE IF NOT EXISTS grupo_favorito (
+    idUsuario INT NOT NULL REFERENCES Usuario(idUsuario) ON DELETE CASCADE,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (idUsuario, idGrupo)
+);
+
+-- Table: busqueda_guardada (Named group search filters of a user, optionally with a weekly email digest)
+CREATE TABLE IF NOT EXISTS busqueda_guardada (
+    idBusqueda SERIAL PRIMARY KEY,
+    idUsuario INT NOT NULL REFERENCES Usuario(idUsuario) ON DELETE CASCADE,
+    nombre VARCHAR(100) NOT NULL,
+    filtros JSONB NOT NULL, -- Filters of GET /grupos, as models.FiltrosBusqueda
+    alertaEmail BOOLEAN NOT NULL DEFAULT FALSE, -- Weekly digest of new or changed matching groups
+    ultimoAviso TIMESTAMP, -- Last digest run; the next one covers the changes since then
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: verificacion_email (Confirmation links sent to investigators' emails)
+CREATE TABLE IF NOT EXISTS verificacion_email (
+    idVerificacion SERIAL PRIMARY KEY,
+    idInvestigador INT NOT NULL REFERENCES Investigador(idInvestigador) ON DELETE CASCADE,
+    email VARCHAR(254) NOT NULL, -- Address being confirmed; the link is void if the email changes
+    tokenHash CHAR(64) UNIQUE NOT NULL, -- SHA-256 of the token; the token itself is never stored
+    expiraEn TIMESTAMP NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: restablecimiento_password (Password reset links emailed to users)
+CREATE TABLE IF NOT EXISTS restablecimiento_password (
+    idRestablecimiento SERIAL PRIMARY KEY,
+    idUsuario INT NOT NULL REFERENCES Usuario(idUsuario) ON DELETE CASCADE,
+    tokenHash CHAR(64) UNIQUE NOT NULL, -- SHA-256 of the token; the token itself is never stored
+    expiraEn TIMESTAMP NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: convocatoria (Calls for group registration/renewal)
+CREATE TABLE IF NOT EXISTS convocatoria (
+    idConvocatoria SERIAL PRIMARY KEY,
+    nombre VARCHAR(200) NOT NULL,
+    descripcion TEXT NOT NULL DEFAULT '',
+    requisitos TEXT NOT NULL DEFAULT '',
+    fechaApertura DATE NOT NULL,
+    fechaCierre DATE NOT NULL, -- Deadline, inclusive
+    estado VARCHAR(20) NOT NULL DEFAULT 'borrador', -- 'borrador', 'abierta', 'cerrada' or 'cancelada'
+    documentosRequeridos TEXT[] NOT NULL DEFAULT '{}', -- Documents every postulacion must attach
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_convocatoria_fechas CHECK (fechaCierre >= fechaApertura)
+);
+
+-- Table: grupo_convocatoria (Groups participating in a convocatoria)
+CREATE TABLE IF NOT EXISTS grupo_convocatoria (
+    idConvocatoria INT NOT NULL REFERENCES convocatoria(idConvocatoria) ON DELETE CASCADE,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (idConvocatoria, idGrupo)
+);
+
+-- Table: convocatoria_recordatorio (Deadline reminders already sent, one per group and threshold)
+CREATE TABLE IF NOT EXISTS convocatoria_recordatorio (
+    idConvocatoria INT NOT NULL REFERENCES convocatoria(idConvocatoria) ON DELETE CASCADE,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    dias INT NOT NULL, -- Threshold (days before fechaCierre) the reminder was sent for
+    enviadoEn TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (idConvocatoria, idGrupo, dias)
+);
+
+-- Table: postulacion (A participating group's submission to a convocatoria and its review state)
+CREATE TABLE IF NOT EXISTS postulacion (
+    idPostulacion SERIAL PRIMARY KEY,
+    idConvocatoria INT NOT NULL,
+    idGrupo INT NOT NULL,
+    estado VARCHAR(20) NOT NULL DEFAULT 'presentado', -- 'presentado', 'observado', 'subsanado' or 'aprobado'
+    observaciones TEXT NOT NULL DEFAULT '', -- Notes of the latest estado change
+    presentadoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_postulacion_grupo UNIQUE (idConvocatoria, idGrupo),
+    CONSTRAINT fk_postulacion_participacion FOREIGN KEY (idConvocatoria, idGrupo)
+        REFERENCES grupo_convocatoria(idConvocatoria, idGrupo) ON DELETE CASCADE
+);
+
+-- Table: postulacion_historial (Every estado a postulacion went through)
+CREATE TABLE IF NOT EXISTS postulacion_historial (
+    idHistorial SERIAL PRIMARY KEY,
+    idPostulacion INT NOT NULL REFERENCES postulacion(idPostulacion) ON DELETE CASCADE,
+    estado VARCHAR(20) NOT NULL,
+    observaciones TEXT NOT NULL DEFAULT '',
+    idUsuario INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: postulacion_documento (Documents attached to a postulacion)
+CREATE TABLE IF NOT EXISTS postulacion_documento (
+    idDocumento SERIAL PRIMARY KEY,
+    idPostulacion INT NOT NULL REFERENCES postulacion(idPostulacion) ON DELETE CASCADE,
+    requisito VARCHAR(200) NOT NULL DEFAULT '', -- Entry of convocatoria.documentosRequeridos it fulfills ('' for extra documents)
+    nombre VARCHAR(200) NOT NULL,
+    archivo VARCHAR(255) NOT NULL, -- Storage ref (see Grupo.archivo)
+    subidoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: publicacion (Research output of investigators and groups)
+CREATE TABLE IF NOT EXISTS publicacion (
+    idPublicacion SERIAL PRIMARY KEY,
+    titulo VARCHAR(500) NOT NULL,
+    doi VARCHAR(255), -- Bare DOI (10.xxxx/...), unique ignoring case
+    revista VARCHAR(300) NOT NULL DEFAULT '', -- Journal, publisher or event
+    anio INT NOT NULL,
+    tipo VARCHAR(30) NOT NULL DEFAULT 'articulo', -- 'articulo', 'libro', 'capitulo', 'ponencia', 'tesis' or 'otro'
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: publicacion_investigador (Authors of a publicacion)
+CREATE TABLE IF NOT EXISTS publicacion_investigador (
+    idPublicacion INT NOT NULL REFERENCES publicacion(idPublicacion) ON DELETE CASCADE,
+    idInvestigador INT NOT NULL REFERENCES Investigador(idInvestigador) ON DELETE CASCADE,
+    PRIMARY KEY (idPublicacion, idInvestigador)
+);
+
+-- Table: publicacion_grupo (Groups a publicacion is credited to)
+CREATE TABLE IF NOT EXISTS publicacion_grupo (
+    idPublicacion INT NOT NULL REFERENCES publicacion(idPublicacion) ON DELETE CASCADE,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    PRIMARY KEY (idPublicacion, idGrupo)
+);
+
+-- Table: investigador_cti_vitae (CTI Vitae registry record an investigator is synchronized from)
+CREATE TABLE IF NOT EXISTS investigador_cti_vitae (
+    idInvestigador INT PRIMARY KEY REFERENCES Investigador(idInvestigador) ON DELETE CASCADE,
+    idCtiVitae VARCHAR(20) NOT NULL, -- Investigator ID in CTI Vitae (CONCYTEC), unique
+    ultimaSincronizacion TIMESTAMP, -- Last attempt, successful or not
+    ultimoError TEXT, -- Error of the last attempt, NULL if it succeeded
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: proyecto (Research projects carried out by a group)
+CREATE TABLE IF NOT EXISTS proyecto (
+    idProyecto SERIAL PRIMARY KEY,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    titulo VARCHAR(500) NOT NULL,
+    codigo VARCHAR(50), -- Institutional or funder code, unique ignoring case
+    fuenteFinanciamiento VARCHAR(300) NOT NULL DEFAULT '', -- Funder or program
+    presupuesto NUMERIC(14, 2), -- In soles
+    fechaInicio DATE NOT NULL,
+    fechaFin DATE,
+    estado VARCHAR(20) NOT NULL DEFAULT 'en_ejecucion', -- 'propuesto', 'en_ejecucion', 'finalizado' or 'cancelado'
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_proyecto_fechas CHECK (fechaFin IS NULL OR fechaFin >= fechaInicio)
+);
+
+-- Table: proyecto_archivo (Files of a project: contracts, reports, budgets...)
+CREATE TABLE IF NOT EXISTS proyecto_archivo (
+    idArchivo SERIAL PRIMARY KEY,
+    idProyecto INT NOT NULL REFERENCES proyecto(idProyecto) ON DELETE CASCADE,
+    nombre VARCHAR(200) NOT NULL,
+    archivo VARCHAR(255) NOT NULL, -- Storage ref (see Grupo.archivo)
+    subidoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: audit_log (Audit trail of sensitive actions)
+CREATE TABLE IF NOT EXISTS audit_log (
+    idAudit SERIAL PRIMARY KEY,
+    idUsuario INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL, -- NULL for anonymous actions
+    accion VARCHAR(50) NOT NULL,
+    entidad VARCHAR(50) NOT NULL,
+    idEntidad INT,
+    detalle TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: uso_api (Requests per hour, route and client for GET /admin/usage, flushed by each instance)
+CREATE TABLE IF NOT EXISTS uso_api (
+    inicio TIMESTAMP NOT NULL, -- Start of the hour (UTC)
+    metodo VARCHAR(10) NOT NULL,
+    ruta VARCHAR(255) NOT NULL, -- Route template, e.g. /grupos/{id:[0-9]+}
+    idUsuario INT NOT NULL DEFAULT 0, -- 0 for anonymous callers
+    ip VARCHAR(45) NOT NULL DEFAULT '', -- Only for anonymous callers
+    peticiones BIGINT NOT NULL DEFAULT 0,
+    errores BIGINT NOT NULL DEFAULT 0, -- Responses with status 400 or higher
+    duracionMs BIGINT NOT NULL DEFAULT 0, -- Total
+    PRIMARY KEY (inicio, metodo, ruta, idUsuario, ip)
+);
+
+-- Table: cambio (Change feed of the directory entities for GET /changes, written by triggers)
+CREATE TABLE IF NOT EXISTS cambio (
+    idCambio BIGSERIAL PRIMARY KEY, -- Order of the feed
+    entidad VARCHAR(50) NOT NULL, -- 'grupo', 'investigador', 'detalle', 'publicacion'...
+    idEntidad INT NOT NULL,
+    operacion VARCHAR(20) NOT NULL, -- 'creado', 'actualizado' or 'eliminado' (also soft deletes)
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: migracion_archivo (Progress of file migrations between storage backends, for resumability)
+CREATE TABLE IF NOT EXISTS migracion_archivo (
+    origen VARCHAR(255) PRIMARY KEY, -- Source storage ref
+    destino VARCHAR(255), -- Destination storage ref, once copied
+    estado VARCHAR(20) NOT NULL, -- 'copiado', 'completado' or 'error'
+    error TEXT,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: archivo_checksum (SHA-256 of each stored file, to detect the same document in several groups)
+CREATE TABLE IF NOT EXISTS archivo_checksum (
+    archivo VARCHAR(255) PRIMARY KEY, -- Storage ref
+    sha256 CHAR(64) NOT NULL,
+    tamano BIGINT NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: export_job (Asynchronous exports; the generated file lives in the storage layer)
+CREATE TABLE IF NOT EXISTS export_job (
+    idExport SERIAL PRIMARY KEY,
+    parametros JSONB NOT NULL, -- tipo and filters
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente', -- 'pendiente', 'en_proceso', 'completado', 'error' or 'expirado'
+    total INT NOT NULL DEFAULT 0,
+    procesados INT NOT NULL DEFAULT 0,
+    archivo VARCHAR(255), -- Storage ref of the generated file
+    nombreArchivo VARCHAR(255),
+    error TEXT,
+    creadoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    finalizadoEn TIMESTAMP
+);
+
+-- Table: notificacion (Outbox of emails and webhook events, delivered in the background with retries)
+CREATE TABLE IF NOT EXISTS notificacion (
+    idNotificacion SERIAL PRIMARY KEY,
+    evento VARCHAR(50) NOT NULL, -- e.g. 'membresia_creada'
+    canal VARCHAR(10) NOT NULL, -- 'email' or 'webhook'
+    destino TEXT NOT NULL, -- Email address or webhook URL
+    asunto TEXT NOT NULL DEFAULT '', -- Email subject; empty for webhooks
+    cuerpo TEXT NOT NULL, -- Email text or webhook JSON payload
+    html TEXT NOT NULL DEFAULT '', -- HTML version of an email; empty for plain-text emails and webhooks
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente', -- 'pendiente', 'enviada' or 'error'
+    intentos INT NOT NULL DEFAULT 0,
+    ultimoError TEXT,
+    siguienteIntento TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    enviadaEn TIMESTAMP
+);
+
+-- Table: job (Scheduled jobs: the next run, shared by the instances, and the result of the last one)
+CREATE TABLE IF NOT EXISTS job (
+    nombre VARCHAR(50) PRIMARY KEY,
+    siguienteEjecucion TIMESTAMP NOT NULL, -- The instance that claims a due run moves it forward
+    leaseHasta TIMESTAMP, -- While an instance runs the job; another may take over once it passes
+    instancia VARCHAR(255), -- Instance that claimed the last run
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente', -- 'pendiente', 'en_curso', 'ok' or 'error'
+    ultimaEjecucion TIMESTAMP,
+    duracionMs BIGINT,
+    detalle TEXT NOT NULL DEFAULT '', -- Summary of the last run
+    ultimoError TEXT
+);
+
+-- Table: estadistica_diaria (Statistics materialized every night by the estadisticas job)
+CREATE TABLE IF NOT EXISTS estadistica_diaria (
+    fecha DATE PRIMARY KEY,
+    grupos JSONB NOT NULL, -- As GET /grupos/stats
+    porFacultad JSONB NOT NULL, -- As GET /estadisticas/por-facultad
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: backup (Logical backups of the database; the file lives in the storage layer)
+CREATE TABLE IF NOT EXISTS backup (
+    idBackup SERIAL PRIMARY KEY,
+    archivo VARCHAR(255) NOT NULL, -- Storage ref of the gzipped JSON file
+    nombreArchivo VARCHAR(255) NOT NULL,
+    origen VARCHAR(20) NOT NULL, -- 'manual', 'programado' or 'pre_restauracion'
+    descripcion TEXT NOT NULL DEFAULT '',
+    motor VARCHAR(20) NOT NULL, -- Database backend it was taken from: only restorable into the same one
+    tamano BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    filas JSONB NOT NULL, -- Rows saved per table
+    creadoPor INT, -- Not a foreign key: restoring a backup replaces the users
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Catálogos
+CREATE TABLE IF NOT EXISTS linea_investigacion (
+    idLinea SERIAL PRIMARY KEY,
+    nombre VARCHAR(200) UNIQUE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS rol_integrante (
+    idRol SERIAL PRIMARY KEY,
+    nombre VARCHAR(50) UNIQUE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tipo_investigacion (
+    idTipo SERIAL PRIMARY KEY,
+    nombre VARCHAR(100) UNIQUE NOT NULL
+);
+
+-- Migraciones para bases de datos existentes
+ALTER TABLE Usuario ADD COLUMN IF NOT EXISTS rol VARCHAR(20) NOT NULL DEFAULT 'usuario';
+ALTER TABLE Grupo ADD COLUMN IF NOT EXISTS deletedAt TIMESTAMP;
+ALTER TABLE Grupo ADD COLUMN IF NOT EXISTS estado VARCHAR(20) NOT NULL DEFAULT 'activo';
+ALTER TABLE Grupo ADD COLUMN IF NOT EXISTS idGrupoPadre INT REFERENCES Grupo(idGrupo) ON DELETE SET NULL;
+ALTER TABLE Grupo ADD COLUMN IF NOT EXISTS idFacultad INT REFERENCES facultad(idFacultad) ON DELETE RESTRICT;
+ALTER TABLE Grupo ADD COLUMN IF NOT EXISTS fechaVencimiento DATE;
+ALTER TABLE resolucion ADD COLUMN IF NOT EXISTS fechaVencimiento DATE;
+ALTER TABLE Grupo DROP CONSTRAINT IF EXISTS chk_grupo_padre_distinto;
+ALTER TABLE Grupo ADD CONSTRAINT chk_grupo_padre_distinto CHECK (idGrupoPadre IS NULL OR idGrupoPadre <> idGrupo);
+ALTER TABLE Investigador ADD COLUMN IF NOT EXISTS email VARCHAR(254);
+ALTER TABLE Investigador ADD COLUMN IF NOT EXISTS emailVerificado BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE Investigador ADD COLUMN IF NOT EXISTS deletedAt TIMESTAMP;
+ALTER TABLE Investigador ADD COLUMN IF NOT EXISTS idFacultad INT REFERENCES facultad(idFacultad) ON DELETE RESTRICT;
+ALTER TABLE Investigador ADD COLUMN IF NOT EXISTS idEscuela INT REFERENCES escuela_profesional(idEscuela) ON DELETE RESTRICT;
+ALTER TABLE Investigador ADD COLUMN IF NOT EXISTS tipo VARCHAR(20) NOT NULL DEFAULT 'docente';
+ALTER TABLE Investigador ADD COLUMN IF NOT EXISTS codigoMatricula VARCHAR(20);
+ALTER TABLE Grupo_Investigador ADD COLUMN IF NOT EXISTS fechaInicio DATE;
+ALTER TABLE Grupo_Investigador ADD COLUMN IF NOT EXISTS fechaFin DATE;
+ALTER TABLE Grupo_Investigador DROP CONSTRAINT IF EXISTS chk_grupo_investigador_periodo;
+ALTER TABLE Grupo_Investigador ADD CONSTRAINT chk_grupo_investigador_periodo CHECK (fechaFin IS NULL OR fechaInicio IS NULL OR fechaFin >= fechaInicio);
+ALTER TABLE convocatoria ADD COLUMN IF NOT EXISTS documentosRequeridos TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE notificacion ADD COLUMN IF NOT EXISTS html TEXT NOT NULL DEFAULT '';
+
+-- Aplicar la collation es_icu a las columnas que se ordenan por nombre (solo si aún no la tienen,
+-- para no reconstruir los índices en cada ejecución)
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'grupo' AND column_name = 'nombre' AND collation_name IS DISTINCT FROM 'es_icu') THEN
+        ALTER TABLE Grupo ALTER COLUMN nombre TYPE VARCHAR(150) COLLATE es_icu;
+    END IF;
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'investigador' AND column_name = 'nombre' AND collation_name IS DISTINCT FROM 'es_icu') THEN
+        ALTER TABLE Investigador ALTER COLUMN nombre TYPE VARCHAR(100) COLLATE es_icu;
+    END IF;
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'investigador' AND column_name = 'apellido' AND collation_name IS DISTINCT FROM 'es_icu') THEN
+        ALTER TABLE Investigador ALTER COLUMN apellido TYPE VARCHAR(100) COLLATE es_icu;
+    END IF;
+END
+$$;
+
+-- Columna de búsqueda de texto completo (después de ajustar la collation de nombre: una columna
+-- usada por una columna generada ya no puede cambiar de tipo)
+ALTER TABLE Grupo ADD COLUMN IF NOT EXISTS busqueda tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('es_unaccent', coalesce(nombre, '')), 'A') ||
+    setweight(to_tsvector('es_unaccent', coalesce(numeroResolucion, '')), 'B') ||
+    setweight(to_tsvector('es_unaccent', coalesce(lineaInvestigacion, '')), 'C')
+) STORED;
+
+-- Un solo coordinador vigente (sin fechaFin) por grupo: antes de crear el índice único, los
+-- coordinadores vigentes sobrantes de datos anteriores pasan a 'Integrante' (se conserva el
+-- registrado primero)
+UPDATE Grupo_Investigador gi SET rol = 'Integrante'
+WHERE lower(gi.rol) = 'coordinador' AND gi.fechaFin IS NULL
+  AND EXISTS (
+      SELECT 1 FROM Grupo_Investigador otro
+      WHERE otro.idGrupo = gi.idGrupo AND lower(otro.rol) = 'coordinador' AND otro.fechaFin IS NULL
+        AND otro.idGrupo_Investigador < gi.idGrupo_Investigador
+  );
+
+-- Periodos de un investigador en un grupo que no se solapan: antes de crear la restricción se
+-- eliminan las membresías solapadas de datos anteriores (se conserva la de coordinador, o si no la
+-- registrada primero). Los periodos terminados que no se solapan se conservan como historial.
+DELETE FROM Grupo_Investigador gi
+WHERE EXISTS (
+    SELECT 1 FROM Grupo_Investigador otro
+    WHERE otro.idGrupo = gi.idGrupo AND otro.idInvestigador = gi.idInvestigador
+      AND otro.idGrupo_Investigador <> gi.idGrupo_Investigador
+      AND daterange(otro.fechaInicio, otro.fechaFin, '[]') && daterange(gi.fechaInicio, gi.fechaFin, '[]')
+      AND (lower(otro.rol) = 'coordinador') >= (lower(gi.rol) = 'coordinador')
+      AND ((lower(otro.rol) = 'coordinador') > (lower(gi.rol) = 'coordinador') OR otro.idGrupo_Investigador < gi.idGrupo_Investigador)
+);
+
+-- Las restricciones de unicidad anteriores a los periodos (un coordinador y una membresía por
+-- investigador y grupo, también pasadas) impedían el relevo de coordinador y volver a un grupo
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_indexes WHERE indexname = 'uq_grupo_investigador_coordinador' AND indexdef NOT ILIKE '%fechafin%') THEN
+        DROP INDEX uq_grupo_investigador_coordinador;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'ex_grupo_investigador_periodo') THEN
+        ALTER TABLE Grupo_Investigador ADD CONSTRAINT ex_grupo_investigador_periodo
+            EXCLUDE USING gist (idGrupo WITH =, idInvestigador WITH =, daterange(fechaInicio, fechaFin, '[]') WITH &&);
+    END IF;
+END
+$$;
+DROP INDEX IF EXISTS uq_grupo_investigador;
+
+-- Índices
+-- idx_grupo_investigador_grupo_investigador también sirve a las búsquedas solo por idGrupo
+DROP INDEX IF EXISTS idx_grupo_investigador_grupo;
+CREATE INDEX IF NOT EXISTS idx_grupo_investigador_grupo_investigador ON Grupo_Investigador(idGrupo, idInvestigador);
+CREATE INDEX IF NOT EXISTS idx_grupo_investigador_investigador ON Grupo_Investigador(idInvestigador);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_grupo_investigador_coordinador ON Grupo_Investigador(idGrupo) WHERE lower(rol) = 'coordinador' AND fechaFin IS NULL;
+CREATE INDEX IF NOT EXISTS idx_solicitud_grupo_estado ON solicitud_grupo(estado);
+CREATE INDEX IF NOT EXISTS idx_grupo_activos ON Grupo(nombre) WHERE deletedAt IS NULL;
+CREATE INDEX IF NOT EXISTS idx_grupo_estado ON Grupo(estado);
+CREATE INDEX IF NOT EXISTS idx_grupo_padre ON Grupo(idGrupoPadre);
+CREATE INDEX IF NOT EXISTS idx_grupo_busqueda ON Grupo USING GIN (busqueda);
+CREATE INDEX IF NOT EXISTS idx_grupo_numero_resolucion ON Grupo(numeroResolucion);
+CREATE INDEX IF NOT EXISTS idx_grupo_fecha_registro ON Grupo(fechaRegistro);
+CREATE INDEX IF NOT EXISTS idx_grupo_anio_registro ON Grupo((EXTRACT(YEAR FROM fechaRegistro)::int)); -- Filtro ?año=
+-- Trigram (pg_trgm) para los ILIKE '%...%' sin acentos; la expresión debe coincidir con la de las consultas
+CREATE INDEX IF NOT EXISTS idx_grupo_nombre_trgm ON Grupo USING GIN (f_unaccent(nombre) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_grupo_linea_trgm ON Grupo USING GIN (f_unaccent(lineaInvestigacion) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_grupo_tipo_trgm ON Grupo USING GIN (f_unaccent(tipoInvestigacion) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_investigador_nombre_trgm ON Investigador USING GIN (f_unaccent(nombre) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_investigador_apellido_trgm ON Investigador USING GIN (f_unaccent(apellido) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_investigador_nombre_completo_trgm ON Investigador USING GIN (f_unaccent(nombre || ' ' || apellido) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_publicacion_titulo_trgm ON publicacion USING GIN (f_unaccent(titulo) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_publicacion_revista_trgm ON publicacion USING GIN (f_unaccent(revista) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_grupo_archivo_grupo ON grupo_archivo(idGrupo);
+CREATE INDEX IF NOT EXISTS idx_grupo_archivo_archivo ON grupo_archivo(archivo);
+CREATE INDEX IF NOT EXISTS idx_grupo_comentario_grupo ON grupo_comentario(idGrupo, createdAt);
+CREATE INDEX IF NOT EXISTS idx_grupo_favorito_grupo ON grupo_favorito(idGrupo);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_busqueda_guardada_nombre ON busqueda_guardada(idUsuario, lower(nombre));
+CREATE INDEX IF NOT EXISTS idx_grupo_ref_archivo ON Grupo(archivo);
+CREATE INDEX IF NOT EXISTS idx_archivo_checksum_sha256 ON archivo_checksum(sha256);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_investigador_email ON Investigador(lower(email)) WHERE email IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_investigador_codigo_matricula ON Investigador(lower(codigoMatricula)) WHERE codigoMatricula IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_investigador_tipo ON Investigador(tipo);
+CREATE INDEX IF NOT EXISTS idx_investigador_activos ON Investigador(nombre, apellido) WHERE deletedAt IS NULL;
+CREATE INDEX IF NOT EXISTS idx_investigador_facultad ON Investigador(idFacultad);
+CREATE INDEX IF NOT EXISTS idx_grupo_facultad ON Grupo(idFacultad);
+CREATE INDEX IF NOT EXISTS idx_grupo_vencimiento ON Grupo(fechaVencimiento);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_renovacion_pendiente ON renovacion(idGrupo) WHERE estado = 'pendiente';
+CREATE INDEX IF NOT EXISTS idx_resolucion_archivo ON resolucion(archivo);
+CREATE INDEX IF NOT EXISTS idx_verificacion_email_investigador ON verificacion_email(idInvestigador);
+CREATE INDEX IF NOT EXISTS idx_restablecimiento_password_usuario ON restablecimiento_password(idUsuario);
+CREATE INDEX IF NOT EXISTS idx_convocatoria_estado_cierre ON convocatoria(estado, fechaCierre);
+CREATE INDEX IF NOT EXISTS idx_grupo_convocatoria_grupo ON grupo_convocatoria(idGrupo);
+CREATE INDEX IF NOT EXISTS idx_postulacion_grupo ON postulacion(idGrupo);
+CREATE INDEX IF NOT EXISTS idx_postulacion_historial_postulacion ON postulacion_historial(idPostulacion);
+CREATE INDEX IF NOT EXISTS idx_postulacion_documento_postulacion ON postulacion_documento(idPostulacion);
+CREATE INDEX IF NOT EXISTS idx_postulacion_documento_archivo ON postulacion_documento(archivo);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_publicacion_doi ON publicacion(lower(doi)) WHERE doi IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_investigador_cti_vitae ON investigador_cti_vitae(idCtiVitae);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_proyecto_codigo ON proyecto(lower(codigo)) WHERE codigo IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_proyecto_grupo ON proyecto(idGrupo);
+CREATE INDEX IF NOT EXISTS idx_proyecto_archivo_proyecto ON proyecto_archivo(idProyecto);
+CREATE INDEX IF NOT EXISTS idx_proyecto_archivo_archivo ON proyecto_archivo(archivo);
+CREATE INDEX IF NOT EXISTS idx_publicacion_anio ON publicacion(anio);
+CREATE INDEX IF NOT EXISTS idx_publicacion_investigador_investigador ON publicacion_investigador(idInvestigador);
+CREATE INDEX IF NOT EXISTS idx_publicacion_grupo_grupo ON publicacion_grupo(idGrupo);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entidad ON audit_log(entidad, idEntidad);
+CREATE INDEX IF NOT EXISTS idx_audit_log_fecha ON audit_log(createdAt);
+CREATE INDEX IF NOT EXISTS idx_cambio_fecha ON cambio(createdAt);
+CREATE INDEX IF NOT EXISTS idx_notificacion_pendiente ON notificacion(siguienteIntento) WHERE estado = 'pendiente';
+
+-- Función para actualizar updated_at (tabla Usuario)
+CREATE OR REPLACE FUNCTION actualizar_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Función para actualizar updatedAt (resto de tablas)
 CREATE OR REPLACE FUNCTION actualizar_updatedat()
 RETURNS TRIGGER AS $$
 BEGIN
-    NEW.updated_at = CURRENT_TIMESTAMP; -- Corrected column name
+    NEW.updatedAt = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Rechaza nuevas membresías de investigadores eliminados (soft delete) como si no existieran
+CREATE OR REPLACE FUNCTION verificar_investigador_activo()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM Investigador WHERE idInvestigador = NEW.idInvestigador AND deletedAt IS NOT NULL) THEN
+        RAISE foreign_key_violation USING MESSAGE = format('investigador %s eliminado', NEW.idInvestigador);
+    END IF;
     RETURN NEW;
 END;
 $$ LANGUAGE plpgsql;
 
+-- Registra en cambio la operación sobre una fila de una entidad del directorio (GET /changes).
+-- TG_ARGV[0] es el nombre de la entidad y TG_ARGV[1] su columna de ID, en minúsculas como en
+-- to_jsonb. Una fila con deletedAt (soft delete) se registra como eliminada.
+CREATE OR REPLACE FUNCTION registrar_cambio()
+RETURNS TRIGGER AS $$
+DECLARE
+    fila JSONB;
+    op VARCHAR(20);
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        fila := to_jsonb(OLD);
+        op := 'eliminado';
+    ELSE
+        fila := to_jsonb(NEW);
+        op := CASE
+            WHEN fila ->> 'deletedat' IS NOT NULL THEN 'eliminado'
+            WHEN TG_OP = 'INSERT' THEN 'creado'
+            ELSE 'actualizado'
+        END;
+    END IF;
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES (TG_ARGV[0], (fila ->> TG_ARGV[1])::int, op);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
 -- Triggers para cada tabla que necesita updatedAt
 
--- Usuario (Updated trigger to use new table name and function)
+-- Usuario
+DROP TRIGGER IF EXISTS trigger_updatedat_usuario ON Usuario;
 CREATE TRIGGER trigger_updatedat_usuario
 BEFORE UPDATE ON Usuario
 FOR EACH ROW
-EXECUTE FUNCTION actualizar_updatedat();
+EXECUTE FUNCTION actualizar_updated_at();
 
 -- Investigador
+DROP TRIGGER IF EXISTS trigger_updatedat_investigador ON Investigador;
 CREATE TRIGGER trigger_updatedat_investigador
 BEFORE UPDATE ON Investigador
 FOR EACH ROW
 EXECUTE FUNCTION actualizar_updatedat();
 
 -- Grupo
+DROP TRIGGER IF EXISTS trigger_updatedat_grupo ON Grupo;
 CREATE TRIGGER trigger_updatedat_grupo
 BEFORE UPDATE ON Grupo
 FOR EACH ROW
 EXECUTE FUNCTION actualizar_updatedat();
 
 -- Grupo_Investigador
+DROP TRIGGER IF EXISTS trigger_updatedat_grupo_investigador ON Grupo_Investigador;
 CREATE TRIGGER trigger_updatedat_grupo_investigador
-BEFORE UPDATE ON grupo_investigador
+BEFORE UPDATE ON Grupo_Investigador
+FOR EACH ROW
+EXECUTE FUNCTION actualizar_updatedat();
+
+-- grupo_archivo
+DROP TRIGGER IF EXISTS trigger_updatedat_grupo_archivo ON grupo_archivo;
+CREATE TRIGGER trigger_updatedat_grupo_archivo
+BEFORE UPDATE ON grupo_archivo
+FOR EACH ROW
+EXECUTE FUNCTION actualizar_updatedat();
+
+-- export_job
+DROP TRIGGER IF EXISTS trigger_updatedat_export_job ON export_job;
+CREATE TRIGGER trigger_updatedat_export_job
+BEFORE UPDATE ON export_job
 FOR EACH ROW
 EXECUTE FUNCTION actualizar_updatedat();
 
-CREATE EXTENSION IF NOT EXISTS unaccent;
\ No newline at end of file
+-- convocatoria
+DROP TRIGGER IF EXISTS trigger_updatedat_convocatoria ON convocatoria;
+CREATE TRIGGER trigger_updatedat_convocatoria
+BEFORE UPDATE ON convocatoria
+FOR EACH ROW
+EXECUTE FUNCTION actualizar_updatedat();
+
+-- postulacion
+DROP TRIGGER IF EXISTS trigger_updatedat_postulacion ON postulacion;
+CREATE TRIGGER trigger_updatedat_postulacion
+BEFORE UPDATE ON postulacion
+FOR EACH ROW
+EXECUTE FUNCTION actualizar_updatedat();
+
+-- publicacion
+DROP TRIGGER IF EXISTS trigger_updatedat_publicacion ON publicacion;
+CREATE TRIGGER trigger_updatedat_publicacion
+BEFORE UPDATE ON publicacion
+FOR EACH ROW
+EXECUTE FUNCTION actualizar_updatedat();
+
+-- facultad
+DROP TRIGGER IF EXISTS trigger_updatedat_facultad ON facultad;
+CREATE TRIGGER trigger_updatedat_facultad
+BEFORE UPDATE ON facultad
+FOR EACH ROW
+EXECUTE FUNCTION actualizar_updatedat();
+
+-- escuela_profesional
+DROP TRIGGER IF EXISTS trigger_updatedat_escuela_profesional ON escuela_profesional;
+CREATE TRIGGER trigger_updatedat_escuela_profesional
+BEFORE UPDATE ON escuela_profesional
+FOR EACH ROW
+EXECUTE FUNCTION actualizar_updatedat();
+
+-- proyecto
+DROP TRIGGER IF EXISTS trigger_updatedat_proyecto ON proyecto;
+CREATE TRIGGER trigger_updatedat_proyecto
+BEFORE UPDATE ON proyecto
+FOR EACH ROW
+EXECUTE FUNCTION actualizar_updatedat();
+
+-- resolucion
+DROP TRIGGER IF EXISTS trigger_updatedat_resolucion ON resolucion;
+CREATE TRIGGER trigger_updatedat_resolucion
+BEFORE UPDATE ON resolucion
+FOR EACH ROW
+EXECUTE FUNCTION actualizar_updatedat();
+
+-- renovacion
+DROP TRIGGER IF EXISTS trigger_updatedat_renovacion ON renovacion;
+CREATE TRIGGER trigger_updatedat_renovacion
+BEFORE UPDATE ON renovacion
+FOR EACH ROW
+EXECUTE FUNCTION actualizar_updatedat();
+
+-- Feed de cambios de las entidades del directorio
+DROP TRIGGER IF EXISTS trigger_cambio_grupo ON Grupo;
+CREATE TRIGGER trigger_cambio_grupo
+AFTER INSERT OR UPDATE OR DELETE ON Grupo
+FOR EACH ROW
+EXECUTE FUNCTION registrar_cambio('grupo', 'idgrupo');
+
+DROP TRIGGER IF EXISTS trigger_cambio_investigador ON Investigador;
+CREATE TRIGGER trigger_cambio_investigador
+AFTER INSERT OR UPDATE OR DELETE ON Investigador
+FOR EACH ROW
+EXECUTE FUNCTION registrar_cambio('investigador', 'idinvestigador');
+
+DROP TRIGGER IF EXISTS trigger_cambio_grupo_investigador ON Grupo_Investigador;
+CREATE TRIGGER trigger_cambio_grupo_investigador
+AFTER INSERT OR UPDATE OR DELETE ON Grupo_Investigador
+FOR EACH ROW
+EXECUTE FUNCTION registrar_cambio('detalle', 'idgrupo_investigador');
+
+DROP TRIGGER IF EXISTS trigger_cambio_publicacion ON publicacion;
+CREATE TRIGGER trigger_cambio_publicacion
+AFTER INSERT OR UPDATE OR DELETE ON publicacion
+FOR EACH ROW
+EXECUTE FUNCTION registrar_cambio('publicacion', 'idpublicacion');
+
+DROP TRIGGER IF EXISTS trigger_cambio_proyecto ON proyecto;
+CREATE TRIGGER trigger_cambio_proyecto
+AFTER INSERT OR UPDATE OR DELETE ON proyecto
+FOR EACH ROW
+EXECUTE FUNCTION registrar_cambio('proyecto', 'idproyecto');
+
+DROP TRIGGER IF EXISTS trigger_cambio_convocatoria ON convocatoria;
+CREATE TRIGGER trigger_cambio_convocatoria
+AFTER INSERT OR UPDATE OR DELETE ON convocatoria
+FOR EACH ROW
+EXECUTE FUNCTION registrar_cambio('convocatoria', 'idconvocatoria');
+
+DROP TRIGGER IF EXISTS trigger_cambio_facultad ON facultad;
+CREATE TRIGGER trigger_cambio_facultad
+AFTER INSERT OR UPDATE OR DELETE ON facultad
+FOR EACH ROW
+EXECUTE FUNCTION registrar_cambio('facultad', 'idfacultad');
+
+DROP TRIGGER IF EXISTS trigger_cambio_escuela_profesional ON escuela_profesional;
+CREATE TRIGGER trigger_cambio_escuela_profesional
+AFTER INSERT OR UPDATE OR DELETE ON escuela_profesional
+FOR EACH ROW
+EXECUTE FUNCTION registrar_cambio('escuela', 'idescuela');
+
+-- Membresías solo de investigadores activos
+DROP TRIGGER IF EXISTS trigger_investigador_activo_grupo_investigador ON Grupo_Investigador;
+CREATE TRIGGER trigger_investigador_activo_grupo_investigador
+BEFORE INSERT OR UPDATE OF idInvestigador ON Grupo_Investigador
+FOR EACH ROW
+EXECUTE FUNCTION verificar_investigador_activo();
+
+-- Datos iniciales de catálogos
+INSERT INTO linea_investigacion (nombre) VALUES
+    ('Ciencias de la Salud'),
+    ('Ciencias Agrarias y Ambientales'),
+    ('Ingeniería y Tecnología'),
+    ('Ciencias Sociales y Humanidades'),
+    ('Educación'),
+    ('Ciencias Económicas y Empresariales')
+ON CONFLICT (nombre) DO NOTHING;
+
+INSERT INTO rol_integrante (nombre) VALUES
+    ('Coordinador'),
+    ('Integrante')
+ON CONFLICT (nombre) DO NOTHING;
+
+INSERT INTO tipo_investigacion (nombre) VALUES
+    ('Básica'),
+    ('Aplicada'),
+    ('Experimental'),
+    ('Desarrollo tecnológico'),
+    ('Innovación')
+ON CONFLICT (nombre) DO NOTHING;
+
+-- Resolución de creación de los grupos que aún no tienen ninguna (los registrados antes de que
+-- existieran las resoluciones), a partir de su numeroResolucion, fechaRegistro y archivo
+INSERT INTO resolucion (idGrupo, numero, fechaEmision, tipo, archivo)
+SELECT g.idGrupo, g.numeroResolucion, g.fechaRegistro, 'creacion', g.archivo
+FROM Grupo g
+WHERE g.numeroResolucion <> '' AND NOT EXISTS (SELECT 1 FROM resolucion r WHERE r.idGrupo = g.idGrupo);
+
+-- Vigencia de las resoluciones de creación y renovación que no la tienen (las registradas antes de
+-- que existiera fechaVencimiento), con la de GRUPO_VIGENCIA_ANIOS por defecto, y la de cada grupo
+UPDATE resolucion SET fechaVencimiento = (fechaEmision + INTERVAL '2 years')::date
+WHERE fechaVencimiento IS NULL AND tipo IN ('creacion', 'renovacion');
+UPDATE Grupo SET fechaVencimiento = (SELECT MAX(r.fechaVencimiento) FROM resolucion r WHERE r.idGrupo = Grupo.idGrupo)
+WHERE fechaVencimiento IS NULL;
@@ -0,0 +1,916 @@
+-- Esquema de la base de datos para DB_DRIVER=sqlite (desarrollo local y demos).
+-- Traducción de schema.sql: mantener ambos archivos sincronizados. Es idempotente.
+--
+-- Diferencias con PostgreSQL:
+--   * Los arrays (TEXT[]) y JSONB se guardan como texto JSON.
+--   * unaccent, pg_trgm (similarity), la búsqueda de texto completo y la collation es_icu son
+--     funciones registradas por la aplicación (database/sqlite_functions.go): el archivo solo
+--     puede abrirse con otras herramientas para consultas que no las usen.
+--   * Sin índices trigram ni GIN: las búsquedas recorren las tablas, suficiente para datos de prueba.
+
+-- Table: usuario (Application Users)
+CREATE TABLE IF NOT EXISTS Usuario (
+    idUsuario INTEGER PRIMARY KEY AUTOINCREMENT,            -- Changed from id_usuario
+    -- Removed supabase_user_id UUID UNIQUE NOT NULL,
+    email VARCHAR(150) UNIQUE NOT NULL,
+    password TEXT NOT NULL,                   -- Added password field (will store hash)
+    rol VARCHAR(20) NOT NULL DEFAULT 'usuario', -- Application role: 'usuario' or 'admin'
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: facultad (Faculties of the university)
+CREATE TABLE IF NOT EXISTS facultad (
+    idFacultad INTEGER PRIMARY KEY AUTOINCREMENT,
+    nombre VARCHAR(200) COLLATE es_icu UNIQUE NOT NULL,
+    siglas VARCHAR(20),
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: escuela_profesional (Professional schools, each within a faculty)
+CREATE TABLE IF NOT EXISTS escuela_profesional (
+    idEscuela INTEGER PRIMARY KEY AUTOINCREMENT,
+    idFacultad INT NOT NULL REFERENCES facultad(idFacultad) ON DELETE RESTRICT,
+    nombre VARCHAR(200) COLLATE es_icu NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_escuela_profesional_nombre UNIQUE (idFacultad, nombre)
+);
+
+-- Table: Investigador (Researchers)
+CREATE TABLE IF NOT EXISTS Investigador (
+    idInvestigador INTEGER PRIMARY KEY AUTOINCREMENT, -- SERIAL is PostgreSQL's auto-incrementing integer
+    nombre VARCHAR(100) COLLATE es_icu NOT NULL,
+    apellido VARCHAR(100) COLLATE es_icu NOT NULL,
+    email VARCHAR(254), -- Contact email, unique ignoring case
+    emailVerificado BOOLEAN NOT NULL DEFAULT FALSE, -- Set when the confirmation link is opened
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deletedAt TIMESTAMP, -- Soft delete: NULL while the investigator is active
+    idFacultad INT REFERENCES facultad(idFacultad) ON DELETE RESTRICT, -- Faculty the investigator belongs to
+    idEscuela INT REFERENCES escuela_profesional(idEscuela) ON DELETE RESTRICT, -- School, within that faculty
+    tipo VARCHAR(20) NOT NULL DEFAULT 'docente', -- 'docente', 'estudiante' (tesistas, semilleros) or 'externo'
+    codigoMatricula VARCHAR(20) -- Student enrollment code, unique ignoring case
+);
+
+-- Table: Grupo (Research Groups)
+CREATE TABLE IF NOT EXISTS Grupo (
+    idGrupo INTEGER PRIMARY KEY AUTOINCREMENT,
+    nombre VARCHAR(150) COLLATE es_icu NOT NULL,
+    numeroResolucion VARCHAR(100) NOT NULL,
+    lineaInvestigacion VARCHAR(200) NOT NULL,
+    tipoInvestigacion VARCHAR(100) NOT NULL,
+    fechaRegistro DATE NOT NULL,
+    archivo VARCHAR(255), -- Storage ref ("<backend>:<key>", or a legacy Google Drive file ID)
+    estado VARCHAR(20) NOT NULL DEFAULT 'activo', -- 'activo', 'inactivo', 'en_renovacion' or 'cerrado'
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deletedAt TIMESTAMP, -- Soft delete: NULL while the group is active
+    idGrupoPadre INT REFERENCES Grupo(idGrupo) ON DELETE SET NULL, -- Parent group (e.g. the institute of a semillero)
+    idFacultad INT REFERENCES facultad(idFacultad) ON DELETE RESTRICT, -- Faculty the group belongs to
+    fechaVencimiento DATE, -- Latest fechaVencimiento of its resoluciones, kept by the repository; NULL = sin vigencia
+    busqueda TEXT GENERATED ALWAYS AS (
+        coalesce(nombre, '') || char(10) || coalesce(numeroResolucion, '') || char(10) || coalesce(lineaInvestigacion, '')
+    ) VIRTUAL, -- Full-text search (?q=): one line per weight, see database.tsMatch
+    CONSTRAINT chk_grupo_padre_distinto CHECK (idGrupoPadre IS NULL OR idGrupoPadre <> idGrupo)
+);
+
+-- Table: resolucion (Resolutions issued for a group: creation, renewal, member changes...)
+CREATE TABLE IF NOT EXISTS resolucion (
+    idResolucion INTEGER PRIMARY KEY AUTOINCREMENT,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    numero VARCHAR(100) NOT NULL,
+    fechaEmision DATE NOT NULL,
+    tipo VARCHAR(30) NOT NULL DEFAULT 'otro', -- 'creacion', 'renovacion', 'cambio_integrantes' or 'otro'
+    descripcion TEXT NOT NULL DEFAULT '',
+    archivo VARCHAR(255), -- Storage ref of the PDF (see Grupo.archivo)
+    fechaVencimiento DATE, -- End of the group's vigencia granted by a creacion or renovacion resolution
+    subidoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_resolucion_grupo_numero UNIQUE (idGrupo, numero)
+);
+
+-- Table: renovacion (Renewal requests of a group's vigencia, reviewed by an admin)
+CREATE TABLE IF NOT EXISTS renovacion (
+    idRenovacion INTEGER PRIMARY KEY AUTOINCREMENT,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente', -- 'pendiente', 'aprobada' or 'rechazada'
+    motivo TEXT NOT NULL DEFAULT '',
+    observaciones TEXT NOT NULL DEFAULT '', -- Reviewer's notes
+    estadoAnterior VARCHAR(20) NOT NULL, -- Group estado when it was submitted, restored if it is rejected
+    idResolucion INT REFERENCES resolucion(idResolucion) ON DELETE SET NULL, -- Renewal resolution, once approved
+    solicitadoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    revisadoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    fechaRevision TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: aviso_vencimiento (Expiry notices already sent, one per group and fechaVencimiento)
+CREATE TABLE IF NOT EXISTS aviso_vencimiento (
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    fechaVencimiento DATE NOT NULL,
+    enviadoEn TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (idGrupo, fechaVencimiento)
+);
+
+-- Table: Grupo_Investigador (Associative table for Groups and Researchers)
+CREATE TABLE IF NOT EXISTS Grupo_Investigador (
+    idGrupo_Investigador INTEGER PRIMARY KEY AUTOINCREMENT,
+    idGrupo INT NOT NULL,
+    idInvestigador INT NOT NULL,
+    rol VARCHAR(50) NOT NULL, -- e.g., 'Coordinador' or 'Integrante'
+    fechaInicio DATE, -- Membership period; NULL start = unknown, NULL end = still a member
+    fechaFin DATE,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (idGrupo) REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    FOREIGN KEY (idInvestigador) REFERENCES Investigador(idInvestigador) ON DELETE CASCADE,
+    CONSTRAINT chk_grupo_investigador_periodo CHECK (fechaFin IS NULL OR fechaInicio IS NULL OR fechaFin >= fechaInicio)
+);
+
+-- Table: solicitud_grupo (Public group registration requests awaiting moderation)
+CREATE TABLE IF NOT EXISTS solicitud_grupo (
+    idSolicitud INTEGER PRIMARY KEY AUTOINCREMENT,
+    nombre VARCHAR(150) NOT NULL,
+    numeroResolucion VARCHAR(100) NOT NULL DEFAULT '',
+    lineaInvestigacion VARCHAR(200) NOT NULL,
+    tipoInvestigacion VARCHAR(100) NOT NULL,
+    descripcion TEXT NOT NULL DEFAULT '',
+    nombreSolicitante VARCHAR(100) NOT NULL,
+    apellidoSolicitante VARCHAR(100) NOT NULL,
+    emailSolicitante VARCHAR(150) NOT NULL,
+    integrantes TEXT NOT NULL DEFAULT '[]', -- Proposed members: [{idInvestigador?, nombre, apellido, rol}]
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente', -- 'pendiente', 'aprobada' or 'rechazada'
+    comentario TEXT, -- Moderator comments
+    idGrupo INT REFERENCES Grupo(idGrupo) ON DELETE SET NULL, -- Group created on approval
+    revisadoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    fechaRevision TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: grupo_archivo (Attachments of a group: resolutions, evaluations, internal reports...)
+CREATE TABLE IF NOT EXISTS grupo_archivo (
+    idArchivo INTEGER PRIMARY KEY AUTOINCREMENT,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    nombre VARCHAR(200) NOT NULL,
+    tipo VARCHAR(50) NOT NULL DEFAULT 'otro', -- 'resolucion', 'evaluacion', 'informe' or 'otro'
+    archivo VARCHAR(255) NOT NULL, -- Storage ref (see Grupo.archivo)
+    publico BOOLEAN NOT NULL DEFAULT FALSE, -- Non-public files are only reachable through share links
+    subidoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: enlace_compartido (Time-boxed share links for non-public attachments)
+CREATE TABLE IF NOT EXISTS enlace_compartido (
+    idEnlace INTEGER PRIMARY KEY AUTOINCREMENT,
+    idArchivo INT NOT NULL REFERENCES grupo_archivo(idArchivo) ON DELETE CASCADE,
+    tokenHash CHAR(64) UNIQUE NOT NULL, -- SHA-256 of the token; the token itself is never stored
+    expiraEn TIMESTAMP NOT NULL,
+    creadoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: grupo_comentario (Notes thread of a group: evaluation committee feedback, internal notes...)
+CREATE TABLE IF NOT EXISTS grupo_comentario (
+    idComentario INTEGER PRIMARY KEY AUTOINCREMENT,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    idUsuario INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL, -- Author; NULL once their account is deleted
+    contenido TEXT NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: grupo_favorito (Groups each user pinned to follow them)
+CREATE TABLE IF NOT EXISTS grupo_favorito (
+    idUsuario INT NOT NULL REFERENCES Usuario(idUsuario) ON DELETE CASCADE,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (idUsuario, idGrupo)
+);
+
+-- Table: busqueda_guardada (Named group search filters of a user, optionally with a weekly email digest)
+CREATE TABLE IF NOT EXISTS busqueda_guardada (
+    idBusqueda INTEGER PRIMARY KEY AUTOINCREMENT,
+    idUsuario INT NOT NULL REFERENCES Usuario(idUsuario) ON DELETE CASCADE,
+    nombre VARCHAR(100) NOT NULL,
+    filtros TEXT NOT NULL, -- Filters of GET /grupos, as models.FiltrosBusqueda
+    alertaEmail BOOLEAN NOT NULL DEFAULT FALSE, -- Weekly digest of new or changed matching groups
+    ultimoAviso TIMESTAMP, -- Last digest run; the next one covers the changes since then
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: verificacion_email (Confirmation links sent to investigators' emails)
+CREATE TABLE IF NOT EXISTS verificacion_email (
+    idVerificacion INTEGER PRIMARY KEY AUTOINCREMENT,
+    idInvestigador INT NOT NULL REFERENCES Investigador(idInvestigador) ON DELETE CASCADE,
+    email VARCHAR(254) NOT NULL, -- Address being confirmed; the link is void if the email changes
+    tokenHash CHAR(64) UNIQUE NOT NULL, -- SHA-256 of the token; the token itself is never stored
+    expiraEn TIMESTAMP NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: restablecimiento_password (Password reset links emailed to users)
+CREATE TABLE IF NOT EXISTS restablecimiento_password (
+    idRestablecimiento INTEGER PRIMARY KEY AUTOINCREMENT,
+    idUsuario INT NOT NULL REFERENCES Usuario(idUsuario) ON DELETE CASCADE,
+    tokenHash CHAR(64) UNIQUE NOT NULL, -- SHA-256 of the token; the token itself is never stored
+    expiraEn TIMESTAMP NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: convocatoria (Calls for group registration/renewal)
+CREATE TABLE IF NOT EXISTS convocatoria (
+    idConvocatoria INTEGER PRIMARY KEY AUTOINCREMENT,
+    nombre VARCHAR(200) NOT NULL,
+    descripcion TEXT NOT NULL DEFAULT '',
+    requisitos TEXT NOT NULL DEFAULT '',
+    fechaApertura DATE NOT NULL,
+    fechaCierre DATE NOT NULL, -- Deadline, inclusive
+    estado VARCHAR(20) NOT NULL DEFAULT 'borrador', -- 'borrador', 'abierta', 'cerrada' or 'cancelada'
+    documentosRequeridos TEXT NOT NULL DEFAULT '[]', -- Documents every postulacion must attach
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_convocatoria_fechas CHECK (fechaCierre >= fechaApertura)
+);
+
+-- Table: grupo_convocatoria (Groups participating in a convocatoria)
+CREATE TABLE IF NOT EXISTS grupo_convocatoria (
+    idConvocatoria INT NOT NULL REFERENCES convocatoria(idConvocatoria) ON DELETE CASCADE,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (idConvocatoria, idGrupo)
+);
+
+-- Table: convocatoria_recordatorio (Deadline reminders already sent, one per group and threshold)
+CREATE TABLE IF NOT EXISTS convocatoria_recordatorio (
+    idConvocatoria INT NOT NULL REFERENCES convocatoria(idConvocatoria) ON DELETE CASCADE,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    dias INT NOT NULL, -- Threshold (days before fechaCierre) the reminder was sent for
+    enviadoEn TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (idConvocatoria, idGrupo, dias)
+);
+
+-- Table: postulacion (A participating group's submission to a convocatoria and its review state)
+CREATE TABLE IF NOT EXISTS postulacion (
+    idPostulacion INTEGER PRIMARY KEY AUTOINCREMENT,
+    idConvocatoria INT NOT NULL,
+    idGrupo INT NOT NULL,
+    estado VARCHAR(20) NOT NULL DEFAULT 'presentado', -- 'presentado', 'observado', 'subsanado' or 'aprobado'
+    observaciones TEXT NOT NULL DEFAULT '', -- Notes of the latest estado change
+    presentadoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_postulacion_grupo UNIQUE (idConvocatoria, idGrupo),
+    CONSTRAINT fk_postulacion_participacion FOREIGN KEY (idConvocatoria, idGrupo)
+        REFERENCES grupo_convocatoria(idConvocatoria, idGrupo) ON DELETE CASCADE
+);
+
+-- Table: postulacion_historial (Every estado a postulacion went through)
+CREATE TABLE IF NOT EXISTS postulacion_historial (
+    idHistorial INTEGER PRIMARY KEY AUTOINCREMENT,
+    idPostulacion INT NOT NULL REFERENCES postulacion(idPostulacion) ON DELETE CASCADE,
+    estado VARCHAR(20) NOT NULL,
+    observaciones TEXT NOT NULL DEFAULT '',
+    idUsuario INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: postulacion_documento (Documents attached to a postulacion)
+CREATE TABLE IF NOT EXISTS postulacion_documento (
+    idDocumento INTEGER PRIMARY KEY AUTOINCREMENT,
+    idPostulacion INT NOT NULL REFERENCES postulacion(idPostulacion) ON DELETE CASCADE,
+    requisito VARCHAR(200) NOT NULL DEFAULT '', -- Entry of convocatoria.documentosRequeridos it fulfills ('' for extra documents)
+    nombre VARCHAR(200) NOT NULL,
+    archivo VARCHAR(255) NOT NULL, -- Storage ref (see Grupo.archivo)
+    subidoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: publicacion (Research output of investigators and groups)
+CREATE TABLE IF NOT EXISTS publicacion (
+    idPublicacion INTEGER PRIMARY KEY AUTOINCREMENT,
+    titulo VARCHAR(500) NOT NULL,
+    doi VARCHAR(255), -- Bare DOI (10.xxxx/...), unique ignoring case
+    revista VARCHAR(300) NOT NULL DEFAULT '', -- Journal, publisher or event
+    anio INT NOT NULL,
+    tipo VARCHAR(30) NOT NULL DEFAULT 'articulo', -- 'articulo', 'libro', 'capitulo', 'ponencia', 'tesis' or 'otro'
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: publicacion_investigador (Authors of a publicacion)
+CREATE TABLE IF NOT EXISTS publicacion_investigador (
+    idPublicacion INT NOT NULL REFERENCES publicacion(idPublicacion) ON DELETE CASCADE,
+    idInvestigador INT NOT NULL REFERENCES Investigador(idInvestigador) ON DELETE CASCADE,
+    PRIMARY KEY (idPublicacion, idInvestigador)
+);
+
+-- Table: publicacion_grupo (Groups a publicacion is credited to)
+CREATE TABLE IF NOT EXISTS publicacion_grupo (
+    idPublicacion INT NOT NULL REFERENCES publicacion(idPublicacion) ON DELETE CASCADE,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    PRIMARY KEY (idPublicacion, idGrupo)
+);
+
+-- Table: investigador_cti_vitae (CTI Vitae registry record an investigator is synchronized from)
+CREATE TABLE IF NOT EXISTS investigador_cti_vitae (
+    idInvestigador INT PRIMARY KEY REFERENCES Investigador(idInvestigador) ON DELETE CASCADE,
+    idCtiVitae VARCHAR(20) NOT NULL, -- Investigator ID in CTI Vitae (CONCYTEC), unique
+    ultimaSincronizacion TIMESTAMP, -- Last attempt, successful or not
+    ultimoError TEXT, -- Error of the last attempt, NULL if it succeeded
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: proyecto (Research projects carried out by a group)
+CREATE TABLE IF NOT EXISTS proyecto (
+    idProyecto INTEGER PRIMARY KEY AUTOINCREMENT,
+    idGrupo INT NOT NULL REFERENCES Grupo(idGrupo) ON DELETE CASCADE,
+    titulo VARCHAR(500) NOT NULL,
+    codigo VARCHAR(50), -- Institutional or funder code, unique ignoring case
+    fuenteFinanciamiento VARCHAR(300) NOT NULL DEFAULT '', -- Funder or program
+    presupuesto REAL, -- In soles
+    fechaInicio DATE NOT NULL,
+    fechaFin DATE,
+    estado VARCHAR(20) NOT NULL DEFAULT 'en_ejecucion', -- 'propuesto', 'en_ejecucion', 'finalizado' or 'cancelado'
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_proyecto_fechas CHECK (fechaFin IS NULL OR fechaFin >= fechaInicio)
+);
+
+-- Table: proyecto_archivo (Files of a project: contracts, reports, budgets...)
+CREATE TABLE IF NOT EXISTS proyecto_archivo (
+    idArchivo INTEGER PRIMARY KEY AUTOINCREMENT,
+    idProyecto INT NOT NULL REFERENCES proyecto(idProyecto) ON DELETE CASCADE,
+    nombre VARCHAR(200) NOT NULL,
+    archivo VARCHAR(255) NOT NULL, -- Storage ref (see Grupo.archivo)
+    subidoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: audit_log (Audit trail of sensitive actions)
+CREATE TABLE IF NOT EXISTS audit_log (
+    idAudit INTEGER PRIMARY KEY AUTOINCREMENT,
+    idUsuario INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL, -- NULL for anonymous actions
+    accion VARCHAR(50) NOT NULL,
+    entidad VARCHAR(50) NOT NULL,
+    idEntidad INT,
+    detalle TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: uso_api (Requests per hour, route and client for GET /admin/usage, flushed by each instance)
+CREATE TABLE IF NOT EXISTS uso_api (
+    inicio TIMESTAMP NOT NULL, -- Start of the hour (UTC)
+    metodo VARCHAR(10) NOT NULL,
+    ruta VARCHAR(255) NOT NULL, -- Route template, e.g. /grupos/{id:[0-9]+}
+    idUsuario INT NOT NULL DEFAULT 0, -- 0 for anonymous callers
+    ip VARCHAR(45) NOT NULL DEFAULT '', -- Only for anonymous callers
+    peticiones BIGINT NOT NULL DEFAULT 0,
+    errores BIGINT NOT NULL DEFAULT 0, -- Responses with status 400 or higher
+    duracionMs BIGINT NOT NULL DEFAULT 0, -- Total
+    PRIMARY KEY (inicio, metodo, ruta, idUsuario, ip)
+);
+
+-- Table: cambio (Change feed of the directory entities for GET /changes, written by triggers)
+CREATE TABLE IF NOT EXISTS cambio (
+    idCambio INTEGER PRIMARY KEY AUTOINCREMENT, -- Order of the feed
+    entidad VARCHAR(50) NOT NULL, -- 'grupo', 'investigador', 'detalle', 'publicacion'...
+    idEntidad INT NOT NULL,
+    operacion VARCHAR(20) NOT NULL, -- 'creado', 'actualizado' or 'eliminado' (also soft deletes)
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: migracion_archivo (Progress of file migrations between storage backends, for resumability)
+CREATE TABLE IF NOT EXISTS migracion_archivo (
+    origen VARCHAR(255) PRIMARY KEY, -- Source storage ref
+    destino VARCHAR(255), -- Destination storage ref, once copied
+    estado VARCHAR(20) NOT NULL, -- 'copiado', 'completado' or 'error'
+    error TEXT,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: archivo_checksum (SHA-256 of each stored file, to detect the same document in several groups)
+CREATE TABLE IF NOT EXISTS archivo_checksum (
+    archivo VARCHAR(255) PRIMARY KEY, -- Storage ref
+    sha256 CHAR(64) NOT NULL,
+    tamano BIGINT NOT NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: export_job (Asynchronous exports; the generated file lives in the storage layer)
+CREATE TABLE IF NOT EXISTS export_job (
+    idExport INTEGER PRIMARY KEY AUTOINCREMENT,
+    parametros TEXT NOT NULL, -- tipo and filters
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente', -- 'pendiente', 'en_proceso', 'completado', 'error' or 'expirado'
+    total INT NOT NULL DEFAULT 0,
+    procesados INT NOT NULL DEFAULT 0,
+    archivo VARCHAR(255), -- Storage ref of the generated file
+    nombreArchivo VARCHAR(255),
+    error TEXT,
+    creadoPor INT REFERENCES Usuario(idUsuario) ON DELETE SET NULL,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    finalizadoEn TIMESTAMP
+);
+
+-- Table: notificacion (Outbox of emails and webhook events, delivered in the background with retries)
+CREATE TABLE IF NOT EXISTS notificacion (
+    idNotificacion INTEGER PRIMARY KEY AUTOINCREMENT,
+    evento VARCHAR(50) NOT NULL, -- e.g. 'membresia_creada'
+    canal VARCHAR(10) NOT NULL, -- 'email' or 'webhook'
+    destino TEXT NOT NULL, -- Email address or webhook URL
+    asunto TEXT NOT NULL DEFAULT '', -- Email subject; empty for webhooks
+    cuerpo TEXT NOT NULL, -- Email text or webhook JSON payload
+    html TEXT NOT NULL DEFAULT '', -- HTML version of an email; empty for plain-text emails and webhooks
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente', -- 'pendiente', 'enviada' or 'error'
+    intentos INT NOT NULL DEFAULT 0,
+    ultimoError TEXT,
+    siguienteIntento TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    enviadaEn TIMESTAMP
+);
+
+-- Table: job (Scheduled jobs: the next run, shared by the instances, and the result of the last one)
+CREATE TABLE IF NOT EXISTS job (
+    nombre VARCHAR(50) PRIMARY KEY,
+    siguienteEjecucion TIMESTAMP NOT NULL, -- The instance that claims a due run moves it forward
+    leaseHasta TIMESTAMP, -- While an instance runs the job; another may take over once it passes
+    instancia VARCHAR(255), -- Instance that claimed the last run
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente', -- 'pendiente', 'en_curso', 'ok' or 'error'
+    ultimaEjecucion TIMESTAMP,
+    duracionMs BIGINT,
+    detalle TEXT NOT NULL DEFAULT '', -- Summary of the last run
+    ultimoError TEXT
+);
+
+-- Table: estadistica_diaria (Statistics materialized every night by the estadisticas job)
+CREATE TABLE IF NOT EXISTS estadistica_diaria (
+    fecha DATE PRIMARY KEY,
+    grupos TEXT NOT NULL, -- As GET /grupos/stats
+    porFacultad TEXT NOT NULL, -- As GET /estadisticas/por-facultad
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: backup (Logical backups of the database; the file lives in the storage layer)
+CREATE TABLE IF NOT EXISTS backup (
+    idBackup INTEGER PRIMARY KEY AUTOINCREMENT,
+    archivo VARCHAR(255) NOT NULL, -- Storage ref of the gzipped JSON file
+    nombreArchivo VARCHAR(255) NOT NULL,
+    origen VARCHAR(20) NOT NULL, -- 'manual', 'programado' or 'pre_restauracion'
+    descripcion TEXT NOT NULL DEFAULT '',
+    motor VARCHAR(20) NOT NULL, -- Database backend it was taken from: only restorable into the same one
+    tamano BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    filas TEXT NOT NULL, -- Rows saved per table
+    creadoPor INT, -- Not a foreign key: restoring a backup replaces the users
+    createdAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Catálogos
+CREATE TABLE IF NOT EXISTS linea_investigacion (
+    idLinea INTEGER PRIMARY KEY AUTOINCREMENT,
+    nombre VARCHAR(200) UNIQUE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS rol_integrante (
+    idRol INTEGER PRIMARY KEY AUTOINCREMENT,
+    nombre VARCHAR(50) UNIQUE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tipo_investigacion (
+    idTipo INTEGER PRIMARY KEY AUTOINCREMENT,
+    nombre VARCHAR(100) UNIQUE NOT NULL
+);
+
+-- Índices
+CREATE INDEX IF NOT EXISTS idx_grupo_investigador_investigador ON Grupo_Investigador(idInvestigador);
+-- Un coordinador vigente (sin fechaFin) por grupo; los índices anteriores a los periodos de
+-- membresía cubrían también los pasados
+DROP INDEX IF EXISTS uq_grupo_investigador_coordinador;
+DROP INDEX IF EXISTS uq_grupo_investigador;
+CREATE UNIQUE INDEX uq_grupo_investigador_coordinador ON Grupo_Investigador(idGrupo) WHERE lower(rol) = 'coordinador' AND fechaFin IS NULL;
+CREATE INDEX IF NOT EXISTS idx_grupo_investigador_grupo_investigador ON Grupo_Investigador(idGrupo, idInvestigador);
+CREATE INDEX IF NOT EXISTS idx_solicitud_grupo_estado ON solicitud_grupo(estado);
+CREATE INDEX IF NOT EXISTS idx_grupo_activos ON Grupo(nombre) WHERE deletedAt IS NULL;
+CREATE INDEX IF NOT EXISTS idx_grupo_estado ON Grupo(estado);
+CREATE INDEX IF NOT EXISTS idx_grupo_padre ON Grupo(idGrupoPadre);
+CREATE INDEX IF NOT EXISTS idx_grupo_numero_resolucion ON Grupo(numeroResolucion);
+CREATE INDEX IF NOT EXISTS idx_grupo_fecha_registro ON Grupo(fechaRegistro);
+CREATE INDEX IF NOT EXISTS idx_grupo_anio_registro ON Grupo(CAST(strftime('%Y', fechaRegistro) AS INTEGER)); -- Filtro ?año=
+CREATE INDEX IF NOT EXISTS idx_grupo_archivo_grupo ON grupo_archivo(idGrupo);
+CREATE INDEX IF NOT EXISTS idx_grupo_archivo_archivo ON grupo_archivo(archivo);
+CREATE INDEX IF NOT EXISTS idx_grupo_comentario_grupo ON grupo_comentario(idGrupo, createdAt);
+CREATE INDEX IF NOT EXISTS idx_grupo_favorito_grupo ON grupo_favorito(idGrupo);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_busqueda_guardada_nombre ON busqueda_guardada(idUsuario, lower(nombre));
+CREATE INDEX IF NOT EXISTS idx_grupo_ref_archivo ON Grupo(archivo);
+CREATE INDEX IF NOT EXISTS idx_archivo_checksum_sha256 ON archivo_checksum(sha256);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_investigador_email ON Investigador(lower(email)) WHERE email IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_investigador_codigo_matricula ON Investigador(lower(codigoMatricula)) WHERE codigoMatricula IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_investigador_tipo ON Investigador(tipo);
+CREATE INDEX IF NOT EXISTS idx_investigador_activos ON Investigador(nombre, apellido) WHERE deletedAt IS NULL;
+CREATE INDEX IF NOT EXISTS idx_investigador_facultad ON Investigador(idFacultad);
+CREATE INDEX IF NOT EXISTS idx_grupo_facultad ON Grupo(idFacultad);
+CREATE INDEX IF NOT EXISTS idx_grupo_vencimiento ON Grupo(fechaVencimiento);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_renovacion_pendiente ON renovacion(idGrupo) WHERE estado = 'pendiente';
+CREATE INDEX IF NOT EXISTS idx_resolucion_archivo ON resolucion(archivo);
+CREATE INDEX IF NOT EXISTS idx_verificacion_email_investigador ON verificacion_email(idInvestigador);
+CREATE INDEX IF NOT EXISTS idx_restablecimiento_password_usuario ON restablecimiento_password(idUsuario);
+CREATE INDEX IF NOT EXISTS idx_convocatoria_estado_cierre ON convocatoria(estado, fechaCierre);
+CREATE INDEX IF NOT EXISTS idx_grupo_convocatoria_grupo ON grupo_convocatoria(idGrupo);
+CREATE INDEX IF NOT EXISTS idx_postulacion_grupo ON postulacion(idGrupo);
+CREATE INDEX IF NOT EXISTS idx_postulacion_historial_postulacion ON postulacion_historial(idPostulacion);
+CREATE INDEX IF NOT EXISTS idx_postulacion_documento_postulacion ON postulacion_documento(idPostulacion);
+CREATE INDEX IF NOT EXISTS idx_postulacion_documento_archivo ON postulacion_documento(archivo);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_publicacion_doi ON publicacion(lower(doi)) WHERE doi IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_investigador_cti_vitae ON investigador_cti_vitae(idCtiVitae);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_proyecto_codigo ON proyecto(lower(codigo)) WHERE codigo IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_proyecto_grupo ON proyecto(idGrupo);
+CREATE INDEX IF NOT EXISTS idx_proyecto_archivo_proyecto ON proyecto_archivo(idProyecto);
+CREATE INDEX IF NOT EXISTS idx_proyecto_archivo_archivo ON proyecto_archivo(archivo);
+CREATE INDEX IF NOT EXISTS idx_publicacion_anio ON publicacion(anio);
+CREATE INDEX IF NOT EXISTS idx_publicacion_investigador_investigador ON publicacion_investigador(idInvestigador);
+CREATE INDEX IF NOT EXISTS idx_publicacion_grupo_grupo ON publicacion_grupo(idGrupo);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entidad ON audit_log(entidad, idEntidad);
+CREATE INDEX IF NOT EXISTS idx_audit_log_fecha ON audit_log(createdAt);
+CREATE INDEX IF NOT EXISTS idx_cambio_fecha ON cambio(createdAt);
+CREATE INDEX IF NOT EXISTS idx_notificacion_pendiente ON notificacion(siguienteIntento) WHERE estado = 'pendiente';
+
+-- Triggers para cada tabla que necesita updatedAt (las sentencias que ya lo asignan no lo repiten)
+CREATE TRIGGER IF NOT EXISTS trigger_updatedat_usuario
+AFTER UPDATE ON Usuario
+FOR EACH ROW WHEN NEW.updated_at IS OLD.updated_at
+BEGIN
+    UPDATE Usuario SET updated_at = CURRENT_TIMESTAMP WHERE idUsuario = NEW.idUsuario;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_updatedat_investigador
+AFTER UPDATE ON Investigador
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE Investigador SET updatedAt = CURRENT_TIMESTAMP WHERE idInvestigador = NEW.idInvestigador;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_updatedat_grupo
+AFTER UPDATE ON Grupo
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE Grupo SET updatedAt = CURRENT_TIMESTAMP WHERE idGrupo = NEW.idGrupo;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_updatedat_grupo_investigador
+AFTER UPDATE ON Grupo_Investigador
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE Grupo_Investigador SET updatedAt = CURRENT_TIMESTAMP WHERE idGrupo_Investigador = NEW.idGrupo_Investigador;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_updatedat_grupo_archivo
+AFTER UPDATE ON grupo_archivo
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE grupo_archivo SET updatedAt = CURRENT_TIMESTAMP WHERE idArchivo = NEW.idArchivo;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_updatedat_export_job
+AFTER UPDATE ON export_job
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE export_job SET updatedAt = CURRENT_TIMESTAMP WHERE idExport = NEW.idExport;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_updatedat_convocatoria
+AFTER UPDATE ON convocatoria
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE convocatoria SET updatedAt = CURRENT_TIMESTAMP WHERE idConvocatoria = NEW.idConvocatoria;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_updatedat_postulacion
+AFTER UPDATE ON postulacion
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE postulacion SET updatedAt = CURRENT_TIMESTAMP WHERE idPostulacion = NEW.idPostulacion;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_updatedat_publicacion
+AFTER UPDATE ON publicacion
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE publicacion SET updatedAt = CURRENT_TIMESTAMP WHERE idPublicacion = NEW.idPublicacion;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_updatedat_facultad
+AFTER UPDATE ON facultad
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE facultad SET updatedAt = CURRENT_TIMESTAMP WHERE idFacultad = NEW.idFacultad;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_updatedat_escuela_profesional
+AFTER UPDATE ON escuela_profesional
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE escuela_profesional SET updatedAt = CURRENT_TIMESTAMP WHERE idEscuela = NEW.idEscuela;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_updatedat_proyecto
+AFTER UPDATE ON proyecto
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE proyecto SET updatedAt = CURRENT_TIMESTAMP WHERE idProyecto = NEW.idProyecto;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_updatedat_resolucion
+AFTER UPDATE ON resolucion
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE resolucion SET updatedAt = CURRENT_TIMESTAMP WHERE idResolucion = NEW.idResolucion;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_updatedat_renovacion
+AFTER UPDATE ON renovacion
+FOR EACH ROW WHEN NEW.updatedAt IS OLD.updatedAt
+BEGIN
+    UPDATE renovacion SET updatedAt = CURRENT_TIMESTAMP WHERE idRenovacion = NEW.idRenovacion;
+END;
+
+-- Feed de cambios de las entidades del directorio (GET /changes). Los triggers de updatedAt hacen un
+-- segundo UPDATE de la fila: el WHEN no repite el cambio que se acaba de registrar para ella.
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_grupo_insert
+AFTER INSERT ON Grupo
+FOR EACH ROW
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('grupo', NEW.idGrupo, CASE WHEN NEW.deletedAt IS NULL THEN 'creado' ELSE 'eliminado' END);
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_grupo_update
+AFTER UPDATE ON Grupo
+FOR EACH ROW WHEN NOT EXISTS (
+    SELECT 1 FROM cambio WHERE idCambio = (SELECT MAX(idCambio) FROM cambio)
+    AND entidad = 'grupo' AND idEntidad = NEW.idGrupo AND operacion = CASE WHEN NEW.deletedAt IS NULL THEN 'actualizado' ELSE 'eliminado' END AND createdAt = CURRENT_TIMESTAMP
+)
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('grupo', NEW.idGrupo, CASE WHEN NEW.deletedAt IS NULL THEN 'actualizado' ELSE 'eliminado' END);
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_grupo_delete
+AFTER DELETE ON Grupo
+FOR EACH ROW
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('grupo', OLD.idGrupo, 'eliminado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_investigador_insert
+AFTER INSERT ON Investigador
+FOR EACH ROW
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('investigador', NEW.idInvestigador, CASE WHEN NEW.deletedAt IS NULL THEN 'creado' ELSE 'eliminado' END);
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_investigador_update
+AFTER UPDATE ON Investigador
+FOR EACH ROW WHEN NOT EXISTS (
+    SELECT 1 FROM cambio WHERE idCambio = (SELECT MAX(idCambio) FROM cambio)
+    AND entidad = 'investigador' AND idEntidad = NEW.idInvestigador AND operacion = CASE WHEN NEW.deletedAt IS NULL THEN 'actualizado' ELSE 'eliminado' END AND createdAt = CURRENT_TIMESTAMP
+)
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('investigador', NEW.idInvestigador, CASE WHEN NEW.deletedAt IS NULL THEN 'actualizado' ELSE 'eliminado' END);
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_investigador_delete
+AFTER DELETE ON Investigador
+FOR EACH ROW
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('investigador', OLD.idInvestigador, 'eliminado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_grupo_investigador_insert
+AFTER INSERT ON Grupo_Investigador
+FOR EACH ROW
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('detalle', NEW.idGrupo_Investigador, 'creado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_grupo_investigador_update
+AFTER UPDATE ON Grupo_Investigador
+FOR EACH ROW WHEN NOT EXISTS (
+    SELECT 1 FROM cambio WHERE idCambio = (SELECT MAX(idCambio) FROM cambio)
+    AND entidad = 'detalle' AND idEntidad = NEW.idGrupo_Investigador AND operacion = 'actualizado' AND createdAt = CURRENT_TIMESTAMP
+)
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('detalle', NEW.idGrupo_Investigador, 'actualizado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_grupo_investigador_delete
+AFTER DELETE ON Grupo_Investigador
+FOR EACH ROW
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('detalle', OLD.idGrupo_Investigador, 'eliminado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_publicacion_insert
+AFTER INSERT ON publicacion
+FOR EACH ROW
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('publicacion', NEW.idPublicacion, 'creado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_publicacion_update
+AFTER UPDATE ON publicacion
+FOR EACH ROW WHEN NOT EXISTS (
+    SELECT 1 FROM cambio WHERE idCambio = (SELECT MAX(idCambio) FROM cambio)
+    AND entidad = 'publicacion' AND idEntidad = NEW.idPublicacion AND operacion = 'actualizado' AND createdAt = CURRENT_TIMESTAMP
+)
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('publicacion', NEW.idPublicacion, 'actualizado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_publicacion_delete
+AFTER DELETE ON publicacion
+FOR EACH ROW
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('publicacion', OLD.idPublicacion, 'eliminado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_proyecto_insert
+AFTER INSERT ON proyecto
+FOR EACH ROW
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('proyecto', NEW.idProyecto, 'creado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_proyecto_update
+AFTER UPDATE ON proyecto
+FOR EACH ROW WHEN NOT EXISTS (
+    SELECT 1 FROM cambio WHERE idCambio = (SELECT MAX(idCambio) FROM cambio)
+    AND entidad = 'proyecto' AND idEntidad = NEW.idProyecto AND operacion = 'actualizado' AND createdAt = CURRENT_TIMESTAMP
+)
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('proyecto', NEW.idProyecto, 'actualizado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_proyecto_delete
+AFTER DELETE ON proyecto
+FOR EACH ROW
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('proyecto', OLD.idProyecto, 'eliminado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_convocatoria_insert
+AFTER INSERT ON convocatoria
+FOR EACH ROW
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('convocatoria', NEW.idConvocatoria, 'creado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_convocatoria_update
+AFTER UPDATE ON convocatoria
+FOR EACH ROW WHEN NOT EXISTS (
+    SELECT 1 FROM cambio WHERE idCambio = (SELECT MAX(idCambio) FROM cambio)
+    AND entidad = 'convocatoria' AND idEntidad = NEW.idConvocatoria AND operacion = 'actualizado' AND createdAt = CURRENT_TIMESTAMP
+)
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('convocatoria', NEW.idConvocatoria, 'actualizado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_convocatoria_delete
+AFTER DELETE ON convocatoria
+FOR EACH ROW
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('convocatoria', OLD.idConvocatoria, 'eliminado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_facultad_insert
+AFTER INSERT ON facultad
+FOR EACH ROW
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('facultad', NEW.idFacultad, 'creado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_facultad_update
+AFTER UPDATE ON facultad
+FOR EACH ROW WHEN NOT EXISTS (
+    SELECT 1 FROM cambio WHERE idCambio = (SELECT MAX(idCambio) FROM cambio)
+    AND entidad = 'facultad' AND idEntidad = NEW.idFacultad AND operacion = 'actualizado' AND createdAt = CURRENT_TIMESTAMP
+)
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('facultad', NEW.idFacultad, 'actualizado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_facultad_delete
+AFTER DELETE ON facultad
+FOR EACH ROW
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('facultad', OLD.idFacultad, 'eliminado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_escuela_profesional_insert
+AFTER INSERT ON escuela_profesional
+FOR EACH ROW
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('escuela', NEW.idEscuela, 'creado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_escuela_profesional_update
+AFTER UPDATE ON escuela_profesional
+FOR EACH ROW WHEN NOT EXISTS (
+    SELECT 1 FROM cambio WHERE idCambio = (SELECT MAX(idCambio) FROM cambio)
+    AND entidad = 'escuela' AND idEntidad = NEW.idEscuela AND operacion = 'actualizado' AND createdAt = CURRENT_TIMESTAMP
+)
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('escuela', NEW.idEscuela, 'actualizado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_cambio_escuela_profesional_delete
+AFTER DELETE ON escuela_profesional
+FOR EACH ROW
+BEGIN
+    INSERT INTO cambio (entidad, idEntidad, operacion) VALUES ('escuela', OLD.idEscuela, 'eliminado');
+END;
+
+-- Periodos de un investigador en un grupo que no se solapan (en PostgreSQL, la restricción de
+-- exclusión ex_grupo_investigador_periodo): RAISE(ABORT) con su nombre llega a la aplicación como
+-- una violación de esa restricción
+CREATE TRIGGER IF NOT EXISTS trigger_grupo_investigador_periodo
+BEFORE INSERT ON Grupo_Investigador
+FOR EACH ROW WHEN EXISTS (
+    SELECT 1 FROM Grupo_Investigador otro
+    WHERE otro.idGrupo = NEW.idGrupo AND otro.idInvestigador = NEW.idInvestigador
+      AND (otro.fechaInicio IS NULL OR NEW.fechaFin IS NULL OR otro.fechaInicio <= NEW.fechaFin)
+      AND (otro.fechaFin IS NULL OR NEW.fechaInicio IS NULL OR NEW.fechaInicio <= otro.fechaFin)
+)
+BEGIN
+    SELECT RAISE(ABORT, 'ex_grupo_investigador_periodo');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_grupo_investigador_periodo_update
+BEFORE UPDATE OF idGrupo, idInvestigador, fechaInicio, fechaFin ON Grupo_Investigador
+FOR EACH ROW WHEN EXISTS (
+    SELECT 1 FROM Grupo_Investigador otro
+    WHERE otro.idGrupo = NEW.idGrupo AND otro.idInvestigador = NEW.idInvestigador
+      AND otro.idGrupo_Investigador <> NEW.idGrupo_Investigador
+      AND (otro.fechaInicio IS NULL OR NEW.fechaFin IS NULL OR otro.fechaInicio <= NEW.fechaFin)
+      AND (otro.fechaFin IS NULL OR NEW.fechaInicio IS NULL OR NEW.fechaInicio <= otro.fechaFin)
+)
+BEGIN
+    SELECT RAISE(ABORT, 'ex_grupo_investigador_periodo');
+END;
+
+-- Membresías solo de investigadores activos: RAISE(ABORT) llega a la aplicación como una violación
+-- de clave foránea, igual que en PostgreSQL
+CREATE TRIGGER IF NOT EXISTS trigger_investigador_activo_grupo_investigador
+BEFORE INSERT ON Grupo_Investigador
+FOR EACH ROW WHEN EXISTS (SELECT 1 FROM Investigador WHERE idInvestigador = NEW.idInvestigador AND deletedAt IS NOT NULL)
+BEGIN
+    SELECT RAISE(ABORT, 'investigador eliminado');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trigger_investigador_activo_grupo_investigador_update
+BEFORE UPDATE OF idInvestigador ON Grupo_Investigador
+FOR EACH ROW WHEN EXISTS (SELECT 1 FROM Investigador WHERE idInvestigador = NEW.idInvestigador AND deletedAt IS NOT NULL)
+BEGIN
+    SELECT RAISE(ABORT, 'investigador eliminado');
+END;
+
+-- Datos iniciales de catálogos
+INSERT INTO linea_investigacion (nombre) VALUES
+    ('Ciencias de la Salud'),
+    ('Ciencias Agrarias y Ambientales'),
+    ('Ingeniería y Tecnología'),
+    ('Ciencias Sociales y Humanidades'),
+    ('Educación'),
+    ('Ciencias Económicas y Empresariales')
+ON CONFLICT (nombre) DO NOTHING;
+
+INSERT INTO rol_integrante (nombre) VALUES
+    ('Coordinador'),
+    ('Integrante')
+ON CONFLICT (nombre) DO NOTHING;
+
+INSERT INTO tipo_investigacion (nombre) VALUES
+    ('Básica'),
+    ('Aplicada'),
+    ('Experimental'),
+    ('Desarrollo tecnológico'),
+    ('Innovación')
+ON CONFLICT (nombre) DO NOTHING;
+
+-- Resolución de creación de los grupos que aún no tienen ninguna (los registrados antes de que
+-- existieran las resoluciones), a partir de su numeroResolucion, fechaRegistro y archivo
+INSERT INTO resolucion (idGrupo, numero, fechaEmision, tipo, archivo)
+SELECT g.idGrupo, g.numeroResolucion, g.fechaRegistro, 'creacion', g.archivo
+FROM Grupo g
+WHERE g.numeroResolucion <> '' AND NOT EXISTS (SELECT 1 FROM resolucion r WHERE r.idGrupo = g.idGrupo);
+
+-- Vigencia de las resoluciones de creación y renovación que no la tienen (las registradas antes de
+-- que existiera fechaVencimiento), con la de GRUPO_VIGENCIA_ANIOS por defecto, y la de cada grupo
+UPDATE resolucion SET fechaVencimiento = date(fechaEmision, '+2 years')
+WHERE fechaVencimiento IS NULL AND tipo IN ('creacion', 'renovacion');
+UPDATE Grupo SET fechaVencimiento = (SELECT MAX(r.fechaVencimiento) FROM resolucion r WHERE r.idGrupo = Grupo.idGrupo)
+WHERE fechaVencimiento IS NULL;
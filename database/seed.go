@@ -0,0 +1,42 @@
+package database
+
+import (
+	"database/sql"
+	_ "embed" // Para embeber seed.sql en el binario
+	"fmt"
+	"log/slog"
+)
+
+// seedSQL contiene los datos de ejemplo (investigadores, grupos y membresías).
+//
+//go:embed seed.sql
+var seedSQL string
+
+// seedSQLiteSQL son los mismos datos para DB_DRIVER=sqlite.
+//
+//go:embed seed_sqlite.sql
+var seedSQLiteSQL string
+
+// Seed carga los datos de ejemplo en una base de datos con el esquema ya aplicado.
+// Es idempotente: los registros existentes no se duplican.
+func Seed(db *sql.DB) error {
+	slog.Info("loading sample data")
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start seed transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	seed := seedSQL
+	if Driver() == DriverSQLite {
+		seed = seedSQLiteSQL
+	}
+	if _, err := tx.Exec(seed); err != nil {
+		return fmt.Errorf("failed to load sample data: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit sample data: %w", err)
+	}
+	slog.Info("Sample data loaded")
+	return nil
+}
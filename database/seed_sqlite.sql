@@ -0,0 +1,89 @@
+-- Datos de ejemplo para DB_DRIVER=sqlite: los mismos de seed.sql (mantener ambos sincronizados).
+-- Idempotente: puede ejecutarse varias veces sin duplicar registros.
+
+INSERT INTO facultad (nombre, siglas) VALUES
+    ('Facultad de Ciencias de la Salud', 'FCS'),
+    ('Facultad de Ingeniería', 'FI'),
+    ('Facultad de Educación', 'FE')
+ON CONFLICT (nombre) DO NOTHING;
+
+WITH e(facultad, nombre) AS (VALUES
+    ('Facultad de Ciencias de la Salud', 'Enfermería'),
+    ('Facultad de Ciencias de la Salud', 'Medicina Humana'),
+    ('Facultad de Ingeniería', 'Ingeniería Mecánica Eléctrica'),
+    ('Facultad de Ingeniería', 'Ingeniería de Sistemas'),
+    ('Facultad de Educación', 'Educación Primaria')
+)
+INSERT INTO escuela_profesional (idFacultad, nombre)
+SELECT f.idFacultad, e.nombre
+FROM e
+JOIN facultad f ON f.nombre = e.facultad
+WHERE true
+ON CONFLICT (idFacultad, nombre) DO NOTHING;
+
+INSERT INTO Investigador (nombre, apellido, email, emailVerificado) VALUES
+    ('Ana', 'Quispe Mamani', 'ana.quispe@example.org', TRUE),
+    ('Luis', 'Huamán Torres', 'luis.huaman@example.org', TRUE),
+    ('María', 'Condori Ramos', 'maria.condori@example.org', FALSE),
+    ('Jorge', 'Vargas Llosa', 'jorge.vargas@example.org', TRUE),
+    ('Rosa', 'Pari Flores', 'rosa.pari@example.org', FALSE)
+ON CONFLICT (lower(email)) WHERE email IS NOT NULL DO NOTHING;
+
+WITH g(nombre, numeroResolucion, lineaInvestigacion, tipoInvestigacion, fechaRegistro) AS (VALUES
+    ('Grupo de Investigación en Salud Andina', 'R-001-2023-VRI', 'Ciencias de la Salud', 'Aplicada', '2023-03-15'),
+    ('Laboratorio de Energías Renovables', 'R-014-2023-VRI', 'Ingeniería y Tecnología', 'Aplicada', '2023-06-02'),
+    ('Observatorio de Educación Rural', 'R-007-2024-VRI', 'Educación', 'Básica', '2024-01-20')
+)
+INSERT INTO Grupo (nombre, numeroResolucion, lineaInvestigacion, tipoInvestigacion, fechaRegistro)
+SELECT g.nombre, g.numeroResolucion, g.lineaInvestigacion, g.tipoInvestigacion, g.fechaRegistro
+FROM g
+WHERE NOT EXISTS (SELECT 1 FROM Grupo WHERE Grupo.nombre = g.nombre);
+
+WITH m(grupo, email, rol) AS (VALUES
+    ('Grupo de Investigación en Salud Andina', 'ana.quispe@example.org', 'Coordinador'),
+    ('Grupo de Investigación en Salud Andina', 'maria.condori@example.org', 'Integrante'),
+    ('Laboratorio de Energías Renovables', 'luis.huaman@example.org', 'Coordinador'),
+    ('Laboratorio de Energías Renovables', 'jorge.vargas@example.org', 'Integrante'),
+    ('Laboratorio de Energías Renovables', 'ana.quispe@example.org', 'Integrante'),
+    ('Observatorio de Educación Rural', 'rosa.pari@example.org', 'Coordinador')
+)
+INSERT INTO Grupo_Investigador (idGrupo, idInvestigador, rol, fechaInicio)
+SELECT gr.idGrupo, i.idInvestigador, m.rol, gr.fechaRegistro
+FROM m
+JOIN Grupo gr ON gr.nombre = m.grupo AND gr.deletedAt IS NULL
+JOIN Investigador i ON lower(i.email) = m.email AND i.deletedAt IS NULL
+WHERE NOT EXISTS (SELECT 1 FROM Grupo_Investigador gi WHERE gi.idGrupo = gr.idGrupo AND gi.idInvestigador = i.idInvestigador);
+
+-- Facultad de cada grupo y facultad y escuela de cada investigador (solo si aún no la tienen)
+WITH x(grupo, facultad) AS (VALUES
+    ('Grupo de Investigación en Salud Andina', 'Facultad de Ciencias de la Salud'),
+    ('Laboratorio de Energías Renovables', 'Facultad de Ingeniería'),
+    ('Observatorio de Educación Rural', 'Facultad de Educación')
+)
+UPDATE Grupo SET idFacultad = (SELECT f.idFacultad FROM x JOIN facultad f ON f.nombre = x.facultad WHERE x.grupo = Grupo.nombre)
+WHERE idFacultad IS NULL AND nombre IN (SELECT grupo FROM x);
+
+WITH x(email, escuela) AS (VALUES
+    ('ana.quispe@example.org', 'Medicina Humana'),
+    ('maria.condori@example.org', 'Enfermería'),
+    ('luis.huaman@example.org', 'Ingeniería Mecánica Eléctrica'),
+    ('jorge.vargas@example.org', 'Ingeniería de Sistemas'),
+    ('rosa.pari@example.org', 'Educación Primaria')
+)
+UPDATE Investigador SET
+    idFacultad = (SELECT e.idFacultad FROM x JOIN escuela_profesional e ON e.nombre = x.escuela WHERE x.email = lower(Investigador.email)),
+    idEscuela = (SELECT e.idEscuela FROM x JOIN escuela_profesional e ON e.nombre = x.escuela WHERE x.email = lower(Investigador.email))
+WHERE idFacultad IS NULL AND lower(email) IN (SELECT email FROM x);
+
+-- Resolución de creación de cada grupo
+INSERT INTO resolucion (idGrupo, numero, fechaEmision, tipo, archivo)
+SELECT g.idGrupo, g.numeroResolucion, g.fechaRegistro, 'creacion', g.archivo
+FROM Grupo g
+WHERE g.numeroResolucion <> '' AND NOT EXISTS (SELECT 1 FROM resolucion r WHERE r.idGrupo = g.idGrupo);
+
+-- Vigencia de las resoluciones de creación y renovación que no la tienen (las registradas antes de
+-- que existiera fechaVencimiento), con la de GRUPO_VIGENCIA_ANIOS por defecto, y la de cada grupo
+UPDATE resolucion SET fechaVencimiento = date(fechaEmision, '+2 years')
+WHERE fechaVencimiento IS NULL AND tipo IN ('creacion', 'renovacion');
+UPDATE Grupo SET fechaVencimiento = (SELECT MAX(r.fechaVencimiento) FROM resolucion r WHERE r.idGrupo = Grupo.idGrupo)
+WHERE fechaVencimiento IS NULL;
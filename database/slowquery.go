@@ -0,0 +1,239 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+)
+
+// defaultSlowQueryThreshold is the duration from which a statement is logged as slow.
+const defaultSlowQueryThreshold = 500 * time.Millisecond
+
+// maxLoggedSQL caps the length of the SQL text in the slow query log.
+const maxLoggedSQL = 2000
+
+// slowQueries counts the statements logged as slow since the process started.
+var slowQueries atomic.Int64
+
+// SlowQueries returns the number of statements logged as slow since the process started.
+func SlowQueries() int64 {
+	return slowQueries.Load()
+}
+
+// slowQueryThresholdFromEnv reads DB_SLOW_QUERY_THRESHOLD (e.g. "200ms"; "0" disables the log).
+func slowQueryThresholdFromEnv() (time.Duration, error) {
+	v := os.Getenv("DB_SLOW_QUERY_THRESHOLD")
+	if v == "" {
+		return defaultSlowQueryThreshold, nil
+	}
+	if v == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid DB_SLOW_QUERY_THRESHOLD %q: use a duration such as 500ms", v)
+	}
+	return d, nil
+}
+
+var (
+	literalesSQL = regexp.MustCompile(`'(?:[^']|'')*'`)
+	espaciosSQL  = regexp.MustCompile(`\s+`)
+)
+
+// sanitizeSQL prepares a statement for the log: string literals are replaced with '?' (the
+// arguments are never logged, but a few queries inline constants) and whitespace is collapsed.
+func sanitizeSQL(query string) string {
+	s := literalesSQL.ReplaceAllString(query, "'?'")
+	s = strings.TrimSpace(espaciosSQL.ReplaceAllString(s, " "))
+	if len(s) > maxLoggedSQL {
+		s = s[:maxLoggedSQL] + "..."
+	}
+	return s
+}
+
+// slowQueryConnector wraps the connections of a driver so that every statement that takes longer
+// than threshold is logged with its SQL and duration, through the logger of the request that ran
+// it. The time measured is until the driver returns, i.e. for a query until the first rows arrive.
+type slowQueryConnector struct {
+	driver.Connector
+	threshold time.Duration
+}
+
+func (c slowQueryConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &slowQueryConn{Conn: conn, threshold: c.threshold}, nil
+}
+
+// observe logs the statement if it ran for longer than threshold.
+func observe(ctx context.Context, threshold time.Duration, query string, inicio time.Time, err error) {
+	duracion := time.Since(inicio)
+	if duracion < threshold {
+		return
+	}
+	slowQueries.Add(1)
+	attrs := []any{"duration_ms", duracion.Milliseconds(), "threshold_ms", threshold.Milliseconds(), "sql", sanitizeSQL(query)}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+	logging.FromContext(ctx).Warn("Slow query", attrs...)
+}
+
+// slowQueryConn forwards every call to the driver's connection, timing statements. The optional
+// interfaces of database/sql/driver are implemented by falling back to driver.ErrSkip (or the
+// non-context method) when the wrapped connection lacks them, as database/sql itself does.
+type slowQueryConn struct {
+	driver.Conn
+	threshold time.Duration
+}
+
+func (c *slowQueryConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	inicio := time.Now()
+	res, err := execer.ExecContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		observe(ctx, c.threshold, query, inicio, err)
+	}
+	return res, err
+}
+
+func (c *slowQueryConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	inicio := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		observe(ctx, c.threshold, query, inicio, err)
+	}
+	return rows, err
+}
+
+func (c *slowQueryConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &slowQueryStmt{Stmt: stmt, query: query, threshold: c.threshold}, nil
+}
+
+func (c *slowQueryConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *slowQueryConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *slowQueryConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *slowQueryConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *slowQueryConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// Raw returns the wrapped connection, e.g. for the pgx batches of the repository.
+func (c *slowQueryConn) Raw() driver.Conn {
+	return c.Conn
+}
+
+func (c *slowQueryConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// slowQueryStmt times the executions of a prepared statement.
+type slowQueryStmt struct {
+	driver.Stmt
+	query     string
+	threshold time.Duration
+}
+
+func (s *slowQueryStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	inicio := time.Now()
+	var (
+		res driver.Result
+		err error
+	)
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = execer.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			res, err = s.Stmt.Exec(values)
+		}
+	}
+	observe(ctx, s.threshold, s.query, inicio, err)
+	return res, err
+}
+
+func (s *slowQueryStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	inicio := time.Now()
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			rows, err = s.Stmt.Query(values)
+		}
+	}
+	observe(ctx, s.threshold, s.query, inicio, err)
+	return rows, err
+}
+
+// namedValues converts the arguments for the methods that predate named parameters.
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("the driver does not support named parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
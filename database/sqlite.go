@@ -0,0 +1,338 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Database backends selectable with DB_DRIVER.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// defaultSQLitePath is the database file used with DB_DRIVER=sqlite when SQLITE_PATH is not set.
+const defaultSQLitePath = "apigrupos.db"
+
+// Driver returns the database backend selected by DB_DRIVER: DriverPostgres (the default) or
+// DriverSQLite, meant for local development and demos. Demo mode always uses SQLite.
+func Driver() string {
+	if DemoMode() || strings.EqualFold(os.Getenv("DB_DRIVER"), DriverSQLite) {
+		return DriverSQLite
+	}
+	return DriverPostgres
+}
+
+// registerSQLite registers the functions and collation the translated statements rely on, for
+// the connections opened afterwards.
+var registerSQLite = sync.OnceValue(func() error {
+	funciones := []struct {
+		nombre string
+		nArgs  int32
+		f      func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error)
+	}{
+		{"f_unaccent", 1, textFunction(unaccent)},
+		{"unaccent", 1, textFunction(unaccent)},
+		{"similarity", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			return trigramSimilarity(textArg(args[0]), textArg(args[1])), nil
+		}},
+		{"ts_match", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			return tsMatch(textArg(args[0]), textArg(args[1])), nil
+		}},
+		{"ts_rank", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			return tsRank(textArg(args[0]), textArg(args[1])), nil
+		}},
+		{"palabras_comunes", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			return int64(palabrasComunes(textArg(args[0]), textArg(args[1]))), nil
+		}},
+	}
+	for _, f := range funciones {
+		if err := sqlite.RegisterDeterministicScalarFunction(f.nombre, f.nArgs, f.f); err != nil {
+			return err
+		}
+	}
+	// The whole database is locked by each write transaction, so advisory locks are not needed
+	if err := sqlite.RegisterScalarFunction("pg_advisory_xact_lock", 1, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+		return nil, nil
+	}); err != nil {
+		return err
+	}
+	return sqlite.RegisterCollationUtf8("es_icu", compareSpanish)
+})
+
+// demoDatabase is the in-memory database of demo mode, shared by the connections of the process.
+const demoDatabase = "/apigrupos-demo"
+
+// demoConn keeps the in-memory database of demo mode alive: it is dropped when its last
+// connection closes, which the pool would otherwise do when it recycles its connections.
+var demoConn driver.Conn
+
+// sqliteConnectorFromEnv opens the SQLite database at SQLITE_PATH (created if missing), with
+// foreign keys enforced, WAL so that reads do not wait for writes and transactions that take the
+// write lock when they begin, as SELECT ... FOR UPDATE would. In demo mode the database lives in
+// memory instead, and is empty until the schema is applied.
+func sqliteConnectorFromEnv() (driver.Connector, string, error) {
+	if err := registerSQLite(); err != nil {
+		return nil, "", fmt.Errorf("failed to register SQLite functions: %w", err)
+	}
+	path := os.Getenv("SQLITE_PATH")
+	if path == "" {
+		path = defaultSQLitePath
+	}
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Set("_txlock", "immediate")
+	if DemoMode() {
+		path = demoDatabase
+		params.Set("vfs", "memdb")
+	} else {
+		params.Add("_pragma", "journal_mode(WAL)")
+	}
+	// The functions are registered on the driver the package registers with database/sql
+	db, err := sql.Open("sqlite", "")
+	if err != nil {
+		return nil, "", err
+	}
+	defer db.Close()
+	c := sqliteConnector{dsn: "file:" + path + "?" + params.Encode(), driver: db.Driver()}
+	if DemoMode() {
+		if demoConn == nil {
+			if demoConn, err = c.driver.Open(c.dsn); err != nil {
+				return nil, "", fmt.Errorf("failed to create the demo database: %w", err)
+			}
+		}
+		path = ":memory:"
+	}
+	return c, path, nil
+}
+
+// sqliteConnector opens connections to a SQLite database that take the PostgreSQL statements of
+// the repository (see translateSQL).
+type sqliteConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c sqliteConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteConn{Conn: conn}, nil
+}
+
+func (c sqliteConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// sqliteConn translates the statements and arguments to SQLite and the constraint errors back to
+// PostgreSQL's (*pgconn.PgError), which is what the repository maps to domain errors.
+type sqliteConn struct {
+	driver.Conn
+}
+
+func (c *sqliteConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.Conn.(driver.ExecerContext).ExecContext(ctx, translateSQL(query), args)
+	return res, translateSQLiteError(err)
+}
+
+func (c *sqliteConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.Conn.(driver.QueryerContext).QueryContext(ctx, translateSQL(query), args)
+	if err != nil {
+		return nil, translateSQLiteError(err)
+	}
+	return sqliteRows{rows}, nil
+}
+
+func (c *sqliteConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, translateSQL(query))
+	if err != nil {
+		return nil, translateSQLiteError(err)
+	}
+	return sqliteStmt{stmt}, nil
+}
+
+func (c *sqliteConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *sqliteConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *sqliteConn) Ping(ctx context.Context) error {
+	return c.Conn.(driver.Pinger).Ping(ctx)
+}
+
+func (c *sqliteConn) ResetSession(ctx context.Context) error {
+	return c.Conn.(driver.SessionResetter).ResetSession(ctx)
+}
+
+func (c *sqliteConn) IsValid() bool {
+	return c.Conn.(driver.Validator).IsValid()
+}
+
+// sqliteTimeFormat is the format of the times bound as arguments, the one of CURRENT_TIMESTAMP so
+// that they compare as text with the stored ones; dates (midnight UTC) are bound as CURRENT_DATE.
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+// CheckNamedValue binds slices (PostgreSQL arrays) as JSON arrays and times as UTC text.
+func (c *sqliteConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if v := reflect.ValueOf(nv.Value); v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		if v.IsNil() {
+			nv.Value = "[]"
+			return nil
+		}
+		b, err := json.Marshal(nv.Value)
+		if err != nil {
+			return err
+		}
+		nv.Value = string(b)
+		return nil
+	}
+	v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	if t, ok := v.(time.Time); ok {
+		t = t.UTC()
+		if t.Equal(t.Truncate(24 * time.Hour)) {
+			v = t.Format(time.DateOnly)
+		} else {
+			v = t.Format(sqliteTimeFormat)
+		}
+	}
+	nv.Value = v
+	return nil
+}
+
+// Raw returns the wrapped connection.
+func (c *sqliteConn) Raw() driver.Conn {
+	return c.Conn
+}
+
+// sqliteRows translates the errors of a statement that fails while its rows are read, such as an
+// INSERT ... RETURNING. The driver returns the values of DATE and TIMESTAMP columns as times, but
+// those of expressions (COALESCE(MAX(updatedAt), ...)) as text, which sqliteRows parses when it is
+// a timestamp as SQLite writes them.
+type sqliteRows struct {
+	driver.Rows
+}
+
+func (r sqliteRows) Next(dest []driver.Value) error {
+	if err := r.Rows.Next(dest); err != nil {
+		return translateSQLiteError(err)
+	}
+	tipos, _ := r.Rows.(driver.RowsColumnTypeDatabaseTypeName)
+	for i, v := range dest {
+		s, ok := v.(string)
+		if !ok || len(s) != len(sqliteTimeFormat) || tipos == nil || tipos.ColumnTypeDatabaseTypeName(i) != "" {
+			continue
+		}
+		if t, err := time.Parse(sqliteTimeFormat, s); err == nil {
+			dest[i] = t
+		}
+	}
+	return nil
+}
+
+// ColumnTypeDatabaseTypeName returns the declared type of a column ("" for expressions), which the
+// embedded interface would hide from database/sql.
+func (r sqliteRows) ColumnTypeDatabaseTypeName(i int) string {
+	if tipos, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return tipos.ColumnTypeDatabaseTypeName(i)
+	}
+	return ""
+}
+
+// sqliteStmt translates the errors of a prepared statement.
+type sqliteStmt struct {
+	driver.Stmt
+}
+
+func (s sqliteStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	res, err := s.Stmt.(driver.StmtExecContext).ExecContext(ctx, args)
+	return res, translateSQLiteError(err)
+}
+
+func (s sqliteStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := s.Stmt.(driver.StmtQueryContext).QueryContext(ctx, args)
+	if err != nil {
+		return nil, translateSQLiteError(err)
+	}
+	return sqliteRows{rows}, nil
+}
+
+// sqliteUniqueIndexes names the unique indexes on columns, which SQLite reports by their columns
+// ("Grupo_Investigador.idGrupo"); the ones on expressions it reports by name.
+var sqliteUniqueIndexes = map[string]string{
+	"grupo_investigador.idgrupo":                      "uq_grupo_investigador_coordinador",
+	"investigador_cti_vitae.idctivitae":               "uq_investigador_cti_vitae",
+	"postulacion.idconvocatoria, postulacion.idgrupo": "uq_postulacion_grupo",
+	"renovacion.idgrupo":                              "uq_renovacion_pendiente",
+}
+
+var (
+	indiceEnError    = regexp.MustCompile(`index '(\w+)'`)
+	exclusionEnError = regexp.MustCompile(`\b(ex_\w+)\b`)
+	columnasEnError  = regexp.MustCompile(`constraint failed: ((\w+)\.\w+(?:, \w+\.\w+)*)`)
+)
+
+// translateSQLiteError turns a constraint violation into the *pgconn.PgError PostgreSQL would
+// return: unique violations with the index (or the name PostgreSQL gives the constraint), and
+// foreign key violations, which SQLite does not attribute to any constraint. The triggers that
+// reject references to soft-deleted rows raise foreign key violations too, as in PostgreSQL, and
+// those standing in for an exclusion constraint raise its violation, naming it (ex_...).
+func translateSQLiteError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	pgErr := &pgconn.PgError{Severity: "ERROR", Message: sqliteErr.Error()}
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		pgErr.Code = "23505"
+		if m := indiceEnError.FindStringSubmatch(pgErr.Message); m != nil {
+			pgErr.ConstraintName = m[1]
+		} else if m := columnasEnError.FindStringSubmatch(pgErr.Message); m != nil {
+			columnas := strings.ToLower(m[1])
+			pgErr.TableName = strings.ToLower(m[2])
+			pgErr.ConstraintName = sqliteUniqueIndexes[columnas]
+			if pgErr.ConstraintName == "" {
+				pgErr.ConstraintName = pgErr.TableName + "_pkey"
+				if sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+					pgErr.ConstraintName = strings.NewReplacer(", "+pgErr.TableName+".", "_", ".", "_").Replace(columnas) + "_key"
+				}
+			}
+		}
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY, sqlite3.SQLITE_CONSTRAINT_TRIGGER:
+		pgErr.Code = "23503"
+		if m := exclusionEnError.FindStringSubmatch(pgErr.Message); m != nil {
+			pgErr.Code = "23P01"
+			pgErr.ConstraintName = m[1]
+		}
+	case sqlite3.SQLITE_CONSTRAINT_CHECK:
+		pgErr.Code = "23514"
+	case sqlite3.SQLITE_CONSTRAINT_NOTNULL:
+		pgErr.Code = "23502"
+	default:
+		return err
+	}
+	return pgErr
+}
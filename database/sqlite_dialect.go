@@ -0,0 +1,167 @@
+package database
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// The repository writes PostgreSQL. With DB_DRIVER=sqlite each statement is translated to SQLite
+// before it reaches the driver, by the rewrites below, applied in order. They cover the constructs
+// the repository uses, not PostgreSQL in general; the queries they do not cover have a SQLite
+// version of their own in the repository (see "Backend SQLite" in the README).
+//
+// Arrays travel as JSON: slice arguments are bound as JSON text (see sqliteConn.CheckNamedValue),
+// array columns are stored as JSON and array expressions produce JSON, which the repository scans
+// like a PostgreSQL array.
+var sqliteRewrites = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// Placeholders: $1 -> ?1
+	{regexp.MustCompile(`\$(\d+)`), `?$1`},
+	// Locks: SQLite locks the whole database for the transaction (BEGIN IMMEDIATE)
+	{regexp.MustCompile(`\s+FOR UPDATE( SKIP LOCKED)?`), ``},
+	// Full-text search on Grupo.busqueda (see tsMatch and tsRank)
+	{regexp.MustCompile(`(\w+\.)?busqueda @@ websearch_to_tsquery\('es_unaccent', (\?\d+)\)`), `ts_match(${1}busqueda, $2)`},
+	{regexp.MustCompile(`ts_rank\(((?:\w+\.)?busqueda), websearch_to_tsquery\('es_unaccent', (\?\d+)\)\)`), `ts_rank($1, $2)`},
+	// Any of a list of patterns: x ILIKE ANY (SELECT f_unaccent(p) FROM unnest($1::text[]) AS p)
+	{regexp.MustCompile(`(\w+\([\w.]+\)) ILIKE ANY \(SELECT (\w+)\(p\) FROM unnest\((\?\d+)(?:::\w+\[\])?\) AS p\)`), `EXISTS (SELECT 1 FROM json_each($3) WHERE $1 LIKE $2(value))`},
+	// LIKE is case-insensitive in SQLite (for ASCII, which is what f_unaccent leaves)
+	{regexp.MustCompile(`\bILIKE\b`), `LIKE`},
+	// INSERT ... SELECT $1, unnest($2::int[]): the WHERE keeps ON CONFLICT from parsing as a join
+	{regexp.MustCompile(`SELECT (\?\d+), unnest\((\?\d+)(?:::\w+\[\])?\)`), `SELECT $1, value FROM json_each($2) WHERE true`},
+	// unnest(x) WITH ORDINALITY AS a(v, n) (see also rewriteUnnestJoins)
+	{regexp.MustCompile(`unnest\(([\w.?]+)\) WITH ORDINALITY AS (\w+)\((\w+), (\w+)\)`), `(SELECT value AS $3, key + 1 AS $4 FROM json_each($1)) AS $2`},
+	{regexp.MustCompile(`FROM unnest\(([\w.?]+)(?:::\w+\[\])?\) (?:AS )?(\w+)`), `FROM (SELECT value AS $2 FROM json_each($1)) AS $2`},
+	// x = ANY(array)
+	{regexp.MustCompile(`=\s*ANY\s*\(([^()]+)\)`), `IN (SELECT value FROM json_each($1))`},
+	// Array literals and concatenation (the recursive CTEs keep the path in an array named ruta)
+	{regexp.MustCompile(`ARRAY\[([^\]]*)\]`), `json_array($1)`},
+	{regexp.MustCompile(`(\w+\.ruta) \|\| ([\w.]+)`), `json_insert($1, '$$[#]', $2)`},
+	{regexp.MustCompile(`\barray_agg\(`), `json_group_array(`},
+	// Dates
+	{regexp.MustCompile(`EXTRACT\(YEAR FROM ([\w.]+)\)`), `CAST(strftime('%Y', $1) AS INTEGER)`},
+	{regexp.MustCompile(`'epoch'`), `'1970-01-01 00:00:00'`},
+	{regexp.MustCompile(`CURRENT_TIMESTAMP \+ (\?\d+) \* INTERVAL '1 second'`), `datetime(CURRENT_TIMESTAMP, '+' || $1 || ' seconds')`},
+	{regexp.MustCompile(`CURRENT_TIMESTAMP \+ make_interval\(hours => (\?\d+)\)`), `datetime(CURRENT_TIMESTAMP, '+' || $1 || ' hours')`},
+	// Casts: to floating point, so that divisions are not integer ones; any other is dropped
+	{regexp.MustCompile(`::(float8|real|double precision)\b`), ` * 1.0`},
+	{regexp.MustCompile(`::\w+(\[\])?`), ``},
+	{regexp.MustCompile(`\bIS NOT DISTINCT FROM\b`), `IS`},
+	{regexp.MustCompile(`\bLEAST\(`), `MIN(`},
+	// UPDATE/DELETE aliases require AS
+	{regexp.MustCompile(`\b(UPDATE|DELETE FROM) (\w+) (\w+) (SET|WHERE)\b`), `$1 $2 AS $3 $4`},
+}
+
+var (
+	// arrayQuery matches the start of ARRAY(SELECT ...), rewritten by rewriteArrayQueries.
+	arrayQuery = regexp.MustCompile(`\bARRAY\(\s*SELECT\s`)
+	// unnestJoin matches unnest(x) WITH ORDINALITY AS a(v, n) as a table of the FROM.
+	unnestJoin = regexp.MustCompile(`, unnest\(([\w.$]+)\) WITH ORDINALITY AS (\w+)\((\w+), (\w+)\)`)
+	// aliasedTarget captures the table and alias of an INSERT, UPDATE or DELETE.
+	aliasedTarget = regexp.MustCompile(`^\s*(?:INSERT INTO|UPDATE|DELETE FROM) (\w+) AS (\w+)\b`)
+)
+
+// sqliteQueries caches the translation of each statement; the repository uses a bounded set.
+var sqliteQueries sync.Map // PostgreSQL -> SQLite
+
+// translateSQL translates a statement of the repository to SQLite.
+func translateSQL(query string) string {
+	if q, ok := sqliteQueries.Load(query); ok {
+		return q.(string)
+	}
+	q := rewriteUnnestJoins(rewriteArrayQueries(query))
+	for _, r := range sqliteRewrites {
+		q = r.re.ReplaceAllString(q, r.repl)
+	}
+	q = qualifyReturning(q)
+	sqliteQueries.Store(query, q)
+	return q
+}
+
+// rewriteArrayQueries turns ARRAY(SELECT x FROM ... ORDER BY y) into
+// (SELECT json_group_array(x ORDER BY y) FROM ...).
+func rewriteArrayQueries(q string) string {
+	for {
+		loc := arrayQuery.FindStringIndex(q)
+		if loc == nil {
+			return q
+		}
+		inicio := loc[0] + len("ARRAY(")
+		fin := closingParen(q, inicio)
+		if fin < 0 {
+			return q
+		}
+		sub := espaciosSQL.ReplaceAllString(strings.TrimSpace(q[inicio:fin]), " ")[len("SELECT "):]
+		from := topLevelIndex(sub, " FROM ")
+		if from < 0 {
+			return q
+		}
+		expr, resto := sub[:from], sub[from:]
+		orden := ""
+		if i := topLevelIndex(resto, " ORDER BY "); i >= 0 {
+			resto, orden = resto[:i], resto[i:]
+		}
+		q = q[:loc[0]] + "(SELECT json_group_array(" + expr + orden + ")" + resto + ")" + q[fin+1:]
+	}
+}
+
+// rewriteUnnestJoins turns a FROM of the form "t, unnest(t.x) WITH ORDINALITY AS a(v, n)" into
+// "t, json_each(t.x) AS a", renaming a.v and a.n to the columns of json_each. Unlike a subquery,
+// json_each can refer to the tables before it.
+func rewriteUnnestJoins(q string) string {
+	for _, m := range unnestJoin.FindAllStringSubmatch(q, -1) {
+		alias := m[2]
+		q = strings.Replace(q, m[0], ", json_each("+m[1]+") AS "+alias, 1)
+		q = regexp.MustCompile(`\b`+alias+`\.`+m[3]+`\b`).ReplaceAllString(q, alias+".value")
+		q = regexp.MustCompile(`\b`+alias+`\.`+m[4]+`\b`).ReplaceAllString(q, alias+".key")
+	}
+	return q
+}
+
+// closingParen returns the index of the parenthesis closing the one open at from, or -1.
+func closingParen(s string, from int) int {
+	nivel := 1
+	for i := from; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			nivel++
+		case ')':
+			if nivel--; nivel == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// topLevelIndex returns the index of the first occurrence of sep in s outside parentheses, or -1.
+func topLevelIndex(s, sep string) int {
+	nivel := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			nivel++
+		case ')':
+			nivel--
+		default:
+			if nivel == 0 && strings.HasPrefix(s[i:], sep) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// qualifyReturning replaces the alias of the target table in the RETURNING clause, which SQLite
+// does not accept there, with the table name.
+func qualifyReturning(q string) string {
+	m := aliasedTarget.FindStringSubmatch(q)
+	i := strings.LastIndex(q, "RETURNING ")
+	if m == nil || i < 0 {
+		return q
+	}
+	alias := regexp.MustCompile(`\b` + m[2] + `\.`)
+	return q[:i] + alias.ReplaceAllString(q[i:], m[1]+".")
+}
@@ -0,0 +1,246 @@
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+	"modernc.org/sqlite"
+)
+
+// The SQLite counterparts of the PostgreSQL functions (unaccent, pg_trgm, full-text search) and
+// of the es_icu collation used by the repository. They are approximations: enough to develop
+// against and to run a demo, not to compare results with PostgreSQL.
+
+// textArg returns a SQL argument as text ("" for NULL).
+func textArg(v driver.Value) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// textFunction adapts a text function to a SQL function of one argument that keeps NULL.
+func textFunction(f func(string) string) func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+	return func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		return f(textArg(args[0])), nil
+	}
+}
+
+// unaccent removes the diacritics of s ("Ñuñoa" -> "Nunoa"), like PostgreSQL's unaccent.
+func unaccent(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// palabras splits s into its lowercase, unaccented words.
+func palabras(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(unaccent(s)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// trigrams returns the trigrams of the words of s as pg_trgm extracts them: each word padded with
+// two spaces before and one after.
+func trigrams(s string) map[string]bool {
+	t := map[string]bool{}
+	for _, p := range palabras(s) {
+		r := []rune("  " + p + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			t[string(r[i:i+3])] = true
+		}
+	}
+	return t
+}
+
+// trigramSimilarity is pg_trgm's similarity: the trigrams in common over the distinct trigrams of
+// both strings.
+func trigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	comunes := 0
+	for t := range ta {
+		if tb[t] {
+			comunes++
+		}
+	}
+	total := len(ta) + len(tb) - comunes
+	if total == 0 {
+		return 0
+	}
+	return float64(comunes) / float64(total)
+}
+
+// stopwords are the most frequent of PostgreSQL's Spanish stop words, ignored by the search.
+var stopwords = map[string]bool{
+	"de": true, "la": true, "que": true, "el": true, "en": true, "y": true, "a": true, "los": true,
+	"del": true, "se": true, "las": true, "por": true, "un": true, "para": true, "con": true,
+	"no": true, "una": true, "su": true, "al": true, "lo": true, "como": true, "o": true, "e": true,
+}
+
+// raiz approximates the Spanish stemmer by dropping the plural endings.
+func raiz(palabra string) string {
+	switch {
+	case len(palabra) > 4 && strings.HasSuffix(palabra, "es"):
+		return palabra[:len(palabra)-2]
+	case len(palabra) > 3 && strings.HasSuffix(palabra, "s"):
+		return palabra[:len(palabra)-1]
+	}
+	return palabra
+}
+
+// termino is a word of a full-text query.
+type termino struct {
+	raiz   string
+	negado bool
+}
+
+// parseTsQuery parses a query with the websearch_to_tsquery syntax: words that must all appear,
+// "-" before a word that must not, and "or" between alternatives. Quotes are ignored (phrases are
+// matched as their words). It returns the alternatives.
+func parseTsQuery(q string) [][]termino {
+	var alternativas [][]termino
+	var actual []termino
+	for _, campo := range strings.Fields(strings.ReplaceAll(q, `"`, " ")) {
+		if strings.EqualFold(campo, "or") {
+			if len(actual) > 0 {
+				alternativas = append(alternativas, actual)
+				actual = nil
+			}
+			continue
+		}
+		negado := strings.HasPrefix(campo, "-")
+		for _, p := range palabras(campo) {
+			if !stopwords[p] {
+				actual = append(actual, termino{raiz: raiz(p), negado: negado})
+			}
+		}
+	}
+	if len(actual) > 0 {
+		alternativas = append(alternativas, actual)
+	}
+	return alternativas
+}
+
+// tsDocument holds the words of each line of a busqueda column: nombre (weight A),
+// numeroResolucion (B) and lineaInvestigacion (C).
+func tsDocument(doc string) [][]string {
+	var lineas [][]string
+	for _, linea := range strings.Split(doc, "\n") {
+		lineas = append(lineas, palabras(linea))
+	}
+	return lineas
+}
+
+// tsWeights are PostgreSQL's default weights of A, B and C.
+var tsWeights = []float64{1.0, 0.4, 0.2}
+
+// tsWeight returns the weight of the best line of doc with a word starting with the root of t, or 0.
+func tsWeight(doc [][]string, t termino) float64 {
+	for i, linea := range doc {
+		for _, p := range linea {
+			if strings.HasPrefix(p, t.raiz) {
+				if i < len(tsWeights) {
+					return tsWeights[i]
+				}
+				return 0.1
+			}
+		}
+	}
+	return 0
+}
+
+// tsMatch reports whether doc (see tsDocument) matches the query q (see parseTsQuery).
+func tsMatch(doc, q string) bool {
+	d := tsDocument(doc)
+	for _, alternativa := range parseTsQuery(q) {
+		ok := true
+		for _, t := range alternativa {
+			if (tsWeight(d, t) > 0) == t.negado {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// tsRank is the relevance of doc for q: the mean weight of the words of q found in doc, scaled
+// like ts_rank.
+func tsRank(doc, q string) float64 {
+	d := tsDocument(doc)
+	var suma float64
+	n := 0
+	for _, alternativa := range parseTsQuery(q) {
+		for _, t := range alternativa {
+			if !t.negado {
+				suma += tsWeight(d, t)
+				n++
+			}
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return 0.1 * suma / float64(n)
+}
+
+// palabrasComunes counts the distinct words (their roots, without stop words) of the nombre and
+// lineaInvestigacion lines of two busqueda columns found in both, as the weight A and C lexemes of
+// tsvector_to_array(ts_filter(busqueda, '{a,c}')) intersected in PostgreSQL.
+func palabrasComunes(a, b string) int {
+	raices := func(doc string) map[string]bool {
+		r := map[string]bool{}
+		for i, linea := range tsDocument(doc) {
+			if i == 1 { // numeroResolucion (weight B)
+				continue
+			}
+			for _, p := range linea {
+				if !stopwords[p] {
+					r[raiz(p)] = true
+				}
+			}
+		}
+		return r
+	}
+	ra, rb := raices(a), raices(b)
+	n := 0
+	for p := range ra {
+		if rb[p] {
+			n++
+		}
+	}
+	return n
+}
+
+// collators holds Spanish collators for the es_icu collation; a collator is not safe for
+// concurrent use.
+var collators = sync.Pool{New: func() any { return collate.New(language.Spanish) }}
+
+// compareSpanish orders text as the es_icu collation of PostgreSQL.
+func compareSpanish(a, b string) int {
+	c := collators.Get().(*collate.Collator)
+	defer collators.Put(c)
+	return c.CompareString(a, b)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/controllers"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+)
+
+// Credentials of the administrator created in demo mode, unless DEMO_ADMIN_EMAIL and
+// DEMO_ADMIN_PASSWORD override them.
+const (
+	defaultDemoAdminEmail    = "admin@demo.local"
+	defaultDemoAdminPassword = "demo"
+)
+
+// applyDemoDefaults fills in the configuration demo mode needs to run without external services:
+// a random JWT_SECRET (tokens do not outlive the process, like the data), uploads to a temporary
+// directory instead of Google Drive, emailed links to http://localhost and, without CAPTCHA_SECRET,
+// captcha verification disabled.
+// Variables that are set are kept.
+func applyDemoDefaults() error {
+	if os.Getenv("CAPTCHA_SECRET") == "" && os.Getenv("CAPTCHA_DISABLED") == "" {
+		os.Setenv("CAPTCHA_DISABLED", "true")
+	}
+	if os.Getenv("JWT_SECRET") == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return err
+		}
+		os.Setenv("JWT_SECRET", hex.EncodeToString(secret))
+	}
+	if os.Getenv("PUBLIC_BASE_URL") == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "3000"
+		}
+		os.Setenv("PUBLIC_BASE_URL", "http://localhost:"+port)
+	}
+	if os.Getenv("STORAGE_BACKEND") == "" {
+		os.Setenv("STORAGE_BACKEND", "local")
+		if os.Getenv("LOCAL_STORAGE_DIR") == "" {
+			dir, err := os.MkdirTemp("", "apigrupos-demo-")
+			if err != nil {
+				return err
+			}
+			os.Setenv("LOCAL_STORAGE_DIR", dir)
+		}
+	}
+	return nil
+}
+
+// prepareDemo creates the schema of the in-memory database, loads the sample data and creates an
+// administrator to log in with.
+func prepareDemo(db *sql.DB) error {
+	if err := database.InitSchema(db); err != nil {
+		return err
+	}
+	if err := database.Seed(db); err != nil {
+		return err
+	}
+
+	email := os.Getenv("DEMO_ADMIN_EMAIL")
+	if email == "" {
+		email = defaultDemoAdminEmail
+	}
+	password := os.Getenv("DEMO_ADMIN_PASSWORD")
+	if password == "" {
+		password = defaultDemoAdminPassword
+	}
+	ctx := context.Background()
+	admin := &models.Usuario{Email: email, Password: password}
+	if err := repository.CreateUsuario(ctx, db, admin); err != nil {
+		return fmt.Errorf("failed to create the demo administrator: %w", err)
+	}
+	if err := repository.SetUsuarioRol(ctx, db, admin.ID, models.RolAdmin); err != nil {
+		return fmt.Errorf("failed to create the demo administrator: %w", err)
+	}
+	controllers.DirectorioCache().Invalidate(ctx)
+	slog.Warn("Demo mode: the data lives in memory and is lost on exit", "admin_email", email, "uploads", os.Getenv("LOCAL_STORAGE_DIR"))
+	return nil
+}
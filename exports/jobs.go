@@ -0,0 +1,175 @@
+// Package exports generates long-running exports in the background and keeps the resulting
+// files in the storage layer.
+package exports
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/reports"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// tiempoRegistro bounds the recording of a failed export, which must happen even once ctx is
+// canceled.
+const tiempoRegistro = 5 * time.Second
+
+// batchSize is the number of groups loaded per query; progress is saved after each batch.
+const batchSize = 200
+
+// StaleAfter is how long a job may go without saving progress before it is considered interrupted
+// (e.g. the instance running it was shut down).
+const StaleAfter = 15 * time.Minute
+
+// Run generates the export described by job, stores the file in backend and marks the job as
+// completed, or as failed if anything goes wrong, including ctx being canceled by shutdown. It is
+// meant to run in its own goroutine.
+func Run(ctx context.Context, db *sql.DB, job *models.ExportJob, backend storage.Backend) {
+	if err := run(ctx, db, job, backend); err != nil {
+		logging.FromContext(ctx).Error("Export failed", "id", job.ID, "tipo", job.Parametros.Tipo, "error", err)
+		recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tiempoRegistro)
+		defer cancel()
+		if err := repository.FailExportJob(recordCtx, db, job.ID, err.Error()); err != nil {
+			logging.FromContext(ctx).Error("Error marking export as failed", "id", job.ID, "error", err)
+		}
+	}
+}
+
+func run(ctx context.Context, db *sql.DB, job *models.ExportJob, backend storage.Backend) error {
+	p := job.Parametros
+	fecha := time.Now().Format("20060102-150405")
+	var key, nombre string
+	var err error
+	switch p.Tipo {
+	case models.ExportGruposDetalles:
+		nombre = fmt.Sprintf("grupos_%s.json", fecha)
+		key, err = putStream(ctx, backend, nombre, func(w io.Writer) error {
+			return writeGruposJSON(ctx, db, job.ID, p, w)
+		})
+	case models.ExportReporteGrupos:
+		// The PDF library keeps the whole document in memory, so the groups are loaded first
+		var grupos []models.GrupoWithInvestigadores
+		grupos, err = loadGrupos(ctx, db, job.ID, p)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := reports.WriteGruposPDF(&buf, tituloReporte(p.Anios), grupos); err != nil {
+			return fmt.Errorf("error generating PDF report: %w", err)
+		}
+		nombre = fmt.Sprintf("reporte_grupos_%s.pdf", fecha)
+		key, err = backend.Put(ctx, nombre, &buf)
+	default:
+		return fmt.Errorf("tipo de exportación desconocido: %q", p.Tipo)
+	}
+	if err != nil {
+		return fmt.Errorf("error storing export file: %w", err)
+	}
+	return repository.CompleteExportJob(ctx, db, job.ID, storage.FormatRef(backend.Name(), key), nombre)
+}
+
+// putStream stores in backend the file that write produces, piping it to Put as it is written
+// instead of buffering it. An error from write is returned in preference to the one it causes in Put.
+func putStream(ctx context.Context, backend storage.Backend, nombre string, write func(w io.Writer) error) (string, error) {
+	pr, pw := io.Pipe()
+	writeErr := make(chan error, 1)
+	go func() {
+		bw := bufio.NewWriter(pw)
+		err := write(bw)
+		if err == nil {
+			err = bw.Flush()
+		}
+		pw.CloseWithError(err) // A nil error ends the file with io.EOF
+		writeErr <- err
+	}()
+	key, err := backend.Put(ctx, nombre, pr)
+	pr.CloseWithError(errors.New("export upload finished")) // Unblocks write if Put stopped reading early
+	if werr := <-writeErr; werr != nil {
+		return "", werr
+	}
+	return key, err
+}
+
+// writeGruposJSON writes every group matching the export filters to w as a JSON array, one group
+// at a time as the batches are loaded, saving progress after each batch.
+func writeGruposJSON(ctx context.Context, db *sql.DB, id int, p models.ParametrosExport, w io.Writer) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	n := 0
+	err := eachGrupoBatch(ctx, db, id, p, func(lote []models.GrupoWithInvestigadores) error {
+		for i := range lote {
+			utils.RewriteLinks(&lote[i])
+			if n > 0 {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			n++
+			if err := enc.Encode(lote[i]); err != nil {
+				return fmt.Errorf("error encoding export: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]\n")
+	return err
+}
+
+// loadGrupos loads every group matching the export filters, in batches, saving progress after each.
+func loadGrupos(ctx context.Context, db *sql.DB, id int, p models.ParametrosExport) ([]models.GrupoWithInvestigadores, error) {
+	grupos := []models.GrupoWithInvestigadores{}
+	err := eachGrupoBatch(ctx, db, id, p, func(lote []models.GrupoWithInvestigadores) error {
+		grupos = append(grupos, lote...)
+		return nil
+	})
+	return grupos, err
+}
+
+// eachGrupoBatch calls fn with each batch of the groups matching the export filters and saves the
+// job's progress after it.
+func eachGrupoBatch(ctx context.Context, db *sql.DB, id int, p models.ParametrosExport, fn func(lote []models.GrupoWithInvestigadores) error) error {
+	procesados := 0
+	return repository.EachBatch(ctx, batchSize, func(limit, offset int) ([]models.GrupoWithInvestigadores, int, error) {
+		grupos, total, err := repository.SearchGrupos(ctx, db, "", "", "", p.Anios, nil, nil, nil, "", "", p.IncludeDeleted, limit, offset)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error loading groups: %w", err)
+		}
+		return grupos, total, nil
+	}, func(lote []models.GrupoWithInvestigadores, total int) error {
+		if err := fn(lote); err != nil {
+			return err
+		}
+		procesados += len(lote)
+		return repository.UpdateExportProgress(ctx, db, id, procesados, total)
+	})
+}
+
+// tituloReporte builds the title of a multi-group report.
+func tituloReporte(anios []int) string {
+	if len(anios) == 0 {
+		return "Grupos de investigación"
+	}
+	partes := make([]string, len(anios))
+	for i, anio := range anios {
+		partes[i] = strconv.Itoa(anio)
+	}
+	return "Grupos de investigación registrados en " + strings.Join(partes, ", ")
+}
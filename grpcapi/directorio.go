@@ -0,0 +1,140 @@
+package grpcapi
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/proto/directoriopb"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// directorioServer implements directoriopb.DirectorioServer on top of the repository, with the
+// same queries as the REST endpoints.
+type directorioServer struct {
+	directoriopb.UnimplementedDirectorioServer
+	db *sql.DB
+}
+
+func (s *directorioServer) ListGrupos(ctx context.Context, req *directoriopb.ListGruposRequest) (*directoriopb.ListGruposResponse, error) {
+	page, limit := utils.NormalizePagination(int(req.GetPage()), int(req.GetLimit()))
+	offset := (page - 1) * limit
+
+	var grupos []models.GrupoWithInvestigadores
+	var total int
+	var err error
+	if req.GetQ() != "" {
+		grupos, total, err = repository.SearchGrupos(ctx, s.db, req.GetQ(), "", "", nil, nil, nil, nil, "", "", false, limit, offset)
+	} else {
+		grupos, total, err = repository.GetAllGruposWithDetails(ctx, s.db, false, limit, offset)
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Error listing groups", err)
+	}
+
+	resp := &directoriopb.ListGruposResponse{Total: int32(total)}
+	for _, g := range grupos {
+		resp.Grupos = append(resp.Grupos, grupoConIntegrantesPB(g))
+	}
+	return resp, nil
+}
+
+func (s *directorioServer) GetGrupo(ctx context.Context, req *directoriopb.GetGrupoRequest) (*directoriopb.GrupoConIntegrantes, error) {
+	g, err := repository.GetGrupoDetails(ctx, s.db, int(req.GetIdGrupo()))
+	if err != nil {
+		return nil, internalError(ctx, "Error getting group", err)
+	}
+	if g == nil || !models.EsGrupoAprobado(g.Grupo.Estado) {
+		return nil, status.Error(codes.NotFound, "Grupo no encontrado")
+	}
+	return grupoConIntegrantesPB(*g), nil
+}
+
+func (s *directorioServer) ListInvestigadores(ctx context.Context, req *directoriopb.ListInvestigadoresRequest) (*directoriopb.ListInvestigadoresResponse, error) {
+	page, limit := utils.NormalizePagination(int(req.GetPage()), int(req.GetLimit()))
+	offset := (page - 1) * limit
+
+	var investigadores []models.Investigador
+	var total int
+	var err error
+	if req.GetNombre() != "" {
+		investigadores, total, err = repository.SearchInvestigadores(ctx, s.db, req.GetNombre(), nil, "", "", limit, offset)
+	} else {
+		investigadores, total, err = repository.GetAllInvestigadores(ctx, s.db, limit, offset)
+	}
+	if err != nil {
+		return nil, internalError(ctx, "Error listing investigators", err)
+	}
+
+	resp := &directoriopb.ListInvestigadoresResponse{Total: int32(total)}
+	for _, inv := range investigadores {
+		resp.Investigadores = append(resp.Investigadores, investigadorPB(inv))
+	}
+	return resp, nil
+}
+
+func (s *directorioServer) GetInvestigador(ctx context.Context, req *directoriopb.GetInvestigadorRequest) (*directoriopb.Investigador, error) {
+	inv, err := repository.GetInvestigadorByID(ctx, s.db, int(req.GetIdInvestigador()))
+	if err != nil {
+		return nil, internalError(ctx, "Error getting investigator", err)
+	}
+	if inv == nil {
+		return nil, status.Error(codes.NotFound, "Investigador no encontrado")
+	}
+	return investigadorPB(*inv), nil
+}
+
+// internalError logs err and returns a generic INTERNAL status, without leaking database details.
+func internalError(ctx context.Context, msg string, err error) error {
+	logging.FromContext(ctx).Error(msg, "error", err)
+	return status.Error(codes.Internal, "Internal server error")
+}
+
+func grupoConIntegrantesPB(g models.GrupoWithInvestigadores) *directoriopb.GrupoConIntegrantes {
+	pb := &directoriopb.GrupoConIntegrantes{Grupo: grupoPB(g.Grupo)}
+	for _, inv := range g.Investigadores {
+		pb.Integrantes = append(pb.Integrantes, &directoriopb.Integrante{
+			IdInvestigador: int32(inv.ID),
+			Nombre:         inv.Nombre,
+			Apellido:       inv.Apellido,
+			Rol:            inv.Rol,
+		})
+	}
+	return pb
+}
+
+func grupoPB(g models.Grupo) *directoriopb.Grupo {
+	pb := &directoriopb.Grupo{
+		IdGrupo:            int32(g.ID),
+		Nombre:             g.Nombre,
+		NumeroResolucion:   g.NumeroResolucion,
+		LineaInvestigacion: g.LineaInvestigacion,
+		TipoInvestigacion:  g.TipoInvestigacion,
+		FechaRegistro:      g.FechaRegistro.Format("2006-01-02"),
+		Estado:             g.Estado,
+		CreatedAt:          timestamppb.New(g.CreatedAt),
+		UpdatedAt:          timestamppb.New(g.UpdatedAt),
+	}
+	if g.IDGrupoPadre != nil {
+		padre := int32(*g.IDGrupoPadre)
+		pb.IdGrupoPadre = &padre
+	}
+	return pb
+}
+
+func investigadorPB(inv models.Investigador) *directoriopb.Investigador {
+	return &directoriopb.Investigador{
+		IdInvestigador:  int32(inv.ID),
+		Nombre:          inv.Nombre,
+		Apellido:        inv.Apellido,
+		Email:           inv.Email,
+		EmailVerificado: inv.EmailVerificado,
+		CreatedAt:       timestamppb.New(inv.CreatedAt),
+		UpdatedAt:       timestamppb.New(inv.UpdatedAt),
+	}
+}
@@ -0,0 +1,101 @@
+// Package i18n localizes the messages of API errors. Every message has a stable code and a text
+// per language (see mensajes); the language of a request is negotiated from its Accept-Language
+// header, Spanish by default, and a message without a text in the requested language falls back
+// to English.
+//
+// Handlers keep passing the text of the message (in either language, or one of its former
+// wordings): Codigo finds its code, so existing messages are localized without rewriting every
+// call site. Messages not in the catalog are sent as written.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported languages.
+const (
+	ES = "es"
+	EN = "en"
+
+	Default = ES // Language of requests without a supported Accept-Language
+)
+
+// Texto is a message in each language. Texts may carry fmt verbs for the message's arguments.
+type Texto struct {
+	ES string
+	EN string
+}
+
+// En returns the text in lang, or in English if it has none.
+func (t Texto) En(lang string) string {
+	if lang == ES && t.ES != "" {
+		return t.ES
+	}
+	return t.EN
+}
+
+// porTexto maps the text of every message, in both languages and in its former wordings, to its
+// code.
+var porTexto = func() map[string]string {
+	m := make(map[string]string, 2*len(mensajes)+len(alias))
+	for code, t := range mensajes {
+		m[t.ES] = code
+		m[t.EN] = code
+	}
+	for texto, code := range alias {
+		m[texto] = code
+	}
+	return m
+}()
+
+// Codigo returns the code of the message with the given text, or "" if it is not in the catalog.
+func Codigo(texto string) string {
+	return porTexto[texto]
+}
+
+// Mensaje returns the message code in lang, formatted with args, and false if code is not in the
+// catalog.
+func Mensaje(lang, code string, args ...interface{}) (string, bool) {
+	t, ok := mensajes[code]
+	if !ok {
+		return "", false
+	}
+	if len(args) == 0 {
+		return t.En(lang), true
+	}
+	return fmt.Sprintf(t.En(lang), args...), true
+}
+
+// Negociar returns the supported language the client prefers according to an Accept-Language
+// header ("en-US,en;q=0.9,es;q=0.8"), or Default if it accepts none of them.
+func Negociar(acceptLanguage string) string {
+	type opcion struct {
+		lang string
+		q    float64
+	}
+	var opciones []opcion
+	for _, parte := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(parte), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if (base == ES || base == EN) && q > 0 {
+			opciones = append(opciones, opcion{base, q})
+		}
+	}
+	if len(opciones) == 0 {
+		return Default
+	}
+	// Stable, so equal weights keep the client's order
+	sort.SliceStable(opciones, func(i, j int) bool { return opciones[i].q > opciones[j].q })
+	return opciones[0].lang
+}
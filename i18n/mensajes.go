@@ -0,0 +1,207 @@
+package i18n
+
+// mensajes is the catalog of error messages by code. Codes are stable: clients may translate
+// them on their own instead of showing the message.
+var mensajes = map[string]Texto{
+	// Generic errors
+	"error_interno":           {"Error interno del servidor", "Internal server error"},
+	"no_autorizado":           {"No autorizado", "Unauthorized"},
+	"requiere_admin":          {"Se requiere el rol de administrador", "Admin role required"},
+	"demasiadas_peticiones":   {"Demasiadas peticiones", "Too many requests"},
+	"cuerpo_demasiado_grande": {"El cuerpo de la petición es demasiado grande (límite: %d bytes)", "Request body too large (limit: %d bytes)"},
+	"cuerpo_invalido":         {"El cuerpo de la petición no es válido", "Invalid request body"},
+	"datos_invalidos":         {"Datos inválidos", "Invalid data"},
+	"datos_duplicados":        {"Datos duplicados", "Duplicate data"},
+	"recurso_no_encontrado":   {"Recurso no encontrado: %s", "Resource not found: %s"},
+	"metodo_no_permitido":     {"Método %s no permitido; permitidos: %s", "Method %s not allowed; allowed: %s"},
+	"archivo_requerido":       {"Falta el campo de archivo requerido: archivo", "Missing required file field: archivo"},
+	"error_guardando_archivo": {"Error interno del servidor al guardar el archivo", "Internal server error saving the file"},
+	"almacenamiento_no_disponible": {
+		"El almacenamiento de archivos no está disponible", "File storage is not available",
+	},
+	"no_soportado_sqlite": {"No disponible con el backend SQLite", "Not available with the SQLite backend"},
+
+	// Authentication
+	"credenciales_invalidas":  {"Email o contraseña incorrectos", "Invalid email or password"},
+	"falta_authorization":     {"Se requiere la cabecera Authorization", "Authorization header required"},
+	"authorization_invalida":  {"La cabecera Authorization debe tener la forma Bearer {token}", "Authorization header format must be Bearer {token}"},
+	"token_malformado":        {"Token mal formado", "Malformed token"},
+	"token_expirado":          {"El token ha expirado o aún no es válido", "Token is either expired or not active yet"},
+	"token_firma_invalida":    {"La firma del token no es válida", "Invalid token signature"},
+	"auth_no_configurada":     {"La autenticación no está configurada", "Authentication is not configured"},
+	"usuario_duplicado":       {"Ya existe un usuario con ese email", "User with this email already exists"},
+	"captcha_invalido":        {"Captcha inválido", "Invalid captcha"},
+	"captcha_no_configurado":  {"La verificación del captcha no está configurada", "Captcha verification is not configured"},
+	"enlaces_no_configurados": {"Los enlaces enviados por email no están configurados", "Emailed links are not configured"},
+
+	// Invalid identifiers in the path
+	"id_grupo_invalido":        {"ID de grupo inválido", "Invalid group ID"},
+	"id_investigador_invalido": {"ID de investigador inválido", "Invalid investigator ID"},
+	"id_convocatoria_invalido": {"ID de convocatoria inválido", "Invalid convocatoria ID"},
+	"id_proyecto_invalido":     {"ID de proyecto inválido", "Invalid proyecto ID"},
+	"id_postulacion_invalido":  {"ID de postulación inválido", "Invalid postulación ID"},
+	"id_facultad_invalido":     {"ID de facultad inválido", "Invalid facultad ID"},
+	"id_escuela_invalido":      {"ID de escuela inválido", "Invalid escuela ID"},
+	"id_solicitud_invalido":    {"ID de solicitud inválido", "Invalid solicitud ID"},
+	"id_publicacion_invalido":  {"ID de publicación inválido", "Invalid publicación ID"},
+	"id_detalle_invalido":      {"ID de membresía inválido", "Invalid detail ID"},
+	"id_renovacion_invalido":   {"ID de renovación inválido", "Invalid renovación ID"},
+	"id_busqueda_invalido":     {"ID de búsqueda inválido", "Invalid búsqueda ID"},
+	"id_archivo_invalido":      {"ID de archivo inválido", "Invalid archivo ID"},
+	"id_resolucion_invalido":   {"ID de resolución inválido", "Invalid resolución ID"},
+	"id_comentario_invalido":   {"ID de comentario inválido", "Invalid comentario ID"},
+	"id_documento_invalido":    {"ID de documento inválido", "Invalid documento ID"},
+	"id_backup_invalido":       {"ID de copia de seguridad inválido", "Invalid backup ID"},
+	"id_exportacion_invalido":  {"ID de exportación inválido", "Invalid export ID"},
+
+	// Resources not found
+	"grupo_no_encontrado":        {"Grupo no encontrado", "Group not found"},
+	"investigador_no_encontrado": {"Investigador no encontrado", "Investigator not found"},
+	"convocatoria_no_encontrada": {"Convocatoria no encontrada", "Convocatoria not found"},
+	"proyecto_no_encontrado":     {"Proyecto no encontrado", "Proyecto not found"},
+	"postulacion_no_encontrada":  {"Postulación no encontrada", "Postulación not found"},
+	"facultad_no_encontrada":     {"Facultad no encontrada", "Facultad not found"},
+	"escuela_no_encontrada":      {"Escuela no encontrada", "Escuela not found"},
+	"solicitud_no_encontrada":    {"Solicitud no encontrada", "Solicitud not found"},
+	"publicacion_no_encontrada":  {"Publicación no encontrada", "Publicación not found"},
+	"detalle_no_encontrado":      {"Membresía no encontrada", "Detail not found"},
+	"resolucion_no_encontrada":   {"Resolución no encontrada", "Resolución not found"},
+	"comentario_no_encontrado":   {"Comentario no encontrado", "Comentario not found"},
+	"archivo_no_encontrado":      {"Archivo no encontrado", "Archivo not found"},
+	"documento_no_encontrado":    {"Documento no encontrado", "Documento not found"},
+	"favorito_no_encontrado":     {"Favorito no encontrado", "Favorito not found"},
+	"backup_no_encontrado":       {"Copia de seguridad no encontrada", "Backup not found"},
+	"exportacion_no_encontrada":  {"Exportación no encontrada", "Export not found"},
+	"esquema_no_encontrado":      {"Esquema no encontrado", "Schema not found"},
+
+	// Invalid query parameters
+	"parametro_tipo_invalido":    {"Parámetro tipo inválido", "Invalid tipo parameter"},
+	"parametro_estado_invalido":  {"Parámetro estado inválido", "Invalid estado parameter"},
+	"parametro_limit_invalido":   {"Parámetro limit inválido", "Invalid limit parameter"},
+	"parametro_since_invalido":   {"Parámetro since inválido", "Invalid since parameter"},
+	"parametro_entidad_invalido": {"Parámetro entidad inválido", "Invalid entidad parameter"},
+	"parametro_sort_invalido":    {"Parámetro sort inválido", "Invalid sort parameter"},
+	"parametro_idgrupo_invalido": {
+		"Parámetro idGrupo inválido", "Invalid idGrupo parameter",
+	},
+	"parametro_intervalo_invalido": {"Parámetro intervalo inválido", "Invalid intervalo parameter"},
+	"parametro_desde_invalido":     {"Parámetro desde inválido", "Invalid desde parameter"},
+	"parametro_hasta_invalido":     {"Parámetro hasta inválido", "Invalid hasta parameter"},
+	"parametro_idusuario_invalido": {"Parámetro idUsuario inválido", "Invalid idUsuario parameter"},
+	"parametro_top_invalido":       {"Parámetro top inválido", "Invalid top parameter"},
+	"desde_posterior_a_hasta":      {"desde debe ser anterior a hasta", "desde must be before hasta"},
+	"ventana_uso_demasiado_larga": {
+		"La ventana es demasiado larga: hasta 31 días por hora o 366 días por día",
+		"The usage window is too long: up to 31 days by hour or 366 days by day",
+	},
+	"parametro_format_export_completo_invalido": {
+		"Parámetro format inválido (json o ndjson)", "Invalid format parameter (json or ndjson)",
+	},
+	"include_deleted_requiere_admin":   {"includeDeleted requiere el rol de administrador", "includeDeleted requires admin role"},
+	"verificar_archivo_requiere_token": {"verificarArchivo requiere autenticación", "verificarArchivo requires authentication"},
+
+	// Conflicts and business rules
+	"solicitud_ya_moderada":   {"La solicitud ya fue moderada", "Solicitud already moderated"},
+	"postulacion_ya_aprobada": {"La postulación ya fue aprobada", "The postulación was already approved"},
+	"no_es_integrante":        {"El investigador no es integrante del grupo", "The investigator is not a member of the group"},
+	"ya_es_integrante":        {"El investigador ya es integrante del grupo", "The investigator is already a member of the group"},
+	"coordinador_existente": {
+		"El grupo ya tiene un coordinador; use POST /grupos/{id}/coordinador para reemplazarlo",
+		"The group already has a coordinator; use POST /grupos/{id}/coordinador to replace them",
+	},
+	"convocatoria_cerrada": {"La convocatoria no está abierta a postulaciones", "The convocatoria is not open to applications"},
+	"grupo_no_participa":   {"El grupo no participa en la convocatoria", "The group does not take part in the convocatoria"},
+	"grupo_no_eliminado":   {"El grupo no está eliminado", "The group is not deleted"},
+	"investigador_no_eliminado": {
+		"El investigador no está eliminado", "The investigator is not deleted",
+	},
+	"grupos_similares": {
+		"Existen grupos similares; envíe forzar=true para crearlo de todas formas",
+		"Similar groups exist; send forzar=true to create it anyway",
+	},
+	"investigador_con_grupos": {
+		"El investigador aún pertenece a grupos; use ?force=true para retirarlo de ellos y eliminarlo",
+		"The investigator still belongs to groups; use ?force=true to remove them from those groups and delete them",
+	},
+	"enlace_invalido":                  {"Enlace inválido o expirado", "Invalid or expired link"},
+	"enlace_verificacion_invalido":     {"Enlace de verificación inválido o expirado", "Invalid or expired verification link"},
+	"enlace_restablecimiento_invalido": {"Enlace para restablecer la contraseña inválido o expirado", "Invalid or expired password reset link"},
+	"email_ya_verificado":              {"El email ya está verificado", "The email is already verified"},
+	"investigador_sin_email":           {"El investigador no tiene email", "The investigator has no email"},
+	"cti_vitae_no_vinculado":           {"El investigador no está vinculado a CTI Vitae", "The investigator is not linked to CTI Vitae"},
+	"cti_vitae_no_configurado":         {"La integración con CTI Vitae no está configurada", "The CTI Vitae integration is not configured"},
+	"cti_vitae_no_existe":              {"El investigador no existe en CTI Vitae", "The investigator does not exist in CTI Vitae"},
+	"cti_vitae_no_disponible":          {"No se pudo consultar CTI Vitae", "CTI Vitae could not be queried"},
+	"bibtex_sin_entradas":              {"El archivo no contiene entradas BibTeX", "The file has no BibTeX entries"},
+	"bibtex_no_legible":                {"Error leyendo el archivo BibTeX", "The BibTeX file could not be read"},
+	"import_grupo_no_existe":           {"idGrupo no corresponde a un grupo existente", "idGrupo is not an existing group"},
+	"import_investigador_no_existe":    {"idInvestigador no corresponde a un investigador existente", "idInvestigador is not an existing investigator"},
+	"export_completo_en_curso":         {"Ya hay una exportación completa en curso", "A full export is already running"},
+	"demasiados_export_completos": {
+		"Demasiadas exportaciones completas; inténtelo más tarde", "Too many full exports; try again later",
+	},
+	"cambios_expirados": {
+		"since es anterior a la retención del feed de cambios; vuelva a descargar los datos completos",
+		"since is older than the retention of the change feed; download the full data again",
+	},
+
+	// Field errors (utils.FieldError) built by the handlers
+	"email_duplicado":              {"El email ya está registrado para otro investigador", "The email is already registered for another investigator"},
+	"codigo_matricula_duplicado":   {"El código de matrícula ya está registrado para otro investigador", "The student code is already registered for another investigator"},
+	"resolucion_duplicada":         {"El grupo ya tiene una resolución con ese número", "The group already has a resolution with that number"},
+	"archivo_no_pdf":               {"La resolución debe adjuntarse en PDF", "The resolution must be attached as a PDF"},
+	"busqueda_duplicada":           {"Ya tiene una búsqueda guardada con ese nombre", "You already have a saved search with that name"},
+	"busqueda_sin_filtros":         {"La búsqueda debe tener al menos un filtro", "The search must have at least one filter"},
+	"doi_duplicado":                {"Ya existe una publicación con el mismo DOI", "A publication with the same DOI already exists"},
+	"doi_invalido":                 {"El DOI debe tener la forma 10.xxxx/sufijo", "The DOI must look like 10.xxxx/suffix"},
+	"cti_vitae_duplicado":          {"El registro de CTI Vitae ya está vinculado a otro investigador", "The CTI Vitae record is already linked to another investigator"},
+	"archivo_bibtex_requerido":     {"Adjunte el archivo BibTeX", "Attach the BibTeX file"},
+	"codigo_proyecto_duplicado":    {"Ya existe un proyecto con el mismo código", "A project with the same code already exists"},
+	"estudiante_sin_escuela":       {"Los estudiantes deben tener escuela profesional", "Students must have a school"},
+	"estudiante_sin_matricula":     {"Los estudiantes deben tener código de matrícula", "Students must have a student code"},
+	"matricula_solo_estudiantes":   {"Solo los integrantes de tipo estudiante tienen código de matrícula", "Only student members have a student code"},
+	"email_sin_registrar":          {"Registre un email antes de solicitar su verificación", "Register an email before requesting its verification"},
+	"email_formato_invalido":       {"El email no tiene un formato válido (usuario@dominio)", "The email is not valid (user@domain)"},
+	"comentario_rechazo_solicitud": {"comentario es obligatorio para rechazar una solicitud", "comentario is required to reject a request"},
+	"comentario_rechazo_grupo":     {"comentario es obligatorio para rechazar un grupo", "comentario is required to reject a group"},
+	"escuela_inexistente":          {"La escuela profesional %d no existe", "School %d does not exist"},
+	"escuela_de_otra_facultad": {
+		"La escuela profesional %d no pertenece a la facultad del investigador",
+		"School %d does not belong to the investigator's facultad",
+	},
+
+	// Field errors of the validation package, by rule; the first argument is the field
+	"campo_obligatorio":           {"%s es obligatorio", "%s is required"},
+	"campo_email_invalido":        {"%s no tiene un formato válido (usuario@dominio)", "%s is not a valid email (user@domain)"},
+	"campo_valor_no_permitido":    {"%s debe ser uno de: %s", "%s must be one of: %s"},
+	"campo_fecha_invalida":        {"%s debe tener el formato AAAA-MM-DD", "%s must have the format YYYY-MM-DD"},
+	"campo_entero_invalido":       {"%s debe ser un número entero", "%s must be an integer"},
+	"campo_anio_fuera_de_rango":   {"%s debe estar entre 1900 y %d", "%s must be between 1900 and %d"},
+	"campo_periodo_invalido":      {"%s no puede ser anterior a la fecha de inicio", "%s cannot be before the start date"},
+	"campo_demasiado_largo":       {"%s admite hasta %s caracteres", "%s allows up to %s characters"},
+	"campo_demasiados_elementos":  {"%s admite hasta %s elementos", "%s allows up to %s items"},
+	"campo_maximo":                {"%s debe ser como máximo %s", "%s must be at most %s"},
+	"campo_demasiado_corto":       {"%s requiere al menos %s caracteres", "%s requires at least %s characters"},
+	"campo_muy_pocos_elementos":   {"%s requiere al menos %s elementos", "%s requires at least %s items"},
+	"campo_minimo":                {"%s debe ser como mínimo %s", "%s must be at least %s"},
+	"campo_invalido":              {"%s no es válido", "%s is not valid"},
+	"campo_tipo_archivo_invalido": {"El contenido de %s no está permitido o no coincide con su extensión", "The content of %s is not allowed or does not match its extension"},
+}
+
+// alias maps other wordings used by the handlers to the code of their message.
+var alias = map[string]string{
+	"Invalid request body format":                      "cuerpo_invalido",
+	"Invalid grupo ID":                                 "id_grupo_invalido",
+	"ID de investigador inválido":                      "id_investigador_invalido",
+	"Grupo not found":                                  "grupo_no_encontrado",
+	"Group not found":                                  "grupo_no_encontrado",
+	"Investigador not found":                           "investigador_no_encontrado",
+	"Invalid email or password":                        "credenciales_invalidas",
+	"Invalid token (general validation failed)":        "token_malformado",
+	"Couldn't handle this token: validation error":     "token_malformado",
+	"Error interno del servidor procesando el archivo": "error_guardando_archivo",
+	"Error interno del servidor guardando archivo":     "error_guardando_archivo",
+	"El almacenamiento del archivo no está disponible": "almacenamiento_no_disponible",
+	"Internal server error generating report":          "error_interno",
+	"Internal server error generating token":           "error_interno",
+}
@@ -0,0 +1,184 @@
+// Package jobs runs scheduled background jobs (file cleanup, token purging, audit log retention,
+// nightly statistics) inside the API, on a cron schedule.
+//
+// Every instance runs the scheduler, but each run of a job happens once: the next run of every job
+// is kept in the job table, and an instance runs a job only after claiming its due run there for a
+// lease. If the instance stops mid-run, another one takes the run over once the lease expires.
+// JOBS_ENABLED=false turns the scheduler off in an instance; JOB_<NOMBRE>_SCHEDULE (e.g.
+// JOB_ESTADISTICAS_SCHEDULE="0 3 * * *") changes the schedule of a job, and "off" disables it.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/background"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	intervalo      = time.Minute      // How often the due jobs are checked (the resolution of the schedules)
+	lease          = 30 * time.Minute // Longest run of a job; past it the run is cancelled and may be taken over
+	tiempoRegistro = 10 * time.Second // To record the result of a run, even during shutdown
+)
+
+// Job is a scheduled job. Run returns a summary of what it did, shown in GET /admin/jobs.
+type Job struct {
+	Nombre       string
+	Descripcion  string
+	Programacion string // Default schedule: a cron expression of 5 fields, in the time zone of the process
+	Run          func(ctx context.Context, db *sql.DB) (string, error)
+}
+
+// programado is a job with its schedule, nil when disabled.
+type programado struct {
+	Job
+	expr     string
+	schedule cron.Schedule
+}
+
+// instancia identifies this process in the job table.
+var instancia = func() string {
+	host, _ := os.Hostname()
+	sufijo := make([]byte, 3)
+	rand.Read(sufijo)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(sufijo))
+}()
+
+// enabled reports whether JOBS_ENABLED allows the scheduler to run (the default).
+func enabled() bool {
+	v, err := strconv.ParseBool(os.Getenv("JOBS_ENABLED"))
+	return err != nil || v
+}
+
+// programar reads the schedule of each job, overridden by JOB_<NOMBRE>_SCHEDULE.
+func programar() ([]programado, error) {
+	programados := make([]programado, 0, len(jobs))
+	for _, j := range jobs {
+		p := programado{Job: j, expr: j.Programacion}
+		variable := "JOB_" + strings.ToUpper(strings.ReplaceAll(j.Nombre, "-", "_")) + "_SCHEDULE"
+		if v := strings.TrimSpace(os.Getenv(variable)); v != "" {
+			p.expr = v
+		}
+		if strings.EqualFold(p.expr, "off") {
+			p.expr = ""
+		} else {
+			schedule, err := cron.ParseStandard(p.expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %w", variable, p.expr, err)
+			}
+			p.schedule = schedule
+		}
+		programados = append(programados, p)
+	}
+	return programados, nil
+}
+
+// Start runs the scheduler in the background until ctx is done. An invalid schedule is a
+// configuration error: it is logged and no job runs in this instance.
+func Start(ctx context.Context, db *sql.DB) {
+	log := logging.FromContext(ctx)
+	if !enabled() {
+		log.Info("Scheduled jobs disabled in this instance (JOBS_ENABLED=false)")
+		return
+	}
+	programados, err := programar()
+	if err != nil {
+		log.Error("Scheduled jobs not started", "error", err)
+		return
+	}
+	activos := programados[:0]
+	for _, p := range programados {
+		if p.schedule == nil {
+			continue
+		}
+		if err := repository.RegisterJob(ctx, db, p.Nombre, p.schedule.Next(time.Now()).UTC()); err != nil {
+			log.Error("Scheduled job not started", "job", p.Nombre, "error", err)
+			continue
+		}
+		activos = append(activos, p)
+	}
+	log.Info("Scheduled jobs started", "jobs", len(activos), "instancia", instancia)
+
+	background.Go(func() {
+		ticker := time.NewTicker(intervalo)
+		defer ticker.Stop()
+		for {
+			for _, p := range activos {
+				claimed, err := repository.ClaimJob(ctx, db, p.Nombre, instancia, lease)
+				if err != nil {
+					log.Error("Error checking scheduled job", "job", p.Nombre, "error", err)
+					continue
+				}
+				if claimed {
+					background.Go(func() { ejecutar(ctx, db, p) })
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// ejecutar runs a claimed job and records its result and next run.
+func ejecutar(ctx context.Context, db *sql.DB, p programado) {
+	log := logging.FromContext(ctx).With("job", p.Nombre)
+	inicio := time.Now()
+	log.Info("Scheduled job started")
+
+	runCtx, cancel := context.WithTimeout(ctx, lease)
+	detalle, err := p.Run(runCtx, db)
+	cancel()
+	if err != nil {
+		log.Error("Scheduled job failed", "duration_ms", time.Since(inicio).Milliseconds(), "detalle", detalle, "error", err)
+	} else {
+		log.Info("Scheduled job finished", "duration_ms", time.Since(inicio).Milliseconds(), "detalle", detalle)
+	}
+
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tiempoRegistro)
+	defer cancel()
+	if err := repository.FinishJob(recordCtx, db, p.Nombre, instancia, inicio.UTC(), p.schedule.Next(time.Now()).UTC(), detalle, err); err != nil {
+		log.Error("Error recording scheduled job result", "error", err)
+	}
+}
+
+// Status returns every job with its schedule in this instance and the result of its last run.
+func Status(ctx context.Context, db *sql.DB) ([]models.Job, error) {
+	programados, err := programar()
+	if err != nil {
+		return nil, err
+	}
+	estados, err := repository.GetJobs(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	activos := enabled()
+	status := make([]models.Job, 0, len(programados))
+	for _, p := range programados {
+		j, ok := estados[p.Nombre]
+		if !ok {
+			j = models.Job{Nombre: p.Nombre, Estado: models.JobPendiente}
+		}
+		j.Descripcion = p.Descripcion
+		j.Programacion = p.expr
+		j.Activo = activos && p.schedule != nil
+		if !j.Activo {
+			j.SiguienteEjecucion = nil
+		}
+		status = append(status, j)
+	}
+	return status, nil
+}
@@ -0,0 +1,439 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/backup"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/ctivitae"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/migration"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/notifier"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+)
+
+// margenHuerfanos is how old an unreferenced file must be to be deleted, so that an upload whose
+// request is still running is not taken for an orphan.
+const margenHuerfanos = 24 * time.Hour
+
+// defaultAuditRetention is how long audit log entries are kept unless AUDIT_LOG_RETENTION says
+// otherwise.
+const defaultAuditRetention = 365 * 24 * time.Hour
+
+// defaultChangesRetention is how long the change feed is kept unless CHANGES_RETENTION says
+// otherwise: 90 days, enough for a sync that runs at least once a quarter.
+const defaultChangesRetention = 90 * 24 * time.Hour
+
+// defaultUsageRetention is how long the API usage counters are kept unless USAGE_RETENTION says
+// otherwise.
+const defaultUsageRetention = 90 * 24 * time.Hour
+
+// defaultBackupKeep is how many backups of the backup job are kept unless BACKUP_KEEP says otherwise.
+const defaultBackupKeep = 14
+
+// jobs are the scheduled jobs, in the order GET /admin/jobs lists them.
+var jobs = []Job{
+	{
+		Nombre:       "archivos-huerfanos",
+		Descripcion:  "Elimina del almacenamiento los archivos a los que ya no se refiere ningún grupo, adjunto, documento ni exportación",
+		Programacion: "0 4 * * *",
+		Run:          limpiarArchivosHuerfanos,
+	},
+	{
+		Nombre:       "tokens-expirados",
+		Descripcion:  "Elimina los enlaces de verificación de email, los enlaces compartidos y los enlaces para restablecer la contraseña vencidos",
+		Programacion: "15 * * * *",
+		Run:          purgarTokensExpirados,
+	},
+	{
+		Nombre:       "retencion-auditoria",
+		Descripcion:  "Elimina las entradas del registro de auditoría más antiguas que AUDIT_LOG_RETENTION",
+		Programacion: "30 3 * * *",
+		Run:          aplicarRetencionAuditoria,
+	},
+	{
+		Nombre:       "retencion-cambios",
+		Descripcion:  "Elimina los cambios de GET /changes más antiguos que CHANGES_RETENTION",
+		Programacion: "45 3 * * *",
+		Run:          aplicarRetencionCambios,
+	},
+	{
+		Nombre:       "retencion-uso",
+		Descripcion:  "Elimina el uso de la API (GET /admin/usage) más antiguo que USAGE_RETENTION",
+		Programacion: "50 3 * * *",
+		Run:          aplicarRetencionUso,
+	},
+	{
+		Nombre:       "estadisticas",
+		Descripcion:  "Guarda las estadísticas del día (GET /estadisticas/historial)",
+		Programacion: "0 2 * * *",
+		Run:          materializarEstadisticas,
+	},
+	{
+		Nombre:       "backup",
+		Descripcion:  "Guarda una copia de seguridad de la base de datos y conserva las BACKUP_KEEP más recientes de esta tarea",
+		Programacion: "0 5 * * *",
+		Run:          crearBackup,
+	},
+	{
+		Nombre:       "vencimiento-grupos",
+		Descripcion:  "Avisa por email a los coordinadores de los grupos por vencer o vencidos (GRUPO_AVISO_VENCIMIENTO_DIAS), una vez por fecha de vencimiento",
+		Programacion: "0 8 * * *",
+		Run:          avisarVencimientoGrupos,
+	},
+	{
+		Nombre:       "recordatorios-convocatorias",
+		Descripcion:  "Avisa por email a los coordinadores de los grupos inscritos en una convocatoria abierta cuando faltan los días de CONVOCATORIA_RECORDATORIO_DIAS para su cierre, una vez por umbral",
+		Programacion: "0 * * * *",
+		Run:          enviarRecordatoriosConvocatorias,
+	},
+	{
+		Nombre:       "busquedas-guardadas",
+		Descripcion:  "Envía a los usuarios el resumen semanal de los grupos nuevos o modificados que coinciden con sus búsquedas guardadas con aviso por email",
+		Programacion: "0 7 * * 1",
+		Run:          enviarResumenBusquedas,
+	},
+	{
+		Nombre:       "cti-vitae",
+		Descripcion:  "Sincroniza con CTI Vitae los datos y publicaciones de los investigadores vinculados (CTI_VITAE_API_URL)",
+		Programacion: "0 6 * * 0",
+		Run:          sincronizarCtiVitae,
+	},
+}
+
+func limpiarArchivosHuerfanos(ctx context.Context, db *sql.DB) (string, error) {
+	res, err := migration.CleanupArchivosHuerfanos(ctx, db, time.Now().Add(-margenHuerfanos))
+	detalle := fmt.Sprintf("%d archivos eliminados, %d errores", res.Eliminados, res.Errores)
+	if err == nil && res.Errores > 0 {
+		err = fmt.Errorf("%d archivos no se pudieron eliminar", res.Errores)
+	}
+	return detalle, err
+}
+
+func purgarTokensExpirados(ctx context.Context, db *sql.DB) (string, error) {
+	verificaciones, err := repository.DeleteVerificacionesExpiradas(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	enlaces, err := repository.DeleteEnlacesExpirados(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	restablecimientos, err := repository.DeleteRestablecimientosExpirados(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d verificaciones de email, %d enlaces compartidos y %d enlaces para restablecer contraseña eliminados", verificaciones, enlaces, restablecimientos), nil
+}
+
+// retencionFromEnv reads a retention period from variable (e.g. "2160h"; "0" keeps every entry).
+func retencionFromEnv(variable string, porDefecto time.Duration) (time.Duration, error) {
+	v := os.Getenv(variable)
+	if v == "" {
+		return porDefecto, nil
+	}
+	if v == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q: use a duration such as 8760h", variable, v)
+	}
+	return d, nil
+}
+
+// auditRetentionFromEnv reads AUDIT_LOG_RETENTION.
+func auditRetentionFromEnv() (time.Duration, error) {
+	return retencionFromEnv("AUDIT_LOG_RETENTION", defaultAuditRetention)
+}
+
+// RetencionCambios reads CHANGES_RETENTION, how long the change feed of GET /changes is kept; 0
+// means forever.
+func RetencionCambios() (time.Duration, error) {
+	return retencionFromEnv("CHANGES_RETENTION", defaultChangesRetention)
+}
+
+func aplicarRetencionAuditoria(ctx context.Context, db *sql.DB) (string, error) {
+	retencion, err := auditRetentionFromEnv()
+	if err != nil {
+		return "", err
+	}
+	if retencion == 0 {
+		return "retención desactivada (AUDIT_LOG_RETENTION=0)", nil
+	}
+	n, err := repository.DeleteAuditLogsAntesDe(ctx, db, time.Now().Add(-retencion).UTC())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d entradas eliminadas", n), nil
+}
+
+func aplicarRetencionCambios(ctx context.Context, db *sql.DB) (string, error) {
+	retencion, err := RetencionCambios()
+	if err != nil {
+		return "", err
+	}
+	if retencion == 0 {
+		return "retención desactivada (CHANGES_RETENTION=0)", nil
+	}
+	n, err := repository.DeleteCambiosAntesDe(ctx, db, time.Now().Add(-retencion).UTC())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d cambios eliminados", n), nil
+}
+
+func aplicarRetencionUso(ctx context.Context, db *sql.DB) (string, error) {
+	retencion, err := retencionFromEnv("USAGE_RETENTION", defaultUsageRetention)
+	if err != nil {
+		return "", err
+	}
+	if retencion == 0 {
+		return "retención desactivada (USAGE_RETENTION=0)", nil
+	}
+	n, err := repository.DeleteUsoAPIAntesDe(ctx, db, time.Now().Add(-retencion).UTC())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d contadores de uso eliminados", n), nil
+}
+
+func materializarEstadisticas(ctx context.Context, db *sql.DB) (string, error) {
+	grupos, err := repository.GetEstadisticasGrupos(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	porFacultad, err := repository.GetEstadisticasPorFacultad(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	hoy := calendario.Hoy()
+	e := &models.EstadisticaDiaria{Fecha: hoy, Grupos: *grupos, PorFacultad: porFacultad}
+	if err := repository.SaveEstadisticaDiaria(ctx, db, e); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s: %d grupos, %d investigadores", hoy, grupos.TotalGrupos, grupos.TotalInvestigadores), nil
+}
+
+// backupKeepFromEnv reads BACKUP_KEEP, the number of scheduled backups kept (at least 1).
+func backupKeepFromEnv() (int, error) {
+	v := os.Getenv("BACKUP_KEEP")
+	if v == "" {
+		return defaultBackupKeep, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid BACKUP_KEEP %q: use a number of backups, at least 1", v)
+	}
+	return n, nil
+}
+
+func crearBackup(ctx context.Context, db *sql.DB) (string, error) {
+	conservar, err := backupKeepFromEnv()
+	if err != nil {
+		return "", err
+	}
+	backend, err := storage.Default()
+	if err != nil {
+		return "", err
+	}
+	b, err := backup.Create(ctx, db, backend, models.BackupProgramado, "", nil)
+	if err != nil {
+		return "", err
+	}
+	eliminados, err := backup.Prune(ctx, db, models.BackupProgramado, conservar)
+	detalle := fmt.Sprintf("%s (%d bytes), %d copias antiguas eliminadas", b.NombreArchivo, b.Tamano, eliminados)
+	return detalle, err
+}
+
+// avisarVencimientoGrupos queues an email to the coordinators of the groups that expire within
+// models.DiasAvisoVencimiento days, or already expired. A notice is recorded as sent once queued
+// (the outbox retries the delivery), even when the group has no coordinator email, so it is not
+// looked at again.
+func avisarVencimientoGrupos(ctx context.Context, db *sql.DB) (string, error) {
+	limite := calendario.Hoy().AddDate(0, 0, models.DiasAvisoVencimiento())
+	// As for convocatoria reminders, only verified emails when verification is automatic
+	soloVerificados := os.Getenv("INVESTIGADOR_EMAIL_VERIFICATION") == "true"
+	avisos, err := repository.GetAvisosVencimientoPendientes(ctx, db, limite, soloVerificados)
+	if err != nil {
+		return "", err
+	}
+
+	for _, a := range avisos {
+		err := notifier.EnqueueEmail(ctx, db, models.EventoVencimientoGrupo, a.Emails, notifier.VencimientoGrupo{
+			NombreGrupo:      a.NombreGrupo,
+			FechaVencimiento: a.FechaVencimiento,
+			Vencido:          models.EstadoVigencia(&a.FechaVencimiento) == models.VigenciaVencido,
+		})
+		if err != nil {
+			return "", err
+		}
+		if err := repository.MarkAvisoVencimientoEnviado(ctx, db, a.IDGrupo, a.FechaVencimiento); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%d grupos avisados", len(avisos)), nil
+}
+
+// umbralesRecordatorio returns the reminder thresholds, in days before a convocatoria's deadline,
+// from CONVOCATORIA_RECORDATORIO_DIAS (comma-separated, default "7,1").
+func umbralesRecordatorio() []int {
+	raw := os.Getenv("CONVOCATORIA_RECORDATORIO_DIAS")
+	if raw == "" {
+		return []int{7, 1}
+	}
+	var umbrales []int
+	for _, v := range strings.Split(raw, ",") {
+		dias, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || dias < 0 {
+			slog.Warn("Ignoring invalid CONVOCATORIA_RECORDATORIO_DIAS value", "v", v)
+			continue
+		}
+		umbrales = append(umbrales, dias)
+	}
+	sort.Ints(umbrales)
+	return umbrales
+}
+
+// enviarRecordatoriosConvocatorias queues an email to the coordinators of the groups in an open
+// convocatoria whose deadline is within one of the umbralesRecordatorio. A reminder is recorded as
+// sent once queued (the outbox retries the delivery), even when its group has no coordinator email,
+// so it is not looked at again.
+func enviarRecordatoriosConvocatorias(ctx context.Context, db *sql.DB) (string, error) {
+	umbrales := umbralesRecordatorio()
+	if len(umbrales) == 0 {
+		return "recordatorios desactivados (CONVOCATORIA_RECORDATORIO_DIAS sin valores válidos)", nil
+	}
+	soloVerificados := os.Getenv("INVESTIGADOR_EMAIL_VERIFICATION") == "true"
+	recordatorios, err := repository.GetRecordatoriosPendientes(ctx, db, umbrales, soloVerificados)
+	if err != nil {
+		return "", err
+	}
+
+	for _, rec := range recordatorios {
+		c := rec.Convocatoria
+		err := notifier.EnqueueEmail(ctx, db, models.EventoRecordatorioConvocatoria, rec.Emails, notifier.RecordatorioConvocatoria{
+			NombreGrupo:        rec.NombreGrupo,
+			NombreConvocatoria: c.Nombre,
+			FechaCierre:        c.FechaCierre,
+			Dias:               rec.Dias,
+			Requisitos:         c.Requisitos,
+		})
+		if err != nil {
+			return "", err
+		}
+		if err := repository.MarkRecordatorioEnviado(ctx, db, c.ID, rec.IDGrupo, rec.Umbral); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%d recordatorios", len(recordatorios)), nil
+}
+
+// paginaResumen is the page size used to run the saved searches of the digest.
+const paginaResumen = 100
+
+// enviarResumenBusquedas queues an email to the owner of each saved search with alerts listing the
+// groups matching it that were created or changed since its previous digest (or since it was saved).
+// Searches without changes send nothing; either way the digest is recorded, only after its email is
+// queued (the outbox retries the delivery), so the next one starts from this run. A search whose
+// digest could not be queued keeps its previous date and gets the changes of both weeks next time.
+func enviarResumenBusquedas(ctx context.Context, db *sql.DB) (string, error) {
+	alertas, err := repository.GetAlertasBusqueda(ctx, db)
+	if err != nil {
+		return "", err
+	}
+
+	inicio := time.Now().UTC()
+	base := strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/")
+	enviados := 0
+	for _, a := range alertas {
+		desde := a.CreatedAt
+		if a.UltimoAviso != nil {
+			desde = *a.UltimoAviso
+		}
+		cambios, err := gruposCambiados(ctx, db, a.Filtros, desde)
+		if err != nil {
+			return "", err
+		}
+		if len(cambios) > 0 {
+			resumen := notifier.ResumenBusqueda{NombreBusqueda: a.Nombre, Desde: desde}
+			for _, g := range cambios {
+				cambio := notifier.GrupoCambiado{Nombre: g.Nombre, Nuevo: g.CreatedAt.After(desde)}
+				if base != "" {
+					cambio.Enlace = fmt.Sprintf("%s/grupos/%d", base, g.ID)
+				}
+				resumen.Grupos = append(resumen.Grupos, cambio)
+			}
+			if err := notifier.EnqueueEmail(ctx, db, models.EventoResumenBusqueda, []string{a.Email}, resumen); err != nil {
+				return "", err
+			}
+			enviados++
+		}
+		if err := repository.MarkBusquedaAvisada(ctx, db, a.ID, inicio); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%d búsquedas con aviso, %d resúmenes", len(alertas), enviados), nil
+}
+
+// gruposCambiados runs a saved search and returns the matching groups created or changed after desde.
+func gruposCambiados(ctx context.Context, db *sql.DB, f models.FiltrosBusqueda, desde time.Time) ([]models.Grupo, error) {
+	cambios := []models.Grupo{}
+	for offset := 0; ; offset += paginaResumen {
+		grupos, total, err := repository.SearchGrupos(ctx, db, f.Q, f.Grupo, f.Investigador, f.Anios, f.LineasInvestigacion, f.TiposInvestigacion, f.Facultades, f.Estado, f.EstadoVigencia, false, paginaResumen, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range grupos {
+			if g.Grupo.UpdatedAt.After(desde) || g.Grupo.CreatedAt.After(desde) {
+				cambios = append(cambios, g.Grupo)
+			}
+		}
+		if offset+paginaResumen >= total {
+			return cambios, nil
+		}
+	}
+}
+
+// sincronizarCtiVitae refreshes every investigator linked to CTI Vitae, those synchronized longest
+// ago first. A failed investigator is recorded in its link and does not stop the others.
+func sincronizarCtiVitae(ctx context.Context, db *sql.DB) (string, error) {
+	if !ctivitae.Configurado() {
+		return "integración desactivada (CTI_VITAE_API_URL no configurada)", nil
+	}
+	vinculos, err := repository.GetCtiVitaeVinculados(ctx, db)
+	if err != nil {
+		return "", err
+	}
+
+	sincronizados, creadas, vinculadas, errores := 0, 0, 0, 0
+	for _, v := range vinculos {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		res, err := ctivitae.Sincronizar(ctx, db, v)
+		if err != nil {
+			logging.FromContext(ctx).Error("Error synchronizing investigator with CTI Vitae", "id_investigador", v.IDInvestigador, "id_cti_vitae", v.IDCtiVitae, "error", err)
+			errores++
+			continue
+		}
+		sincronizados++
+		creadas += res.PublicacionesCreadas
+		vinculadas += res.PublicacionesVinculadas
+	}
+	detalle := fmt.Sprintf("%d investigadores sincronizados, %d publicaciones nuevas, %d vinculadas, %d errores", sincronizados, creadas, vinculadas, errores)
+	if errores > 0 {
+		return detalle, fmt.Errorf("%d investigadores no se pudieron sincronizar", errores)
+	}
+	return detalle, nil
+}
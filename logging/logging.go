@@ -0,0 +1,122 @@
+// Package logging configures the service's structured logger: JSON lines on stderr with the
+// severity and message keys Cloud Logging understands, filtered by LOG_LEVEL (debug, info, warn or
+// error; info by default).
+//
+// Every request gets a logger carrying its request ID (and, once authenticated, the user ID), which
+// handlers and repositories retrieve with FromContext, so all the lines of a request can be found
+// together.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/version"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the request ID. An ID sent by the client (or a proxy) is kept, so the
+// request can be followed across services; otherwise one is generated.
+const RequestIDHeader = "X-Request-ID"
+
+type contextKey struct{}
+
+// Setup installs a JSON logger writing to w as the default logger. Lines written with the standard
+// log package also go through it, at info level.
+func Setup(w io.Writer) *slog.Logger {
+	level, err := ParseLevel(os.Getenv("LOG_LEVEL"))
+	logger := slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: cloudLoggingAttr,
+	})).With("version", version.String())
+	slog.SetDefault(logger)
+	if err != nil {
+		logger.Warn("Invalid LOG_LEVEL, using info", "error", err)
+	}
+	return logger
+}
+
+// ParseLevel parses a LOG_LEVEL value. An empty value is info.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(strings.ToUpper(s))); err != nil {
+		return slog.LevelInfo, err
+	}
+	return level, nil
+}
+
+// cloudLoggingAttr renames the level and message keys to the ones Cloud Logging reads from
+// structured logs (severity, message).
+func cloudLoggingAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.LevelKey:
+		level, _ := a.Value.Any().(slog.Level)
+		severity := "DEBUG"
+		switch {
+		case level >= slog.LevelError:
+			severity = "ERROR"
+		case level >= slog.LevelWarn:
+			severity = "WARNING"
+		case level >= slog.LevelInfo:
+			severity = "INFO"
+		}
+		return slog.String("severity", severity)
+	case slog.MessageKey:
+		a.Key = "message"
+	}
+	return a
+}
+
+// FromContext returns the logger attached to ctx, or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// WithLogger returns a copy of ctx carrying logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// With returns a copy of ctx whose logger adds the given attributes to every line.
+func With(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
+
+// Middleware attaches a logger with the request ID (echoed in the response) and, when the request
+// is traced, the trace ID to the request context.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		args := []any{"request_id", id, "method", r.Method, "path", r.URL.Path}
+		if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+			args = append(args, "trace_id", sc.TraceID().String())
+		}
+		next.ServeHTTP(w, r.WithContext(With(r.Context(), args...)))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
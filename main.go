@@ -1,63 +1,187 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"log"
-	"net/http"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"strings"
 
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/controllers"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database"
-	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/routes" // Usa gorilla/mux
-	"github.com/joho/godotenv"                                            // Para cargar variables de entorno desde .env
-	"github.com/rs/cors"                                                  // Importar CORS para gorilla/mux
-	// Se eliminan imports de gin
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/secrets"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/telemetry"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/version"
+	"github.com/joho/godotenv" // Para cargar variables de entorno desde .env
 )
 
-var db *sql.DB
+// command is a subcommand of the binary. run receives the arguments that follow its name.
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
 
-// Se elimina struct Grupo si no se usa aquí
+// commands lists the subcommands; serve runs when none is given.
+var commands = []command{
+	{"serve", "start the HTTP API (default)", runServe},
+	{"migrate", "create or update the database schema (tables, indexes, extensions and seed catalogs)", runMigrate},
+	{"seed", "load sample investigators, groups and memberships for development", runSeed},
+	{"create-admin", "create an administrator, or promote an existing user (--email, --password)", runCreateAdmin},
+	{"cleanup-files", "delete old export files and expired share links (--older-than)", runCleanupFiles},
+	{"migrate-files", "copy every stored file to another storage backend and update the references (--to, --from)", runMigrateFiles},
+	{"migrate-uploads", "upload the files left on local disk to the storage backend and rewrite their references (--to, --dir)", runMigrateUploads},
+	{"backfill-checksums", "compute the checksum of files uploaded before checksums were recorded", runBackfillChecksums},
+	{"backup", "save a backup of the database to the storage backend (--description)", runBackup},
+	{"restore", "replace the data with a backup (--id or --file, and --yes to confirm)", runRestore},
+	{"routes", "print the route authorization matrix (method, path, required access)", runRoutes},
+}
 
 func main() {
-	log.Print("starting server...")
+	args := legacyArgs(os.Args[1:])
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		usage(os.Stdout)
+		return
+	}
 
-	// Cargar variables de entorno desde .env
-	err := godotenv.Load()
-	if err != nil && !os.IsNotExist(err) {
-		log.Printf("Warning: Error loading .env file: %v", err)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		if err := cmd.run(args); err != nil {
+			slog.Error("Command failed", "command", name, "error", err)
+			os.Exit(1)
+		}
+		return
 	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage(os.Stderr)
+	os.Exit(2)
+}
 
-	// Initialize database connection
-	db, err = database.InitDB()
-	if err != nil {
-		log.Fatal("Failed to initialize database:", err)
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s [command] [flags]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-20s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(w, "\nRun '%s <command> -h' for the flags of a command.\n", os.Args[0])
+}
+
+// legacyArgs translates the flags of the former single-purpose binary (--init-schema,
+// --migrate-files=..., --migrate-from, --migrate-delete-source, --backfill-checksums and
+// --list-routes) into the equivalent subcommand, so existing scripts and jobs keep working.
+func legacyArgs(args []string) []string {
+	if len(args) == 0 || !strings.HasPrefix(args[0], "-") {
+		return args
+	}
+	name := ""
+	flags := []string{}
+	for i := 0; i < len(args); i++ {
+		flag, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		takeValue := func() string {
+			if !hasValue && i+1 < len(args) {
+				i++
+				return args[i]
+			}
+			return value
+		}
+		switch flag {
+		case "init-schema":
+			name = "migrate"
+		case "backfill-checksums":
+			name = "backfill-checksums"
+		case "list-routes":
+			name = "routes"
+		case "migrate-files":
+			name = "migrate-files"
+			flags = append(flags, "--to="+takeValue())
+		case "migrate-from":
+			flags = append(flags, "--from="+takeValue())
+		case "migrate-delete-source":
+			flags = append(flags, "--delete-source")
+		default:
+			flags = append(flags, args[i])
+		}
+	}
+	if name == "" {
+		return args
 	}
-	defer db.Close()
+	fmt.Fprintf(os.Stderr, "warning: %q is deprecated, use the %q command instead\n", strings.Join(args, " "), name)
+	return append([]string{name}, flags...)
+}
 
-	// Setup routes using the routes package (gorilla/mux)
-	r := routes.SetupRoutes(db)
+// setup loads the configuration shared by every command that needs the database: .env, logging,
+// tracing, storage backends and the directory cache. It returns the open database and the
+// function that flushes pending traces; the caller closes both.
+func setup(name string) (*sql.DB, func(context.Context) error, error) {
+	// Cargar variables de entorno desde .env (antes del logger, que lee LOG_LEVEL)
+	envErr := godotenv.Load()
 
-	// --- Configuración de CORS usando rs/cors ---
-	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:4200"},                   // Origen permitido
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}, // Métodos permitidos
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},           // Cabeceras permitidas
-		AllowCredentials: true,
-		// Debug:            true, // Habilita logs de CORS si necesitas depurar
-	})
+	// Logs JSON con nivel (LOG_LEVEL); cada línea incluye la versión para que los reportes indiquen
+	// el build, y los recientes se conservan en memoria para el support bundle (GET /admin/support-bundle)
+	logging.Setup(io.MultiWriter(os.Stderr, utils.RecentLogs))
 
-	// Envolver el router 'r' con el handler CORS
-	httpHandler := c.Handler(r)
+	slog.Info("starting", "command", name, "built", version.Get().BuildTime)
+	if envErr != nil && !os.IsNotExist(envErr) {
+		slog.Warn("Error loading .env file", "error", envErr)
+	}
 
-	// Determine port for HTTP service.
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "3000"
-		log.Printf("defaulting to port %s", port)
+	// Secretos guardados en Secret Manager o en archivos (JWT_SECRET=sm://jwt-secret), antes de leer la configuración
+	if err := secrets.Load(context.Background()); err != nil {
+		return nil, nil, fmt.Errorf("failed to load secrets: %w", err)
 	}
 
-	// Start HTTP server using net/http with the CORS handler
-	log.Printf("listening on port %s", port)
-	if err := http.ListenAndServe(":"+port, httpHandler); err != nil {
-		log.Fatal(err)
+	// Zona horaria de la institución (INSTITUTION_TIMEZONE) en la que se leen y muestran las fechas
+	if err := calendario.Configurar(); err != nil {
+		return nil, nil, fmt.Errorf("failed to configure time zone: %w", err)
+	}
+
+	// Modo demo (DEMO_MODE=true): base de datos en memoria y configuración sin servicios externos
+	if database.DemoMode() {
+		if err := applyDemoDefaults(); err != nil {
+			return nil, nil, fmt.Errorf("failed to configure demo mode: %w", err)
+		}
+	}
+
+	// Sin CAPTCHA_SECRET el formulario público de solicitudes responde 503, salvo CAPTCHA_DISABLED=true
+	utils.LogCaptchaConfig()
+	// Sin PUBLIC_BASE_URL los enlaces por email (contraseña, verificación) no se envían: responden 503
+	controllers.LogEnlacesConfig()
+
+	// Tracing (OpenTelemetry) de peticiones, consultas SQL y llamadas a Drive, si hay un endpoint OTLP
+	shutdownTracing, err := telemetry.Setup(context.Background())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+
+	// Backends de almacenamiento (disco local y, si está configurado, Google Drive)
+	if err := controllers.InitStorage(context.Background()); err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize file storage: %w", err)
+	}
+
+	// Caché del directorio (CACHE_BACKEND=memory o redis); debe iniciarse antes de las rutas
+	if err := controllers.InitCache(); err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize cache: %w", err)
+	}
+
+	db, err := database.InitDB()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	if database.DemoMode() {
+		if err := prepareDemo(db); err != nil {
+			db.Close()
+			return nil, nil, fmt.Errorf("failed to prepare the demo database: %w", err)
+		}
 	}
+	return db, shutdownTracing, nil
 }
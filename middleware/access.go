@@ -0,0 +1,44 @@
+package middleware
+
+import "net/http"
+
+// Access is the authorization level a route requires.
+type Access int
+
+const (
+	// Public routes are open to anyone (claims are still attached by OptionalJWTMiddleware).
+	Public Access = iota
+	// Authenticated routes require a valid Bearer token.
+	Authenticated
+	// Admin routes require a valid Bearer token with the admin role.
+	Admin
+)
+
+// String returns the access level name used in listings of the route table.
+func (a Access) String() string {
+	switch a {
+	case Public:
+		return "public"
+	case Authenticated:
+		return "authenticated"
+	case Admin:
+		return "admin"
+	default:
+		return "unknown"
+	}
+}
+
+// Authorize wraps next with the middleware required by the access level.
+func Authorize(access Access, next http.Handler) http.Handler {
+	switch access {
+	case Public:
+		return next
+	case Authenticated:
+		return JWTMiddleware(next)
+	case Admin:
+		return JWTMiddleware(AdminMiddleware(next))
+	default:
+		// Unknown levels fail closed
+		return JWTMiddleware(AdminMiddleware(next))
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -18,56 +21,64 @@ type contextKey string
 const (
 	// UserIDKey is the key used to store the user ID in the request context
 	UserIDKey contextKey = "userID"
+	// RolKey is the key used to store the user's application role in the request context
+	RolKey contextKey = "rol"
 )
 
+// ErrJWTSecretMissing is returned by CheckConfig when JWT_SECRET is not set.
+var ErrJWTSecretMissing = errors.New("JWT_SECRET environment variable not set")
+
+// CheckConfig reports whether authentication is configured. Without JWT_SECRET the middlewares
+// still work, but reject every token (JWTMiddleware answers 503) and treat requests as anonymous.
+func CheckConfig() error {
+	if os.Getenv("JWT_SECRET") == "" {
+		return ErrJWTSecretMissing
+	}
+	return nil
+}
+
 // JWTMiddleware verifies the JWT token from the Authorization header.
 func JWTMiddleware(next http.Handler) http.Handler {
 	// Get the secret key from environment variable
 	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		// Log fatal error if secret is not set, as the app cannot securely function
-		log.Fatal("FATAL: JWT_SECRET environment variable not set.")
-	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if jwtSecret == "" {
+			utils.RespondError(w, "Authentication is not configured", http.StatusServiceUnavailable)
+			return
+		}
+
 		// 1. Get the token from the Authorization header
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
+			utils.RespondError(w, "Authorization header required", http.StatusUnauthorized)
 			return
 		}
 
 		// Check if the header is in the format "Bearer <token>"
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			http.Error(w, "Authorization header format must be Bearer {token}", http.StatusUnauthorized)
+			utils.RespondError(w, "Authorization header format must be Bearer {token}", http.StatusUnauthorized)
 			return
 		}
 
 		tokenString := parts[1]
 
 		// 2. Parse and validate the token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Don't forget to validate the alg is what you expect:
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			// Return the secret key for validation
-			return []byte(jwtSecret), nil
-		})
+		token, err := parseToken(tokenString, jwtSecret)
 
 		if err != nil {
-			log.Printf("Token validation error: %v", err)
+			logging.FromContext(r.Context()).Info("Token validation error", "error", err)
 			// Check for specific JWT error types using errors.Is
 			if errors.Is(err, jwt.ErrTokenMalformed) {
-				http.Error(w, "Malformed token", http.StatusUnauthorized)
+				utils.RespondError(w, "Malformed token", http.StatusUnauthorized)
 			} else if errors.Is(err, jwt.ErrTokenSignatureInvalid) {
-				http.Error(w, "Invalid token signature", http.StatusUnauthorized)
+				utils.RespondError(w, "Invalid token signature", http.StatusUnauthorized)
 			} else if errors.Is(err, jwt.ErrTokenExpired) || errors.Is(err, jwt.ErrTokenNotValidYet) {
-				http.Error(w, "Token is either expired or not active yet", http.StatusUnauthorized)
+				utils.RespondError(w, "Token is either expired or not active yet", http.StatusUnauthorized)
 			} else {
 				// Other errors (e.g., network issues during key fetch if using JWKS, or other validation errors)
-				http.Error(w, "Couldn't handle this token: validation error", http.StatusUnauthorized)
+				utils.RespondError(w, "Couldn't handle this token: validation error", http.StatusUnauthorized)
 			}
 			return
 		}
@@ -75,27 +86,119 @@ func JWTMiddleware(next http.Handler) http.Handler {
 		if !token.Valid {
 			// This case should ideally not be reached if the checks above are exhaustive
 			// but kept as a fallback.
-			http.Error(w, "Invalid token (general validation failed)", http.StatusUnauthorized)
+			utils.RespondError(w, "Invalid token (general validation failed)", http.StatusUnauthorized)
 			return
 		}
 
-		// 3. Optional: Extract claims (e.g., user ID) and add to context
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			// Example: Extract 'sub' (subject) claim, often used for user ID
-			if userID, ok := claims["sub"].(string); ok { // Assuming user ID is a string in 'sub'
-				// Add user ID to context
-				ctx := context.WithValue(r.Context(), UserIDKey, userID)
-				r = r.WithContext(ctx)
-			} else {
-				// Handle case where 'sub' claim is missing or not a string if it's mandatory
-				// log.Printf("Warning: 'sub' claim missing or not a string in token")
+		// 3. Extract claims (user ID and role) and add them to the context
+		r = withClaims(r, token)
+
+		// 4. Call the next handler if the token is valid
+		next.ServeHTTP(w, r)
+	})
+}
+
+// OptionalJWTMiddleware attaches the user's claims to the context when a valid Bearer token is
+// present, but never rejects the request. Public routes use it to enable extra options for admins.
+func OptionalJWTMiddleware(next http.Handler) http.Handler {
+	jwtSecret := os.Getenv("JWT_SECRET")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(r.Header.Get("Authorization"), " ")
+		if jwtSecret != "" && len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
+			if token, err := parseToken(parts[1], jwtSecret); err == nil && token.Valid {
+				r = withClaims(r, token)
 			}
-			// You can extract other claims similarly
-		} else {
-			log.Printf("Warning: Could not parse token claims")
 		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		// 4. Call the next handler if the token is valid
+// parseToken parses and validates a signed JWT using the HMAC secret.
+func parseToken(tokenString, jwtSecret string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// Don't forget to validate the alg is what you expect:
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		// Return the secret key for validation
+		return []byte(jwtSecret), nil
+	})
+}
+
+// ContextWithToken validates the value of an Authorization header ("Bearer <token>") and returns
+// ctx with the token's user ID and role, as JWTMiddleware does for HTTP requests. The gRPC server
+// authenticates calls with it.
+func ContextWithToken(ctx context.Context, authorization string) (context.Context, error) {
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		return ctx, ErrJWTSecretMissing
+	}
+	parts := strings.Split(authorization, " ")
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return ctx, errors.New("authorization format must be Bearer {token}")
+	}
+	token, err := parseToken(parts[1], jwtSecret)
+	if err != nil {
+		return ctx, err
+	}
+	if !token.Valid {
+		return ctx, errors.New("invalid token")
+	}
+	return claimsContext(ctx, token), nil
+}
+
+// withClaims returns the request with the token's user ID and role stored in its context.
+func withClaims(r *http.Request, token *jwt.Token) *http.Request {
+	return r.WithContext(claimsContext(r.Context(), token))
+}
+
+// claimsContext returns ctx with the token's user ID and role.
+func claimsContext(ctx context.Context, token *jwt.Token) context.Context {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		logging.FromContext(ctx).Warn("Could not parse token claims")
+		return ctx
+	}
+	// Extract 'sub' (subject) claim, used for the user ID
+	if userID, ok := claims["sub"].(string); ok {
+		ctx = context.WithValue(ctx, UserIDKey, userID)
+		ctx = logging.With(ctx, "user_id", userID)
+	}
+	// Application role, used by AdminMiddleware
+	if rol, ok := claims["rol"].(string); ok {
+		ctx = context.WithValue(ctx, RolKey, rol)
+	}
+	return ctx
+}
+
+// IsAdmin reports whether the request was made by an authenticated admin.
+func IsAdmin(r *http.Request) bool {
+	rol, _ := r.Context().Value(RolKey).(string)
+	return rol == models.RolAdmin
+}
+
+// AdminMiddleware allows the request only if the authenticated user has the admin role.
+// It must run after JWTMiddleware, which stores the role in the context.
+func AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(r) {
+			utils.RespondError(w, "Admin role required", http.StatusForbidden)
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// UserIDFromContext returns the authenticated user's ID stored by JWTMiddleware.
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	userIDStr, ok := ctx.Value(UserIDKey).(string)
+	if !ok {
+		return 0, false
+	}
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		return 0, false
+	}
+	return userID, true
+}
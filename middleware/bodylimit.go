@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+const (
+	defaultMaxBodySize   = 1 << 20  // 1 MiB, plenty for any JSON body of the API
+	defaultMaxUploadSize = 32 << 20 // 32 MiB for the routes that receive files
+)
+
+// BodyLimits are the maximum request body sizes, in bytes.
+type BodyLimits struct {
+	Default int64 // Every route
+	Upload  int64 // Routes that receive multipart file uploads
+}
+
+// BodyLimitsFromEnv reads MAX_BODY_SIZE and MAX_UPLOAD_SIZE (bytes), using the defaults for unset
+// or invalid values.
+func BodyLimitsFromEnv() BodyLimits {
+	l := BodyLimits{Default: defaultMaxBodySize, Upload: defaultMaxUploadSize}
+	if v, err := strconv.ParseInt(os.Getenv("MAX_BODY_SIZE"), 10, 64); err == nil && v > 0 {
+		l.Default = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("MAX_UPLOAD_SIZE"), 10, 64); err == nil && v > 0 {
+		l.Upload = v
+	}
+	return l
+}
+
+// LimitBody caps the request body at limit bytes. A declared Content-Length over the limit is
+// answered with 413 right away; otherwise reading past the limit fails with *http.MaxBytesError,
+// which the handlers answer with 413 as well (see utils.RespondDecodeError).
+func LimitBody(limit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			utils.RespondBodyTooLarge(w, limit)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
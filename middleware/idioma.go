@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/i18n"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// Language negotiates the language of the error messages from the Accept-Language header (see
+// i18n.Negociar); utils.RespondError and the other error helpers write them in that language.
+func Language(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(utils.NewLanguageWriter(w, i18n.Negociar(r.Header.Get("Accept-Language"))), r)
+	})
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+const defaultPublicCacheMaxAge = 60 // Seconds
+
+// PublicCacheMaxAgeFromEnv reads PUBLIC_CACHE_MAX_AGE, how long (seconds) shared caches may keep
+// the responses of the public tier, using the default for unset or invalid values. 0 disables
+// caching.
+func PublicCacheMaxAgeFromEnv() int {
+	if v, err := strconv.Atoi(os.Getenv("PUBLIC_CACHE_MAX_AGE")); err == nil && v >= 0 {
+		return v
+	}
+	return defaultPublicCacheMaxAge
+}
+
+// PublicTier shapes the responses of a public read-only route by caller. Anonymous callers get the
+// public tier: responses without the fields tagged with utils.PublicoTag and, when successful,
+// cacheable by shared caches for maxAge seconds (see utils.NewPublicWriter). Authenticated callers
+// get the full response, marked private. It must run after OptionalJWTMiddleware.
+func PublicTier(maxAge int, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Authorization")
+		if _, ok := UserIDFromContext(r.Context()); ok {
+			w.Header().Set("Cache-Control", "private")
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(utils.NewPublicWriter(w, maxAge), r)
+	})
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+const (
+	defaultRateLimitPublic        = 60  // Requests per minute of each anonymous client IP
+	defaultRateLimitAuthenticated = 600 // Requests per minute of each authenticated user
+)
+
+// RateLimits are the requests per minute allowed to each caller; 0 disables the limit.
+type RateLimits struct {
+	Public        int // Anonymous callers, by client IP
+	Authenticated int // Callers with a valid token, by user
+}
+
+// RateLimitsFromEnv reads RATE_LIMIT_PUBLIC and RATE_LIMIT_AUTHENTICATED (requests per minute),
+// using the defaults for unset or invalid values.
+func RateLimitsFromEnv() RateLimits {
+	l := RateLimits{Public: defaultRateLimitPublic, Authenticated: defaultRateLimitAuthenticated}
+	if v, err := strconv.Atoi(os.Getenv("RATE_LIMIT_PUBLIC")); err == nil && v >= 0 {
+		l.Public = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("RATE_LIMIT_AUTHENTICATED")); err == nil && v >= 0 {
+		l.Authenticated = v
+	}
+	return l
+}
+
+// bucket is the token bucket of one caller: it holds up to a minute's worth of requests and
+// refills continuously.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type rateLimiter struct {
+	limits RateLimits
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// take spends a request of the caller key, whose limit is perMinute. It returns whether the
+// request is allowed, the requests left and, when not allowed, how long until the next one is.
+func (l *rateLimiter) take(key string, perMinute int, now time.Time) (ok bool, remaining int, retry time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	rate := float64(perMinute) / 60 // Per second
+	b, found := l.buckets[key]
+	if !found {
+		b = &bucket{tokens: float64(perMinute), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(perMinute), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, 0, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, int(b.tokens), 0
+}
+
+// sweep drops, once a minute, the buckets idle for a minute: they are full again, the same as a
+// new one.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= time.Minute {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimit limits the requests of each caller to limits: anonymous callers (the public tier) by
+// client IP, authenticated ones by user. It must run after OptionalJWTMiddleware. Responses carry
+// X-RateLimit-Limit and X-RateLimit-Remaining; requests over the limit are answered with 429 and
+// Retry-After. Preflight (OPTIONS) requests are not counted.
+func RateLimit(limits RateLimits) func(http.Handler) http.Handler {
+	l := &rateLimiter{limits: limits, buckets: map[string]*bucket{}}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key, limit := "ip:"+utils.ClientIP(r), l.limits.Public
+			if userID, ok := UserIDFromContext(r.Context()); ok {
+				key, limit = "user:"+strconv.Itoa(userID), l.limits.Authenticated
+			}
+			if limit <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ok, remaining, retry := l.take(key, limit, time.Now())
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+				utils.RespondErrorDetails(w, "Too many requests", http.StatusTooManyRequests, map[string]int{"limitePorMinuto": limit})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
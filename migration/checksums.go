@@ -0,0 +1,50 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+)
+
+// BackfillChecksums computes and stores the checksum of every group file and attachment uploaded
+// before checksums were recorded. Files that can't be read are logged and skipped; running it
+// again only processes the files still missing a checksum.
+func BackfillChecksums(ctx context.Context, db *sql.DB) (calculados, errores int, err error) {
+	refs, err := repository.GetArchivoRefsSinChecksum(ctx, db)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, ref := range refs {
+		if err := ctx.Err(); err != nil {
+			return calculados, errores, err
+		}
+		if err := backfillChecksum(ctx, db, ref); err != nil {
+			logging.FromContext(ctx).Error("Error calculando checksum", "ref", ref, "error", err)
+			errores++
+			continue
+		}
+		calculados++
+	}
+	return calculados, errores, nil
+}
+
+func backfillChecksum(ctx context.Context, db *sql.DB, ref string) error {
+	backend, key, err := storage.Resolve(ref)
+	if err != nil {
+		return err
+	}
+	obj, err := backend.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer obj.Body.Close()
+
+	sum, size, err := storage.Checksum(obj.Body)
+	if err != nil {
+		return err
+	}
+	return repository.SaveArchivoChecksum(ctx, db, ref, sum, size)
+}
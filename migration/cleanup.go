@@ -0,0 +1,97 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+)
+
+// ResultadoLimpieza summarizes a CleanupFiles run.
+type ResultadoLimpieza struct {
+	Exports int   // Export files deleted from storage
+	Enlaces int64 // Expired share links deleted
+	Errores int   // Files that could not be deleted; they are retried on the next run
+}
+
+// CleanupFiles deletes the generated files of exports completed before antes, marking their jobs
+// as expired, and removes the share links that have already expired. Group files and attachments
+// are never touched: soft-deleted groups keep theirs for restore.
+func CleanupFiles(ctx context.Context, db *sql.DB, antes time.Time) (ResultadoLimpieza, error) {
+	var res ResultadoLimpieza
+
+	jobs, err := repository.GetExportsCompletadosAntesDe(ctx, db, antes)
+	if err != nil {
+		return res, err
+	}
+	for _, job := range jobs {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		if err := deleteExportFile(ctx, db, job.ID, *job.Archivo); err != nil {
+			logging.FromContext(ctx).Error("Error eliminando archivo de exportación", "id", job.ID, "ref", *job.Archivo, "error", err)
+			res.Errores++
+			continue
+		}
+		res.Exports++
+	}
+
+	if res.Enlaces, err = repository.DeleteEnlacesExpirados(ctx, db); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func deleteExportFile(ctx context.Context, db *sql.DB, id int, ref string) error {
+	backend, key, err := storage.Resolve(ref)
+	if err != nil {
+		return err
+	}
+	if err := backend.Delete(ctx, key); err != nil {
+		return err
+	}
+	return repository.ExpireExportJob(ctx, db, id)
+}
+
+// ResultadoHuerfanos summarizes a CleanupArchivosHuerfanos run.
+type ResultadoHuerfanos struct {
+	Eliminados int // Files deleted from storage
+	Errores    int // Files that could not be deleted; they are retried on the next run
+}
+
+// CleanupArchivosHuerfanos deletes from storage the files stored before antes that nothing refers
+// to any longer (see repository.GetArchivosHuerfanos). antes leaves a margin for the uploads whose
+// request is still recording the reference.
+func CleanupArchivosHuerfanos(ctx context.Context, db *sql.DB, antes time.Time) (ResultadoHuerfanos, error) {
+	var res ResultadoHuerfanos
+	refs, err := repository.GetArchivosHuerfanos(ctx, db, antes)
+	if err != nil {
+		return res, err
+	}
+	for _, ref := range refs {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		if err := deleteArchivoHuerfano(ctx, db, ref); err != nil {
+			logging.FromContext(ctx).Error("Error eliminando archivo huérfano", "ref", ref, "error", err)
+			res.Errores++
+			continue
+		}
+		res.Eliminados++
+	}
+	return res, nil
+}
+
+func deleteArchivoHuerfano(ctx context.Context, db *sql.DB, ref string) error {
+	backend, key, err := storage.Resolve(ref)
+	if err != nil {
+		return err
+	}
+	if err := backend.Delete(ctx, key); err != nil {
+		return err
+	}
+	return repository.DeleteArchivoChecksum(ctx, db, ref)
+}
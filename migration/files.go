@@ -0,0 +1,173 @@
+// Package migration moves stored files between storage backends.
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+)
+
+// Options configures a file migration run.
+type Options struct {
+	// DeleteSource removes each file from the source backend once its references are updated.
+	DeleteSource bool
+	// Progress, if set, is called after every file with the current progress.
+	Progress func(models.ProgresoMigracion)
+}
+
+// MigrateFiles copies every file referenced by groups and attachments from one backend to another
+// and updates the database references. Each file is handled independently: it is copied, the copy
+// is recorded in migracion_archivo, and then all its references are switched in one transaction.
+// Running it again resumes where a previous run stopped without copying files twice.
+// Per-file failures are counted and recorded; the run continues with the next file.
+func MigrateFiles(ctx context.Context, db *sql.DB, from, to storage.Backend, opts Options) (models.ProgresoMigracion, error) {
+	if from.Name() == to.Name() {
+		progreso := models.ProgresoMigracion{Origen: from.Name(), Destino: to.Name(), IniciadoEn: time.Now()}
+		progreso.FinalizadoEn = &progreso.IniciadoEn
+		if opts.Progress != nil {
+			opts.Progress(progreso)
+		}
+		return progreso, fmt.Errorf("el origen y el destino de la migración son el mismo backend (%s)", from.Name())
+	}
+	return migrate(ctx, db, from, to, func(ref string) (string, bool) {
+		backend, key := storage.ParseRef(ref)
+		return key, backend == from.Name()
+	}, opts)
+}
+
+// migrate copies to the destination the files whose ref is selected by clave, which returns the
+// file's key in from, and updates their references.
+func migrate(ctx context.Context, db *sql.DB, from, to storage.Backend, clave func(ref string) (string, bool), opts Options) (models.ProgresoMigracion, error) {
+	progreso := models.ProgresoMigracion{
+		Origen:     from.Name(),
+		Destino:    to.Name(),
+		EnCurso:    true,
+		IniciadoEn: time.Now(),
+	}
+	report := func() {
+		if opts.Progress != nil {
+			opts.Progress(progreso)
+		}
+	}
+	finish := func() {
+		now := time.Now()
+		progreso.EnCurso = false
+		progreso.Actual = ""
+		progreso.FinalizadoEn = &now
+		report()
+	}
+
+	refs, err := repository.GetArchivoRefs(ctx, db)
+	if err != nil {
+		finish()
+		return progreso, err
+	}
+	pendientes := []string{}
+	claves := map[string]string{}
+	for _, ref := range refs {
+		if key, ok := clave(ref); ok {
+			pendientes = append(pendientes, ref)
+			claves[ref] = key
+		}
+	}
+	progreso.Total = len(pendientes)
+	logging.FromContext(ctx).Info("Migración de archivos iniciada", "from", from.Name(), "to", to.Name(), "total", progreso.Total)
+	report()
+
+	for i, ref := range pendientes {
+		if err := ctx.Err(); err != nil {
+			finish()
+			return progreso, err
+		}
+		progreso.Actual = ref
+
+		omitido, err := migrateFile(ctx, db, from, to, ref, claves[ref], opts.DeleteSource)
+		switch {
+		case err != nil:
+			progreso.Errores++
+			progreso.UltimoError = fmt.Sprintf("%s: %v", ref, err)
+			logging.FromContext(ctx).Error("Error migrando archivo", "ref", ref, "error", err)
+			if saveErr := repository.SaveMigracionError(ctx, db, ref, err.Error()); saveErr != nil {
+				logging.FromContext(ctx).Error("Error registrando fallo de migración", "ref", ref, "error", saveErr)
+			}
+		case omitido:
+			progreso.Omitidos++
+		default:
+			progreso.Migrados++
+		}
+
+		if (i+1)%10 == 0 || i+1 == len(pendientes) {
+			logging.FromContext(ctx).Info("Migración de archivos en curso", "procesados", i+1, "total", progreso.Total, "migrados", progreso.Migrados, "omitidos", progreso.Omitidos, "errores", progreso.Errores)
+		}
+		report()
+	}
+
+	finish()
+	return progreso, nil
+}
+
+// migrateFile migrates a single ref whose file is key in from. It reports omitido=true when the
+// copy already existed from a previous run and only the references had to be updated. When from
+// and to are the same backend the file is not copied: only the ref is rewritten.
+func migrateFile(ctx context.Context, db *sql.DB, from, to storage.Backend, ref, key string, deleteSource bool) (omitido bool, err error) {
+	registro, err := repository.GetMigracionArchivo(ctx, db, ref)
+	if err != nil {
+		return false, err
+	}
+
+	var destino string
+	if registro != nil && registro.Destino != nil {
+		// Ya se copió en una ejecución anterior que no llegó a actualizar las referencias
+		destino = *registro.Destino
+		omitido = true
+	} else if from == to {
+		existe, err := from.Exists(ctx, key)
+		if err != nil {
+			return false, err
+		}
+		if !existe {
+			return false, fmt.Errorf("el archivo no existe en el origen")
+		}
+		destino = storage.FormatRef(to.Name(), key)
+		if err := repository.SaveMigracionCopia(ctx, db, ref, destino); err != nil {
+			return false, err
+		}
+		deleteSource = false
+	} else {
+		obj, err := from.Open(ctx, key)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return false, fmt.Errorf("el archivo no existe en el origen")
+			}
+			return false, err
+		}
+		nuevaClave, err := to.Put(ctx, obj.Name, obj.Body)
+		obj.Body.Close()
+		if err != nil {
+			return false, err
+		}
+		destino = storage.FormatRef(to.Name(), nuevaClave)
+		if err := repository.SaveMigracionCopia(ctx, db, ref, destino); err != nil {
+			return false, err
+		}
+	}
+
+	if err := repository.CompleteMigracionArchivo(ctx, db, ref, destino); err != nil {
+		return false, err
+	}
+
+	if deleteSource {
+		if err := from.Delete(ctx, key); err != nil {
+			// Las referencias ya apuntan al destino; el archivo huérfano en el origen no bloquea la migración
+			logging.FromContext(ctx).Warn("No se pudo eliminar del origen tras migrarlo", "ref", ref, "error", err)
+		}
+	}
+	return omitido, nil
+}
@@ -0,0 +1,49 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+)
+
+// LegacyUploadKey returns the local storage key of an archivo value saved when files were served
+// statically from ./uploads: a path or URL such as "/uploads/2024/05/acta.pdf" or
+// "https://api.example.com/uploads/acta.pdf". Storage refs and Drive IDs (which never contain a
+// slash) are not legacy uploads.
+func LegacyUploadKey(value string) (string, bool) {
+	if backend, _ := storage.ParseRef(value); backend != storage.DriveName || !strings.Contains(value, "/") {
+		return "", false
+	}
+	ruta := value
+	if u, err := url.Parse(value); err == nil {
+		ruta = u.Path
+	}
+	_, key, ok := strings.Cut("/"+strings.TrimPrefix(ruta, "/"), "/uploads/")
+	if !ok || key == "" {
+		return "", false
+	}
+	return key, true
+}
+
+// MigrateLegacyUploads uploads the files still on local disk to the destination backend and
+// rewrites their archivo values: the legacy ./uploads paths (see LegacyUploadKey) and, unless the
+// destination is the local backend, the "local:" refs. When local is also the destination, legacy
+// values are only rewritten as "local:" refs, without copying the files. Like MigrateFiles it
+// can be interrupted and run again.
+func MigrateLegacyUploads(ctx context.Context, db *sql.DB, local, to storage.Backend, opts Options) (models.ProgresoMigracion, error) {
+	if local.Name() != storage.LocalName {
+		return models.ProgresoMigracion{}, fmt.Errorf("el origen de las subidas locales debe ser el backend %s, no %s", storage.LocalName, local.Name())
+	}
+	return migrate(ctx, db, local, to, func(ref string) (string, bool) {
+		if key, ok := LegacyUploadKey(ref); ok {
+			return key, true
+		}
+		backend, key := storage.ParseRef(ref)
+		return key, backend == storage.LocalName && to.Name() != storage.LocalName
+	}, opts)
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Tipos de adjunto de un grupo.
+const (
+	TipoArchivoResolucion = "resolucion"
+	TipoArchivoEvaluacion = "evaluacion"
+	TipoArchivoInforme    = "informe"
+	TipoArchivoOtro       = "otro"
+)
+
+// Acceso requerido para descargar un archivo por GET /files/{id}, según dónde se referencia.
+const (
+	AccesoArchivoPublico     = "publico"     // Documento o resolución de un grupo activo, o adjunto público
+	AccesoArchivoAutenticado = "autenticado" // Archivo de un proyecto o documento de una postulación
+	AccesoArchivoAdmin       = "admin"       // Adjunto no público, archivo de un grupo eliminado o exportación
+)
+
+// GrupoArchivo is a document attached to a group. Non-public attachments (evaluations, internal
+// reports) are only visible to authenticated users and can be shared through temporary links.
+type GrupoArchivo struct {
+	ID        int       `json:"idArchivo"`
+	IDGrupo   int       `json:"idGrupo"`
+	Nombre    string    `json:"nombre" validate:"notblank"`
+	Tipo      string    `json:"tipo" validate:"oneof=resolucion evaluacion informe otro"`
+	Archivo   *string   `json:"archivo" link:"file"` // Storage ref in the DB; link in responses (nil for non-public files)
+	Publico   bool      `json:"publico"`
+	SubidoPor *int      `json:"subidoPor,omitempty" publico:"omitir"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CrearEnlaceCompartidoRequest is the body of POST /grupos/{id}/archivos/{fid}/share.
+type CrearEnlaceCompartidoRequest struct {
+	Horas int `json:"horas" validate:"min=1,max=720"` // Validity in hours (default 24)
+}
+
+// EnlaceCompartido is a tokenized, time-boxed link to a non-public attachment.
+// Only the token's hash is stored; the token itself is returned once, on creation.
+type EnlaceCompartido struct {
+	ID        int       `json:"idEnlace"`
+	IDArchivo int       `json:"idArchivo"`
+	Token     string    `json:"token,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	ExpiraEn  time.Time `json:"expiraEn"`
+	CreadoPor *int      `json:"creadoPor,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// OcurrenciaArchivo is one place where a stored file is referenced: a group's resolution file
+// (origen "grupo") or one of its attachments (origen "adjunto").
+type OcurrenciaArchivo struct {
+	IDGrupo     int    `json:"idGrupo"`
+	NombreGrupo string `json:"nombreGrupo"`
+	Origen      string `json:"origen"`
+	IDArchivo   *int   `json:"idArchivo,omitempty"` // Attachment ID, for origen "adjunto"
+	Nombre      string `json:"nombre,omitempty"`    // Attachment name, for origen "adjunto"
+}
+
+// ArchivoDuplicado groups the references to files with identical content (same SHA-256).
+type ArchivoDuplicado struct {
+	Checksum    string              `json:"checksum"`
+	Tamano      int64               `json:"tamano"` // Bytes
+	Ocurrencias []OcurrenciaArchivo `json:"ocurrencias"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Acciones registradas en la auditoría.
+const (
+	AuditCompartirArchivo = "compartir_archivo"
+	AuditAccesoCompartido = "acceso_compartido"
+	AuditEliminarForzado  = "eliminar_forzado" // Deletion that also removed the entity's relations
+	AuditRestaurarBackup  = "restaurar_backup"
+	AuditEnviarGrupo      = "enviar_grupo" // A non-admin registered a group, pending approval
+	AuditAprobarGrupo     = "aprobar_grupo"
+	AuditRechazarGrupo    = "rechazar_grupo"
+)
+
+// AuditLog is an entry of the audit trail.
+type AuditLog struct {
+	ID        int       `json:"idAudit"`
+	IDUsuario *int      `json:"idUsuario,omitempty"` // nil for anonymous actions (e.g. opening a share link)
+	Accion    string    `json:"accion"`
+	Entidad   string    `json:"entidad"`
+	IDEntidad *int      `json:"idEntidad,omitempty"`
+	Detalle   string    `json:"detalle"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"createdAt"`
+}
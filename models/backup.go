@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Orígenes de una copia de seguridad.
+const (
+	BackupManual          = "manual"           // POST /admin/backup or the backup command
+	BackupProgramado      = "programado"       // The backup scheduled job
+	BackupPreRestauracion = "pre_restauracion" // Taken automatically before a restore, to undo it
+)
+
+// Backup is a logical copy of the database: every table of the registry as a gzipped JSON file
+// kept in the storage layer.
+type Backup struct {
+	ID            int              `json:"idBackup"`
+	Archivo       string           `json:"-"` // Storage ref of the file
+	NombreArchivo string           `json:"nombreArchivo"`
+	Origen        string           `json:"origen"`
+	Descripcion   string           `json:"descripcion"`
+	Motor         string           `json:"motor"` // Database backend it was taken from ("postgres" or "sqlite")
+	Tamano        int64            `json:"tamano"`
+	Sha256        string           `json:"sha256"`
+	Filas         map[string]int64 `json:"filas"` // Rows saved per table
+	CreadoPor     *int             `json:"creadoPor,omitempty"`
+	CreatedAt     time.Time        `json:"createdAt"`
+}
+
+// CreateBackupRequest is the (optional) body of POST /admin/backup.
+type CreateBackupRequest struct {
+	Descripcion string `json:"descripcion" validate:"max=500"`
+}
+
+// RestoreBackupRequest is the body of POST /admin/backups/{id}/restore. Confirmacion must repeat
+// the backup's nombreArchivo, so that a restore can't be started by mistake.
+type RestoreBackupRequest struct {
+	Confirmacion string `json:"confirmacion" validate:"required"`
+}
+
+// ResultadoRestauracion reports a completed restore.
+type ResultadoRestauracion struct {
+	Backup       Backup           `json:"backup"`
+	Filas        map[string]int64 `json:"filas"`        // Rows restored per table
+	PrevioBackup *Backup          `json:"previoBackup"` // Copy of the data replaced, to undo the restore
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// FiltrosBusqueda are the filters of a saved search, as in GET /grupos (?q, ?grupo, ?investigador,
+// ?año, ?lineaInvestigacion, ?tipoInvestigacion, ?facultad, ?estado and ?estadoVigencia).
+type FiltrosBusqueda struct {
+	Q                   string   `json:"q,omitempty" validate:"max=200"`
+	Grupo               string   `json:"grupo,omitempty" validate:"max=200"`
+	Investigador        string   `json:"investigador,omitempty" validate:"max=200"`
+	Anios               []int    `json:"anios,omitempty" validate:"max=20,dive,min=1900,max=2200"`
+	LineasInvestigacion []string `json:"lineasInvestigacion,omitempty" validate:"max=20,dive,max=200"`
+	TiposInvestigacion  []string `json:"tiposInvestigacion,omitempty" validate:"max=20,dive,max=200"`
+	Facultades          []int    `json:"facultades,omitempty" validate:"max=20,dive,min=1"`
+	Estado              string   `json:"estado,omitempty"`
+	EstadoVigencia      string   `json:"estadoVigencia,omitempty"`
+}
+
+// Vacios reports whether no filter is set, so the search would match every group.
+func (f FiltrosBusqueda) Vacios() bool {
+	return f.Q == "" && f.Grupo == "" && f.Investigador == "" && len(f.Anios) == 0 && len(f.LineasInvestigacion) == 0 &&
+		len(f.TiposInvestigacion) == 0 && len(f.Facultades) == 0 && f.Estado == "" && f.EstadoVigencia == ""
+}
+
+// BusquedaGuardada is a named group search of a user (/me/busquedas). With AlertaEmail the user is
+// mailed a weekly digest of the matching groups created or changed since the previous one.
+type BusquedaGuardada struct {
+	ID          int             `json:"idBusqueda"`
+	IDUsuario   int             `json:"idUsuario"`
+	Nombre      string          `json:"nombre" validate:"notblank,max=100"` // Unique per user, ignoring case
+	Filtros     FiltrosBusqueda `json:"filtros"`
+	AlertaEmail bool            `json:"alertaEmail"`
+	UltimoAviso *time.Time      `json:"ultimoAviso"` // Last digest run, nil if none yet
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
+// AlertaBusqueda is a saved search with email alerts and the email of its owner, as the
+// busquedas-guardadas job reads it.
+type AlertaBusqueda struct {
+	BusquedaGuardada
+	Email string
+}
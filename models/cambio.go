@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Operaciones registradas en el feed de cambios (GET /changes).
+const (
+	CambioCreado      = "creado"
+	CambioActualizado = "actualizado"
+	CambioEliminado   = "eliminado" // Also a soft delete: the entity is gone from the listings
+)
+
+// Entidades del feed de cambios, each with the path of its resource.
+var EntidadesCambio = map[string]string{
+	"grupo":        "/grupos/",
+	"investigador": "/investigadores/",
+	"detalle":      "/detalles/",
+	"publicacion":  "/publicaciones/",
+	"proyecto":     "/proyectos/",
+	"convocatoria": "/convocatorias/",
+	"facultad":     "/facultades/",
+	"escuela":      "/escuelas/",
+}
+
+// Cambio is an entry of the change feed: an entity was created, updated or deleted. It carries no
+// data; clients fetch the entity from URL (a 404 there means it is gone).
+type Cambio struct {
+	ID        int64     `json:"idCambio"`
+	Cursor    string    `json:"cursor"` // since value that resumes the feed after this change
+	Entidad   string    `json:"entidad"`
+	IDEntidad int       `json:"idEntidad"`
+	Operacion string    `json:"operacion"`
+	Fecha     time.Time `json:"fecha"`
+	URL       string    `json:"url"`
+}
+
+// FeedCambios is a page of GET /changes. NextCursor is the since of the next request, also when
+// the page is empty.
+type FeedCambios struct {
+	Data       []Cambio `json:"data"`
+	NextCursor string   `json:"nextCursor"`
+	HasMore    bool     `json:"hasMore"`
+}
+
+// SinEnvoltura implements utils.Envuelta: the feed keeps its envelope, which carries the cursor.
+func (f FeedCambios) SinEnvoltura() (interface{}, bool) {
+	return nil, false
+}
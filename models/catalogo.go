@@ -0,0 +1,8 @@
+package models
+
+// TipoInvestigacion is an entry of the catalog of research types, the values accepted for a
+// group's tipoInvestigacion.
+type TipoInvestigacion struct {
+	ID     int    `json:"idTipo"`
+	Nombre string `json:"nombre"`
+}
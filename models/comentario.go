@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// GrupoComentario is a note in the comments thread of a group, such as feedback from the
+// evaluation committee. Only authenticated users can read and write them.
+type GrupoComentario struct {
+	ID        int       `json:"idComentario"`
+	IDGrupo   int       `json:"idGrupo"`
+	IDUsuario *int      `json:"idUsuario"` // Author, from the JWT; nil once their account is deleted
+	Autor     *string   `json:"autor"`     // Author's email
+	Contenido string    `json:"contenido" validate:"notblank,max=5000"`
+	CreatedAt time.Time `json:"createdAt"`
+}
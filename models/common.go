@@ -2,10 +2,20 @@ package models
 
 // PaginationMetadata holds information about the pagination state.
 type PaginationMetadata struct {
-	TotalItems  int `json:"totalItems"`
-	TotalPages  int `json:"totalPages"`
-	CurrentPage int `json:"currentPage"`
-	Limit       int `json:"limit"`
+	TotalItems  int             `json:"totalItems"`
+	TotalPages  int             `json:"totalPages"`
+	CurrentPage int             `json:"currentPage"`
+	Limit       int             `json:"limit"`
+	NextPage    *int            `json:"nextPage"` // null on the last page
+	PrevPage    *int            `json:"prevPage"` // null on the first page
+	Links       PaginationLinks `json:"links"`
+}
+
+// PaginationLinks are the absolute URLs of the neighbouring pages, with the same query parameters
+// as the current request. They are null when there is no such page.
+type PaginationLinks struct {
+	Next *string `json:"next"`
+	Prev *string `json:"prev"`
 }
 
 // PaginatedResponse is a generic wrapper for paginated API responses.
@@ -13,3 +23,22 @@ type PaginatedResponse struct {
 	Data       interface{}        `json:"data"` // Holds the actual slice of results (e.g., []Investigador, []GrupoWithInvestigadores)
 	Pagination PaginationMetadata `json:"pagination"`
 }
+
+// SinEnvoltura returns the records alone, for JSON_ENVELOPE=none: the pagination is also sent in
+// the X-Total-Count and Link headers.
+func (p PaginatedResponse) SinEnvoltura() (interface{}, bool) {
+	return p.Data, true
+}
+
+// LoteResponse is the response of a batched GET by IDs (?ids=1,5,9): the records found, in the
+// requested order, and the IDs that do not exist (or were deleted).
+type LoteResponse struct {
+	Data          interface{} `json:"data"`
+	NoEncontrados []int       `json:"noEncontrados"`
+}
+
+// SinEnvoltura implements utils.Envuelta: the IDs not found have no header to go to, so the
+// envelope is kept.
+func (l LoteResponse) SinEnvoltura() (interface{}, bool) {
+	return nil, false
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+)
+
+// Estados de una convocatoria.
+const (
+	ConvocatoriaBorrador  = "borrador"  // Being prepared, not visible as open
+	ConvocatoriaAbierta   = "abierta"   // Accepting groups; reminders are sent before fechaCierre
+	ConvocatoriaCerrada   = "cerrada"   // Deadline passed
+	ConvocatoriaCancelada = "cancelada" // Withdrawn
+)
+
+// EsEstadoConvocatoriaValido reports whether estado is a known convocatoria state.
+func EsEstadoConvocatoriaValido(estado string) bool {
+	switch estado {
+	case ConvocatoriaBorrador, ConvocatoriaAbierta, ConvocatoriaCerrada, ConvocatoriaCancelada:
+		return true
+	}
+	return false
+}
+
+// Convocatoria is a call for group registration or renewal with its deadlines.
+type Convocatoria struct {
+	ID                   int              `json:"idConvocatoria" db:"idConvocatoria"`
+	Nombre               string           `json:"nombre" db:"nombre" validate:"notblank"`
+	Descripcion          string           `json:"descripcion" db:"descripcion"`
+	Requisitos           string           `json:"requisitos" db:"requisitos"`                                             // Free text listing what groups must submit
+	DocumentosRequeridos []string         `json:"documentosRequeridos" db:"documentosRequeridos" validate:"dive,max=200"` // Documents every postulacion must attach
+	FechaApertura        calendario.Fecha `json:"fechaApertura" db:"fechaApertura" fecha:"dia" validate:"required"`
+	FechaCierre          calendario.Fecha `json:"fechaCierre" db:"fechaCierre" fecha:"dia" validate:"required,gtefield=FechaApertura"` // Deadline, inclusive
+	Estado               string           `json:"estado" db:"estado" validate:"oneof=borrador abierta cerrada cancelada"`              // borrador, abierta, cerrada or cancelada
+	TotalGrupos          int              `json:"totalGrupos"`                                                                         // Participating groups (read-only)
+	CreatedAt            time.Time        `json:"createdAt" db:"createdAt"`
+	UpdatedAt            time.Time        `json:"updatedAt" db:"updatedAt"`
+}
+
+// ParticipacionConvocatoriaRequest is the body of POST /convocatorias/{id}/grupos.
+type ParticipacionConvocatoriaRequest struct {
+	IDGrupo int `json:"idGrupo" validate:"required,min=1"`
+}
+
+// RecordatorioConvocatoria is a deadline reminder due for one participating group.
+type RecordatorioConvocatoria struct {
+	Convocatoria Convocatoria
+	Umbral       int // Reminder threshold reached (days before fechaCierre); each one is sent once
+	Dias         int // Days actually left until fechaCierre
+	IDGrupo      int
+	NombreGrupo  string
+	Emails       []string // Emails of the group's coordinator
+}
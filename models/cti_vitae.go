@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// CtiVitae links an investigator to their record in CTI Vitae, the national researcher registry
+// of CONCYTEC (DINA), and holds the state of its last synchronization.
+type CtiVitae struct {
+	IDInvestigador       int        `json:"idInvestigador"`
+	IDCtiVitae           string     `json:"idCtiVitae" validate:"notblank,max=20,numeric"` // Number in the CTI Vitae profile URL (id_investigador)
+	UltimaSincronizacion *time.Time `json:"ultimaSincronizacion"`                          // Last attempt, successful or not
+	UltimoError          *string    `json:"ultimoError"`                                   // Why the last attempt failed; nil if it succeeded
+	CreatedAt            time.Time  `json:"createdAt"`
+}
+
+// ResultadoSyncCtiVitae summarizes what a synchronization changed in the local records.
+type ResultadoSyncCtiVitae struct {
+	IDInvestigador          int                  `json:"idInvestigador"`
+	IDCtiVitae              string               `json:"idCtiVitae"`
+	CamposActualizados      []string             `json:"camposActualizados"`      // Investigator fields filled in from the registry
+	Diferencias             []DiferenciaCtiVitae `json:"diferencias"`             // Fields that differ but were kept
+	PublicacionesCreadas    int                  `json:"publicacionesCreadas"`    // Registry publications added locally
+	PublicacionesVinculadas int                  `json:"publicacionesVinculadas"` // Local publications the investigator was added to as author
+	PublicacionesOmitidas   []PublicacionOmitida `json:"publicacionesOmitidas"`   // Registry publications that could not be imported
+	Sincronizado            time.Time            `json:"sincronizado"`
+}
+
+// DiferenciaCtiVitae is an investigator field whose local value differs from the registry's. The
+// local value wins: it is reported for review, not overwritten.
+type DiferenciaCtiVitae struct {
+	Campo    string `json:"campo"`
+	Local    string `json:"local"`
+	CtiVitae string `json:"ctiVitae"`
+}
+
+// PublicacionOmitida is a registry publication left out of a synchronization, and why.
+type PublicacionOmitida struct {
+	Titulo string `json:"titulo"`
+	Motivo string `json:"motivo"`
+}
@@ -1,13 +1,114 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+)
+
+// Roles de un investigador dentro de un grupo. Cada grupo tiene exactamente un coordinador.
+const (
+	RolCoordinador = "Coordinador"
+	RolIntegrante  = "Integrante"
+)
+
+// ErrSinCoordinador and ErrVariosCoordinadores are returned by ValidarCoordinadorUnico.
+var (
+	ErrSinCoordinador      = errors.New("el grupo debe tener un integrante con rol Coordinador")
+	ErrVariosCoordinadores = errors.New("el grupo solo puede tener un integrante con rol Coordinador")
+)
+
+// EsCoordinador reports whether rol is the coordinator role (case-insensitive, as stored by older clients).
+func EsCoordinador(rol string) bool {
+	return strings.EqualFold(strings.TrimSpace(rol), RolCoordinador)
+}
+
+// ValidarCoordinadorUnico checks that a full member list has exactly one coordinator.
+// An empty list is valid: the group simply has no members yet.
+func ValidarCoordinadorUnico(investigadores []InvestigatorRelationshipRequest) error {
+	if len(investigadores) == 0 {
+		return nil
+	}
+	coordinadores := 0
+	for _, inv := range investigadores {
+		if EsCoordinador(inv.TipoRelacion) {
+			coordinadores++
+		}
+	}
+	switch {
+	case coordinadores == 0:
+		return ErrSinCoordinador
+	case coordinadores > 1:
+		return ErrVariosCoordinadores
+	}
+	return nil
+}
 
 // DetalleGrupoInvestigador represents the relationship between a group and an investigator.
 type DetalleGrupoInvestigador struct {
-	ID             int       `json:"idGrupoInvestigador" db:"id_grupo_investigador"`
-	IDGrupo        int       `json:"idGrupo" db:"idGrupo"`
-	IDInvestigador int       `json:"idInvestigador" db:"idInvestigador"`
-	Rol            string    `json:"rol" db:"rol"`
-	CreatedAt      time.Time `json:"createdAt" db:"createdAt"`
-	UpdatedAt      time.Time `json:"updatedAt" db:"updatedAt"`
+	ID             int               `json:"idGrupoInvestigador" db:"id_grupo_investigador"`
+	IDGrupo        int               `json:"idGrupo" db:"idGrupo" validate:"required,min=1"`
+	IDInvestigador int               `json:"idInvestigador" db:"idInvestigador" validate:"required,min=1"`
+	Rol            string            `json:"rol" db:"rol" validate:"notblank"`
+	FechaInicio    *calendario.Fecha `json:"fechaInicio" db:"fechaInicio" fecha:"dia"` // Start of the membership; nil if unknown
+	FechaFin       *calendario.Fecha `json:"fechaFin" db:"fechaFin" fecha:"dia"`       // Last day in the group (inclusive); nil while still a member
+	CreatedAt      time.Time         `json:"createdAt" db:"createdAt"`
+	UpdatedAt      time.Time         `json:"updatedAt" db:"updatedAt"`
+}
+
+// DetalleConGrupo is a membership together with the name of its group, as listed by
+// GET /investigadores/{id}/detalles.
+type DetalleConGrupo struct {
+	DetalleGrupoInvestigador
+	NombreGrupo string `json:"nombreGrupo"`
+}
+
+// PeriodoValido reports whether the membership does not end before it starts.
+func (d DetalleGrupoInvestigador) PeriodoValido() bool {
+	return d.FechaInicio == nil || d.FechaFin == nil || !d.FechaFin.Before(*d.FechaInicio)
+}
+
+// SeSolapaCon reports whether the periods of two memberships share a day (a nil date leaves that end
+// open). An investigator can't be a member of a group twice in overlapping periods.
+func (d DetalleGrupoInvestigador) SeSolapaCon(o DetalleGrupoInvestigador) bool {
+	empiezaAntesDelFin := d.FechaInicio == nil || o.FechaFin == nil || !o.FechaFin.Before(*d.FechaInicio)
+	terminaDespuesDelInicio := d.FechaFin == nil || o.FechaInicio == nil || !d.FechaFin.Before(*o.FechaInicio)
+	return empiezaAntesDelFin && terminaDespuesDelInicio
+}
+
+// FiltroDetalles holds the optional filters of the membership listings. Zero values mean no filter.
+type FiltroDetalles struct {
+	IDGrupo   int
+	Rol       string            // Exact role, case-insensitive
+	Nombre    string            // Partial investigator name or surname
+	Tipo      string            // Investigator's tipo (docente, estudiante or externo)
+	ActivosEn *calendario.Fecha // Memberships whose period contains this date
+
+	NoAprobados bool // Include memberships of pending or rejected groups (authenticated callers)
+}
+
+// MaxDetallesBulk is the maximum number of relations accepted by POST /detalles/bulk.
+const MaxDetallesBulk = 500
+
+// ErrorDetalleBulk is a problem with one item of POST /detalles/bulk, identified by its position
+// in the request array.
+type ErrorDetalleBulk struct {
+	Indice  int
+	Campo   string
+	Codigo  string
+	Mensaje string
+}
+
+// IntegranteRequest is the body of POST /grupos/{id}/investigadores and PUT /grupos/{id}/investigadores/{invId}.
+// IDInvestigador is ignored on PUT, where it comes from the path.
+type IntegranteRequest struct {
+	IDInvestigador int    `json:"idInvestigador" validate:"required,min=1"`
+	Rol            string `json:"rol" validate:"notblank"`
+}
+
+// CambiarCoordinadorRequest is the body of POST /grupos/{id}/coordinador.
+type CambiarCoordinadorRequest struct {
+	IDInvestigador int `json:"idInvestigador" validate:"required,min=1"`
 }
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ConteoAgrupado is the number of groups for one value of a grouping key (year, line, type...).
+type ConteoAgrupado struct {
+	Clave string `json:"clave"`
+	Total int    `json:"total"`
+}
+
+// EstadisticasGrupos holds aggregate figures about the (non-deleted) research groups.
+type EstadisticasGrupos struct {
+	TotalGrupos         int              `json:"totalGrupos"`
+	TotalInvestigadores int              `json:"totalInvestigadores"` // Distinct investigators with at least one group
+	PromedioIntegrantes float64          `json:"promedioIntegrantes"` // Average members per group
+	PorAnio             []ConteoAgrupado `json:"porAnio"`             // By year of fechaRegistro
+	PorLinea            []ConteoAgrupado `json:"porLinea"`            // By lineaInvestigacion
+	PorTipo             []ConteoAgrupado `json:"porTipo"`             // By tipoInvestigacion
+}
+
+// EstadisticasFacultad holds the figures of one facultad; groups and investigators not assigned to
+// any are reported together with a nil IDFacultad.
+type EstadisticasFacultad struct {
+	IDFacultad              *int    `json:"idFacultad"`
+	Facultad                string  `json:"facultad"`
+	Siglas                  *string `json:"siglas"`
+	TotalEscuelas           int     `json:"totalEscuelas"`
+	TotalGrupos             int     `json:"totalGrupos"`
+	TotalInvestigadores     int     `json:"totalInvestigadores"`     // Distinct investigators in the facultad's groups
+	InvestigadoresAdscritos int     `json:"investigadoresAdscritos"` // Investigators who belong to the facultad
+	PromedioIntegrantes     float64 `json:"promedioIntegrantes"`     // Average members per group
+}
+
+// Agrupaciones de GET /reportes/grupos.
+const (
+	ReportePorFacultad = "facultad"
+	ReportePorLinea    = "linea"
+	ReportePorAnio     = "año" // Year of fechaRegistro
+)
+
+// EsAgrupacionReporteValida reports whether groupBy is a known grouping of the group report.
+func EsAgrupacionReporteValida(groupBy string) bool {
+	switch groupBy {
+	case ReportePorFacultad, ReportePorLinea, ReportePorAnio:
+		return true
+	}
+	return false
+}
+
+// FilaReporteGrupos holds the figures of the (non-deleted, approved) groups sharing one value of the
+// report's grouping key, or of all of them in the report's total.
+type FilaReporteGrupos struct {
+	Clave               string  `json:"clave"`
+	TotalGrupos         int     `json:"totalGrupos"`
+	TotalIntegrantes    int     `json:"totalIntegrantes"` // Distinct investigators in those groups
+	Docentes            int     `json:"docentes"`
+	Estudiantes         int     `json:"estudiantes"`
+	Externos            int     `json:"externos"`
+	PromedioIntegrantes float64 `json:"promedioIntegrantes"` // Average members per group
+}
+
+// ReporteGrupos is an aggregated institutional report of the groups (GET /reportes/grupos).
+type ReporteGrupos struct {
+	AgrupadoPor string              `json:"agrupadoPor"` // facultad, linea or año
+	GeneradoEn  time.Time           `json:"generadoEn"`
+	Filas       []FilaReporteGrupos `json:"filas"`
+	Total       FilaReporteGrupos   `json:"total"` // Distinct counts, so not the sum of the rows when investigators are in several
+}
+
+// PlanConsulta is the execution plan of the group search (GET /admin/explain/grupos), to check
+// which indexes it uses.
+type PlanConsulta struct {
+	Consulta  string          `json:"consulta"`  // SQL that was explained
+	Analizado bool            `json:"analizado"` // EXPLAIN ANALYZE: the query was run and the plan has actual times
+	Indices   []string        `json:"indices"`   // Indexes the plan scans, in order of appearance
+	Plan      json.RawMessage `json:"plan"`      // Output of EXPLAIN (FORMAT JSON)
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Tipos de exportación asíncrona.
+const (
+	ExportGruposDetalles = "grupos_detalles" // JSON with every group and its members
+	ExportReporteGrupos  = "reporte_grupos"  // PDF report with one section per group
+)
+
+// Estados de un trabajo de exportación.
+const (
+	ExportPendiente  = "pendiente"
+	ExportEnProceso  = "en_proceso"
+	ExportCompletado = "completado"
+	ExportError      = "error"
+	ExportExpirado   = "expirado" // The file was removed by cleanup-files
+)
+
+// EsTipoExportValido reports whether tipo is a known export type.
+func EsTipoExportValido(tipo string) bool {
+	return tipo == ExportGruposDetalles || tipo == ExportReporteGrupos
+}
+
+// ParametrosExport is the body of POST /exports and the filters a job was created with.
+type ParametrosExport struct {
+	Tipo           string `json:"tipo" validate:"oneof=grupos_detalles reporte_grupos"`
+	Anios          []int  `json:"anios,omitempty"` // Registration years; all when empty
+	IncludeDeleted bool   `json:"includeDeleted,omitempty"`
+}
+
+// ExportJob is a long-running export. The generated file is kept in the storage layer and
+// downloaded from GET /exports/{id}/download once the job is completed.
+type ExportJob struct {
+	ID            int              `json:"idExport"`
+	Parametros    ParametrosExport `json:"parametros"`
+	Estado        string           `json:"estado"`
+	Total         int              `json:"total"`      // Groups to export, known once the job starts
+	Procesados    int              `json:"procesados"` // Groups exported so far
+	Archivo       *string          `json:"-"`          // Storage ref of the generated file
+	NombreArchivo string           `json:"nombreArchivo,omitempty"`
+	URL           string           `json:"url,omitempty"` // Download link, when completed
+	Error         *string          `json:"error,omitempty"`
+	CreadoPor     *int             `json:"creadoPor,omitempty"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	UpdatedAt     time.Time        `json:"updatedAt"`
+	FinalizadoEn  *time.Time       `json:"finalizadoEn,omitempty"`
+}
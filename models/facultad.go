@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Facultad is a faculty of the university. Groups and investigators belong to one, and the
+// institutional reports are broken down by them.
+type Facultad struct {
+	ID        int       `json:"idFacultad"`
+	Nombre    string    `json:"nombre" validate:"notblank,max=200"`
+	Siglas    *string   `json:"siglas" validate:"omitempty,max=20"` // Abbreviation (FI, FCS...), optional
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// FacultadConEscuelas is a faculty with its professional schools.
+type FacultadConEscuelas struct {
+	Facultad
+	Escuelas []EscuelaProfesional `json:"escuelas"`
+}
+
+// EscuelaProfesional is a professional school of a faculty. Investigators may belong to one, which
+// must be of their faculty.
+type EscuelaProfesional struct {
+	ID         int       `json:"idEscuela"`
+	IDFacultad int       `json:"idFacultad" validate:"required,min=1"`
+	Nombre     string    `json:"nombre" validate:"notblank,max=200"` // Unique within the faculty
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// GrupoFavorito is a group pinned by the current user (GET /me/favoritos).
+type GrupoFavorito struct {
+	Grupo
+	FavoritoDesde time.Time `json:"favoritoDesde"`
+}
@@ -1,22 +1,183 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+)
+
+// Estados del ciclo de vida de un grupo.
+const (
+	EstadoGrupoActivo       = "activo"
+	EstadoGrupoInactivo     = "inactivo"
+	EstadoGrupoEnRenovacion = "en_renovacion"
+	EstadoGrupoCerrado      = "cerrado"
+	EstadoGrupoPendiente    = "pendiente" // Registered by a non-admin, awaiting approval
+	EstadoGrupoRechazado    = "rechazado" // Registration rejected by an admin
+)
+
+// transicionesEstadoGrupo lists the allowed target states for each state. A closed group is final.
+// Pending and rejected groups only change through the approval endpoints.
+var transicionesEstadoGrupo = map[string][]string{
+	EstadoGrupoActivo:       {EstadoGrupoInactivo, EstadoGrupoEnRenovacion, EstadoGrupoCerrado},
+	EstadoGrupoInactivo:     {EstadoGrupoActivo, EstadoGrupoEnRenovacion, EstadoGrupoCerrado},
+	EstadoGrupoEnRenovacion: {EstadoGrupoActivo, EstadoGrupoInactivo, EstadoGrupoCerrado},
+	EstadoGrupoCerrado:      {},
+	EstadoGrupoPendiente:    {},
+	EstadoGrupoRechazado:    {},
+}
+
+// EsEstadoGrupoValido reports whether estado is a known group state.
+func EsEstadoGrupoValido(estado string) bool {
+	_, ok := transicionesEstadoGrupo[estado]
+	return ok
+}
+
+// EsGrupoAprobado reports whether a group in estado has had its registration approved, i.e. it is
+// neither pendiente nor rechazado. Only approved groups are shown to anonymous callers.
+func EsGrupoAprobado(estado string) bool {
+	return estado != EstadoGrupoPendiente && estado != EstadoGrupoRechazado
+}
+
+// PuedeTransicionarEstadoGrupo reports whether a group can move from one state to another.
+func PuedeTransicionarEstadoGrupo(desde, hacia string) bool {
+	for _, permitido := range transicionesEstadoGrupo[desde] {
+		if permitido == hacia {
+			return true
+		}
+	}
+	return false
+}
 
 // Grupo represents a research group in the database.
 type Grupo struct {
-	ID                 int       `json:"idGrupo" db:"idGrupo"`
-	Nombre             string    `json:"nombre" db:"nombre"`
-	NumeroResolucion   string    `json:"numeroResolucion" db:"numeroResolucion"`
-	LineaInvestigacion string    `json:"lineaInvestigacion" db:"lineaInvestigacion"`
-	TipoInvestigacion  string    `json:"tipoInvestigacion" db:"tipoInvestigacion"`
-	FechaRegistro      time.Time `json:"fechaRegistro" db:"fechaRegistro"`
-	Archivo            *string   `json:"archivo" db:"archivo"`
-	CreatedAt          time.Time `json:"createdAt" db:"createdAt"`
-	UpdatedAt          time.Time `json:"updatedAt" db:"updatedAt"`
+	ID                 int               `json:"idGrupo" db:"idGrupo"`
+	Nombre             string            `json:"nombre" db:"nombre" validate:"notblank"`
+	NumeroResolucion   string            `json:"numeroResolucion" db:"numeroResolucion" validate:"notblank"`
+	LineaInvestigacion string            `json:"lineaInvestigacion" db:"lineaInvestigacion" validate:"notblank"`
+	TipoInvestigacion  string            `json:"tipoInvestigacion" db:"tipoInvestigacion" validate:"notblank"`
+	FechaRegistro      calendario.Fecha  `json:"fechaRegistro" db:"fechaRegistro" fecha:"dia" validate:"required"`
+	Archivo            *string           `json:"archivo" db:"archivo" link:"file"` // Storage ref in the DB; link in responses
+	Estado             string            `json:"estado" db:"estado"`               // activo, inactivo, en_renovacion, cerrado, pendiente or rechazado
+	CreatedAt          time.Time         `json:"createdAt" db:"createdAt"`
+	UpdatedAt          time.Time         `json:"updatedAt" db:"updatedAt"`
+	DeletedAt          *time.Time        `json:"deletedAt,omitempty" db:"deletedAt"`                    // Set when the group is soft-deleted
+	IDGrupoPadre       *int              `json:"idGrupoPadre" db:"idGrupoPadre"`                        // Parent group, nil for top-level groups
+	IDFacultad         *int              `json:"idFacultad" db:"idFacultad" validate:"omitempty,min=0"` // On update: omit to keep it, 0 to remove it
+	FechaVencimiento   *calendario.Fecha `json:"fechaVencimiento" db:"fechaVencimiento" fecha:"dia"`    // Derived from its resoluciones; read-only
+	EstadoVigencia     string            `json:"estadoVigencia" db:"-"`                                 // sin_vigencia, vigente, por_vencer or vencido (see EstadoVigencia)
+}
+
+// Estados de verificación del archivo de un grupo en Google Drive.
+const (
+	ArchivoDisponible = "disponible" // The file exists in Drive
+	ArchivoRoto       = "roto"       // The group references a file that no longer exists (or is in the trash)
+	ArchivoPendiente  = "pendiente"  // No file attached yet, or it could not be verified
+)
+
+// GrupoConEstadoArchivo is a group plus the result of verifying its Drive file.
+type GrupoConEstadoArchivo struct {
+	Grupo
+	ArchivoEstado string `json:"archivoEstado"`
 }
 
 // GrupoWithInvestigadores represents a group with its associated investigators including their roles.
 type GrupoWithInvestigadores struct {
 	Grupo          Grupo                `json:"grupo"`
 	Investigadores []InvestigadorConRol `json:"investigadores"`
+	Publicaciones  []Publicacion        `json:"publicaciones,omitempty"`      // Only in GET /grupos/{id}/details
+	Proyectos      []ProyectoResumen    `json:"proyectos,omitempty"`          // Only in GET /grupos/{id}/details
+	PorTipo        map[string]int       `json:"integrantesPorTipo,omitempty"` // Only in GET /grupos/{id}/details: members of each tipo
+}
+
+// GrupoDeInvestigador is a group an investigator belongs to, with all its members (GET
+// /investigadores/{id}/grupos).
+type GrupoDeInvestigador struct {
+	Grupo       Grupo                `json:"grupo"`
+	Integrantes []InvestigadorConRol `json:"integrantes"`
+}
+
+// CambiarEstadoGrupoRequest is the body of POST /grupos/{id}/estado.
+type CambiarEstadoGrupoRequest struct {
+	Estado string `json:"estado" validate:"oneof=activo inactivo en_renovacion cerrado"`
+}
+
+// RevisarGrupoRequest is the body used by admins to approve (optional) or reject a pending group.
+type RevisarGrupoRequest struct {
+	Comentario string `json:"comentario" validate:"max=2000"` // Required to reject; kept in the audit log
+}
+
+// InvestigatorRelationshipRequest represents the investigator relationship in the combined creation request.
+type InvestigatorRelationshipRequest struct {
+	IDInvestigador int    `json:"idInvestigador" validate:"required,min=1"`
+	TipoRelacion   string `json:"tipoRelacion" validate:"notblank"`
+}
+
+// CreateGrupoWithDetailsRequest is the combined group and details creation request body.
+type CreateGrupoWithDetailsRequest struct {
+	Grupo          `json:"grupo"`
+	Investigadores []InvestigatorRelationshipRequest `json:"investigadores" validate:"dive"`
+}
+
+// GrupoDuplicado is an existing group that looks like a duplicate of another (or of a proposed) group.
+type GrupoDuplicado struct {
+	Grupo           Grupo   `json:"grupo"`
+	Similitud       float64 `json:"similitud"`       // Trigram similarity of the names (0..1)
+	MismaResolucion bool    `json:"mismaResolucion"` // Same numeroResolucion
+}
+
+// ParGruposDuplicados is a pair of existing groups flagged as likely duplicates.
+type ParGruposDuplicados struct {
+	GrupoA          Grupo   `json:"grupoA"`
+	GrupoB          Grupo   `json:"grupoB"`
+	Similitud       float64 `json:"similitud"`
+	MismaResolucion bool    `json:"mismaResolucion"`
+}
+
+// GrupoRelacionado is a group similar to another one, for the "grupos similares" section of a
+// group's public profile. Puntaje ranks the results: 3 for the same línea, 2 per shared member and
+// 1 per shared keyword.
+type GrupoRelacionado struct {
+	Grupo              Grupo `json:"grupo"`
+	Puntaje            int   `json:"puntaje"`
+	MismaLinea         bool  `json:"mismaLinea"`
+	PalabrasComunes    int   `json:"palabrasComunes"`    // Keywords (stemmed words of nombre and línea) in common
+	IntegrantesComunes int   `json:"integrantesComunes"` // Investigators in both groups
+}
+
+// CambiarGrupoPadreRequest is the body of PUT /grupos/{id}/padre. A null idGrupoPadre makes the group top-level.
+type CambiarGrupoPadreRequest struct {
+	IDGrupoPadre *int `json:"idGrupoPadre"`
+}
+
+// GrupoNodo is a group with its descendants, as returned by GET /grupos/{id}/subgrupos.
+type GrupoNodo struct {
+	Grupo     Grupo       `json:"grupo"`
+	Subgrupos []GrupoNodo `json:"subgrupos"`
+}
+
+// Tipos de resolución de un grupo.
+const (
+	ResolucionCreacion          = "creacion"
+	ResolucionRenovacion        = "renovacion"
+	ResolucionCambioIntegrantes = "cambio_integrantes"
+	ResolucionOtro              = "otro"
+)
+
+// Resolucion is a resolution issued for a group. A group accumulates several over its life
+// (creation, renewals, member changes); Grupo.numeroResolucion and Grupo.archivo keep the one it
+// was registered with.
+type Resolucion struct {
+	ID           int              `json:"idResolucion"`
+	IDGrupo      int              `json:"idGrupo"`
+	Numero       string           `json:"numero" validate:"notblank,max=100"`
+	FechaEmision calendario.Fecha `json:"fechaEmision" fecha:"dia" validate:"required"`
+	Tipo         string           `json:"tipo" validate:"oneof=creacion renovacion cambio_integrantes otro"` // creacion, renovacion, cambio_integrantes or otro
+	Descripcion  string           `json:"descripcion"`
+	Archivo      *string          `json:"archivo" link:"file"` // Storage ref of the PDF in the DB; link in responses
+	// End of the vigencia it grants; creacion and renovacion default to fechaEmision + VigenciaAnios
+	FechaVencimiento *calendario.Fecha `json:"fechaVencimiento" fecha:"dia" validate:"omitempty,gtfield=FechaEmision"`
+	SubidoPor        *int              `json:"subidoPor,omitempty" publico:"omitir"`
+	CreatedAt        time.Time         `json:"createdAt"`
+	UpdatedAt        time.Time         `json:"updatedAt"`
 }
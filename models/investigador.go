@@ -2,13 +2,36 @@ package models
 
 import "time"
 
+// Tipos de integrante: los semilleros y tesistas se registran como estudiantes.
+const (
+	TipoDocente    = "docente"
+	TipoEstudiante = "estudiante" // Needs codigoMatricula and idEscuela
+	TipoExterno    = "externo"    // From another institution
+)
+
+// EsTipoIntegranteValido reports whether tipo is a known member type.
+func EsTipoIntegranteValido(tipo string) bool {
+	switch tipo {
+	case TipoDocente, TipoEstudiante, TipoExterno:
+		return true
+	}
+	return false
+}
+
 // Investigador represents an investigator in the database.
 type Investigador struct {
-	ID        int       `json:"idInvestigador" db:"idInvestigador"`
-	Nombre    string    `json:"nombre" db:"nombre"`
-	Apellido  string    `json:"apellido" db:"apellido"`
-	CreatedAt time.Time `json:"createdAt" db:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt" db:"updatedAt"`
+	ID              int        `json:"idInvestigador" db:"idInvestigador"`
+	Nombre          string     `json:"nombre" db:"nombre" validate:"notblank"`
+	Apellido        string     `json:"apellido" db:"apellido" validate:"notblank"`
+	Email           *string    `json:"email" db:"email" publico:"omitir"`    // Optional, unique ignoring case. On update: omit to keep it, "" to remove it
+	EmailVerificado bool       `json:"emailVerificado" db:"emailVerificado"` // Read-only: set through the confirmation link
+	CreatedAt       time.Time  `json:"createdAt" db:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt" db:"updatedAt"`
+	DeletedAt       *time.Time `json:"deletedAt,omitempty" db:"deletedAt"`                                                // Set when the investigator is soft-deleted
+	IDFacultad      *int       `json:"idFacultad" db:"idFacultad" validate:"omitempty,min=0"`                             // On update: omit to keep it, 0 to remove it
+	IDEscuela       *int       `json:"idEscuela" db:"idEscuela" validate:"omitempty,min=0"`                               // Must be of idFacultad, which it fills in when omitted
+	Tipo            string     `json:"tipo" db:"tipo" validate:"omitempty,oneof=docente estudiante externo"`              // docente by default. On update: omit to keep it
+	CodigoMatricula *string    `json:"codigoMatricula" db:"codigoMatricula" validate:"omitempty,max=20" publico:"omitir"` // Students only, unique ignoring case. On update: omit to keep it, "" to remove it
 }
 
 // InvestigadorConRol represents an investigator with their specific role within a group.
@@ -16,7 +39,40 @@ type InvestigadorConRol struct {
 	ID        int       `json:"idInvestigador"`
 	Nombre    string    `json:"nombre"`
 	Apellido  string    `json:"apellido"`
-	Rol       string    `json:"rol"` // Role within the specific group
+	Tipo      string    `json:"tipo"` // docente, estudiante or externo
+	Rol       string    `json:"rol"`  // Role within the specific group
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
+
+// ResumenGruposInvestigador summarizes an investigator's group memberships (active groups only).
+type ResumenGruposInvestigador struct {
+	TotalGrupos       int `json:"totalGrupos"`
+	GruposCoordinados int `json:"gruposCoordinados"` // Groups where the investigator is 'Coordinador'
+}
+
+// InvestigadorConResumen is an investigator listed with ?include=grupos.
+type InvestigadorConResumen struct {
+	Investigador
+	ResumenGruposInvestigador
+}
+
+// RelacionGrupoInvestigador is a group membership that blocks deleting an investigator.
+type RelacionGrupoInvestigador struct {
+	IDGrupo        int    `json:"idGrupo"`
+	Nombre         string `json:"nombre"`
+	Rol            string `json:"rol"`
+	GrupoEliminado bool   `json:"grupoEliminado"` // The group is soft-deleted but could be restored
+}
+
+// FilaExportInvestigador is a row of GET /investigadores/export: one per membership, or one per
+// investigator with every group aggregated. IDGrupo, Grupo and Rol are empty for investigators
+// without groups and in aggregated rows.
+type FilaExportInvestigador struct {
+	Investigador
+	IDGrupo     *int
+	Grupo       string
+	Rol         string
+	TotalGrupos int
+	Grupos      string // Aggregated rows: "Grupo (Rol); ..."
+}
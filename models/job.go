@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+)
+
+// Estados de un job programado.
+const (
+	JobPendiente = "pendiente" // Never run
+	JobEnCurso   = "en_curso"
+	JobOK        = "ok"
+	JobError     = "error"
+)
+
+// Job is a scheduled background job with the result of its last run (GET /admin/jobs).
+type Job struct {
+	Nombre             string     `json:"nombre"`
+	Descripcion        string     `json:"descripcion"`
+	Programacion       string     `json:"programacion"` // Cron expression; empty when disabled
+	Activo             bool       `json:"activo"`
+	Estado             string     `json:"estado"`
+	SiguienteEjecucion *time.Time `json:"siguienteEjecucion"`
+	UltimaEjecucion    *time.Time `json:"ultimaEjecucion"`
+	DuracionMs         *int64     `json:"duracionMs"`
+	Detalle            string     `json:"detalle"`
+	UltimoError        *string    `json:"ultimoError"`
+	Instancia          *string    `json:"instancia"` // Instance that ran (or runs) it
+}
+
+// EstadisticaDiaria holds the statistics materialized on a day by the estadisticas job.
+type EstadisticaDiaria struct {
+	Fecha       calendario.Fecha       `json:"fecha" fecha:"dia"`
+	Grupos      EstadisticasGrupos     `json:"grupos"`
+	PorFacultad []EstadisticasFacultad `json:"porFacultad"`
+}
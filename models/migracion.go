@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Estados de la migración de un archivo.
+const (
+	MigracionCopiado    = "copiado"    // Copied to the destination, DB references not updated yet
+	MigracionCompletado = "completado" // DB references point to the destination
+	MigracionError      = "error"
+)
+
+// MigracionArchivo tracks the migration of one stored file between storage backends,
+// so an interrupted migration can resume without copying files twice.
+type MigracionArchivo struct {
+	Origen    string    `json:"origen"`  // Source storage ref
+	Destino   *string   `json:"destino"` // Destination storage ref, once copied
+	Estado    string    `json:"estado"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ProgresoMigracion reports the progress of a file migration run.
+type ProgresoMigracion struct {
+	Origen       string     `json:"origen"`  // Source backend
+	Destino      string     `json:"destino"` // Destination backend
+	EnCurso      bool       `json:"enCurso"`
+	Total        int        `json:"total"`
+	Migrados     int        `json:"migrados"`
+	Omitidos     int        `json:"omitidos"` // Already migrated in a previous run
+	Errores      int        `json:"errores"`
+	Actual       string     `json:"actual,omitempty"` // Ref being migrated
+	UltimoError  string     `json:"ultimoError,omitempty"`
+	IniciadoEn   time.Time  `json:"iniciadoEn"`
+	FinalizadoEn *time.Time `json:"finalizadoEn,omitempty"`
+}
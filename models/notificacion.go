@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Canales de entrega de una notificación.
+const (
+	CanalEmail   = "email"
+	CanalWebhook = "webhook"
+)
+
+// Estados de una notificación.
+const (
+	NotificacionPendiente = "pendiente"
+	NotificacionEnviada   = "enviada"
+	NotificacionError     = "error" // Gave up after the maximum number of attempts
+)
+
+// Eventos que generan notificaciones.
+const (
+	EventoMembresiaCreada          = "membresia_creada"
+	EventoMembresiaActualizada     = "membresia_actualizada"
+	EventoMembresiaEliminada       = "membresia_eliminada"
+	EventoVerificacionEmail        = "verificacion_email"
+	EventoRestablecerPassword      = "password_restablecer"
+	EventoVencimientoGrupo         = "vencimiento_grupo"
+	EventoRecordatorioConvocatoria = "recordatorio_convocatoria"
+	EventoSolicitudModerada        = "solicitud_moderada"
+	EventoAlerta                   = "alerta"
+	EventoResumenBusqueda          = "resumen_busqueda"
+)
+
+// Notificacion is a queued email or webhook event.
+type Notificacion struct {
+	ID               int        `json:"idNotificacion"`
+	Evento           string     `json:"evento"`
+	Canal            string     `json:"canal"`
+	Destino          string     `json:"destino"` // Email address or webhook URL
+	Asunto           string     `json:"asunto,omitempty"`
+	Cuerpo           string     `json:"cuerpo"`         // Email text or webhook JSON payload
+	HTML             string     `json:"html,omitempty"` // HTML version of an email, if any
+	Estado           string     `json:"estado"`
+	Intentos         int        `json:"intentos"`
+	UltimoError      *string    `json:"ultimoError,omitempty"`
+	SiguienteIntento time.Time  `json:"siguienteIntento"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	EnviadaEn        *time.Time `json:"enviadaEn,omitempty"`
+}
+
+// CambioMembresia describes a membership that was created, updated or deleted. It is the payload of
+// the webhook event and the data of the email templates.
+type CambioMembresia struct {
+	Evento             string                    `json:"evento"`
+	Fecha              time.Time                 `json:"fecha"`
+	IDGrupo            int                       `json:"idGrupo"`
+	NombreGrupo        string                    `json:"nombreGrupo"`
+	IDInvestigador     int                       `json:"idInvestigador"`
+	NombreInvestigador string                    `json:"nombreInvestigador"`
+	Antes              *DetalleGrupoInvestigador `json:"antes"`   // nil when the membership was created
+	Despues            *DetalleGrupoInvestigador `json:"despues"` // nil when the membership was deleted
+	IDUsuario          *int                      `json:"idUsuario,omitempty"`
+}
+
+// ContactosMembresia are the names and email addresses notified of a membership change.
+type ContactosMembresia struct {
+	NombreGrupo        string
+	NombreInvestigador string
+	EmailInvestigador  string   // Empty if the investigator has no (verified) email
+	EmailsCoordinador  []string // Coordinators of the group other than the investigator
+}
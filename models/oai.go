@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// FiltroOAI selects the records of an OAI-PMH harvest: those with a datestamp in [Desde, Hasta)
+// and an ID after DespuesDe (keyset pagination). Nil dates mean no bound.
+type FiltroOAI struct {
+	Desde     *time.Time
+	Hasta     *time.Time // Exclusive
+	DespuesDe int
+}
+
+// GrupoOAI is a group as harvested through OAI-PMH, with what its Dublin Core record shows.
+// Soft-deleted groups are harvested too, as deleted records.
+type GrupoOAI struct {
+	Grupo
+	Facultad      string    // Name of its facultad, empty if none
+	Coordinadores []string  // Current coordinator, "Apellido, Nombre"
+	Integrantes   []string  // Other current members, "Apellido, Nombre"
+	Datestamp     time.Time // Last change, including its deletion
+}
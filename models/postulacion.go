@@ -0,0 +1,103 @@
+package models
+
+import "time"
+
+// Estados de una postulación a una convocatoria.
+const (
+	PostulacionPresentado = "presentado" // Submitted, awaiting review
+	PostulacionObservado  = "observado"  // Reviewed with observations the group must address
+	PostulacionSubsanado  = "subsanado"  // The group addressed the observations, awaiting review again
+	PostulacionAprobado   = "aprobado"   // Final
+)
+
+// transicionesPostulacion lists the estados a postulacion can move to from each estado.
+var transicionesPostulacion = map[string][]string{
+	PostulacionPresentado: {PostulacionObservado, PostulacionAprobado},
+	PostulacionObservado:  {PostulacionSubsanado},
+	PostulacionSubsanado:  {PostulacionObservado, PostulacionAprobado},
+}
+
+// EsEstadoPostulacionValido reports whether estado is a known postulacion state.
+func EsEstadoPostulacionValido(estado string) bool {
+	switch estado {
+	case PostulacionPresentado, PostulacionObservado, PostulacionSubsanado, PostulacionAprobado:
+		return true
+	}
+	return false
+}
+
+// PuedeCambiarEstadoPostulacion reports whether a postulacion in estado desde can move to hacia.
+func PuedeCambiarEstadoPostulacion(desde, hacia string) bool {
+	for _, e := range transicionesPostulacion[desde] {
+		if e == hacia {
+			return true
+		}
+	}
+	return false
+}
+
+// Postulacion is a participating group's submission to a convocatoria.
+type Postulacion struct {
+	ID                  int                    `json:"idPostulacion"`
+	IDConvocatoria      int                    `json:"idConvocatoria"`
+	IDGrupo             int                    `json:"idGrupo"`
+	NombreGrupo         string                 `json:"nombreGrupo"`
+	Estado              string                 `json:"estado"`        // presentado, observado, subsanado or aprobado
+	Observaciones       string                 `json:"observaciones"` // Notes of the latest estado change
+	PresentadoPor       *int                   `json:"presentadoPor,omitempty"`
+	DocumentosFaltantes []string               `json:"documentosFaltantes"`  // Required documents not attached yet (read-only)
+	Documentos          []DocumentoPostulacion `json:"documentos,omitempty"` // Only in GET /postulaciones/{id}
+	Historial           []HistorialPostulacion `json:"historial,omitempty"`  // Only in GET /postulaciones/{id}
+	CreatedAt           time.Time              `json:"createdAt"`
+	UpdatedAt           time.Time              `json:"updatedAt"`
+}
+
+// CrearPostulacionRequest is the body of POST /convocatorias/{id}/postulaciones.
+type CrearPostulacionRequest struct {
+	IDGrupo int `json:"idGrupo" validate:"required,min=1"`
+}
+
+// CambiarEstadoPostulacionRequest is the body of PUT /postulaciones/{id}/estado.
+type CambiarEstadoPostulacionRequest struct {
+	Estado        string `json:"estado" validate:"oneof=observado subsanado aprobado"`
+	Observaciones string `json:"observaciones" validate:"required_if=Estado observado"` // Required when moving to observado
+}
+
+// HistorialPostulacion is one estado a postulacion went through.
+type HistorialPostulacion struct {
+	Estado        string    `json:"estado"`
+	Observaciones string    `json:"observaciones"`
+	IDUsuario     *int      `json:"idUsuario,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// DocumentoPostulacion is a document attached to a postulacion.
+type DocumentoPostulacion struct {
+	ID            int       `json:"idDocumento"`
+	IDPostulacion int       `json:"idPostulacion"`
+	Requisito     string    `json:"requisito"` // Required document it fulfills, "" for extra documents
+	Nombre        string    `json:"nombre" validate:"notblank"`
+	Archivo       *string   `json:"archivo" link:"file"` // Storage ref in the DB; link in responses
+	SubidoPor     *int      `json:"subidoPor,omitempty" publico:"omitir"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// RequisitoReporte counts the postulaciones that attached one required document.
+type RequisitoReporte struct {
+	Requisito   string  `json:"requisito"`
+	Presentados int     `json:"presentados"`
+	Tasa        float64 `json:"tasa"` // presentados / postulaciones
+}
+
+// ReporteConvocatoria summarizes how far the participating groups got in a convocatoria.
+type ReporteConvocatoria struct {
+	IDConvocatoria        int                `json:"idConvocatoria"`
+	GruposParticipantes   int                `json:"gruposParticipantes"`
+	Postulaciones         int                `json:"postulaciones"`
+	PorEstado             map[string]int     `json:"porEstado"`
+	DocumentacionCompleta int                `json:"documentacionCompleta"` // Postulaciones with every required document
+	TasaPostulacion       float64            `json:"tasaPostulacion"`       // postulaciones / gruposParticipantes
+	TasaDocumentacion     float64            `json:"tasaDocumentacion"`     // documentacionCompleta / postulaciones
+	TasaAprobacion        float64            `json:"tasaAprobacion"`        // aprobadas / postulaciones
+	Requisitos            []RequisitoReporte `json:"requisitos"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+)
+
+// Estados de un proyecto de investigación.
+const (
+	ProyectoPropuesto   = "propuesto"    // Submitted for funding, not started
+	ProyectoEnEjecucion = "en_ejecucion" // Running
+	ProyectoFinalizado  = "finalizado"
+	ProyectoCancelado   = "cancelado"
+)
+
+// EsEstadoProyectoValido reports whether estado is a known proyecto state.
+func EsEstadoProyectoValido(estado string) bool {
+	switch estado {
+	case ProyectoPropuesto, ProyectoEnEjecucion, ProyectoFinalizado, ProyectoCancelado:
+		return true
+	}
+	return false
+}
+
+// Proyecto is a research project carried out by a group.
+type Proyecto struct {
+	ID                   int               `json:"idProyecto"`
+	IDGrupo              int               `json:"idGrupo" validate:"required,min=1"`
+	Titulo               string            `json:"titulo" validate:"notblank,max=500"`
+	Codigo               *string           `json:"codigo" validate:"omitempty,max=50"` // Institutional or funder code, unique ignoring case
+	FuenteFinanciamiento string            `json:"fuenteFinanciamiento" validate:"max=300"`
+	Presupuesto          *float64          `json:"presupuesto" validate:"omitempty,min=0"` // In soles
+	FechaInicio          calendario.Fecha  `json:"fechaInicio" fecha:"dia" validate:"required"`
+	FechaFin             *calendario.Fecha `json:"fechaFin" fecha:"dia" validate:"omitempty,gtefield=FechaInicio"`
+	Estado               string            `json:"estado" validate:"oneof=propuesto en_ejecucion finalizado cancelado"` // propuesto, en_ejecucion, finalizado or cancelado
+	Archivos             []ProyectoArchivo `json:"archivos,omitempty"`                                                  // Only in GET /proyectos/{id}
+	CreatedAt            time.Time         `json:"createdAt"`
+	UpdatedAt            time.Time         `json:"updatedAt"`
+}
+
+// ProyectoResumen is the summary of a project embedded in a group's detail.
+type ProyectoResumen struct {
+	ID          int               `json:"idProyecto"`
+	Titulo      string            `json:"titulo"`
+	Codigo      *string           `json:"codigo"`
+	Estado      string            `json:"estado"`
+	FechaInicio calendario.Fecha  `json:"fechaInicio" fecha:"dia"`
+	FechaFin    *calendario.Fecha `json:"fechaFin" fecha:"dia"`
+}
+
+// FiltroProyectos holds the optional filters of GET /proyectos. Zero values mean no filter.
+type FiltroProyectos struct {
+	Q       string // Matches titulo, codigo or fuenteFinanciamiento
+	IDGrupo int
+	Estado  string
+}
+
+// ProyectoArchivo is a file attached to a project.
+type ProyectoArchivo struct {
+	ID         int       `json:"idArchivo"`
+	IDProyecto int       `json:"idProyecto"`
+	Nombre     string    `json:"nombre" validate:"notblank,max=200"`
+	Archivo    *string   `json:"archivo" link:"file" publico:"omitir"` // Storage ref in the DB; link in responses (nil for anonymous callers)
+	SubidoPor  *int      `json:"subidoPor,omitempty" publico:"omitir"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
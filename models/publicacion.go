@@ -0,0 +1,88 @@
+package models
+
+import "time"
+
+// Tipos de publicación.
+const (
+	PublicacionArticulo = "articulo"
+	PublicacionLibro    = "libro"
+	PublicacionCapitulo = "capitulo"
+	PublicacionPonencia = "ponencia"
+	PublicacionTesis    = "tesis"
+	PublicacionOtro     = "otro"
+)
+
+// EsTipoPublicacionValido reports whether tipo is a known publication type.
+func EsTipoPublicacionValido(tipo string) bool {
+	switch tipo {
+	case PublicacionArticulo, PublicacionLibro, PublicacionCapitulo, PublicacionPonencia, PublicacionTesis, PublicacionOtro:
+		return true
+	}
+	return false
+}
+
+// Publicacion is a research output credited to investigators and groups.
+type Publicacion struct {
+	ID               int       `json:"idPublicacion"`
+	Titulo           string    `json:"titulo" validate:"notblank,max=500"`
+	DOI              *string   `json:"doi"` // Bare DOI (10.xxxx/...), unique ignoring case
+	Revista          string    `json:"revista" validate:"max=300"`
+	Anio             int       `json:"anio" validate:"required,anio"`
+	Tipo             string    `json:"tipo" validate:"oneof=articulo libro capitulo ponencia tesis otro"` // articulo, libro, capitulo, ponencia, tesis or otro
+	IDInvestigadores []int     `json:"idInvestigadores"`                                                  // Authors; replaced as a whole on update
+	IDGrupos         []int     `json:"idGrupos"`                                                          // Credited groups; replaced as a whole on update
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// FiltroPublicaciones holds the optional filters of GET /publicaciones. Zero values mean no filter.
+type FiltroPublicaciones struct {
+	Q              string // Matches titulo or revista
+	Anio           int
+	Tipo           string
+	IDGrupo        int
+	IDInvestigador int
+}
+
+// PublicacionConAutores is a publicacion with its authors' names, as harvested through OAI-PMH or
+// exported to BibTeX.
+type PublicacionConAutores struct {
+	Publicacion
+	Autores []string // "Apellido, Nombre"
+}
+
+// PublicacionReconciliada is the outcome of merging an external publicacion into the local ones
+// (see repository.ReconciliarPublicaciones).
+type PublicacionReconciliada struct {
+	ID        int
+	Creada    bool // Otherwise it matched an existing one
+	Vinculada bool // The existing one got new authors or groups
+}
+
+// ResultadoImportPublicaciones is the outcome of importing a BibTeX file: counts and what was done
+// with each entry, in file order.
+type ResultadoImportPublicaciones struct {
+	Creadas    int                `json:"creadas"`
+	Duplicadas int                `json:"duplicadas"`
+	Omitidas   int                `json:"omitidas"`
+	Entradas   []EntradaImportada `json:"entradas"`
+}
+
+// Estados of an imported entry.
+const (
+	EntradaCreada    = "creada"
+	EntradaDuplicada = "duplicada" // Matched an existing publicacion by DOI or titulo and anio
+	EntradaOmitida   = "omitida"
+)
+
+// EntradaImportada is what an import did with one BibTeX entry. IDInvestigadores are the authors
+// matched to investigators by name; Motivo says why an entry was omitted.
+type EntradaImportada struct {
+	Clave            string `json:"clave"`
+	Linea            int    `json:"linea"`
+	Estado           string `json:"estado"`
+	IDPublicacion    *int   `json:"idPublicacion,omitempty"`
+	Titulo           string `json:"titulo"`
+	IDInvestigadores []int  `json:"idInvestigadores,omitempty"`
+	Motivo           string `json:"motivo,omitempty"`
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+)
+
+// Estados de vigencia de un grupo, según Grupo.FechaVencimiento.
+const (
+	VigenciaSinVigencia = "sin_vigencia" // No resolution grants it a fechaVencimiento
+	VigenciaVigente     = "vigente"
+	VigenciaPorVencer   = "por_vencer" // Expires within DiasAvisoVencimiento days
+	VigenciaVencido     = "vencido"
+)
+
+// defaultVigenciaAnios and defaultDiasAvisoVencimiento apply unless GRUPO_VIGENCIA_ANIOS and
+// GRUPO_AVISO_VENCIMIENTO_DIAS say otherwise.
+const (
+	defaultVigenciaAnios        = 2
+	defaultDiasAvisoVencimiento = 60
+)
+
+// VigenciaAnios is how many years a creacion or renovacion resolution keeps a group vigente when it
+// does not say (GRUPO_VIGENCIA_ANIOS, default 2).
+func VigenciaAnios() int {
+	return enteroPositivoEnv("GRUPO_VIGENCIA_ANIOS", defaultVigenciaAnios)
+}
+
+// DiasAvisoVencimiento is how many days before its fechaVencimiento a group is por_vencer
+// (GRUPO_AVISO_VENCIMIENTO_DIAS, default 60).
+func DiasAvisoVencimiento() int {
+	return enteroPositivoEnv("GRUPO_AVISO_VENCIMIENTO_DIAS", defaultDiasAvisoVencimiento)
+}
+
+// enteroPositivoEnv reads a positive integer from the environment variable name, or returns def if
+// it is unset or not valid.
+func enteroPositivoEnv(name string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+// EsEstadoVigenciaValido reports whether estado is a known vigencia state.
+func EsEstadoVigenciaValido(estado string) bool {
+	switch estado {
+	case VigenciaSinVigencia, VigenciaVigente, VigenciaPorVencer, VigenciaVencido:
+		return true
+	}
+	return false
+}
+
+// EstadoVigencia returns the vigencia state of a group that expires on fechaVencimiento (nil for
+// none). A group is still vigente on its fechaVencimiento.
+func EstadoVigencia(fechaVencimiento *calendario.Fecha) string {
+	if fechaVencimiento == nil {
+		return VigenciaSinVigencia
+	}
+	hoy := calendario.Hoy()
+	switch {
+	case fechaVencimiento.Before(hoy):
+		return VigenciaVencido
+	case fechaVencimiento.Before(hoy.AddDate(0, 0, DiasAvisoVencimiento())):
+		return VigenciaPorVencer
+	}
+	return VigenciaVigente
+}
+
+// Estados de una solicitud de renovación.
+const (
+	RenovacionPendiente = "pendiente"
+	RenovacionAprobada  = "aprobada"
+	RenovacionRechazada = "rechazada"
+)
+
+// Renovacion is a request to renew the vigencia of a group. Submitting it moves the group to
+// en_renovacion; approving it attaches the renewal resolution and makes the group activo again,
+// and rejecting it restores EstadoAnterior.
+type Renovacion struct {
+	ID             int        `json:"idRenovacion"`
+	IDGrupo        int        `json:"idGrupo"`
+	NombreGrupo    string     `json:"nombreGrupo,omitempty"` // Only in GET /renovaciones
+	Estado         string     `json:"estado"`                // pendiente, aprobada or rechazada
+	Motivo         string     `json:"motivo" validate:"max=2000"`
+	Observaciones  string     `json:"observaciones"` // Reviewer's notes
+	EstadoAnterior string     `json:"estadoAnterior"`
+	IDResolucion   *int       `json:"idResolucion"` // Renewal resolution, once approved
+	SolicitadoPor  *int       `json:"solicitadoPor,omitempty"`
+	RevisadoPor    *int       `json:"revisadoPor,omitempty"`
+	FechaRevision  *time.Time `json:"fechaRevision"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// SolicitarRenovacionRequest is the body of POST /grupos/{id}/renovaciones.
+type SolicitarRenovacionRequest struct {
+	Motivo string `json:"motivo" validate:"max=2000"`
+}
+
+// RechazarRenovacionRequest is the body of POST /renovaciones/{id}/rechazar.
+type RechazarRenovacionRequest struct {
+	Observaciones string `json:"observaciones" validate:"notblank,max=2000"`
+}
+
+// AvisoVencimiento is a group whose coordinators are due a notice about its fechaVencimiento.
+type AvisoVencimiento struct {
+	IDGrupo          int
+	NombreGrupo      string
+	FechaVencimiento calendario.Fecha
+	Emails           []string // Coordinators' emails
+}
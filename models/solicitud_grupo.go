@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Estados posibles de una solicitud de registro de grupo.
+const (
+	SolicitudPendiente = "pendiente"
+	SolicitudAprobada  = "aprobada"
+	SolicitudRechazada = "rechazada"
+)
+
+// IntegranteSolicitud represents a proposed member listed in a group registration request.
+type IntegranteSolicitud struct {
+	IDInvestigador *int   `json:"idInvestigador,omitempty"` // Optional: existing investigator
+	Nombre         string `json:"nombre" validate:"required_without=IDInvestigador"`
+	Apellido       string `json:"apellido" validate:"required_without=IDInvestigador"`
+	Rol            string `json:"rol"`
+}
+
+// SolicitudGrupo represents a public group registration request waiting for moderation.
+type SolicitudGrupo struct {
+	ID                  int                   `json:"idSolicitud" db:"idSolicitud"`
+	Nombre              string                `json:"nombre" db:"nombre" validate:"notblank"`
+	NumeroResolucion    string                `json:"numeroResolucion" db:"numeroResolucion"`
+	LineaInvestigacion  string                `json:"lineaInvestigacion" db:"lineaInvestigacion" validate:"notblank"`
+	TipoInvestigacion   string                `json:"tipoInvestigacion" db:"tipoInvestigacion" validate:"notblank"`
+	Descripcion         string                `json:"descripcion" db:"descripcion"`
+	NombreSolicitante   string                `json:"nombreSolicitante" db:"nombreSolicitante" validate:"notblank"`
+	ApellidoSolicitante string                `json:"apellidoSolicitante" db:"apellidoSolicitante" validate:"notblank"`
+	EmailSolicitante    string                `json:"emailSolicitante" db:"emailSolicitante" validate:"required,email"`
+	Integrantes         []IntegranteSolicitud `json:"integrantes" db:"integrantes" validate:"dive"`
+	Estado              string                `json:"estado" db:"estado"`
+	Comentario          *string               `json:"comentario" db:"comentario"`   // Moderator comments
+	IDGrupo             *int                  `json:"idGrupo" db:"idGrupo"`         // Group created on approval
+	RevisadoPor         *int                  `json:"revisadoPor" db:"revisadoPor"` // Admin user who moderated it
+	FechaRevision       *time.Time            `json:"fechaRevision" db:"fechaRevision"`
+	CreatedAt           time.Time             `json:"createdAt" db:"createdAt"`
+	UpdatedAt           time.Time             `json:"updatedAt" db:"updatedAt"`
+}
+
+// CreateSolicitudGrupoRequest is the public intake form body, including the captcha token.
+type CreateSolicitudGrupoRequest struct {
+	SolicitudGrupo
+	CaptchaToken string `json:"captchaToken"`
+}
+
+// ModerarSolicitudRequest is the body used by admins to approve or reject a request.
+type ModerarSolicitudRequest struct {
+	Comentario       string `json:"comentario"`
+	NumeroResolucion string `json:"numeroResolucion"`                                       // Optional on approval: overrides the proposed value
+	FechaRegistro    string `json:"fechaRegistro" validate:"omitempty,datetime=2006-01-02"` // Optional on approval (YYYY-MM-DD), defaults to today
+}
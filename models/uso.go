@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// Intervalos de GET /admin/usage.
+const (
+	IntervaloHora = "hora"
+	IntervaloDia  = "dia"
+)
+
+// UsoAPI is the usage of a route by a client during an hour: a user, or an anonymous caller by IP.
+type UsoAPI struct {
+	Inicio     time.Time // Start of the hour
+	Metodo     string
+	Ruta       string // Route template, e.g. /grupos/{id:[0-9]+}
+	IDUsuario  *int   // nil for anonymous callers
+	Email      string // Of the user, if it still exists
+	IP         string // Only for anonymous callers
+	Peticiones int64
+	Errores    int64 // Responses with status 400 or higher
+	DuracionMs int64 // Total
+}
+
+// EstadisticaUso are the totals of a set of requests.
+type EstadisticaUso struct {
+	Peticiones      int64   `json:"peticiones"`
+	Errores         int64   `json:"errores"`
+	DuracionMediaMs float64 `json:"duracionMediaMs"`
+}
+
+// PuntoUso is a time bucket of a usage series.
+type PuntoUso struct {
+	Inicio time.Time `json:"inicio"`
+	EstadisticaUso
+}
+
+// UsoRuta is the usage of a route.
+type UsoRuta struct {
+	Metodo string `json:"metodo"`
+	Ruta   string `json:"ruta"`
+	EstadisticaUso
+}
+
+// UsoCliente is the usage of a client: a user, or an anonymous caller by IP.
+type UsoCliente struct {
+	IDUsuario *int   `json:"idUsuario"`
+	Email     string `json:"email,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	EstadisticaUso
+	Rutas []UsoRuta  `json:"rutas"` // Busiest first
+	Serie []PuntoUso `json:"serie"`
+}
+
+// ReporteUso is the response of GET /admin/usage.
+type ReporteUso struct {
+	Desde     time.Time      `json:"desde"`
+	Hasta     time.Time      `json:"hasta"`
+	Intervalo string         `json:"intervalo"`
+	Total     EstadisticaUso `json:"total"`
+	Serie     []PuntoUso     `json:"serie"`
+	Rutas     []UsoRuta      `json:"rutas"`    // Busiest first
+	Clientes  []UsoCliente   `json:"clientes"` // Busiest first, up to ?top
+}
@@ -2,17 +2,36 @@ package models
 
 import "time"
 
+// Roles de aplicación de un usuario.
+const (
+	RolUsuario = "usuario"
+	RolAdmin   = "admin"
+)
+
 // Usuario represents a user in the application database.
 type Usuario struct {
 	ID        int       `json:"idUsuario" db:"idusuario"` // Use lowercase db tag
 	Email     string    `json:"email" db:"email"`
 	Password  string    `json:"-" db:"password"` // Exclude password hash from JSON responses
+	Rol       string    `json:"rol" db:"rol"`    // Application role: "usuario" or "admin"
 	CreatedAt time.Time `json:"createdAt" db:"created_at"`
 	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
 }
 
 // Credentials represents the data needed for login.
 type Credentials struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// SolicitudRestablecimientoPassword is the body of POST /password/forgot.
+type SolicitudRestablecimientoPassword struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// RestablecimientoPassword is the body of POST /password/reset: the token of the emailed link and
+// the new password.
+type RestablecimientoPassword struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,max=72"` // bcrypt only uses 72 bytes
 }
@@ -0,0 +1,232 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/quotedprintable"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Proveedores de correo (EMAIL_PROVIDER).
+const (
+	ProveedorSMTP     = "smtp"
+	ProveedorSendGrid = "sendgrid"
+)
+
+// defaultSendGridURL is the SendGrid API; SENDGRID_API_URL changes it, e.g. to the EU region.
+const defaultSendGridURL = "https://api.sendgrid.com"
+
+var sendgridClient = &http.Client{Timeout: 15 * time.Second}
+
+// Email is an email to one recipient. HTML is optional: when set the email carries both versions
+// and the client chooses.
+type Email struct {
+	Para   string
+	Asunto string
+	Texto  string
+	HTML   string
+}
+
+// Proveedor delivers emails.
+type Proveedor interface {
+	Enviar(ctx context.Context, e Email) error
+}
+
+// ProveedorActual returns the provider selected by EMAIL_PROVIDER ("smtp" by default, or
+// "sendgrid") and whether it is configured. An unconfigured SMTP provider only logs the emails, so
+// development setups keep working.
+func ProveedorActual() (nombre string, configurado bool, err error) {
+	nombre = strings.ToLower(strings.TrimSpace(os.Getenv("EMAIL_PROVIDER")))
+	switch nombre {
+	case "", ProveedorSMTP:
+		return ProveedorSMTP, os.Getenv("SMTP_HOST") != "", nil
+	case ProveedorSendGrid:
+		return ProveedorSendGrid, os.Getenv("SENDGRID_API_KEY") != "", nil
+	}
+	return nombre, false, fmt.Errorf("unknown EMAIL_PROVIDER %q: use smtp or sendgrid", nombre)
+}
+
+func proveedor() (Proveedor, error) {
+	nombre, configurado, err := ProveedorActual()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case nombre == ProveedorSendGrid && !configurado:
+		return nil, fmt.Errorf("EMAIL_PROVIDER is sendgrid but SENDGRID_API_KEY is not set")
+	case nombre == ProveedorSendGrid:
+		return sendgridProveedor{}, nil
+	case !configurado:
+		return logProveedor{}, nil
+	}
+	return smtpProveedor{}, nil
+}
+
+// EnviarEmail sends an email right away through the configured provider. Emails that must survive
+// a failure of the provider are queued instead (see EnqueueEmail).
+func EnviarEmail(ctx context.Context, e Email) error {
+	p, err := proveedor()
+	if err != nil {
+		return err
+	}
+	if err := p.Enviar(ctx, e); err != nil {
+		return fmt.Errorf("error sending email to %s: %w", e.Para, err)
+	}
+	return nil
+}
+
+// remitente is the sender address: EMAIL_FROM, SMTP_FROM or SMTP_USER.
+func remitente() string {
+	for _, v := range []string{"EMAIL_FROM", "SMTP_FROM", "SMTP_USER"} {
+		if from := os.Getenv(v); from != "" {
+			return from
+		}
+	}
+	return ""
+}
+
+// logProveedor only logs emails; it is used when SMTP_HOST is not set.
+type logProveedor struct{}
+
+func (logProveedor) Enviar(ctx context.Context, e Email) error {
+	slog.Info("SMTP_HOST not set, email not sent", "to", e.Para, "subject", e.Asunto)
+	return nil
+}
+
+// smtpProveedor sends through SMTP_HOST:SMTP_PORT (587 by default), authenticating with SMTP_USER
+// and SMTP_PASSWORD when set.
+type smtpProveedor struct{}
+
+func (smtpProveedor) Enviar(ctx context.Context, e Email) error {
+	host := os.Getenv("SMTP_HOST")
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	var auth smtp.Auth
+	if user := os.Getenv("SMTP_USER"); user != "" {
+		auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	from := remitente()
+	msg, err := mensajeMIME(from, e)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(host+":"+port, auth, from, []string{e.Para}, msg)
+}
+
+// mensajeMIME builds the message: plain text, or multipart/alternative with the HTML version.
+func mensajeMIME(from string, e Email) ([]byte, error) {
+	var b bytes.Buffer
+	for _, h := range [][2]string{
+		{"From", from},
+		{"To", e.Para},
+		{"Subject", mime.QEncoding.Encode("utf-8", e.Asunto)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+	} {
+		fmt.Fprintf(&b, "%s: %s\r\n", h[0], h[1])
+	}
+	if e.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := escribirQP(&b, e.Texto); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+	limite := make([]byte, 12)
+	if _, err := rand.Read(limite); err != nil {
+		return nil, fmt.Errorf("error generating MIME boundary: %w", err)
+	}
+	separador := "apigrupos-" + hex.EncodeToString(limite)
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", separador)
+	for _, parte := range [][2]string{{"text/plain", e.Texto}, {"text/html", e.HTML}} {
+		fmt.Fprintf(&b, "--%s\r\nContent-Type: %s; charset=UTF-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n", separador, parte[0])
+		if err := escribirQP(&b, parte[1]); err != nil {
+			return nil, err
+		}
+		b.WriteString("\r\n")
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", separador)
+	return b.Bytes(), nil
+}
+
+func escribirQP(w io.Writer, s string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, strings.ReplaceAll(s, "\n", "\r\n")); err != nil {
+		return fmt.Errorf("error encoding email body: %w", err)
+	}
+	return qp.Close()
+}
+
+// sendgridProveedor sends through the SendGrid v3 API with SENDGRID_API_KEY.
+type sendgridProveedor struct{}
+
+// sendgridMail is the body of POST /v3/mail/send.
+type sendgridMail struct {
+	Personalizations []sendgridDestinatarios `json:"personalizations"`
+	From             sendgridDireccion       `json:"from"`
+	Subject          string                  `json:"subject"`
+	Content          []sendgridContenido     `json:"content"`
+}
+
+type sendgridDestinatarios struct {
+	To []sendgridDireccion `json:"to"`
+}
+
+type sendgridDireccion struct {
+	Email string `json:"email"`
+}
+
+type sendgridContenido struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func (sendgridProveedor) Enviar(ctx context.Context, e Email) error {
+	cuerpo := sendgridMail{
+		Personalizations: []sendgridDestinatarios{{To: []sendgridDireccion{{e.Para}}}},
+		From:             sendgridDireccion{remitente()},
+		Subject:          e.Asunto,
+		Content:          []sendgridContenido{{"text/plain", e.Texto}},
+	}
+	if e.HTML != "" {
+		cuerpo.Content = append(cuerpo.Content, sendgridContenido{"text/html", e.HTML})
+	}
+	payload, err := json.Marshal(cuerpo)
+	if err != nil {
+		return fmt.Errorf("error encoding SendGrid request: %w", err)
+	}
+
+	base := strings.TrimRight(os.Getenv("SENDGRID_API_URL"), "/")
+	if base == "" {
+		base = defaultSendGridURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/v3/mail/send", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("error creating SendGrid request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("SENDGRID_API_KEY"))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := sendgridClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling SendGrid: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detalle, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("SendGrid responded %s: %s", resp.Status, strings.TrimSpace(string(detalle)))
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+// Package notifier sends the emails of the registry and tells the people involved about changes
+// that affect them, such as an investigator added to or removed from a group, by email and through
+// a webhook.
+//
+// Emails go out through SMTP or SendGrid, as EMAIL_PROVIDER says (see EnviarEmail). Those about
+// an event (membership changes, email verification, password reset, group renewal reminders) are
+// queued in the notificacion table and delivered in the background by Start, so they are not lost
+// if the provider or the webhook is down: failed deliveries are retried with an increasing delay up
+// to maxIntentos times. Their text and HTML versions come from templates (templates/, embedded in
+// the binary), which NOTIFICATIONS_TEMPLATES_DIR can override. NOTIFICATIONS_WEBHOOK_URL receives
+// every membership event as a JSON POST, signed with NOTIFICATIONS_WEBHOOK_SECRET when set.
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/background"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+)
+
+const (
+	intervalo   = time.Minute      // How often the queue is checked when nothing wakes the worker
+	loteMaximo  = 50               // Notifications claimed per query
+	lease       = 5 * time.Minute  // Time a claimed notification is reserved for the instance sending it
+	maxIntentos = 6                // Deliveries attempted before giving up
+	maxEspera   = 6 * time.Hour    // Longest delay between two attempts
+	esperaBase  = 30 * time.Second // Delay after the first failure, doubled on every attempt
+)
+
+var (
+	webhookClient = &http.Client{Timeout: 10 * time.Second}
+	// despertar wakes the worker when notifications are queued, so they go out right away
+	despertar = make(chan struct{}, 1)
+)
+
+// EnqueueCambioMembresia queues the notifications of a membership change: an email to the
+// investigator and to the group's coordinators, and the webhook event. With soloVerificados only
+// verified emails are used. Recipients without an email are skipped.
+func EnqueueCambioMembresia(ctx context.Context, db *sql.DB, cambio models.CambioMembresia, soloVerificados bool) error {
+	contactos, err := repository.GetContactosMembresia(ctx, db, cambio.IDGrupo, cambio.IDInvestigador, soloVerificados)
+	if err != nil {
+		return err
+	}
+	if contactos == nil {
+		return nil // The group or the investigator no longer exists
+	}
+	cambio.NombreGrupo = contactos.NombreGrupo
+	cambio.NombreInvestigador = contactos.NombreInvestigador
+	if cambio.Fecha.IsZero() {
+		cambio.Fecha = time.Now()
+	}
+
+	var notificaciones []models.Notificacion
+	addEmail := func(to string, paraInvestigador bool) error {
+		n, err := email(cambio.Evento, to, datosPlantilla{CambioMembresia: cambio, ParaInvestigador: paraInvestigador})
+		if err != nil {
+			return err
+		}
+		notificaciones = append(notificaciones, n)
+		return nil
+	}
+	if contactos.EmailInvestigador != "" {
+		if err := addEmail(contactos.EmailInvestigador, true); err != nil {
+			return err
+		}
+	}
+	for _, to := range contactos.EmailsCoordinador {
+		if err := addEmail(to, false); err != nil {
+			return err
+		}
+	}
+	if url := os.Getenv("NOTIFICATIONS_WEBHOOK_URL"); url != "" {
+		payload, err := json.Marshal(cambio)
+		if err != nil {
+			return fmt.Errorf("error encoding %s event: %w", cambio.Evento, err)
+		}
+		notificaciones = append(notificaciones, models.Notificacion{Evento: cambio.Evento, Canal: models.CanalWebhook, Destino: url, Cuerpo: string(payload)})
+	}
+
+	return encolar(ctx, db, notificaciones)
+}
+
+// EnqueueEmail queues the email about evento, rendered from its templates with datos, for each
+// address in para: one of the VerificacionEmail, RestablecerPassword, VencimientoGrupo,
+// RecordatorioConvocatoria, SolicitudModerada, Alerta or ResumenBusqueda types.
+func EnqueueEmail(ctx context.Context, db *sql.DB, evento string, para []string, datos any) error {
+	notificaciones := make([]models.Notificacion, 0, len(para))
+	for _, to := range para {
+		n, err := email(evento, to, datos)
+		if err != nil {
+			return err
+		}
+		notificaciones = append(notificaciones, n)
+	}
+	return encolar(ctx, db, notificaciones)
+}
+
+// email renders the email about evento for one recipient.
+func email(evento, to string, datos any) (models.Notificacion, error) {
+	asunto, texto, html, err := renderEmail(evento, datos)
+	if err != nil {
+		return models.Notificacion{}, err
+	}
+	return models.Notificacion{Evento: evento, Canal: models.CanalEmail, Destino: to, Asunto: asunto, Cuerpo: texto, HTML: html}, nil
+}
+
+// encolar stores the notifications and wakes the worker to deliver them.
+func encolar(ctx context.Context, db *sql.DB, notificaciones []models.Notificacion) error {
+	if err := repository.CreateNotificaciones(ctx, db, notificaciones); err != nil {
+		return err
+	}
+	if len(notificaciones) > 0 {
+		select {
+		case despertar <- struct{}{}:
+		default: // Already awake
+		}
+	}
+	return nil
+}
+
+// Start delivers queued notifications in the background until ctx is done.
+func Start(ctx context.Context, db *sql.DB) {
+	background.Go(func() {
+		ticker := time.NewTicker(intervalo)
+		defer ticker.Stop()
+		for {
+			enviarPendientes(ctx, db)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-despertar:
+			}
+		}
+	})
+}
+
+// enviarPendientes delivers the notifications that are due, batch by batch.
+func enviarPendientes(ctx context.Context, db *sql.DB) {
+	for {
+		notificaciones, err := repository.ClaimNotificaciones(ctx, db, loteMaximo, lease)
+		if err != nil {
+			logging.FromContext(ctx).Error("Error getting pending notifications", "error", err)
+			return
+		}
+		for _, n := range notificaciones {
+			if err := enviar(ctx, n); err != nil {
+				final := n.Intentos >= maxIntentos
+				logging.FromContext(ctx).Error("Error sending notification", "id", n.ID, "canal", n.Canal, "destino", n.Destino, "intentos", n.Intentos, "error", err)
+				if err := repository.MarkNotificacionFallida(ctx, db, n.ID, err.Error(), time.Now().Add(espera(n.Intentos)), final); err != nil {
+					logging.FromContext(ctx).Error("Error recording failure of notification", "id", n.ID, "error", err)
+				}
+				continue
+			}
+			if err := repository.MarkNotificacionEnviada(ctx, db, n.ID); err != nil {
+				logging.FromContext(ctx).Error("Error recording delivery of notification", "id", n.ID, "error", err)
+			}
+		}
+		if len(notificaciones) < loteMaximo {
+			return
+		}
+	}
+}
+
+// espera is the delay before retrying after the given number of failed attempts.
+func espera(intentos int) time.Duration {
+	d := esperaBase
+	for i := 1; i < intentos && d < maxEspera; i++ {
+		d *= 2
+	}
+	return min(d, maxEspera)
+}
+
+func enviar(ctx context.Context, n models.Notificacion) error {
+	switch n.Canal {
+	case models.CanalEmail:
+		return EnviarEmail(ctx, Email{Para: n.Destino, Asunto: n.Asunto, Texto: n.Cuerpo, HTML: n.HTML})
+	case models.CanalWebhook:
+		return postWebhook(n)
+	}
+	return fmt.Errorf("unknown notification channel %q", n.Canal)
+}
+
+// postWebhook sends the event. The receiver can use X-ApiGrupos-Notificacion to ignore repeated
+// deliveries and, with a shared secret, check X-ApiGrupos-Firma (hex HMAC-SHA256 of the body).
+func postWebhook(n models.Notificacion) error {
+	req, err := http.NewRequest(http.MethodPost, n.Destino, bytes.NewReader([]byte(n.Cuerpo)))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-ApiGrupos-Evento", n.Evento)
+	req.Header.Set("X-ApiGrupos-Notificacion", fmt.Sprint(n.ID))
+	if secret := os.Getenv("NOTIFICATIONS_WEBHOOK_SECRET"); secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(n.Cuerpo))
+		req.Header.Set("X-ApiGrupos-Firma", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package notifier
+
+import (
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// defaultTemplates holds two templates per event: <evento>.tmpl, defining "asunto" and "cuerpo"
+// (the plain-text version) with text/template, and <evento>.html, defining "contenido" (the HTML
+// version) with html/template. comun.tmpl is shared by the text ones and base.html, the layout
+// around "contenido", by the HTML ones. Both receive the same data.
+//
+//go:embed templates/*.tmpl templates/*.html
+var defaultTemplates embed.FS
+
+// eventosEmail are the events sent by email, each with its templates.
+var eventosEmail = []string{
+	models.EventoMembresiaCreada, models.EventoMembresiaActualizada, models.EventoMembresiaEliminada,
+	models.EventoVerificacionEmail, models.EventoRestablecerPassword, models.EventoVencimientoGrupo,
+	models.EventoRecordatorioConvocatoria, models.EventoSolicitudModerada, models.EventoAlerta,
+	models.EventoResumenBusqueda,
+}
+
+// plantilla is the text and HTML templates of an event.
+type plantilla struct {
+	texto *template.Template
+	html  *htmltemplate.Template
+}
+
+var (
+	plantillasOnce sync.Once
+	plantillas     map[string]plantilla
+)
+
+// fecha formats an optional date, e.g. the start or end of a membership.
+func fecha(t *calendario.Fecha) string {
+	if t == nil {
+		return "sin definir"
+	}
+	return t.Format("02/01/2006")
+}
+
+var templateFuncs = template.FuncMap{"fecha": fecha}
+
+var htmlTemplateFuncs = htmltemplate.FuncMap{
+	"fecha": fecha,
+	// enlace is the argument of base.html's "enlace" template: a button to url and the url in full
+	"enlace": func(url, texto string) map[string]string {
+		return map[string]string{"Enlace": url, "Texto": texto}
+	},
+}
+
+// datosPlantilla is the data of a membership email template.
+type datosPlantilla struct {
+	models.CambioMembresia
+	ParaInvestigador bool // The recipient is the investigator whose membership changed, not a coordinator
+}
+
+// VerificacionEmail is the data of the email that confirms an investigator's email address.
+type VerificacionEmail struct {
+	Nombre string
+	Enlace string
+	Horas  int // Validity of the link
+}
+
+// RestablecerPassword is the data of the email with a password reset link.
+type RestablecerPassword struct {
+	Email  string
+	Enlace string
+	Horas  int // Validity of the link
+}
+
+// VencimientoGrupo is the data of the renewal reminder sent to a group's coordinators.
+type VencimientoGrupo struct {
+	NombreGrupo      string
+	FechaVencimiento calendario.Fecha
+	Vencido          bool // Already expired, rather than about to
+}
+
+// RecordatorioConvocatoria is the data of the reminder sent to a group's coordinators as the
+// deadline of a convocatoria it participates in approaches.
+type RecordatorioConvocatoria struct {
+	NombreGrupo        string
+	NombreConvocatoria string
+	FechaCierre        calendario.Fecha
+	Dias               int // Days left until FechaCierre; 0 on the day itself
+	Requisitos         string
+}
+
+// SolicitudModerada is the data of the email that tells the requester of a group registration the
+// outcome of its moderation.
+type SolicitudModerada struct {
+	NombreSolicitante string
+	NombreGrupo       string
+	Aprobada          bool
+	Comentario        string // The moderator's comments, if any
+}
+
+// Alerta is the data of an operational alert emailed to the administrators (see package alerts).
+type Alerta struct {
+	Tipo    string
+	Mensaje string
+	Valor   float64
+	Umbral  float64
+	Fecha   time.Time
+}
+
+// ResumenBusqueda is the data of the weekly digest of a saved search with alerts.
+type ResumenBusqueda struct {
+	NombreBusqueda string
+	Desde          time.Time // Date of the previous digest, or of the search if there was none
+	Grupos         []GrupoCambiado
+}
+
+// GrupoCambiado is a group of a ResumenBusqueda.
+type GrupoCambiado struct {
+	Nombre string
+	Nuevo  bool   // Created, rather than changed, since the previous digest
+	Enlace string // Its page under PUBLIC_BASE_URL; empty without it
+}
+
+// getPlantillas loads the templates once. A template missing from NOTIFICATIONS_TEMPLATES_DIR, or
+// one that does not parse, falls back to the embedded one; the text and HTML versions of an event
+// are replaced independently.
+func getPlantillas() map[string]plantilla {
+	plantillasOnce.Do(func() {
+		plantillas = map[string]plantilla{}
+		var override fs.FS
+		if dir := os.Getenv("NOTIFICATIONS_TEMPLATES_DIR"); dir != "" {
+			override = os.DirFS(dir)
+		}
+		for _, evento := range eventosEmail {
+			var p plantilla
+			if override != nil {
+				var err error
+				if p.texto, err = parsePlantilla(override, ".", evento); err != nil {
+					slog.Warn("Using the default notification template", "evento", evento, "error", err)
+				}
+				if p.html, err = parsePlantillaHTML(override, ".", evento); err != nil {
+					slog.Warn("Using the default HTML notification template", "evento", evento, "error", err)
+				}
+			}
+			var err error
+			if p.texto == nil {
+				if p.texto, err = parsePlantilla(defaultTemplates, "templates", evento); err != nil {
+					panic(err) // The embedded templates are part of the build
+				}
+			}
+			if p.html == nil {
+				if p.html, err = parsePlantillaHTML(defaultTemplates, "templates", evento); err != nil {
+					panic(err)
+				}
+			}
+			plantillas[evento] = p
+		}
+	})
+	return plantillas
+}
+
+// parsePlantilla parses the text template of evento and the shared comun.tmpl from dir in fsys.
+func parsePlantilla(fsys fs.FS, dir, evento string) (*template.Template, error) {
+	t, err := template.New(evento).Funcs(templateFuncs).ParseFS(fsys, dir+"/comun.tmpl", dir+"/"+evento+".tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s template: %w", evento, err)
+	}
+	for _, nombre := range []string{"asunto", "cuerpo"} {
+		if t.Lookup(nombre) == nil {
+			return nil, fmt.Errorf("the %s template does not define %q", evento, nombre)
+		}
+	}
+	return t, nil
+}
+
+// parsePlantillaHTML parses the HTML template of evento and the layout base.html from dir in fsys.
+func parsePlantillaHTML(fsys fs.FS, dir, evento string) (*htmltemplate.Template, error) {
+	t, err := htmltemplate.New(evento).Funcs(htmlTemplateFuncs).ParseFS(fsys, dir+"/base.html", dir+"/"+evento+".html")
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s HTML template: %w", evento, err)
+	}
+	for _, nombre := range []string{"base", "contenido"} {
+		if t.Lookup(nombre) == nil {
+			return nil, fmt.Errorf("the %s HTML template does not define %q", evento, nombre)
+		}
+	}
+	return t, nil
+}
+
+// renderEmail returns the subject and the text and HTML bodies of the email about evento.
+func renderEmail(evento string, datos any) (asunto, texto, html string, err error) {
+	p, ok := getPlantillas()[evento]
+	if !ok {
+		return "", "", "", fmt.Errorf("no template for event %q", evento)
+	}
+	var b strings.Builder
+	if err := p.texto.ExecuteTemplate(&b, "asunto", datos); err != nil {
+		return "", "", "", fmt.Errorf("error rendering %s subject: %w", evento, err)
+	}
+	// Subjects are a single header line
+	asunto = strings.Join(strings.Fields(b.String()), " ")
+	b.Reset()
+	if err := p.texto.ExecuteTemplate(&b, "cuerpo", datos); err != nil {
+		return "", "", "", fmt.Errorf("error rendering %s body: %w", evento, err)
+	}
+	texto = strings.TrimSpace(b.String())
+	b.Reset()
+	if err := p.html.ExecuteTemplate(&b, "base", datosHTML{Asunto: asunto, Datos: datos}); err != nil {
+		return "", "", "", fmt.Errorf("error rendering %s HTML body: %w", evento, err)
+	}
+	return asunto, texto, b.String(), nil
+}
+
+// datosHTML is the data of base.html: the subject, for the title, and the data of "contenido".
+type datosHTML struct {
+	Asunto string
+	Datos  any
+}
@@ -0,0 +1,131 @@
+package oai
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// DublinCore is an oai_dc record: unqualified Dublin Core, every element repeatable.
+type DublinCore struct {
+	XmlnsOAIDC     string   `xml:"xmlns:oai_dc,attr"`
+	XmlnsDC        string   `xml:"xmlns:dc,attr"`
+	XmlnsXSI       string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+	Title          []string `xml:"dc:title"`
+	Creator        []string `xml:"dc:creator"`
+	Subject        []string `xml:"dc:subject"`
+	Description    []string `xml:"dc:description"`
+	Publisher      []string `xml:"dc:publisher"`
+	Contributor    []string `xml:"dc:contributor"`
+	Date           []string `xml:"dc:date"`
+	Type           []string `xml:"dc:type"`
+	Identifier     []string `xml:"dc:identifier"`
+	Source         []string `xml:"dc:source"`
+	Language       []string `xml:"dc:language"`
+	Relation       []string `xml:"dc:relation"`
+}
+
+func newDublinCore() DublinCore {
+	return DublinCore{
+		XmlnsOAIDC:     "http://www.openarchives.org/OAI/2.0/oai_dc/",
+		XmlnsDC:        "http://purl.org/dc/elements/1.1/",
+		XmlnsXSI:       "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: "http://www.openarchives.org/OAI/2.0/oai_dc/ http://www.openarchives.org/OAI/2.0/oai_dc.xsd",
+	}
+}
+
+// tiposPublicacion maps the publicacion types to the info:eu-repo vocabulary used by the
+// national aggregators.
+var tiposPublicacion = map[string]string{
+	models.PublicacionArticulo: "info:eu-repo/semantics/article",
+	models.PublicacionLibro:    "info:eu-repo/semantics/book",
+	models.PublicacionCapitulo: "info:eu-repo/semantics/bookPart",
+	models.PublicacionPonencia: "info:eu-repo/semantics/conferenceObject",
+	models.PublicacionTesis:    "info:eu-repo/semantics/doctoralThesis",
+	models.PublicacionOtro:     "info:eu-repo/semantics/other",
+}
+
+// GrupoRecord returns the record of a group; apiURL is the base URL of the API, to link the group.
+// A soft-deleted group is a deleted record, without metadata.
+func (c Config) GrupoRecord(g models.GrupoOAI, apiURL string, conMetadata bool) Record {
+	rec := Record{Header: Header{
+		Identifier: c.Identifier(SetGrupo, g.ID),
+		Datestamp:  Datestamp(g.Datestamp),
+		SetSpec:    SetGrupo,
+	}}
+	if g.DeletedAt != nil {
+		rec.Header.Status = "deleted"
+		return rec
+	}
+	if !conMetadata {
+		return rec
+	}
+	dc := newDublinCore()
+	dc.Title = []string{g.Nombre}
+	dc.Creator = g.Coordinadores
+	dc.Contributor = g.Integrantes
+	dc.Subject = noVacios(g.LineaInvestigacion, g.TipoInvestigacion)
+	descripcion := "Grupo de investigación"
+	if strings.HasPrefix(g.Facultad, "Facultad") {
+		descripcion += " de la " + g.Facultad
+	} else if g.Facultad != "" {
+		descripcion += " de " + g.Facultad
+	}
+	if g.NumeroResolucion != "" {
+		descripcion += ", reconocido por la resolución " + g.NumeroResolucion
+	}
+	dc.Description = []string{descripcion + "."}
+	dc.Publisher = []string{c.RepositoryName}
+	dc.Date = []string{g.FechaRegistro.Format("2006-01-02")}
+	dc.Type = []string{"Grupo de investigación"}
+	dc.Identifier = []string{fmt.Sprintf("%s/grupos/%d", apiURL, g.ID)}
+	dc.Language = []string{"spa"}
+	if g.IDGrupoPadre != nil {
+		dc.Relation = []string{c.Identifier(SetGrupo, *g.IDGrupoPadre)}
+	}
+	rec.Metadata = &Metadata{DC: dc}
+	return rec
+}
+
+// PublicacionRecord returns the record of a publicacion; apiURL is the base URL of the API, to
+// link the publicacion.
+func (c Config) PublicacionRecord(p models.PublicacionConAutores, apiURL string, conMetadata bool) Record {
+	rec := Record{Header: Header{
+		Identifier: c.Identifier(SetPublicacion, p.ID),
+		Datestamp:  Datestamp(p.UpdatedAt),
+		SetSpec:    SetPublicacion,
+	}}
+	if !conMetadata {
+		return rec
+	}
+	dc := newDublinCore()
+	dc.Title = []string{p.Titulo}
+	dc.Creator = p.Autores
+	dc.Publisher = []string{c.RepositoryName}
+	dc.Date = []string{strconv.Itoa(p.Anio)}
+	dc.Type = noVacios(tiposPublicacion[p.Tipo])
+	dc.Identifier = []string{fmt.Sprintf("%s/publicaciones/%d", apiURL, p.ID)}
+	if p.DOI != nil && *p.DOI != "" {
+		dc.Identifier = append(dc.Identifier, "https://doi.org/"+*p.DOI)
+	}
+	dc.Source = noVacios(strings.TrimSpace(p.Revista))
+	for _, id := range p.IDGrupos {
+		dc.Relation = append(dc.Relation, c.Identifier(SetGrupo, id))
+	}
+	rec.Metadata = &Metadata{DC: dc}
+	return rec
+}
+
+// noVacios returns the non-empty values.
+func noVacios(valores ...string) []string {
+	var out []string
+	for _, v := range valores {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
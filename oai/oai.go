@@ -0,0 +1,450 @@
+// Package oai implements the protocol side of a minimal OAI-PMH 2.0 data provider
+// (https://www.openarchives.org/OAI/openarchivesprotocol.html): the XML responses, the arguments
+// of each verb, resumption tokens and the Dublin Core (oai_dc) records of groups and
+// publicaciones. Fetching the records is left to the caller.
+//
+// Records are identified as oai:<repositorio>:grupo/<id> and oai:<repositorio>:publicacion/<id>,
+// and grouped in the sets "grupo" and "publicacion".
+package oai
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Verbs.
+const (
+	VerbIdentify            = "Identify"
+	VerbListMetadataFormats = "ListMetadataFormats"
+	VerbListSets            = "ListSets"
+	VerbListIdentifiers     = "ListIdentifiers"
+	VerbListRecords         = "ListRecords"
+	VerbGetRecord           = "GetRecord"
+)
+
+// Error codes.
+const (
+	BadArgument             = "badArgument"
+	BadResumptionToken      = "badResumptionToken"
+	BadVerb                 = "badVerb"
+	CannotDisseminateFormat = "cannotDisseminateFormat"
+	IDDoesNotExist          = "idDoesNotExist"
+	NoRecordsMatch          = "noRecordsMatch"
+)
+
+// Sets, which are also the kind of record in an identifier.
+const (
+	SetGrupo       = "grupo"
+	SetPublicacion = "publicacion"
+)
+
+// Sets lists the sets in harvesting order.
+var Sets = []string{SetGrupo, SetPublicacion}
+
+// MetadataPrefixDC is the only metadata format offered, unqualified Dublin Core.
+const MetadataPrefixDC = "oai_dc"
+
+// PageSize is the number of records or headers of each page of a list.
+const PageSize = 100
+
+// granularidad is the format of datestamps, in UTC.
+const granularidad = "2006-01-02T15:04:05Z"
+
+// Config describes the repository in the Identify response and its identifiers.
+type Config struct {
+	RepositoryName string
+	AdminEmail     string
+	RepositoryID   string // Namespace of the identifiers, a domain name
+}
+
+// ConfigFromEnv reads OAI_REPOSITORY_NAME, OAI_ADMIN_EMAIL (default SMTP_FROM) and
+// OAI_REPOSITORY_ID (default the host of baseURL).
+func ConfigFromEnv(baseURL string) Config {
+	c := Config{
+		RepositoryName: os.Getenv("OAI_REPOSITORY_NAME"),
+		AdminEmail:     os.Getenv("OAI_ADMIN_EMAIL"),
+		RepositoryID:   os.Getenv("OAI_REPOSITORY_ID"),
+	}
+	if c.RepositoryName == "" {
+		c.RepositoryName = "Grupos de investigación"
+	}
+	if c.AdminEmail == "" {
+		c.AdminEmail = os.Getenv("SMTP_FROM")
+	}
+	if c.AdminEmail == "" {
+		c.AdminEmail = "admin@localhost"
+	}
+	if c.RepositoryID == "" {
+		if u, err := url.Parse(baseURL); err == nil && u.Hostname() != "" {
+			c.RepositoryID = u.Hostname()
+		} else {
+			c.RepositoryID = "localhost"
+		}
+	}
+	return c
+}
+
+// Identifier returns the OAI identifier of a record of the given set.
+func (c Config) Identifier(set string, id int) string {
+	return fmt.Sprintf("oai:%s:%s/%d", c.RepositoryID, set, id)
+}
+
+// ParseIdentifier returns the set and ID of an identifier of this repository.
+func (c Config) ParseIdentifier(identifier string) (set string, id int, ok bool) {
+	resto, ok := strings.CutPrefix(identifier, "oai:"+c.RepositoryID+":")
+	if !ok {
+		return "", 0, false
+	}
+	set, num, ok := strings.Cut(resto, "/")
+	if !ok || (set != SetGrupo && set != SetPublicacion) {
+		return "", 0, false
+	}
+	id, err := strconv.Atoi(num)
+	if err != nil || id <= 0 {
+		return "", 0, false
+	}
+	return set, id, true
+}
+
+// Datestamp formats t with the repository's granularity.
+func Datestamp(t time.Time) string {
+	return t.UTC().Format(granularidad)
+}
+
+// parseDate parses a from or until argument, in either granularity. For until it returns the
+// exclusive bound: the next day or second.
+func parseDate(s string, until bool) (time.Time, string, error) {
+	for _, layout := range []string{granularidad, time.DateOnly} {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		if until && layout == time.DateOnly {
+			t = t.AddDate(0, 0, 1)
+		} else if until {
+			t = t.Add(time.Second)
+		}
+		return t, layout, nil
+	}
+	return time.Time{}, "", fmt.Errorf("fecha inválida: %q", s)
+}
+
+// Lista is the selection of a ListIdentifiers or ListRecords request and where its current page
+// starts.
+type Lista struct {
+	MetadataPrefix string
+	Set            string // Empty for every set
+	From, Until    string // As received
+	Desde, Hasta   *time.Time
+	// Position of the page: the set being harvested and the last ID already returned in it
+	SetActual string
+	DespuesDe int
+	Cursor    int // Records returned by the previous pages
+}
+
+// Token returns the resumption token of the page that starts at set and after ID despuesDe.
+func (l Lista) Token(set string, despuesDe, cursor int) string {
+	v := url.Values{}
+	v.Set("m", l.MetadataPrefix)
+	v.Set("s", l.Set)
+	v.Set("f", l.From)
+	v.Set("u", l.Until)
+	v.Set("a", set)
+	v.Set("d", strconv.Itoa(despuesDe))
+	v.Set("c", strconv.Itoa(cursor))
+	return base64.RawURLEncoding.EncodeToString([]byte(v.Encode()))
+}
+
+// SetsPendientes returns the sets still to harvest, from SetActual on.
+func (l Lista) SetsPendientes() []string {
+	for i, s := range Sets {
+		if s == l.SetActual {
+			return Sets[i:]
+		}
+	}
+	return nil
+}
+
+// ParseLista reads the arguments of ListIdentifiers or ListRecords.
+func ParseLista(args url.Values) (Lista, *Error) {
+	if token := args.Get("resumptionToken"); token != "" {
+		if len(args) != 2 {
+			return Lista{}, &Error{Code: BadArgument, Message: "resumptionToken es un argumento exclusivo"}
+		}
+		return parseToken(token)
+	}
+	if e := CheckArgs(args, []string{"metadataPrefix"}, []string{"from", "until", "set"}); e != nil {
+		return Lista{}, e
+	}
+	l := Lista{
+		MetadataPrefix: args.Get("metadataPrefix"),
+		Set:            args.Get("set"),
+		From:           args.Get("from"),
+		Until:          args.Get("until"),
+	}
+	if e := l.completar(); e != nil {
+		return Lista{}, e
+	}
+	l.SetActual = Sets[0]
+	if l.Set != "" {
+		l.SetActual = l.Set
+	}
+	return l, nil
+}
+
+// completar checks the selection of l and parses its dates.
+func (l *Lista) completar() *Error {
+	if l.MetadataPrefix != MetadataPrefixDC {
+		return &Error{Code: CannotDisseminateFormat, Message: "Solo se ofrece el formato oai_dc"}
+	}
+	if l.Set != "" && l.Set != SetGrupo && l.Set != SetPublicacion {
+		return &Error{Code: BadArgument, Message: "El set no existe; use grupo o publicacion"}
+	}
+	var layoutFrom, layoutUntil string
+	if l.From != "" {
+		t, layout, err := parseDate(l.From, false)
+		if err != nil {
+			return &Error{Code: BadArgument, Message: "from no tiene el formato AAAA-MM-DD o AAAA-MM-DDThh:mm:ssZ"}
+		}
+		l.Desde, layoutFrom = &t, layout
+	}
+	if l.Until != "" {
+		t, layout, err := parseDate(l.Until, true)
+		if err != nil {
+			return &Error{Code: BadArgument, Message: "until no tiene el formato AAAA-MM-DD o AAAA-MM-DDThh:mm:ssZ"}
+		}
+		l.Hasta, layoutUntil = &t, layout
+	}
+	if layoutFrom != "" && layoutUntil != "" && layoutFrom != layoutUntil {
+		return &Error{Code: BadArgument, Message: "from y until deben tener la misma granularidad"}
+	}
+	if l.Desde != nil && l.Hasta != nil && !l.Desde.Before(*l.Hasta) {
+		return &Error{Code: BadArgument, Message: "from no puede ser posterior a until"}
+	}
+	return nil
+}
+
+func parseToken(token string) (Lista, *Error) {
+	bad := &Error{Code: BadResumptionToken, Message: "El resumptionToken no es válido"}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Lista{}, bad
+	}
+	v, err := url.ParseQuery(string(raw))
+	if err != nil {
+		return Lista{}, bad
+	}
+	l := Lista{MetadataPrefix: v.Get("m"), Set: v.Get("s"), From: v.Get("f"), Until: v.Get("u"), SetActual: v.Get("a")}
+	if l.completar() != nil || len(l.SetsPendientes()) == 0 || (l.Set != "" && l.Set != l.SetActual) {
+		return Lista{}, bad
+	}
+	if l.DespuesDe, err = strconv.Atoi(v.Get("d")); err != nil || l.DespuesDe < 0 {
+		return Lista{}, bad
+	}
+	if l.Cursor, err = strconv.Atoi(v.Get("c")); err != nil || l.Cursor < 0 {
+		return Lista{}, bad
+	}
+	return l, nil
+}
+
+// ParseGetRecord reads the arguments of GetRecord.
+func ParseGetRecord(args url.Values) (identifier string, e *Error) {
+	if e := CheckArgs(args, []string{"identifier", "metadataPrefix"}, nil); e != nil {
+		return "", e
+	}
+	if args.Get("metadataPrefix") != MetadataPrefixDC {
+		return "", &Error{Code: CannotDisseminateFormat, Message: "Solo se ofrece el formato oai_dc"}
+	}
+	return args.Get("identifier"), nil
+}
+
+// CheckArgs checks that args has the verb, every required argument and no other than the
+// optional ones, each once.
+func CheckArgs(args url.Values, required, optional []string) *Error {
+	permitidos := map[string]bool{"verb": true}
+	for _, a := range append(append([]string{}, required...), optional...) {
+		permitidos[a] = true
+	}
+	for k, v := range args {
+		if !permitidos[k] {
+			return &Error{Code: BadArgument, Message: fmt.Sprintf("Argumento no permitido: %s", k)}
+		}
+		if len(v) > 1 {
+			return &Error{Code: BadArgument, Message: fmt.Sprintf("Argumento repetido: %s", k)}
+		}
+	}
+	for _, a := range required {
+		if args.Get(a) == "" {
+			return &Error{Code: BadArgument, Message: fmt.Sprintf("Falta el argumento %s", a)}
+		}
+	}
+	return nil
+}
+
+// Response is the OAI-PMH envelope; exactly one of the verb elements, or Errors, is set.
+type Response struct {
+	XMLName        xml.Name `xml:"OAI-PMH"`
+	Xmlns          string   `xml:"xmlns,attr"`
+	XmlnsXSI       string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+	ResponseDate   string   `xml:"responseDate"`
+	Request        Request  `xml:"request"`
+	Errors         []Error  `xml:"error,omitempty"`
+
+	Identify            *Identify            `xml:"Identify,omitempty"`
+	ListMetadataFormats *ListMetadataFormats `xml:"ListMetadataFormats,omitempty"`
+	ListSets            *ListSets            `xml:"ListSets,omitempty"`
+	GetRecord           *GetRecord           `xml:"GetRecord,omitempty"`
+	ListIdentifiers     *ListIdentifiers     `xml:"ListIdentifiers,omitempty"`
+	ListRecords         *ListRecords         `xml:"ListRecords,omitempty"`
+}
+
+// NewResponse returns the envelope of a response to a request with args sent to baseURL. The
+// request element only echoes the arguments of a valid request.
+func NewResponse(baseURL string, args url.Values, valid bool) *Response {
+	r := &Response{
+		Xmlns:          "http://www.openarchives.org/OAI/2.0/",
+		XmlnsXSI:       "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: "http://www.openarchives.org/OAI/2.0/ http://www.openarchives.org/OAI/2.0/OAI-PMH.xsd",
+		ResponseDate:   Datestamp(time.Now()),
+		Request:        Request{URL: baseURL},
+	}
+	if valid {
+		r.Request.Verb = args.Get("verb")
+		r.Request.Identifier = args.Get("identifier")
+		r.Request.MetadataPrefix = args.Get("metadataPrefix")
+		r.Request.From = args.Get("from")
+		r.Request.Until = args.Get("until")
+		r.Request.Set = args.Get("set")
+		r.Request.ResumptionToken = args.Get("resumptionToken")
+	}
+	return r
+}
+
+// Request echoes the request in a response.
+type Request struct {
+	Verb            string `xml:"verb,attr,omitempty"`
+	Identifier      string `xml:"identifier,attr,omitempty"`
+	MetadataPrefix  string `xml:"metadataPrefix,attr,omitempty"`
+	From            string `xml:"from,attr,omitempty"`
+	Until           string `xml:"until,attr,omitempty"`
+	Set             string `xml:"set,attr,omitempty"`
+	ResumptionToken string `xml:"resumptionToken,attr,omitempty"`
+	URL             string `xml:",chardata"`
+}
+
+// Error is an OAI-PMH error.
+type Error struct {
+	Code    string `xml:"code,attr"`
+	Message string `xml:",chardata"`
+}
+
+// Identify describes the repository.
+type Identify struct {
+	RepositoryName    string `xml:"repositoryName"`
+	BaseURL           string `xml:"baseURL"`
+	ProtocolVersion   string `xml:"protocolVersion"`
+	AdminEmail        string `xml:"adminEmail"`
+	EarliestDatestamp string `xml:"earliestDatestamp"`
+	DeletedRecord     string `xml:"deletedRecord"`
+	Granularity       string `xml:"granularity"`
+}
+
+// NewIdentify returns the Identify response of the repository. Deleted groups are reported
+// while they are kept soft-deleted, and deleted publicaciones are not, so deleted records are
+// "transient".
+func NewIdentify(c Config, baseURL string, earliest time.Time) *Identify {
+	return &Identify{
+		RepositoryName:    c.RepositoryName,
+		BaseURL:           baseURL,
+		ProtocolVersion:   "2.0",
+		AdminEmail:        c.AdminEmail,
+		EarliestDatestamp: Datestamp(earliest),
+		DeletedRecord:     "transient",
+		Granularity:       "YYYY-MM-DDThh:mm:ssZ",
+	}
+}
+
+// ListMetadataFormats lists the metadata formats.
+type ListMetadataFormats struct {
+	Formats []MetadataFormat `xml:"metadataFormat"`
+}
+
+// MetadataFormat is a metadata format of the repository.
+type MetadataFormat struct {
+	Prefix    string `xml:"metadataPrefix"`
+	Schema    string `xml:"schema"`
+	Namespace string `xml:"metadataNamespace"`
+}
+
+// Formats is the ListMetadataFormats response: only oai_dc.
+var Formats = &ListMetadataFormats{Formats: []MetadataFormat{{
+	Prefix:    MetadataPrefixDC,
+	Schema:    "http://www.openarchives.org/OAI/2.0/oai_dc.xsd",
+	Namespace: "http://www.openarchives.org/OAI/2.0/oai_dc/",
+}}}
+
+// ListSets lists the sets.
+type ListSets struct {
+	Sets []Set `xml:"set"`
+}
+
+// Set is a set of records.
+type Set struct {
+	Spec string `xml:"setSpec"`
+	Name string `xml:"setName"`
+}
+
+// SetList is the ListSets response.
+var SetList = &ListSets{Sets: []Set{
+	{Spec: SetGrupo, Name: "Grupos de investigación"},
+	{Spec: SetPublicacion, Name: "Publicaciones"},
+}}
+
+// GetRecord holds a single record.
+type GetRecord struct {
+	Record Record `xml:"record"`
+}
+
+// ListIdentifiers holds a page of headers.
+type ListIdentifiers struct {
+	Headers         []Header         `xml:"header"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken,omitempty"`
+}
+
+// ListRecords holds a page of records.
+type ListRecords struct {
+	Records         []Record         `xml:"record"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken,omitempty"`
+}
+
+// ResumptionToken continues an incomplete list; the last page has an empty one.
+type ResumptionToken struct {
+	Cursor int    `xml:"cursor,attr"`
+	Value  string `xml:",chardata"`
+}
+
+// Record is a record: its header and, unless it is deleted, its metadata.
+type Record struct {
+	Header   Header    `xml:"header"`
+	Metadata *Metadata `xml:"metadata,omitempty"`
+}
+
+// Header identifies a record.
+type Header struct {
+	Status     string `xml:"status,attr,omitempty"` // "deleted" for deleted records
+	Identifier string `xml:"identifier"`
+	Datestamp  string `xml:"datestamp"`
+	SetSpec    string `xml:"setSpec"`
+}
+
+// Metadata holds the Dublin Core description of a record.
+type Metadata struct {
+	DC DublinCore `xml:"oai_dc:dc"`
+}
@@ -0,0 +1,815 @@
+// Read-only gRPC API over the directory of research groups and investigators, for other
+// institutional services. Every call requires the same JWT as the REST API, sent as
+// "authorization: Bearer <token>" metadata.
+//
+// Regenerate the Go code after editing (from this directory):
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//	       --go-grpc_out=. --go-grpc_opt=paths=source_relative directorio.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: directorio.proto
+
+package directoriopb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Grupo struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	IdGrupo            int32                  `protobuf:"varint,1,opt,name=id_grupo,json=idGrupo,proto3" json:"id_grupo,omitempty"`
+	Nombre             string                 `protobuf:"bytes,2,opt,name=nombre,proto3" json:"nombre,omitempty"`
+	NumeroResolucion   string                 `protobuf:"bytes,3,opt,name=numero_resolucion,json=numeroResolucion,proto3" json:"numero_resolucion,omitempty"`
+	LineaInvestigacion string                 `protobuf:"bytes,4,opt,name=linea_investigacion,json=lineaInvestigacion,proto3" json:"linea_investigacion,omitempty"`
+	TipoInvestigacion  string                 `protobuf:"bytes,5,opt,name=tipo_investigacion,json=tipoInvestigacion,proto3" json:"tipo_investigacion,omitempty"`
+	FechaRegistro      string                 `protobuf:"bytes,6,opt,name=fecha_registro,json=fechaRegistro,proto3" json:"fecha_registro,omitempty"` // YYYY-MM-DD
+	Estado             string                 `protobuf:"bytes,7,opt,name=estado,proto3" json:"estado,omitempty"`                                    // activo, inactivo, en_renovacion or cerrado
+	IdGrupoPadre       *int32                 `protobuf:"varint,8,opt,name=id_grupo_padre,json=idGrupoPadre,proto3,oneof" json:"id_grupo_padre,omitempty"`
+	CreatedAt          *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt          *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *Grupo) Reset() {
+	*x = Grupo{}
+	mi := &file_directorio_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Grupo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Grupo) ProtoMessage() {}
+
+func (x *Grupo) ProtoReflect() protoreflect.Message {
+	mi := &file_directorio_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Grupo.ProtoReflect.Descriptor instead.
+func (*Grupo) Descriptor() ([]byte, []int) {
+	return file_directorio_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Grupo) GetIdGrupo() int32 {
+	if x != nil {
+		return x.IdGrupo
+	}
+	return 0
+}
+
+func (x *Grupo) GetNombre() string {
+	if x != nil {
+		return x.Nombre
+	}
+	return ""
+}
+
+func (x *Grupo) GetNumeroResolucion() string {
+	if x != nil {
+		return x.NumeroResolucion
+	}
+	return ""
+}
+
+func (x *Grupo) GetLineaInvestigacion() string {
+	if x != nil {
+		return x.LineaInvestigacion
+	}
+	return ""
+}
+
+func (x *Grupo) GetTipoInvestigacion() string {
+	if x != nil {
+		return x.TipoInvestigacion
+	}
+	return ""
+}
+
+func (x *Grupo) GetFechaRegistro() string {
+	if x != nil {
+		return x.FechaRegistro
+	}
+	return ""
+}
+
+func (x *Grupo) GetEstado() string {
+	if x != nil {
+		return x.Estado
+	}
+	return ""
+}
+
+func (x *Grupo) GetIdGrupoPadre() int32 {
+	if x != nil && x.IdGrupoPadre != nil {
+		return *x.IdGrupoPadre
+	}
+	return 0
+}
+
+func (x *Grupo) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Grupo) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type Integrante struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	IdInvestigador int32                  `protobuf:"varint,1,opt,name=id_investigador,json=idInvestigador,proto3" json:"id_investigador,omitempty"`
+	Nombre         string                 `protobuf:"bytes,2,opt,name=nombre,proto3" json:"nombre,omitempty"`
+	Apellido       string                 `protobuf:"bytes,3,opt,name=apellido,proto3" json:"apellido,omitempty"`
+	Rol            string                 `protobuf:"bytes,4,opt,name=rol,proto3" json:"rol,omitempty"` // Role within the group (Coordinador, Integrante...)
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Integrante) Reset() {
+	*x = Integrante{}
+	mi := &file_directorio_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Integrante) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Integrante) ProtoMessage() {}
+
+func (x *Integrante) ProtoReflect() protoreflect.Message {
+	mi := &file_directorio_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Integrante.ProtoReflect.Descriptor instead.
+func (*Integrante) Descriptor() ([]byte, []int) {
+	return file_directorio_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Integrante) GetIdInvestigador() int32 {
+	if x != nil {
+		return x.IdInvestigador
+	}
+	return 0
+}
+
+func (x *Integrante) GetNombre() string {
+	if x != nil {
+		return x.Nombre
+	}
+	return ""
+}
+
+func (x *Integrante) GetApellido() string {
+	if x != nil {
+		return x.Apellido
+	}
+	return ""
+}
+
+func (x *Integrante) GetRol() string {
+	if x != nil {
+		return x.Rol
+	}
+	return ""
+}
+
+type GrupoConIntegrantes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Grupo         *Grupo                 `protobuf:"bytes,1,opt,name=grupo,proto3" json:"grupo,omitempty"`
+	Integrantes   []*Integrante          `protobuf:"bytes,2,rep,name=integrantes,proto3" json:"integrantes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GrupoConIntegrantes) Reset() {
+	*x = GrupoConIntegrantes{}
+	mi := &file_directorio_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GrupoConIntegrantes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrupoConIntegrantes) ProtoMessage() {}
+
+func (x *GrupoConIntegrantes) ProtoReflect() protoreflect.Message {
+	mi := &file_directorio_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrupoConIntegrantes.ProtoReflect.Descriptor instead.
+func (*GrupoConIntegrantes) Descriptor() ([]byte, []int) {
+	return file_directorio_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GrupoConIntegrantes) GetGrupo() *Grupo {
+	if x != nil {
+		return x.Grupo
+	}
+	return nil
+}
+
+func (x *GrupoConIntegrantes) GetIntegrantes() []*Integrante {
+	if x != nil {
+		return x.Integrantes
+	}
+	return nil
+}
+
+type Investigador struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	IdInvestigador  int32                  `protobuf:"varint,1,opt,name=id_investigador,json=idInvestigador,proto3" json:"id_investigador,omitempty"`
+	Nombre          string                 `protobuf:"bytes,2,opt,name=nombre,proto3" json:"nombre,omitempty"`
+	Apellido        string                 `protobuf:"bytes,3,opt,name=apellido,proto3" json:"apellido,omitempty"`
+	Email           *string                `protobuf:"bytes,4,opt,name=email,proto3,oneof" json:"email,omitempty"`
+	EmailVerificado bool                   `protobuf:"varint,5,opt,name=email_verificado,json=emailVerificado,proto3" json:"email_verificado,omitempty"`
+	CreatedAt       *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt       *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Investigador) Reset() {
+	*x = Investigador{}
+	mi := &file_directorio_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Investigador) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Investigador) ProtoMessage() {}
+
+func (x *Investigador) ProtoReflect() protoreflect.Message {
+	mi := &file_directorio_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Investigador.ProtoReflect.Descriptor instead.
+func (*Investigador) Descriptor() ([]byte, []int) {
+	return file_directorio_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Investigador) GetIdInvestigador() int32 {
+	if x != nil {
+		return x.IdInvestigador
+	}
+	return 0
+}
+
+func (x *Investigador) GetNombre() string {
+	if x != nil {
+		return x.Nombre
+	}
+	return ""
+}
+
+func (x *Investigador) GetApellido() string {
+	if x != nil {
+		return x.Apellido
+	}
+	return ""
+}
+
+func (x *Investigador) GetEmail() string {
+	if x != nil && x.Email != nil {
+		return *x.Email
+	}
+	return ""
+}
+
+func (x *Investigador) GetEmailVerificado() bool {
+	if x != nil {
+		return x.EmailVerificado
+	}
+	return false
+}
+
+func (x *Investigador) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Investigador) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+// Pages work as in the REST API: page starts at 1 and limit defaults to 6, up to 100.
+type ListGruposRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Q             string                 `protobuf:"bytes,1,opt,name=q,proto3" json:"q,omitempty"` // Full-text search over name, resolution and research line, as ?q= in GET /grupos
+	Page          int32                  `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGruposRequest) Reset() {
+	*x = ListGruposRequest{}
+	mi := &file_directorio_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGruposRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGruposRequest) ProtoMessage() {}
+
+func (x *ListGruposRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_directorio_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGruposRequest.ProtoReflect.Descriptor instead.
+func (*ListGruposRequest) Descriptor() ([]byte, []int) {
+	return file_directorio_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListGruposRequest) GetQ() string {
+	if x != nil {
+		return x.Q
+	}
+	return ""
+}
+
+func (x *ListGruposRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListGruposRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListGruposResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Grupos        []*GrupoConIntegrantes `protobuf:"bytes,1,rep,name=grupos,proto3" json:"grupos,omitempty"`
+	Total         int32                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGruposResponse) Reset() {
+	*x = ListGruposResponse{}
+	mi := &file_directorio_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGruposResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGruposResponse) ProtoMessage() {}
+
+func (x *ListGruposResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_directorio_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGruposResponse.ProtoReflect.Descriptor instead.
+func (*ListGruposResponse) Descriptor() ([]byte, []int) {
+	return file_directorio_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListGruposResponse) GetGrupos() []*GrupoConIntegrantes {
+	if x != nil {
+		return x.Grupos
+	}
+	return nil
+}
+
+func (x *ListGruposResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type GetGrupoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IdGrupo       int32                  `protobuf:"varint,1,opt,name=id_grupo,json=idGrupo,proto3" json:"id_grupo,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetGrupoRequest) Reset() {
+	*x = GetGrupoRequest{}
+	mi := &file_directorio_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetGrupoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetGrupoRequest) ProtoMessage() {}
+
+func (x *GetGrupoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_directorio_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetGrupoRequest.ProtoReflect.Descriptor instead.
+func (*GetGrupoRequest) Descriptor() ([]byte, []int) {
+	return file_directorio_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetGrupoRequest) GetIdGrupo() int32 {
+	if x != nil {
+		return x.IdGrupo
+	}
+	return 0
+}
+
+type ListInvestigadoresRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Nombre        string                 `protobuf:"bytes,1,opt,name=nombre,proto3" json:"nombre,omitempty"` // Matches name or surname, as ?name= in GET /investigadores
+	Page          int32                  `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListInvestigadoresRequest) Reset() {
+	*x = ListInvestigadoresRequest{}
+	mi := &file_directorio_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListInvestigadoresRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListInvestigadoresRequest) ProtoMessage() {}
+
+func (x *ListInvestigadoresRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_directorio_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListInvestigadoresRequest.ProtoReflect.Descriptor instead.
+func (*ListInvestigadoresRequest) Descriptor() ([]byte, []int) {
+	return file_directorio_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListInvestigadoresRequest) GetNombre() string {
+	if x != nil {
+		return x.Nombre
+	}
+	return ""
+}
+
+func (x *ListInvestigadoresRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListInvestigadoresRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListInvestigadoresResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Investigadores []*Investigador        `protobuf:"bytes,1,rep,name=investigadores,proto3" json:"investigadores,omitempty"`
+	Total          int32                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ListInvestigadoresResponse) Reset() {
+	*x = ListInvestigadoresResponse{}
+	mi := &file_directorio_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListInvestigadoresResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListInvestigadoresResponse) ProtoMessage() {}
+
+func (x *ListInvestigadoresResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_directorio_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListInvestigadoresResponse.ProtoReflect.Descriptor instead.
+func (*ListInvestigadoresResponse) Descriptor() ([]byte, []int) {
+	return file_directorio_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListInvestigadoresResponse) GetInvestigadores() []*Investigador {
+	if x != nil {
+		return x.Investigadores
+	}
+	return nil
+}
+
+func (x *ListInvestigadoresResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type GetInvestigadorRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	IdInvestigador int32                  `protobuf:"varint,1,opt,name=id_investigador,json=idInvestigador,proto3" json:"id_investigador,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GetInvestigadorRequest) Reset() {
+	*x = GetInvestigadorRequest{}
+	mi := &file_directorio_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetInvestigadorRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetInvestigadorRequest) ProtoMessage() {}
+
+func (x *GetInvestigadorRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_directorio_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetInvestigadorRequest.ProtoReflect.Descriptor instead.
+func (*GetInvestigadorRequest) Descriptor() ([]byte, []int) {
+	return file_directorio_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetInvestigadorRequest) GetIdInvestigador() int32 {
+	if x != nil {
+		return x.IdInvestigador
+	}
+	return 0
+}
+
+var File_directorio_proto protoreflect.FileDescriptor
+
+const file_directorio_proto_rawDesc = "" +
+	"\n" +
+	"\x10directorio.proto\x12\x17apigrupos.directorio.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xba\x03\n" +
+	"\x05Grupo\x12\x19\n" +
+	"\bid_grupo\x18\x01 \x01(\x05R\aidGrupo\x12\x16\n" +
+	"\x06nombre\x18\x02 \x01(\tR\x06nombre\x12+\n" +
+	"\x11numero_resolucion\x18\x03 \x01(\tR\x10numeroResolucion\x12/\n" +
+	"\x13linea_investigacion\x18\x04 \x01(\tR\x12lineaInvestigacion\x12-\n" +
+	"\x12tipo_investigacion\x18\x05 \x01(\tR\x11tipoInvestigacion\x12%\n" +
+	"\x0efecha_registro\x18\x06 \x01(\tR\rfechaRegistro\x12\x16\n" +
+	"\x06estado\x18\a \x01(\tR\x06estado\x12)\n" +
+	"\x0eid_grupo_padre\x18\b \x01(\x05H\x00R\fidGrupoPadre\x88\x01\x01\x129\n" +
+	"\n" +
+	"created_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAtB\x11\n" +
+	"\x0f_id_grupo_padre\"{\n" +
+	"\n" +
+	"Integrante\x12'\n" +
+	"\x0fid_investigador\x18\x01 \x01(\x05R\x0eidInvestigador\x12\x16\n" +
+	"\x06nombre\x18\x02 \x01(\tR\x06nombre\x12\x1a\n" +
+	"\bapellido\x18\x03 \x01(\tR\bapellido\x12\x10\n" +
+	"\x03rol\x18\x04 \x01(\tR\x03rol\"\x92\x01\n" +
+	"\x13GrupoConIntegrantes\x124\n" +
+	"\x05grupo\x18\x01 \x01(\v2\x1e.apigrupos.directorio.v1.GrupoR\x05grupo\x12E\n" +
+	"\vintegrantes\x18\x02 \x03(\v2#.apigrupos.directorio.v1.IntegranteR\vintegrantes\"\xb1\x02\n" +
+	"\fInvestigador\x12'\n" +
+	"\x0fid_investigador\x18\x01 \x01(\x05R\x0eidInvestigador\x12\x16\n" +
+	"\x06nombre\x18\x02 \x01(\tR\x06nombre\x12\x1a\n" +
+	"\bapellido\x18\x03 \x01(\tR\bapellido\x12\x19\n" +
+	"\x05email\x18\x04 \x01(\tH\x00R\x05email\x88\x01\x01\x12)\n" +
+	"\x10email_verificado\x18\x05 \x01(\bR\x0femailVerificado\x129\n" +
+	"\n" +
+	"created_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAtB\b\n" +
+	"\x06_email\"K\n" +
+	"\x11ListGruposRequest\x12\f\n" +
+	"\x01q\x18\x01 \x01(\tR\x01q\x12\x12\n" +
+	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"p\n" +
+	"\x12ListGruposResponse\x12D\n" +
+	"\x06grupos\x18\x01 \x03(\v2,.apigrupos.directorio.v1.GrupoConIntegrantesR\x06grupos\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x05R\x05total\",\n" +
+	"\x0fGetGrupoRequest\x12\x19\n" +
+	"\bid_grupo\x18\x01 \x01(\x05R\aidGrupo\"]\n" +
+	"\x19ListInvestigadoresRequest\x12\x16\n" +
+	"\x06nombre\x18\x01 \x01(\tR\x06nombre\x12\x12\n" +
+	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"\x81\x01\n" +
+	"\x1aListInvestigadoresResponse\x12M\n" +
+	"\x0einvestigadores\x18\x01 \x03(\v2%.apigrupos.directorio.v1.InvestigadorR\x0einvestigadores\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x05R\x05total\"A\n" +
+	"\x16GetInvestigadorRequest\x12'\n" +
+	"\x0fid_investigador\x18\x01 \x01(\x05R\x0eidInvestigador2\xc1\x03\n" +
+	"\n" +
+	"Directorio\x12e\n" +
+	"\n" +
+	"ListGrupos\x12*.apigrupos.directorio.v1.ListGruposRequest\x1a+.apigrupos.directorio.v1.ListGruposResponse\x12b\n" +
+	"\bGetGrupo\x12(.apigrupos.directorio.v1.GetGrupoRequest\x1a,.apigrupos.directorio.v1.GrupoConIntegrantes\x12}\n" +
+	"\x12ListInvestigadores\x122.apigrupos.directorio.v1.ListInvestigadoresRequest\x1a3.apigrupos.directorio.v1.ListInvestigadoresResponse\x12i\n" +
+	"\x0fGetInvestigador\x12/.apigrupos.directorio.v1.GetInvestigadorRequest\x1a%.apigrupos.directorio.v1.InvestigadorBQZOgithub.com/GoogleCloudPlatform/golang-samples/run/helloworld/proto/directoriopbb\x06proto3"
+
+var (
+	file_directorio_proto_rawDescOnce sync.Once
+	file_directorio_proto_rawDescData []byte
+)
+
+func file_directorio_proto_rawDescGZIP() []byte {
+	file_directorio_proto_rawDescOnce.Do(func() {
+		file_directorio_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_directorio_proto_rawDesc), len(file_directorio_proto_rawDesc)))
+	})
+	return file_directorio_proto_rawDescData
+}
+
+var file_directorio_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_directorio_proto_goTypes = []any{
+	(*Grupo)(nil),                      // 0: apigrupos.directorio.v1.Grupo
+	(*Integrante)(nil),                 // 1: apigrupos.directorio.v1.Integrante
+	(*GrupoConIntegrantes)(nil),        // 2: apigrupos.directorio.v1.GrupoConIntegrantes
+	(*Investigador)(nil),               // 3: apigrupos.directorio.v1.Investigador
+	(*ListGruposRequest)(nil),          // 4: apigrupos.directorio.v1.ListGruposRequest
+	(*ListGruposResponse)(nil),         // 5: apigrupos.directorio.v1.ListGruposResponse
+	(*GetGrupoRequest)(nil),            // 6: apigrupos.directorio.v1.GetGrupoRequest
+	(*ListInvestigadoresRequest)(nil),  // 7: apigrupos.directorio.v1.ListInvestigadoresRequest
+	(*ListInvestigadoresResponse)(nil), // 8: apigrupos.directorio.v1.ListInvestigadoresResponse
+	(*GetInvestigadorRequest)(nil),     // 9: apigrupos.directorio.v1.GetInvestigadorRequest
+	(*timestamppb.Timestamp)(nil),      // 10: google.protobuf.Timestamp
+}
+var file_directorio_proto_depIdxs = []int32{
+	10, // 0: apigrupos.directorio.v1.Grupo.created_at:type_name -> google.protobuf.Timestamp
+	10, // 1: apigrupos.directorio.v1.Grupo.updated_at:type_name -> google.protobuf.Timestamp
+	0,  // 2: apigrupos.directorio.v1.GrupoConIntegrantes.grupo:type_name -> apigrupos.directorio.v1.Grupo
+	1,  // 3: apigrupos.directorio.v1.GrupoConIntegrantes.integrantes:type_name -> apigrupos.directorio.v1.Integrante
+	10, // 4: apigrupos.directorio.v1.Investigador.created_at:type_name -> google.protobuf.Timestamp
+	10, // 5: apigrupos.directorio.v1.Investigador.updated_at:type_name -> google.protobuf.Timestamp
+	2,  // 6: apigrupos.directorio.v1.ListGruposResponse.grupos:type_name -> apigrupos.directorio.v1.GrupoConIntegrantes
+	3,  // 7: apigrupos.directorio.v1.ListInvestigadoresResponse.investigadores:type_name -> apigrupos.directorio.v1.Investigador
+	4,  // 8: apigrupos.directorio.v1.Directorio.ListGrupos:input_type -> apigrupos.directorio.v1.ListGruposRequest
+	6,  // 9: apigrupos.directorio.v1.Directorio.GetGrupo:input_type -> apigrupos.directorio.v1.GetGrupoRequest
+	7,  // 10: apigrupos.directorio.v1.Directorio.ListInvestigadores:input_type -> apigrupos.directorio.v1.ListInvestigadoresRequest
+	9,  // 11: apigrupos.directorio.v1.Directorio.GetInvestigador:input_type -> apigrupos.directorio.v1.GetInvestigadorRequest
+	5,  // 12: apigrupos.directorio.v1.Directorio.ListGrupos:output_type -> apigrupos.directorio.v1.ListGruposResponse
+	2,  // 13: apigrupos.directorio.v1.Directorio.GetGrupo:output_type -> apigrupos.directorio.v1.GrupoConIntegrantes
+	8,  // 14: apigrupos.directorio.v1.Directorio.ListInvestigadores:output_type -> apigrupos.directorio.v1.ListInvestigadoresResponse
+	3,  // 15: apigrupos.directorio.v1.Directorio.GetInvestigador:output_type -> apigrupos.directorio.v1.Investigador
+	12, // [12:16] is the sub-list for method output_type
+	8,  // [8:12] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_directorio_proto_init() }
+func file_directorio_proto_init() {
+	if File_directorio_proto != nil {
+		return
+	}
+	file_directorio_proto_msgTypes[0].OneofWrappers = []any{}
+	file_directorio_proto_msgTypes[3].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_directorio_proto_rawDesc), len(file_directorio_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_directorio_proto_goTypes,
+		DependencyIndexes: file_directorio_proto_depIdxs,
+		MessageInfos:      file_directorio_proto_msgTypes,
+	}.Build()
+	File_directorio_proto = out.File
+	file_directorio_proto_goTypes = nil
+	file_directorio_proto_depIdxs = nil
+}
@@ -0,0 +1,252 @@
+// Read-only gRPC API over the directory of research groups and investigators, for other
+// institutional services. Every call requires the same JWT as the REST API, sent as
+// "authorization: Bearer <token>" metadata.
+//
+// Regenerate the Go code after editing (from this directory):
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//	       --go-grpc_out=. --go-grpc_opt=paths=source_relative directorio.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: directorio.proto
+
+package directoriopb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Directorio_ListGrupos_FullMethodName         = "/apigrupos.directorio.v1.Directorio/ListGrupos"
+	Directorio_GetGrupo_FullMethodName           = "/apigrupos.directorio.v1.Directorio/GetGrupo"
+	Directorio_ListInvestigadores_FullMethodName = "/apigrupos.directorio.v1.Directorio/ListInvestigadores"
+	Directorio_GetInvestigador_FullMethodName    = "/apigrupos.directorio.v1.Directorio/GetInvestigador"
+)
+
+// DirectorioClient is the client API for Directorio service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DirectorioClient interface {
+	// ListGrupos lists the groups (not deleted) with their members, ordered like GET /grupos.
+	ListGrupos(ctx context.Context, in *ListGruposRequest, opts ...grpc.CallOption) (*ListGruposResponse, error)
+	// GetGrupo returns a group with its members. NOT_FOUND if it does not exist or was deleted.
+	GetGrupo(ctx context.Context, in *GetGrupoRequest, opts ...grpc.CallOption) (*GrupoConIntegrantes, error)
+	// ListInvestigadores lists the investigators (not deleted), optionally filtered by name.
+	ListInvestigadores(ctx context.Context, in *ListInvestigadoresRequest, opts ...grpc.CallOption) (*ListInvestigadoresResponse, error)
+	// GetInvestigador returns an investigator. NOT_FOUND if it does not exist or was deleted.
+	GetInvestigador(ctx context.Context, in *GetInvestigadorRequest, opts ...grpc.CallOption) (*Investigador, error)
+}
+
+type directorioClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDirectorioClient(cc grpc.ClientConnInterface) DirectorioClient {
+	return &directorioClient{cc}
+}
+
+func (c *directorioClient) ListGrupos(ctx context.Context, in *ListGruposRequest, opts ...grpc.CallOption) (*ListGruposResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListGruposResponse)
+	err := c.cc.Invoke(ctx, Directorio_ListGrupos_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *directorioClient) GetGrupo(ctx context.Context, in *GetGrupoRequest, opts ...grpc.CallOption) (*GrupoConIntegrantes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GrupoConIntegrantes)
+	err := c.cc.Invoke(ctx, Directorio_GetGrupo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *directorioClient) ListInvestigadores(ctx context.Context, in *ListInvestigadoresRequest, opts ...grpc.CallOption) (*ListInvestigadoresResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListInvestigadoresResponse)
+	err := c.cc.Invoke(ctx, Directorio_ListInvestigadores_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *directorioClient) GetInvestigador(ctx context.Context, in *GetInvestigadorRequest, opts ...grpc.CallOption) (*Investigador, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Investigador)
+	err := c.cc.Invoke(ctx, Directorio_GetInvestigador_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DirectorioServer is the server API for Directorio service.
+// All implementations must embed UnimplementedDirectorioServer
+// for forward compatibility.
+type DirectorioServer interface {
+	// ListGrupos lists the groups (not deleted) with their members, ordered like GET /grupos.
+	ListGrupos(context.Context, *ListGruposRequest) (*ListGruposResponse, error)
+	// GetGrupo returns a group with its members. NOT_FOUND if it does not exist or was deleted.
+	GetGrupo(context.Context, *GetGrupoRequest) (*GrupoConIntegrantes, error)
+	// ListInvestigadores lists the investigators (not deleted), optionally filtered by name.
+	ListInvestigadores(context.Context, *ListInvestigadoresRequest) (*ListInvestigadoresResponse, error)
+	// GetInvestigador returns an investigator. NOT_FOUND if it does not exist or was deleted.
+	GetInvestigador(context.Context, *GetInvestigadorRequest) (*Investigador, error)
+	mustEmbedUnimplementedDirectorioServer()
+}
+
+// UnimplementedDirectorioServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDirectorioServer struct{}
+
+func (UnimplementedDirectorioServer) ListGrupos(context.Context, *ListGruposRequest) (*ListGruposResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListGrupos not implemented")
+}
+func (UnimplementedDirectorioServer) GetGrupo(context.Context, *GetGrupoRequest) (*GrupoConIntegrantes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGrupo not implemented")
+}
+func (UnimplementedDirectorioServer) ListInvestigadores(context.Context, *ListInvestigadoresRequest) (*ListInvestigadoresResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListInvestigadores not implemented")
+}
+func (UnimplementedDirectorioServer) GetInvestigador(context.Context, *GetInvestigadorRequest) (*Investigador, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInvestigador not implemented")
+}
+func (UnimplementedDirectorioServer) mustEmbedUnimplementedDirectorioServer() {}
+func (UnimplementedDirectorioServer) testEmbeddedByValue()                    {}
+
+// UnsafeDirectorioServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DirectorioServer will
+// result in compilation errors.
+type UnsafeDirectorioServer interface {
+	mustEmbedUnimplementedDirectorioServer()
+}
+
+func RegisterDirectorioServer(s grpc.ServiceRegistrar, srv DirectorioServer) {
+	// If the following call pancis, it indicates UnimplementedDirectorioServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Directorio_ServiceDesc, srv)
+}
+
+func _Directorio_ListGrupos_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGruposRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DirectorioServer).ListGrupos(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Directorio_ListGrupos_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DirectorioServer).ListGrupos(ctx, req.(*ListGruposRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Directorio_GetGrupo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetGrupoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DirectorioServer).GetGrupo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Directorio_GetGrupo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DirectorioServer).GetGrupo(ctx, req.(*GetGrupoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Directorio_ListInvestigadores_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListInvestigadoresRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DirectorioServer).ListInvestigadores(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Directorio_ListInvestigadores_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DirectorioServer).ListInvestigadores(ctx, req.(*ListInvestigadoresRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Directorio_GetInvestigador_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetInvestigadorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DirectorioServer).GetInvestigador(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Directorio_GetInvestigador_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DirectorioServer).GetInvestigador(ctx, req.(*GetInvestigadorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Directorio_ServiceDesc is the grpc.ServiceDesc for Directorio service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Directorio_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "apigrupos.directorio.v1.Directorio",
+	HandlerType: (*DirectorioServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListGrupos",
+			Handler:    _Directorio_ListGrupos_Handler,
+		},
+		{
+			MethodName: "GetGrupo",
+			Handler:    _Directorio_GetGrupo_Handler,
+		},
+		{
+			MethodName: "ListInvestigadores",
+			Handler:    _Directorio_ListInvestigadores_Handler,
+		},
+		{
+			MethodName: "GetInvestigador",
+			Handler:    _Directorio_GetInvestigador_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "directorio.proto",
+}
@@ -0,0 +1,142 @@
+package reports
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/go-pdf/fpdf"
+)
+
+// newPDF creates an A4 document with the report footer. The returned translator converts UTF-8
+// to the cp1252 core fonts so accents and Ñ render correctly.
+func newPDF(titulo string) (*fpdf.Fpdf, func(string) string) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetTitle(tr(titulo), false)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, 10, tr(fmt.Sprintf("Generado el %s - Página %d", time.Now().Format("02/01/2006 15:04"), pdf.PageNo())), "", 0, "C", false, 0, "")
+	})
+	return pdf, tr
+}
+
+// WriteGrupoPDF renders a printable report of a group (data, resolution and member roster) to w.
+func WriteGrupoPDF(w io.Writer, g *models.GrupoWithInvestigadores) error {
+	pdf, tr := newPDF("Reporte de grupo de investigación")
+	writeGrupoPage(pdf, tr, g)
+	return pdf.Output(w)
+}
+
+// WriteGruposPDF renders a report of many groups to w: a cover page with the title and the number
+// of groups, followed by one page per group.
+func WriteGruposPDF(w io.Writer, titulo string, grupos []models.GrupoWithInvestigadores) error {
+	pdf, tr := newPDF(titulo)
+
+	pdf.AddPage()
+	pdf.SetY(100)
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.MultiCell(0, 10, tr(titulo), "", "C", false)
+	pdf.Ln(6)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.CellFormat(0, 8, tr(fmt.Sprintf("%d grupos de investigación", len(grupos))), "", 1, "C", false, 0, "")
+
+	for i := range grupos {
+		writeGrupoPage(pdf, tr, &grupos[i])
+	}
+	return pdf.Output(w)
+}
+
+// writeGrupoPage adds a page with a group's data, member roster and publications.
+func writeGrupoPage(pdf *fpdf.Fpdf, tr func(string) string, g *models.GrupoWithInvestigadores) {
+	pdf.AddPage()
+
+	// Title
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.MultiCell(0, 8, tr(g.Grupo.Nombre), "", "C", false)
+	pdf.Ln(4)
+
+	// Group data
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(0, 8, tr("Datos del grupo"), "B", 1, "L", false, 0, "")
+	pdf.Ln(2)
+	fields := [][2]string{
+		{"Número de resolución", g.Grupo.NumeroResolucion},
+		{"Línea de investigación", g.Grupo.LineaInvestigacion},
+		{"Tipo de investigación", g.Grupo.TipoInvestigacion},
+		{"Fecha de registro", g.Grupo.FechaRegistro.Format("02/01/2006")},
+	}
+	for _, f := range fields {
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(55, 7, tr(f[0]+":"), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.MultiCell(0, 7, tr(f[1]), "", "L", false)
+	}
+	pdf.Ln(4)
+
+	// Member roster
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(0, 8, tr(fmt.Sprintf("Integrantes (%d)", len(g.Investigadores))), "B", 1, "L", false, 0, "")
+	pdf.Ln(2)
+
+	widths := []float64{10, 65, 65, 50}
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(230, 230, 230)
+	for i, h := range []string{"N°", "Apellidos", "Nombres", "Rol"} {
+		pdf.CellFormat(widths[i], 7, tr(h), "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 10)
+	for i, inv := range g.Investigadores {
+		pdf.CellFormat(widths[0], 7, fmt.Sprintf("%d", i+1), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(widths[1], 7, tr(inv.Apellido), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[2], 7, tr(inv.Nombre), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[3], 7, tr(inv.Rol), "1", 0, "L", false, 0, "")
+		pdf.Ln(-1)
+	}
+	if len(g.Investigadores) == 0 {
+		pdf.CellFormat(0, 7, tr("El grupo no tiene integrantes registrados."), "1", 1, "C", false, 0, "")
+	}
+
+	// Publications, when loaded (single-group report)
+	if len(g.Publicaciones) > 0 {
+		pdf.Ln(4)
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(0, 8, tr(fmt.Sprintf("Publicaciones (%d)", len(g.Publicaciones))), "B", 1, "L", false, 0, "")
+		pdf.Ln(2)
+		pdf.SetFont("Helvetica", "", 10)
+		for i, pub := range g.Publicaciones {
+			ref := fmt.Sprintf("%d. %s (%d). %s", i+1, pub.Titulo, pub.Anio, pub.Revista)
+			if pub.DOI != nil {
+				ref += ". doi:" + *pub.DOI
+			}
+			pdf.MultiCell(0, 6, tr(ref), "", "L", false)
+		}
+	}
+
+	// Projects, when loaded (single-group report)
+	if len(g.Proyectos) > 0 {
+		pdf.Ln(4)
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(0, 8, tr(fmt.Sprintf("Proyectos (%d)", len(g.Proyectos))), "B", 1, "L", false, 0, "")
+		pdf.Ln(2)
+		pdf.SetFont("Helvetica", "", 10)
+		for i, p := range g.Proyectos {
+			ref := fmt.Sprintf("%d. %s", i+1, p.Titulo)
+			if p.Codigo != nil {
+				ref += " [" + *p.Codigo + "]"
+			}
+			periodo := p.FechaInicio.Format("02/01/2006") + " - "
+			if p.FechaFin != nil {
+				periodo += p.FechaFin.Format("02/01/2006")
+			}
+			ref += fmt.Sprintf(". %s (%s)", periodo, strings.ReplaceAll(p.Estado, "_", " "))
+			pdf.MultiCell(0, 6, tr(ref), "", "L", false)
+		}
+	}
+}
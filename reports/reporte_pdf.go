@@ -0,0 +1,100 @@
+package reports
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/go-pdf/fpdf"
+)
+
+// titulosAgrupacion names the first column of the group report for each grouping.
+var titulosAgrupacion = map[string]string{
+	models.ReportePorFacultad: "Facultad",
+	models.ReportePorLinea:    "Línea de investigación",
+	models.ReportePorAnio:     "Año de registro",
+}
+
+// TablaReporteGrupos returns the group report as table rows: the header, one row per key and the
+// total as the last row. It is shared by the XLSX and PDF versions of the report.
+func TablaReporteGrupos(r *models.ReporteGrupos) [][]string {
+	fila := func(f models.FilaReporteGrupos) []string {
+		return []string{
+			f.Clave,
+			strconv.Itoa(f.TotalGrupos),
+			strconv.Itoa(f.TotalIntegrantes),
+			strconv.Itoa(f.Docentes),
+			strconv.Itoa(f.Estudiantes),
+			strconv.Itoa(f.Externos),
+			strconv.FormatFloat(f.PromedioIntegrantes, 'f', 2, 64),
+		}
+	}
+	tabla := [][]string{{titulosAgrupacion[r.AgrupadoPor], "Grupos", "Integrantes", "Docentes", "Estudiantes", "Externos", "Promedio por grupo"}}
+	for _, f := range r.Filas {
+		tabla = append(tabla, fila(f))
+	}
+	return append(tabla, fila(r.Total))
+}
+
+// WriteReporteGruposPDF renders the group report to w as a table with the total in bold.
+func WriteReporteGruposPDF(w io.Writer, r *models.ReporteGrupos) error {
+	titulo := "Reporte de grupos de investigación por " + titulosAgrupacion[r.AgrupadoPor]
+	pdf, tr := newPDF(titulo)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.MultiCell(0, 8, tr(titulo), "", "C", false)
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 7, tr(fmt.Sprintf("Grupos aprobados al %s", r.GeneradoEn.Local().Format("02/01/2006"))), "", 1, "C", false, 0, "")
+	pdf.Ln(4)
+
+	tabla := TablaReporteGrupos(r)
+	widths := []float64{58, 20, 22, 20, 22, 20, 28}
+	encabezado := func() {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(230, 230, 230)
+		for i, h := range tabla[0] {
+			pdf.CellFormat(widths[i], 7, tr(h), "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	encabezado()
+	_, alto := pdf.GetPageSize()
+	_, _, _, margen := pdf.GetMargins()
+	for n, celdas := range tabla[1:] {
+		total := n == len(tabla)-2
+		if total {
+			pdf.SetFont("Helvetica", "B", 9)
+		} else {
+			pdf.SetFont("Helvetica", "", 9)
+		}
+		// Repeat the header on each new page
+		if pdf.GetY()+7 > alto-margen {
+			pdf.AddPage()
+			encabezado()
+			pdf.SetFont("Helvetica", "", 9)
+		}
+		pdf.CellFormat(widths[0], 7, recortar(pdf, tr(celdas[0]), widths[0]-2), "1", 0, "L", total, 0, "")
+		for i, c := range celdas[1:] {
+			pdf.CellFormat(widths[i+1], 7, c, "1", 0, "R", total, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	if len(r.Filas) == 0 {
+		pdf.Ln(2)
+		pdf.CellFormat(0, 7, tr("No hay grupos aprobados registrados."), "", 1, "C", false, 0, "")
+	}
+	return pdf.Output(w)
+}
+
+// recortar shortens s with "..." so it fits in ancho mm with the current font.
+func recortar(pdf *fpdf.Fpdf, s string, ancho float64) string {
+	if pdf.GetStringWidth(s) <= ancho {
+		return s
+	}
+	for len(s) > 0 && pdf.GetStringWidth(s+"...") > ancho {
+		s = s[:len(s)-1]
+	}
+	return s + "..."
+}
@@ -0,0 +1,140 @@
+package reports
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Formatos de las exportaciones tabulares.
+const (
+	FormatoCSV  = "csv"
+	FormatoXLSX = "xlsx"
+)
+
+// TablaWriter writes a table row by row, so exports can be streamed without holding every row in
+// memory. Close must be called once all rows are written.
+type TablaWriter interface {
+	WriteRow(celdas []string) error
+	Close() error
+}
+
+// NewTablaWriter returns a writer for formato (FormatoCSV or FormatoXLSX) and the response's
+// content type.
+func NewTablaWriter(w io.Writer, formato, hoja string) (TablaWriter, string, error) {
+	switch formato {
+	case FormatoCSV:
+		return newCSVWriter(w), "text/csv; charset=utf-8", nil
+	case FormatoXLSX:
+		t, err := newXLSXWriter(w, hoja)
+		return t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", err
+	}
+	return nil, "", fmt.Errorf("formato desconocido: %q", formato)
+}
+
+type csvWriter struct {
+	w     *csv.Writer
+	first bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w), first: true}
+}
+
+func (t *csvWriter) WriteRow(celdas []string) error {
+	fila := make([]string, len(celdas))
+	for i, c := range celdas {
+		// Cells that a spreadsheet would evaluate as formulas are quoted as text
+		if c != "" && strings.ContainsRune("=+-@", rune(c[0])) {
+			c = "'" + c
+		}
+		fila[i] = c
+	}
+	if t.first && len(fila) > 0 {
+		// BOM so Excel opens the UTF-8 file with accents intact
+		fila[0] = "\ufeff" + fila[0]
+		t.first = false
+	}
+	return t.w.Write(fila)
+}
+
+func (t *csvWriter) Close() error {
+	t.w.Flush()
+	return t.w.Error()
+}
+
+// xlsxWriter writes a minimal single-sheet workbook. Cells are inline strings, so no shared string
+// table has to be built before the sheet is streamed.
+type xlsxWriter struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	fila  int
+}
+
+var xlsxPartes = []struct{ nombre, contenido string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+func newXLSXWriter(w io.Writer, hoja string) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+	for _, p := range xlsxPartes {
+		if err := writeZipEntry(zw, p.nombre, p.contenido); err != nil {
+			return nil, err
+		}
+	}
+	var nombre strings.Builder
+	xml.EscapeText(&nombre, []byte(hoja))
+	workbook := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="` + nombre.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	if err := writeZipEntry(zw, "xl/workbook.xml", workbook); err != nil {
+		return nil, err
+	}
+
+	// The sheet is the last entry, so it can be written as the rows arrive
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("error creating xlsx sheet: %w", err)
+	}
+	sheet := bufio.NewWriter(f)
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return &xlsxWriter{zw: zw, sheet: sheet}, nil
+}
+
+func writeZipEntry(zw *zip.Writer, nombre, contenido string) error {
+	f, err := zw.Create(nombre)
+	if err != nil {
+		return fmt.Errorf("error creating xlsx part %s: %w", nombre, err)
+	}
+	_, err = io.WriteString(f, contenido)
+	return err
+}
+
+func (t *xlsxWriter) WriteRow(celdas []string) error {
+	t.fila++
+	fmt.Fprintf(t.sheet, `<row r="%d">`, t.fila)
+	for _, c := range celdas {
+		t.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		xml.EscapeText(t.sheet, []byte(c))
+		t.sheet.WriteString(`</t></is></c>`)
+	}
+	_, err := t.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (t *xlsxWriter) Close() error {
+	t.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := t.sheet.Flush(); err != nil {
+		return fmt.Errorf("error writing xlsx sheet: %w", err)
+	}
+	return t.zw.Close()
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+const grupoArchivoColumns = `a.idArchivo, a.idGrupo, a.nombre, a.tipo, a.archivo, a.publico, a.subidoPor, a.createdAt, a.updatedAt`
+
+// scanGrupoArchivo scans a row selected with grupoArchivoColumns.
+func scanGrupoArchivo(scanner interface{ Scan(...interface{}) error }) (*models.GrupoArchivo, error) {
+	var a models.GrupoArchivo
+	var subidoPor sql.NullInt64
+	if err := scanner.Scan(&a.ID, &a.IDGrupo, &a.Nombre, &a.Tipo, &a.Archivo, &a.Publico, &subidoPor, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return nil, err
+	}
+	a.SubidoPor = nullIntPtr(subidoPor)
+	return &a, nil
+}
+
+// nullIntPtr converts a nullable integer column to *int.
+func nullIntPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
+
+// CreateGrupoArchivo inserts a new attachment for a group.
+func CreateGrupoArchivo(ctx context.Context, db *sql.DB, a *models.GrupoArchivo) error {
+	query := `INSERT INTO grupo_archivo (idGrupo, nombre, tipo, archivo, publico, subidoPor)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING idArchivo, createdAt, updatedAt`
+	if err := db.QueryRowContext(ctx, query, a.IDGrupo, a.Nombre, a.Tipo, a.Archivo, a.Publico, a.SubidoPor).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return fmt.Errorf("error inserting group attachment: %w", err)
+	}
+	return nil
+}
+
+// GetGrupoArchivos lists a group's attachments, only the public ones if soloPublicos is set.
+func GetGrupoArchivos(ctx context.Context, db *sql.DB, idGrupo int, soloPublicos bool) ([]models.GrupoArchivo, error) {
+	query := `SELECT ` + grupoArchivoColumns + ` FROM grupo_archivo a WHERE a.idGrupo = $1 AND (NOT $2 OR a.publico) ORDER BY a.createdAt, a.idArchivo`
+	rows, err := db.QueryContext(ctx, query, idGrupo, soloPublicos)
+	if err != nil {
+		return nil, fmt.Errorf("error querying group attachments: %w", err)
+	}
+	defer rows.Close()
+
+	archivos := []models.GrupoArchivo{}
+	for rows.Next() {
+		a, err := scanGrupoArchivo(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning group attachment: %w", err)
+		}
+		archivos = append(archivos, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating group attachments: %w", err)
+	}
+	return archivos, nil
+}
+
+// GetGrupoArchivoByID retrieves one attachment of a group. Returns (nil, nil) if not found.
+func GetGrupoArchivoByID(ctx context.Context, db *sql.DB, idGrupo, idArchivo int) (*models.GrupoArchivo, error) {
+	query := `SELECT ` + grupoArchivoColumns + ` FROM grupo_archivo a WHERE a.idGrupo = $1 AND a.idArchivo = $2`
+	a, err := scanGrupoArchivo(db.QueryRowContext(ctx, query, idGrupo, idArchivo))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting group attachment by ID: %w", err)
+	}
+	return a, nil
+}
+
+// CreateEnlaceCompartido stores a share link (by token hash) valid for the given number of hours.
+func CreateEnlaceCompartido(ctx context.Context, db *sql.DB, idArchivo int, tokenHash string, horas int, creadoPor *int) (*models.EnlaceCompartido, error) {
+	e := models.EnlaceCompartido{IDArchivo: idArchivo, CreadoPor: creadoPor}
+	query := `INSERT INTO enlace_compartido (idArchivo, tokenHash, expiraEn, creadoPor)
+		VALUES ($1, $2, CURRENT_TIMESTAMP + make_interval(hours => $3), $4) RETURNING idEnlace, expiraEn, createdAt`
+	if err := db.QueryRowContext(ctx, query, idArchivo, tokenHash, horas, creadoPor).Scan(&e.ID, &e.ExpiraEn, &e.CreatedAt); err != nil {
+		return nil, fmt.Errorf("error inserting share link: %w", err)
+	}
+	return &e, nil
+}
+
+// GetArchivoByEnlaceCompartido resolves a share token hash to its attachment. It returns (nil, nil)
+// if the link does not exist, has expired, or the attachment's group was deleted.
+func GetArchivoByEnlaceCompartido(ctx context.Context, db *sql.DB, tokenHash string) (*models.GrupoArchivo, *models.EnlaceCompartido, error) {
+	var e models.EnlaceCompartido
+	var creadoPor sql.NullInt64
+	query := `SELECT ` + grupoArchivoColumns + `, e.idEnlace, e.expiraEn, e.creadoPor, e.createdAt
+		FROM enlace_compartido e
+		JOIN grupo_archivo a ON a.idArchivo = e.idArchivo
+		JOIN grupo g ON g.idGrupo = a.idGrupo
+		WHERE e.tokenHash = $1 AND e.expiraEn > CURRENT_TIMESTAMP AND g.deletedAt IS NULL`
+	var a models.GrupoArchivo
+	var subidoPor sql.NullInt64
+	err := db.QueryRowContext(ctx, query, tokenHash).Scan(&a.ID, &a.IDGrupo, &a.Nombre, &a.Tipo, &a.Archivo, &a.Publico, &subidoPor, &a.CreatedAt, &a.UpdatedAt,
+		&e.ID, &e.ExpiraEn, &creadoPor, &e.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("error resolving share link: %w", err)
+	}
+	a.SubidoPor = nullIntPtr(subidoPor)
+	e.IDArchivo = a.ID
+	e.CreadoPor = nullIntPtr(creadoPor)
+	return &a, &e, nil
+}
+
+// DeleteEnlacesExpirados removes the share links that have already expired and returns how many
+// were deleted.
+func DeleteEnlacesExpirados(ctx context.Context, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM enlace_compartido WHERE expiraEn <= CURRENT_TIMESTAMP`)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired share links: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// GetAccesoArchivo returns the access a caller needs to download a stored file (one of the
+// models.AccesoArchivo* levels), from the least restrictive place it is referenced.
+// It returns "" if no record references ref.
+func GetAccesoArchivo(ctx context.Context, db *sql.DB, ref string) (string, error) {
+	query := `
+	SELECT CASE
+		WHEN EXISTS (SELECT 1 FROM grupo g WHERE g.archivo = $1 AND g.deletedAt IS NULL)
+			OR EXISTS (SELECT 1 FROM resolucion r JOIN grupo g ON g.idGrupo = r.idGrupo WHERE r.archivo = $1 AND g.deletedAt IS NULL)
+			OR EXISTS (SELECT 1 FROM grupo_archivo a JOIN grupo g ON g.idGrupo = a.idGrupo WHERE a.archivo = $1 AND a.publico AND g.deletedAt IS NULL)
+			THEN $2
+		WHEN EXISTS (SELECT 1 FROM proyecto_archivo WHERE archivo = $1)
+			OR EXISTS (SELECT 1 FROM postulacion_documento WHERE archivo = $1)
+			THEN $3
+		WHEN EXISTS (SELECT 1 FROM grupo WHERE archivo = $1)
+			OR EXISTS (SELECT 1 FROM resolucion WHERE archivo = $1)
+			OR EXISTS (SELECT 1 FROM grupo_archivo WHERE archivo = $1)
+			OR EXISTS (SELECT 1 FROM export_job WHERE archivo = $1)
+			THEN $4
+		ELSE '' END`
+	var acceso string
+	if err := db.QueryRowContext(ctx, query, ref, models.AccesoArchivoPublico, models.AccesoArchivoAutenticado, models.AccesoArchivoAdmin).Scan(&acceso); err != nil {
+		return "", fmt.Errorf("error getting file access level: %w", err)
+	}
+	return acceso, nil
+}
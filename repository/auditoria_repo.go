@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// CreateAuditLog appends an entry to the audit trail.
+func CreateAuditLog(ctx context.Context, db *sql.DB, e *models.AuditLog) error {
+	query := `INSERT INTO audit_log (idUsuario, accion, entidad, idEntidad, detalle, ip)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING idAudit, createdAt`
+	if err := db.QueryRowContext(ctx, query, e.IDUsuario, e.Accion, e.Entidad, e.IDEntidad, e.Detalle, e.IP).Scan(&e.ID, &e.CreatedAt); err != nil {
+		return fmt.Errorf("error inserting audit log entry: %w", err)
+	}
+	return nil
+}
+
+// GetAuditLogs retrieves a paginated list of audit entries, newest first, optionally filtered by entidad.
+func GetAuditLogs(ctx context.Context, db *sql.DB, entidad string, limit, offset int) ([]models.AuditLog, int, error) {
+	query := `SELECT idAudit, idUsuario, accion, entidad, idEntidad, detalle, ip, createdAt
+		FROM audit_log WHERE ($1 = '' OR entidad = $1) ORDER BY createdAt DESC, idAudit DESC LIMIT $2 OFFSET $3`
+	rows, err := db.QueryContext(ctx, query, entidad, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entradas := []models.AuditLog{}
+	for rows.Next() {
+		var e models.AuditLog
+		var idUsuario, idEntidad sql.NullInt64
+		if err := rows.Scan(&e.ID, &idUsuario, &e.Accion, &e.Entidad, &idEntidad, &e.Detalle, &e.IP, &e.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("error scanning audit log entry: %w", err)
+		}
+		e.IDUsuario = nullIntPtr(idUsuario)
+		e.IDEntidad = nullIntPtr(idEntidad)
+		entradas = append(entradas, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error after iterating audit log: %w", err)
+	}
+
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log WHERE ($1 = '' OR entidad = $1)`, entidad).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting audit log entries: %w", err)
+	}
+	return entradas, total, nil
+}
+
+// DeleteAuditLogsAntesDe removes the audit entries recorded before antes and returns how many were
+// removed.
+func DeleteAuditLogsAntesDe(ctx context.Context, db *sql.DB, antes time.Time) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM audit_log WHERE createdAt < $1`, antes)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting old audit log entries: %w", err)
+	}
+	return res.RowsAffected()
+}
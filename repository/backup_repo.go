@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+const backupColumns = `idBackup, archivo, nombreArchivo, origen, descripcion, motor, tamano, sha256, filas, creadoPor, createdAt`
+
+// scanBackup scans a row selected with backupColumns.
+func scanBackup(scanner interface{ Scan(...interface{}) error }) (*models.Backup, error) {
+	var b models.Backup
+	var filas []byte
+	var creadoPor sql.NullInt64
+	err := scanner.Scan(&b.ID, &b.Archivo, &b.NombreArchivo, &b.Origen, &b.Descripcion, &b.Motor, &b.Tamano, &b.Sha256, &filas, &creadoPor, &b.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(filas, &b.Filas); err != nil {
+		return nil, fmt.Errorf("error decoding backup row counts: %w", err)
+	}
+	b.CreadoPor = nullIntPtr(creadoPor)
+	return &b, nil
+}
+
+// CreateBackup records a backup whose file has already been stored, filling in its ID and createdAt.
+func CreateBackup(ctx context.Context, db *sql.DB, b *models.Backup) error {
+	filas, err := json.Marshal(b.Filas)
+	if err != nil {
+		return fmt.Errorf("error encoding backup row counts: %w", err)
+	}
+	query := `INSERT INTO backup (archivo, nombreArchivo, origen, descripcion, motor, tamano, sha256, filas, creadoPor)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING idBackup, createdAt`
+	err = db.QueryRowContext(ctx, query, b.Archivo, b.NombreArchivo, b.Origen, b.Descripcion, b.Motor, b.Tamano, b.Sha256, filas, b.CreadoPor).
+		Scan(&b.ID, &b.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error inserting backup: %w", err)
+	}
+	return nil
+}
+
+// GetBackup returns a backup by ID, or (nil, nil) if it does not exist.
+func GetBackup(ctx context.Context, db *sql.DB, id int) (*models.Backup, error) {
+	b, err := scanBackup(db.QueryRowContext(ctx, `SELECT `+backupColumns+` FROM backup WHERE idBackup = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting backup: %w", err)
+	}
+	return b, nil
+}
+
+// GetBackups lists the backups, newest first.
+func GetBackups(ctx context.Context, db *sql.DB) ([]models.Backup, error) {
+	return queryBackups(ctx, db, `SELECT `+backupColumns+` FROM backup ORDER BY idBackup DESC`)
+}
+
+// GetBackupsPorOrigen lists the backups of an origin (models.BackupManual...), newest first.
+func GetBackupsPorOrigen(ctx context.Context, db *sql.DB, origen string) ([]models.Backup, error) {
+	return queryBackups(ctx, db, `SELECT `+backupColumns+` FROM backup WHERE origen = $1 ORDER BY idBackup DESC`, origen)
+}
+
+func queryBackups(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]models.Backup, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying backups: %w", err)
+	}
+	defer rows.Close()
+
+	backups := []models.Backup{}
+	for rows.Next() {
+		b, err := scanBackup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning backup: %w", err)
+		}
+		backups = append(backups, *b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating backups: %w", err)
+	}
+	return backups, nil
+}
+
+// DeleteBackup removes the record of a backup; its file must be deleted by the caller.
+func DeleteBackup(ctx context.Context, db *sql.DB, id int) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM backup WHERE idBackup = $1`, id); err != nil {
+		return fmt.Errorf("error deleting backup: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// ErrBusquedaDuplicada is returned when the user already has a saved search with the same nombre.
+var ErrBusquedaDuplicada = conflictError("ya tiene una búsqueda guardada con ese nombre")
+
+// ErrBusquedaNoEncontrada is returned when the user has no saved search with the given ID.
+var ErrBusquedaNoEncontrada = notFoundError("búsqueda guardada no encontrada")
+
+const busquedaColumns = `b.idBusqueda, b.idUsuario, b.nombre, b.filtros, b.alertaEmail, b.ultimoAviso, b.createdAt, b.updatedAt`
+
+// busquedaRow scans a row of busquedaColumns, decoding its filtros.
+type busquedaRow struct {
+	b       *models.BusquedaGuardada
+	filtros []byte
+}
+
+func (r *busquedaRow) fields() []interface{} {
+	return []interface{}{&r.b.ID, &r.b.IDUsuario, &r.b.Nombre, &r.filtros, &r.b.AlertaEmail, &r.b.UltimoAviso, &r.b.CreatedAt, &r.b.UpdatedAt}
+}
+
+func (r *busquedaRow) decode() error {
+	if err := json.Unmarshal(r.filtros, &r.b.Filtros); err != nil {
+		return fmt.Errorf("error decoding saved search filters: %w", err)
+	}
+	return nil
+}
+
+// GetBusquedasByUsuario lists the saved searches of a user by nombre.
+func GetBusquedasByUsuario(ctx context.Context, db *sql.DB, idUsuario int) ([]models.BusquedaGuardada, error) {
+	query := `SELECT ` + busquedaColumns + ` FROM busqueda_guardada b WHERE b.idUsuario = $1 ORDER BY b.nombre COLLATE es_icu, b.idBusqueda`
+	rows, err := db.QueryContext(ctx, query, idUsuario)
+	if err != nil {
+		return nil, fmt.Errorf("error querying saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	busquedas := []models.BusquedaGuardada{}
+	for rows.Next() {
+		var b models.BusquedaGuardada
+		row := busquedaRow{b: &b}
+		if err := rows.Scan(row.fields()...); err != nil {
+			return nil, fmt.Errorf("error scanning saved search: %w", err)
+		}
+		if err := row.decode(); err != nil {
+			return nil, err
+		}
+		busquedas = append(busquedas, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating saved searches: %w", err)
+	}
+	return busquedas, nil
+}
+
+// CreateBusqueda saves a search for b.IDUsuario and reloads it into b. It returns
+// ErrBusquedaDuplicada if the user already has one with the same nombre.
+func CreateBusqueda(ctx context.Context, db *sql.DB, b *models.BusquedaGuardada) error {
+	filtros, err := json.Marshal(b.Filtros)
+	if err != nil {
+		return fmt.Errorf("error encoding saved search filters: %w", err)
+	}
+	query := `INSERT INTO busqueda_guardada AS b (idUsuario, nombre, filtros, alertaEmail) VALUES ($1, $2, $3, $4) RETURNING ` + busquedaColumns
+	row := busquedaRow{b: b}
+	if err := db.QueryRowContext(ctx, query, b.IDUsuario, b.Nombre, filtros, b.AlertaEmail).Scan(row.fields()...); err != nil {
+		if isPgError(err, pgUniqueViolation, "uq_busqueda_guardada_nombre") {
+			return ErrBusquedaDuplicada
+		}
+		return fmt.Errorf("error inserting saved search: %w", err)
+	}
+	return row.decode()
+}
+
+// UpdateBusqueda replaces the nombre, filtros and alertaEmail of a saved search of b.IDUsuario and
+// reloads it into b. It returns ErrBusquedaNoEncontrada or ErrBusquedaDuplicada.
+func UpdateBusqueda(ctx context.Context, db *sql.DB, b *models.BusquedaGuardada) error {
+	filtros, err := json.Marshal(b.Filtros)
+	if err != nil {
+		return fmt.Errorf("error encoding saved search filters: %w", err)
+	}
+	query := `UPDATE busqueda_guardada AS b SET nombre = $1, filtros = $2, alertaEmail = $3, updatedAt = CURRENT_TIMESTAMP
+		WHERE b.idBusqueda = $4 AND b.idUsuario = $5 RETURNING ` + busquedaColumns
+	row := busquedaRow{b: b}
+	if err := db.QueryRowContext(ctx, query, b.Nombre, filtros, b.AlertaEmail, b.ID, b.IDUsuario).Scan(row.fields()...); err != nil {
+		switch {
+		case err == sql.ErrNoRows:
+			return ErrBusquedaNoEncontrada
+		case isPgError(err, pgUniqueViolation, "uq_busqueda_guardada_nombre"):
+			return ErrBusquedaDuplicada
+		}
+		return fmt.Errorf("error updating saved search: %w", err)
+	}
+	return row.decode()
+}
+
+// DeleteBusqueda deletes a saved search of a user. It returns ErrBusquedaNoEncontrada if there is
+// no such search.
+func DeleteBusqueda(ctx context.Context, db *sql.DB, idUsuario, idBusqueda int) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM busqueda_guardada WHERE idBusqueda = $1 AND idUsuario = $2`, idBusqueda, idUsuario)
+	if err != nil {
+		return fmt.Errorf("error deleting saved search: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrBusquedaNoEncontrada
+	}
+	return nil
+}
+
+// GetAlertasBusqueda lists the saved searches with email alerts, with the email of their owner.
+func GetAlertasBusqueda(ctx context.Context, db *sql.DB) ([]models.AlertaBusqueda, error) {
+	query := `SELECT ` + busquedaColumns + `, u.email FROM busqueda_guardada b
+		JOIN Usuario u ON u.idUsuario = b.idUsuario
+		WHERE b.alertaEmail ORDER BY b.idBusqueda`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying saved search alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alertas := []models.AlertaBusqueda{}
+	for rows.Next() {
+		var a models.AlertaBusqueda
+		row := busquedaRow{b: &a.BusquedaGuardada}
+		if err := rows.Scan(append(row.fields(), &a.Email)...); err != nil {
+			return nil, fmt.Errorf("error scanning saved search alert: %w", err)
+		}
+		if err := row.decode(); err != nil {
+			return nil, err
+		}
+		alertas = append(alertas, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating saved search alerts: %w", err)
+	}
+	return alertas, nil
+}
+
+// MarkBusquedaAvisada records when the digest of a saved search was last run.
+func MarkBusquedaAvisada(ctx context.Context, db *sql.DB, idBusqueda int, fecha time.Time) error {
+	if _, err := db.ExecContext(ctx, `UPDATE busqueda_guardada SET ultimoAviso = $1 WHERE idBusqueda = $2`, fecha, idBusqueda); err != nil {
+		return fmt.Errorf("error marking saved search digest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// GetCambios retrieves up to limit changes of the feed in the order they were recorded, after the
+// change despuesDe and from desde on (zero values don't filter), optionally only of entidades.
+func GetCambios(ctx context.Context, db *sql.DB, despuesDe int64, desde time.Time, entidades []string, limit int) ([]models.Cambio, error) {
+	conditions := []string{"idCambio > $1"}
+	args := []interface{}{despuesDe}
+	if !desde.IsZero() {
+		args = append(args, desde)
+		conditions = append(conditions, fmt.Sprintf("createdAt >= $%d", len(args)))
+	}
+	if len(entidades) > 0 {
+		args = append(args, entidades)
+		conditions = append(conditions, fmt.Sprintf("entidad = ANY($%d)", len(args)))
+	}
+	args = append(args, limit)
+	query := fmt.Sprintf(`SELECT idCambio, entidad, idEntidad, operacion, createdAt FROM cambio
+		WHERE %s ORDER BY idCambio LIMIT $%d`, strings.Join(conditions, " AND "), len(args))
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying changes: %w", err)
+	}
+	defer rows.Close()
+
+	cambios := []models.Cambio{}
+	for rows.Next() {
+		var c models.Cambio
+		if err := rows.Scan(&c.ID, &c.Entidad, &c.IDEntidad, &c.Operacion, &c.Fecha); err != nil {
+			return nil, fmt.Errorf("error scanning change: %w", err)
+		}
+		cambios = append(cambios, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating changes: %w", err)
+	}
+	return cambios, nil
+}
+
+// DeleteCambiosAntesDe removes the changes recorded before antes and returns how many were removed.
+func DeleteCambiosAntesDe(ctx context.Context, db *sql.DB, antes time.Time) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM cambio WHERE createdAt < $1`, antes)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting old changes: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// GetTiposInvestigacion lists the catalog of research types, by name.
+func GetTiposInvestigacion(ctx context.Context, db *sql.DB) ([]models.TipoInvestigacion, error) {
+	rows, err := db.QueryContext(ctx, `SELECT idTipo, nombre FROM tipo_investigacion ORDER BY nombre`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying research types: %w", err)
+	}
+	defer rows.Close()
+
+	tipos := []models.TipoInvestigacion{}
+	for rows.Next() {
+		var t models.TipoInvestigacion
+		if err := rows.Scan(&t.ID, &t.Nombre); err != nil {
+			return nil, fmt.Errorf("error scanning research type: %w", err)
+		}
+		tipos = append(tipos, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating research types: %w", err)
+	}
+	return tipos, nil
+}
+
+// BuscarTipoInvestigacion returns the name, as written in the catalog, of the research type that
+// matches nombre ignoring case and accents ("basica" finds "Básica"), or "" if there is none.
+func BuscarTipoInvestigacion(ctx context.Context, db *sql.DB, nombre string) (string, error) {
+	var encontrado string
+	err := db.QueryRowContext(ctx, `SELECT nombre FROM tipo_investigacion WHERE lower(f_unaccent(nombre)) = lower(f_unaccent($1))`,
+		strings.TrimSpace(nombre)).Scan(&encontrado)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error finding research type: %w", err)
+	}
+	return encontrado, nil
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// SaveArchivoChecksum stores the SHA-256 and size of a stored file.
+func SaveArchivoChecksum(ctx context.Context, db *sql.DB, archivo, sha256 string, tamano int64) error {
+	query := `INSERT INTO archivo_checksum (archivo, sha256, tamano) VALUES ($1, $2, $3)
+		ON CONFLICT (archivo) DO UPDATE SET sha256 = EXCLUDED.sha256, tamano = EXCLUDED.tamano`
+	if _, err := db.ExecContext(ctx, query, archivo, sha256, tamano); err != nil {
+		return fmt.Errorf("error saving file checksum: %w", err)
+	}
+	return nil
+}
+
+// GetArchivoRefsSinChecksum returns the refs of group files, attachments, resolutions, postulacion
+// documents and project files with no stored checksum yet.
+func GetArchivoRefsSinChecksum(ctx context.Context, db *sql.DB) ([]string, error) {
+	query := `
+	SELECT r.archivo FROM (
+		SELECT archivo FROM grupo WHERE archivo IS NOT NULL AND archivo <> ''
+		UNION
+		SELECT archivo FROM grupo_archivo WHERE archivo <> ''
+		UNION
+		SELECT archivo FROM resolucion WHERE archivo IS NOT NULL AND archivo <> ''
+		UNION
+		SELECT archivo FROM postulacion_documento WHERE archivo <> ''
+		UNION
+		SELECT archivo FROM proyecto_archivo WHERE archivo <> ''
+	) r
+	WHERE NOT EXISTS (SELECT 1 FROM archivo_checksum c WHERE c.archivo = r.archivo)
+	ORDER BY 1`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying files without checksum: %w", err)
+	}
+	defer rows.Close()
+
+	refs := []string{}
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err != nil {
+			return nil, fmt.Errorf("error scanning file ref: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating file refs: %w", err)
+	}
+	return refs, nil
+}
+
+// GetArchivosHuerfanos returns the refs of the stored files, recorded before antes, that no group,
+// attachment, resolution, postulacion document, project file or export refers to any longer: replaced or deleted files whose
+// removal from storage failed, and uploads whose request failed after storing the file.
+func GetArchivosHuerfanos(ctx context.Context, db *sql.DB, antes time.Time) ([]string, error) {
+	query := `
+	SELECT c.archivo FROM archivo_checksum c
+	WHERE c.createdAt < $1
+		AND NOT EXISTS (SELECT 1 FROM grupo g WHERE g.archivo = c.archivo)
+		AND NOT EXISTS (SELECT 1 FROM grupo_archivo a WHERE a.archivo = c.archivo)
+		AND NOT EXISTS (SELECT 1 FROM resolucion r WHERE r.archivo = c.archivo)
+		AND NOT EXISTS (SELECT 1 FROM postulacion_documento d WHERE d.archivo = c.archivo)
+		AND NOT EXISTS (SELECT 1 FROM proyecto_archivo p WHERE p.archivo = c.archivo)
+		AND NOT EXISTS (SELECT 1 FROM export_job e WHERE e.archivo = c.archivo)
+	ORDER BY c.archivo`
+	rows, err := db.QueryContext(ctx, query, antes)
+	if err != nil {
+		return nil, fmt.Errorf("error querying orphan files: %w", err)
+	}
+	defer rows.Close()
+
+	refs := []string{}
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err != nil {
+			return nil, fmt.Errorf("error scanning file ref: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating orphan files: %w", err)
+	}
+	return refs, nil
+}
+
+// DeleteArchivoChecksum forgets a stored file, once it has been deleted from storage.
+func DeleteArchivoChecksum(ctx context.Context, db *sql.DB, archivo string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM archivo_checksum WHERE archivo = $1`, archivo); err != nil {
+		return fmt.Errorf("error deleting file checksum: %w", err)
+	}
+	return nil
+}
+
+// ocurrenciasArchivoCTE lists every reference to a file with a stored checksum, in non-deleted groups.
+const ocurrenciasArchivoCTE = `
+	WITH ocurrencias AS (
+		SELECT c.sha256, c.tamano, g.idGrupo, g.nombre AS nombreGrupo, 'grupo' AS origen, NULL::int AS idArchivo, '' AS nombre
+		FROM archivo_checksum c JOIN grupo g ON g.archivo = c.archivo
+		WHERE g.deletedAt IS NULL
+		UNION ALL
+		SELECT c.sha256, c.tamano, a.idGrupo, g.nombre, 'adjunto', a.idArchivo, a.nombre
+		FROM archivo_checksum c
+		JOIN grupo_archivo a ON a.archivo = c.archivo
+		JOIN grupo g ON g.idGrupo = a.idGrupo
+		WHERE g.deletedAt IS NULL
+	),
+	duplicados AS (
+		SELECT sha256, MAX(tamano) AS tamano FROM ocurrencias
+		GROUP BY sha256
+		HAVING COUNT(DISTINCT idGrupo) > 1 OR ($1 AND COUNT(*) > 1)
+	)`
+
+// GetArchivosDuplicados returns files whose content (SHA-256) is referenced by more than one group,
+// or, with mismoGrupo, also repeated within a single group, with pagination.
+func GetArchivosDuplicados(ctx context.Context, db *sql.DB, mismoGrupo bool, limit, offset int) ([]models.ArchivoDuplicado, int, error) {
+	var total int
+	if err := db.QueryRowContext(ctx, ocurrenciasArchivoCTE+` SELECT COUNT(*) FROM duplicados`, mismoGrupo).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting duplicate files: %w", err)
+	}
+	if total == 0 {
+		return []models.ArchivoDuplicado{}, 0, nil
+	}
+
+	rows, err := db.QueryContext(ctx, ocurrenciasArchivoCTE+` SELECT sha256, tamano FROM duplicados ORDER BY sha256 LIMIT $2 OFFSET $3`, mismoGrupo, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying duplicate files: %w", err)
+	}
+	duplicados := []models.ArchivoDuplicado{}
+	indice := map[string]int{}
+	hashes := []string{}
+	for rows.Next() {
+		var d models.ArchivoDuplicado
+		if err := rows.Scan(&d.Checksum, &d.Tamano); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("error scanning duplicate file: %w", err)
+		}
+		d.Ocurrencias = []models.OcurrenciaArchivo{}
+		indice[d.Checksum] = len(duplicados)
+		duplicados = append(duplicados, d)
+		hashes = append(hashes, d.Checksum)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error after iterating duplicate files: %w", err)
+	}
+
+	rows, err = db.QueryContext(ctx, ocurrenciasArchivoCTE+`
+		SELECT sha256, idGrupo, nombreGrupo, origen, idArchivo, nombre FROM ocurrencias
+		WHERE sha256 = ANY($2) ORDER BY sha256, idGrupo, idArchivo NULLS FIRST`, mismoGrupo, hashes)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying duplicate file references: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sha string
+		var o models.OcurrenciaArchivo
+		var idArchivo sql.NullInt64
+		if err := rows.Scan(&sha, &o.IDGrupo, &o.NombreGrupo, &o.Origen, &idArchivo, &o.Nombre); err != nil {
+			return nil, 0, fmt.Errorf("error scanning duplicate file reference: %w", err)
+		}
+		o.IDArchivo = nullIntPtr(idArchivo)
+		if i, ok := indice[sha]; ok {
+			duplicados[i].Ocurrencias = append(duplicados[i].Ocurrencias, o)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error after iterating duplicate file references: %w", err)
+	}
+	return duplicados, total, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+const comentarioColumns = `c.idComentario, c.idGrupo, c.idUsuario,
+	(SELECT u.email FROM Usuario u WHERE u.idUsuario = c.idUsuario), c.contenido, c.createdAt`
+
+// comentarioScanFields returns the scan destinations matching comentarioColumns.
+func comentarioScanFields(c *models.GrupoComentario) []interface{} {
+	return []interface{}{&c.ID, &c.IDGrupo, &c.IDUsuario, &c.Autor, &c.Contenido, &c.CreatedAt}
+}
+
+// GetComentariosByGrupo returns a page of the comments of a group, newest first, with the total count.
+func GetComentariosByGrupo(ctx context.Context, db *sql.DB, idGrupo, limit, offset int) ([]models.GrupoComentario, int, error) {
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM grupo_comentario WHERE idGrupo = $1`, idGrupo).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting group comments: %w", err)
+	}
+
+	query := `SELECT ` + comentarioColumns + ` FROM grupo_comentario c
+		WHERE c.idGrupo = $1 ORDER BY c.createdAt DESC, c.idComentario DESC LIMIT $2 OFFSET $3`
+	rows, err := db.QueryContext(ctx, query, idGrupo, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying group comments: %w", err)
+	}
+	defer rows.Close()
+
+	comentarios := []models.GrupoComentario{}
+	for rows.Next() {
+		var c models.GrupoComentario
+		if err := rows.Scan(comentarioScanFields(&c)...); err != nil {
+			return nil, 0, fmt.Errorf("error scanning group comment: %w", err)
+		}
+		comentarios = append(comentarios, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error after iterating group comments: %w", err)
+	}
+	return comentarios, total, nil
+}
+
+// GetComentario retrieves a comment of a group, or (nil, nil) if it does not exist.
+func GetComentario(ctx context.Context, db *sql.DB, idGrupo, idComentario int) (*models.GrupoComentario, error) {
+	var c models.GrupoComentario
+	query := `SELECT ` + comentarioColumns + ` FROM grupo_comentario c WHERE c.idGrupo = $1 AND c.idComentario = $2`
+	if err := db.QueryRowContext(ctx, query, idGrupo, idComentario).Scan(comentarioScanFields(&c)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting group comment: %w", err)
+	}
+	return &c, nil
+}
+
+// CreateComentario adds a comment to a group and reloads it into c. It returns
+// ErrGrupoNoEncontrado if the group does not exist.
+func CreateComentario(ctx context.Context, db *sql.DB, c *models.GrupoComentario) error {
+	query := `INSERT INTO grupo_comentario AS c (idGrupo, idUsuario, contenido) VALUES ($1, $2, $3) RETURNING ` + comentarioColumns
+	err := db.QueryRowContext(ctx, query, c.IDGrupo, c.IDUsuario, c.Contenido).Scan(comentarioScanFields(c)...)
+	if err != nil {
+		if isPgError(err, pgForeignKeyViolation, "") {
+			return ErrGrupoNoEncontrado
+		}
+		return fmt.Errorf("error inserting group comment: %w", err)
+	}
+	return nil
+}
+
+// DeleteComentario deletes a comment of a group, reporting whether it existed.
+func DeleteComentario(ctx context.Context, db *sql.DB, idGrupo, idComentario int) (bool, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM grupo_comentario WHERE idGrupo = $1 AND idComentario = $2`, idGrupo, idComentario)
+	if err != nil {
+		return false, fmt.Errorf("error deleting group comment: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
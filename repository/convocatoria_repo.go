@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// ErrConvocatoriaNoEncontrada is returned by write operations on a convocatoria that does not exist.
+var ErrConvocatoriaNoEncontrada = notFoundError("convocatoria no encontrada")
+
+// ErrGrupoYaParticipa is returned when linking a group that already participates in the convocatoria.
+var ErrGrupoYaParticipa = conflictError("el grupo ya participa en la convocatoria")
+
+// convocatoriaColumns is the column list selected for a convocatoria (aliased as c), in the order
+// expected by convocatoriaScanFields.
+const convocatoriaColumns = `c.idConvocatoria, c.nombre, c.descripcion, c.requisitos, c.documentosRequeridos, c.fechaApertura, c.fechaCierre, c.estado,
+	(SELECT COUNT(*) FROM grupo_convocatoria gc JOIN grupo g ON g.idGrupo = gc.idGrupo AND g.deletedAt IS NULL
+		AND g.estado NOT IN ` + estadosNoAprobados + ` WHERE gc.idConvocatoria = c.idConvocatoria) AS totalGrupos,
+	c.createdAt, c.updatedAt`
+
+// convocatoriaScanFields returns the scan destinations matching convocatoriaColumns.
+func convocatoriaScanFields(c *models.Convocatoria) []interface{} {
+	return []interface{}{&c.ID, &c.Nombre, &c.Descripcion, &c.Requisitos, scanArray(&c.DocumentosRequeridos), &c.FechaApertura, &c.FechaCierre, &c.Estado, &c.TotalGrupos, &c.CreatedAt, &c.UpdatedAt}
+}
+
+// GetConvocatorias retrieves a paginated list of convocatorias, optionally filtered by estado,
+// the nearest deadlines first.
+func GetConvocatorias(ctx context.Context, db *sql.DB, estado string, limit, offset int) ([]models.Convocatoria, int, error) {
+	query := `SELECT ` + convocatoriaColumns + ` FROM convocatoria c WHERE ($1 = '' OR c.estado = $1)
+		ORDER BY c.fechaCierre DESC, c.idConvocatoria LIMIT $2 OFFSET $3`
+	rows, err := db.QueryContext(ctx, query, estado, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying convocatorias page: %w", err)
+	}
+	defer rows.Close()
+
+	convocatorias := []models.Convocatoria{}
+	for rows.Next() {
+		var c models.Convocatoria
+		if err := rows.Scan(convocatoriaScanFields(&c)...); err != nil {
+			return nil, 0, fmt.Errorf("error scanning convocatoria row: %w", err)
+		}
+		convocatorias = append(convocatorias, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error after iterating through convocatoria rows: %w", err)
+	}
+
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM convocatoria WHERE ($1 = '' OR estado = $1)`, estado).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error querying total convocatoria count: %w", err)
+	}
+	return convocatorias, total, nil
+}
+
+// GetConvocatoriaByID retrieves a single convocatoria, or (nil, nil) if it does not exist.
+func GetConvocatoriaByID(ctx context.Context, db *sql.DB, id int) (*models.Convocatoria, error) {
+	var c models.Convocatoria
+	err := db.QueryRowContext(ctx, `SELECT `+convocatoriaColumns+` FROM convocatoria c WHERE c.idConvocatoria = $1`, id).Scan(convocatoriaScanFields(&c)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting convocatoria by ID: %w", err)
+	}
+	return &c, nil
+}
+
+// CreateConvocatoria inserts a new convocatoria and reloads it into c.
+func CreateConvocatoria(ctx context.Context, db *sql.DB, c *models.Convocatoria) error {
+	query := `INSERT INTO convocatoria AS c (nombre, descripcion, requisitos, documentosRequeridos, fechaApertura, fechaCierre, estado)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING ` + convocatoriaColumns
+	if err := db.QueryRowContext(ctx, query, c.Nombre, c.Descripcion, c.Requisitos, c.DocumentosRequeridos, c.FechaApertura, c.FechaCierre, c.Estado).Scan(convocatoriaScanFields(c)...); err != nil {
+		return fmt.Errorf("error inserting convocatoria: %w", err)
+	}
+	return nil
+}
+
+// UpdateConvocatoria replaces a convocatoria's fields and reloads it into c.
+// It returns ErrConvocatoriaNoEncontrada if it does not exist.
+func UpdateConvocatoria(ctx context.Context, db *sql.DB, c *models.Convocatoria) error {
+	query := `UPDATE convocatoria AS c SET nombre = $1, descripcion = $2, requisitos = $3, documentosRequeridos = $4, fechaApertura = $5, fechaCierre = $6, estado = $7
+		WHERE c.idConvocatoria = $8 RETURNING ` + convocatoriaColumns
+	err := db.QueryRowContext(ctx, query, c.Nombre, c.Descripcion, c.Requisitos, c.DocumentosRequeridos, c.FechaApertura, c.FechaCierre, c.Estado, c.ID).Scan(convocatoriaScanFields(c)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrConvocatoriaNoEncontrada
+		}
+		return fmt.Errorf("error updating convocatoria: %w", err)
+	}
+	return nil
+}
+
+// DeleteConvocatoria deletes a convocatoria, its group links and postulaciones. It reports whether it existed.
+func DeleteConvocatoria(ctx context.Context, db *sql.DB, id int) (bool, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM convocatoria WHERE idConvocatoria = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting convocatoria: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking deleted convocatoria: %w", err)
+	}
+	return n > 0, nil
+}
+
+// AddGrupoConvocatoria registers a group's participation in a convocatoria. It returns
+// ErrConvocatoriaNoEncontrada or ErrGrupoNoEncontrado for unknown ids and ErrGrupoYaParticipa if
+// the group is already linked.
+func AddGrupoConvocatoria(ctx context.Context, db *sql.DB, idConvocatoria, idGrupo int) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO grupo_convocatoria (idConvocatoria, idGrupo) VALUES ($1, $2)`, idConvocatoria, idGrupo)
+	if err != nil {
+		switch {
+		case isPgError(err, pgUniqueViolation, ""):
+			return ErrGrupoYaParticipa
+		case isPgError(err, pgForeignKeyViolation, "grupo_convocatoria_idconvocatoria_fkey"):
+			return ErrConvocatoriaNoEncontrada
+		case isPgError(err, pgForeignKeyViolation, "grupo_convocatoria_idgrupo_fkey"):
+			return ErrGrupoNoEncontrado
+		}
+		return fmt.Errorf("error linking group to convocatoria: %w", err)
+	}
+	return nil
+}
+
+// RemoveGrupoConvocatoria removes a group from a convocatoria, along with its postulacion. It reports
+// whether it participated.
+func RemoveGrupoConvocatoria(ctx context.Context, db *sql.DB, idConvocatoria, idGrupo int) (bool, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM grupo_convocatoria WHERE idConvocatoria = $1 AND idGrupo = $2`, idConvocatoria, idGrupo)
+	if err != nil {
+		return false, fmt.Errorf("error unlinking group from convocatoria: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking unlinked group: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetGruposByConvocatoria returns the non-deleted, approved groups participating in a convocatoria.
+func GetGruposByConvocatoria(ctx context.Context, db *sql.DB, idConvocatoria int) ([]models.Grupo, error) {
+	query := `SELECT ` + grupoColumns + ` FROM grupo_convocatoria gc
+		JOIN grupo g ON g.idGrupo = gc.idGrupo
+		WHERE gc.idConvocatoria = $1 AND g.deletedAt IS NULL AND g.estado NOT IN ` + estadosNoAprobados + `
+		ORDER BY g.nombre`
+	rows, err := db.QueryContext(ctx, query, idConvocatoria)
+	if err != nil {
+		return nil, fmt.Errorf("error querying convocatoria groups: %w", err)
+	}
+	defer rows.Close()
+
+	grupos := []models.Grupo{}
+	for rows.Next() {
+		var g models.Grupo
+		if err := rows.Scan(grupoScanFields(&g)...); err != nil {
+			return nil, fmt.Errorf("error scanning convocatoria group: %w", err)
+		}
+		grupos = append(grupos, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating convocatoria groups: %w", err)
+	}
+	return grupos, nil
+}
+
+// GetConvocatoriasByGrupo returns the convocatorias a group participates in, the latest deadline first.
+func GetConvocatoriasByGrupo(ctx context.Context, db *sql.DB, idGrupo int) ([]models.Convocatoria, error) {
+	query := `SELECT ` + convocatoriaColumns + ` FROM convocatoria c
+		JOIN grupo_convocatoria p ON p.idConvocatoria = c.idConvocatoria
+		WHERE p.idGrupo = $1
+		ORDER BY c.fechaCierre DESC, c.idConvocatoria`
+	rows, err := db.QueryContext(ctx, query, idGrupo)
+	if err != nil {
+		return nil, fmt.Errorf("error querying group convocatorias: %w", err)
+	}
+	defer rows.Close()
+
+	convocatorias := []models.Convocatoria{}
+	for rows.Next() {
+		var c models.Convocatoria
+		if err := rows.Scan(convocatoriaScanFields(&c)...); err != nil {
+			return nil, fmt.Errorf("error scanning group convocatoria: %w", err)
+		}
+		convocatorias = append(convocatorias, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating group convocatorias: %w", err)
+	}
+	return convocatorias, nil
+}
+
+// GetRecordatoriosPendientes returns the deadline reminders due now: for every open convocatoria
+// whose fechaCierre is at most one of umbrales days away, one reminder per participating group for
+// the smallest threshold reached, unless it was already sent. With soloVerificados only verified
+// coordinator emails are included.
+func GetRecordatoriosPendientes(ctx context.Context, db *sql.DB, umbrales []int, soloVerificados bool) ([]models.RecordatorioConvocatoria, error) {
+	query := `
+	SELECT ` + convocatoriaColumns + `, u.dias, c.fechaCierre - CURRENT_DATE, g.idGrupo, g.nombre,
+		COALESCE(array_agg(DISTINCT i.email) FILTER (WHERE i.email IS NOT NULL AND (i.emailVerificado OR NOT $3)), '{}')
+	FROM convocatoria c
+	CROSS JOIN LATERAL (SELECT MIN(d) AS dias FROM unnest($1::int[]) d WHERE d >= c.fechaCierre - CURRENT_DATE) u
+	JOIN grupo_convocatoria p ON p.idConvocatoria = c.idConvocatoria
+	JOIN grupo g ON g.idGrupo = p.idGrupo AND g.deletedAt IS NULL
+	LEFT JOIN grupo_investigador gi ON gi.idGrupo = g.idGrupo AND lower(gi.rol) = 'coordinador' AND gi.fechaFin IS NULL
+	LEFT JOIN investigador i ON i.idInvestigador = gi.idInvestigador
+	WHERE c.estado = $2 AND c.fechaCierre >= CURRENT_DATE AND u.dias IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM convocatoria_recordatorio r
+			WHERE r.idConvocatoria = c.idConvocatoria AND r.idGrupo = g.idGrupo AND r.dias = u.dias)
+	GROUP BY c.idConvocatoria, u.dias, g.idGrupo, g.nombre
+	ORDER BY c.fechaCierre, c.idConvocatoria, g.idGrupo`
+	if database.Driver() == database.DriverSQLite {
+		// No LATERAL nor date subtraction: the days left and the threshold are computed per
+		// convocatoria in a subquery joined by id
+		query = `
+		SELECT ` + convocatoriaColumns + `, u.dias, u.restantes, g.idGrupo, g.nombre,
+			json_group_array(DISTINCT i.email) FILTER (WHERE i.email IS NOT NULL AND (i.emailVerificado OR NOT $3))
+		FROM convocatoria c
+		JOIN (
+			SELECT idConvocatoria, restantes, (SELECT MIN(value) FROM json_each($1) WHERE value >= restantes) AS dias
+			FROM (SELECT idConvocatoria, CAST(julianday(fechaCierre) - julianday(CURRENT_DATE) AS INTEGER) AS restantes FROM convocatoria)
+		) u ON u.idConvocatoria = c.idConvocatoria
+		JOIN grupo_convocatoria p ON p.idConvocatoria = c.idConvocatoria
+		JOIN grupo g ON g.idGrupo = p.idGrupo AND g.deletedAt IS NULL
+		LEFT JOIN grupo_investigador gi ON gi.idGrupo = g.idGrupo AND lower(gi.rol) = 'coordinador' AND gi.fechaFin IS NULL
+		LEFT JOIN investigador i ON i.idInvestigador = gi.idInvestigador
+		WHERE c.estado = $2 AND c.fechaCierre >= CURRENT_DATE AND u.dias IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM convocatoria_recordatorio r
+				WHERE r.idConvocatoria = c.idConvocatoria AND r.idGrupo = g.idGrupo AND r.dias = u.dias)
+		GROUP BY c.idConvocatoria, u.dias, g.idGrupo, g.nombre
+		ORDER BY c.fechaCierre, c.idConvocatoria, g.idGrupo`
+	}
+	rows, err := db.QueryContext(ctx, query, umbrales, models.ConvocatoriaAbierta, soloVerificados)
+	if err != nil {
+		return nil, fmt.Errorf("error querying pending convocatoria reminders: %w", err)
+	}
+	defer rows.Close()
+
+	recordatorios := []models.RecordatorioConvocatoria{}
+	for rows.Next() {
+		var r models.RecordatorioConvocatoria
+		dest := append(convocatoriaScanFields(&r.Convocatoria), &r.Umbral, &r.Dias, &r.IDGrupo, &r.NombreGrupo, scanArray(&r.Emails))
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("error scanning convocatoria reminder: %w", err)
+		}
+		recordatorios = append(recordatorios, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating convocatoria reminders: %w", err)
+	}
+	return recordatorios, nil
+}
+
+// MarkRecordatorioEnviado records that a reminder was sent so it is not repeated.
+func MarkRecordatorioEnviado(ctx context.Context, db *sql.DB, idConvocatoria, idGrupo, umbral int) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO convocatoria_recordatorio (idConvocatoria, idGrupo, dias) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`, idConvocatoria, idGrupo, umbral)
+	if err != nil {
+		return fmt.Errorf("error recording convocatoria reminder: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"database/sql"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database/databasetest"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+func TestGetRecordatoriosPendientes(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		ctx := context.Background()
+		umbrales := []int{7, 3, 1}
+
+		c := &models.Convocatoria{
+			Nombre:        "Fondo semilla 2026",
+			FechaApertura: calendario.Hoy(),
+			FechaCierre:   calendario.Hoy().AddDate(0, 0, 5),
+			Estado:        models.ConvocatoriaAbierta,
+		}
+		if err := CreateConvocatoria(ctx, db, c); err != nil {
+			t.Fatalf("CreateConvocatoria: %v", err)
+		}
+		if err := AddGrupoConvocatoria(ctx, db, c.ID, idGrupoSalud); err != nil {
+			t.Fatalf("AddGrupoConvocatoria: %v", err)
+		}
+
+		recordatorios, err := GetRecordatoriosPendientes(ctx, db, umbrales, false)
+		if err != nil {
+			t.Fatalf("GetRecordatoriosPendientes: %v", err)
+		}
+		if len(recordatorios) != 1 {
+			t.Fatalf("GetRecordatoriosPendientes = %d reminders; want 1", len(recordatorios))
+		}
+		r := recordatorios[0]
+		// Five days left (four where CURRENT_DATE, in UTC, is already tomorrow): the smallest threshold
+		// reached is 7
+		if r.Convocatoria.ID != c.ID || r.IDGrupo != idGrupoSalud || r.Umbral != 7 || r.Dias < 4 || r.Dias > 5 {
+			t.Errorf("reminder = convocatoria %d, group %d, umbral %d, %d days; want %d, %d, 7, 5", r.Convocatoria.ID, r.IDGrupo, r.Umbral, r.Dias, c.ID, idGrupoSalud)
+		}
+		if len(r.Emails) != 1 || r.Emails[0] != "ana.quispe@example.org" {
+			t.Errorf("reminder emails = %v; want the coordinator's", r.Emails)
+		}
+
+		if err := MarkRecordatorioEnviado(ctx, db, c.ID, idGrupoSalud, r.Umbral); err != nil {
+			t.Fatalf("MarkRecordatorioEnviado: %v", err)
+		}
+		recordatorios, err = GetRecordatoriosPendientes(ctx, db, umbrales, false)
+		if err != nil {
+			t.Fatalf("GetRecordatoriosPendientes: %v", err)
+		}
+		if len(recordatorios) != 0 {
+			t.Errorf("GetRecordatoriosPendientes after sending = %d reminders; want none", len(recordatorios))
+		}
+	})
+}
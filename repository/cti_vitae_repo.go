@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// ErrCtiVitaeDuplicado is returned when another investigator is already linked to the same CTI
+// Vitae record.
+var ErrCtiVitaeDuplicado = conflictError("el registro de CTI Vitae ya está vinculado a otro investigador")
+
+const ctiVitaeColumns = `c.idInvestigador, c.idCtiVitae, c.ultimaSincronizacion, c.ultimoError, c.createdAt`
+
+// ctiVitaeScanFields returns the scan destinations matching ctiVitaeColumns.
+func ctiVitaeScanFields(c *models.CtiVitae) []interface{} {
+	return []interface{}{&c.IDInvestigador, &c.IDCtiVitae, &c.UltimaSincronizacion, &c.UltimoError, &c.CreatedAt}
+}
+
+// GetCtiVitae returns the CTI Vitae link of an investigator, or nil if it has none.
+func GetCtiVitae(ctx context.Context, db *sql.DB, idInvestigador int) (*models.CtiVitae, error) {
+	var c models.CtiVitae
+	err := db.QueryRowContext(ctx, `SELECT `+ctiVitaeColumns+` FROM investigador_cti_vitae c WHERE c.idInvestigador = $1`, idInvestigador).
+		Scan(ctiVitaeScanFields(&c)...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting CTI Vitae link: %w", err)
+	}
+	return &c, nil
+}
+
+// SetCtiVitae links an investigator to a CTI Vitae record, replacing its previous link, and reloads
+// it into c. Changing the record clears the state of the last synchronization. It returns
+// ErrCtiVitaeDuplicado or ErrInvestigadorNoExiste.
+func SetCtiVitae(ctx context.Context, db *sql.DB, c *models.CtiVitae) error {
+	query := `INSERT INTO investigador_cti_vitae AS c (idInvestigador, idCtiVitae) VALUES ($1, $2)
+		ON CONFLICT (idInvestigador) DO UPDATE SET idCtiVitae = EXCLUDED.idCtiVitae,
+			ultimaSincronizacion = CASE WHEN c.idCtiVitae = EXCLUDED.idCtiVitae THEN c.ultimaSincronizacion END,
+			ultimoError = CASE WHEN c.idCtiVitae = EXCLUDED.idCtiVitae THEN c.ultimoError END
+		RETURNING ` + ctiVitaeColumns
+	err := db.QueryRowContext(ctx, query, c.IDInvestigador, c.IDCtiVitae).Scan(ctiVitaeScanFields(c)...)
+	if err != nil {
+		switch {
+		case isPgError(err, pgUniqueViolation, "uq_investigador_cti_vitae"):
+			return ErrCtiVitaeDuplicado
+		case isPgError(err, pgForeignKeyViolation, ""):
+			return ErrInvestigadorNoExiste
+		}
+		return fmt.Errorf("error saving CTI Vitae link: %w", err)
+	}
+	return nil
+}
+
+// DeleteCtiVitae unlinks an investigator from CTI Vitae, keeping what was already imported. It
+// reports whether there was a link.
+func DeleteCtiVitae(ctx context.Context, db *sql.DB, idInvestigador int) (bool, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM investigador_cti_vitae WHERE idInvestigador = $1`, idInvestigador)
+	if err != nil {
+		return false, fmt.Errorf("error deleting CTI Vitae link: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking deleted CTI Vitae link: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetCtiVitaeVinculados lists the CTI Vitae links of the active investigators, those synchronized
+// longest ago (or never) first.
+func GetCtiVitaeVinculados(ctx context.Context, db *sql.DB) ([]models.CtiVitae, error) {
+	query := `SELECT ` + ctiVitaeColumns + ` FROM investigador_cti_vitae c
+		JOIN investigador i ON i.idInvestigador = c.idInvestigador AND i.deletedAt IS NULL
+		ORDER BY c.ultimaSincronizacion IS NOT NULL, c.ultimaSincronizacion, c.idInvestigador`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying CTI Vitae links: %w", err)
+	}
+	defer rows.Close()
+
+	vinculos := []models.CtiVitae{}
+	for rows.Next() {
+		var c models.CtiVitae
+		if err := rows.Scan(ctiVitaeScanFields(&c)...); err != nil {
+			return nil, fmt.Errorf("error scanning CTI Vitae link: %w", err)
+		}
+		vinculos = append(vinculos, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating CTI Vitae links: %w", err)
+	}
+	return vinculos, nil
+}
+
+// RegistrarSincronizacionCtiVitae records a synchronization attempt of an investigator: errMsg is
+// why it failed, or nil if it succeeded.
+func RegistrarSincronizacionCtiVitae(ctx context.Context, db *sql.DB, idInvestigador int, errMsg *string) error {
+	_, err := db.ExecContext(ctx, `UPDATE investigador_cti_vitae SET ultimaSincronizacion = CURRENT_TIMESTAMP, ultimoError = $1
+		WHERE idInvestigador = $2`, errMsg, idInvestigador)
+	if err != nil {
+		return fmt.Errorf("error recording CTI Vitae synchronization: %w", err)
+	}
+	return nil
+}
+
+// CompletarEmailInvestigador sets the email of an active investigator that has none, unverified. It
+// reports whether it was set, and returns ErrEmailDuplicado if another investigator uses it.
+func CompletarEmailInvestigador(ctx context.Context, db *sql.DB, idInvestigador int, email string) (bool, error) {
+	res, err := db.ExecContext(ctx, `UPDATE investigador SET email = $1, emailVerificado = FALSE, updatedAt = CURRENT_TIMESTAMP
+		WHERE idInvestigador = $2 AND email IS NULL AND deletedAt IS NULL`, email, idInvestigador)
+	if err != nil {
+		if isPgError(err, pgUniqueViolation, "uq_investigador_email") {
+			return false, ErrEmailDuplicado
+		}
+		return false, fmt.Errorf("error completing investigator email: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking completed investigator email: %w", err)
+	}
+	return n > 0, nil
+}
@@ -1,26 +1,272 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils/querybuilder"
 )
 
+// coordinadorUnicoIndex is the partial unique index allowing one current coordinator (without
+// fechaFin) per group. Past coordinators are kept as history.
+const coordinadorUnicoIndex = "uq_grupo_investigador_coordinador"
+
+// detalleColumns is the column list selected for a membership, in the order expected by detalleScanFields.
+const detalleColumns = `idGrupo_Investigador, idGrupo, idInvestigador, rol, fechaInicio, fechaFin, createdAt, updatedAt`
+
+// detalleColumnsGI is detalleColumns for queries that alias Grupo_Investigador as gi and join other tables.
+const detalleColumnsGI = `gi.idGrupo_Investigador, gi.idGrupo, gi.idInvestigador, gi.rol, gi.fechaInicio, gi.fechaFin, gi.createdAt, gi.updatedAt`
+
+// detalleScanFields returns the scan destinations matching detalleColumns.
+func detalleScanFields(d *models.DetalleGrupoInvestigador) []interface{} {
+	return []interface{}{&d.ID, &d.IDGrupo, &d.IDInvestigador, &d.Rol, &d.FechaInicio, &d.FechaFin, &d.CreatedAt, &d.UpdatedAt}
+}
+
+// activosEnCondition is the condition of a membership whose period (inclusive, open-ended when a
+// date is NULL) contains the date in the given placeholder.
+func activosEnCondition(alias, placeholder string) string {
+	return fmt.Sprintf(`(%[1]sfechaInicio IS NULL OR %[1]sfechaInicio <= %[2]s) AND (%[1]sfechaFin IS NULL OR %[1]sfechaFin >= %[2]s)`, alias, placeholder)
+}
+
+// membresiaPeriodoConstraint is the exclusion constraint keeping the membership periods of an
+// investigator in a group from overlapping: they can leave and rejoin, but not be a member twice at
+// the same time.
+const membresiaPeriodoConstraint = "ex_grupo_investigador_periodo"
+
+// ErrCoordinadorDuplicado is returned when a write would give a group a second coordinator.
+var ErrCoordinadorDuplicado = conflictError("el grupo ya tiene un coordinador")
+
+// ErrDetalleNoEncontrado is returned by write operations on a membership that does not exist.
+var ErrDetalleNoEncontrado = notFoundError("integrante del grupo no encontrado")
+
+// ErrMembresiaDuplicada matches a *MembresiaDuplicadaError with errors.Is.
+var ErrMembresiaDuplicada = conflictError("el investigador ya es integrante del grupo")
+
+// MembresiaDuplicadaError is returned when a write would add an investigator to a group they
+// already belong to in an overlapping period. It identifies the conflicting pair.
+type MembresiaDuplicadaError struct {
+	IDGrupo        int
+	IDInvestigador int
+}
+
+func (e *MembresiaDuplicadaError) Error() string {
+	return fmt.Sprintf("el investigador %d ya es integrante del grupo %d", e.IDInvestigador, e.IDGrupo)
+}
+
+// Unwrap makes errors.Is(err, ErrMembresiaDuplicada) (and ErrConflict) report true.
+func (e *MembresiaDuplicadaError) Unwrap() error {
+	return ErrMembresiaDuplicada
+}
+
+// MembresiaError converts a unique or exclusion violation of a Grupo_Investigador write for the given
+// pair into ErrCoordinadorDuplicado or a *MembresiaDuplicadaError. It returns nil for any other error.
+func MembresiaError(err error, idGrupo, idInvestigador int) error {
+	switch {
+	case isPgError(err, pgUniqueViolation, coordinadorUnicoIndex):
+		return ErrCoordinadorDuplicado
+	case isPgError(err, pgExclusionViolation, membresiaPeriodoConstraint):
+		return &MembresiaDuplicadaError{IDGrupo: idGrupo, IDInvestigador: idInvestigador}
+	}
+	return nil
+}
+
 // CreateDetalleGrupoInvestigador inserts a new relationship between a group and an investigator.
-func CreateDetalleGrupoInvestigador(db *sql.DB, detalle *models.DetalleGrupoInvestigador) error {
+// Like the bulk path, it returns ErrGrupoNoEncontrado or ErrInvestigadorNoExiste when either does not
+// exist or is soft-deleted.
+func CreateDetalleGrupoInvestigador(ctx context.Context, db *sql.DB, detalle *models.DetalleGrupoInvestigador) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	// Lock the group and the investigator so neither is deleted between the checks and the insert
+	if err := grupoActivoForUpdate(ctx, tx, detalle.IDGrupo); err != nil {
+		return err
+	}
+	if err := investigadorActivo(ctx, tx, detalle.IDInvestigador); err != nil {
+		return err
+	}
+
 	// Usar nombres exactos de tabla y campos según la base de datos
-	query := `INSERT INTO Grupo_Investigador (idGrupo, idInvestigador, rol) VALUES ($1, $2, $3) RETURNING idGrupo_Investigador, createdAt, updatedAt`
-	err := db.QueryRow(query, detalle.IDGrupo, detalle.IDInvestigador, detalle.Rol).Scan(&detalle.ID, &detalle.CreatedAt, &detalle.UpdatedAt)
+	query := `INSERT INTO Grupo_Investigador (idGrupo, idInvestigador, rol, fechaInicio, fechaFin) VALUES ($1, $2, $3, $4, $5) RETURNING ` + detalleColumns
+	err = tx.QueryRowContext(ctx, query, detalle.IDGrupo, detalle.IDInvestigador, detalle.Rol, detalle.FechaInicio, detalle.FechaFin).Scan(detalleScanFields(detalle)...)
 	if err != nil {
+		if merr := MembresiaError(err, detalle.IDGrupo, detalle.IDInvestigador); merr != nil {
+			return merr
+		}
 		return fmt.Errorf("error inserting group-investigator detail: %w", err)
 	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing group-investigator detail: %w", err)
+	}
+	return nil
+}
+
+// grupoActivoForUpdate locks a group that is not soft-deleted, or returns ErrGrupoNoEncontrado.
+func grupoActivoForUpdate(ctx context.Context, tx *sql.Tx, idGrupo int) error {
+	var id int
+	err := tx.QueryRowContext(ctx, `SELECT idGrupo FROM grupo WHERE idGrupo = $1 AND deletedAt IS NULL FOR UPDATE`, idGrupo).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrGrupoNoEncontrado
+	}
+	if err != nil {
+		return fmt.Errorf("error locking group: %w", err)
+	}
+	return nil
+}
+
+// investigadorActivo locks an investigator that is not soft-deleted, so DeleteInvestigador waits for
+// the membership being added, or returns ErrInvestigadorNoExiste.
+func investigadorActivo(ctx context.Context, tx *sql.Tx, idInvestigador int) error {
+	var id int
+	err := tx.QueryRowContext(ctx, `SELECT idInvestigador FROM investigador WHERE idInvestigador = $1 AND deletedAt IS NULL FOR UPDATE`, idInvestigador).Scan(&id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: %d", ErrInvestigadorNoExiste, idInvestigador)
+	}
+	if err != nil {
+		return fmt.Errorf("error checking investigator: %w", err)
+	}
 	return nil
 }
 
+// CreateDetallesGrupoInvestigador inserts many group-investigator relations in one transaction. Each
+// relation is checked against the database first (active group and investigator, not already a
+// member in an overlapping period, at most one current coordinator per group); if any fails nothing is inserted and the problems are
+// returned, one per offending item. Checks that need no database (required fields, repeated items)
+// are the caller's. On success the relations are filled with their IDs and timestamps.
+func CreateDetallesGrupoInvestigador(ctx context.Context, db *sql.DB, detalles []models.DetalleGrupoInvestigador) ([]models.ErrorDetalleBulk, error) {
+	tx, end, err := beginTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	idsGrupo := make([]int, len(detalles))
+	idsInvestigador := make([]int, len(detalles))
+	for i, d := range detalles {
+		idsGrupo[i] = d.IDGrupo
+		idsInvestigador[i] = d.IDInvestigador
+	}
+
+	// Lock the groups so concurrent membership changes can't slip between the checks and the inserts
+	grupos, err := idsExistentes(ctx, tx, `SELECT idGrupo FROM grupo WHERE idGrupo = ANY($1) AND deletedAt IS NULL ORDER BY idGrupo FOR UPDATE`, idsGrupo)
+	if err != nil {
+		return nil, fmt.Errorf("error locking groups: %w", err)
+	}
+	investigadores, err := idsExistentes(ctx, tx, `SELECT idInvestigador FROM investigador WHERE idInvestigador = ANY($1) AND deletedAt IS NULL`, idsInvestigador)
+	if err != nil {
+		return nil, fmt.Errorf("error checking investigators: %w", err)
+	}
+
+	type clave struct{ idGrupo, idInvestigador int }
+	periodos := map[clave][]models.DetalleGrupoInvestigador{}
+	conCoordinador := map[int]bool{}
+	rows, err := tx.QueryContext(ctx, `SELECT `+detalleColumns+` FROM Grupo_Investigador WHERE idGrupo = ANY($1)`, idsGrupo)
+	if err != nil {
+		return nil, fmt.Errorf("error querying current members: %w", err)
+	}
+	for rows.Next() {
+		var d models.DetalleGrupoInvestigador
+		if err := rows.Scan(detalleScanFields(&d)...); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning current member: %w", err)
+		}
+		k := clave{d.IDGrupo, d.IDInvestigador}
+		periodos[k] = append(periodos[k], d)
+		if models.EsCoordinador(d.Rol) && d.FechaFin == nil {
+			conCoordinador[d.IDGrupo] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating current members: %w", err)
+	}
+	miembro := func(d models.DetalleGrupoInvestigador) bool {
+		for _, p := range periodos[clave{d.IDGrupo, d.IDInvestigador}] {
+			if p.SeSolapaCon(d) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var errores []models.ErrorDetalleBulk
+	for i, d := range detalles {
+		switch {
+		case !grupos[d.IDGrupo]:
+			errores = append(errores, models.ErrorDetalleBulk{Indice: i, Campo: "idGrupo", Codigo: "grupo_no_encontrado", Mensaje: fmt.Sprintf("El grupo %d no existe", d.IDGrupo)})
+		case !investigadores[d.IDInvestigador]:
+			errores = append(errores, models.ErrorDetalleBulk{Indice: i, Campo: "idInvestigador", Codigo: "investigador_no_encontrado", Mensaje: fmt.Sprintf("El investigador %d no existe", d.IDInvestigador)})
+		case miembro(d):
+			errores = append(errores, models.ErrorDetalleBulk{Indice: i, Campo: "idInvestigador", Codigo: "membresia_duplicada", Mensaje: fmt.Sprintf("El investigador %d ya es integrante del grupo %d", d.IDInvestigador, d.IDGrupo)})
+		case models.EsCoordinador(d.Rol) && d.FechaFin == nil && conCoordinador[d.IDGrupo]:
+			errores = append(errores, models.ErrorDetalleBulk{Indice: i, Campo: "rol", Codigo: "coordinador_duplicado", Mensaje: fmt.Sprintf("El grupo %d ya tiene un coordinador", d.IDGrupo)})
+		}
+	}
+	if len(errores) > 0 {
+		return errores, nil
+	}
+
+	// All the inserts go to the server in a single batch
+	sentencias := make([]sentencia, len(detalles))
+	for i := range detalles {
+		d := &detalles[i]
+		sentencias[i] = sentencia{
+			query: `INSERT INTO Grupo_Investigador (idGrupo, idInvestigador, rol, fechaInicio, fechaFin) VALUES ($1, $2, $3, $4, $5) RETURNING ` + detalleColumns,
+			args:  []any{d.IDGrupo, d.IDInvestigador, d.Rol, d.FechaInicio, d.FechaFin},
+			dest:  detalleScanFields(d),
+		}
+	}
+	var berr *batchError
+	if err := execBatch(ctx, tx, sentencias); errors.As(err, &berr) {
+		i, d := berr.i, detalles[berr.i]
+		if isPgError(err, pgUniqueViolation, coordinadorUnicoIndex) {
+			return []models.ErrorDetalleBulk{{Indice: i, Campo: "rol", Codigo: "coordinador_duplicado", Mensaje: fmt.Sprintf("El grupo %d ya tiene un coordinador", d.IDGrupo)}}, nil
+		}
+		if isPgError(err, pgExclusionViolation, membresiaPeriodoConstraint) {
+			return []models.ErrorDetalleBulk{{Indice: i, Campo: "idInvestigador", Codigo: "membresia_duplicada", Mensaje: fmt.Sprintf("El investigador %d ya es integrante del grupo %d", d.IDInvestigador, d.IDGrupo)}}, nil
+		}
+		if isPgError(err, pgForeignKeyViolation, "") {
+			return []models.ErrorDetalleBulk{{Indice: i, Campo: "idInvestigador", Codigo: "investigador_no_encontrado", Mensaje: fmt.Sprintf("El investigador %d no existe", d.IDInvestigador)}}, nil
+		}
+		return nil, fmt.Errorf("error inserting group-investigator detail %d: %w", i, berr.err)
+	} else if err != nil {
+		return nil, fmt.Errorf("error inserting group-investigator details: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing group-investigator details: %w", err)
+	}
+	return nil, nil
+}
+
+// idsExistentes runs a query selecting one int column filtered by ANY($1) and returns the IDs found.
+func idsExistentes(ctx context.Context, tx *sql.Tx, query string, ids []int) (map[int]bool, error) {
+	rows, err := tx.QueryContext(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	existentes := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		existentes[id] = true
+	}
+	return existentes, rows.Err()
+}
+
 // GetDetallesByGrupoID retrieves all relationship details for a given group ID.
-func GetDetallesByGrupoID(db *sql.DB, grupoID int) ([]models.DetalleGrupoInvestigador, error) {
-	// Use lowercase snake_case and $1 placeholder
-	rows, err := db.Query(`SELECT idGrupo_Investigador, idGrupo, idInvestigador, rol, createdAt, updatedAt FROM Grupo_Investigador WHERE idGrupo = $1`, grupoID)
+func GetDetallesByGrupoID(ctx context.Context, db *sql.DB, grupoID int) ([]models.DetalleGrupoInvestigador, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+detalleColumns+` FROM Grupo_Investigador WHERE idGrupo = $1 ORDER BY idGrupo_Investigador`, grupoID)
 	if err != nil {
 		return nil, fmt.Errorf("error querying group-investigator details by group ID: %w", err)
 	}
@@ -30,7 +276,7 @@ func GetDetallesByGrupoID(db *sql.DB, grupoID int) ([]models.DetalleGrupoInvesti
 	for rows.Next() {
 		var d models.DetalleGrupoInvestigador
 		// Ensure SELECT order matches struct fields
-		if err := rows.Scan(&d.ID, &d.IDGrupo, &d.IDInvestigador, &d.Rol, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		if err := rows.Scan(detalleScanFields(&d)...); err != nil {
 			return nil, fmt.Errorf("error scanning group-investigator detail row: %w", err)
 		}
 		detalles = append(detalles, d)
@@ -43,22 +289,55 @@ func GetDetallesByGrupoID(db *sql.DB, grupoID int) ([]models.DetalleGrupoInvesti
 	return detalles, nil
 }
 
+// GetDetallesByInvestigadorID retrieves the memberships of an investigator in non-deleted groups,
+// with the group names; those in pending or rejected groups only with noAprobados. With activosEn
+// only the memberships whose period contains that date are returned.
+func GetDetallesByInvestigadorID(ctx context.Context, db *sql.DB, idInvestigador int, activosEn *calendario.Fecha, noAprobados bool) ([]models.DetalleConGrupo, error) {
+	rows, err := db.QueryContext(ctx, `
+	SELECT `+detalleColumnsGI+`, g.nombre
+	FROM Grupo_Investigador gi
+	JOIN grupo g ON g.idGrupo = gi.idGrupo AND g.deletedAt IS NULL AND ($3 OR g.estado NOT IN `+estadosNoAprobados+`)
+	WHERE gi.idInvestigador = $1 AND ($2::date IS NULL OR `+activosEnCondition("gi.", "$2")+`)
+	ORDER BY g.nombre, gi.idGrupo_Investigador`, idInvestigador, activosEn, noAprobados)
+	if err != nil {
+		return nil, fmt.Errorf("error querying group-investigator details by investigator ID: %w", err)
+	}
+	defer rows.Close()
+
+	detalles := []models.DetalleConGrupo{}
+	for rows.Next() {
+		var d models.DetalleConGrupo
+		if err := rows.Scan(append(detalleScanFields(&d.DetalleGrupoInvestigador), &d.NombreGrupo)...); err != nil {
+			return nil, fmt.Errorf("error scanning group-investigator detail row: %w", err)
+		}
+		detalles = append(detalles, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating through group-investigator detail rows: %w", err)
+	}
+	return detalles, nil
+}
+
 // DeleteDetalleGrupoInvestigador deletes a specific relationship detail by its ID.
-func DeleteDetalleGrupoInvestigador(db *sql.DB, id int) error {
+// It returns ErrDetalleNoEncontrado if it does not exist.
+func DeleteDetalleGrupoInvestigador(ctx context.Context, db *sql.DB, id int) error {
 	// Use lowercase snake_case and $1 placeholder
-	_, err := db.Exec(`DELETE FROM Grupo_Investigador WHERE idGrupo_Investigador = $1`, id)
+	res, err := db.ExecContext(ctx, `DELETE FROM Grupo_Investigador WHERE idGrupo_Investigador = $1`, id)
 	if err != nil {
 		return fmt.Errorf("error deleting group-investigator detail: %w", err)
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrDetalleNoEncontrado
+	}
 	return nil
 }
 
 // GetDetalleGrupoInvestigadorByID retrieves a single relationship detail by its ID.
 // This might be useful for updating a specific relationship (e.g., changing a role).
-func GetDetalleGrupoInvestigadorByID(db *sql.DB, id int) (*models.DetalleGrupoInvestigador, error) {
+func GetDetalleGrupoInvestigadorByID(ctx context.Context, db *sql.DB, id int) (*models.DetalleGrupoInvestigador, error) {
 	var d models.DetalleGrupoInvestigador
 	// Use lowercase snake_case and $1 placeholder
-	err := db.QueryRow(`SELECT idGrupo_Investigador, idGrupo, idInvestigador, rol, createdAt, updatedAt FROM Grupo_Investigador WHERE idGrupo_Investigador = $1`, id).Scan(&d.ID, &d.IDGrupo, &d.IDInvestigador, &d.Rol, &d.CreatedAt, &d.UpdatedAt)
+	err := db.QueryRowContext(ctx, `SELECT `+detalleColumns+` FROM Grupo_Investigador WHERE idGrupo_Investigador = $1`, id).Scan(detalleScanFields(&d)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Return nil for both when not found
@@ -69,25 +348,62 @@ func GetDetalleGrupoInvestigadorByID(db *sql.DB, id int) (*models.DetalleGrupoIn
 }
 
 // UpdateDetalleGrupoInvestigador updates an existing relationship detail.
-func UpdateDetalleGrupoInvestigador(db *sql.DB, detalle *models.DetalleGrupoInvestigador) error {
+// It returns ErrDetalleNoEncontrado if it does not exist.
+func UpdateDetalleGrupoInvestigador(ctx context.Context, db *sql.DB, detalle *models.DetalleGrupoInvestigador) error {
 	// Use lowercase snake_case and $n placeholders
-	_, err := db.Exec(`UPDATE Grupo_Investigador SET idGrupo = $1, idInvestigador = $2, rol = $3, updatedAt = CURRENT_TIMESTAMP WHERE idGrupo_Investigador = $4`, detalle.IDGrupo, detalle.IDInvestigador, detalle.Rol, detalle.ID)
+	res, err := db.ExecContext(ctx, `UPDATE Grupo_Investigador SET idGrupo = $1, idInvestigador = $2, rol = $3, fechaInicio = $4, fechaFin = $5, updatedAt = CURRENT_TIMESTAMP WHERE idGrupo_Investigador = $6`,
+		detalle.IDGrupo, detalle.IDInvestigador, detalle.Rol, detalle.FechaInicio, detalle.FechaFin, detalle.ID)
 	if err != nil {
+		if merr := MembresiaError(err, detalle.IDGrupo, detalle.IDInvestigador); merr != nil {
+			return merr
+		}
 		return fmt.Errorf("error updating group-investigator detail: %w", err)
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrDetalleNoEncontrado
+	}
 	return nil
 }
 
+// detalleFilter builds the WHERE clause of the membership listings. The listings join the
+// membership's group as g; memberships of soft-deleted groups are left out, and those of
+// unapproved groups unless f.NoAprobados.
+func detalleFilter(f models.FiltroDetalles) *querybuilder.Where {
+	var where querybuilder.Where
+	where.Add(`g.deletedAt IS NULL`)
+	if !f.NoAprobados {
+		where.Add(`g.estado NOT IN ` + estadosNoAprobados)
+	}
+	if f.IDGrupo != 0 {
+		where.Add(`gi.idGrupo = ?`, f.IDGrupo)
+	}
+	if f.Rol != "" {
+		where.Add(`lower(gi.rol) = lower(?)`, strings.TrimSpace(f.Rol))
+	}
+	if f.Nombre != "" {
+		where.Add(`EXISTS (SELECT 1 FROM Investigador i WHERE i.idInvestigador = gi.idInvestigador AND f_unaccent(i.nombre || ' ' || i.apellido) ILIKE f_unaccent(?))`, "%"+f.Nombre+"%")
+	}
+	if f.Tipo != "" {
+		where.Add(`EXISTS (SELECT 1 FROM Investigador i WHERE i.idInvestigador = gi.idInvestigador AND i.tipo = ?)`, f.Tipo)
+	}
+	if f.ActivosEn != nil {
+		where.Add(activosEnCondition("gi.", "?1::date"), *f.ActivosEn)
+	}
+	return &where
+}
+
 // GetAllDetallesGrupoInvestigador retrieves all group-investigator relationships with pagination.
-func GetAllDetallesGrupoInvestigador(db *sql.DB, limit, offset int) ([]models.DetalleGrupoInvestigador, int, error) {
+// With activosEn only the memberships whose period contains that date are returned.
+func GetAllDetallesGrupoInvestigador(ctx context.Context, db *sql.DB, f models.FiltroDetalles, limit, offset int) ([]models.DetalleGrupoInvestigador, int, error) {
+	where := detalleFilter(f)
+	args := where.Args()
 	// Query for the data page
 	query := `
-		SELECT dgi.idGrupo_Investigador, dgi.idGrupo, dgi.idInvestigador, dgi.rol, dgi.createdAt, dgi.updatedAt
-		FROM Grupo_Investigador dgi
-		ORDER BY dgi.idGrupo_Investigador
-		LIMIT $1 OFFSET $2
-	`
-	rows, err := db.Query(query, limit, offset)
+		SELECT ` + detalleColumnsGI + `
+		FROM Grupo_Investigador gi JOIN grupo g ON g.idGrupo = gi.idGrupo` + where.SQL() + `
+		ORDER BY gi.idGrupo_Investigador
+		LIMIT ` + where.Next(1) + ` OFFSET ` + where.Next(2)
+	rows, err := db.QueryContext(ctx, query, append(args, limit, offset)...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error querying group-investigator details page: %w", err)
 	}
@@ -96,7 +412,7 @@ func GetAllDetallesGrupoInvestigador(db *sql.DB, limit, offset int) ([]models.De
 	detalles := []models.DetalleGrupoInvestigador{}
 	for rows.Next() {
 		var d models.DetalleGrupoInvestigador
-		if err := rows.Scan(&d.ID, &d.IDGrupo, &d.IDInvestigador, &d.Rol, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		if err := rows.Scan(detalleScanFields(&d)...); err != nil {
 			return nil, 0, fmt.Errorf("error scanning group-investigator detail row: %w", err)
 		}
 		detalles = append(detalles, d)
@@ -107,9 +423,103 @@ func GetAllDetallesGrupoInvestigador(db *sql.DB, limit, offset int) ([]models.De
 
 	// Query for the total count
 	var total int
-	countQuery := `SELECT COUNT(*) FROM Grupo_Investigador`
-	if err := db.QueryRow(countQuery).Scan(&total); err != nil {
+	countQuery := `SELECT COUNT(*) FROM Grupo_Investigador gi JOIN grupo g ON g.idGrupo = gi.idGrupo` + where.SQL()
+	if err := db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
 		return nil, 0, fmt.Errorf("error querying total group-investigator detail count: %w", err)
 	}
 	return detalles, total, nil
-}
\ No newline at end of file
+}
+
+// GetDetalleByGrupoInvestigador retrieves the membership of an investigator in a group: the current
+// one, or else the one that ended last.
+func GetDetalleByGrupoInvestigador(ctx context.Context, db *sql.DB, idGrupo, idInvestigador int) (*models.DetalleGrupoInvestigador, error) {
+	var d models.DetalleGrupoInvestigador
+	err := db.QueryRowContext(ctx, `SELECT `+detalleColumns+` FROM Grupo_Investigador WHERE idGrupo = $1 AND idInvestigador = $2
+		ORDER BY fechaFin IS NULL DESC, fechaFin DESC, idGrupo_Investigador DESC LIMIT 1`, idGrupo, idInvestigador).Scan(detalleScanFields(&d)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting group-investigator detail by group and investigator: %w", err)
+	}
+	return &d, nil
+}
+
+// UpdateRolGrupoInvestigador changes the role of an investigator in their current membership of a
+// group. Returns nil when the investigator is not currently a member of the group.
+func UpdateRolGrupoInvestigador(ctx context.Context, db *sql.DB, idGrupo, idInvestigador int, rol string) (*models.DetalleGrupoInvestigador, error) {
+	var d models.DetalleGrupoInvestigador
+	query := `UPDATE Grupo_Investigador SET rol = $3, updatedAt = CURRENT_TIMESTAMP WHERE idGrupo = $1 AND idInvestigador = $2 AND fechaFin IS NULL
+		RETURNING ` + detalleColumns
+	err := db.QueryRowContext(ctx, query, idGrupo, idInvestigador, rol).Scan(detalleScanFields(&d)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if isPgError(err, pgUniqueViolation, coordinadorUnicoIndex) {
+			return nil, ErrCoordinadorDuplicado
+		}
+		return nil, fmt.Errorf("error updating group-investigator role: %w", err)
+	}
+	return &d, nil
+}
+
+// DeleteGrupoInvestigador removes an investigator from a group. It reports whether a membership was deleted.
+func DeleteGrupoInvestigador(ctx context.Context, db *sql.DB, idGrupo, idInvestigador int) (bool, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM Grupo_Investigador WHERE idGrupo = $1 AND idInvestigador = $2`, idGrupo, idInvestigador)
+	if err != nil {
+		return false, fmt.Errorf("error deleting group-investigator membership: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking deleted group-investigator membership: %w", err)
+	}
+	return n > 0, nil
+}
+
+// SetCoordinadorGrupo makes an investigator the coordinator of a group in one transaction: the current
+// coordinator is demoted to Integrante and the investigator's current membership is promoted (or they
+// join the group from today if they were not a member). Past memberships are left as they were.
+func SetCoordinadorGrupo(ctx context.Context, db *sql.DB, idGrupo, idInvestigador int) (*models.DetalleGrupoInvestigador, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	// Lock the group so concurrent coordinator changes are serialized
+	if err := grupoActivoForUpdate(ctx, tx, idGrupo); err != nil {
+		return nil, err
+	}
+
+	// Demote first so the partial unique index never sees two coordinators
+	_, err = tx.ExecContext(ctx, `UPDATE Grupo_Investigador SET rol = $1, updatedAt = CURRENT_TIMESTAMP WHERE idGrupo = $2 AND lower(rol) = 'coordinador' AND fechaFin IS NULL AND idInvestigador <> $3`,
+		models.RolIntegrante, idGrupo, idInvestigador)
+	if err != nil {
+		return nil, fmt.Errorf("error demoting previous coordinator: %w", err)
+	}
+
+	var d models.DetalleGrupoInvestigador
+	err = tx.QueryRowContext(ctx, `UPDATE Grupo_Investigador SET rol = $1, updatedAt = CURRENT_TIMESTAMP WHERE idGrupo = $2 AND idInvestigador = $3 AND fechaFin IS NULL
+		RETURNING `+detalleColumns, models.RolCoordinador, idGrupo, idInvestigador).
+		Scan(detalleScanFields(&d)...)
+	if err == sql.ErrNoRows {
+		if err := investigadorActivo(ctx, tx, idInvestigador); err != nil {
+			return nil, err
+		}
+		err = tx.QueryRowContext(ctx, `INSERT INTO Grupo_Investigador (idGrupo, idInvestigador, rol, fechaInicio) VALUES ($1, $2, $3, $4)
+			RETURNING `+detalleColumns, idGrupo, idInvestigador, models.RolCoordinador, calendario.Hoy()).
+			Scan(detalleScanFields(&d)...)
+	}
+	if merr := MembresiaError(err, idGrupo, idInvestigador); merr != nil {
+		return nil, merr // Joining again from today overlaps a membership that ends today
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error promoting coordinator: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing coordinator change: %w", err)
+	}
+	return &d, nil
+}
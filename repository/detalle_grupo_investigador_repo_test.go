@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database/databasetest"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// TestCreateDetalleGrupoInvestigadorConflicts checks that the unique violations of each backend
+// become the repository's errors.
+func TestCreateDetalleGrupoInvestigadorConflicts(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		tests := []struct {
+			name    string
+			detalle models.DetalleGrupoInvestigador
+			want    error
+		}{
+			{"member twice", models.DetalleGrupoInvestigador{IDGrupo: idGrupoSalud, IDInvestigador: 3, Rol: models.RolIntegrante}, ErrMembresiaDuplicada},
+			{"second coordinator", models.DetalleGrupoInvestigador{IDGrupo: idGrupoSalud, IDInvestigador: 4, Rol: models.RolCoordinador}, ErrCoordinadorDuplicado},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := CreateDetalleGrupoInvestigador(context.Background(), db, &tt.detalle)
+				if !errors.Is(err, tt.want) || !errors.Is(err, ErrConflict) {
+					t.Errorf("CreateDetalleGrupoInvestigador = %v; want %v", err, tt.want)
+				}
+			})
+		}
+
+		var dup *MembresiaDuplicadaError
+		err := CreateDetalleGrupoInvestigador(context.Background(), db, &models.DetalleGrupoInvestigador{IDGrupo: idGrupoSalud, IDInvestigador: 3, Rol: models.RolIntegrante})
+		if !errors.As(err, &dup) || dup.IDGrupo != idGrupoSalud || dup.IDInvestigador != 3 {
+			t.Errorf("CreateDetalleGrupoInvestigador = %v; want a MembresiaDuplicadaError for the pair", err)
+		}
+	})
+}
+
+// terminarMembresia ends the current membership of an investigator in a group on the given day.
+func terminarMembresia(t *testing.T, db *sql.DB, idGrupo, idInvestigador int, fin calendario.Fecha) {
+	t.Helper()
+	d, err := GetDetalleByGrupoInvestigador(context.Background(), db, idGrupo, idInvestigador)
+	if err != nil || d == nil {
+		t.Fatalf("GetDetalleByGrupoInvestigador(%d, %d) = %v, %v", idGrupo, idInvestigador, d, err)
+	}
+	d.FechaFin = &fin
+	if err := UpdateDetalleGrupoInvestigador(context.Background(), db, d); err != nil {
+		t.Fatalf("UpdateDetalleGrupoInvestigador: %v", err)
+	}
+}
+
+// TestCoordinadorHandover checks that once the coordinator's membership ends another investigator can
+// become coordinator, while the past coordinator is kept as history.
+func TestCoordinadorHandover(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		ctx := context.Background()
+		hoy := calendario.Hoy()
+		terminarMembresia(t, db, idGrupoSalud, 1, hoy) // Ana
+
+		nuevo := models.DetalleGrupoInvestigador{IDGrupo: idGrupoSalud, IDInvestigador: 4, Rol: models.RolCoordinador, FechaInicio: &hoy}
+		if err := CreateDetalleGrupoInvestigador(ctx, db, &nuevo); err != nil {
+			t.Fatalf("CreateDetalleGrupoInvestigador(new coordinator) = %v; want nil", err)
+		}
+
+		// SetCoordinadorGrupo hands over to María, demoting Jorge but not touching Ana's past period
+		d, err := SetCoordinadorGrupo(ctx, db, idGrupoSalud, 3)
+		if err != nil || d == nil || !models.EsCoordinador(d.Rol) {
+			t.Fatalf("SetCoordinadorGrupo = %+v, %v; want María as coordinator", d, err)
+		}
+		detalles, err := GetDetallesByGrupoID(ctx, db, idGrupoSalud)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roles := map[int]string{}
+		for _, d := range detalles {
+			roles[d.IDInvestigador] = d.Rol
+		}
+		want := map[int]string{1: models.RolCoordinador, 3: models.RolCoordinador, 4: models.RolIntegrante}
+		for id, rol := range want {
+			if roles[id] != rol {
+				t.Errorf("role of investigator %d = %q; want %q", id, roles[id], rol)
+			}
+		}
+	})
+}
+
+// TestRejoinGrupo checks that an investigator whose membership ended can join the group again for a
+// later period, but not for one that overlaps the past one.
+func TestRejoinGrupo(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		ctx := context.Background()
+		hoy := calendario.Hoy()
+		manana := hoy.AddDate(0, 0, 1)
+		terminarMembresia(t, db, idGrupoSalud, 3, hoy) // María
+
+		solapado := models.DetalleGrupoInvestigador{IDGrupo: idGrupoSalud, IDInvestigador: 3, Rol: models.RolIntegrante, FechaInicio: &hoy}
+		if err := CreateDetalleGrupoInvestigador(ctx, db, &solapado); !errors.Is(err, ErrMembresiaDuplicada) {
+			t.Errorf("CreateDetalleGrupoInvestigador(overlapping period) = %v; want %v", err, ErrMembresiaDuplicada)
+		}
+
+		vuelta := models.DetalleGrupoInvestigador{IDGrupo: idGrupoSalud, IDInvestigador: 3, Rol: models.RolIntegrante, FechaInicio: &manana}
+		if err := CreateDetalleGrupoInvestigador(ctx, db, &vuelta); err != nil {
+			t.Fatalf("CreateDetalleGrupoInvestigador(later period) = %v; want nil", err)
+		}
+		actual, err := GetDetalleByGrupoInvestigador(ctx, db, idGrupoSalud, 3)
+		if err != nil || actual == nil || actual.ID != vuelta.ID {
+			t.Errorf("GetDetalleByGrupoInvestigador = %+v, %v; want the new period %d", actual, err, vuelta.ID)
+		}
+
+		// The bulk path applies the same rule
+		errs, err := CreateDetallesGrupoInvestigador(ctx, db, []models.DetalleGrupoInvestigador{
+			{IDGrupo: idGrupoSalud, IDInvestigador: 3, Rol: models.RolIntegrante, FechaInicio: &manana},
+		})
+		if err != nil || len(errs) != 1 {
+			t.Errorf("CreateDetallesGrupoInvestigador(overlapping period) = %v, %v; want one error", errs, err)
+		}
+	})
+}
+
+// TestMembresiaDeleted checks that a membership can't be created for a soft-deleted group or
+// investigator, directly or by making them coordinator.
+func TestMembresiaDeleted(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		ctx := context.Background()
+		if _, err := DeleteInvestigador(ctx, db, 5, true); err != nil { // Rosa
+			t.Fatal(err)
+		}
+		if err := DeleteGrupo(ctx, db, idGrupoSalud); err != nil {
+			t.Fatal(err)
+		}
+
+		err := CreateDetalleGrupoInvestigador(ctx, db, &models.DetalleGrupoInvestigador{IDGrupo: 2, IDInvestigador: 5, Rol: models.RolIntegrante})
+		if !errors.Is(err, ErrInvestigadorNoExiste) {
+			t.Errorf("CreateDetalleGrupoInvestigador(deleted investigator) = %v; want %v", err, ErrInvestigadorNoExiste)
+		}
+		err = CreateDetalleGrupoInvestigador(ctx, db, &models.DetalleGrupoInvestigador{IDGrupo: idGrupoSalud, IDInvestigador: 4, Rol: models.RolIntegrante})
+		if !errors.Is(err, ErrGrupoNoEncontrado) {
+			t.Errorf("CreateDetalleGrupoInvestigador(deleted group) = %v; want %v", err, ErrGrupoNoEncontrado)
+		}
+		if _, err := SetCoordinadorGrupo(ctx, db, 2, 5); !errors.Is(err, ErrInvestigadorNoExiste) {
+			t.Errorf("SetCoordinadorGrupo(deleted investigator) = %v; want %v", err, ErrInvestigadorNoExiste)
+		}
+		// The failed handover leaves Luis as coordinator
+		if d, err := GetDetalleByGrupoInvestigador(ctx, db, 2, 2); err != nil || d == nil || !models.EsCoordinador(d.Rol) {
+			t.Errorf("coordinator of group 2 = %+v, %v; want Luis", d, err)
+		}
+	})
+}
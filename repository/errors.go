@@ -0,0 +1,31 @@
+package repository
+
+import "errors"
+
+// Kinds of domain errors. Every error the repository returns for a missing row, a conflict with
+// the stored data or an invalid value matches one of them with errors.Is, so callers can tell those
+// apart from real failures without knowing each specific error (ErrGrupoNoEncontrado...).
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+)
+
+// domainError is a specific domain error of one of the kinds above.
+type domainError struct {
+	kind error
+	msg  string
+}
+
+func (e *domainError) Error() string { return e.msg }
+
+// Unwrap makes errors.Is(err, kind) report true.
+func (e *domainError) Unwrap() error { return e.kind }
+
+func notFoundError(msg string) error   { return &domainError{kind: ErrNotFound, msg: msg} }
+func conflictError(msg string) error   { return &domainError{kind: ErrConflict, msg: msg} }
+func validationError(msg string) error { return &domainError{kind: ErrValidation, msg: msg} }
+
+// ErrNoSoportado is returned by the operations the SQLite backend (DB_DRIVER=sqlite) has no
+// counterpart for.
+var ErrNoSoportado = errors.New("not supported by the SQLite backend")
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// GetEstadisticasGrupos computes aggregate statistics over the non-deleted, approved groups.
+func GetEstadisticasGrupos(ctx context.Context, db *sql.DB) (*models.EstadisticasGrupos, error) {
+	stats := models.EstadisticasGrupos{}
+
+	totalsQuery := `
+	WITH miembros AS (
+		SELECT g.idGrupo, COUNT(gi.idInvestigador) AS integrantes
+		FROM grupo g
+		LEFT JOIN grupo_investigador gi ON gi.idGrupo = g.idGrupo
+		WHERE g.deletedAt IS NULL AND g.estado NOT IN ` + estadosNoAprobados + `
+		GROUP BY g.idGrupo
+	)
+	SELECT
+		(SELECT COUNT(*) FROM miembros),
+		(SELECT COUNT(DISTINCT gi.idInvestigador)
+			FROM grupo_investigador gi JOIN grupo g ON g.idGrupo = gi.idGrupo
+			WHERE g.deletedAt IS NULL AND g.estado NOT IN ` + estadosNoAprobados + `),
+		(SELECT COALESCE(AVG(integrantes), 0) FROM miembros)`
+	if err := db.QueryRowContext(ctx, totalsQuery).Scan(&stats.TotalGrupos, &stats.TotalInvestigadores, &stats.PromedioIntegrantes); err != nil {
+		return nil, fmt.Errorf("error querying group totals: %w", err)
+	}
+
+	var err error
+	if stats.PorAnio, err = countGruposBy(ctx, db, "EXTRACT(YEAR FROM g.fechaRegistro)::int::text"); err != nil {
+		return nil, err
+	}
+	if stats.PorLinea, err = countGruposBy(ctx, db, "g.lineaInvestigacion"); err != nil {
+		return nil, err
+	}
+	if stats.PorTipo, err = countGruposBy(ctx, db, "g.tipoInvestigacion"); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// countGruposBy counts non-deleted, approved groups grouped by the given SQL expression.
+// expr must be a trusted constant, never user input.
+func countGruposBy(ctx context.Context, db *sql.DB, expr string) ([]models.ConteoAgrupado, error) {
+	query := `SELECT ` + expr + ` AS clave, COUNT(*) FROM grupo g WHERE g.deletedAt IS NULL AND g.estado NOT IN ` + estadosNoAprobados + ` GROUP BY clave ORDER BY clave COLLATE es_icu`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying group counts by %s: %w", expr, err)
+	}
+	defer rows.Close()
+
+	conteos := []models.ConteoAgrupado{}
+	for rows.Next() {
+		var c models.ConteoAgrupado
+		if err := rows.Scan(&c.Clave, &c.Total); err != nil {
+			return nil, fmt.Errorf("error scanning group count: %w", err)
+		}
+		conteos = append(conteos, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating group counts: %w", err)
+	}
+	return conteos, nil
+}
+
+// sinFacultad labels the groups and investigators not assigned to any facultad.
+const sinFacultad = "Sin facultad"
+
+// GetEstadisticasPorFacultad computes the figures of each facultad, by name, from its (non-deleted,
+// approved) groups and investigators, followed by a "Sin facultad" row for those not assigned to any if
+// there are some.
+func GetEstadisticasPorFacultad(ctx context.Context, db *sql.DB) ([]models.EstadisticasFacultad, error) {
+	query := `
+	WITH por_grupos AS (
+		SELECT g.idFacultad,
+			COUNT(DISTINCT g.idGrupo) AS grupos,
+			COUNT(DISTINCT gi.idInvestigador) AS investigadores,
+			COALESCE(COUNT(gi.idGrupo_Investigador)::float8 / NULLIF(COUNT(DISTINCT g.idGrupo), 0), 0) AS promedio
+		FROM grupo g
+		LEFT JOIN grupo_investigador gi ON gi.idGrupo = g.idGrupo
+		WHERE g.deletedAt IS NULL AND g.estado NOT IN ` + estadosNoAprobados + `
+		GROUP BY g.idFacultad
+	),
+	adscritos AS (
+		SELECT i.idFacultad, COUNT(*) AS total FROM investigador i WHERE i.deletedAt IS NULL GROUP BY i.idFacultad
+	),
+	escuelas AS (
+		SELECT e.idFacultad, COUNT(*) AS total FROM escuela_profesional e GROUP BY e.idFacultad
+	)
+	SELECT * FROM (
+		SELECT f.idFacultad AS idFacultad, f.nombre AS facultad, f.siglas AS siglas, COALESCE(e.total, 0) AS escuelas,
+			COALESCE(p.grupos, 0) AS grupos, COALESCE(p.investigadores, 0) AS investigadores,
+			COALESCE(a.total, 0) AS adscritos, COALESCE(p.promedio, 0) AS promedio
+		FROM facultad f
+		LEFT JOIN escuelas e ON e.idFacultad = f.idFacultad
+		LEFT JOIN por_grupos p ON p.idFacultad = f.idFacultad
+		LEFT JOIN adscritos a ON a.idFacultad = f.idFacultad
+		UNION ALL
+		SELECT NULL, $1, NULL, 0, COALESCE(p.grupos, 0), COALESCE(p.investigadores, 0), COALESCE(a.total, 0), COALESCE(p.promedio, 0)
+		FROM (SELECT 1 AS uno) AS x
+		LEFT JOIN por_grupos p ON p.idFacultad IS NULL
+		LEFT JOIN adscritos a ON a.idFacultad IS NULL
+		WHERE p.grupos > 0 OR a.total > 0
+	) AS s
+	ORDER BY s.idFacultad IS NULL, s.facultad COLLATE es_icu`
+	rows, err := db.QueryContext(ctx, query, sinFacultad)
+	if err != nil {
+		return nil, fmt.Errorf("error querying statistics by facultad: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []models.EstadisticasFacultad{}
+	for rows.Next() {
+		var s models.EstadisticasFacultad
+		var idFacultad sql.NullInt64
+		if err := rows.Scan(&idFacultad, &s.Facultad, &s.Siglas, &s.TotalEscuelas, &s.TotalGrupos, &s.TotalInvestigadores, &s.InvestigadoresAdscritos, &s.PromedioIntegrantes); err != nil {
+			return nil, fmt.Errorf("error scanning facultad statistics: %w", err)
+		}
+		s.IDFacultad = nullIntPtr(idFacultad)
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating facultad statistics: %w", err)
+	}
+	return stats, nil
+}
+
+// clavesReporteGrupos maps each grouping of GetReporteGrupos to its SQL key.
+var clavesReporteGrupos = map[string]string{
+	models.ReportePorFacultad: "COALESCE(f.nombre, '" + sinFacultad + "')",
+	models.ReportePorLinea:    "g.lineaInvestigacion",
+	models.ReportePorAnio:     "EXTRACT(YEAR FROM g.fechaRegistro)::int::text",
+}
+
+// agregadosReporteGrupos are the figures of FilaReporteGrupos after its Clave.
+const agregadosReporteGrupos = `COUNT(DISTINCT g.idGrupo),
+		COUNT(DISTINCT i.idInvestigador),
+		COUNT(DISTINCT CASE WHEN i.tipo = 'docente' THEN i.idInvestigador END),
+		COUNT(DISTINCT CASE WHEN i.tipo = 'estudiante' THEN i.idInvestigador END),
+		COUNT(DISTINCT CASE WHEN i.tipo = 'externo' THEN i.idInvestigador END),
+		COALESCE(COUNT(gi.idGrupo_Investigador)::float8 / NULLIF(COUNT(DISTINCT g.idGrupo), 0), 0)
+	FROM grupo g
+	LEFT JOIN facultad f ON f.idFacultad = g.idFacultad
+	LEFT JOIN grupo_investigador gi ON gi.idGrupo = g.idGrupo
+	LEFT JOIN investigador i ON i.idInvestigador = gi.idInvestigador
+	WHERE g.deletedAt IS NULL AND g.estado NOT IN ` + estadosNoAprobados
+
+// GetReporteGrupos computes the figures of the non-deleted, approved groups for each value of
+// groupBy (models.ReportePorFacultad, ReportePorLinea or ReportePorAnio), ordered by it, and for
+// all of them.
+func GetReporteGrupos(ctx context.Context, db *sql.DB, groupBy string) (*models.ReporteGrupos, error) {
+	clave, ok := clavesReporteGrupos[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("agrupación de reporte desconocida: %q", groupBy)
+	}
+	reporte := models.ReporteGrupos{AgrupadoPor: groupBy, GeneradoEn: time.Now().UTC(), Filas: []models.FilaReporteGrupos{}}
+
+	query := `SELECT ` + clave + ` AS clave, ` + agregadosReporteGrupos + ` GROUP BY clave ORDER BY clave COLLATE es_icu`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying group report by %s: %w", groupBy, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f models.FilaReporteGrupos
+		if err := rows.Scan(filaReporteScanFields(&f)...); err != nil {
+			return nil, fmt.Errorf("error scanning group report row: %w", err)
+		}
+		reporte.Filas = append(reporte.Filas, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating group report rows: %w", err)
+	}
+
+	// Investigators in several rows are counted once in the total, so it is queried, not added up
+	reporte.Total.Clave = "Total"
+	if err := db.QueryRowContext(ctx, `SELECT `+agregadosReporteGrupos).Scan(filaReporteScanFields(&reporte.Total)[1:]...); err != nil {
+		return nil, fmt.Errorf("error querying group report total: %w", err)
+	}
+	return &reporte, nil
+}
+
+// filaReporteScanFields returns the scan destinations of a report row: its clave followed by
+// agregadosReporteGrupos.
+func filaReporteScanFields(f *models.FilaReporteGrupos) []interface{} {
+	return []interface{}{&f.Clave, &f.TotalGrupos, &f.TotalIntegrantes, &f.Docentes, &f.Estudiantes, &f.Externos, &f.PromedioIntegrantes}
+}
+
+// SaveEstadisticaDiaria stores the statistics of a day, replacing those already stored for it.
+func SaveEstadisticaDiaria(ctx context.Context, db *sql.DB, e *models.EstadisticaDiaria) error {
+	grupos, err := json.Marshal(e.Grupos)
+	if err != nil {
+		return fmt.Errorf("error encoding group statistics: %w", err)
+	}
+	porFacultad, err := json.Marshal(e.PorFacultad)
+	if err != nil {
+		return fmt.Errorf("error encoding facultad statistics: %w", err)
+	}
+	query := `INSERT INTO estadistica_diaria (fecha, grupos, porFacultad) VALUES ($1, $2, $3)
+		ON CONFLICT (fecha) DO UPDATE SET grupos = EXCLUDED.grupos, porFacultad = EXCLUDED.porFacultad, createdAt = CURRENT_TIMESTAMP`
+	if _, err := db.ExecContext(ctx, query, e.Fecha, grupos, porFacultad); err != nil {
+		return fmt.Errorf("error saving daily statistics: %w", err)
+	}
+	return nil
+}
+
+// GetEstadisticasDiarias returns the statistics stored for the days between desde and hasta (both
+// optional and inclusive), oldest first.
+func GetEstadisticasDiarias(ctx context.Context, db *sql.DB, desde, hasta *calendario.Fecha) ([]models.EstadisticaDiaria, error) {
+	query := `SELECT fecha, grupos, porFacultad FROM estadistica_diaria
+		WHERE ($1::date IS NULL OR fecha >= $1) AND ($2::date IS NULL OR fecha <= $2)
+		ORDER BY fecha`
+	rows, err := db.QueryContext(ctx, query, desde, hasta)
+	if err != nil {
+		return nil, fmt.Errorf("error querying daily statistics: %w", err)
+	}
+	defer rows.Close()
+
+	estadisticas := []models.EstadisticaDiaria{}
+	for rows.Next() {
+		var e models.EstadisticaDiaria
+		var grupos, porFacultad []byte
+		if err := rows.Scan(&e.Fecha, &grupos, &porFacultad); err != nil {
+			return nil, fmt.Errorf("error scanning daily statistics: %w", err)
+		}
+		if err := json.Unmarshal(grupos, &e.Grupos); err != nil {
+			return nil, fmt.Errorf("error decoding group statistics: %w", err)
+		}
+		if err := json.Unmarshal(porFacultad, &e.PorFacultad); err != nil {
+			return nil, fmt.Errorf("error decoding facultad statistics: %w", err)
+		}
+		estadisticas = append(estadisticas, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating daily statistics: %w", err)
+	}
+	return estadisticas, nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// ExplainSearchGrupos returns the execution plan of the query SearchGrupos runs for the given
+// filters and page, and the indexes it uses. With analyze the query is actually run (EXPLAIN
+// ANALYZE), so the plan includes real row counts and times. The plan is PostgreSQL's: with the
+// SQLite backend it returns ErrNoSoportado.
+func ExplainSearchGrupos(ctx context.Context, db *sql.DB, q, groupName, investigatorName string, years []int, lineasInvestigacion, tiposInvestigacion []string, facultades []int, estado, estadoVigencia string, includeDeleted bool, limit, offset int, analyze bool) (*models.PlanConsulta, error) {
+	if database.Driver() == database.DriverSQLite {
+		return nil, ErrNoSoportado
+	}
+	s := buildSearchGrupos(q, groupName, investigatorName, years, lineasInvestigacion, tiposInvestigacion, facultades, estado, estadoVigencia, includeDeleted)
+	explain := `EXPLAIN (FORMAT JSON) `
+	if analyze {
+		explain = `EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) `
+	}
+
+	var plan []byte
+	if err := db.QueryRowContext(ctx, explain+s.data, append(s.args, limit, offset)...).Scan(&plan); err != nil {
+		return nil, fmt.Errorf("error explaining group search: %w", err)
+	}
+	var arbol interface{}
+	if err := json.Unmarshal(plan, &arbol); err != nil {
+		return nil, fmt.Errorf("error decoding group search plan: %w", err)
+	}
+	return &models.PlanConsulta{
+		Consulta:  s.data,
+		Analizado: analyze,
+		Indices:   indicesDelPlan(arbol, []string{}),
+		Plan:      plan,
+	}, nil
+}
+
+// indicesDelPlan appends to indices the distinct "Index Name" values of a decoded EXPLAIN (FORMAT
+// JSON) plan, walking the nodes depth-first in the order they are listed.
+func indicesDelPlan(nodo interface{}, indices []string) []string {
+	switch v := nodo.(type) {
+	case map[string]interface{}:
+		if nombre, ok := v["Index Name"].(string); ok && !slices.Contains(indices, nombre) {
+			indices = append(indices, nombre)
+		}
+		indices = indicesDelPlan(v["Plan"], indices)
+		indices = indicesDelPlan(v["Plans"], indices)
+	case []interface{}:
+		for _, hijo := range v {
+			indices = indicesDelPlan(hijo, indices)
+		}
+	}
+	return indices
+}
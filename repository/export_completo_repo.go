@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// Secciones of the full export, in the order ExportarDirectorio reads them: the catalogs first,
+// so that a migration can load the rows in order.
+const (
+	SeccionFacultades         = "facultades"
+	SeccionEscuelas           = "escuelas"
+	SeccionTiposInvestigacion = "tiposInvestigacion"
+	SeccionInvestigadores     = "investigadores"
+	SeccionGrupos             = "grupos"
+	SeccionMembresias         = "membresias"
+)
+
+// ExportarDirectorio reads the catalogs, investigators, groups (of every estado) and memberships in
+// one read-only transaction, so the dump is consistent, and calls fila with a pointer to each row
+// as soon as it is read, section by section and by ID. Soft-deleted investigators and groups, and
+// their memberships, are only included with incluirEliminados. inicio is called before the first
+// row of each section.
+func ExportarDirectorio(ctx context.Context, db *sql.DB, incluirEliminados bool, inicio func(seccion string) error, fila func(seccion string, v any) error) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("error starting export transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	noEliminados := func(cond string) string {
+		if incluirEliminados {
+			return ""
+		}
+		return " WHERE " + cond
+	}
+	secciones := []struct {
+		nombre string
+		query  string
+		nueva  func() (any, []interface{})
+	}{
+		{SeccionFacultades, `SELECT ` + facultadColumns + ` FROM facultad ORDER BY idFacultad`, func() (any, []interface{}) {
+			var f models.Facultad
+			return &f, facultadScanFields(&f)
+		}},
+		{SeccionEscuelas, `SELECT ` + escuelaColumns + ` FROM escuela_profesional ORDER BY idEscuela`, func() (any, []interface{}) {
+			var e models.EscuelaProfesional
+			return &e, escuelaScanFields(&e)
+		}},
+		{SeccionTiposInvestigacion, `SELECT idTipo, nombre FROM tipo_investigacion ORDER BY idTipo`, func() (any, []interface{}) {
+			var t models.TipoInvestigacion
+			return &t, []interface{}{&t.ID, &t.Nombre}
+		}},
+		{SeccionInvestigadores, `SELECT ` + investigadorColumns + ` FROM investigador` + noEliminados("deletedAt IS NULL") + ` ORDER BY idInvestigador`, func() (any, []interface{}) {
+			var inv models.Investigador
+			return &inv, investigadorScanFields(&inv)
+		}},
+		{SeccionGrupos, `SELECT ` + grupoColumns + ` FROM grupo g` + noEliminados("g.deletedAt IS NULL") + ` ORDER BY g.idGrupo`, func() (any, []interface{}) {
+			var g models.Grupo
+			return &g, grupoScanFields(&g)
+		}},
+		{SeccionMembresias, `SELECT ` + detalleColumns + ` FROM Grupo_Investigador gi` + noEliminados(`EXISTS (SELECT 1 FROM grupo g WHERE g.idGrupo = gi.idGrupo AND g.deletedAt IS NULL)
+			AND EXISTS (SELECT 1 FROM investigador i WHERE i.idInvestigador = gi.idInvestigador AND i.deletedAt IS NULL)`) + ` ORDER BY idGrupo_Investigador`, func() (any, []interface{}) {
+			var d models.DetalleGrupoInvestigador
+			return &d, detalleScanFields(&d)
+		}},
+	}
+	for _, s := range secciones {
+		if err := inicio(s.nombre); err != nil {
+			return err
+		}
+		if err := exportarSeccion(ctx, tx, s.nombre, s.query, s.nueva, fila); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportarSeccion calls fila with each row of query, scanned into a new value.
+func exportarSeccion(ctx context.Context, tx *sql.Tx, seccion, query string, nueva func() (any, []interface{}), fila func(seccion string, v any) error) error {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("error querying %s for export: %w", seccion, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		v, dest := nueva()
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("error scanning %s for export: %w", seccion, err)
+		}
+		if err := fila(seccion, v); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error after iterating %s for export: %w", seccion, err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+const exportJobColumns = `idExport, parametros, estado, total, procesados, archivo, nombreArchivo, error, creadoPor, createdAt, updatedAt, finalizadoEn`
+
+// scanExportJob scans a row selected with exportJobColumns.
+func scanExportJob(scanner interface{ Scan(...interface{}) error }) (*models.ExportJob, error) {
+	var j models.ExportJob
+	var parametros []byte
+	var nombreArchivo sql.NullString
+	var creadoPor sql.NullInt64
+	err := scanner.Scan(&j.ID, &parametros, &j.Estado, &j.Total, &j.Procesados, &j.Archivo, &nombreArchivo, &j.Error, &creadoPor, &j.CreatedAt, &j.UpdatedAt, &j.FinalizadoEn)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(parametros, &j.Parametros); err != nil {
+		return nil, fmt.Errorf("error decoding export parameters: %w", err)
+	}
+	j.NombreArchivo = nombreArchivo.String
+	j.CreadoPor = nullIntPtr(creadoPor)
+	return &j, nil
+}
+
+// CreateExportJob inserts a pending export job.
+func CreateExportJob(ctx context.Context, db *sql.DB, parametros models.ParametrosExport, creadoPor *int) (*models.ExportJob, error) {
+	raw, err := json.Marshal(parametros)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding export parameters: %w", err)
+	}
+	query := `INSERT INTO export_job (parametros, estado, creadoPor) VALUES ($1, $2, $3) RETURNING ` + exportJobColumns
+	j, err := scanExportJob(db.QueryRowContext(ctx, query, raw, models.ExportPendiente, creadoPor))
+	if err != nil {
+		return nil, fmt.Errorf("error inserting export job: %w", err)
+	}
+	return j, nil
+}
+
+// GetExportJob returns an export job by ID, or (nil, nil) if it does not exist.
+func GetExportJob(ctx context.Context, db *sql.DB, id int) (*models.ExportJob, error) {
+	j, err := scanExportJob(db.QueryRowContext(ctx, `SELECT `+exportJobColumns+` FROM export_job WHERE idExport = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting export job: %w", err)
+	}
+	return j, nil
+}
+
+// UpdateExportProgress marks a job as running and records how many groups have been exported.
+func UpdateExportProgress(ctx context.Context, db *sql.DB, id, procesados, total int) error {
+	query := `UPDATE export_job SET estado = $2, procesados = $3, total = $4 WHERE idExport = $1`
+	if _, err := db.ExecContext(ctx, query, id, models.ExportEnProceso, procesados, total); err != nil {
+		return fmt.Errorf("error updating export progress: %w", err)
+	}
+	return nil
+}
+
+// CompleteExportJob stores the generated file's ref and marks the job as completed.
+func CompleteExportJob(ctx context.Context, db *sql.DB, id int, archivo, nombreArchivo string) error {
+	query := `UPDATE export_job SET estado = $2, archivo = $3, nombreArchivo = $4, procesados = total, error = NULL, finalizadoEn = CURRENT_TIMESTAMP
+		WHERE idExport = $1`
+	if _, err := db.ExecContext(ctx, query, id, models.ExportCompletado, archivo, nombreArchivo); err != nil {
+		return fmt.Errorf("error completing export job: %w", err)
+	}
+	return nil
+}
+
+// FailExportJob marks a job as failed with the given message.
+func FailExportJob(ctx context.Context, db *sql.DB, id int, mensaje string) error {
+	query := `UPDATE export_job SET estado = $2, error = $3, finalizadoEn = CURRENT_TIMESTAMP WHERE idExport = $1`
+	if _, err := db.ExecContext(ctx, query, id, models.ExportError, mensaje); err != nil {
+		return fmt.Errorf("error marking export job as failed: %w", err)
+	}
+	return nil
+}
+
+// GetExportsCompletadosAntesDe returns the completed jobs that finished before antes and still
+// have a generated file.
+func GetExportsCompletadosAntesDe(ctx context.Context, db *sql.DB, antes time.Time) ([]models.ExportJob, error) {
+	query := `SELECT ` + exportJobColumns + ` FROM export_job
+		WHERE estado = $1 AND archivo IS NOT NULL AND finalizadoEn < $2 ORDER BY idExport`
+	rows, err := db.QueryContext(ctx, query, models.ExportCompletado, antes)
+	if err != nil {
+		return nil, fmt.Errorf("error querying old export jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []models.ExportJob{}
+	for rows.Next() {
+		j, err := scanExportJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning export job: %w", err)
+		}
+		jobs = append(jobs, *j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating export jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// ExpireExportJob forgets the file of a completed job once it has been deleted from storage.
+func ExpireExportJob(ctx context.Context, db *sql.DB, id int) error {
+	query := `UPDATE export_job SET estado = $2, archivo = NULL WHERE idExport = $1`
+	if _, err := db.ExecContext(ctx, query, id, models.ExportExpirado); err != nil {
+		return fmt.Errorf("error expiring export job: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// ErrFacultadNoEncontrada is returned by write operations on a faculty that does not exist.
+var ErrFacultadNoEncontrada = notFoundError("facultad no encontrada")
+
+// ErrEscuelaNoEncontrada is returned by write operations on a professional school that does not exist.
+var ErrEscuelaNoEncontrada = notFoundError("escuela profesional no encontrada")
+
+const facultadColumns = `idFacultad, nombre, siglas, createdAt, updatedAt`
+
+func facultadScanFields(f *models.Facultad) []interface{} {
+	return []interface{}{&f.ID, &f.Nombre, &f.Siglas, &f.CreatedAt, &f.UpdatedAt}
+}
+
+const escuelaColumns = `idEscuela, idFacultad, nombre, createdAt, updatedAt`
+
+func escuelaScanFields(e *models.EscuelaProfesional) []interface{} {
+	return []interface{}{&e.ID, &e.IDFacultad, &e.Nombre, &e.CreatedAt, &e.UpdatedAt}
+}
+
+// GetFacultades lists the faculties with their schools, both by name.
+func GetFacultades(ctx context.Context, db *sql.DB) ([]models.FacultadConEscuelas, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+facultadColumns+` FROM facultad ORDER BY nombre`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying faculties: %w", err)
+	}
+	defer rows.Close()
+
+	facultades := []models.FacultadConEscuelas{}
+	indice := map[int]int{}
+	for rows.Next() {
+		f := models.FacultadConEscuelas{Escuelas: []models.EscuelaProfesional{}}
+		if err := rows.Scan(facultadScanFields(&f.Facultad)...); err != nil {
+			return nil, fmt.Errorf("error scanning faculty: %w", err)
+		}
+		indice[f.ID] = len(facultades)
+		facultades = append(facultades, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating faculties: %w", err)
+	}
+	rows.Close()
+
+	escuelas, err := queryEscuelas(ctx, db, `SELECT `+escuelaColumns+` FROM escuela_profesional ORDER BY nombre`)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range escuelas {
+		if i, ok := indice[e.IDFacultad]; ok {
+			facultades[i].Escuelas = append(facultades[i].Escuelas, e)
+		}
+	}
+	return facultades, nil
+}
+
+// GetFacultad returns a faculty with its schools, or (nil, nil) if it does not exist.
+func GetFacultad(ctx context.Context, db *sql.DB, id int) (*models.FacultadConEscuelas, error) {
+	var f models.FacultadConEscuelas
+	err := db.QueryRowContext(ctx, `SELECT `+facultadColumns+` FROM facultad WHERE idFacultad = $1`, id).Scan(facultadScanFields(&f.Facultad)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting faculty: %w", err)
+	}
+	f.Escuelas, err = queryEscuelas(ctx, db, `SELECT `+escuelaColumns+` FROM escuela_profesional WHERE idFacultad = $1 ORDER BY nombre`, id)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// CreateFacultad inserts a faculty, filling in its ID and timestamps.
+func CreateFacultad(ctx context.Context, db *sql.DB, f *models.Facultad) error {
+	err := db.QueryRowContext(ctx, `INSERT INTO facultad (nombre, siglas) VALUES ($1, $2) RETURNING `+facultadColumns, f.Nombre, f.Siglas).
+		Scan(facultadScanFields(f)...)
+	if err != nil {
+		return fmt.Errorf("error inserting faculty: %w", err)
+	}
+	return nil
+}
+
+// UpdateFacultad replaces the name and abbreviation of a faculty and reloads it into f. It returns
+// ErrFacultadNoEncontrada if there is no such faculty.
+func UpdateFacultad(ctx context.Context, db *sql.DB, f *models.Facultad) error {
+	err := db.QueryRowContext(ctx, `UPDATE facultad SET nombre = $1, siglas = $2, updatedAt = CURRENT_TIMESTAMP WHERE idFacultad = $3 RETURNING `+facultadColumns,
+		f.Nombre, f.Siglas, f.ID).Scan(facultadScanFields(f)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrFacultadNoEncontrada
+		}
+		return fmt.Errorf("error updating faculty: %w", err)
+	}
+	return nil
+}
+
+// DeleteFacultad deletes a faculty. It fails with a foreign key violation (see ConstraintError)
+// while it has schools, groups or investigators, and returns ErrFacultadNoEncontrada if there is
+// no such faculty.
+func DeleteFacultad(ctx context.Context, db *sql.DB, id int) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM facultad WHERE idFacultad = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting faculty: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrFacultadNoEncontrada
+	}
+	return nil
+}
+
+// GetEscuela returns a professional school, or (nil, nil) if it does not exist.
+func GetEscuela(ctx context.Context, db *sql.DB, id int) (*models.EscuelaProfesional, error) {
+	var e models.EscuelaProfesional
+	err := db.QueryRowContext(ctx, `SELECT `+escuelaColumns+` FROM escuela_profesional WHERE idEscuela = $1`, id).Scan(escuelaScanFields(&e)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting professional school: %w", err)
+	}
+	return &e, nil
+}
+
+// CreateEscuela inserts a professional school, filling in its ID and timestamps.
+func CreateEscuela(ctx context.Context, db *sql.DB, e *models.EscuelaProfesional) error {
+	err := db.QueryRowContext(ctx, `INSERT INTO escuela_profesional (idFacultad, nombre) VALUES ($1, $2) RETURNING `+escuelaColumns, e.IDFacultad, e.Nombre).
+		Scan(escuelaScanFields(e)...)
+	if err != nil {
+		return fmt.Errorf("error inserting professional school: %w", err)
+	}
+	return nil
+}
+
+// UpdateEscuela renames a professional school and reloads it into e; its faculty does not change.
+// It returns ErrEscuelaNoEncontrada if there is no such school.
+func UpdateEscuela(ctx context.Context, db *sql.DB, e *models.EscuelaProfesional) error {
+	err := db.QueryRowContext(ctx, `UPDATE escuela_profesional SET nombre = $1, updatedAt = CURRENT_TIMESTAMP WHERE idEscuela = $2 RETURNING `+escuelaColumns,
+		e.Nombre, e.ID).Scan(escuelaScanFields(e)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrEscuelaNoEncontrada
+		}
+		return fmt.Errorf("error updating professional school: %w", err)
+	}
+	return nil
+}
+
+// DeleteEscuela deletes a professional school. It fails with a foreign key violation (see
+// ConstraintError) while investigators belong to it, and returns ErrEscuelaNoEncontrada if there
+// is no such school.
+func DeleteEscuela(ctx context.Context, db *sql.DB, id int) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM escuela_profesional WHERE idEscuela = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting professional school: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrEscuelaNoEncontrada
+	}
+	return nil
+}
+
+func queryEscuelas(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]models.EscuelaProfesional, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying professional schools: %w", err)
+	}
+	defer rows.Close()
+
+	escuelas := []models.EscuelaProfesional{}
+	for rows.Next() {
+		var e models.EscuelaProfesional
+		if err := rows.Scan(escuelaScanFields(&e)...); err != nil {
+			return nil, fmt.Errorf("error scanning professional school: %w", err)
+		}
+		escuelas = append(escuelas, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating professional schools: %w", err)
+	}
+	return escuelas, nil
+}
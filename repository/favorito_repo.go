@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// GetFavoritosByUsuario returns a page of the non-deleted groups pinned by a user, most recently
+// pinned first, with the total count.
+func GetFavoritosByUsuario(ctx context.Context, db *sql.DB, idUsuario, limit, offset int) ([]models.GrupoFavorito, int, error) {
+	const from = ` FROM grupo_favorito fav JOIN grupo g ON g.idGrupo = fav.idGrupo
+		WHERE fav.idUsuario = $1 AND g.deletedAt IS NULL`
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*)`+from, idUsuario).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting favorite groups: %w", err)
+	}
+
+	query := `SELECT ` + grupoColumns + `, fav.createdAt` + from + ` ORDER BY fav.createdAt DESC, g.idGrupo LIMIT $2 OFFSET $3`
+	rows, err := db.QueryContext(ctx, query, idUsuario, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying favorite groups: %w", err)
+	}
+	defer rows.Close()
+
+	favoritos := []models.GrupoFavorito{}
+	for rows.Next() {
+		var f models.GrupoFavorito
+		if err := rows.Scan(append(grupoScanFields(&f.Grupo), &f.FavoritoDesde)...); err != nil {
+			return nil, 0, fmt.Errorf("error scanning favorite group: %w", err)
+		}
+		favoritos = append(favoritos, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error after iterating favorite groups: %w", err)
+	}
+	return favoritos, total, nil
+}
+
+// AddFavorito pins a group for a user; pinning it again changes nothing.
+func AddFavorito(ctx context.Context, db *sql.DB, idUsuario, idGrupo int) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO grupo_favorito (idUsuario, idGrupo) VALUES ($1, $2) ON CONFLICT DO NOTHING`, idUsuario, idGrupo)
+	if err != nil {
+		if isPgError(err, pgForeignKeyViolation, "") {
+			return ErrGrupoNoEncontrado
+		}
+		return fmt.Errorf("error adding favorite group: %w", err)
+	}
+	return nil
+}
+
+// RemoveFavorito unpins a group for a user. It reports whether it was pinned.
+func RemoveFavorito(ctx context.Context, db *sql.DB, idUsuario, idGrupo int) (bool, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM grupo_favorito WHERE idUsuario = $1 AND idGrupo = $2`, idUsuario, idGrupo)
+	if err != nil {
+		return false, fmt.Errorf("error removing favorite group: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking removed favorite group: %w", err)
+	}
+	return n > 0, nil
+}
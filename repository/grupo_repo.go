@@ -1,28 +1,79 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database"
 	// Import math for ceiling calculation
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils/querybuilder"
 )
 
-// GetAllGrupos retrieves a paginated list of all groups.
-func GetAllGrupos(db *sql.DB, limit, offset int) ([]models.Grupo, int, error) {
-	// Query for the data page
-	query := `SELECT idGrupo, nombre, numeroResolucion, lineaInvestigacion, tipoInvestigacion, fechaRegistro, archivo, createdAt, updatedAt FROM grupo ORDER BY nombre LIMIT $1 OFFSET $2`
-	rows, err := db.Query(query, limit, offset)
+// grupoColumns is the column list selected for a group (aliased as g), in the order expected by grupoScanFields.
+const grupoColumns = `g.idGrupo, g.nombre, g.numeroResolucion, g.lineaInvestigacion, g.tipoInvestigacion, g.fechaRegistro, g.archivo, g.estado, g.createdAt, g.updatedAt, g.deletedAt, g.idGrupoPadre, g.idFacultad, g.fechaVencimiento`
+
+// grupoScanFields returns the scan destinations matching grupoColumns.
+func grupoScanFields(g *models.Grupo) []interface{} {
+	return []interface{}{&g.ID, &g.Nombre, &g.NumeroResolucion, &g.LineaInvestigacion, &g.TipoInvestigacion, &g.FechaRegistro, &g.Archivo, &g.Estado, &g.CreatedAt, &g.UpdatedAt, &g.DeletedAt, &g.IDGrupoPadre, &g.IDFacultad, vigenciaScanner{g}}
+}
+
+// vigenciaScanner scans Grupo.fechaVencimiento and derives the group's EstadoVigencia from it.
+type vigenciaScanner struct {
+	g *models.Grupo
+}
+
+func (s vigenciaScanner) Scan(src interface{}) error {
+	var fecha calendario.Fecha
+	if err := fecha.Scan(src); err != nil {
+		return err
+	}
+	s.g.FechaVencimiento = nil
+	if !fecha.IsZero() {
+		s.g.FechaVencimiento = &fecha
+	}
+	s.g.EstadoVigencia = models.EstadoVigencia(s.g.FechaVencimiento)
+	return nil
+}
+
+// ErrGrupoNoEncontrado is returned by write operations on a group that does not exist (or is soft-deleted).
+var ErrGrupoNoEncontrado = notFoundError("grupo no encontrado")
+
+// ErrInvestigadorNoExiste is returned when a membership references an unknown investigator.
+var ErrInvestigadorNoExiste = notFoundError("investigador no existe")
+
+// ErrGrupoNoPendiente is returned when approving or rejecting a group that is not awaiting review.
+var ErrGrupoNoPendiente = conflictError("el grupo no está pendiente de aprobación")
+
+// estadosNoAprobados are the states of groups whose registration has not been approved; listings
+// leave them out unless they are explicitly filtered by estado.
+const estadosNoAprobados = `('pendiente', 'rechazado')`
+
+// grupoColumnsAs returns grupoColumns using a different table alias.
+func grupoColumnsAs(alias string) string {
+	return strings.ReplaceAll(grupoColumns, "g.", alias+".")
+}
+
+// GetAllGrupos retrieves a paginated list of all non-deleted, approved groups.
+func GetAllGrupos(ctx context.Context, db *sql.DB, limit, offset int) ([]models.Grupo, int, error) {
+	// Query for the data page; every row carries the total count
+	query := `SELECT ` + grupoColumns + `, COUNT(*) OVER () FROM grupo g WHERE g.deletedAt IS NULL AND g.estado NOT IN ` + estadosNoAprobados + ` ORDER BY g.nombre LIMIT $1 OFFSET $2`
+	rows, err := db.QueryContext(ctx, query, limit, offset)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error querying groups page: %w", err)
 	}
 	defer rows.Close()
 
 	grupos := []models.Grupo{}
+	var total int
 	for rows.Next() {
 		var g models.Grupo
-		if err := rows.Scan(&g.ID, &g.Nombre, &g.NumeroResolucion, &g.LineaInvestigacion, &g.TipoInvestigacion, &g.FechaRegistro, &g.Archivo, &g.CreatedAt, &g.UpdatedAt); err != nil {
+		if err := rows.Scan(append(grupoScanFields(&g), &total)...); err != nil {
 			return nil, 0, fmt.Errorf("error scanning group row: %w", err)
 		}
 		grupos = append(grupos, g)
@@ -31,20 +82,28 @@ func GetAllGrupos(db *sql.DB, limit, offset int) ([]models.Grupo, int, error) {
 		return nil, 0, fmt.Errorf("error after iterating through group rows: %w", err)
 	}
 
-	// Query for the total count
-	var total int
-	countQuery := `SELECT COUNT(*) FROM grupo`
-	if err := db.QueryRow(countQuery).Scan(&total); err != nil {
-		return nil, 0, fmt.Errorf("error querying total group count: %w", err)
+	if len(grupos) == 0 {
+		total, err = countPastEnd(ctx, db, offset, `SELECT COUNT(*) FROM grupo WHERE deletedAt IS NULL AND estado NOT IN `+estadosNoAprobados)
+		if err != nil {
+			return nil, 0, err
+		}
 	}
-
 	return grupos, total, nil
 }
 
-// GetGrupoByID retrieves a single group by its ID.
-func GetGrupoByID(db *sql.DB, id int) (*models.Grupo, error) {
+// GetGrupoByID retrieves a single group by its ID. Soft-deleted groups are treated as not found.
+func GetGrupoByID(ctx context.Context, db *sql.DB, id int) (*models.Grupo, error) {
+	return getGrupoByID(ctx, db, id, false)
+}
+
+// GetGrupoByIDIncludingDeleted retrieves a single group by its ID, even if it was soft-deleted.
+func GetGrupoByIDIncludingDeleted(ctx context.Context, db *sql.DB, id int) (*models.Grupo, error) {
+	return getGrupoByID(ctx, db, id, true)
+}
+
+func getGrupoByID(ctx context.Context, db *sql.DB, id int, includeDeleted bool) (*models.Grupo, error) {
 	var g models.Grupo
-	err := db.QueryRow(`SELECT idGrupo, nombre, numeroResolucion, lineaInvestigacion, tipoInvestigacion, fechaRegistro, archivo, createdAt, updatedAt FROM grupo WHERE idGrupo = $1`, id).Scan(&g.ID, &g.Nombre, &g.NumeroResolucion, &g.LineaInvestigacion, &g.TipoInvestigacion, &g.FechaRegistro, &g.Archivo, &g.CreatedAt, &g.UpdatedAt)
+	err := db.QueryRowContext(ctx, `SELECT `+grupoColumns+` FROM grupo g WHERE g.idGrupo = $1 AND ($2 OR g.deletedAt IS NULL)`, id, includeDeleted).Scan(grupoScanFields(&g)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Return nil for both when not found
@@ -54,123 +113,187 @@ func GetGrupoByID(db *sql.DB, id int) (*models.Grupo, error) {
 	return &g, nil
 }
 
-// CreateGrupo inserts a new group into the database.
-func CreateGrupo(db *sql.DB, g *models.Grupo) error {
-	query := `INSERT INTO grupo (nombre, numeroResolucion, lineaInvestigacion, tipoInvestigacion, fechaRegistro, archivo) VALUES ($1, $2, $3, $4, $5, $6) RETURNING idGrupo, createdAt, updatedAt`
-	err := db.QueryRow(query, g.Nombre, g.NumeroResolucion, g.LineaInvestigacion, g.TipoInvestigacion, g.FechaRegistro, g.Archivo).Scan(&g.ID, &g.CreatedAt, &g.UpdatedAt)
+// CreateGrupo inserts a new group into the database in g.Estado, or "activo" if it is empty.
+func CreateGrupo(ctx context.Context, db *sql.DB, g *models.Grupo) error {
+	query := `INSERT INTO grupo (nombre, numeroResolucion, lineaInvestigacion, tipoInvestigacion, fechaRegistro, archivo, idFacultad, estado) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING idGrupo, estado, createdAt, updatedAt, fechaVencimiento`
+	err := db.QueryRowContext(ctx, query, g.Nombre, g.NumeroResolucion, g.LineaInvestigacion, g.TipoInvestigacion, g.FechaRegistro, g.Archivo, g.IDFacultad, estadoInicial(g.Estado)).Scan(&g.ID, &g.Estado, &g.CreatedAt, &g.UpdatedAt, vigenciaScanner{g})
 	if err != nil {
 		return fmt.Errorf("error inserting group: %w", err)
 	}
 	return nil
 }
 
+// estadoInicial returns the state a new group is inserted in: estado, or activo if it is empty.
+func estadoInicial(estado string) string {
+	if estado == "" {
+		return models.EstadoGrupoActivo
+	}
+	return estado
+}
+
 // UpdateGrupo updates an existing group in the database.
-func UpdateGrupo(db *sql.DB, g *models.Grupo) error {
-	_, err := db.Exec(`UPDATE grupo SET nombre = $1, numeroResolucion = $2, lineaInvestigacion = $3, tipoInvestigacion = $4, fechaRegistro = $5, archivo = $6, updatedAt = CURRENT_TIMESTAMP WHERE idGrupo = $7`, g.Nombre, g.NumeroResolucion, g.LineaInvestigacion, g.TipoInvestigacion, g.FechaRegistro, g.Archivo, g.ID)
+// It returns ErrGrupoNoEncontrado if it does not exist (or is soft-deleted).
+func UpdateGrupo(ctx context.Context, db *sql.DB, g *models.Grupo) error {
+	res, err := db.ExecContext(ctx, `UPDATE grupo SET nombre = $1, numeroResolucion = $2, lineaInvestigacion = $3, tipoInvestigacion = $4, fechaRegistro = $5, archivo = $6, idFacultad = $7, updatedAt = CURRENT_TIMESTAMP WHERE idGrupo = $8 AND deletedAt IS NULL`, g.Nombre, g.NumeroResolucion, g.LineaInvestigacion, g.TipoInvestigacion, g.FechaRegistro, g.Archivo, g.IDFacultad, g.ID)
 	if err != nil {
 		return fmt.Errorf("error updating group: %w", err)
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrGrupoNoEncontrado
+	}
 	return nil
 }
 
-// DeleteGrupo deletes a group from the database.
-func DeleteGrupo(db *sql.DB, id int) error {
-	_, err := db.Exec(`DELETE FROM grupo WHERE idGrupo = $1`, id)
+// CreateGrupoWithDetails creates a group and its Grupo_Investigador rows within tx; run it through
+// WithTx so a failure never leaves a group with a partial member list. g is refreshed with the
+// stored values. It returns ErrInvestigadorNoExiste or a membership error (see MembresiaError)
+// when an investigator cannot be added.
+func CreateGrupoWithDetails(ctx context.Context, tx *sql.Tx, g *models.Grupo, investigadores []models.InvestigatorRelationshipRequest) error {
+	query := `INSERT INTO grupo AS g (nombre, numeroResolucion, lineaInvestigacion, tipoInvestigacion, fechaRegistro, archivo, idFacultad, estado)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + grupoColumns
+	err := tx.QueryRowContext(ctx, query, g.Nombre, g.NumeroResolucion, g.LineaInvestigacion, g.TipoInvestigacion, g.FechaRegistro, g.Archivo, g.IDFacultad, estadoInicial(g.Estado)).Scan(grupoScanFields(g)...)
 	if err != nil {
-		return fmt.Errorf("error deleting group: %w", err)
+		return fmt.Errorf("error inserting group: %w", err)
 	}
-	return nil
+	return insertIntegrantes(ctx, tx, g.ID, investigadores)
 }
 
-// SearchGrupos searches for groups with pagination and returns them with investigators and roles.
-func SearchGrupos(db *sql.DB, groupName, investigatorName, year, lineaInvestigacion, tipoInvestigacion string, limit, offset int) ([]models.GrupoWithInvestigadores, int, error) {
-	args := []interface{}{}
-	placeholderCount := 1
+// UpdateGrupoWithDetails updates a group's fields and replaces all its Grupo_Investigador rows in a
+// single transaction, so a failure never leaves a partial member list. The group's file is not changed.
+// g is refreshed with the stored values.
+func UpdateGrupoWithDetails(ctx context.Context, db *sql.DB, g *models.Grupo, investigadores []models.InvestigatorRelationshipRequest) error {
+	return WithTx(ctx, db, func(tx *sql.Tx) error {
+		query := `UPDATE grupo AS g SET nombre = $1, numeroResolucion = $2, lineaInvestigacion = $3, tipoInvestigacion = $4, fechaRegistro = $5, idFacultad = $6, updatedAt = CURRENT_TIMESTAMP
+			WHERE g.idGrupo = $7 AND g.deletedAt IS NULL
+			RETURNING ` + grupoColumns
+		err := tx.QueryRowContext(ctx, query, g.Nombre, g.NumeroResolucion, g.LineaInvestigacion, g.TipoInvestigacion, g.FechaRegistro, g.IDFacultad, g.ID).Scan(grupoScanFields(g)...)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrGrupoNoEncontrado
+			}
+			return fmt.Errorf("error updating group: %w", err)
+		}
 
-	// --- Build WHERE clause dynamically (for the initial filtering CTE) ---
-	whereConditions := ""
+		if _, err := tx.ExecContext(ctx, `DELETE FROM Grupo_Investigador WHERE idGrupo = $1`, g.ID); err != nil {
+			return fmt.Errorf("error deleting group members: %w", err)
+		}
+		return insertIntegrantes(ctx, tx, g.ID, investigadores)
+	})
+}
 
-	if groupName != "" {
-		whereConditions += fmt.Sprintf(` AND unaccent(g.nombre) ILIKE unaccent($%d)`, placeholderCount)
-		args = append(args, "%"+groupName+"%")
-		placeholderCount++
+// insertIntegrantes adds the given members to a group within tx, in a single batch.
+func insertIntegrantes(ctx context.Context, tx *sql.Tx, idGrupo int, investigadores []models.InvestigatorRelationshipRequest) error {
+	sentencias := make([]sentencia, len(investigadores))
+	for i, inv := range investigadores {
+		sentencias[i] = sentencia{
+			query: `INSERT INTO Grupo_Investigador (idGrupo, idInvestigador, rol) VALUES ($1, $2, $3)`,
+			args:  []any{idGrupo, inv.IDInvestigador, inv.TipoRelacion},
+		}
 	}
-
-	if investigatorName != "" {
-		whereConditions += fmt.Sprintf(` AND unaccent(i.nombre || ' ' || i.apellido) ILIKE unaccent($%d)`, placeholderCount)
-		args = append(args, "%"+investigatorName+"%")
-		placeholderCount++
+	err := execBatch(ctx, tx, sentencias)
+	var berr *batchError
+	if !errors.As(err, &berr) {
+		return err
 	}
-
-	if year != "" {
-		whereConditions += fmt.Sprintf(` AND EXTRACT(YEAR FROM g.fechaRegistro) = $%d`, placeholderCount)
-		args = append(args, year)
-		placeholderCount++
+	inv := investigadores[berr.i]
+	if isPgError(err, pgForeignKeyViolation, "") {
+		return fmt.Errorf("%w: %d", ErrInvestigadorNoExiste, inv.IDInvestigador)
 	}
-
-	if lineaInvestigacion != "" {
-		whereConditions += fmt.Sprintf(` AND unaccent(g.lineaInvestigacion) ILIKE unaccent($%d)`, placeholderCount)
-		args = append(args, "%"+lineaInvestigacion+"%")
-		placeholderCount++
+	if merr := MembresiaError(err, idGrupo, inv.IDInvestigador); merr != nil {
+		return merr
 	}
+	return fmt.Errorf("error inserting group member: %w", err)
+}
 
-	if tipoInvestigacion != "" {
-		whereConditions += fmt.Sprintf(` AND unaccent(g.tipoInvestigacion) ILIKE unaccent($%d)`, placeholderCount)
-		args = append(args, "%"+tipoInvestigacion+"%")
-		placeholderCount++
+// UpdateGrupoEstado changes the lifecycle state of a group.
+// Transition rules are validated by the caller (see models.PuedeTransicionarEstadoGrupo).
+// It returns ErrGrupoNoEncontrado if the group does not exist (or is soft-deleted).
+func UpdateGrupoEstado(ctx context.Context, db *sql.DB, id int, estado string) error {
+	res, err := db.ExecContext(ctx, `UPDATE grupo SET estado = $1, updatedAt = CURRENT_TIMESTAMP WHERE idGrupo = $2 AND deletedAt IS NULL`, estado, id)
+	if err != nil {
+		return fmt.Errorf("error updating group estado: %w", err)
 	}
-	// --- End WHERE clause build ---
-
-	// CTE 1: Find all unique group IDs matching the filters
-	cteFilteredGroups := `
-	WITH FilteredGroups AS (
-		SELECT DISTINCT g.idGrupo
-		FROM grupo g
-		LEFT JOIN Grupo_Investigador dgi ON g.idGrupo = dgi.idGrupo
-		LEFT JOIN investigador i ON dgi.idInvestigador = i.idInvestigador
-		WHERE 1=1` + whereConditions + `
-	)`
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrGrupoNoEncontrado
+	}
+	return nil
+}
 
-	// --- Query for the total count using the first CTE ---
-	var totalItems int
-	countQuery := cteFilteredGroups + ` SELECT COUNT(*) FROM FilteredGroups`
-	if err := db.QueryRow(countQuery, args...).Scan(&totalItems); err != nil { // Use original args for count
-		return nil, 0, fmt.Errorf("error searching total group count: %w", err)
+// RevisarRegistroGrupo approves (estado activo) or rejects (estado rechazado) the registration of
+// a pendiente group and returns it updated. It returns ErrGrupoNoEncontrado if the group does not
+// exist (or is soft-deleted) and ErrGrupoNoPendiente if it is not awaiting approval.
+func RevisarRegistroGrupo(ctx context.Context, db *sql.DB, id int, estado string) (*models.Grupo, error) {
+	var g models.Grupo
+	err := db.QueryRowContext(ctx, `UPDATE grupo AS g SET estado = $1, updatedAt = CURRENT_TIMESTAMP
+		WHERE g.idGrupo = $2 AND g.deletedAt IS NULL AND g.estado = $3
+		RETURNING `+grupoColumns, estado, id, models.EstadoGrupoPendiente).Scan(grupoScanFields(&g)...)
+	if err == sql.ErrNoRows {
+		existente, err := GetGrupoByID(ctx, db, id)
+		if err != nil {
+			return nil, err
+		}
+		if existente == nil {
+			return nil, ErrGrupoNoEncontrado
+		}
+		return nil, ErrGrupoNoPendiente
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reviewing group registration: %w", err)
 	}
+	return &g, nil
+}
 
-	// If no items found, return early
-	if totalItems == 0 {
-		return []models.GrupoWithInvestigadores{}, 0, nil
+// DeleteGrupo soft-deletes a group by setting its deletedAt timestamp.
+// The row, its memberships and its Drive file are kept so the group can be restored.
+// It returns ErrGrupoNoEncontrado if it does not exist (or is already deleted).
+func DeleteGrupo(ctx context.Context, db *sql.DB, id int) error {
+	res, err := db.ExecContext(ctx, `UPDATE grupo SET deletedAt = CURRENT_TIMESTAMP WHERE idGrupo = $1 AND deletedAt IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting group: %w", err)
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrGrupoNoEncontrado
+	}
+	return nil
+}
 
-	// --- Build the final query to get paginated details ---
+// RestoreGrupo clears the deletedAt timestamp of a soft-deleted group.
+// It returns ErrGrupoNoEncontrado if it does not exist.
+func RestoreGrupo(ctx context.Context, db *sql.DB, id int) error {
+	res, err := db.ExecContext(ctx, `UPDATE grupo SET deletedAt = NULL, updatedAt = CURRENT_TIMESTAMP WHERE idGrupo = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error restoring group: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrGrupoNoEncontrado
+	}
+	return nil
+}
 
-	// CTE 2: Paginate the filtered group IDs
-	ctePaginatedIDs := fmt.Sprintf(`,
-	PaginatedGroupIDs AS (
-		SELECT idGrupo
-		FROM FilteredGroups
-		ORDER BY idGrupo -- Or another relevant field like g.nombre from the join if needed
-		LIMIT $%d OFFSET $%d
-	)`, placeholderCount, placeholderCount+1)
+// likePatterns wraps each value in % wildcards for a partial ILIKE match.
+func likePatterns(values []string) []string {
+	patterns := make([]string, len(values))
+	for i, v := range values {
+		patterns[i] = "%" + v + "%"
+	}
+	return patterns
+}
 
-	// Main query to get details for the paginated group IDs
-	dataQuery := cteFilteredGroups + ctePaginatedIDs + `
-	SELECT
-		g.idGrupo, g.nombre, g.numeroResolucion, g.lineaInvestigacion, g.tipoInvestigacion, g.fechaRegistro, g.archivo, g.createdAt, g.updatedAt,
-		i.idInvestigador, i.nombre as invNombre, i.apellido as invApellido, i.createdAt as invCreatedAt, i.updatedAt as invUpdatedAt,
-		dgi.rol
-	FROM grupo g
-	LEFT JOIN Grupo_Investigador dgi ON g.idGrupo = dgi.idGrupo
-	LEFT JOIN investigador i ON dgi.idInvestigador = i.idInvestigador
-	WHERE g.idGrupo IN (SELECT idGrupo FROM PaginatedGroupIDs)
-	ORDER BY g.idGrupo, i.idInvestigador -- Ensure consistent order for grouping`
+// SearchGrupos searches for groups with pagination and returns them with investigators and roles.
+// q is a full-text query over nombre, numeroResolucion and lineaInvestigacion (web search syntax,
+// Spanish stemming, accent-insensitive); when set, results are ordered by relevance.
+// years, lineasInvestigacion, tiposInvestigacion and facultades (IDs) accept several values each
+// (matched with ANY); an empty slice means no filter. estadoVigencia filters by models.EstadoVigencia. Soft-deleted groups are excluded unless includeDeleted is true,
+// and unapproved ones unless estado asks for them.
+func SearchGrupos(ctx context.Context, db *sql.DB, q, groupName, investigatorName string, years []int, lineasInvestigacion, tiposInvestigacion []string, facultades []int, estado, estadoVigencia string, includeDeleted bool, limit, offset int) ([]models.GrupoWithInvestigadores, int, error) {
+	s := buildSearchGrupos(q, groupName, investigatorName, years, lineasInvestigacion, tiposInvestigacion, facultades, estado, estadoVigencia, includeDeleted)
 
 	// Append limit and offset to the original args
-	finalArgs := append(args, limit, offset)
-	rows, err := db.Query(dataQuery, finalArgs...)
+	finalArgs := append(s.args, limit, offset)
+	rows, err := db.QueryContext(ctx, s.data, finalArgs...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("error searching groups page with details: %w, Query: %s, Args: %v", err, dataQuery, finalArgs)
+		return nil, 0, fmt.Errorf("error searching groups page with details: %w, Query: %s, Args: %v", err, s.data, finalArgs)
 	}
 	defer rows.Close()
 
@@ -178,18 +301,18 @@ func SearchGrupos(db *sql.DB, groupName, investigatorName, year, lineaInvestigac
 	grupoMap := make(map[int]*models.GrupoWithInvestigadores)
 	// Slice to maintain order based on PaginatedGroupIDs query order
 	orderedGrupos := []*models.GrupoWithInvestigadores{}
+	var totalItems int
 
 	for rows.Next() {
 		var g models.Grupo
 		var invID sql.NullInt64 // Use Null types for LEFT JOIN results
-		var invNombre, invApellido, invRol sql.NullString
+		var invNombre, invApellido, invTipo, invRol sql.NullString
 		var invCreatedAt, invUpdatedAt sql.NullTime
 
-		if err := rows.Scan(
-			&g.ID, &g.Nombre, &g.NumeroResolucion, &g.LineaInvestigacion, &g.TipoInvestigacion, &g.FechaRegistro, &g.Archivo, &g.CreatedAt, &g.UpdatedAt,
-			&invID, &invNombre, &invApellido, &invCreatedAt, &invUpdatedAt,
-			&invRol,
-		); err != nil {
+		if err := rows.Scan(append(grupoScanFields(&g),
+			&invID, &invNombre, &invApellido, &invTipo, &invCreatedAt, &invUpdatedAt,
+			&invRol, &totalItems,
+		)...); err != nil {
 			return nil, 0, fmt.Errorf("error scanning group/investigator row during search: %w", err)
 		}
 
@@ -211,6 +334,7 @@ func SearchGrupos(db *sql.DB, groupName, investigatorName, year, lineaInvestigac
 				ID:       int(invID.Int64),
 				Nombre:   invNombre.String,
 				Apellido: invApellido.String,
+				Tipo:     invTipo.String,
 				Rol:      invRol.String,
 			}
 			if invCreatedAt.Valid {
@@ -227,6 +351,13 @@ func SearchGrupos(db *sql.DB, groupName, investigatorName, year, lineaInvestigac
 	if err := rows.Err(); err != nil {
 		return nil, 0, fmt.Errorf("error after iterating through group search rows: %w", err)
 	}
+	if len(orderedGrupos) == 0 {
+		totalItems, err = countPastEnd(ctx, db, offset, s.count, s.args...)
+		if err != nil {
+			return nil, 0, err
+		}
+		return []models.GrupoWithInvestigadores{}, totalItems, nil
+	}
 
 	// Convert []*models.GrupoWithInvestigadores to []models.GrupoWithInvestigadores
 	result := make([]models.GrupoWithInvestigadores, len(orderedGrupos))
@@ -237,10 +368,119 @@ func SearchGrupos(db *sql.DB, groupName, investigatorName, year, lineaInvestigac
 	return result, totalItems, nil
 }
 
+// searchGruposQuery is the SQL of SearchGrupos for a set of filters.
+type searchGruposQuery struct {
+	data  string // Page of matching groups with their members; takes args, limit and offset
+	count string // Number of matching groups; takes args
+	args  []interface{}
+}
+
+// buildSearchGrupos builds the queries of SearchGrupos (see its parameters).
+func buildSearchGrupos(q, groupName, investigatorName string, years []int, lineasInvestigacion, tiposInvestigacion []string, facultades []int, estado, estadoVigencia string, includeDeleted bool) searchGruposQuery {
+	// --- Build WHERE clause dynamically (for the initial filtering CTE) ---
+	var where querybuilder.Where
+
+	if !includeDeleted {
+		where.Add(`g.deletedAt IS NULL`)
+	}
+	if estado == "" {
+		where.Add(`g.estado NOT IN ` + estadosNoAprobados)
+	}
+
+	// Relevance of each group for the full-text query (0 without q)
+	rankExpr := `0::real`
+	if q != "" {
+		consulta := where.Arg(q)
+		where.Add(`g.busqueda @@ websearch_to_tsquery('es_unaccent', ` + consulta + `)`)
+		rankExpr = `ts_rank(g.busqueda, websearch_to_tsquery('es_unaccent', ` + consulta + `))`
+	}
+
+	if groupName != "" {
+		where.Add(`f_unaccent(g.nombre) ILIKE f_unaccent(?)`, "%"+groupName+"%")
+	}
+
+	if investigatorName != "" {
+		where.Add(`f_unaccent(i.nombre || ' ' || i.apellido) ILIKE f_unaccent(?)`, "%"+investigatorName+"%")
+	}
+
+	if len(years) > 0 {
+		where.Add(`EXTRACT(YEAR FROM g.fechaRegistro)::int = ANY(?)`, years)
+	}
+
+	if len(lineasInvestigacion) > 0 {
+		where.Add(`f_unaccent(g.lineaInvestigacion) ILIKE ANY (SELECT f_unaccent(p) FROM unnest(?::text[]) AS p)`, likePatterns(lineasInvestigacion))
+	}
+
+	if len(tiposInvestigacion) > 0 {
+		where.Add(`f_unaccent(g.tipoInvestigacion) ILIKE ANY (SELECT f_unaccent(p) FROM unnest(?::text[]) AS p)`, likePatterns(tiposInvestigacion))
+	}
+
+	if len(facultades) > 0 {
+		where.Add(`g.idFacultad = ANY(?)`, facultades)
+	}
+
+	if estado != "" {
+		where.Add(`g.estado = ?`, estado)
+	}
+
+	// estadoVigencia compares fechaVencimiento with today and the start of the notice period
+	hoy := calendario.Hoy()
+	switch estadoVigencia {
+	case models.VigenciaSinVigencia:
+		where.Add(`g.fechaVencimiento IS NULL`)
+	case models.VigenciaVencido:
+		where.Add(`g.fechaVencimiento < ?`, hoy)
+	case models.VigenciaPorVencer:
+		where.Add(`g.fechaVencimiento >= ? AND g.fechaVencimiento < ?`, hoy, hoy.AddDate(0, 0, models.DiasAvisoVencimiento()))
+	case models.VigenciaVigente:
+		where.Add(`g.fechaVencimiento >= ?`, hoy.AddDate(0, 0, models.DiasAvisoVencimiento()))
+	}
+	// --- End WHERE clause build ---
+
+	// CTE 1: Find all unique group IDs matching the filters
+	cteFilteredGroups := `
+	WITH FilteredGroups AS (
+		SELECT DISTINCT g.idGrupo, ` + rankExpr + ` AS rank
+		FROM grupo g
+		LEFT JOIN Grupo_Investigador dgi ON g.idGrupo = dgi.idGrupo
+		LEFT JOIN investigador i ON dgi.idInvestigador = i.idInvestigador
+		WHERE 1=1` + where.And() + `
+	)`
+
+	// --- Build the final query to get paginated details ---
+
+	// CTE 2: Paginate the filtered group IDs. The window runs before LIMIT, so total is the number
+	// of matching groups and no separate count query is needed.
+	ctePaginatedIDs := `,
+	PaginatedGroupIDs AS (
+		SELECT idGrupo, rank, COUNT(*) OVER () AS total
+		FROM FilteredGroups
+		ORDER BY rank DESC, idGrupo -- Most relevant first when searching with q
+		LIMIT ` + where.Next(1) + ` OFFSET ` + where.Next(2) + `
+	)`
+
+	// Main query to get details for the paginated group IDs
+	return searchGruposQuery{
+		data: cteFilteredGroups + ctePaginatedIDs + `
+	SELECT
+		` + grupoColumns + `,
+		i.idInvestigador, i.nombre as invNombre, i.apellido as invApellido, i.tipo as invTipo, i.createdAt as invCreatedAt, i.updatedAt as invUpdatedAt,
+		dgi.rol,
+		p.total
+	FROM grupo g
+	JOIN PaginatedGroupIDs p ON p.idGrupo = g.idGrupo
+	LEFT JOIN Grupo_Investigador dgi ON g.idGrupo = dgi.idGrupo
+	LEFT JOIN investigador i ON dgi.idInvestigador = i.idInvestigador
+	ORDER BY p.rank DESC, g.idGrupo, i.idInvestigador -- Keep the page order; consistent order for grouping`,
+		count: cteFilteredGroups + ` SELECT COUNT(*) FROM FilteredGroups`,
+		args:  where.Args(),
+	}
+}
+
 // GetGrupoDetails retrieves a group and its associated investigators including their roles.
-func GetGrupoDetails(db *sql.DB, id int) (*models.GrupoWithInvestigadores, error) {
+func GetGrupoDetails(ctx context.Context, db *sql.DB, id int) (*models.GrupoWithInvestigadores, error) {
 	// 1. Get the group details
-	grupo, err := GetGrupoByID(db, id)
+	grupo, err := GetGrupoByID(ctx, db, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Not found
@@ -253,117 +493,108 @@ func GetGrupoDetails(db *sql.DB, id int) (*models.GrupoWithInvestigadores, error
 
 	// 2. Get associated investigators with their roles in this specific group
 	query := `
-		SELECT i.idInvestigador, i.nombre, i.apellido, dgi.rol, i.createdAt, i.updatedAt
+		SELECT i.idInvestigador, i.nombre, i.apellido, i.tipo, dgi.rol, i.createdAt, i.updatedAt
 		FROM investigador i
 		JOIN Grupo_Investigador dgi ON i.idInvestigador = dgi.idInvestigador
 		WHERE dgi.idGrupo = $1
 	`
-	rows, err := db.Query(query, id)
+	rows, err := db.QueryContext(ctx, query, id)
 	if err != nil {
 		return nil, fmt.Errorf("error querying investigators for group details: %w", err)
 	}
 	defer rows.Close()
 
 	investigadores := []models.InvestigadorConRol{}
+	porTipo := map[string]int{}
 	for rows.Next() {
 		var inv models.InvestigadorConRol
-		// Scan id, nombre, apellido, rol, createdAt, updatedAt
-		if err := rows.Scan(&inv.ID, &inv.Nombre, &inv.Apellido, &inv.Rol, &inv.CreatedAt, &inv.UpdatedAt); err != nil {
+		// Scan id, nombre, apellido, tipo, rol, createdAt, updatedAt
+		if err := rows.Scan(&inv.ID, &inv.Nombre, &inv.Apellido, &inv.Tipo, &inv.Rol, &inv.CreatedAt, &inv.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("error scanning investigator row with role for group details: %w", err)
 		}
 		investigadores = append(investigadores, inv)
+		porTipo[inv.Tipo]++
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error after iterating investigator rows for group details: %w", err)
 	}
 
-	// 3. Combine results
+	// 3. Get the group's publications
+	publicaciones, err := GetPublicacionesByGrupo(ctx, db, id)
+	if err != nil {
+		return nil, fmt.Errorf("error querying publications for group details: %w", err)
+	}
+
+	// 4. Get the group's projects
+	proyectos, err := GetProyectosResumenByGrupo(ctx, db, id)
+	if err != nil {
+		return nil, fmt.Errorf("error querying projects for group details: %w", err)
+	}
+
+	// 5. Combine results
 	grupoDetail := &models.GrupoWithInvestigadores{
 		Grupo:          *grupo,
 		Investigadores: investigadores, // Now contains investigators with roles
+		Publicaciones:  publicaciones,
+		Proyectos:      proyectos,
+		PorTipo:        porTipo,
 	}
 
 	return grupoDetail, nil
 }
 
 // GetGruposByInvestigadorID obtiene todos los grupos a los que pertenece un investigador dado su id.
-func GetGruposByInvestigadorID(db *sql.DB, idInvestigador int) ([]map[string]interface{}, error) {
-	query := `SELECT g.idGrupo, g.nombre, g.numeroResolucion, g.lineaInvestigacion, g.tipoInvestigacion, g.fechaRegistro, g.archivo, g.createdAt, g.updatedAt
-				 , dgi.rol
-			 FROM grupo g
-			 JOIN Grupo_Investigador dgi ON g.idGrupo = dgi.idGrupo
-			 WHERE dgi.idInvestigador = $1`
-	rows, err := db.Query(query, idInvestigador)
+// Los integrantes de todos sus grupos se leen en la misma consulta.
+func GetGruposByInvestigadorID(ctx context.Context, db *sql.DB, idInvestigador int) ([]models.GrupoDeInvestigador, error) {
+	query := `
+	SELECT
+		` + grupoColumns + `,
+		i.idInvestigador, i.nombre, i.apellido, i.tipo, i.createdAt, i.updatedAt,
+		m.rol
+	FROM grupo g
+	JOIN Grupo_Investigador dgi ON dgi.idGrupo = g.idGrupo AND dgi.idInvestigador = $1
+	JOIN Grupo_Investigador m ON m.idGrupo = g.idGrupo
+	JOIN investigador i ON i.idInvestigador = m.idInvestigador
+	WHERE g.deletedAt IS NULL AND g.estado NOT IN ` + estadosNoAprobados + `
+	ORDER BY g.nombre, g.idGrupo, i.apellido, i.nombre`
+	rows, err := db.QueryContext(ctx, query, idInvestigador)
 	if err != nil {
 		return nil, fmt.Errorf("error obteniendo grupos por idInvestigador: %w", err)
 	}
 	defer rows.Close()
 
-	var gruposConIntegrantes []map[string]interface{}
+	grupos := []models.GrupoDeInvestigador{}
 	for rows.Next() {
 		var g models.Grupo
-		var rol string
-		if err := rows.Scan(&g.ID, &g.Nombre, &g.NumeroResolucion, &g.LineaInvestigacion, &g.TipoInvestigacion, &g.FechaRegistro, &g.Archivo, &g.CreatedAt, &g.UpdatedAt, &rol); err != nil {
-			return nil, fmt.Errorf("error escaneando grupo: %w", err)
+		var integrante models.InvestigadorConRol
+		if err := rows.Scan(append(grupoScanFields(&g),
+			&integrante.ID, &integrante.Nombre, &integrante.Apellido, &integrante.Tipo, &integrante.CreatedAt, &integrante.UpdatedAt,
+			&integrante.Rol,
+		)...); err != nil {
+			return nil, fmt.Errorf("error escaneando grupo e integrante: %w", err)
 		}
-
-		// Obtener los integrantes y sus roles para este grupo
-		queryIntegrantes := `SELECT i.idInvestigador, i.nombre, i.apellido, dgi.rol
-			FROM investigador i
-			JOIN Grupo_Investigador dgi ON i.idInvestigador = dgi.idInvestigador
-			WHERE dgi.idGrupo = $1`
-		rowsIntegrantes, err := db.Query(queryIntegrantes, g.ID)
-		if err != nil {
-			return nil, fmt.Errorf("error obteniendo integrantes del grupo: %w", err)
-		}
-		var integrantesConRol []map[string]interface{}
-		for rowsIntegrantes.Next() {
-			var idInvestigador int
-			var nombre, apellido, rolIntegrante string
-			if err := rowsIntegrantes.Scan(&idInvestigador, &nombre, &apellido, &rolIntegrante); err != nil {
-				rowsIntegrantes.Close()
-				return nil, fmt.Errorf("error escaneando integrante: %w", err)
-			}
-			integrantesConRol = append(integrantesConRol, map[string]interface{}{
-				"idInvestigador": idInvestigador,
-				"nombre":         nombre,
-				"apellido":       apellido,
-				"rol":            rolIntegrante,
-			})
-		}
-		rowsIntegrantes.Close()
-
-		grupoMap := map[string]interface{}{
-			"grupo":       g,
-			"integrantes": integrantesConRol,
+		// Rows come ordered by group, so a new group starts whenever the ID changes
+		if n := len(grupos); n == 0 || grupos[n-1].Grupo.ID != g.ID {
+			grupos = append(grupos, models.GrupoDeInvestigador{Grupo: g})
 		}
-		gruposConIntegrantes = append(gruposConIntegrantes, grupoMap)
+		actual := &grupos[len(grupos)-1]
+		actual.Integrantes = append(actual.Integrantes, integrante)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error después de iterar los grupos: %w", err)
 	}
-	return gruposConIntegrantes, nil
+	return grupos, nil
 }
 
-// GetAllGruposWithDetails retrieves a paginated list of all groups with their associated investigators and roles.
-func GetAllGruposWithDetails(db *sql.DB, limit, offset int) ([]models.GrupoWithInvestigadores, int, error) {
-	// 1. Get the total count of groups
+// GetAllGruposWithDetails retrieves a paginated list of all approved groups with their associated
+// investigators and roles. Soft-deleted groups are excluded unless includeDeleted is true.
+func GetAllGruposWithDetails(ctx context.Context, db *sql.DB, includeDeleted bool, limit, offset int) ([]models.GrupoWithInvestigadores, int, error) {
+	// 1. Get the IDs of the groups for the current page, each row with the total count of groups
 	var totalItems int
-	countQuery := `SELECT COUNT(*) FROM grupo`
-	if err := db.QueryRow(countQuery).Scan(&totalItems); err != nil {
-		return nil, 0, fmt.Errorf("error querying total group count for get all with details: %w", err)
-	}
-
-	// If no groups, return early
-	if totalItems == 0 {
-		return []models.GrupoWithInvestigadores{}, 0, nil
-	}
-
-	// 2. Get the IDs of the groups for the current page
-	paginatedIDsQuery := `SELECT idGrupo FROM grupo ORDER BY nombre, idGrupo LIMIT $1 OFFSET $2`
-	rowsIDs, err := db.Query(paginatedIDsQuery, limit, offset)
+	paginatedIDsQuery := `SELECT idGrupo, COUNT(*) OVER () FROM grupo WHERE ($1 OR deletedAt IS NULL) AND estado NOT IN ` + estadosNoAprobados + ` ORDER BY nombre, idGrupo LIMIT $2 OFFSET $3`
+	rowsIDs, err := db.QueryContext(ctx, paginatedIDsQuery, includeDeleted, limit, offset)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error querying paginated group IDs: %w", err)
 	}
@@ -373,7 +604,7 @@ func GetAllGruposWithDetails(db *sql.DB, limit, offset int) ([]models.GrupoWithI
 	var groupIDOrder []int     // Maintain the order for final result sorting
 	for rowsIDs.Next() {
 		var id int
-		if err := rowsIDs.Scan(&id); err != nil {
+		if err := rowsIDs.Scan(&id, &totalItems); err != nil {
 			return nil, 0, fmt.Errorf("error scanning group ID: %w", err)
 		}
 		groupIDs = append(groupIDs, id)
@@ -383,12 +614,16 @@ func GetAllGruposWithDetails(db *sql.DB, limit, offset int) ([]models.GrupoWithI
 		return nil, 0, fmt.Errorf("error after iterating group IDs: %w", err)
 	}
 
-	// If no IDs found for this page (shouldn't happen if totalItems > 0 and offset is valid, but check anyway)
+	// No groups, or a page past the end
 	if len(groupIDs) == 0 {
+		totalItems, err = countPastEnd(ctx, db, offset, `SELECT COUNT(*) FROM grupo WHERE ($1 OR deletedAt IS NULL) AND estado NOT IN `+estadosNoAprobados, includeDeleted)
+		if err != nil {
+			return nil, 0, err
+		}
 		return []models.GrupoWithInvestigadores{}, totalItems, nil
 	}
 
-	// 3. Get details for the selected group IDs using LEFT JOINs
+	// 2. Get details for the selected group IDs using LEFT JOINs
 	// Build the placeholder string for the IN clause ($1, $2, $3...)
 	placeholders := make([]string, len(groupIDs))
 	for i := range placeholders {
@@ -398,8 +633,8 @@ func GetAllGruposWithDetails(db *sql.DB, limit, offset int) ([]models.GrupoWithI
 
 	detailsQuery := `
 	SELECT
-		g.idGrupo, g.nombre, g.numeroResolucion, g.lineaInvestigacion, g.tipoInvestigacion, g.fechaRegistro, g.archivo, g.createdAt, g.updatedAt,
-		i.idInvestigador, i.nombre as invNombre, i.apellido as invApellido, i.createdAt as invCreatedAt, i.updatedAt as invUpdatedAt,
+		` + grupoColumns + `,
+		i.idInvestigador, i.nombre as invNombre, i.apellido as invApellido, i.tipo as invTipo, i.createdAt as invCreatedAt, i.updatedAt as invUpdatedAt,
 		dgi.rol
 	FROM grupo g
 	LEFT JOIN Grupo_Investigador dgi ON g.idGrupo = dgi.idGrupo
@@ -407,26 +642,25 @@ func GetAllGruposWithDetails(db *sql.DB, limit, offset int) ([]models.GrupoWithI
 	WHERE g.idGrupo IN ` + placeholderString + `
 	ORDER BY g.nombre, g.idGrupo, invApellido, invNombre -- Consistent ordering is important for grouping` // Order matching the ID query helps, but Go map iteration isn't ordered
 
-	rowsDetails, err := db.Query(detailsQuery, groupIDs...) // Pass IDs as variadic arguments
+	rowsDetails, err := db.QueryContext(ctx, detailsQuery, groupIDs...) // Pass IDs as variadic arguments
 	if err != nil {
 		return nil, 0, fmt.Errorf("error querying group details for selected IDs: %w, Query: %s, Args: %v", err, detailsQuery, groupIDs)
 	}
 	defer rowsDetails.Close()
 
-	// 4. Group results in Go
+	// 3. Group results in Go
 	grupoMap := make(map[int]*models.GrupoWithInvestigadores)
 
 	for rowsDetails.Next() {
 		var g models.Grupo
 		var invID sql.NullInt64
-		var invNombre, invApellido, invRol sql.NullString
+		var invNombre, invApellido, invTipo, invRol sql.NullString
 		var invCreatedAt, invUpdatedAt sql.NullTime
 
-		if err := rowsDetails.Scan(
-			&g.ID, &g.Nombre, &g.NumeroResolucion, &g.LineaInvestigacion, &g.TipoInvestigacion, &g.FechaRegistro, &g.Archivo, &g.CreatedAt, &g.UpdatedAt,
-			&invID, &invNombre, &invApellido, &invCreatedAt, &invUpdatedAt,
+		if err := rowsDetails.Scan(append(grupoScanFields(&g),
+			&invID, &invNombre, &invApellido, &invTipo, &invCreatedAt, &invUpdatedAt,
 			&invRol,
-		); err != nil {
+		)...); err != nil {
 			return nil, 0, fmt.Errorf("error scanning group/investigator row during get all with details: %w", err)
 		}
 
@@ -446,6 +680,7 @@ func GetAllGruposWithDetails(db *sql.DB, limit, offset int) ([]models.GrupoWithI
 				ID:       int(invID.Int64),
 				Nombre:   invNombre.String,
 				Apellido: invApellido.String,
+				Tipo:     invTipo.String,
 				Rol:      invRol.String,
 			}
 			if invCreatedAt.Valid {
@@ -472,7 +707,7 @@ func GetAllGruposWithDetails(db *sql.DB, limit, offset int) ([]models.GrupoWithI
 		return nil, 0, fmt.Errorf("error after iterating through get all groups with details rows: %w", err)
 	}
 
-	// 5. Build the final result slice, respecting the paginated order
+	// 4. Build the final result slice, respecting the paginated order
 	result := make([]models.GrupoWithInvestigadores, 0, len(groupIDOrder))
 	for _, id := range groupIDOrder {
 		if grupoData, ok := grupoMap[id]; ok {
@@ -483,3 +718,331 @@ func GetAllGruposWithDetails(db *sql.DB, limit, offset int) ([]models.GrupoWithI
 
 	return result, totalItems, nil
 }
+
+// FindGrupoDuplicateCandidates returns non-deleted groups whose name is similar (trigram similarity on the
+// unaccented, lowercased name >= threshold) or whose numeroResolucion matches exactly.
+// excludeID skips a group (e.g. the one being edited); use 0 to skip nothing.
+func FindGrupoDuplicateCandidates(ctx context.Context, db *sql.DB, nombre, numeroResolucion string, threshold float64, excludeID int) ([]models.GrupoDuplicado, error) {
+	query := `
+	SELECT ` + grupoColumns + `,
+		similarity(lower(unaccent(g.nombre)), lower(unaccent($1))) AS similitud,
+		($2 <> '' AND g.numeroResolucion = $2) AS mismaResolucion
+	FROM grupo g
+	WHERE g.deletedAt IS NULL AND g.idGrupo <> $4
+		AND (similarity(lower(unaccent(g.nombre)), lower(unaccent($1))) >= $3 OR ($2 <> '' AND g.numeroResolucion = $2))
+	ORDER BY mismaResolucion DESC, similitud DESC
+	LIMIT 10`
+	rows, err := db.QueryContext(ctx, query, nombre, numeroResolucion, threshold, excludeID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying duplicate group candidates: %w", err)
+	}
+	defer rows.Close()
+
+	candidatos := []models.GrupoDuplicado{}
+	for rows.Next() {
+		var d models.GrupoDuplicado
+		if err := rows.Scan(append(grupoScanFields(&d.Grupo), &d.Similitud, &d.MismaResolucion)...); err != nil {
+			return nil, fmt.Errorf("error scanning duplicate group candidate: %w", err)
+		}
+		candidatos = append(candidatos, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating duplicate group candidates: %w", err)
+	}
+	return candidatos, nil
+}
+
+// GetGruposRelacionados returns up to limit non-deleted groups related to the given one by línea,
+// keywords (the weight A and C lexemes of busqueda, i.e. nombre and línea) or shared members,
+// ranked by models.GrupoRelacionado.Puntaje. Groups with nothing in common are not returned.
+func GetGruposRelacionados(ctx context.Context, db *sql.DB, idGrupo, limit int) ([]models.GrupoRelacionado, error) {
+	palabrasComunes := `cardinality(ARRAY(
+				SELECT unnest(tsvector_to_array(ts_filter(g.busqueda, '{a,c}')))
+				INTERSECT
+				SELECT unnest(tsvector_to_array(ts_filter(b.busqueda, '{a,c}')))
+			))`
+	if database.Driver() == database.DriverSQLite {
+		// SQLite has no tsvector: busqueda is text, compared word by word (see database.palabrasComunes)
+		palabrasComunes = `palabras_comunes(g.busqueda, b.busqueda)`
+	}
+	query := `
+	WITH base AS (
+		SELECT idGrupo, lineaInvestigacion, busqueda FROM grupo WHERE idGrupo = $1
+	), candidatos AS (
+		SELECT g.idGrupo,
+			lower(g.lineaInvestigacion) = lower(b.lineaInvestigacion) AS mismaLinea,
+			` + palabrasComunes + ` AS palabrasComunes,
+			(SELECT COUNT(*) FROM grupo_investigador gi
+				JOIN grupo_investigador bi ON bi.idInvestigador = gi.idInvestigador AND bi.idGrupo = b.idGrupo
+				WHERE gi.idGrupo = g.idGrupo) AS integrantesComunes
+		FROM grupo g CROSS JOIN base b
+		WHERE g.idGrupo <> b.idGrupo AND g.deletedAt IS NULL AND g.estado NOT IN ` + estadosNoAprobados + `
+	)
+	SELECT ` + grupoColumns + `,
+		c.mismaLinea::int * 3 + c.integrantesComunes * 2 + c.palabrasComunes AS puntaje,
+		c.mismaLinea, c.palabrasComunes, c.integrantesComunes
+	FROM candidatos c
+	JOIN grupo g ON g.idGrupo = c.idGrupo
+	WHERE c.mismaLinea OR c.palabrasComunes > 0 OR c.integrantesComunes > 0
+	ORDER BY puntaje DESC, g.nombre
+	LIMIT $2`
+	rows, err := db.QueryContext(ctx, query, idGrupo, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying related groups: %w", err)
+	}
+	defer rows.Close()
+
+	relacionados := []models.GrupoRelacionado{}
+	for rows.Next() {
+		var rel models.GrupoRelacionado
+		if err := rows.Scan(append(grupoScanFields(&rel.Grupo), &rel.Puntaje, &rel.MismaLinea, &rel.PalabrasComunes, &rel.IntegrantesComunes)...); err != nil {
+			return nil, fmt.Errorf("error scanning related group: %w", err)
+		}
+		relacionados = append(relacionados, rel)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating related groups: %w", err)
+	}
+	return relacionados, nil
+}
+
+// FindDuplicateGrupoPairs returns pairs of non-deleted groups that are likely duplicates of each other,
+// exact numeroResolucion matches first, then by descending name similarity.
+func FindDuplicateGrupoPairs(ctx context.Context, db *sql.DB, threshold float64, limit int) ([]models.ParGruposDuplicados, error) {
+	query := `
+	SELECT ` + grupoColumnsAs("a") + `, ` + grupoColumnsAs("b") + `,
+		similarity(lower(unaccent(a.nombre)), lower(unaccent(b.nombre))) AS similitud,
+		(a.numeroResolucion <> '' AND a.numeroResolucion = b.numeroResolucion) AS mismaResolucion
+	FROM grupo a
+	JOIN grupo b ON a.idGrupo < b.idGrupo
+	WHERE a.deletedAt IS NULL AND b.deletedAt IS NULL
+		AND (similarity(lower(unaccent(a.nombre)), lower(unaccent(b.nombre))) >= $1
+			OR (a.numeroResolucion <> '' AND a.numeroResolucion = b.numeroResolucion))
+	ORDER BY mismaResolucion DESC, similitud DESC, a.idGrupo, b.idGrupo
+	LIMIT $2`
+	rows, err := db.QueryContext(ctx, query, threshold, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying duplicate group pairs: %w", err)
+	}
+	defer rows.Close()
+
+	pares := []models.ParGruposDuplicados{}
+	for rows.Next() {
+		var p models.ParGruposDuplicados
+		dest := append(grupoScanFields(&p.GrupoA), grupoScanFields(&p.GrupoB)...)
+		if err := rows.Scan(append(dest, &p.Similitud, &p.MismaResolucion)...); err != nil {
+			return nil, fmt.Errorf("error scanning duplicate group pair: %w", err)
+		}
+		pares = append(pares, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating duplicate group pairs: %w", err)
+	}
+	return pares, nil
+}
+
+// ErrCicloJerarquia is returned when setting a parent would make a group its own ancestor.
+var ErrCicloJerarquia = conflictError("el grupo padre no puede ser el mismo grupo ni uno de sus subgrupos")
+
+// ErrGrupoPadreNoEncontrado is returned when the requested parent group does not exist (or is soft-deleted).
+var ErrGrupoPadreNoEncontrado = validationError("grupo padre no encontrado")
+
+// hierarchyLockKey is the advisory lock serializing parent changes, so two concurrent changes can't
+// together create a cycle that each one alone would not.
+const hierarchyLockKey = 7311001
+
+// SetGrupoPadre sets (or clears, with nil) the parent of a group, rejecting cycles.
+func SetGrupoPadre(ctx context.Context, db *sql.DB, id int, idPadre *int) (*models.Grupo, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, hierarchyLockKey); err != nil {
+		return nil, fmt.Errorf("error locking group hierarchy: %w", err)
+	}
+
+	if idPadre != nil {
+		if *idPadre == id {
+			return nil, ErrCicloJerarquia
+		}
+		var existe bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM grupo WHERE idGrupo = $1 AND deletedAt IS NULL)`, *idPadre).Scan(&existe); err != nil {
+			return nil, fmt.Errorf("error checking parent group: %w", err)
+		}
+		if !existe {
+			return nil, ErrGrupoPadreNoEncontrado
+		}
+
+		// The new parent must not be a descendant of the group
+		var ciclo bool
+		query := `
+		WITH RECURSIVE descendientes AS (
+			SELECT idGrupo FROM grupo WHERE idGrupoPadre = $1
+			UNION
+			SELECT g.idGrupo FROM grupo g JOIN descendientes d ON g.idGrupoPadre = d.idGrupo
+		)
+		SELECT EXISTS (SELECT 1 FROM descendientes WHERE idGrupo = $2)`
+		if err := tx.QueryRowContext(ctx, query, id, *idPadre).Scan(&ciclo); err != nil {
+			return nil, fmt.Errorf("error checking group hierarchy: %w", err)
+		}
+		if ciclo {
+			return nil, ErrCicloJerarquia
+		}
+	}
+
+	var g models.Grupo
+	query := `UPDATE grupo AS g SET idGrupoPadre = $2, updatedAt = CURRENT_TIMESTAMP
+		WHERE g.idGrupo = $1 AND g.deletedAt IS NULL
+		RETURNING ` + grupoColumns
+	if err := tx.QueryRowContext(ctx, query, id, idPadre).Scan(grupoScanFields(&g)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrGrupoNoEncontrado
+		}
+		return nil, fmt.Errorf("error setting parent group: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing parent group change: %w", err)
+	}
+	return &g, nil
+}
+
+// GetSubgruposTree returns a group with all its non-deleted, approved descendants as a tree, or (nil, nil)
+// if the group does not exist. The recursion stops at any cycle left by inconsistent data.
+func GetSubgruposTree(ctx context.Context, db *sql.DB, id int) (*models.GrupoNodo, error) {
+	raiz, err := GetGrupoByID(ctx, db, id)
+	if err != nil || raiz == nil {
+		return nil, err
+	}
+
+	query := `
+	WITH RECURSIVE arbol AS (
+		SELECT g.idGrupo, ARRAY[$1::int, g.idGrupo] AS ruta
+		FROM grupo g WHERE g.idGrupoPadre = $1 AND g.deletedAt IS NULL AND g.estado NOT IN ` + estadosNoAprobados + `
+		UNION ALL
+		SELECT g.idGrupo, a.ruta || g.idGrupo
+		FROM grupo g JOIN arbol a ON g.idGrupoPadre = a.idGrupo
+		WHERE g.deletedAt IS NULL AND g.estado NOT IN ` + estadosNoAprobados + ` AND NOT g.idGrupo = ANY(a.ruta)
+	)
+	SELECT ` + grupoColumns + `
+	FROM arbol a JOIN grupo g ON g.idGrupo = a.idGrupo
+	ORDER BY g.nombre, g.idGrupo`
+	rows, err := db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("error querying subgroups: %w", err)
+	}
+	defer rows.Close()
+
+	hijos := map[int][]models.Grupo{}
+	for rows.Next() {
+		var g models.Grupo
+		if err := rows.Scan(grupoScanFields(&g)...); err != nil {
+			return nil, fmt.Errorf("error scanning subgroup row: %w", err)
+		}
+		if g.IDGrupoPadre != nil {
+			hijos[*g.IDGrupoPadre] = append(hijos[*g.IDGrupoPadre], g)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating subgroup rows: %w", err)
+	}
+
+	visitados := map[int]bool{}
+	var construir func(g models.Grupo) models.GrupoNodo
+	construir = func(g models.Grupo) models.GrupoNodo {
+		visitados[g.ID] = true
+		nodo := models.GrupoNodo{Grupo: g, Subgrupos: []models.GrupoNodo{}}
+		for _, hijo := range hijos[g.ID] {
+			if !visitados[hijo.ID] {
+				nodo.Subgrupos = append(nodo.Subgrupos, construir(hijo))
+			}
+		}
+		return nodo
+	}
+	arbol := construir(*raiz)
+	return &arbol, nil
+}
+
+// GetDirectorioVersion returns what the public directory lists (groups, investigators and
+// memberships, deleted or not) are built from: the number of rows and the latest updatedAt. Any
+// insert, update or delete changes one of them, so together they identify a version of the lists.
+func GetDirectorioVersion(ctx context.Context, db *sql.DB) (count int, lastModified time.Time, err error) {
+	query := `
+	SELECT COALESCE(SUM(n), 0), COALESCE(MAX(ultimo), 'epoch')
+	FROM (
+		SELECT COUNT(*) AS n, MAX(updatedAt) AS ultimo FROM grupo
+		UNION ALL
+		SELECT COUNT(*), MAX(updatedAt) FROM investigador
+		UNION ALL
+		SELECT COUNT(*), MAX(updatedAt) FROM grupo_investigador
+	) t`
+	if err := db.QueryRowContext(ctx, query).Scan(&count, &lastModified); err != nil {
+		return 0, time.Time{}, fmt.Errorf("error querying directory version: %w", err)
+	}
+	return count, lastModified, nil
+}
+
+// GetGruposWithDetailsByIDs retrieves the given groups with their investigators and roles in one
+// query, in the order of ids. Missing and unapproved groups (and soft-deleted ones unless
+// includeDeleted) are left out.
+func GetGruposWithDetailsByIDs(ctx context.Context, db *sql.DB, ids []int, includeDeleted bool) ([]models.GrupoWithInvestigadores, error) {
+	query := `
+	SELECT
+		` + grupoColumns + `,
+		i.idInvestigador, i.nombre, i.apellido, i.tipo, i.createdAt, i.updatedAt,
+		dgi.rol
+	FROM grupo g
+	LEFT JOIN Grupo_Investigador dgi ON g.idGrupo = dgi.idGrupo
+	LEFT JOIN investigador i ON dgi.idInvestigador = i.idInvestigador
+	WHERE g.idGrupo = ANY($1) AND ($2 OR g.deletedAt IS NULL) AND g.estado NOT IN ` + estadosNoAprobados + `
+	ORDER BY g.idGrupo, i.apellido, i.nombre`
+	rows, err := db.QueryContext(ctx, query, ids, includeDeleted)
+	if err != nil {
+		return nil, fmt.Errorf("error querying groups by IDs: %w", err)
+	}
+	defer rows.Close()
+
+	grupoMap := make(map[int]*models.GrupoWithInvestigadores, len(ids))
+	for rows.Next() {
+		var g models.Grupo
+		var invID sql.NullInt64
+		var invNombre, invApellido, invTipo, invRol sql.NullString
+		var invCreatedAt, invUpdatedAt sql.NullTime
+		if err := rows.Scan(append(grupoScanFields(&g),
+			&invID, &invNombre, &invApellido, &invTipo, &invCreatedAt, &invUpdatedAt,
+			&invRol,
+		)...); err != nil {
+			return nil, fmt.Errorf("error scanning group/investigator row by IDs: %w", err)
+		}
+
+		grupoWithDetails, exists := grupoMap[g.ID]
+		if !exists {
+			grupoWithDetails = &models.GrupoWithInvestigadores{Grupo: g, Investigadores: []models.InvestigadorConRol{}}
+			grupoMap[g.ID] = grupoWithDetails
+		}
+		if invID.Valid {
+			grupoWithDetails.Investigadores = append(grupoWithDetails.Investigadores, models.InvestigadorConRol{
+				ID:        int(invID.Int64),
+				Nombre:    invNombre.String,
+				Apellido:  invApellido.String,
+				Tipo:      invTipo.String,
+				Rol:       invRol.String,
+				CreatedAt: invCreatedAt.Time,
+				UpdatedAt: invUpdatedAt.Time,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating groups by IDs: %w", err)
+	}
+
+	result := make([]models.GrupoWithInvestigadores, 0, len(grupoMap))
+	for _, id := range ids {
+		if g, ok := grupoMap[id]; ok {
+			result = append(result, *g)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database/databasetest"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// IDs of the sample data (see databasetest.Open).
+const (
+	idGrupoSalud      = 1 // Members: Ana (coordinador) and María
+	idGrupoEnergias   = 2 // Members: Luis (coordinador), Jorge and Ana
+	idInvestigadorAna = 1
+)
+
+// buscarGrupos returns the IDs of a page of SearchGrupos without text filters, and the total.
+func buscarGrupos(t *testing.T, db *sql.DB, estado string, includeDeleted bool, limit, offset int) ([]int, int) {
+	t.Helper()
+	grupos, total, err := SearchGrupos(context.Background(), db, "", "", "", nil, nil, nil, nil, estado, "", includeDeleted, limit, offset)
+	if err != nil {
+		t.Fatalf("SearchGrupos: %v", err)
+	}
+	ids := []int{}
+	for _, g := range grupos {
+		ids = append(ids, g.Grupo.ID)
+	}
+	return ids, total
+}
+
+// idsDe returns the ID that id picks from each of items.
+func idsDe[T any](items []T, id func(T) int) []int {
+	ids := []int{}
+	for _, item := range items {
+		ids = append(ids, id(item))
+	}
+	return ids
+}
+
+// crearGrupoPendiente creates a group awaiting approval, in the same línea as the Salud group and
+// with Ana as its coordinator.
+func crearGrupoPendiente(t *testing.T, db *sql.DB) *models.Grupo {
+	t.Helper()
+	ctx := context.Background()
+	g := &models.Grupo{
+		Nombre:             "Grupo Pendiente de Salud",
+		NumeroResolucion:   "RS-001-2026",
+		LineaInvestigacion: "Ciencias de la Salud",
+		TipoInvestigacion:  "Aplicada",
+		FechaRegistro:      calendario.Hoy(),
+		Estado:             models.EstadoGrupoPendiente,
+	}
+	if err := CreateGrupo(ctx, db, g); err != nil {
+		t.Fatalf("CreateGrupo: %v", err)
+	}
+	d := &models.DetalleGrupoInvestigador{IDGrupo: g.ID, IDInvestigador: idInvestigadorAna, Rol: "Coordinador"}
+	if err := CreateDetalleGrupoInvestigador(ctx, db, d); err != nil {
+		t.Fatalf("CreateDetalleGrupoInvestigador: %v", err)
+	}
+	return g
+}
+
+func TestDeleteGrupoSoftDelete(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		ctx := context.Background()
+
+		if err := DeleteGrupo(ctx, db, idGrupoSalud); err != nil {
+			t.Fatalf("DeleteGrupo: %v", err)
+		}
+
+		if g, err := GetGrupoByID(ctx, db, idGrupoSalud); err != nil || g != nil {
+			t.Errorf("GetGrupoByID of a deleted group = %v, %v; want nil, nil", g, err)
+		}
+		g, err := GetGrupoByIDIncludingDeleted(ctx, db, idGrupoSalud)
+		if err != nil || g == nil || g.DeletedAt == nil {
+			t.Errorf("GetGrupoByIDIncludingDeleted = %+v, %v; want the group with deletedAt", g, err)
+		}
+		if ids, total := buscarGrupos(t, db, "", false, 10, 0); total != 2 || slices.Contains(ids, idGrupoSalud) {
+			t.Errorf("SearchGrupos = %v (total %d); want the other 2 groups", ids, total)
+		}
+		if ids, total := buscarGrupos(t, db, "", true, 10, 0); total != 3 || !slices.Contains(ids, idGrupoSalud) {
+			t.Errorf("SearchGrupos with includeDeleted = %v (total %d); want the 3 groups", ids, total)
+		}
+
+		// The memberships are kept for a restore, but no listing shows them
+		detalles, total, err := GetAllDetallesGrupoInvestigador(ctx, db, models.FiltroDetalles{NoAprobados: true}, 10, 0)
+		if err != nil {
+			t.Fatalf("GetAllDetallesGrupoInvestigador: %v", err)
+		}
+		grupos := idsDe(detalles, func(d models.DetalleGrupoInvestigador) int { return d.IDGrupo })
+		if total != 4 || slices.Contains(grupos, idGrupoSalud) {
+			t.Errorf("GetAllDetallesGrupoInvestigador = groups %v (total %d); want 4 memberships of the other groups", grupos, total)
+		}
+		porInvestigador, err := GetDetallesByInvestigadorID(ctx, db, idInvestigadorAna, nil, true)
+		if err != nil {
+			t.Fatalf("GetDetallesByInvestigadorID: %v", err)
+		}
+		grupos = idsDe(porInvestigador, func(d models.DetalleConGrupo) int { return d.IDGrupo })
+		if !slices.Equal(grupos, []int{idGrupoEnergias}) {
+			t.Errorf("GetDetallesByInvestigadorID = groups %v; want [%d]", grupos, idGrupoEnergias)
+		}
+
+		if err := DeleteGrupo(ctx, db, idGrupoSalud); !errors.Is(err, ErrNotFound) {
+			t.Errorf("DeleteGrupo of a deleted group = %v; want ErrNotFound", err)
+		}
+		if err := RestoreGrupo(ctx, db, idGrupoSalud); err != nil {
+			t.Fatalf("RestoreGrupo: %v", err)
+		}
+		if g, err := GetGrupoByID(ctx, db, idGrupoSalud); err != nil || g == nil {
+			t.Errorf("GetGrupoByID after RestoreGrupo = %v, %v; want the group", g, err)
+		}
+	})
+}
+
+func TestGrupoPendienteVisibility(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		ctx := context.Background()
+		pendiente := crearGrupoPendiente(t, db)
+
+		if ids, total := buscarGrupos(t, db, "", false, 10, 0); total != 3 || slices.Contains(ids, pendiente.ID) {
+			t.Errorf("SearchGrupos = %v (total %d); want the 3 approved groups", ids, total)
+		}
+		if ids, _ := buscarGrupos(t, db, models.EstadoGrupoPendiente, false, 10, 0); !slices.Equal(ids, []int{pendiente.ID}) {
+			t.Errorf("SearchGrupos with estado pendiente = %v; want [%d]", ids, pendiente.ID)
+		}
+		grupos, total, err := GetAllGrupos(ctx, db, 10, 0)
+		if err != nil {
+			t.Fatalf("GetAllGrupos: %v", err)
+		}
+		if total != 3 || len(grupos) != 3 {
+			t.Errorf("GetAllGrupos = %d groups (total %d); want 3", len(grupos), total)
+		}
+
+		tests := []struct {
+			name      string
+			filtro    models.FiltroDetalles
+			wantTotal int
+		}{
+			{"anonymous", models.FiltroDetalles{}, 6},
+			{"authenticated", models.FiltroDetalles{NoAprobados: true}, 7},
+			{"anonymous, by group", models.FiltroDetalles{IDGrupo: pendiente.ID}, 0},
+			{"authenticated, by group", models.FiltroDetalles{IDGrupo: pendiente.ID, NoAprobados: true}, 1},
+		}
+		for _, tt := range tests {
+			t.Run("GetAllDetallesGrupoInvestigador "+tt.name, func(t *testing.T) {
+				detalles, total, err := GetAllDetallesGrupoInvestigador(ctx, db, tt.filtro, 10, 0)
+				if err != nil {
+					t.Fatalf("GetAllDetallesGrupoInvestigador: %v", err)
+				}
+				if total != tt.wantTotal || len(detalles) != tt.wantTotal {
+					t.Errorf("got %d memberships (total %d); want %d", len(detalles), total, tt.wantTotal)
+				}
+			})
+		}
+
+		for _, noAprobados := range []bool{false, true} {
+			detalles, err := GetDetallesByInvestigadorID(ctx, db, idInvestigadorAna, nil, noAprobados)
+			if err != nil {
+				t.Fatalf("GetDetallesByInvestigadorID: %v", err)
+			}
+			grupos := idsDe(detalles, func(d models.DetalleConGrupo) int { return d.IDGrupo })
+			if got := slices.Contains(grupos, pendiente.ID); got != noAprobados {
+				t.Errorf("GetDetallesByInvestigadorID(noAprobados=%t) = groups %v; pending group included = %t", noAprobados, grupos, got)
+			}
+		}
+
+		relacionados, err := GetGruposRelacionados(ctx, db, idGrupoSalud, 10)
+		if err != nil {
+			t.Fatalf("GetGruposRelacionados: %v", err)
+		}
+		for _, r := range relacionados {
+			if r.Grupo.ID == pendiente.ID {
+				t.Errorf("GetGruposRelacionados includes the pending group %d", pendiente.ID)
+			}
+		}
+	})
+}
+
+func TestSearchGruposPagination(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+
+		tests := []struct {
+			limit, offset int
+			wantLen       int
+		}{
+			{limit: 2, offset: 0, wantLen: 2},
+			{limit: 2, offset: 2, wantLen: 1},
+			{limit: 2, offset: 4, wantLen: 0},
+			{limit: 10, offset: 0, wantLen: 3},
+		}
+		vistos := map[int]bool{}
+		for _, tt := range tests {
+			ids, total := buscarGrupos(t, db, "", false, tt.limit, tt.offset)
+			if len(ids) != tt.wantLen || total != 3 {
+				t.Errorf("SearchGrupos(limit=%d, offset=%d) = %v (total %d); want %d groups of 3", tt.limit, tt.offset, ids, total, tt.wantLen)
+			}
+			if tt.limit == 2 {
+				for _, id := range ids {
+					if vistos[id] {
+						t.Errorf("group %d is on two pages", id)
+					}
+					vistos[id] = true
+				}
+			}
+		}
+		if len(vistos) != 3 {
+			t.Errorf("the pages of 2 hold %d distinct groups; want 3", len(vistos))
+		}
+	})
+}
+
+func TestGetAllDetallesGrupoInvestigadorPagination(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+
+		tests := []struct {
+			limit, offset int
+			wantIDs       []int
+		}{
+			{limit: 4, offset: 0, wantIDs: []int{1, 2, 3, 4}},
+			{limit: 4, offset: 4, wantIDs: []int{5, 6}},
+			{limit: 4, offset: 8, wantIDs: []int{}},
+		}
+		for _, tt := range tests {
+			detalles, total, err := GetAllDetallesGrupoInvestigador(context.Background(), db, models.FiltroDetalles{}, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("GetAllDetallesGrupoInvestigador: %v", err)
+			}
+			ids := idsDe(detalles, func(d models.DetalleGrupoInvestigador) int { return d.ID })
+			if !slices.Equal(ids, tt.wantIDs) || total != 6 {
+				t.Errorf("GetAllDetallesGrupoInvestigador(limit=%d, offset=%d) = %v (total %d); want %v of 6", tt.limit, tt.offset, ids, total, tt.wantIDs)
+			}
+		}
+	})
+}
+
+func TestGetGruposRelacionados(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+
+		relacionados, err := GetGruposRelacionados(context.Background(), db, idGrupoSalud, 10)
+		if err != nil {
+			t.Fatalf("GetGruposRelacionados: %v", err)
+		}
+		// Ana is in both groups; nothing else is shared
+		if len(relacionados) != 1 {
+			t.Fatalf("GetGruposRelacionados = %d groups; want 1", len(relacionados))
+		}
+		r := relacionados[0]
+		if r.Grupo.ID != idGrupoEnergias || r.IntegrantesComunes != 1 || r.MismaLinea || r.Puntaje != 2 {
+			t.Errorf("GetGruposRelacionados = %+v; want group %d with 1 member in common (puntaje 2)", r, idGrupoEnergias)
+		}
+	})
+}
+
+func TestSearchGruposFilters(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		tests := []struct {
+			name            string
+			q, investigador string
+			lineas, tipos   []string
+			years           []int
+			want            []int
+		}{
+			{name: "full-text query", q: "energías renovables", want: []int{idGrupoEnergias}},
+			{name: "full-text query without accents", q: "energias", want: []int{idGrupoEnergias}},
+			{name: "full-text query with no match", q: "astronomía", want: []int{}},
+			{name: "investigator name", investigador: "quispe", want: []int{idGrupoSalud, idGrupoEnergias}},
+			{name: "líneas, any of", lineas: []string{"salud", "educacion"}, want: []int{idGrupoSalud, 3}},
+			{name: "tipos", tipos: []string{"Básica"}, want: []int{3}},
+			{name: "year", years: []int{2023}, want: []int{idGrupoSalud, idGrupoEnergias}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				grupos, total, err := SearchGrupos(context.Background(), db, tt.q, "", tt.investigador, tt.years, tt.lineas, tt.tipos, nil, "", "", false, 10, 0)
+				if err != nil {
+					t.Fatalf("SearchGrupos: %v", err)
+				}
+				ids := idsDe(grupos, func(g models.GrupoWithInvestigadores) int { return g.Grupo.ID })
+				slices.Sort(ids)
+				if !slices.Equal(ids, tt.want) || total != len(tt.want) {
+					t.Errorf("SearchGrupos = %v (total %d); want %v", ids, total, tt.want)
+				}
+			})
+		}
+	})
+}
@@ -1,27 +1,49 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings" // Import strings for query building
 
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils/querybuilder"
 )
 
-// GetAllInvestigadores retrieves a paginated list of all investigators.
-func GetAllInvestigadores(db *sql.DB, limit, offset int) ([]models.Investigador, int, error) {
-	// Query for the data page
-	query := `SELECT idInvestigador, nombre, apellido, createdAt, updatedAt FROM investigador ORDER BY nombre, apellido LIMIT $1 OFFSET $2`
-	rows, err := db.Query(query, limit, offset)
+// investigadorColumns is the column list selected for an investigator, in the order expected by investigadorScanFields.
+const investigadorColumns = `idInvestigador, nombre, apellido, email, emailVerificado, createdAt, updatedAt, deletedAt, idFacultad, idEscuela, tipo, codigoMatricula`
+
+// investigadorScanFields returns the scan destinations matching investigadorColumns.
+func investigadorScanFields(inv *models.Investigador) []interface{} {
+	return []interface{}{&inv.ID, &inv.Nombre, &inv.Apellido, &inv.Email, &inv.EmailVerificado, &inv.CreatedAt, &inv.UpdatedAt, &inv.DeletedAt, &inv.IDFacultad, &inv.IDEscuela, &inv.Tipo, &inv.CodigoMatricula}
+}
+
+// ErrEmailDuplicado is returned when another investigator already uses the email (ignoring case).
+var ErrEmailDuplicado = conflictError("el email ya está registrado para otro investigador")
+
+// ErrCodigoMatriculaDuplicado is returned when another investigator already uses the codigoMatricula
+// (ignoring case).
+var ErrCodigoMatriculaDuplicado = conflictError("el código de matrícula ya está registrado para otro investigador")
+
+// ErrInvestigadorConRelaciones is returned when deleting an investigator who still belongs to groups
+// without forcing it.
+var ErrInvestigadorConRelaciones = conflictError("el investigador aún pertenece a grupos")
+
+// GetAllInvestigadores retrieves a paginated list of all (non-deleted) investigators.
+func GetAllInvestigadores(ctx context.Context, db *sql.DB, limit, offset int) ([]models.Investigador, int, error) {
+	// Query for the data page; every row carries the total count
+	query := `SELECT ` + investigadorColumns + `, COUNT(*) OVER () FROM investigador WHERE deletedAt IS NULL ORDER BY nombre, apellido LIMIT $1 OFFSET $2`
+	rows, err := db.QueryContext(ctx, query, limit, offset)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error querying investigators page: %w", err)
 	}
 	defer rows.Close()
 
 	investigadores := []models.Investigador{}
+	var total int
 	for rows.Next() {
 		var inv models.Investigador
-		if err := rows.Scan(&inv.ID, &inv.Nombre, &inv.Apellido, &inv.CreatedAt, &inv.UpdatedAt); err != nil {
+		if err := rows.Scan(append(investigadorScanFields(&inv), &total)...); err != nil {
 			return nil, 0, fmt.Errorf("error scanning investigator row: %w", err)
 		}
 		investigadores = append(investigadores, inv)
@@ -30,20 +52,30 @@ func GetAllInvestigadores(db *sql.DB, limit, offset int) ([]models.Investigador,
 		return nil, 0, fmt.Errorf("error after iterating through investigator rows: %w", err)
 	}
 
-	// Query for the total count
-	var total int
-	countQuery := `SELECT COUNT(*) FROM investigador`
-	if err := db.QueryRow(countQuery).Scan(&total); err != nil {
-		return nil, 0, fmt.Errorf("error querying total investigator count: %w", err)
+	if len(investigadores) == 0 {
+		total, err = countPastEnd(ctx, db, offset, `SELECT COUNT(*) FROM investigador WHERE deletedAt IS NULL`)
+		if err != nil {
+			return nil, 0, err
+		}
 	}
-
 	return investigadores, total, nil
 }
 
-// GetInvestigadorByID retrieves a single investigator by their ID.
-func GetInvestigadorByID(db *sql.DB, id int) (*models.Investigador, error) {
+// GetInvestigadorByID retrieves a single investigator by their ID. Soft-deleted investigators are
+// treated as not found.
+func GetInvestigadorByID(ctx context.Context, db *sql.DB, id int) (*models.Investigador, error) {
+	return getInvestigadorByID(ctx, db, id, false)
+}
+
+// GetInvestigadorByIDIncludingDeleted retrieves a single investigator by their ID, even if they were
+// soft-deleted.
+func GetInvestigadorByIDIncludingDeleted(ctx context.Context, db *sql.DB, id int) (*models.Investigador, error) {
+	return getInvestigadorByID(ctx, db, id, true)
+}
+
+func getInvestigadorByID(ctx context.Context, db *sql.DB, id int, includeDeleted bool) (*models.Investigador, error) {
 	var inv models.Investigador
-	err := db.QueryRow(`SELECT idInvestigador, nombre, apellido, createdAt, updatedAt FROM investigador WHERE idInvestigador = $1`, id).Scan(&inv.ID, &inv.Nombre, &inv.Apellido, &inv.CreatedAt, &inv.UpdatedAt)
+	err := db.QueryRowContext(ctx, `SELECT `+investigadorColumns+` FROM investigador WHERE idInvestigador = $1 AND ($2 OR deletedAt IS NULL)`, id, includeDeleted).Scan(investigadorScanFields(&inv)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Return nil for both when not found
@@ -53,67 +85,221 @@ func GetInvestigadorByID(db *sql.DB, id int) (*models.Investigador, error) {
 	return &inv, nil
 }
 
+// BuscarInvestigadorPorNombre returns the ID of the one active investigator whose "nombre
+// apellido" is nombreCompleto, ignoring case and accents, or 0 if there is none or more than one.
+func BuscarInvestigadorPorNombre(ctx context.Context, db *sql.DB, nombreCompleto string) (int, error) {
+	nombreCompleto = strings.Join(strings.Fields(nombreCompleto), " ")
+	rows, err := db.QueryContext(ctx, `SELECT idInvestigador FROM investigador
+		WHERE deletedAt IS NULL AND lower(f_unaccent(nombre || ' ' || apellido)) = lower(f_unaccent($1))
+		LIMIT 2`, nombreCompleto)
+	if err != nil {
+		return 0, fmt.Errorf("error searching investigator by name: %w", err)
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return 0, fmt.Errorf("error scanning investigator ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error after iterating through investigator rows: %w", err)
+	}
+	if len(ids) != 1 {
+		return 0, nil
+	}
+	return ids[0], nil
+}
+
 // CreateInvestigador inserts a new investigator into the database.
-func CreateInvestigador(db *sql.DB, inv *models.Investigador) error {
-	query := `INSERT INTO investigador (nombre, apellido) VALUES ($1, $2) RETURNING idInvestigador, createdAt, updatedAt`
-	err := db.QueryRow(query, inv.Nombre, inv.Apellido).Scan(&inv.ID, &inv.CreatedAt, &inv.UpdatedAt)
+// It returns ErrEmailDuplicado or ErrCodigoMatriculaDuplicado if either is already in use.
+func CreateInvestigador(ctx context.Context, db *sql.DB, inv *models.Investigador) error {
+	query := `INSERT INTO investigador (nombre, apellido, email, idFacultad, idEscuela, tipo, codigoMatricula) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7) RETURNING ` + investigadorColumns
+	err := db.QueryRowContext(ctx, query, inv.Nombre, inv.Apellido, inv.Email, inv.IDFacultad, inv.IDEscuela, inv.Tipo, inv.CodigoMatricula).Scan(investigadorScanFields(inv)...)
 	if err != nil {
+		if isPgError(err, pgUniqueViolation, "uq_investigador_email") {
+			return ErrEmailDuplicado
+		}
+		if isPgError(err, pgUniqueViolation, "uq_investigador_codigo_matricula") {
+			return ErrCodigoMatriculaDuplicado
+		}
 		return fmt.Errorf("error inserting investigator: %w", err)
 	}
 	return nil
 }
 
-// UpdateInvestigador updates an existing investigator in the database.
-func UpdateInvestigador(db *sql.DB, inv *models.Investigador) error {
-	_, err := db.Exec(`UPDATE investigador SET nombre = $1, apellido = $2, updatedAt = CURRENT_TIMESTAMP WHERE idInvestigador = $3`, inv.Nombre, inv.Apellido, inv.ID)
+// UpdateInvestigador updates an existing investigator in the database and reloads it into inv.
+// A nil Email keeps the current one and "" removes it; changing the email clears its verification.
+// IDFacultad, IDEscuela, Tipo and CodigoMatricula are stored as they are (nil for none).
+// It returns ErrInvestigadorNoExiste if there is no such investigator and ErrEmailDuplicado or
+// ErrCodigoMatriculaDuplicado if either is already in use.
+func UpdateInvestigador(ctx context.Context, db *sql.DB, inv *models.Investigador) error {
+	query := `
+	UPDATE investigador SET nombre = $1, apellido = $2,
+		emailVerificado = CASE
+			WHEN $3::text IS NULL OR lower(NULLIF($3, '')) IS NOT DISTINCT FROM lower(email) THEN emailVerificado
+			ELSE FALSE
+		END,
+		email = CASE WHEN $3::text IS NULL THEN email ELSE NULLIF($3, '') END,
+		idFacultad = $4, idEscuela = $5, tipo = $6, codigoMatricula = $7,
+		updatedAt = CURRENT_TIMESTAMP
+	WHERE idInvestigador = $8 AND deletedAt IS NULL
+	RETURNING ` + investigadorColumns
+	err := db.QueryRowContext(ctx, query, inv.Nombre, inv.Apellido, inv.Email, inv.IDFacultad, inv.IDEscuela, inv.Tipo, inv.CodigoMatricula, inv.ID).Scan(investigadorScanFields(inv)...)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrInvestigadorNoExiste
+		}
+		if isPgError(err, pgUniqueViolation, "uq_investigador_email") {
+			return ErrEmailDuplicado
+		}
+		if isPgError(err, pgUniqueViolation, "uq_investigador_codigo_matricula") {
+			return ErrCodigoMatriculaDuplicado
+		}
 		return fmt.Errorf("error updating investigator: %w", err)
 	}
 	return nil
 }
 
-// DeleteInvestigador deletes an investigator from the database.
-func DeleteInvestigador(db *sql.DB, id int) error {
-	_, err := db.Exec(`DELETE FROM investigador WHERE idInvestigador = $1`, id)
+// GetRelacionesInvestigador lists the group memberships of an investigator, including those in
+// soft-deleted groups.
+func GetRelacionesInvestigador(ctx context.Context, db *sql.DB, id int) ([]models.RelacionGrupoInvestigador, error) {
+	rows, err := db.QueryContext(ctx, `
+	SELECT g.idGrupo, g.nombre, gi.rol, g.deletedAt IS NOT NULL
+	FROM Grupo_Investigador gi
+	JOIN grupo g ON g.idGrupo = gi.idGrupo
+	WHERE gi.idInvestigador = $1
+	ORDER BY g.nombre, g.idGrupo`, id)
 	if err != nil {
-		return fmt.Errorf("error deleting investigator: %w", err)
+		return nil, fmt.Errorf("error querying investigator relations: %w", err)
+	}
+	defer rows.Close()
+
+	relaciones := []models.RelacionGrupoInvestigador{}
+	for rows.Next() {
+		var rel models.RelacionGrupoInvestigador
+		if err := rows.Scan(&rel.IDGrupo, &rel.Nombre, &rel.Rol, &rel.GrupoEliminado); err != nil {
+			return nil, fmt.Errorf("error scanning investigator relation: %w", err)
+		}
+		relaciones = append(relaciones, rel)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating investigator relations: %w", err)
+	}
+	return relaciones, nil
+}
+
+// DeleteInvestigador soft-deletes an investigator by setting its deletedAt timestamp. An
+// investigator who still belongs to groups is only deleted with force, which removes those
+// memberships in the same transaction; without it ErrInvestigadorConRelaciones is returned. It
+// returns ErrInvestigadorNoExiste if there is no such (non-deleted) investigator, and the number of
+// memberships removed.
+func DeleteInvestigador(ctx context.Context, db *sql.DB, id int, force bool) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	// Lock the investigator so no membership is added while deleting it
+	var existe int
+	if err := tx.QueryRowContext(ctx, `SELECT idInvestigador FROM investigador WHERE idInvestigador = $1 AND deletedAt IS NULL FOR UPDATE`, id).Scan(&existe); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrInvestigadorNoExiste
+		}
+		return 0, fmt.Errorf("error locking investigator: %w", err)
+	}
+
+	var relaciones int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM Grupo_Investigador WHERE idInvestigador = $1`, id).Scan(&relaciones); err != nil {
+		return 0, fmt.Errorf("error counting investigator relations: %w", err)
+	}
+	if relaciones > 0 {
+		if !force {
+			return 0, ErrInvestigadorConRelaciones
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM Grupo_Investigador WHERE idInvestigador = $1`, id); err != nil {
+			return 0, fmt.Errorf("error deleting investigator memberships: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE investigador SET deletedAt = CURRENT_TIMESTAMP WHERE idInvestigador = $1`, id); err != nil {
+		return 0, fmt.Errorf("error deleting investigator: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing investigator deletion: %w", err)
+	}
+	return relaciones, nil
+}
+
+// RestoreInvestigador clears the deletedAt timestamp of a soft-deleted investigator. Memberships
+// removed by a forced deletion are not restored. It returns ErrInvestigadorNoExiste if there is no
+// such investigator.
+func RestoreInvestigador(ctx context.Context, db *sql.DB, id int) error {
+	res, err := db.ExecContext(ctx, `UPDATE investigador SET deletedAt = NULL, updatedAt = CURRENT_TIMESTAMP WHERE idInvestigador = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error restoring investigator: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrInvestigadorNoExiste
 	}
 	return nil
 }
 
-// SearchInvestigadores searches for investigators with pagination.
-func SearchInvestigadores(db *sql.DB, name string, limit, offset int) ([]models.Investigador, int, error) {
+// ordenInvestigadores are the keys GET /investigadores can sort by (?sort=).
+var ordenInvestigadores = querybuilder.Columnas{
+	"idInvestigador": "idInvestigador",
+	"nombre":         "nombre",
+	"apellido":       "apellido",
+	"tipo":           "tipo",
+	"createdAt":      "createdAt",
+	"updatedAt":      "updatedAt",
+}
+
+// SearchInvestigadores searches for investigators with pagination, by name, by faculty (any of
+// the IDs in facultades; empty for no filter) and by tipo ("" for any). orden is a sort parameter
+// over the keys of ordenInvestigadores ("" for nombre, apellido); an unknown key is an error
+// wrapping querybuilder.ErrClaveNoPermitida.
+func SearchInvestigadores(ctx context.Context, db *sql.DB, name string, facultades []int, tipo, orden string, limit, offset int) ([]models.Investigador, int, error) {
+	orderBy, err := ordenInvestigadores.Orden(orden)
+	if err != nil {
+		return nil, 0, err
+	}
+	if orderBy == "" {
+		orderBy = "nombre, apellido"
+	}
+
 	// Base query and conditions
-	baseQuery := `FROM investigador WHERE 1=1`
-	var conditions []string
-	args := []interface{}{}
-	placeholderCount := 1
+	baseQuery := `FROM investigador WHERE deletedAt IS NULL`
+	var where querybuilder.Where
 
 	if name != "" {
-		conditions = append(conditions, fmt.Sprintf(`(unaccent(nombre) ILIKE unaccent($%d) OR unaccent(apellido) ILIKE unaccent($%d))`, placeholderCount, placeholderCount+1))
-		searchPattern := "%" + name + "%"
-		args = append(args, searchPattern, searchPattern)
-		placeholderCount += 2
+		where.Add(`(f_unaccent(nombre) ILIKE f_unaccent(?1) OR f_unaccent(apellido) ILIKE f_unaccent(?1))`, "%"+name+"%")
 	}
-
-	whereClause := ""
-	if len(conditions) > 0 {
-		whereClause = " AND " + strings.Join(conditions, " AND ")
+	if len(facultades) > 0 {
+		where.Add(`idFacultad = ANY(?)`, facultades)
 	}
+	if tipo != "" {
+		where.Add(`tipo = ?`, tipo)
+	}
+	args := where.Args()
 
-	// Query for the data page
-	query := fmt.Sprintf(`SELECT idInvestigador, nombre, apellido, createdAt, updatedAt %s %s ORDER BY nombre, apellido LIMIT $%d OFFSET $%d`, baseQuery, whereClause, placeholderCount, placeholderCount+1)
+	// Query for the data page; every row carries the total count with the same filters
+	query := `SELECT ` + investigadorColumns + `, COUNT(*) OVER () ` + baseQuery + where.And() + ` ORDER BY ` + orderBy + `, idInvestigador LIMIT ` + where.Next(1) + ` OFFSET ` + where.Next(2)
 	finalArgs := append(args, limit, offset)
-	rows, err := db.Query(query, finalArgs...)
+	rows, err := db.QueryContext(ctx, query, finalArgs...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error searching investigators page: %w", err)
 	}
 	defer rows.Close()
 
 	investigadores := []models.Investigador{}
+	var total int
 	for rows.Next() {
 		var inv models.Investigador
-		if err := rows.Scan(&inv.ID, &inv.Nombre, &inv.Apellido, &inv.CreatedAt, &inv.UpdatedAt); err != nil {
+		if err := rows.Scan(append(investigadorScanFields(&inv), &total)...); err != nil {
 			return nil, 0, fmt.Errorf("error scanning investigator row during search: %w", err)
 		}
 		investigadores = append(investigadores, inv)
@@ -122,20 +308,19 @@ func SearchInvestigadores(db *sql.DB, name string, limit, offset int) ([]models.
 		return nil, 0, fmt.Errorf("error after iterating through investigator search rows: %w", err)
 	}
 
-	// Query for the total count with the same filters
-	var total int
-	countQuery := fmt.Sprintf(`SELECT COUNT(*) %s %s`, baseQuery, whereClause)
-	if err := db.QueryRow(countQuery, args...).Scan(&total); err != nil { // Use original args for count
-		return nil, 0, fmt.Errorf("error searching total investigator count: %w", err)
+	if len(investigadores) == 0 {
+		total, err = countPastEnd(ctx, db, offset, `SELECT COUNT(*) `+baseQuery+where.And(), args...) // Use original args for count
+		if err != nil {
+			return nil, 0, err
+		}
 	}
-
 	return investigadores, total, nil
 }
 
-// GetAllInvestigadoresNoPagination retrieves ALL investigators without pagination.
-func GetAllInvestigadoresNoPagination(db *sql.DB) ([]models.Investigador, error) {
-	query := `SELECT idInvestigador, nombre, apellido, createdAt, updatedAt FROM investigador ORDER BY nombre, apellido`
-	rows, err := db.Query(query)
+// GetAllInvestigadoresNoPagination retrieves ALL (non-deleted) investigators without pagination.
+func GetAllInvestigadoresNoPagination(ctx context.Context, db *sql.DB) ([]models.Investigador, error) {
+	query := `SELECT ` + investigadorColumns + ` FROM investigador WHERE deletedAt IS NULL ORDER BY nombre, apellido`
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("error querying all investigators: %w", err)
 	}
@@ -144,7 +329,7 @@ func GetAllInvestigadoresNoPagination(db *sql.DB) ([]models.Investigador, error)
 	investigadores := []models.Investigador{}
 	for rows.Next() {
 		var inv models.Investigador
-		if err := rows.Scan(&inv.ID, &inv.Nombre, &inv.Apellido, &inv.CreatedAt, &inv.UpdatedAt); err != nil {
+		if err := rows.Scan(investigadorScanFields(&inv)...); err != nil {
 			return nil, fmt.Errorf("error scanning investigator row (no pagination): %w", err)
 		}
 		investigadores = append(investigadores, inv)
@@ -155,3 +340,132 @@ func GetAllInvestigadoresNoPagination(db *sql.DB) ([]models.Investigador, error)
 
 	return investigadores, nil
 }
+
+// GetResumenGruposInvestigadores returns, for each of the given investigators, how many (non-deleted, approved) groups
+// they belong to and how many they coordinate, using a single aggregated query.
+// Investigators without memberships are present in the map with zero counts.
+func GetResumenGruposInvestigadores(ctx context.Context, db *sql.DB, ids []int) (map[int]models.ResumenGruposInvestigador, error) {
+	resumen := make(map[int]models.ResumenGruposInvestigador, len(ids))
+	if len(ids) == 0 {
+		return resumen, nil
+	}
+	query := `
+	SELECT i.idInvestigador,
+		COUNT(g.idGrupo) AS totalGrupos,
+		COUNT(g.idGrupo) FILTER (WHERE lower(gi.rol) = 'coordinador') AS gruposCoordinados
+	FROM investigador i
+	LEFT JOIN grupo_investigador gi ON gi.idInvestigador = i.idInvestigador
+	LEFT JOIN grupo g ON g.idGrupo = gi.idGrupo AND g.deletedAt IS NULL AND g.estado NOT IN ` + estadosNoAprobados + `
+	WHERE i.idInvestigador = ANY($1)
+	GROUP BY i.idInvestigador`
+	rows, err := db.QueryContext(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error querying investigator group summary: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var r models.ResumenGruposInvestigador
+		if err := rows.Scan(&id, &r.TotalGrupos, &r.GruposCoordinados); err != nil {
+			return nil, fmt.Errorf("error scanning investigator group summary: %w", err)
+		}
+		resumen[id] = r
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating investigator group summary: %w", err)
+	}
+	return resumen, nil
+}
+
+// ExportInvestigadores calls fn with each (non-deleted) investigator matching name, ordered like
+// the listing, without loading them all in memory. With agrupado each investigator is one row with
+// their groups aggregated; otherwise there is one row per membership (and one for investigators
+// without groups). Memberships of soft-deleted groups are left out. It stops at the first error
+// returned by fn.
+func ExportInvestigadores(ctx context.Context, db *sql.DB, name string, agrupado bool, fn func(models.FilaExportInvestigador) error) error {
+	where := `i.deletedAt IS NULL`
+	args := []interface{}{}
+	if name != "" {
+		where += ` AND (f_unaccent(i.nombre) ILIKE f_unaccent($1) OR f_unaccent(i.apellido) ILIKE f_unaccent($1))`
+		args = append(args, "%"+name+"%")
+	}
+	var query string
+	if agrupado {
+		query = `
+	SELECT i.idInvestigador, i.nombre, i.apellido, i.email, i.emailVerificado, i.createdAt, i.updatedAt, i.deletedAt, i.idFacultad, i.idEscuela,
+		COUNT(g.idGrupo),
+		COALESCE(string_agg(g.nombre || ' (' || gi.rol || ')', '; ' ORDER BY g.nombre) FILTER (WHERE g.idGrupo IS NOT NULL), '')
+	FROM investigador i
+	LEFT JOIN Grupo_Investigador gi ON gi.idInvestigador = i.idInvestigador
+	LEFT JOIN grupo g ON g.idGrupo = gi.idGrupo AND g.deletedAt IS NULL
+	WHERE ` + where + `
+	GROUP BY i.idInvestigador
+	ORDER BY i.nombre, i.apellido, i.idInvestigador`
+	} else {
+		query = `
+	SELECT i.idInvestigador, i.nombre, i.apellido, i.email, i.emailVerificado, i.createdAt, i.updatedAt, i.deletedAt, i.idFacultad, i.idEscuela,
+		g.idGrupo, COALESCE(g.nombre, ''), COALESCE(gi.rol, '')
+	FROM investigador i
+	LEFT JOIN (Grupo_Investigador gi JOIN grupo g ON g.idGrupo = gi.idGrupo AND g.deletedAt IS NULL)
+		ON gi.idInvestigador = i.idInvestigador
+	WHERE ` + where + `
+	ORDER BY i.nombre, i.apellido, i.idInvestigador, g.nombre`
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("error querying investigator export: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f models.FilaExportInvestigador
+		dest := investigadorScanFields(&f.Investigador)
+		if agrupado {
+			dest = append(dest, &f.TotalGrupos, &f.Grupos)
+		} else {
+			dest = append(dest, &f.IDGrupo, &f.Grupo, &f.Rol)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("error scanning investigator export row: %w", err)
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error after iterating investigator export rows: %w", err)
+	}
+	return nil
+}
+
+// GetInvestigadoresByIDs retrieves the given (non-deleted) investigators in one query, in the order
+// of ids. Missing investigators are left out.
+func GetInvestigadoresByIDs(ctx context.Context, db *sql.DB, ids []int) ([]models.Investigador, error) {
+	query := `SELECT ` + investigadorColumns + ` FROM investigador WHERE idInvestigador = ANY($1) AND deletedAt IS NULL`
+	rows, err := db.QueryContext(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error querying investigators by IDs: %w", err)
+	}
+	defer rows.Close()
+
+	porID := make(map[int]models.Investigador, len(ids))
+	for rows.Next() {
+		var inv models.Investigador
+		if err := rows.Scan(investigadorScanFields(&inv)...); err != nil {
+			return nil, fmt.Errorf("error scanning investigator row: %w", err)
+		}
+		porID[inv.ID] = inv
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating investigators by IDs: %w", err)
+	}
+
+	investigadores := make([]models.Investigador, 0, len(porID))
+	for _, id := range ids {
+		if inv, ok := porID[id]; ok {
+			investigadores = append(investigadores, inv)
+		}
+	}
+	return investigadores, nil
+}
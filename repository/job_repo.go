@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// RegisterJob records a scheduled job with its next run, keeping an earlier one already recorded
+// (e.g. a run missed while no instance was up, which is then run right away).
+func RegisterJob(ctx context.Context, db *sql.DB, nombre string, siguiente time.Time) error {
+	query := `INSERT INTO job (nombre, siguienteEjecucion) VALUES ($1, $2)
+		ON CONFLICT (nombre) DO UPDATE SET siguienteEjecucion = LEAST(job.siguienteEjecucion, EXCLUDED.siguienteEjecucion)`
+	if _, err := db.ExecContext(ctx, query, nombre, siguiente); err != nil {
+		return fmt.Errorf("error registering job %s: %w", nombre, err)
+	}
+	return nil
+}
+
+// ClaimJob takes the run of a job that is due, unless another instance holds it, for lease. It
+// reports whether the run was claimed: of the instances that try, only one gets it.
+func ClaimJob(ctx context.Context, db *sql.DB, nombre, instancia string, lease time.Duration) (bool, error) {
+	query := `UPDATE job SET leaseHasta = CURRENT_TIMESTAMP + $3 * INTERVAL '1 second', instancia = $2, estado = $4
+		WHERE nombre = $1 AND siguienteEjecucion <= CURRENT_TIMESTAMP AND (leaseHasta IS NULL OR leaseHasta < CURRENT_TIMESTAMP)`
+	res, err := db.ExecContext(ctx, query, nombre, instancia, int(lease.Seconds()), models.JobEnCurso)
+	if err != nil {
+		return false, fmt.Errorf("error claiming job %s: %w", nombre, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error claiming job %s: %w", nombre, err)
+	}
+	return n == 1, nil
+}
+
+// FinishJob records the result of a run claimed by instancia and schedules the next one.
+func FinishJob(ctx context.Context, db *sql.DB, nombre, instancia string, inicio, siguiente time.Time, detalle string, causa error) error {
+	estado, ultimoError := models.JobOK, sql.NullString{}
+	if causa != nil {
+		estado, ultimoError = models.JobError, sql.NullString{String: causa.Error(), Valid: true}
+	}
+	query := `UPDATE job SET leaseHasta = NULL, estado = $3, siguienteEjecucion = $4, ultimaEjecucion = $5, duracionMs = $6, detalle = $7, ultimoError = $8
+		WHERE nombre = $1 AND instancia = $2`
+	_, err := db.ExecContext(ctx, query, nombre, instancia, estado, siguiente, inicio, time.Since(inicio).Milliseconds(), detalle, ultimoError)
+	if err != nil {
+		return fmt.Errorf("error finishing job %s: %w", nombre, err)
+	}
+	return nil
+}
+
+// GetJobs returns the recorded state of every job, by name. The schedule and description are not
+// stored: the caller fills them in.
+func GetJobs(ctx context.Context, db *sql.DB) (map[string]models.Job, error) {
+	query := `SELECT nombre, estado, siguienteEjecucion, ultimaEjecucion, duracionMs, detalle, ultimoError, instancia FROM job`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := map[string]models.Job{}
+	for rows.Next() {
+		var j models.Job
+		var siguiente time.Time
+		if err := rows.Scan(&j.Nombre, &j.Estado, &siguiente, &j.UltimaEjecucion, &j.DuracionMs, &j.Detalle, &j.UltimoError, &j.Instancia); err != nil {
+			return nil, fmt.Errorf("error scanning job: %w", err)
+		}
+		j.SiguienteEjecucion = &siguiente
+		jobs[j.Nombre] = j
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating jobs: %w", err)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"os"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database/databasetest"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(databasetest.Main(m))
+}
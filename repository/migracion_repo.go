@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// GetArchivoRefs returns every distinct storage ref referenced by groups, their attachments and
+// resolutions, postulacion documents, project files and exports
+// (including soft-deleted groups, whose files are kept for restore).
+func GetArchivoRefs(ctx context.Context, db *sql.DB) ([]string, error) {
+	query := `
+	SELECT archivo FROM grupo WHERE archivo IS NOT NULL AND archivo <> ''
+	UNION
+	SELECT archivo FROM grupo_archivo WHERE archivo <> ''
+	UNION
+	SELECT archivo FROM resolucion WHERE archivo IS NOT NULL AND archivo <> ''
+	UNION
+	SELECT archivo FROM postulacion_documento WHERE archivo <> ''
+	UNION
+	SELECT archivo FROM proyecto_archivo WHERE archivo <> ''
+	UNION
+	SELECT archivo FROM export_job WHERE archivo IS NOT NULL AND archivo <> ''
+	ORDER BY 1`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying file refs: %w", err)
+	}
+	defer rows.Close()
+
+	refs := []string{}
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err != nil {
+			return nil, fmt.Errorf("error scanning file ref: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating file refs: %w", err)
+	}
+	return refs, nil
+}
+
+// GetMigracionArchivo returns the migration record of a source ref, or (nil, nil) if there is none.
+func GetMigracionArchivo(ctx context.Context, db *sql.DB, origen string) (*models.MigracionArchivo, error) {
+	var m models.MigracionArchivo
+	var errMsg sql.NullString
+	err := db.QueryRowContext(ctx, `SELECT origen, destino, estado, error, updatedAt FROM migracion_archivo WHERE origen = $1`, origen).
+		Scan(&m.Origen, &m.Destino, &m.Estado, &errMsg, &m.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting file migration record: %w", err)
+	}
+	m.Error = errMsg.String
+	return &m, nil
+}
+
+// SaveMigracionCopia records that a file was copied to its destination.
+func SaveMigracionCopia(ctx context.Context, db *sql.DB, origen, destino string) error {
+	query := `INSERT INTO migracion_archivo (origen, destino, estado, error) VALUES ($1, $2, $3, NULL)
+		ON CONFLICT (origen) DO UPDATE SET destino = EXCLUDED.destino, estado = EXCLUDED.estado, error = NULL, updatedAt = CURRENT_TIMESTAMP`
+	if _, err := db.ExecContext(ctx, query, origen, destino, models.MigracionCopiado); err != nil {
+		return fmt.Errorf("error saving file migration copy: %w", err)
+	}
+	return nil
+}
+
+// SaveMigracionError records a failed migration attempt for a file.
+func SaveMigracionError(ctx context.Context, db *sql.DB, origen, errMsg string) error {
+	query := `INSERT INTO migracion_archivo (origen, estado, error) VALUES ($1, $2, $3)
+		ON CONFLICT (origen) DO UPDATE SET estado = CASE WHEN migracion_archivo.destino IS NULL THEN EXCLUDED.estado ELSE migracion_archivo.estado END,
+			error = EXCLUDED.error, updatedAt = CURRENT_TIMESTAMP`
+	if _, err := db.ExecContext(ctx, query, origen, models.MigracionError, errMsg); err != nil {
+		return fmt.Errorf("error saving file migration error: %w", err)
+	}
+	return nil
+}
+
+// CompleteMigracionArchivo points every reference to origen at destino and marks the migration
+// as completed, all in one transaction.
+func CompleteMigracionArchivo(ctx context.Context, db *sql.DB, origen, destino string) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `UPDATE grupo SET archivo = $2 WHERE archivo = $1`, origen, destino); err != nil {
+		return fmt.Errorf("error updating group file refs: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE grupo_archivo SET archivo = $2 WHERE archivo = $1`, origen, destino); err != nil {
+		return fmt.Errorf("error updating attachment file refs: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE resolucion SET archivo = $2 WHERE archivo = $1`, origen, destino); err != nil {
+		return fmt.Errorf("error updating resolution file refs: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE postulacion_documento SET archivo = $2 WHERE archivo = $1`, origen, destino); err != nil {
+		return fmt.Errorf("error updating postulacion document file refs: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE proyecto_archivo SET archivo = $2 WHERE archivo = $1`, origen, destino); err != nil {
+		return fmt.Errorf("error updating project file refs: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE export_job SET archivo = $2 WHERE archivo = $1`, origen, destino); err != nil {
+		return fmt.Errorf("error updating export file refs: %w", err)
+	}
+	// Same content, new ref
+	if _, err = tx.ExecContext(ctx, `UPDATE archivo_checksum SET archivo = $2 WHERE archivo = $1`, origen, destino); err != nil {
+		return fmt.Errorf("error updating file checksum refs: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE migracion_archivo SET destino = $2, estado = $3, error = NULL, updatedAt = CURRENT_TIMESTAMP WHERE origen = $1`,
+		origen, destino, models.MigracionCompletado); err != nil {
+		return fmt.Errorf("error completing file migration record: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing file migration: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+const notificacionColumns = `idNotificacion, evento, canal, destino, asunto, cuerpo, html, estado, intentos, ultimoError, siguienteIntento, createdAt, enviadaEn`
+
+func notificacionScanFields(n *models.Notificacion) []interface{} {
+	return []interface{}{&n.ID, &n.Evento, &n.Canal, &n.Destino, &n.Asunto, &n.Cuerpo, &n.HTML, &n.Estado, &n.Intentos, &n.UltimoError, &n.SiguienteIntento, &n.CreatedAt, &n.EnviadaEn}
+}
+
+// CreateNotificaciones queues notifications for delivery, all or none.
+func CreateNotificaciones(ctx context.Context, db *sql.DB, notificaciones []models.Notificacion) error {
+	if len(notificaciones) == 0 {
+		return nil
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting notification transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	for _, n := range notificaciones {
+		_, err := tx.ExecContext(ctx, `INSERT INTO notificacion (evento, canal, destino, asunto, cuerpo, html, estado) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			n.Evento, n.Canal, n.Destino, n.Asunto, n.Cuerpo, n.HTML, models.NotificacionPendiente)
+		if err != nil {
+			return fmt.Errorf("error inserting notification: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing notifications: %w", err)
+	}
+	return nil
+}
+
+// ClaimNotificaciones takes up to limit pending notifications that are due, counting the attempt
+// and postponing their next attempt by lease. If the instance stops before reporting the result,
+// they are retried once the lease expires. Concurrent instances claim different notifications.
+func ClaimNotificaciones(ctx context.Context, db *sql.DB, limit int, lease time.Duration) ([]models.Notificacion, error) {
+	query := `
+	UPDATE notificacion n SET intentos = n.intentos + 1, siguienteIntento = CURRENT_TIMESTAMP + $3 * INTERVAL '1 second'
+	FROM (
+		SELECT idNotificacion FROM notificacion
+		WHERE estado = $1 AND siguienteIntento <= CURRENT_TIMESTAMP
+		ORDER BY idNotificacion
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	) p
+	WHERE n.idNotificacion = p.idNotificacion
+	RETURNING n.idNotificacion, n.evento, n.canal, n.destino, n.asunto, n.cuerpo, n.html, n.estado, n.intentos, n.ultimoError, n.siguienteIntento, n.createdAt, n.enviadaEn`
+	rows, err := db.QueryContext(ctx, query, models.NotificacionPendiente, limit, int(lease.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("error claiming pending notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notificaciones := []models.Notificacion{}
+	for rows.Next() {
+		var n models.Notificacion
+		if err := rows.Scan(notificacionScanFields(&n)...); err != nil {
+			return nil, fmt.Errorf("error scanning notification: %w", err)
+		}
+		notificaciones = append(notificaciones, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating pending notifications: %w", err)
+	}
+	return notificaciones, nil
+}
+
+// MarkNotificacionEnviada records that a notification was delivered.
+func MarkNotificacionEnviada(ctx context.Context, db *sql.DB, id int) error {
+	_, err := db.ExecContext(ctx, `UPDATE notificacion SET estado = $2, ultimoError = NULL, enviadaEn = CURRENT_TIMESTAMP WHERE idNotificacion = $1`,
+		id, models.NotificacionEnviada)
+	if err != nil {
+		return fmt.Errorf("error marking notification as sent: %w", err)
+	}
+	return nil
+}
+
+// MarkNotificacionFallida records a failed delivery. The notification is retried at
+// siguienteIntento, or never again if final.
+func MarkNotificacionFallida(ctx context.Context, db *sql.DB, id int, causa string, siguienteIntento time.Time, final bool) error {
+	estado := models.NotificacionPendiente
+	if final {
+		estado = models.NotificacionError
+	}
+	_, err := db.ExecContext(ctx, `UPDATE notificacion SET estado = $2, ultimoError = $3, siguienteIntento = $4 WHERE idNotificacion = $1`,
+		id, estado, causa, siguienteIntento)
+	if err != nil {
+		return fmt.Errorf("error marking notification as failed: %w", err)
+	}
+	return nil
+}
+
+// GetContactosMembresia returns the names of a group and an investigator and the emails to notify
+// of a change in that membership: the investigator's and those of the group's coordinators. With
+// soloVerificados only verified emails are included.
+func GetContactosMembresia(ctx context.Context, db *sql.DB, idGrupo, idInvestigador int, soloVerificados bool) (*models.ContactosMembresia, error) {
+	query := `
+	SELECT g.nombre, i.nombre || ' ' || i.apellido,
+		COALESCE(CASE WHEN i.emailVerificado OR NOT $3 THEN i.email END, ''),
+		COALESCE((SELECT array_agg(DISTINCT c.email)
+			FROM Grupo_Investigador gi
+			JOIN Investigador c ON c.idInvestigador = gi.idInvestigador AND c.deletedAt IS NULL
+			WHERE gi.idGrupo = g.idGrupo AND lower(gi.rol) = 'coordinador' AND gi.fechaFin IS NULL AND gi.idInvestigador <> i.idInvestigador
+				AND c.email IS NOT NULL AND (c.emailVerificado OR NOT $3)), '{}')
+	FROM grupo g, Investigador i
+	WHERE g.idGrupo = $1 AND i.idInvestigador = $2`
+	var c models.ContactosMembresia
+	err := db.QueryRowContext(ctx, query, idGrupo, idInvestigador, soloVerificados).
+		Scan(&c.NombreGrupo, &c.NombreInvestigador, &c.EmailInvestigador, scanArray(&c.EmailsCoordinador))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting membership contacts: %w", err)
+	}
+	return &c, nil
+}
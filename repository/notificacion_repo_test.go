@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database/databasetest"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+func TestClaimNotificaciones(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		ctx := context.Background()
+		var pendientes []models.Notificacion
+		for _, destino := range []string{"a@example.org", "b@example.org", "c@example.org"} {
+			pendientes = append(pendientes, models.Notificacion{Evento: "prueba", Canal: models.CanalEmail, Destino: destino, Asunto: "Prueba", Cuerpo: "Prueba"})
+		}
+		if err := CreateNotificaciones(ctx, db, pendientes); err != nil {
+			t.Fatalf("CreateNotificaciones: %v", err)
+		}
+
+		// A claim leases what it takes: the next one gets the rest, and then nothing until the lease expires
+		primeras, err := ClaimNotificaciones(ctx, db, 2, time.Minute)
+		if err != nil {
+			t.Fatalf("ClaimNotificaciones: %v", err)
+		}
+		resto, err := ClaimNotificaciones(ctx, db, 2, time.Minute)
+		if err != nil {
+			t.Fatalf("ClaimNotificaciones: %v", err)
+		}
+		if len(primeras) != 2 || len(resto) != 1 {
+			t.Fatalf("ClaimNotificaciones = %d, then %d notifications; want 2, then 1", len(primeras), len(resto))
+		}
+		for _, n := range append(primeras, resto...) {
+			if n.Intentos != 1 || !n.SiguienteIntento.After(time.Now()) {
+				t.Errorf("claimed notification %d: intentos %d, siguienteIntento %v; want 1 and within the lease", n.ID, n.Intentos, n.SiguienteIntento)
+			}
+		}
+		if resto[0].ID == primeras[0].ID || resto[0].ID == primeras[1].ID {
+			t.Errorf("notification %d was claimed twice", resto[0].ID)
+		}
+		if otras, err := ClaimNotificaciones(ctx, db, 2, time.Minute); err != nil || len(otras) != 0 {
+			t.Errorf("ClaimNotificaciones during the lease = %d notifications, %v; want none", len(otras), err)
+		}
+
+		// A failed delivery is retried at its siguienteIntento
+		if err := MarkNotificacionFallida(ctx, db, primeras[0].ID, "smtp: timeout", time.Now().Add(-time.Second), false); err != nil {
+			t.Fatalf("MarkNotificacionFallida: %v", err)
+		}
+		otra, err := ClaimNotificaciones(ctx, db, 2, time.Minute)
+		if err != nil {
+			t.Fatalf("ClaimNotificaciones: %v", err)
+		}
+		if len(otra) != 1 || otra[0].ID != primeras[0].ID || otra[0].Intentos != 2 {
+			t.Errorf("ClaimNotificaciones after a failure = %+v; want notification %d on its second attempt", otra, primeras[0].ID)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// datestampGrupoOAI is the OAI-PMH datestamp of a group (aliased as g): its last update or its
+// deletion, whichever is later.
+const datestampGrupoOAI = `CASE WHEN g.deletedAt > g.updatedAt THEN g.deletedAt ELSE g.updatedAt END`
+
+// filtroOAI appends to where the conditions of f on the datestamp expression and the ID column,
+// returning the clause and its arguments after args.
+func filtroOAI(f models.FiltroOAI, where, datestamp, id string, args []interface{}) (string, []interface{}) {
+	args = append(args, f.DespuesDe)
+	where += fmt.Sprintf(` AND %s > $%d`, id, len(args))
+	if f.Desde != nil {
+		args = append(args, *f.Desde)
+		where += fmt.Sprintf(` AND %s >= $%d`, datestamp, len(args))
+	}
+	if f.Hasta != nil {
+		args = append(args, *f.Hasta)
+		where += fmt.Sprintf(` AND %s < $%d`, datestamp, len(args))
+	}
+	return where, args
+}
+
+// placeholdersIn returns "($1, $2, ...)" for n arguments.
+func placeholdersIn(n int) string {
+	placeholders := make([]string, n)
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return "(" + strings.Join(placeholders, ", ") + ")"
+}
+
+// GetGruposOAI returns up to limit approved groups matching f, soft-deleted ones included, by ID.
+func GetGruposOAI(ctx context.Context, db *sql.DB, f models.FiltroOAI, limit int) ([]models.GrupoOAI, error) {
+	where, args := filtroOAI(f, `WHERE g.estado NOT IN `+estadosNoAprobados, datestampGrupoOAI, "g.idGrupo", nil)
+	args = append(args, limit)
+	query := fmt.Sprintf(`SELECT %s, COALESCE(fa.nombre, ''), %s FROM grupo g LEFT JOIN facultad fa ON fa.idFacultad = g.idFacultad
+		%s ORDER BY g.idGrupo LIMIT $%d`, grupoColumns, datestampGrupoOAI, where, len(args))
+	return queryGruposOAI(ctx, db, query, args...)
+}
+
+// GetGrupoOAI returns an approved group, even if soft-deleted, or (nil, nil) if there is none.
+func GetGrupoOAI(ctx context.Context, db *sql.DB, id int) (*models.GrupoOAI, error) {
+	query := `SELECT ` + grupoColumns + `, COALESCE(fa.nombre, ''), ` + datestampGrupoOAI + ` FROM grupo g
+		LEFT JOIN facultad fa ON fa.idFacultad = g.idFacultad WHERE g.idGrupo = $1 AND g.estado NOT IN ` + estadosNoAprobados
+	grupos, err := queryGruposOAI(ctx, db, query, id)
+	if err != nil || len(grupos) == 0 {
+		return nil, err
+	}
+	return &grupos[0], nil
+}
+
+// queryGruposOAI runs a query selecting grupoColumns, the facultad and the datestamp, and loads
+// the current members of the groups.
+func queryGruposOAI(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]models.GrupoOAI, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying OAI groups: %w", err)
+	}
+	defer rows.Close()
+
+	grupos := []models.GrupoOAI{}
+	for rows.Next() {
+		var g models.GrupoOAI
+		if err := rows.Scan(append(grupoScanFields(&g.Grupo), &g.Facultad, &g.Datestamp)...); err != nil {
+			return nil, fmt.Errorf("error scanning OAI group: %w", err)
+		}
+		grupos = append(grupos, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating OAI groups: %w", err)
+	}
+	if len(grupos) == 0 {
+		return grupos, nil
+	}
+
+	ids := make([]interface{}, len(grupos))
+	indice := make(map[int]int, len(grupos))
+	for i, g := range grupos {
+		ids[i] = g.ID
+		indice[g.ID] = i
+	}
+	hoy := time.Now().UTC().Truncate(24 * time.Hour)
+	query = fmt.Sprintf(`SELECT dgi.idGrupo, i.apellido, i.nombre, dgi.rol FROM Grupo_Investigador dgi
+		JOIN investigador i ON i.idInvestigador = dgi.idInvestigador
+		WHERE dgi.idGrupo IN %s AND i.deletedAt IS NULL AND %s
+		ORDER BY dgi.idGrupo, i.apellido, i.nombre`, placeholdersIn(len(ids)), activosEnCondition("dgi.", fmt.Sprintf("$%d", len(ids)+1)))
+	rows, err = db.QueryContext(ctx, query, append(ids, hoy)...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying OAI group members: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var idGrupo int
+		var apellido, nombre, rol string
+		if err := rows.Scan(&idGrupo, &apellido, &nombre, &rol); err != nil {
+			return nil, fmt.Errorf("error scanning OAI group member: %w", err)
+		}
+		g := &grupos[indice[idGrupo]]
+		if models.EsCoordinador(rol) {
+			g.Coordinadores = append(g.Coordinadores, apellido+", "+nombre)
+		} else {
+			g.Integrantes = append(g.Integrantes, apellido+", "+nombre)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating OAI group members: %w", err)
+	}
+	return grupos, nil
+}
+
+// GetPublicacionesOAI returns up to limit publicaciones matching f, by ID.
+func GetPublicacionesOAI(ctx context.Context, db *sql.DB, f models.FiltroOAI, limit int) ([]models.PublicacionConAutores, error) {
+	where, args := filtroOAI(f, `WHERE 1 = 1`, "p.updatedAt", "p.idPublicacion", nil)
+	args = append(args, limit)
+	query := fmt.Sprintf(`SELECT %s FROM publicacion p %s ORDER BY p.idPublicacion LIMIT $%d`, publicacionColumns, where, len(args))
+	return queryPublicacionesConAutores(ctx, db, query, args...)
+}
+
+// GetPublicacionOAI returns a publicacion, or (nil, nil) if there is none.
+func GetPublicacionOAI(ctx context.Context, db *sql.DB, id int) (*models.PublicacionConAutores, error) {
+	publicaciones, err := queryPublicacionesConAutores(ctx, db, `SELECT `+publicacionColumns+` FROM publicacion p WHERE p.idPublicacion = $1`, id)
+	if err != nil || len(publicaciones) == 0 {
+		return nil, err
+	}
+	return &publicaciones[0], nil
+}
+
+// GetEarliestDatestampOAI returns the creation of the oldest group or publicacion, for the
+// Identify response, or the zero time if there are none.
+func GetEarliestDatestampOAI(ctx context.Context, db *sql.DB) (time.Time, error) {
+	var grupo, publicacion sql.NullTime
+	if err := db.QueryRowContext(ctx, `SELECT MIN(createdAt) FROM grupo WHERE estado NOT IN `+estadosNoAprobados).Scan(&grupo); err != nil {
+		return time.Time{}, fmt.Errorf("error querying earliest group: %w", err)
+	}
+	if err := db.QueryRowContext(ctx, `SELECT MIN(createdAt) FROM publicacion`).Scan(&publicacion); err != nil {
+		return time.Time{}, fmt.Errorf("error querying earliest publicacion: %w", err)
+	}
+	switch {
+	case !grupo.Valid:
+		return publicacion.Time, nil
+	case !publicacion.Valid || grupo.Time.Before(publicacion.Time):
+		return grupo.Time, nil
+	}
+	return publicacion.Time, nil
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// EachBatch pages through a listing with load, batchSize rows at a time, and calls fn with each
+// non-empty batch and the total reported by load, so large result sets can be processed without
+// holding them all in memory. It stops after a short batch, once total rows were seen, or at the
+// first error (including ctx being cancelled).
+func EachBatch[T any](ctx context.Context, batchSize int, load func(limit, offset int) ([]T, int, error), fn func(batch []T, total int) error) error {
+	for offset := 0; ; offset += batchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch, total, err := load(batchSize, offset)
+		if err != nil {
+			return err
+		}
+		if len(batch) > 0 {
+			if err := fn(batch, total); err != nil {
+				return err
+			}
+		}
+		if len(batch) < batchSize || offset+len(batch) >= total {
+			return nil
+		}
+	}
+}
+
+// countPastEnd returns the total of a listing whose page came back empty. Paginated queries read
+// the total from a COUNT(*) OVER () column, which has no row to appear on when the page is past the
+// end; only then is countQuery run. An empty first page means there are no rows at all.
+func countPastEnd(ctx context.Context, db *sql.DB, offset int, countQuery string, args ...interface{}) (int, error) {
+	if offset == 0 {
+		return 0, nil
+	}
+	var total int
+	if err := db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("error querying total count: %w", err)
+	}
+	return total, nil
+}
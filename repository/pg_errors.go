@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PostgreSQL error codes mapped to domain errors.
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+	pgExclusionViolation  = "23P01"
+)
+
+// isPgError reports whether err is a PostgreSQL error with the given code and, if constraint is
+// not empty, raised by that constraint (or index).
+func isPgError(err error, code, constraint string) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != code {
+		return false
+	}
+	return constraint == "" || pgErr.ConstraintName == constraint
+}
+
+// tablaEnDetalle extracts the table named in the detail of a foreign key violation (`Key (x)=(1)
+// is not present in table "grupo".`), quoted with "" or «» depending on the server's language.
+var tablaEnDetalle = regexp.MustCompile(`["«](\w+)["»]`)
+
+// ConstraintError turns a unique or foreign key violation that the repository did not map to a
+// specific domain error into an ErrConflict error describing it; any other error is returned
+// unchanged. Handlers apply it before giving up with a 500, so a write that clashes with the stored
+// data is answered with 409 instead of as a server failure.
+func ConstraintError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		return conflictError(fmt.Sprintf("ya existe un registro de %s con esos datos", pgErr.TableName))
+	case pgForeignKeyViolation:
+		// The error belongs to the referencing table; the detail names the other table when a row
+		// references a missing one, or the referencing table again when a row in use is deleted
+		var tabla string
+		if m := tablaEnDetalle.FindStringSubmatch(pgErr.Detail); m != nil {
+			tabla = m[1]
+		}
+		switch tabla {
+		case "":
+			return conflictError("el registro está relacionado con otros datos")
+		case pgErr.TableName:
+			return conflictError(fmt.Sprintf("el registro está en uso en %s", tabla))
+		default:
+			return conflictError(fmt.Sprintf("el registro hace referencia a un registro de %s que no existe", tabla))
+		}
+	}
+	return err
+}
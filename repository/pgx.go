@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// pgTypes holds the pgx type maps used to scan PostgreSQL arrays. A map caches its scan plans and
+// is not safe for concurrent use, hence the pool.
+var pgTypes = sync.Pool{New: func() any { return pgtype.NewMap() }}
+
+// arrayScanner scans a PostgreSQL array into a pointer to a slice (see scanArray). It also takes
+// JSON arrays, which is how the SQLite backend returns them.
+type arrayScanner struct {
+	dest any
+}
+
+func (s arrayScanner) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		if strings.HasPrefix(v, "[") {
+			return json.Unmarshal([]byte(v), s.dest)
+		}
+	case []byte:
+		if bytes.HasPrefix(v, []byte("[")) {
+			return json.Unmarshal(v, s.dest)
+		}
+	}
+	m := pgTypes.Get().(*pgtype.Map)
+	defer pgTypes.Put(m)
+	return m.SQLScanner(s.dest).Scan(src)
+}
+
+// scanArray returns the scan destination for a PostgreSQL array column, e.g. scanArray(&emails)
+// for a text[] into a []string. Arrays are passed as arguments as plain slices.
+func scanArray(dest any) sql.Scanner {
+	return arrayScanner{dest: dest}
+}
+
+// txConns maps the transactions started by beginTx to their connection, so that execBatch can
+// reach the pgx connection behind a *sql.Tx with sql.Conn.Raw. pgx is used through its database/sql
+// adapter (pgx/v5/stdlib, see database.InitDB) rather than pgxpool because the repository and the
+// handlers take a *sql.DB, shared with the SQLite backend, and the tracing and slow query wrappers
+// of the driver connections live under database/sql's pool, which pgxpool would bypass. The pgx
+// connection, needed to batch statements, is what the adapter hides.
+var txConns sync.Map // *sql.Tx -> *sql.Conn
+
+// beginTx starts a transaction on a connection of its own and registers it for execBatch. end
+// rolls the transaction back unless it was committed and returns the connection to the pool; defer
+// it right away.
+func beginTx(ctx context.Context, db *sql.DB) (tx *sql.Tx, end func(), err error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	tx, err = conn.BeginTx(ctx, nil)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	txConns.Store(tx, conn)
+	return tx, func() {
+		tx.Rollback() // No-op after a successful commit
+		txConns.Delete(tx)
+		conn.Close()
+	}, nil
+}
+
+// sentencia is a statement of a batch. If dest is not empty the statement returns one row, scanned
+// into dest.
+type sentencia struct {
+	query string
+	args  []any
+	dest  []any
+}
+
+// batchError is the error of the statement at index i of a batch.
+type batchError struct {
+	i   int
+	err error
+}
+
+func (e *batchError) Error() string {
+	return fmt.Sprintf("statement %d of the batch: %v", e.i, e.err)
+}
+
+func (e *batchError) Unwrap() error {
+	return e.err
+}
+
+// execBatch runs the statements within tx in order and stops at the first that fails, returning a
+// *batchError with its index. When tx was started by beginTx (e.g. through WithTx) on a pgx
+// connection the statements are sent to the server as a pgx batch, in a single round trip;
+// otherwise (or with the SQLite backend) they run one by one.
+func execBatch(ctx context.Context, tx *sql.Tx, sentencias []sentencia) error {
+	if len(sentencias) == 0 {
+		return nil
+	}
+	conn, ok := txConns.Load(tx)
+	if !ok {
+		return execEach(ctx, tx, sentencias)
+	}
+
+	batched := false
+	err := conn.(*sql.Conn).Raw(func(driverConn any) error {
+		pgxConn := unwrapPgxConn(driverConn)
+		if pgxConn == nil {
+			return nil
+		}
+		batched = true
+		var err error
+		b := &pgx.Batch{}
+		for _, s := range sentencias {
+			b.Queue(s.query, s.args...)
+		}
+		results := pgxConn.SendBatch(ctx, b)
+		defer results.Close()
+		for i, s := range sentencias {
+			if len(s.dest) > 0 {
+				err = results.QueryRow().Scan(s.dest...)
+			} else {
+				_, err = results.Exec()
+			}
+			if err != nil {
+				return &batchError{i: i, err: err}
+			}
+		}
+		return results.Close()
+	})
+	if err != nil || batched {
+		return err
+	}
+	return execEach(ctx, tx, sentencias)
+}
+
+// execEach runs the statements of a batch one by one (see execBatch).
+func execEach(ctx context.Context, tx *sql.Tx, sentencias []sentencia) error {
+	for i, s := range sentencias {
+		var err error
+		if len(s.dest) > 0 {
+			err = tx.QueryRowContext(ctx, s.query, s.args...).Scan(s.dest...)
+		} else {
+			_, err = tx.ExecContext(ctx, s.query, s.args...)
+		}
+		if err != nil {
+			return &batchError{i: i, err: err}
+		}
+	}
+	return nil
+}
+
+// unwrapPgxConn returns the pgx connection under the wrappers of the driver connection (tracing,
+// slow query log), which expose the connection they wrap with Raw, or nil if the driver is not pgx.
+func unwrapPgxConn(driverConn any) *pgx.Conn {
+	for {
+		switch c := driverConn.(type) {
+		case *stdlib.Conn:
+			return c.Conn()
+		case interface{ Raw() driver.Conn }:
+			driverConn = c.Raw()
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// ErrPostulacionDuplicada is returned when a group already submitted a postulacion to the convocatoria.
+var ErrPostulacionDuplicada = conflictError("el grupo ya presentó su postulación a la convocatoria")
+
+// ErrTransicionPostulacion is returned when a postulacion cannot move to the requested estado.
+var ErrTransicionPostulacion = conflictError("cambio de estado de la postulación no permitido")
+
+// postulacionColumns is the column list selected for a postulacion (FROM postulacionFrom), in the
+// order expected by postulacionScanFields. documentosFaltantes keeps the convocatoria's order.
+const postulacionColumns = `p.idPostulacion, p.idConvocatoria, p.idGrupo, g.nombre, p.estado, p.observaciones, p.presentadoPor,
+	ARRAY(SELECT r FROM unnest(c.documentosRequeridos) WITH ORDINALITY AS req(r, n)
+		WHERE NOT EXISTS (SELECT 1 FROM postulacion_documento d WHERE d.idPostulacion = p.idPostulacion AND d.requisito = r)
+		ORDER BY n) AS documentosFaltantes,
+	p.createdAt, p.updatedAt`
+
+const postulacionFrom = `postulacion p
+	JOIN convocatoria c ON c.idConvocatoria = p.idConvocatoria
+	JOIN grupo g ON g.idGrupo = p.idGrupo`
+
+// postulacionScanFields returns the scan destinations matching postulacionColumns.
+func postulacionScanFields(p *models.Postulacion) []interface{} {
+	return []interface{}{&p.ID, &p.IDConvocatoria, &p.IDGrupo, &p.NombreGrupo, &p.Estado, &p.Observaciones, &p.PresentadoPor,
+		scanArray(&p.DocumentosFaltantes), &p.CreatedAt, &p.UpdatedAt}
+}
+
+const documentoPostulacionColumns = `d.idDocumento, d.idPostulacion, d.requisito, d.nombre, d.archivo, d.subidoPor, d.createdAt`
+
+// documentoPostulacionScanFields returns the scan destinations matching documentoPostulacionColumns.
+func documentoPostulacionScanFields(d *models.DocumentoPostulacion) []interface{} {
+	return []interface{}{&d.ID, &d.IDPostulacion, &d.Requisito, &d.Nombre, &d.Archivo, &d.SubidoPor, &d.CreatedAt}
+}
+
+// CreatePostulacion registers a group's postulacion to a convocatoria, also linking the group to it if
+// it was not participating yet, and records the initial estado. It returns ErrConvocatoriaNoEncontrada
+// or ErrGrupoNoEncontrado for unknown ids and ErrPostulacionDuplicada if the group already submitted one.
+func CreatePostulacion(ctx context.Context, db *sql.DB, idConvocatoria, idGrupo int, presentadoPor *int) (*models.Postulacion, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO grupo_convocatoria (idConvocatoria, idGrupo) VALUES ($1, $2) ON CONFLICT DO NOTHING`, idConvocatoria, idGrupo)
+	if err != nil {
+		switch {
+		case isPgError(err, pgForeignKeyViolation, "grupo_convocatoria_idconvocatoria_fkey"):
+			return nil, ErrConvocatoriaNoEncontrada
+		case isPgError(err, pgForeignKeyViolation, "grupo_convocatoria_idgrupo_fkey"):
+			return nil, ErrGrupoNoEncontrado
+		}
+		return nil, fmt.Errorf("error linking group to convocatoria: %w", err)
+	}
+
+	var id int
+	err = tx.QueryRowContext(ctx, `INSERT INTO postulacion (idConvocatoria, idGrupo, estado, presentadoPor) VALUES ($1, $2, $3, $4) RETURNING idPostulacion`,
+		idConvocatoria, idGrupo, models.PostulacionPresentado, presentadoPor).Scan(&id)
+	if err != nil {
+		if isPgError(err, pgUniqueViolation, "uq_postulacion_grupo") {
+			return nil, ErrPostulacionDuplicada
+		}
+		return nil, fmt.Errorf("error inserting postulacion: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO postulacion_historial (idPostulacion, estado, idUsuario) VALUES ($1, $2, $3)`,
+		id, models.PostulacionPresentado, presentadoPor); err != nil {
+		return nil, fmt.Errorf("error inserting postulacion history: %w", err)
+	}
+
+	var p models.Postulacion
+	if err := tx.QueryRowContext(ctx, `SELECT `+postulacionColumns+` FROM `+postulacionFrom+` WHERE p.idPostulacion = $1`, id).Scan(postulacionScanFields(&p)...); err != nil {
+		return nil, fmt.Errorf("error reloading postulacion: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing postulacion: %w", err)
+	}
+	return &p, nil
+}
+
+// GetPostulacionByID retrieves a postulacion, or (nil, nil) if it does not exist.
+// Documents and history are not loaded.
+func GetPostulacionByID(ctx context.Context, db *sql.DB, id int) (*models.Postulacion, error) {
+	var p models.Postulacion
+	err := db.QueryRowContext(ctx, `SELECT `+postulacionColumns+` FROM `+postulacionFrom+` WHERE p.idPostulacion = $1`, id).Scan(postulacionScanFields(&p)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting postulacion by ID: %w", err)
+	}
+	return &p, nil
+}
+
+// GetPostulacionesByConvocatoria lists the postulaciones of non-deleted groups to a convocatoria,
+// optionally filtered by estado.
+func GetPostulacionesByConvocatoria(ctx context.Context, db *sql.DB, idConvocatoria int, estado string) ([]models.Postulacion, error) {
+	query := `SELECT ` + postulacionColumns + ` FROM ` + postulacionFrom + `
+		WHERE p.idConvocatoria = $1 AND g.deletedAt IS NULL AND ($2 = '' OR p.estado = $2)
+		ORDER BY g.nombre`
+	rows, err := db.QueryContext(ctx, query, idConvocatoria, estado)
+	if err != nil {
+		return nil, fmt.Errorf("error querying convocatoria postulaciones: %w", err)
+	}
+	defer rows.Close()
+
+	postulaciones := []models.Postulacion{}
+	for rows.Next() {
+		var p models.Postulacion
+		if err := rows.Scan(postulacionScanFields(&p)...); err != nil {
+			return nil, fmt.Errorf("error scanning postulacion: %w", err)
+		}
+		postulaciones = append(postulaciones, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating postulaciones: %w", err)
+	}
+	return postulaciones, nil
+}
+
+// GetHistorialPostulacion returns every estado a postulacion went through, oldest first.
+func GetHistorialPostulacion(ctx context.Context, db *sql.DB, idPostulacion int) ([]models.HistorialPostulacion, error) {
+	rows, err := db.QueryContext(ctx, `SELECT estado, observaciones, idUsuario, createdAt FROM postulacion_historial
+		WHERE idPostulacion = $1 ORDER BY createdAt, idHistorial`, idPostulacion)
+	if err != nil {
+		return nil, fmt.Errorf("error querying postulacion history: %w", err)
+	}
+	defer rows.Close()
+
+	historial := []models.HistorialPostulacion{}
+	for rows.Next() {
+		var h models.HistorialPostulacion
+		if err := rows.Scan(&h.Estado, &h.Observaciones, &h.IDUsuario, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning postulacion history: %w", err)
+		}
+		historial = append(historial, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating postulacion history: %w", err)
+	}
+	return historial, nil
+}
+
+// CambiarEstadoPostulacion moves a postulacion to estado and records it in its history. It returns
+// (nil, nil) if the postulacion does not exist and ErrTransicionPostulacion if the change is not
+// allowed from its current estado (see models.PuedeCambiarEstadoPostulacion).
+func CambiarEstadoPostulacion(ctx context.Context, db *sql.DB, id int, estado, observaciones string, idUsuario *int) (*models.Postulacion, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	// Lock the row so two reviewers can't change it at the same time
+	var actual string
+	if err := tx.QueryRowContext(ctx, `SELECT estado FROM postulacion WHERE idPostulacion = $1 FOR UPDATE`, id).Scan(&actual); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting postulacion for estado change: %w", err)
+	}
+	if !models.PuedeCambiarEstadoPostulacion(actual, estado) {
+		return nil, ErrTransicionPostulacion
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE postulacion SET estado = $1, observaciones = $2 WHERE idPostulacion = $3`, estado, observaciones, id); err != nil {
+		return nil, fmt.Errorf("error updating postulacion estado: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO postulacion_historial (idPostulacion, estado, observaciones, idUsuario) VALUES ($1, $2, $3, $4)`,
+		id, estado, observaciones, idUsuario); err != nil {
+		return nil, fmt.Errorf("error inserting postulacion history: %w", err)
+	}
+
+	var p models.Postulacion
+	if err := tx.QueryRowContext(ctx, `SELECT `+postulacionColumns+` FROM `+postulacionFrom+` WHERE p.idPostulacion = $1`, id).Scan(postulacionScanFields(&p)...); err != nil {
+		return nil, fmt.Errorf("error reloading postulacion: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing postulacion estado: %w", err)
+	}
+	return &p, nil
+}
+
+// CreateDocumentoPostulacion inserts a document for a postulacion.
+func CreateDocumentoPostulacion(ctx context.Context, db *sql.DB, d *models.DocumentoPostulacion) error {
+	query := `INSERT INTO postulacion_documento (idPostulacion, requisito, nombre, archivo, subidoPor)
+		VALUES ($1, $2, $3, $4, $5) RETURNING idDocumento, createdAt`
+	if err := db.QueryRowContext(ctx, query, d.IDPostulacion, d.Requisito, d.Nombre, d.Archivo, d.SubidoPor).Scan(&d.ID, &d.CreatedAt); err != nil {
+		return fmt.Errorf("error inserting postulacion document: %w", err)
+	}
+	return nil
+}
+
+// GetDocumentosPostulacion lists the documents of a postulacion in upload order.
+func GetDocumentosPostulacion(ctx context.Context, db *sql.DB, idPostulacion int) ([]models.DocumentoPostulacion, error) {
+	query := `SELECT ` + documentoPostulacionColumns + ` FROM postulacion_documento d
+		WHERE d.idPostulacion = $1 ORDER BY d.createdAt, d.idDocumento`
+	rows, err := db.QueryContext(ctx, query, idPostulacion)
+	if err != nil {
+		return nil, fmt.Errorf("error querying postulacion documents: %w", err)
+	}
+	defer rows.Close()
+
+	documentos := []models.DocumentoPostulacion{}
+	for rows.Next() {
+		var d models.DocumentoPostulacion
+		if err := rows.Scan(documentoPostulacionScanFields(&d)...); err != nil {
+			return nil, fmt.Errorf("error scanning postulacion document: %w", err)
+		}
+		documentos = append(documentos, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating postulacion documents: %w", err)
+	}
+	return documentos, nil
+}
+
+// DeleteDocumentoPostulacion deletes a document of a postulacion and returns it, so the caller can
+// remove its file, or (nil, nil) if it does not exist.
+func DeleteDocumentoPostulacion(ctx context.Context, db *sql.DB, idPostulacion, idDocumento int) (*models.DocumentoPostulacion, error) {
+	var d models.DocumentoPostulacion
+	query := `DELETE FROM postulacion_documento d WHERE d.idPostulacion = $1 AND d.idDocumento = $2 RETURNING ` + documentoPostulacionColumns
+	if err := db.QueryRowContext(ctx, query, idPostulacion, idDocumento).Scan(documentoPostulacionScanFields(&d)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error deleting postulacion document: %w", err)
+	}
+	return &d, nil
+}
+
+// GetReporteConvocatoria computes the completion rates of a convocatoria's postulaciones. Groups
+// that were soft-deleted are left out. It returns (nil, nil) if the convocatoria does not exist.
+func GetReporteConvocatoria(ctx context.Context, db *sql.DB, idConvocatoria int) (*models.ReporteConvocatoria, error) {
+	c, err := GetConvocatoriaByID(ctx, db, idConvocatoria)
+	if err != nil || c == nil {
+		return nil, err
+	}
+	rep := models.ReporteConvocatoria{
+		IDConvocatoria:      c.ID,
+		GruposParticipantes: c.TotalGrupos,
+		PorEstado: map[string]int{
+			models.PostulacionPresentado: 0,
+			models.PostulacionObservado:  0,
+			models.PostulacionSubsanado:  0,
+			models.PostulacionAprobado:   0,
+		},
+		Requisitos: []models.RequisitoReporte{},
+	}
+
+	query := `
+	SELECT p.estado, COUNT(*),
+		COUNT(*) FILTER (WHERE NOT EXISTS (SELECT 1 FROM unnest(c.documentosRequeridos) r
+			WHERE NOT EXISTS (SELECT 1 FROM postulacion_documento d WHERE d.idPostulacion = p.idPostulacion AND d.requisito = r)))
+	FROM ` + postulacionFrom + `
+	WHERE p.idConvocatoria = $1 AND g.deletedAt IS NULL
+	GROUP BY p.estado`
+	rows, err := db.QueryContext(ctx, query, idConvocatoria)
+	if err != nil {
+		return nil, fmt.Errorf("error querying postulaciones by estado: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var estado string
+		var total, completas int
+		if err := rows.Scan(&estado, &total, &completas); err != nil {
+			return nil, fmt.Errorf("error scanning postulaciones by estado: %w", err)
+		}
+		rep.PorEstado[estado] = total
+		rep.Postulaciones += total
+		rep.DocumentacionCompleta += completas
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating postulaciones by estado: %w", err)
+	}
+
+	query = `
+	SELECT req.r, (SELECT COUNT(DISTINCT d.idPostulacion) FROM postulacion_documento d
+		JOIN postulacion p ON p.idPostulacion = d.idPostulacion
+		JOIN grupo g ON g.idGrupo = p.idGrupo AND g.deletedAt IS NULL
+		WHERE p.idConvocatoria = c.idConvocatoria AND d.requisito = req.r)
+	FROM convocatoria c, unnest(c.documentosRequeridos) WITH ORDINALITY AS req(r, n)
+	WHERE c.idConvocatoria = $1
+	ORDER BY req.n`
+	reqRows, err := db.QueryContext(ctx, query, idConvocatoria)
+	if err != nil {
+		return nil, fmt.Errorf("error querying required documents: %w", err)
+	}
+	defer reqRows.Close()
+	for reqRows.Next() {
+		var rr models.RequisitoReporte
+		if err := reqRows.Scan(&rr.Requisito, &rr.Presentados); err != nil {
+			return nil, fmt.Errorf("error scanning required document: %w", err)
+		}
+		rr.Tasa = tasa(rr.Presentados, rep.Postulaciones)
+		rep.Requisitos = append(rep.Requisitos, rr)
+	}
+	if err := reqRows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating required documents: %w", err)
+	}
+
+	rep.TasaPostulacion = tasa(rep.Postulaciones, rep.GruposParticipantes)
+	rep.TasaDocumentacion = tasa(rep.DocumentacionCompleta, rep.Postulaciones)
+	rep.TasaAprobacion = tasa(rep.PorEstado[models.PostulacionAprobado], rep.Postulaciones)
+	return &rep, nil
+}
+
+// tasa returns parte/total rounded to four decimals, or 0 when total is 0.
+func tasa(parte, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(parte)/float64(total)*10000) / 10000
+}
@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// ErrProyectoNoEncontrado is returned by write operations on a proyecto that does not exist.
+var ErrProyectoNoEncontrado = notFoundError("proyecto no encontrado")
+
+// ErrCodigoProyectoDuplicado is returned when another proyecto already has the same codigo.
+var ErrCodigoProyectoDuplicado = conflictError("código de proyecto duplicado")
+
+// proyectoColumns is the column list selected for a proyecto (aliased as p), in the order expected
+// by proyectoScanFields.
+const proyectoColumns = `p.idProyecto, p.idGrupo, p.titulo, p.codigo, p.fuenteFinanciamiento, p.presupuesto,
+	p.fechaInicio, p.fechaFin, p.estado, p.createdAt, p.updatedAt`
+
+// proyectoScanFields returns the scan destinations matching proyectoColumns.
+func proyectoScanFields(p *models.Proyecto) []interface{} {
+	return []interface{}{&p.ID, &p.IDGrupo, &p.Titulo, &p.Codigo, &p.FuenteFinanciamiento, &p.Presupuesto,
+		&p.FechaInicio, &p.FechaFin, &p.Estado, &p.CreatedAt, &p.UpdatedAt}
+}
+
+const proyectoArchivoColumns = `a.idArchivo, a.idProyecto, a.nombre, a.archivo, a.subidoPor, a.createdAt`
+
+// proyectoArchivoScanFields returns the scan destinations matching proyectoArchivoColumns.
+func proyectoArchivoScanFields(a *models.ProyectoArchivo) []interface{} {
+	return []interface{}{&a.ID, &a.IDProyecto, &a.Nombre, &a.Archivo, &a.SubidoPor, &a.CreatedAt}
+}
+
+// proyectoFilter builds the WHERE clause and arguments for the given filters, numbering
+// placeholders from 1.
+func proyectoFilter(f models.FiltroProyectos) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Q != "" {
+		add(`(f_unaccent(p.titulo) ILIKE f_unaccent($%[1]d) OR p.codigo ILIKE $%[1]d OR f_unaccent(p.fuenteFinanciamiento) ILIKE f_unaccent($%[1]d))`, "%"+f.Q+"%")
+	}
+	if f.IDGrupo != 0 {
+		add(`p.idGrupo = $%d`, f.IDGrupo)
+	}
+	if f.Estado != "" {
+		add(`p.estado = $%d`, f.Estado)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// GetProyectos retrieves a paginated list of proyectos matching the filters, newest first.
+func GetProyectos(ctx context.Context, db *sql.DB, f models.FiltroProyectos, limit, offset int) ([]models.Proyecto, int, error) {
+	where, args := proyectoFilter(f)
+
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM proyecto p`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error querying total proyecto count: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM proyecto p%s ORDER BY p.fechaInicio DESC, p.titulo, p.idProyecto LIMIT $%d OFFSET $%d`,
+		proyectoColumns, where, len(args)+1, len(args)+2)
+	rows, err := db.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying proyectos page: %w", err)
+	}
+	defer rows.Close()
+
+	proyectos := []models.Proyecto{}
+	for rows.Next() {
+		var p models.Proyecto
+		if err := rows.Scan(proyectoScanFields(&p)...); err != nil {
+			return nil, 0, fmt.Errorf("error scanning proyecto row: %w", err)
+		}
+		proyectos = append(proyectos, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error after iterating through proyecto rows: %w", err)
+	}
+	return proyectos, total, nil
+}
+
+// GetProyectosResumenByGrupo returns the summaries of a group's proyectos, newest first.
+func GetProyectosResumenByGrupo(ctx context.Context, db *sql.DB, idGrupo int) ([]models.ProyectoResumen, error) {
+	query := `SELECT p.idProyecto, p.titulo, p.codigo, p.estado, p.fechaInicio, p.fechaFin
+		FROM proyecto p WHERE p.idGrupo = $1 ORDER BY p.fechaInicio DESC, p.titulo, p.idProyecto`
+	rows, err := db.QueryContext(ctx, query, idGrupo)
+	if err != nil {
+		return nil, fmt.Errorf("error querying proyectos of group: %w", err)
+	}
+	defer rows.Close()
+
+	proyectos := []models.ProyectoResumen{}
+	for rows.Next() {
+		var p models.ProyectoResumen
+		if err := rows.Scan(&p.ID, &p.Titulo, &p.Codigo, &p.Estado, &p.FechaInicio, &p.FechaFin); err != nil {
+			return nil, fmt.Errorf("error scanning proyecto of group: %w", err)
+		}
+		proyectos = append(proyectos, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating proyectos of group: %w", err)
+	}
+	return proyectos, nil
+}
+
+// GetProyectoByID retrieves a single proyecto with its files, or (nil, nil) if it does not exist.
+func GetProyectoByID(ctx context.Context, db *sql.DB, id int) (*models.Proyecto, error) {
+	var p models.Proyecto
+	err := db.QueryRowContext(ctx, `SELECT `+proyectoColumns+` FROM proyecto p WHERE p.idProyecto = $1`, id).Scan(proyectoScanFields(&p)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting proyecto by ID: %w", err)
+	}
+	if p.Archivos, err = GetProyectoArchivos(ctx, db, id); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// CreateProyecto inserts a proyecto and reloads it into p. It returns ErrGrupoNoEncontrado or
+// ErrCodigoProyectoDuplicado.
+func CreateProyecto(ctx context.Context, db *sql.DB, p *models.Proyecto) error {
+	query := `INSERT INTO proyecto AS p (idGrupo, titulo, codigo, fuenteFinanciamiento, presupuesto, fechaInicio, fechaFin, estado)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING ` + proyectoColumns
+	err := db.QueryRowContext(ctx, query, p.IDGrupo, p.Titulo, p.Codigo, p.FuenteFinanciamiento, p.Presupuesto, p.FechaInicio, p.FechaFin, p.Estado).
+		Scan(proyectoScanFields(p)...)
+	if err != nil {
+		return proyectoWriteError(err, "inserting")
+	}
+	return nil
+}
+
+// UpdateProyecto replaces a proyecto's fields and reloads it into p. It returns
+// ErrProyectoNoEncontrado, ErrGrupoNoEncontrado or ErrCodigoProyectoDuplicado.
+func UpdateProyecto(ctx context.Context, db *sql.DB, p *models.Proyecto) error {
+	query := `UPDATE proyecto AS p SET idGrupo = $1, titulo = $2, codigo = $3, fuenteFinanciamiento = $4, presupuesto = $5,
+		fechaInicio = $6, fechaFin = $7, estado = $8
+		WHERE p.idProyecto = $9 RETURNING ` + proyectoColumns
+	err := db.QueryRowContext(ctx, query, p.IDGrupo, p.Titulo, p.Codigo, p.FuenteFinanciamiento, p.Presupuesto, p.FechaInicio, p.FechaFin, p.Estado, p.ID).
+		Scan(proyectoScanFields(p)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrProyectoNoEncontrado
+		}
+		return proyectoWriteError(err, "updating")
+	}
+	return nil
+}
+
+// proyectoWriteError maps the constraint violations of a proyecto write to domain errors.
+func proyectoWriteError(err error, accion string) error {
+	switch {
+	case isPgError(err, pgUniqueViolation, "uq_proyecto_codigo"):
+		return ErrCodigoProyectoDuplicado
+	case isPgError(err, pgForeignKeyViolation, ""):
+		return ErrGrupoNoEncontrado
+	}
+	return fmt.Errorf("error %s proyecto: %w", accion, err)
+}
+
+// DeleteProyecto deletes a proyecto and its file records, returning the storage refs of those
+// files so the caller can remove them. It returns ErrProyectoNoEncontrado if it does not exist.
+func DeleteProyecto(ctx context.Context, db *sql.DB, id int) ([]string, error) {
+	var refs []string
+	err := WithTx(ctx, db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `SELECT archivo FROM proyecto_archivo WHERE idProyecto = $1`, id)
+		if err != nil {
+			return fmt.Errorf("error querying proyecto files: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var ref string
+			if err := rows.Scan(&ref); err != nil {
+				return fmt.Errorf("error scanning proyecto file: %w", err)
+			}
+			refs = append(refs, ref)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error after iterating proyecto files: %w", err)
+		}
+		rows.Close()
+
+		res, err := tx.ExecContext(ctx, `DELETE FROM proyecto WHERE idProyecto = $1`, id)
+		if err != nil {
+			return fmt.Errorf("error deleting proyecto: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrProyectoNoEncontrado
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// CreateProyectoArchivo inserts a file record for a proyecto.
+func CreateProyectoArchivo(ctx context.Context, db *sql.DB, a *models.ProyectoArchivo) error {
+	query := `INSERT INTO proyecto_archivo (idProyecto, nombre, archivo, subidoPor)
+		VALUES ($1, $2, $3, $4) RETURNING idArchivo, createdAt`
+	if err := db.QueryRowContext(ctx, query, a.IDProyecto, a.Nombre, a.Archivo, a.SubidoPor).Scan(&a.ID, &a.CreatedAt); err != nil {
+		if isPgError(err, pgForeignKeyViolation, "") {
+			return ErrProyectoNoEncontrado
+		}
+		return fmt.Errorf("error inserting proyecto file: %w", err)
+	}
+	return nil
+}
+
+// GetProyectoArchivos lists the files of a proyecto in upload order.
+func GetProyectoArchivos(ctx context.Context, db *sql.DB, idProyecto int) ([]models.ProyectoArchivo, error) {
+	query := `SELECT ` + proyectoArchivoColumns + ` FROM proyecto_archivo a
+		WHERE a.idProyecto = $1 ORDER BY a.createdAt, a.idArchivo`
+	rows, err := db.QueryContext(ctx, query, idProyecto)
+	if err != nil {
+		return nil, fmt.Errorf("error querying proyecto files: %w", err)
+	}
+	defer rows.Close()
+
+	archivos := []models.ProyectoArchivo{}
+	for rows.Next() {
+		var a models.ProyectoArchivo
+		if err := rows.Scan(proyectoArchivoScanFields(&a)...); err != nil {
+			return nil, fmt.Errorf("error scanning proyecto file: %w", err)
+		}
+		archivos = append(archivos, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating proyecto files: %w", err)
+	}
+	return archivos, nil
+}
+
+// DeleteProyectoArchivo deletes a file record of a proyecto and returns it, so the caller can remove
+// its file, or (nil, nil) if it does not exist.
+func DeleteProyectoArchivo(ctx context.Context, db *sql.DB, idProyecto, idArchivo int) (*models.ProyectoArchivo, error) {
+	var a models.ProyectoArchivo
+	query := `DELETE FROM proyecto_archivo a WHERE a.idProyecto = $1 AND a.idArchivo = $2 RETURNING ` + proyectoArchivoColumns
+	if err := db.QueryRowContext(ctx, query, idProyecto, idArchivo).Scan(proyectoArchivoScanFields(&a)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error deleting proyecto file: %w", err)
+	}
+	return &a, nil
+}
@@ -0,0 +1,381 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// ErrPublicacionNoEncontrada is returned by write operations on a publicacion that does not exist.
+var ErrPublicacionNoEncontrada = notFoundError("publicación no encontrada")
+
+// ErrDOIDuplicado is returned when another publicacion already has the same DOI.
+var ErrDOIDuplicado = conflictError("doi duplicado")
+
+// publicacionColumns is the column list selected for a publicacion (aliased as p), in the order
+// scanned by scanPublicacion.
+const publicacionColumns = `p.idPublicacion, p.titulo, p.doi, p.revista, p.anio, p.tipo,
+	ARRAY(SELECT pi.idInvestigador FROM publicacion_investigador pi WHERE pi.idPublicacion = p.idPublicacion ORDER BY pi.idInvestigador),
+	ARRAY(SELECT pg.idGrupo FROM publicacion_grupo pg WHERE pg.idPublicacion = p.idPublicacion ORDER BY pg.idGrupo),
+	p.createdAt, p.updatedAt`
+
+// scanPublicacion scans a row selected with publicacionColumns.
+func scanPublicacion(scanner interface{ Scan(...interface{}) error }) (*models.Publicacion, error) {
+	var p models.Publicacion
+	var investigadores, grupos []int64
+	if err := scanner.Scan(&p.ID, &p.Titulo, &p.DOI, &p.Revista, &p.Anio, &p.Tipo, scanArray(&investigadores), scanArray(&grupos), &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	p.IDInvestigadores = intsFromInt64s(investigadores)
+	p.IDGrupos = intsFromInt64s(grupos)
+	return &p, nil
+}
+
+// intsFromInt64s converts a scanned integer array to []int (never nil).
+func intsFromInt64s(a []int64) []int {
+	out := make([]int, len(a))
+	for i, v := range a {
+		out[i] = int(v)
+	}
+	return out
+}
+
+// publicacionFilter builds the WHERE clause and arguments for the given filters, numbering
+// placeholders from 1.
+func publicacionFilter(f models.FiltroPublicaciones) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Q != "" {
+		add(`(f_unaccent(p.titulo) ILIKE f_unaccent($%[1]d) OR f_unaccent(p.revista) ILIKE f_unaccent($%[1]d))`, "%"+f.Q+"%")
+	}
+	if f.Anio != 0 {
+		add(`p.anio = $%d`, f.Anio)
+	}
+	if f.Tipo != "" {
+		add(`p.tipo = $%d`, f.Tipo)
+	}
+	if f.IDGrupo != 0 {
+		add(`EXISTS (SELECT 1 FROM publicacion_grupo pg WHERE pg.idPublicacion = p.idPublicacion AND pg.idGrupo = $%d)`, f.IDGrupo)
+	}
+	if f.IDInvestigador != 0 {
+		add(`EXISTS (SELECT 1 FROM publicacion_investigador pi WHERE pi.idPublicacion = p.idPublicacion AND pi.idInvestigador = $%d)`, f.IDInvestigador)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// GetPublicaciones retrieves a paginated list of publicaciones matching the filters, newest first.
+func GetPublicaciones(ctx context.Context, db *sql.DB, f models.FiltroPublicaciones, limit, offset int) ([]models.Publicacion, int, error) {
+	where, args := publicacionFilter(f)
+
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM publicacion p`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error querying total publicacion count: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM publicacion p%s ORDER BY p.anio DESC, p.titulo, p.idPublicacion LIMIT $%d OFFSET $%d`,
+		publicacionColumns, where, len(args)+1, len(args)+2)
+	rows, err := db.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying publicaciones page: %w", err)
+	}
+	defer rows.Close()
+
+	publicaciones := []models.Publicacion{}
+	for rows.Next() {
+		p, err := scanPublicacion(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning publicacion row: %w", err)
+		}
+		publicaciones = append(publicaciones, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error after iterating through publicacion rows: %w", err)
+	}
+	return publicaciones, total, nil
+}
+
+// maxPublicacionesPorGrupo bounds the publicaciones embedded in a group's detail.
+const maxPublicacionesPorGrupo = 1000
+
+// GetPublicacionesByGrupo returns the publicaciones credited to a group (up to
+// maxPublicacionesPorGrupo), newest first.
+func GetPublicacionesByGrupo(ctx context.Context, db *sql.DB, idGrupo int) ([]models.Publicacion, error) {
+	publicaciones, _, err := GetPublicaciones(ctx, db, models.FiltroPublicaciones{IDGrupo: idGrupo}, maxPublicacionesPorGrupo, 0)
+	return publicaciones, err
+}
+
+// GetPublicacionByID retrieves a single publicacion, or (nil, nil) if it does not exist.
+func GetPublicacionByID(ctx context.Context, db *sql.DB, id int) (*models.Publicacion, error) {
+	p, err := scanPublicacion(db.QueryRowContext(ctx, `SELECT `+publicacionColumns+` FROM publicacion p WHERE p.idPublicacion = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting publicacion by ID: %w", err)
+	}
+	return p, nil
+}
+
+// CreatePublicacion inserts a publicacion with its authors and groups in one transaction and reloads
+// it into p. It returns ErrDOIDuplicado, ErrInvestigadorNoExiste or ErrGrupoNoEncontrado.
+func CreatePublicacion(ctx context.Context, db *sql.DB, p *models.Publicacion) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO publicacion (titulo, doi, revista, anio, tipo) VALUES ($1, $2, $3, $4, $5) RETURNING idPublicacion`,
+		p.Titulo, p.DOI, p.Revista, p.Anio, p.Tipo).Scan(&p.ID)
+	if err != nil {
+		if isPgError(err, pgUniqueViolation, "uq_publicacion_doi") {
+			return ErrDOIDuplicado
+		}
+		return fmt.Errorf("error inserting publicacion: %w", err)
+	}
+	if err := savePublicacionLinks(ctx, tx, p); err != nil {
+		return err
+	}
+	return reloadPublicacion(ctx, tx, p)
+}
+
+// UpdatePublicacion replaces a publicacion's fields, authors and groups in one transaction and
+// reloads it into p. It returns ErrPublicacionNoEncontrada, ErrDOIDuplicado, ErrInvestigadorNoExiste
+// or ErrGrupoNoEncontrado.
+func UpdatePublicacion(ctx context.Context, db *sql.DB, p *models.Publicacion) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	res, err := tx.ExecContext(ctx, `UPDATE publicacion SET titulo = $1, doi = $2, revista = $3, anio = $4, tipo = $5 WHERE idPublicacion = $6`,
+		p.Titulo, p.DOI, p.Revista, p.Anio, p.Tipo, p.ID)
+	if err != nil {
+		if isPgError(err, pgUniqueViolation, "uq_publicacion_doi") {
+			return ErrDOIDuplicado
+		}
+		return fmt.Errorf("error updating publicacion: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrPublicacionNoEncontrada
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM publicacion_investigador WHERE idPublicacion = $1`, p.ID); err != nil {
+		return fmt.Errorf("error clearing publicacion authors: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM publicacion_grupo WHERE idPublicacion = $1`, p.ID); err != nil {
+		return fmt.Errorf("error clearing publicacion groups: %w", err)
+	}
+	if err := savePublicacionLinks(ctx, tx, p); err != nil {
+		return err
+	}
+	return reloadPublicacion(ctx, tx, p)
+}
+
+// savePublicacionLinks inserts the author and group links of p.
+func savePublicacionLinks(ctx context.Context, tx *sql.Tx, p *models.Publicacion) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO publicacion_investigador (idPublicacion, idInvestigador)
+		SELECT $1, unnest($2::int[]) ON CONFLICT DO NOTHING`, p.ID, p.IDInvestigadores)
+	if err != nil {
+		if isPgError(err, pgForeignKeyViolation, "") {
+			return ErrInvestigadorNoExiste
+		}
+		return fmt.Errorf("error linking publicacion authors: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO publicacion_grupo (idPublicacion, idGrupo)
+		SELECT $1, unnest($2::int[]) ON CONFLICT DO NOTHING`, p.ID, p.IDGrupos)
+	if err != nil {
+		if isPgError(err, pgForeignKeyViolation, "") {
+			return ErrGrupoNoEncontrado
+		}
+		return fmt.Errorf("error linking publicacion groups: %w", err)
+	}
+	return nil
+}
+
+// reloadPublicacion reads p back inside tx and commits it.
+func reloadPublicacion(ctx context.Context, tx *sql.Tx, p *models.Publicacion) error {
+	saved, err := scanPublicacion(tx.QueryRowContext(ctx, `SELECT `+publicacionColumns+` FROM publicacion p WHERE p.idPublicacion = $1`, p.ID))
+	if err != nil {
+		return fmt.Errorf("error reloading publicacion: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing publicacion: %w", err)
+	}
+	*p = *saved
+	return nil
+}
+
+// DeletePublicacion deletes a publicacion and its links. It reports whether it existed.
+func DeletePublicacion(ctx context.Context, db *sql.DB, id int) (bool, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM publicacion WHERE idPublicacion = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting publicacion: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking deleted publicacion: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ReconciliarPublicaciones merges publicaciones from an external source (a BibTeX file, a
+// researcher registry) into the local ones, in one transaction and in order, so that repeated
+// entries match the first. Each one matches a local publicacion by DOI or, failing that, by titulo
+// (ignoring case and accents) and anio: its IDInvestigadores and IDGrupos are added to the links of
+// that one and its missing DOI filled in, nothing else changes. Those without a match are created.
+// Nothing is removed. It returns ErrInvestigadorNoExiste or ErrGrupoNoEncontrado.
+func ReconciliarPublicaciones(ctx context.Context, db *sql.DB, pubs []models.Publicacion) ([]models.PublicacionReconciliada, error) {
+	resultados := make([]models.PublicacionReconciliada, len(pubs))
+	err := WithTx(ctx, db, func(tx *sql.Tx) error {
+		for i, p := range pubs {
+			id, err := buscarPublicacion(ctx, tx, p)
+			if err != nil {
+				return err
+			}
+			res := &resultados[i]
+			if id == 0 {
+				err := tx.QueryRowContext(ctx, `INSERT INTO publicacion (titulo, doi, revista, anio, tipo) VALUES ($1, $2, $3, $4, $5) RETURNING idPublicacion`,
+					p.Titulo, p.DOI, p.Revista, p.Anio, p.Tipo).Scan(&id)
+				if err != nil {
+					return fmt.Errorf("error inserting publicacion: %w", err)
+				}
+				res.Creada = true
+			} else if p.DOI != nil {
+				if _, err := tx.ExecContext(ctx, `UPDATE publicacion SET doi = $1 WHERE idPublicacion = $2 AND doi IS NULL`, p.DOI, id); err != nil {
+					return fmt.Errorf("error completing publicacion DOI: %w", err)
+				}
+			}
+			res.ID = id
+			for _, idInvestigador := range p.IDInvestigadores {
+				nuevo, err := agregarVinculo(ctx, tx, `INSERT INTO publicacion_investigador (idPublicacion, idInvestigador) VALUES ($1, $2) ON CONFLICT DO NOTHING`, id, idInvestigador)
+				if err != nil {
+					if isPgError(err, pgForeignKeyViolation, "") {
+						return ErrInvestigadorNoExiste
+					}
+					return fmt.Errorf("error linking publicacion author: %w", err)
+				}
+				res.Vinculada = res.Vinculada || (nuevo && !res.Creada)
+			}
+			for _, idGrupo := range p.IDGrupos {
+				nuevo, err := agregarVinculo(ctx, tx, `INSERT INTO publicacion_grupo (idPublicacion, idGrupo) VALUES ($1, $2) ON CONFLICT DO NOTHING`, id, idGrupo)
+				if err != nil {
+					if isPgError(err, pgForeignKeyViolation, "") {
+						return ErrGrupoNoEncontrado
+					}
+					return fmt.Errorf("error linking publicacion group: %w", err)
+				}
+				res.Vinculada = res.Vinculada || (nuevo && !res.Creada)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resultados, nil
+}
+
+// agregarVinculo runs an INSERT ... ON CONFLICT DO NOTHING of a link, reporting whether it was new.
+func agregarVinculo(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (bool, error) {
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// buscarPublicacion returns the ID of the local publicacion matching p by DOI or by titulo and
+// anio, or 0 if there is none.
+func buscarPublicacion(ctx context.Context, tx *sql.Tx, p models.Publicacion) (int, error) {
+	var id int
+	var err error
+	if p.DOI != nil {
+		err = tx.QueryRowContext(ctx, `SELECT idPublicacion FROM publicacion WHERE lower(doi) = lower($1)`, *p.DOI).Scan(&id)
+	}
+	if p.DOI == nil || err == sql.ErrNoRows {
+		err = tx.QueryRowContext(ctx, `SELECT idPublicacion FROM publicacion WHERE lower(f_unaccent(titulo)) = lower(f_unaccent($1)) AND anio = $2
+			ORDER BY idPublicacion LIMIT 1`, p.Titulo, p.Anio).Scan(&id)
+	}
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error matching publicacion: %w", err)
+	}
+	return id, nil
+}
+
+// GetPublicacionesConAutoresByGrupo returns the publicaciones credited to a group (up to
+// maxPublicacionesPorGrupo), oldest first, with their authors' names.
+func GetPublicacionesConAutoresByGrupo(ctx context.Context, db *sql.DB, idGrupo int) ([]models.PublicacionConAutores, error) {
+	query := `SELECT ` + publicacionColumns + ` FROM publicacion p
+		JOIN publicacion_grupo pg ON pg.idPublicacion = p.idPublicacion AND pg.idGrupo = $1
+		ORDER BY p.anio, p.idPublicacion LIMIT $2`
+	return queryPublicacionesConAutores(ctx, db, query, idGrupo, maxPublicacionesPorGrupo)
+}
+
+// queryPublicacionesConAutores runs a query selecting publicacionColumns and loads the authors' names.
+func queryPublicacionesConAutores(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]models.PublicacionConAutores, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying publicaciones with authors: %w", err)
+	}
+	defer rows.Close()
+
+	publicaciones := []models.PublicacionConAutores{}
+	for rows.Next() {
+		p, err := scanPublicacion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning publicacion: %w", err)
+		}
+		publicaciones = append(publicaciones, models.PublicacionConAutores{Publicacion: *p})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating publicaciones with authors: %w", err)
+	}
+	if len(publicaciones) == 0 {
+		return publicaciones, nil
+	}
+
+	ids := make([]interface{}, len(publicaciones))
+	indice := make(map[int]int, len(publicaciones))
+	for i, p := range publicaciones {
+		ids[i] = p.ID
+		indice[p.ID] = i
+	}
+	rows, err = db.QueryContext(ctx, `SELECT pi.idPublicacion, i.apellido, i.nombre FROM publicacion_investigador pi
+		JOIN investigador i ON i.idInvestigador = pi.idInvestigador
+		WHERE pi.idPublicacion IN `+placeholdersIn(len(ids))+` ORDER BY pi.idPublicacion, i.apellido, i.nombre`, ids...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying publicacion authors: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var idPublicacion int
+		var apellido, nombre string
+		if err := rows.Scan(&idPublicacion, &apellido, &nombre); err != nil {
+			return nil, fmt.Errorf("error scanning publicacion author: %w", err)
+		}
+		p := &publicaciones[indice[idPublicacion]]
+		p.Autores = append(p.Autores, apellido+", "+nombre)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating publicacion authors: %w", err)
+	}
+	return publicaciones, nil
+}
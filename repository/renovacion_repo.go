@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+var (
+	// ErrRenovacionNoEncontrada is returned when the renovacion does not exist.
+	ErrRenovacionNoEncontrada = notFoundError("renovación no encontrada")
+	// ErrRenovacionPendiente is returned when the group already has a renovacion awaiting review.
+	ErrRenovacionPendiente = conflictError("el grupo ya tiene una renovación pendiente")
+	// ErrRenovacionRevisada is returned when approving or rejecting a renovacion that is no longer pendiente.
+	ErrRenovacionRevisada = conflictError("la renovación ya fue revisada")
+	// ErrGrupoCerrado is returned when requesting the renovacion of a cerrado group.
+	ErrGrupoCerrado = conflictError("un grupo cerrado no se puede renovar")
+	// ErrGrupoNoAprobado is returned when requesting the renovacion of a group whose registration
+	// has not been approved.
+	ErrGrupoNoAprobado = conflictError("el registro del grupo aún no fue aprobado")
+)
+
+const renovacionColumns = `v.idRenovacion, v.idGrupo, v.estado, v.motivo, v.observaciones, v.estadoAnterior, v.idResolucion, v.solicitadoPor, v.revisadoPor, v.fechaRevision, v.createdAt, v.updatedAt`
+
+// renovacionScanFields returns the scan destinations matching renovacionColumns.
+func renovacionScanFields(v *models.Renovacion) []interface{} {
+	return []interface{}{&v.ID, &v.IDGrupo, &v.Estado, &v.Motivo, &v.Observaciones, &v.EstadoAnterior, &v.IDResolucion, &v.SolicitadoPor, &v.RevisadoPor, &v.FechaRevision, &v.CreatedAt, &v.UpdatedAt}
+}
+
+// GetRenovaciones lists the renovaciones with the name of their group, oldest first; estado ""
+// lists them all.
+func GetRenovaciones(ctx context.Context, db *sql.DB, estado string) ([]models.Renovacion, error) {
+	query := `SELECT ` + renovacionColumns + `, g.nombre FROM renovacion v
+		JOIN grupo g ON g.idGrupo = v.idGrupo
+		WHERE ($1 = '' OR v.estado = $1) ORDER BY v.createdAt, v.idRenovacion`
+	return queryRenovaciones(ctx, db, query, estado)
+}
+
+// GetRenovacionesByGrupo lists the renovaciones of a group, newest first.
+func GetRenovacionesByGrupo(ctx context.Context, db *sql.DB, idGrupo int) ([]models.Renovacion, error) {
+	query := `SELECT ` + renovacionColumns + `, g.nombre FROM renovacion v
+		JOIN grupo g ON g.idGrupo = v.idGrupo
+		WHERE v.idGrupo = $1 ORDER BY v.createdAt DESC, v.idRenovacion DESC`
+	return queryRenovaciones(ctx, db, query, idGrupo)
+}
+
+func queryRenovaciones(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]models.Renovacion, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying renewals: %w", err)
+	}
+	defer rows.Close()
+
+	renovaciones := []models.Renovacion{}
+	for rows.Next() {
+		var v models.Renovacion
+		if err := rows.Scan(append(renovacionScanFields(&v), &v.NombreGrupo)...); err != nil {
+			return nil, fmt.Errorf("error scanning renewal: %w", err)
+		}
+		renovaciones = append(renovaciones, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating renewals: %w", err)
+	}
+	return renovaciones, nil
+}
+
+// CreateRenovacion submits a renovacion for v.IDGrupo, remembering the group's estado and moving it
+// to en_renovacion, and reloads v. It returns ErrGrupoNoEncontrado, ErrGrupoCerrado,
+// ErrGrupoNoAprobado or ErrRenovacionPendiente.
+func CreateRenovacion(ctx context.Context, db *sql.DB, v *models.Renovacion) error {
+	return WithTx(ctx, db, func(tx *sql.Tx) error {
+		var estado string
+		err := tx.QueryRowContext(ctx, `SELECT estado FROM grupo WHERE idGrupo = $1 AND deletedAt IS NULL FOR UPDATE`, v.IDGrupo).Scan(&estado)
+		if err == sql.ErrNoRows {
+			return ErrGrupoNoEncontrado
+		}
+		if err != nil {
+			return fmt.Errorf("error locking group for renewal: %w", err)
+		}
+		if estado == models.EstadoGrupoCerrado {
+			return ErrGrupoCerrado
+		}
+		if !models.EsGrupoAprobado(estado) {
+			return ErrGrupoNoAprobado
+		}
+
+		query := `INSERT INTO renovacion AS v (idGrupo, motivo, estadoAnterior, solicitadoPor)
+			VALUES ($1, $2, $3, $4) RETURNING ` + renovacionColumns
+		err = tx.QueryRowContext(ctx, query, v.IDGrupo, v.Motivo, estado, v.SolicitadoPor).Scan(renovacionScanFields(v)...)
+		if err != nil {
+			if isPgError(err, pgUniqueViolation, "uq_renovacion_pendiente") {
+				return ErrRenovacionPendiente
+			}
+			return fmt.Errorf("error inserting renewal: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE grupo SET estado = $1, updatedAt = CURRENT_TIMESTAMP WHERE idGrupo = $2`, models.EstadoGrupoEnRenovacion, v.IDGrupo); err != nil {
+			return fmt.Errorf("error updating group estado for renewal: %w", err)
+		}
+		return nil
+	})
+}
+
+// AprobarRenovacion approves a pendiente renovacion: it records res as the group's renovacion
+// resolution, updates the group's fechaVencimiento and makes it activo again if it is still
+// en_renovacion. res and the returned renovacion are reloaded. It returns
+// ErrRenovacionNoEncontrada, ErrRenovacionRevisada or ErrResolucionDuplicada.
+func AprobarRenovacion(ctx context.Context, db *sql.DB, id int, res *models.Resolucion, observaciones string, revisadoPor *int) (*models.Renovacion, error) {
+	return revisarRenovacion(ctx, db, id, func(tx *sql.Tx, v *models.Renovacion) error {
+		res.IDGrupo = v.IDGrupo
+		if err := insertResolucion(ctx, tx, res); err != nil {
+			return err
+		}
+		if err := actualizarVencimientoGrupo(ctx, tx, v.IDGrupo); err != nil {
+			return err
+		}
+		query := `UPDATE renovacion AS v SET estado = $1, idResolucion = $2, observaciones = $3, revisadoPor = $4, fechaRevision = CURRENT_TIMESTAMP
+			WHERE v.idRenovacion = $5 RETURNING ` + renovacionColumns
+		if err := tx.QueryRowContext(ctx, query, models.RenovacionAprobada, res.ID, observaciones, revisadoPor, id).Scan(renovacionScanFields(v)...); err != nil {
+			return fmt.Errorf("error approving renewal: %w", err)
+		}
+		return restaurarEstadoGrupo(ctx, tx, v.IDGrupo, models.EstadoGrupoActivo)
+	})
+}
+
+// RechazarRenovacion rejects a pendiente renovacion and puts its group back in the estado it had
+// when it was submitted, if it is still en_renovacion. It returns ErrRenovacionNoEncontrada or
+// ErrRenovacionRevisada.
+func RechazarRenovacion(ctx context.Context, db *sql.DB, id int, observaciones string, revisadoPor *int) (*models.Renovacion, error) {
+	return revisarRenovacion(ctx, db, id, func(tx *sql.Tx, v *models.Renovacion) error {
+		query := `UPDATE renovacion AS v SET estado = $1, observaciones = $2, revisadoPor = $3, fechaRevision = CURRENT_TIMESTAMP
+			WHERE v.idRenovacion = $4 RETURNING ` + renovacionColumns
+		if err := tx.QueryRowContext(ctx, query, models.RenovacionRechazada, observaciones, revisadoPor, id).Scan(renovacionScanFields(v)...); err != nil {
+			return fmt.Errorf("error rejecting renewal: %w", err)
+		}
+		return restaurarEstadoGrupo(ctx, tx, v.IDGrupo, v.EstadoAnterior)
+	})
+}
+
+// revisarRenovacion locks a pendiente renovacion and runs fn on it within a transaction.
+func revisarRenovacion(ctx context.Context, db *sql.DB, id int, fn func(tx *sql.Tx, v *models.Renovacion) error) (*models.Renovacion, error) {
+	var v models.Renovacion
+	err := WithTx(ctx, db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `SELECT `+renovacionColumns+` FROM renovacion v WHERE v.idRenovacion = $1 FOR UPDATE`, id).
+			Scan(renovacionScanFields(&v)...)
+		if err == sql.ErrNoRows {
+			return ErrRenovacionNoEncontrada
+		}
+		if err != nil {
+			return fmt.Errorf("error locking renewal: %w", err)
+		}
+		if v.Estado != models.RenovacionPendiente {
+			return ErrRenovacionRevisada
+		}
+		return fn(tx, &v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// restaurarEstadoGrupo moves a group out of en_renovacion to estado; a group an admin moved to
+// another estado meanwhile is left as it is.
+func restaurarEstadoGrupo(ctx context.Context, tx *sql.Tx, idGrupo int, estado string) error {
+	_, err := tx.ExecContext(ctx, `UPDATE grupo SET estado = $1, updatedAt = CURRENT_TIMESTAMP WHERE idGrupo = $2 AND estado = $3`,
+		estado, idGrupo, models.EstadoGrupoEnRenovacion)
+	if err != nil {
+		return fmt.Errorf("error restoring group estado after renewal review: %w", err)
+	}
+	return nil
+}
+
+// GetAvisosVencimientoPendientes returns the approved groups, neither deleted nor cerrado, whose
+// fechaVencimiento is before limite and whose coordinators have not been warned about it yet, with
+// the coordinators' emails (only verified ones with soloVerificados).
+func GetAvisosVencimientoPendientes(ctx context.Context, db *sql.DB, limite calendario.Fecha, soloVerificados bool) ([]models.AvisoVencimiento, error) {
+	rows, err := db.QueryContext(ctx, `SELECT g.idGrupo, g.nombre, g.fechaVencimiento FROM grupo g
+		WHERE g.deletedAt IS NULL AND g.estado <> $1 AND g.estado NOT IN `+estadosNoAprobados+` AND g.fechaVencimiento < $2
+			AND NOT EXISTS (SELECT 1 FROM aviso_vencimiento a WHERE a.idGrupo = g.idGrupo AND a.fechaVencimiento = g.fechaVencimiento)
+		ORDER BY g.fechaVencimiento, g.idGrupo`, models.EstadoGrupoCerrado, limite)
+	if err != nil {
+		return nil, fmt.Errorf("error querying groups near expiry: %w", err)
+	}
+	defer rows.Close()
+
+	avisos := []models.AvisoVencimiento{}
+	for rows.Next() {
+		var a models.AvisoVencimiento
+		if err := rows.Scan(&a.IDGrupo, &a.NombreGrupo, &a.FechaVencimiento); err != nil {
+			return nil, fmt.Errorf("error scanning group near expiry: %w", err)
+		}
+		avisos = append(avisos, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating groups near expiry: %w", err)
+	}
+	rows.Close()
+
+	for i := range avisos {
+		if avisos[i].Emails, err = getEmailsCoordinadores(ctx, db, avisos[i].IDGrupo, soloVerificados); err != nil {
+			return nil, err
+		}
+	}
+	return avisos, nil
+}
+
+// getEmailsCoordinadores returns the emails of the active coordinators of a group.
+func getEmailsCoordinadores(ctx context.Context, db *sql.DB, idGrupo int, soloVerificados bool) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT DISTINCT i.email FROM Grupo_Investigador gi
+		JOIN Investigador i ON i.idInvestigador = gi.idInvestigador
+		WHERE gi.idGrupo = $1 AND lower(gi.rol) = 'coordinador' AND gi.fechaFin IS NULL AND i.deletedAt IS NULL AND i.email IS NOT NULL
+			AND (i.emailVerificado OR NOT $2)
+		ORDER BY i.email`, idGrupo, soloVerificados)
+	if err != nil {
+		return nil, fmt.Errorf("error querying coordinator emails: %w", err)
+	}
+	defer rows.Close()
+
+	emails := []string{}
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, fmt.Errorf("error scanning coordinator email: %w", err)
+		}
+		emails = append(emails, email)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating coordinator emails: %w", err)
+	}
+	return emails, nil
+}
+
+// MarkAvisoVencimientoEnviado records that the coordinators of a group were warned about
+// fechaVencimiento, so the notice is not repeated until the group gets a new one.
+func MarkAvisoVencimientoEnviado(ctx context.Context, db *sql.DB, idGrupo int, fechaVencimiento calendario.Fecha) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO aviso_vencimiento (idGrupo, fechaVencimiento) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, idGrupo, fechaVencimiento)
+	if err != nil {
+		return fmt.Errorf("error recording expiry notice: %w", err)
+	}
+	return nil
+}
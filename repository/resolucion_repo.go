@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// ErrResolucionDuplicada is returned when the group already has a resolucion with the same numero.
+var ErrResolucionDuplicada = conflictError("el grupo ya tiene una resolución con ese número")
+
+const resolucionColumns = `r.idResolucion, r.idGrupo, r.numero, r.fechaEmision, r.tipo, r.descripcion, r.archivo, r.fechaVencimiento, r.subidoPor, r.createdAt, r.updatedAt`
+
+// resolucionScanFields returns the scan destinations matching resolucionColumns.
+func resolucionScanFields(r *models.Resolucion) []interface{} {
+	return []interface{}{&r.ID, &r.IDGrupo, &r.Numero, &r.FechaEmision, &r.Tipo, &r.Descripcion, &r.Archivo, &r.FechaVencimiento, &r.SubidoPor, &r.CreatedAt, &r.UpdatedAt}
+}
+
+// GetResolucionesByGrupo lists the resoluciones of a group, oldest first.
+func GetResolucionesByGrupo(ctx context.Context, db *sql.DB, idGrupo int) ([]models.Resolucion, error) {
+	query := `SELECT ` + resolucionColumns + ` FROM resolucion r
+		WHERE r.idGrupo = $1 ORDER BY r.fechaEmision, r.idResolucion`
+	rows, err := db.QueryContext(ctx, query, idGrupo)
+	if err != nil {
+		return nil, fmt.Errorf("error querying group resolutions: %w", err)
+	}
+	defer rows.Close()
+
+	resoluciones := []models.Resolucion{}
+	for rows.Next() {
+		var r models.Resolucion
+		if err := rows.Scan(resolucionScanFields(&r)...); err != nil {
+			return nil, fmt.Errorf("error scanning group resolution: %w", err)
+		}
+		resoluciones = append(resoluciones, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating group resolutions: %w", err)
+	}
+	return resoluciones, nil
+}
+
+// CreateResolucion records a resolucion of a group, updating the group's fechaVencimiento, and
+// reloads it into r. It returns ErrResolucionDuplicada or ErrGrupoNoEncontrado.
+func CreateResolucion(ctx context.Context, db *sql.DB, r *models.Resolucion) error {
+	return WithTx(ctx, db, func(tx *sql.Tx) error {
+		if err := insertResolucion(ctx, tx, r); err != nil {
+			return err
+		}
+		return actualizarVencimientoGrupo(ctx, tx, r.IDGrupo)
+	})
+}
+
+// insertResolucion inserts r within tx and reloads it.
+func insertResolucion(ctx context.Context, tx *sql.Tx, r *models.Resolucion) error {
+	query := `INSERT INTO resolucion AS r (idGrupo, numero, fechaEmision, tipo, descripcion, archivo, fechaVencimiento, subidoPor)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING ` + resolucionColumns
+	err := tx.QueryRowContext(ctx, query, r.IDGrupo, r.Numero, r.FechaEmision, r.Tipo, r.Descripcion, r.Archivo, r.FechaVencimiento, r.SubidoPor).
+		Scan(resolucionScanFields(r)...)
+	if err != nil {
+		switch {
+		case isPgError(err, pgUniqueViolation, "uq_resolucion_grupo_numero"):
+			return ErrResolucionDuplicada
+		case isPgError(err, pgForeignKeyViolation, ""):
+			return ErrGrupoNoEncontrado
+		}
+		return fmt.Errorf("error inserting resolution: %w", err)
+	}
+	return nil
+}
+
+// actualizarVencimientoGrupo sets the fechaVencimiento of a group to the latest of its resoluciones.
+func actualizarVencimientoGrupo(ctx context.Context, tx *sql.Tx, idGrupo int) error {
+	_, err := tx.ExecContext(ctx, `UPDATE grupo SET fechaVencimiento = (SELECT MAX(r.fechaVencimiento) FROM resolucion r WHERE r.idGrupo = $1)
+		WHERE idGrupo = $1`, idGrupo)
+	if err != nil {
+		return fmt.Errorf("error updating group fechaVencimiento: %w", err)
+	}
+	return nil
+}
+
+// DeleteResolucion deletes a resolucion of a group, updating the group's fechaVencimiento, and
+// returns it, so the caller can remove its file, or (nil, nil) if it does not exist.
+func DeleteResolucion(ctx context.Context, db *sql.DB, idGrupo, idResolucion int) (*models.Resolucion, error) {
+	var r models.Resolucion
+	err := WithTx(ctx, db, func(tx *sql.Tx) error {
+		query := `DELETE FROM resolucion r WHERE r.idGrupo = $1 AND r.idResolucion = $2 RETURNING ` + resolucionColumns
+		if err := tx.QueryRowContext(ctx, query, idGrupo, idResolucion).Scan(resolucionScanFields(&r)...); err != nil {
+			return err
+		}
+		return actualizarVencimientoGrupo(ctx, tx, idGrupo)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error deleting resolution: %w", err)
+	}
+	return &r, nil
+}
+
+// ResolucionUsaArchivo reports whether some resolucion keeps archivo as its PDF.
+func ResolucionUsaArchivo(ctx context.Context, db *sql.DB, archivo string) (bool, error) {
+	var existe bool
+	if err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM resolucion WHERE archivo = $1)`, archivo).Scan(&existe); err != nil {
+		return false, fmt.Errorf("error checking resolution files: %w", err)
+	}
+	return existe, nil
+}
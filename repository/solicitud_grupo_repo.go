@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// ErrSolicitudYaRevisada is returned when trying to moderate a request that is no longer pending.
+var ErrSolicitudYaRevisada = conflictError("solicitud ya fue revisada")
+
+const solicitudColumns = `idSolicitud, nombre, numeroResolucion, lineaInvestigacion, tipoInvestigacion, descripcion, nombreSolicitante, apellidoSolicitante, emailSolicitante, integrantes, estado, comentario, idGrupo, revisadoPor, fechaRevision, createdAt, updatedAt`
+
+// scanSolicitud scans a row selected with solicitudColumns.
+func scanSolicitud(scanner interface{ Scan(...interface{}) error }) (*models.SolicitudGrupo, error) {
+	var s models.SolicitudGrupo
+	var integrantesJSON []byte
+	var idGrupo, revisadoPor sql.NullInt64
+	var fechaRevision sql.NullTime
+	if err := scanner.Scan(&s.ID, &s.Nombre, &s.NumeroResolucion, &s.LineaInvestigacion, &s.TipoInvestigacion, &s.Descripcion,
+		&s.NombreSolicitante, &s.ApellidoSolicitante, &s.EmailSolicitante, &integrantesJSON, &s.Estado, &s.Comentario,
+		&idGrupo, &revisadoPor, &fechaRevision, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(integrantesJSON, &s.Integrantes); err != nil {
+		return nil, fmt.Errorf("error decoding solicitud members: %w", err)
+	}
+	if idGrupo.Valid {
+		v := int(idGrupo.Int64)
+		s.IDGrupo = &v
+	}
+	if revisadoPor.Valid {
+		v := int(revisadoPor.Int64)
+		s.RevisadoPor = &v
+	}
+	if fechaRevision.Valid {
+		s.FechaRevision = &fechaRevision.Time
+	}
+	return &s, nil
+}
+
+// CreateSolicitudGrupo inserts a new group registration request in the moderation queue.
+func CreateSolicitudGrupo(ctx context.Context, db *sql.DB, s *models.SolicitudGrupo) error {
+	if s.Integrantes == nil {
+		s.Integrantes = []models.IntegranteSolicitud{}
+	}
+	integrantesJSON, err := json.Marshal(s.Integrantes)
+	if err != nil {
+		return fmt.Errorf("error encoding solicitud members: %w", err)
+	}
+
+	query := `INSERT INTO solicitud_grupo (nombre, numeroResolucion, lineaInvestigacion, tipoInvestigacion, descripcion, nombreSolicitante, apellidoSolicitante, emailSolicitante, integrantes, estado)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING idSolicitud, createdAt, updatedAt`
+	err = db.QueryRowContext(ctx, query, s.Nombre, s.NumeroResolucion, s.LineaInvestigacion, s.TipoInvestigacion, s.Descripcion,
+		s.NombreSolicitante, s.ApellidoSolicitante, s.EmailSolicitante, integrantesJSON, models.SolicitudPendiente).
+		Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error inserting solicitud: %w", err)
+	}
+	s.Estado = models.SolicitudPendiente
+	return nil
+}
+
+// GetSolicitudesGrupo retrieves a paginated list of requests, optionally filtered by estado.
+func GetSolicitudesGrupo(ctx context.Context, db *sql.DB, estado string, limit, offset int) ([]models.SolicitudGrupo, int, error) {
+	query := `SELECT ` + solicitudColumns + ` FROM solicitud_grupo WHERE ($1 = '' OR estado = $1) ORDER BY createdAt, idSolicitud LIMIT $2 OFFSET $3`
+	rows, err := db.QueryContext(ctx, query, estado, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying solicitudes page: %w", err)
+	}
+	defer rows.Close()
+
+	solicitudes := []models.SolicitudGrupo{}
+	for rows.Next() {
+		s, err := scanSolicitud(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning solicitud row: %w", err)
+		}
+		solicitudes = append(solicitudes, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error after iterating through solicitud rows: %w", err)
+	}
+
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM solicitud_grupo WHERE ($1 = '' OR estado = $1)`, estado).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error querying total solicitud count: %w", err)
+	}
+	return solicitudes, total, nil
+}
+
+// GetSolicitudGrupoByID retrieves a single request by its ID.
+func GetSolicitudGrupoByID(ctx context.Context, db *sql.DB, id int) (*models.SolicitudGrupo, error) {
+	s, err := scanSolicitud(db.QueryRowContext(ctx, `SELECT `+solicitudColumns+` FROM solicitud_grupo WHERE idSolicitud = $1`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Not found
+		}
+		return nil, fmt.Errorf("error getting solicitud by ID: %w", err)
+	}
+	return s, nil
+}
+
+// RejectSolicitudGrupo marks a pending request as rejected with the moderator's comments.
+// Returns (nil, nil) if the request does not exist.
+func RejectSolicitudGrupo(ctx context.Context, db *sql.DB, id int, comentario string, revisadoPor int) (*models.SolicitudGrupo, error) {
+	s, err := GetSolicitudGrupoByID(ctx, db, id)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	if s.Estado != models.SolicitudPendiente {
+		return nil, ErrSolicitudYaRevisada
+	}
+
+	query := `UPDATE solicitud_grupo SET estado = $1, comentario = $2, revisadoPor = $3, fechaRevision = CURRENT_TIMESTAMP, updatedAt = CURRENT_TIMESTAMP
+		WHERE idSolicitud = $4 AND estado = $5`
+	res, err := db.ExecContext(ctx, query, models.SolicitudRechazada, comentario, revisadoPor, id, models.SolicitudPendiente)
+	if err != nil {
+		return nil, fmt.Errorf("error rejecting solicitud: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrSolicitudYaRevisada // Moderated concurrently
+	}
+	return GetSolicitudGrupoByID(ctx, db, id)
+}
+
+// ApproveSolicitudGrupo converts a pending request into a group plus its memberships in one transaction.
+// The requester becomes the group's coordinator; proposed members without idInvestigador are created
+// as new investigators. Returns (nil, nil, nil) if the request does not exist.
+func ApproveSolicitudGrupo(ctx context.Context, db *sql.DB, id int, numeroResolucion string, fechaRegistro calendario.Fecha, comentario string, revisadoPor int) (*models.SolicitudGrupo, *models.Grupo, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	// Lock the request row so two admins can't approve it at the same time
+	s, err := scanSolicitud(tx.QueryRowContext(ctx, `SELECT `+solicitudColumns+` FROM solicitud_grupo WHERE idSolicitud = $1 FOR UPDATE`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("error getting solicitud for approval: %w", err)
+	}
+	if s.Estado != models.SolicitudPendiente {
+		return nil, nil, ErrSolicitudYaRevisada
+	}
+
+	if numeroResolucion == "" {
+		numeroResolucion = s.NumeroResolucion
+	}
+	g := models.Grupo{
+		Nombre:             s.Nombre,
+		NumeroResolucion:   numeroResolucion,
+		LineaInvestigacion: s.LineaInvestigacion,
+		TipoInvestigacion:  s.TipoInvestigacion,
+		FechaRegistro:      fechaRegistro,
+	}
+	err = tx.QueryRowContext(ctx, `INSERT INTO grupo (nombre, numeroResolucion, lineaInvestigacion, tipoInvestigacion, fechaRegistro) VALUES ($1, $2, $3, $4, $5) RETURNING idGrupo, estado, createdAt, updatedAt`,
+		g.Nombre, g.NumeroResolucion, g.LineaInvestigacion, g.TipoInvestigacion, g.FechaRegistro).Scan(&g.ID, &g.Estado, &g.CreatedAt, &g.UpdatedAt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error inserting group from solicitud: %w", err)
+	}
+
+	// The requester is registered as coordinator, followed by the proposed members
+	integrantes := append([]models.IntegranteSolicitud{{
+		Nombre:   s.NombreSolicitante,
+		Apellido: s.ApellidoSolicitante,
+		Rol:      models.RolCoordinador,
+	}}, s.Integrantes...)
+
+	agregados := map[int]bool{}
+	for _, integrante := range integrantes {
+		var idInvestigador int
+		if integrante.IDInvestigador != nil {
+			idInvestigador = *integrante.IDInvestigador
+		} else {
+			err = tx.QueryRowContext(ctx, `INSERT INTO investigador (nombre, apellido) VALUES ($1, $2) RETURNING idInvestigador`, integrante.Nombre, integrante.Apellido).Scan(&idInvestigador)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error inserting investigator from solicitud: %w", err)
+			}
+		}
+		// A member proposed twice is added once
+		if agregados[idInvestigador] {
+			continue
+		}
+		agregados[idInvestigador] = true
+		if _, err = tx.ExecContext(ctx, `INSERT INTO Grupo_Investigador (idGrupo, idInvestigador, rol) VALUES ($1, $2, $3)`, g.ID, idInvestigador, integrante.Rol); err != nil {
+			return nil, nil, fmt.Errorf("error inserting group-investigator detail from solicitud: %w", err)
+		}
+	}
+
+	query := `UPDATE solicitud_grupo SET estado = $1, comentario = $2, idGrupo = $3, revisadoPor = $4, fechaRevision = CURRENT_TIMESTAMP, updatedAt = CURRENT_TIMESTAMP
+		WHERE idSolicitud = $5`
+	if _, err = tx.ExecContext(ctx, query, models.SolicitudAprobada, comentario, g.ID, revisadoPor, id); err != nil {
+		return nil, nil, fmt.Errorf("error marking solicitud as approved: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("error committing solicitud approval: %w", err)
+	}
+
+	s, err = GetSolicitudGrupoByID(ctx, db, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, &g, nil
+}
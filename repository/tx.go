@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// WithTx runs fn in a transaction, committing it if fn returns nil and rolling it back otherwise
+// (or if fn panics). fn's error is returned as is, so callers can match domain errors. Repository
+// functions called with tx can send their statements in batches (see execBatch).
+func WithTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, end, err := beginTx(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// AddUsoAPI adds the counters of usos to those already stored for the same hour, route and
+// client; each instance of the API flushes its own.
+func AddUsoAPI(ctx context.Context, db *sql.DB, usos []models.UsoAPI) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	query := `INSERT INTO uso_api (inicio, metodo, ruta, idUsuario, ip, peticiones, errores, duracionMs)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (inicio, metodo, ruta, idUsuario, ip) DO UPDATE SET
+			peticiones = uso_api.peticiones + EXCLUDED.peticiones,
+			errores = uso_api.errores + EXCLUDED.errores,
+			duracionMs = uso_api.duracionMs + EXCLUDED.duracionMs`
+	for _, u := range usos {
+		idUsuario := 0 // Anonymous callers; the column is part of the key, so it can't be NULL
+		if u.IDUsuario != nil {
+			idUsuario = *u.IDUsuario
+		}
+		if _, err := tx.ExecContext(ctx, query, u.Inicio, u.Metodo, u.Ruta, idUsuario, u.IP, u.Peticiones, u.Errores, u.DuracionMs); err != nil {
+			return fmt.Errorf("error saving API usage: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing API usage: %w", err)
+	}
+	return nil
+}
+
+// GetUsoAPI retrieves the hourly usage between desde and hasta, optionally of a route template and
+// of a user, with the email of each user.
+func GetUsoAPI(ctx context.Context, db *sql.DB, desde, hasta time.Time, ruta string, idUsuario int) ([]models.UsoAPI, error) {
+	query := `SELECT u.inicio, u.metodo, u.ruta, u.idUsuario, COALESCE(us.email, ''), u.ip, u.peticiones, u.errores, u.duracionMs
+		FROM uso_api u LEFT JOIN Usuario us ON us.idUsuario = u.idUsuario
+		WHERE u.inicio >= $1 AND u.inicio < $2 AND ($3 = '' OR u.ruta = $3) AND ($4 = 0 OR u.idUsuario = $4)
+		ORDER BY u.inicio`
+	rows, err := db.QueryContext(ctx, query, desde, hasta, ruta, idUsuario)
+	if err != nil {
+		return nil, fmt.Errorf("error querying API usage: %w", err)
+	}
+	defer rows.Close()
+
+	usos := []models.UsoAPI{}
+	for rows.Next() {
+		var u models.UsoAPI
+		var id int
+		if err := rows.Scan(&u.Inicio, &u.Metodo, &u.Ruta, &id, &u.Email, &u.IP, &u.Peticiones, &u.Errores, &u.DuracionMs); err != nil {
+			return nil, fmt.Errorf("error scanning API usage: %w", err)
+		}
+		if id != 0 {
+			u.IDUsuario = &id
+		}
+		usos = append(usos, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating API usage: %w", err)
+	}
+	return usos, nil
+}
+
+// DeleteUsoAPIAntesDe removes the usage of the hours before antes and returns how many rows were
+// removed.
+func DeleteUsoAPIAntesDe(ctx context.Context, db *sql.DB, antes time.Time) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM uso_api WHERE inicio < $1`, antes)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting old API usage: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -1,15 +1,17 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // CreateUsuario inserts a new user into the database after hashing the password.
-func CreateUsuario(db *sql.DB, u *models.Usuario) error {
+func CreateUsuario(ctx context.Context, db *sql.DB, u *models.Usuario) error {
 	// Hash the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -17,8 +19,8 @@ func CreateUsuario(db *sql.DB, u *models.Usuario) error {
 	}
 
 	// Store the hashed password
-	query := `INSERT INTO usuario (email, password) VALUES ($1, $2) RETURNING idusuario, created_at, updated_at`
-	err = db.QueryRow(query, u.Email, string(hashedPassword)).Scan(&u.ID, &u.CreatedAt, &u.UpdatedAt)
+	query := `INSERT INTO usuario (email, password) VALUES ($1, $2) RETURNING idusuario, rol, created_at, updated_at`
+	err = db.QueryRowContext(ctx, query, u.Email, string(hashedPassword)).Scan(&u.ID, &u.Rol, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		// Consider checking for unique constraint violation on email
 		return fmt.Errorf("error inserting user: %w", err)
@@ -30,11 +32,11 @@ func CreateUsuario(db *sql.DB, u *models.Usuario) error {
 }
 
 // GetUsuarioByEmail retrieves a user by their email address.
-func GetUsuarioByEmail(db *sql.DB, email string) (*models.Usuario, error) {
+func GetUsuarioByEmail(ctx context.Context, db *sql.DB, email string) (*models.Usuario, error) {
 	var u models.Usuario
 	// Select all necessary fields, including the password hash
-	query := `SELECT idusuario, email, password, created_at, updated_at FROM usuario WHERE email = $1`
-	err := db.QueryRow(query, email).Scan(&u.ID, &u.Email, &u.Password, &u.CreatedAt, &u.UpdatedAt)
+	query := `SELECT idusuario, email, password, rol, created_at, updated_at FROM usuario WHERE email = $1`
+	err := db.QueryRowContext(ctx, query, email).Scan(&u.ID, &u.Email, &u.Password, &u.Rol, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // User not found, return nil error and nil user
@@ -49,3 +51,70 @@ func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil // Returns true if password matches hash
 }
+
+// SetUsuarioRol changes a user's application role.
+func SetUsuarioRol(ctx context.Context, db *sql.DB, id int, rol string) error {
+	query := `UPDATE usuario SET rol = $2 WHERE idusuario = $1`
+	if _, err := db.ExecContext(ctx, query, id, rol); err != nil {
+		return fmt.Errorf("error updating user role: %w", err)
+	}
+	return nil
+}
+
+// CreateRestablecimientoPassword stores a password reset link for a user, replacing any previous
+// one, and returns its expiry.
+func CreateRestablecimientoPassword(ctx context.Context, db *sql.DB, idUsuario int, tokenHash string, horas int) (time.Time, error) {
+	var expiraEn time.Time
+	err := WithTx(ctx, db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM restablecimiento_password WHERE idUsuario = $1`, idUsuario); err != nil {
+			return fmt.Errorf("error deleting previous password resets: %w", err)
+		}
+		query := `INSERT INTO restablecimiento_password (idUsuario, tokenHash, expiraEn)
+			VALUES ($1, $2, CURRENT_TIMESTAMP + make_interval(hours => $3)) RETURNING expiraEn`
+		if err := tx.QueryRowContext(ctx, query, idUsuario, tokenHash, horas).Scan(&expiraEn); err != nil {
+			return fmt.Errorf("error inserting password reset: %w", err)
+		}
+		return nil
+	})
+	return expiraEn, err
+}
+
+// RestablecerPassword sets the password of the user a reset link was sent to and consumes the
+// link. It returns false if the link does not exist or has expired.
+func RestablecerPassword(ctx context.Context, db *sql.DB, tokenHash, password string) (bool, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return false, fmt.Errorf("error hashing password: %w", err)
+	}
+	restablecida := false
+	err = WithTx(ctx, db, func(tx *sql.Tx) error {
+		var idUsuario int
+		err := tx.QueryRowContext(ctx, `SELECT idUsuario FROM restablecimiento_password
+			WHERE tokenHash = $1 AND expiraEn > CURRENT_TIMESTAMP FOR UPDATE`, tokenHash).Scan(&idUsuario)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error resolving password reset: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE usuario SET password = $2 WHERE idusuario = $1`, idUsuario, string(hashedPassword)); err != nil {
+			return fmt.Errorf("error updating user password: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM restablecimiento_password WHERE idUsuario = $1`, idUsuario); err != nil {
+			return fmt.Errorf("error deleting used password reset: %w", err)
+		}
+		restablecida = true
+		return nil
+	})
+	return restablecida, err
+}
+
+// DeleteRestablecimientosExpirados removes the password reset links that have expired and returns
+// how many were removed.
+func DeleteRestablecimientosExpirados(ctx context.Context, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM restablecimiento_password WHERE expiraEn <= CURRENT_TIMESTAMP`)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired password resets: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// CreateVerificacionEmail stores a confirmation link for the investigator's current email, replacing
+// any previous one, and returns its expiry.
+func CreateVerificacionEmail(ctx context.Context, db *sql.DB, idInvestigador int, email, tokenHash string, horas int) (time.Time, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM verificacion_email WHERE idInvestigador = $1`, idInvestigador); err != nil {
+		return time.Time{}, fmt.Errorf("error deleting previous email verifications: %w", err)
+	}
+	var expiraEn time.Time
+	query := `INSERT INTO verificacion_email (idInvestigador, email, tokenHash, expiraEn)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(hours => $4)) RETURNING expiraEn`
+	if err := tx.QueryRowContext(ctx, query, idInvestigador, email, tokenHash, horas).Scan(&expiraEn); err != nil {
+		return time.Time{}, fmt.Errorf("error inserting email verification: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return time.Time{}, fmt.Errorf("error committing email verification: %w", err)
+	}
+	return expiraEn, nil
+}
+
+// ConfirmarEmailInvestigador marks the email a confirmation link was sent to as verified and
+// consumes the link. It returns (nil, nil) if the link does not exist, has expired or the
+// investigator's email changed since it was sent.
+func ConfirmarEmailInvestigador(ctx context.Context, db *sql.DB, tokenHash string) (*models.Investigador, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after a successful commit
+
+	var idInvestigador int
+	var email string
+	err = tx.QueryRowContext(ctx, `SELECT idInvestigador, email FROM verificacion_email
+		WHERE tokenHash = $1 AND expiraEn > CURRENT_TIMESTAMP FOR UPDATE`, tokenHash).Scan(&idInvestigador, &email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error resolving email verification: %w", err)
+	}
+
+	var inv models.Investigador
+	query := `UPDATE investigador SET emailVerificado = TRUE
+		WHERE idInvestigador = $1 AND lower(email) = lower($2)
+		RETURNING ` + investigadorColumns
+	if err := tx.QueryRowContext(ctx, query, idInvestigador, email).Scan(investigadorScanFields(&inv)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error verifying investigator email: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM verificacion_email WHERE idInvestigador = $1`, idInvestigador); err != nil {
+		return nil, fmt.Errorf("error deleting used email verification: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing email verification: %w", err)
+	}
+	return &inv, nil
+}
+
+// DeleteVerificacionesExpiradas removes the confirmation links that have expired and returns how
+// many were removed.
+func DeleteVerificacionesExpiradas(ctx context.Context, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, `DELETE FROM verificacion_email WHERE expiraEn <= CURRENT_TIMESTAMP`)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired email verifications: %w", err)
+	}
+	return res.RowsAffected()
+}
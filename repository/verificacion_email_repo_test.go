@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database/databasetest"
+)
+
+func TestVerificacionEmail(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		ctx := context.Background()
+		const idMaria = 3 // Email not verified
+
+		antes := time.Now()
+		expiraEn, err := CreateVerificacionEmail(ctx, db, idMaria, "maria.condori@example.org", "hash-del-token", 48)
+		if err != nil {
+			t.Fatalf("CreateVerificacionEmail: %v", err)
+		}
+		if d := expiraEn.Sub(antes); d < 47*time.Hour || d > 49*time.Hour {
+			t.Errorf("CreateVerificacionEmail expiry in %v; want 48h", d)
+		}
+
+		if inv, err := ConfirmarEmailInvestigador(ctx, db, "otro-hash"); err != nil || inv != nil {
+			t.Errorf("ConfirmarEmailInvestigador with an unknown token = %v, %v; want nil, nil", inv, err)
+		}
+		inv, err := ConfirmarEmailInvestigador(ctx, db, "hash-del-token")
+		if err != nil {
+			t.Fatalf("ConfirmarEmailInvestigador: %v", err)
+		}
+		if inv == nil || inv.ID != idMaria || !inv.EmailVerificado {
+			t.Errorf("ConfirmarEmailInvestigador = %+v; want investigator %d with the email verified", inv, idMaria)
+		}
+		if inv, err := ConfirmarEmailInvestigador(ctx, db, "hash-del-token"); err != nil || inv != nil {
+			t.Errorf("ConfirmarEmailInvestigador of a used token = %v, %v; want nil, nil", inv, err)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/gorilla/mux"
+)
+
+// candidateMethods are the methods probed to build the Allow header.
+var candidateMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// allowedMethods returns the methods the router accepts for the request's path (plus OPTIONS),
+// or nil if no route matches the path at all.
+func allowedMethods(router *mux.Router, r *http.Request) []string {
+	var allowed []string
+	for _, method := range candidateMethods {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+		var match mux.RouteMatch
+		if router.Match(probe, &match) && match.MatchErr == nil {
+			allowed = append(allowed, method)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	return append(allowed, http.MethodOptions)
+}
+
+// notFoundHandler returns the JSON error envelope for unknown paths. A path that exists with
+// other methods is reported as 405, in case mux did not detect the method mismatch itself.
+func notFoundHandler(router *mux.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if allowed := allowedMethods(router, r); allowed != nil {
+			respondMethodNotAllowed(w, r, allowed)
+			return
+		}
+		utils.RespondErrorCode(w, "recurso_no_encontrado", http.StatusNotFound, r.URL.Path)
+	}
+}
+
+// respondMethodNotAllowed writes 405 with the JSON error envelope and an Allow header.
+func respondMethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed []string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	utils.RespondErrorCode(w, "metodo_no_permitido", http.StatusMethodNotAllowed, r.Method, strings.Join(allowed, ", "))
+}
+
+// methodNotAllowedHandler returns 405 with the JSON error envelope and an Allow header. The
+// OPTIONS catch-all matches every path, so mux reports a method mismatch even for unknown
+// paths; those get a 404 instead.
+func methodNotAllowedHandler(router *mux.Router) http.HandlerFunc {
+	notFound := notFoundHandler(router)
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed := allowedMethods(router, r)
+		if allowed == nil {
+			notFound(w, r)
+			return
+		}
+		respondMethodNotAllowed(w, r, allowed)
+	}
+}
+
+// optionsHandler answers OPTIONS for every route with 204 and an Allow header (CORS preflight
+// requests are answered earlier by the CORS layer), or 404 if the path does not exist.
+func optionsHandler(router *mux.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed := allowedMethods(router, r)
+		if allowed == nil {
+			utils.RespondErrorCode(w, "recurso_no_encontrado", http.StatusNotFound, r.URL.Path)
+			return
+		}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -4,57 +4,318 @@ import (
 	"database/sql"
 	"net/http"
 
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/analytics"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/controllers"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/telemetry"
 	"github.com/gorilla/mux"
 )
 
-// SetupRoutes configures the application routes.
+// Route is one entry of the API's authorization matrix: a path and method, the access level
+// required to call it and its handler.
+type Route struct {
+	Method  string
+	Path    string
+	Access  middleware.Access
+	Handler http.HandlerFunc
+}
+
+// Niveles de acceso abreviados para que la tabla se lea en columnas.
+const (
+	public = middleware.Public
+	authn  = middleware.Authenticated
+	admin  = middleware.Admin
+)
+
+// Routes returns the route table. It is the single place where the security posture of the API
+// is declared: every route lists the access level it requires, and SetupRoutes enforces it.
+// Literal paths must come before parameterized ones that could capture them.
+func Routes(db *sql.DB) []Route {
+	return []Route{
+		// --- Build information ---
+		{"GET", "/version", public, controllers.VersionHandler},
+
+		// --- JSON Schemas de los eventos de webhook y los modelos de la API ---
+		{"GET", "/schemas", public, controllers.GetSchemasHandler},
+		{"GET", "/schemas/{nombre:[a-z0-9_-]+}.json", public, controllers.GetSchemaHandler},
+
+		// --- Authentication ---
+		{"POST", "/register", public, controllers.RegisterHandler(db)},
+		{"POST", "/login", public, controllers.LoginHandler(db)},
+		{"POST", "/password/forgot", public, controllers.SolicitarRestablecimientoPasswordHandler(db)},
+		{"POST", "/password/reset", public, controllers.RestablecerPasswordHandler(db)},
+
+		// --- Public group registration intake (moderated) ---
+		{"POST", "/solicitudes-grupo", public, controllers.CreateSolicitudGrupoHandler(db)},
+
+		// --- Investigadores ---
+		{"GET", "/investigadores", public, controllers.GetInvestigadoresHandler(db)},
+		{"GET", "/investigadores/all", public, controllers.GetAllInvestigadoresNoPaginationHandler(db)},
+		{"GET", "/investigadores/export", authn, controllers.ExportInvestigadoresHandler(db)},
+		{"GET", "/investigadores/{id}", public, controllers.GetInvestigadorHandler(db)},
+		{"GET", "/investigadores/{idInvestigador}/grupos", public, controllers.GetGruposByInvestigadorHandler(db)},
+		{"GET", "/investigadores/{id}/detalles", public, controllers.GetDetallesByInvestigadorHandler(db)},
+		{"POST", "/investigadores", authn, controllers.CreateInvestigadorHandler(db)},
+		{"PUT", "/investigadores/{id}", authn, controllers.UpdateInvestigadorHandler(db)},
+		{"DELETE", "/investigadores/{id}", authn, controllers.DeleteInvestigadorHandler(db)},
+		{"POST", "/investigadores/{id}/restore", authn, controllers.RestoreInvestigadorHandler(db)},
+		{"POST", "/investigadores/{id:[0-9]+}/verificar-email", authn, controllers.SolicitarVerificacionEmailHandler(db)},
+		{"GET", "/investigadores/{id:[0-9]+}/cti-vitae", authn, controllers.GetCtiVitaeInvestigadorHandler(db)},
+		{"PUT", "/investigadores/{id:[0-9]+}/cti-vitae", authn, controllers.SetCtiVitaeInvestigadorHandler(db)},
+		{"DELETE", "/investigadores/{id:[0-9]+}/cti-vitae", authn, controllers.DeleteCtiVitaeInvestigadorHandler(db)},
+		{"POST", "/investigadores/{id:[0-9]+}/cti-vitae/sync", authn, controllers.SyncCtiVitaeInvestigadorHandler(db)},
+		{"GET", "/verificacion-email/{token}", public, controllers.ConfirmarEmailHandler(db)},
+
+		// --- Grupos ---
+		{"GET", "/grupos", public, controllers.GetGruposHandler(db)},
+		{"GET", "/grupos/with-details", public, controllers.GetAllGruposWithDetailsHandler(db)},
+		{"GET", "/grupos/stats", public, controllers.GetGruposStatsHandler(db)},
+		{"GET", "/estadisticas/por-facultad", public, controllers.GetEstadisticasPorFacultadHandler(db)},
+		{"GET", "/estadisticas/historial", public, controllers.GetEstadisticasHistorialHandler(db)},
+		{"GET", "/reportes/grupos", public, controllers.GetReporteGruposHandler(db)},
+		{"GET", "/grupos/duplicates", authn, controllers.GetGrupoDuplicadosHandler(db)},
+		{"GET", "/grupos/{id:[0-9]+}", public, controllers.GetGrupoHandler(db)},
+		{"GET", "/grupos/{id:[0-9]+}/details", public, controllers.GetGrupoDetailsHandler(db)},
+		{"GET", "/grupos/{id:[0-9]+}/report", public, controllers.GetGrupoReportHandler(db)},
+		{"GET", "/grupos/{id:[0-9]+}/subgrupos", public, controllers.GetSubgruposHandler(db)},
+		{"GET", "/grupos/{id:[0-9]+}/relacionados", public, controllers.GetGruposRelacionadosHandler(db)},
+		{"POST", "/grupos", authn, controllers.CreateGrupoHandler(db)}, // Handles file upload
+		{"POST", "/grupos/with-details", authn, controllers.CreateGrupoWithDetailsHandler(db)},
+		{"PUT", "/grupos/{id:[0-9]+}/with-details", authn, controllers.UpdateGrupoWithDetailsHandler(db)},
+		{"PUT", "/grupos/{id}", authn, controllers.UpdateGrupoHandler(db)},    // Handles file upload
+		{"DELETE", "/grupos/{id}", authn, controllers.DeleteGrupoHandler(db)}, // Soft delete
+		{"POST", "/grupos/{id}/restore", authn, controllers.RestoreGrupoHandler(db)},
+		{"POST", "/grupos/{id}/estado", authn, controllers.CambiarEstadoGrupoHandler(db)},
+		{"PUT", "/grupos/{id:[0-9]+}/padre", authn, controllers.SetGrupoPadreHandler(db)},
+
+		// Integrantes del grupo por clave natural (grupo + investigador)
+		{"POST", "/grupos/{id:[0-9]+}/investigadores", authn, controllers.AddIntegranteHandler(db)},
+		{"PUT", "/grupos/{id:[0-9]+}/investigadores/{invId:[0-9]+}", authn, controllers.UpdateIntegranteHandler(db)},
+		{"DELETE", "/grupos/{id:[0-9]+}/investigadores/{invId:[0-9]+}", authn, controllers.RemoveIntegranteHandler(db)},
+		{"POST", "/grupos/{id:[0-9]+}/coordinador", authn, controllers.SetCoordinadorHandler(db)},
+
+		// Adjuntos del grupo y enlaces temporales para compartir los no públicos
+		{"GET", "/grupos/{id:[0-9]+}/archivos", public, controllers.GetGrupoArchivosHandler(db)},
+		{"POST", "/grupos/{id}/archivos", authn, controllers.CreateGrupoArchivoHandler(db)}, // Handles file upload
+		{"POST", "/grupos/{id}/archivos/{fid}/share", authn, controllers.CreateEnlaceCompartidoHandler(db)},
+		{"GET", "/grupos/{id:[0-9]+}/resoluciones", public, controllers.GetResolucionesGrupoHandler(db)},
+		{"POST", "/grupos/{id:[0-9]+}/resoluciones", authn, controllers.CreateResolucionGrupoHandler(db)}, // Handles file upload
+		{"DELETE", "/grupos/{id:[0-9]+}/resoluciones/{rid:[0-9]+}", authn, controllers.DeleteResolucionGrupoHandler(db)},
+		{"GET", "/compartido/{token}", public, controllers.GetArchivoCompartidoHandler(db)},
+		{"GET", "/files/{id:.+}", public, controllers.GetFileHandler(db)}, // Access checked per file
+
+		// Comentarios internos de un grupo (retroalimentación del comité evaluador, notas)
+		{"GET", "/grupos/{id:[0-9]+}/comentarios", authn, controllers.GetComentariosGrupoHandler(db)},
+		{"POST", "/grupos/{id:[0-9]+}/comentarios", authn, controllers.CreateComentarioGrupoHandler(db)},
+		{"DELETE", "/grupos/{id:[0-9]+}/comentarios/{cid:[0-9]+}", authn, controllers.DeleteComentarioGrupoHandler(db)},
+
+		// Grupos favoritos del usuario del token
+		{"GET", "/me/favoritos", authn, controllers.GetFavoritosHandler(db)},
+		{"POST", "/me/favoritos/grupos/{id:[0-9]+}", authn, controllers.AddFavoritoHandler(db)},
+		{"DELETE", "/me/favoritos/grupos/{id:[0-9]+}", authn, controllers.RemoveFavoritoHandler(db)},
+
+		// Búsquedas guardadas del usuario del token, con aviso semanal opcional por email
+		{"GET", "/me/busquedas", authn, controllers.GetBusquedasHandler(db)},
+		{"POST", "/me/busquedas", authn, controllers.CreateBusquedaHandler(db)},
+		{"PUT", "/me/busquedas/{id:[0-9]+}", authn, controllers.UpdateBusquedaHandler(db)},
+		{"DELETE", "/me/busquedas/{id:[0-9]+}", authn, controllers.DeleteBusquedaHandler(db)},
+
+		// Renovación de la vigencia de los grupos: la solicita el grupo y la revisa un administrador
+		{"GET", "/grupos/{id:[0-9]+}/renovaciones", authn, controllers.GetRenovacionesGrupoHandler(db)},
+		{"POST", "/grupos/{id:[0-9]+}/renovaciones", authn, controllers.SolicitarRenovacionHandler(db)},
+		{"GET", "/renovaciones", admin, controllers.GetRenovacionesHandler(db)},
+		{"POST", "/renovaciones/{id:[0-9]+}/aprobar", admin, controllers.AprobarRenovacionHandler(db)}, // Handles file upload
+		{"POST", "/renovaciones/{id:[0-9]+}/rechazar", admin, controllers.RechazarRenovacionHandler(db)},
+
+		// Convocatorias en las que participa el grupo
+		{"GET", "/grupos/{id:[0-9]+}/convocatorias", public, controllers.GetConvocatoriasGrupoHandler(db)},
+
+		// --- Convocatorias de registro y renovación ---
+		{"GET", "/convocatorias", public, controllers.GetConvocatoriasHandler(db)},
+		{"GET", "/convocatorias/{id:[0-9]+}", public, controllers.GetConvocatoriaHandler(db)},
+		{"POST", "/convocatorias", admin, controllers.CreateConvocatoriaHandler(db)},
+		{"PUT", "/convocatorias/{id:[0-9]+}", admin, controllers.UpdateConvocatoriaHandler(db)},
+		{"DELETE", "/convocatorias/{id:[0-9]+}", admin, controllers.DeleteConvocatoriaHandler(db)},
+		{"GET", "/convocatorias/{id:[0-9]+}/grupos", public, controllers.GetGruposConvocatoriaHandler(db)},
+		{"POST", "/convocatorias/{id:[0-9]+}/grupos", authn, controllers.AddGrupoConvocatoriaHandler(db)},
+		{"DELETE", "/convocatorias/{id:[0-9]+}/grupos/{idGrupo:[0-9]+}", authn, controllers.RemoveGrupoConvocatoriaHandler(db)},
+
+		// Postulaciones de los grupos: estado, documentos y tasas de cumplimiento
+		{"GET", "/convocatorias/{id:[0-9]+}/postulaciones", authn, controllers.GetPostulacionesConvocatoriaHandler(db)},
+		{"POST", "/convocatorias/{id:[0-9]+}/postulaciones", authn, controllers.CreatePostulacionHandler(db)},
+		{"GET", "/convocatorias/{id:[0-9]+}/reporte", authn, controllers.GetReporteConvocatoriaHandler(db)},
+		{"GET", "/postulaciones/{id:[0-9]+}", authn, controllers.GetPostulacionHandler(db)},
+		{"PUT", "/postulaciones/{id:[0-9]+}/estado", authn, controllers.CambiarEstadoPostulacionHandler(db)},        // observado/aprobado: admin only
+		{"POST", "/postulaciones/{id:[0-9]+}/documentos", authn, controllers.CreateDocumentoPostulacionHandler(db)}, // Handles file upload
+		{"DELETE", "/postulaciones/{id:[0-9]+}/documentos/{did:[0-9]+}", authn, controllers.DeleteDocumentoPostulacionHandler(db)},
+
+		// --- Publicaciones de investigadores y grupos ---
+		{"GET", "/publicaciones", public, controllers.GetPublicacionesHandler(db)},
+		{"GET", "/publicaciones/{id:[0-9]+}", public, controllers.GetPublicacionHandler(db)},
+		{"POST", "/publicaciones", authn, controllers.CreatePublicacionHandler(db)},
+		{"PUT", "/publicaciones/{id:[0-9]+}", authn, controllers.UpdatePublicacionHandler(db)},
+		{"DELETE", "/publicaciones/{id:[0-9]+}", authn, controllers.DeletePublicacionHandler(db)},
+		{"POST", "/publicaciones/import", authn, controllers.ImportPublicacionesBibtexHandler(db)},
+		{"GET", "/grupos/{id:[0-9]+}/publicaciones.bib", public, controllers.ExportPublicacionesBibtexHandler(db)},
+
+		// --- Cosecha OAI-PMH de grupos y publicaciones (repositorio institucional, agregadores) ---
+		{"GET", "/oai", public, controllers.OAIHandler(db)},
+		{"POST", "/oai", public, controllers.OAIHandler(db)},
+
+		// --- Proyectos de investigación de los grupos ---
+		{"GET", "/proyectos", public, controllers.GetProyectosHandler(db)},
+		{"GET", "/proyectos/{id:[0-9]+}", public, controllers.GetProyectoHandler(db)},
+		{"POST", "/proyectos", authn, controllers.CreateProyectoHandler(db)},
+		{"PUT", "/proyectos/{id:[0-9]+}", authn, controllers.UpdateProyectoHandler(db)},
+		{"DELETE", "/proyectos/{id:[0-9]+}", authn, controllers.DeleteProyectoHandler(db)},
+		{"POST", "/proyectos/{id:[0-9]+}/archivos", authn, controllers.CreateProyectoArchivoHandler(db)}, // Handles file upload
+		{"DELETE", "/proyectos/{id:[0-9]+}/archivos/{aid:[0-9]+}", authn, controllers.DeleteProyectoArchivoHandler(db)},
+
+		// --- Facultades y escuelas profesionales ---
+		{"GET", "/facultades", public, controllers.GetFacultadesHandler(db)},
+		{"GET", "/facultades/{id:[0-9]+}", public, controllers.GetFacultadHandler(db)},
+		{"POST", "/facultades", admin, controllers.CreateFacultadHandler(db)},
+		{"PUT", "/facultades/{id:[0-9]+}", admin, controllers.UpdateFacultadHandler(db)},
+		{"DELETE", "/facultades/{id:[0-9]+}", admin, controllers.DeleteFacultadHandler(db)},
+		{"POST", "/facultades/{id:[0-9]+}/escuelas", admin, controllers.CreateEscuelaHandler(db)},
+		{"GET", "/escuelas/{id:[0-9]+}", public, controllers.GetEscuelaHandler(db)},
+		{"PUT", "/escuelas/{id:[0-9]+}", admin, controllers.UpdateEscuelaHandler(db)},
+		{"DELETE", "/escuelas/{id:[0-9]+}", admin, controllers.DeleteEscuelaHandler(db)},
+
+		// --- Catálogos ---
+		{"GET", "/catalogos/tipos-investigacion", public, controllers.GetTiposInvestigacionHandler(db)},
+
+		// --- Feed de cambios para sincronizaciones incrementales (Zapier, Make, scripts) ---
+		{"GET", "/changes", authn, controllers.GetCambiosHandler(db)},
+
+		// --- Exportaciones asíncronas ---
+		{"POST", "/exports", authn, controllers.CreateExportHandler(db)},
+		{"GET", "/exports/{id:[0-9]+}", authn, controllers.GetExportHandler(db)},
+		{"GET", "/exports/{id:[0-9]+}/download", authn, controllers.DownloadExportHandler(db)},
+
+		// --- DetalleGrupoInvestigador ---
+		{"GET", "/detalles", public, controllers.GetAllDetallesGrupoInvestigadorHandler(db)},
+		{"GET", "/detalles/{id}", public, controllers.GetDetalleGrupoInvestigadorHandler(db)},
+		{"GET", "/grupos/{grupoID}/detalles", public, controllers.GetDetallesByGrupoHandler(db)},
+		{"POST", "/detalles", authn, controllers.CreateDetalleGrupoInvestigadorHandler(db)},
+		{"POST", "/detalles/bulk", authn, controllers.CreateDetallesBulkHandler(db)},
+		{"PUT", "/detalles/{id}", authn, controllers.UpdateDetalleGrupoInvestigadorHandler(db)},
+		{"DELETE", "/detalles/{id}", authn, controllers.DeleteDetalleGrupoInvestigadorHandler(db)},
+
+		// --- Admin: moderation queue for public group registration requests ---
+		{"GET", "/admin/solicitudes", admin, controllers.GetSolicitudesHandler(db)},
+		{"GET", "/admin/solicitudes/{id}", admin, controllers.GetSolicitudHandler(db)},
+		{"POST", "/admin/solicitudes/{id}/aprobar", admin, controllers.AprobarSolicitudHandler(db)},
+		{"POST", "/admin/solicitudes/{id}/rechazar", admin, controllers.RechazarSolicitudHandler(db)},
+
+		// --- Admin: approval of groups registered by non-admin users (see GET /grupos?estado=pendiente) ---
+		{"POST", "/admin/grupos/{id:[0-9]+}/aprobar", admin, controllers.AprobarGrupoHandler(db)},
+		{"POST", "/admin/grupos/{id:[0-9]+}/rechazar", admin, controllers.RechazarGrupoHandler(db)},
+
+		// --- Admin: diagnostics ---
+		{"GET", "/admin/support-bundle", admin, controllers.GetSupportBundleHandler(db)},
+		{"GET", "/admin/metrics", admin, controllers.GetMetricsHandler(db)},
+		{"GET", "/admin/usage", admin, controllers.GetUsageHandler(db)},
+		{"GET", "/admin/auditoria", admin, controllers.GetAuditLogsHandler(db)},
+		{"GET", "/admin/archivos-duplicados", admin, controllers.GetArchivosDuplicadosHandler(db)},
+		{"GET", "/admin/jobs", admin, controllers.GetJobsHandler(db)},
+
+		// --- Admin: copias de seguridad de la base de datos ---
+		{"POST", "/admin/backup", admin, controllers.CreateBackupHandler(db)},
+		{"GET", "/admin/backups", admin, controllers.GetBackupsHandler(db)},
+		{"GET", "/admin/backups/{id:[0-9]+}/download", admin, controllers.DownloadBackupHandler(db)},
+		{"POST", "/admin/backups/{id:[0-9]+}/restore", admin, controllers.RestoreBackupHandler(db)},
+		{"DELETE", "/admin/backups/{id:[0-9]+}", admin, controllers.DeleteBackupHandler(db)},
+
+		// --- Admin: volcado completo del directorio para análisis y migraciones ---
+		{"GET", "/admin/export/full", admin, controllers.GetExportFullHandler(db)},
+
+		// --- Admin: migración de archivos entre backends de almacenamiento ---
+		{"POST", "/admin/storage/migrate", admin, controllers.StartFileMigrationHandler(db)},
+		{"GET", "/admin/storage/migrate", admin, controllers.GetFileMigrationHandler},
+		{"GET", "/admin/storage/drive", admin, controllers.GetDriveStatusHandler},
+
+		// --- Admin: modo snapshot de los endpoints públicos ---
+		{"GET", "/admin/snapshot", admin, controllers.GetSnapshotStatusHandler},
+		{"POST", "/admin/snapshot/refresh", admin, controllers.RefreshSnapshotHandler},
+
+		// --- Admin: caché del directorio ---
+		{"GET", "/admin/cache", admin, controllers.GetCacheStatusHandler},
+
+		// --- Admin: plan de ejecución de la búsqueda de grupos ---
+		{"GET", "/admin/explain/grupos", admin, controllers.ExplainBusquedaGruposHandler(db)},
+	}
+}
+
+// sinSnapshot lists the public GET routes that must always run live: they have side effects
+// (audit trail, one-time tokens) or depend on the moment they are called.
+var sinSnapshot = map[string]bool{
+	"/version":                    true,
+	"/compartido/{token}":         true,
+	"/files/{id:.+}":              true,
+	"/verificacion-email/{token}": true,
+}
+
+// conArchivo lists the routes that receive multipart file uploads: their body can reach
+// MAX_UPLOAD_SIZE instead of MAX_BODY_SIZE. Keyed by method and path.
+var conArchivo = map[string]bool{
+	"POST /grupos":                               true,
+	"PUT /grupos/{id}":                           true,
+	"POST /grupos/{id}/archivos":                 true,
+	"POST /grupos/{id:[0-9]+}/resoluciones":      true,
+	"POST /postulaciones/{id:[0-9]+}/documentos": true,
+	"POST /proyectos/{id:[0-9]+}/archivos":       true,
+	"POST /publicaciones/import":                 true,
+	"POST /renovaciones/{id:[0-9]+}/aprobar":     true,
+}
+
+// SetupRoutes configures the application routes from the route table. For anonymous callers public
+// GET routes form the public tier (see middleware.PublicTier), with its own rate limit. In snapshot
+// mode (see controllers.StartPublicSnapshot) public GET routes are served from the snapshot. When the
+// directory cache is enabled (see controllers.InitCache) every successful write through an
+// authenticated route invalidates it. Every request is counted for GET /admin/usage (see package
+// analytics), including those rejected by the authorization.
 func SetupRoutes(db *sql.DB) *mux.Router {
 	r := mux.NewRouter()
+	// Name the request span after the matched route
+	r.Use(telemetry.RouteMiddleware)
+	// Request ID (X-Request-ID) and a logger carrying it for the handlers
+	r.Use(logging.Middleware)
+	// Language of the error messages, from Accept-Language
+	r.Use(middleware.Language)
+	// Attach claims from a Bearer token when present so public routes can offer admin-only options
+	r.Use(middleware.OptionalJWTMiddleware)
+	// Requests per minute by client IP (anonymous callers) or by user (authenticated ones)
+	r.Use(middleware.RateLimit(middleware.RateLimitsFromEnv()))
+
+	// JSON errors for unknown paths and wrong methods, and OPTIONS with Allow for every route
+	r.NotFoundHandler = middleware.Language(notFoundHandler(r))
+	r.MethodNotAllowedHandler = middleware.Language(methodNotAllowedHandler(r))
+	r.Methods("OPTIONS").HandlerFunc(optionsHandler(r))
 
-	// --- Authentication Routes (Public) ---
-	r.HandleFunc("/register", controllers.RegisterHandler(db)).Methods("POST")
-	r.HandleFunc("/login", controllers.LoginHandler(db)).Methods("POST")
-
-	// --- Public GET Routes (No Auth Required) ---
-	r.HandleFunc("/investigadores", controllers.GetInvestigadoresHandler(db)).Methods("GET")
-	r.HandleFunc("/investigadores/all", controllers.GetAllInvestigadoresNoPaginationHandler(db)).Methods("GET")
-	r.HandleFunc("/investigadores/{id}", controllers.GetInvestigadorHandler(db)).Methods("GET")
-	r.HandleFunc("/investigadores/{idInvestigador}/grupos", controllers.GetGruposByInvestigadorHandler(db)).Methods("GET")
-	r.HandleFunc("/grupos", controllers.GetGruposHandler(db)).Methods("GET")
-	r.HandleFunc("/grupos/{id}", controllers.GetGrupoHandler(db)).Methods("GET")
-	r.HandleFunc("/grupos/{id}/details", controllers.GetGrupoDetailsHandler(db)).Methods("GET")
-	r.HandleFunc("/grupos/with-details", controllers.GetAllGruposWithDetailsHandler(db)).Methods("GET")
-	r.HandleFunc("/detalles/{id}", controllers.GetDetalleGrupoInvestigadorHandler(db)).Methods("GET")
-	r.HandleFunc("/grupos/{grupoID}/detalles", controllers.GetDetallesByGrupoHandler(db)).Methods("GET")
-	r.HandleFunc("/detalles", controllers.GetAllDetallesGrupoInvestigadorHandler(db)).Methods("GET")
-
-	// Static file server (public)
-	fs := http.FileServer(http.Dir("./uploads/"))
-	r.PathPrefix("/uploads/").Handler(http.StripPrefix("/uploads/", fs))
-
-	// --- Protected Routes (Auth Required) ---
-
-	// Create a subrouter for authenticated routes
-	authRouter := r.PathPrefix("").Subrouter()
-	authRouter.Use(middleware.JWTMiddleware) // Apply JWT middleware to this subrouter
-
-	// Investigador (Create, Update, Delete)
-	authRouter.HandleFunc("/investigadores", controllers.CreateInvestigadorHandler(db)).Methods("POST")
-	authRouter.HandleFunc("/investigadores/{id}", controllers.UpdateInvestigadorHandler(db)).Methods("PUT")
-	authRouter.HandleFunc("/investigadores/{id}", controllers.DeleteInvestigadorHandler(db)).Methods("DELETE")
-
-	// Grupo (Create, Update, Delete, Create with Details)
-	authRouter.HandleFunc("/grupos", controllers.CreateGrupoHandler(db)).Methods("POST") // Handles file upload
-	authRouter.HandleFunc("/grupos/with-details", controllers.CreateGrupoWithDetailsHandler(db)).Methods("POST")
-	authRouter.HandleFunc("/grupos/{id}", controllers.UpdateGrupoHandler(db)).Methods("PUT") // Handles file upload
-	authRouter.HandleFunc("/grupos/{id}", controllers.DeleteGrupoHandler(db)).Methods("DELETE")
-
-	// DetalleGrupoInvestigador (Create, Update, Delete)
-	authRouter.HandleFunc("/detalles", controllers.CreateDetalleGrupoInvestigadorHandler(db)).Methods("POST")
-	authRouter.HandleFunc("/detalles/{id}", controllers.UpdateDetalleGrupoInvestigadorHandler(db)).Methods("PUT")
-	authRouter.HandleFunc("/detalles/{id}", controllers.DeleteDetalleGrupoInvestigadorHandler(db)).Methods("DELETE")
+	snap := controllers.PublicSnapshot()
+	cached := controllers.DirectorioCache() != nil
+	limites := middleware.BodyLimitsFromEnv()
+	maxAge := middleware.PublicCacheMaxAgeFromEnv()
+	for _, route := range Routes(db) {
+		var h http.Handler = route.Handler
+		if route.Access == public && route.Method == http.MethodGet {
+			h = middleware.PublicTier(maxAge, h)
+		}
+		if snap != nil && route.Access == public && route.Method == http.MethodGet && !sinSnapshot[route.Path] {
+			h = snap.Middleware(h)
+		}
+		if cached && route.Access != public && route.Method != http.MethodGet {
+			h = controllers.InvalidarCacheDirectorio(h)
+		}
+		limite := limites.Default
+		if conArchivo[route.Method+" "+route.Path] {
+			limite = limites.Upload
+		}
+		h = middleware.LimitBody(limite, h)
+		r.Handle(route.Path, analytics.Middleware(route.Method, route.Path, middleware.Authorize(route.Access, h))).Methods(route.Method)
+	}
 
 	return r
 }
@@ -0,0 +1,501 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/controllers"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database/databasetest"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/repository"
+)
+
+// The handlers are tested through the router, with its middlewares (authentication, access levels,
+// rate limit, errors), on a seeded database of each test's own, in PostgreSQL and in SQLite (see
+// databasetest.Run). Files go to the local storage backend in a temporary directory, as in demo mode.
+
+const (
+	adminEmail    = "admin@test.local"
+	adminPassword = "Admin123!"
+	userPassword  = "Secreta123!"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "apigrupos-test-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Setenv("JWT_SECRET", "test-secret")
+	os.Setenv("STORAGE_BACKEND", "local")
+	os.Setenv("LOCAL_STORAGE_DIR", dir)
+	if err := calendario.Configurar(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := controllers.InitStorage(context.Background()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code := databasetest.Main(m)
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// apiTest is the API served on a test database.
+type apiTest struct {
+	t   *testing.T
+	db  *sql.DB
+	srv *httptest.Server
+}
+
+func nuevaAPI(t *testing.T, db *sql.DB) *apiTest {
+	t.Helper()
+	srv := httptest.NewServer(SetupRoutes(db))
+	t.Cleanup(srv.Close)
+
+	admin := &models.Usuario{Email: adminEmail, Password: adminPassword}
+	if err := repository.CreateUsuario(context.Background(), db, admin); err != nil {
+		t.Fatalf("creating the administrator: %v", err)
+	}
+	if err := repository.SetUsuarioRol(context.Background(), db, admin.ID, models.RolAdmin); err != nil {
+		t.Fatalf("creating the administrator: %v", err)
+	}
+	return &apiTest{t: t, db: db, srv: srv}
+}
+
+// respuesta is a response read whole.
+type respuesta struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// decodificar decodes the body into v, failing the test if it is not JSON.
+func (r respuesta) decodificar(t *testing.T, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.body, v); err != nil {
+		t.Fatalf("decoding %s: %v", r.body, err)
+	}
+}
+
+// hacer sends a request, with the token when it is not empty.
+func (a *apiTest) hacer(method, path, token, contentType string, body io.Reader) respuesta {
+	a.t.Helper()
+	req, err := http.NewRequest(method, a.srv.URL+path, body)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := a.srv.Client().Do(req)
+	if err != nil {
+		a.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		a.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	return respuesta{status: res.StatusCode, header: res.Header, body: b}
+}
+
+// json sends v as a JSON body (none if v is nil).
+func (a *apiTest) json(method, path, token string, v interface{}) respuesta {
+	a.t.Helper()
+	if v == nil {
+		return a.hacer(method, path, token, "", nil)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return a.hacer(method, path, token, "application/json", bytes.NewReader(b))
+}
+
+// login returns the token of a user, failing the test if the credentials are rejected.
+func (a *apiTest) login(email, password string) string {
+	a.t.Helper()
+	res := a.json(http.MethodPost, "/login", "", map[string]string{"email": email, "password": password})
+	if res.status != http.StatusOK {
+		a.t.Fatalf("POST /login = %d %s", res.status, res.body)
+	}
+	var out struct {
+		Token string `json:"token"`
+	}
+	res.decodificar(a.t, &out)
+	return out.Token
+}
+
+// registrar registers a user without the admin role and returns their token.
+func (a *apiTest) registrar(email string) string {
+	a.t.Helper()
+	res := a.json(http.MethodPost, "/register", "", map[string]string{"email": email, "password": userPassword})
+	if res.status != http.StatusCreated {
+		a.t.Fatalf("POST /register = %d %s", res.status, res.body)
+	}
+	return a.login(email, userPassword)
+}
+
+// formularioGrupo returns the multipart form of POST /grupos, with a file named archivo holding
+// contenido unless it is nil.
+func formularioGrupo(t *testing.T, nombre, archivo string, contenido []byte) (string, io.Reader) {
+	t.Helper()
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	campos := map[string]string{
+		"nombre":             nombre,
+		"numeroResolucion":   "R-900-2026-VRI",
+		"lineaInvestigacion": "Matemática Aplicada",
+		"tipoInvestigacion":  "Básica",
+		"fechaRegistro":      "2026-02-10",
+	}
+	for k, v := range campos {
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if contenido != nil {
+		fw, err := mw.CreateFormFile("archivo", archivo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(contenido)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return mw.FormDataContentType(), &b
+}
+
+// errorBody is the error schema of every response (see utils.ErrorResponse).
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details []struct {
+		Campo  string `json:"campo"`
+		Codigo string `json:"codigo"`
+	} `json:"details"`
+}
+
+func TestAuthFlow(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		a := nuevaAPI(t, db)
+
+		if res := a.json(http.MethodPost, "/register", "", map[string]string{"email": "ana@test.local", "password": userPassword}); res.status != http.StatusCreated {
+			t.Fatalf("POST /register = %d %s", res.status, res.body)
+		}
+		if res := a.json(http.MethodPost, "/register", "", map[string]string{"email": "ana@test.local", "password": userPassword}); res.status != http.StatusConflict {
+			t.Errorf("POST /register with a registered email = %d; want 409", res.status)
+		}
+
+		res := a.json(http.MethodPost, "/login", "", map[string]string{"email": "ana@test.local", "password": "incorrecta"})
+		if res.status != http.StatusUnauthorized {
+			t.Errorf("POST /login with a wrong password = %d; want 401", res.status)
+		}
+		var e errorBody
+		res.decodificar(t, &e)
+		if e.Code == "" || e.Message == "" {
+			t.Errorf("error body = %s; want code and message", res.body)
+		}
+		if res.header.Get("X-API-Version") == "" {
+			t.Error("error response without X-API-Version")
+		}
+
+		token := a.login("ana@test.local", userPassword)
+		tests := []struct {
+			name   string
+			method string
+			path   string
+			token  string
+			want   int
+		}{
+			{"public route, anonymous", http.MethodGet, "/grupos", "", http.StatusOK},
+			{"authenticated route, anonymous", http.MethodGet, "/grupos/duplicates", "", http.StatusUnauthorized},
+			{"authenticated route, invalid token", http.MethodGet, "/grupos/duplicates", "no-es-un-token", http.StatusUnauthorized},
+			{"authenticated route, user", http.MethodGet, "/grupos/duplicates", token, http.StatusOK},
+			{"file check, anonymous", http.MethodGet, "/grupos/1?verificarArchivo=true", "", http.StatusUnauthorized},
+			{"file check, user", http.MethodGet, "/grupos/1?verificarArchivo=true", token, http.StatusOK},
+			{"admin route, user", http.MethodGet, "/admin/solicitudes", token, http.StatusForbidden},
+			{"admin route, admin", http.MethodGet, "/admin/solicitudes", a.login(adminEmail, adminPassword), http.StatusOK},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if res := a.json(tt.method, tt.path, tt.token, nil); res.status != tt.want {
+					t.Errorf("%s %s = %d %s; want %d", tt.method, tt.path, res.status, res.body, tt.want)
+				}
+			})
+		}
+	})
+}
+
+func TestGrupoCRUD(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		a := nuevaAPI(t, db)
+		admin := a.login(adminEmail, adminPassword)
+
+		// Created by an admin with a PDF, which goes to the local backend
+		ct, body := formularioGrupo(t, "Grupo de Topología Computacional", "resolucion.pdf", []byte("%PDF-1.4\n%test\n"))
+		res := a.hacer(http.MethodPost, "/grupos", admin, ct, body)
+		if res.status != http.StatusCreated {
+			t.Fatalf("POST /grupos = %d %s", res.status, res.body)
+		}
+		var creado models.Grupo
+		res.decodificar(t, &creado)
+		if creado.ID == 0 || creado.Estado != models.EstadoGrupoActivo || creado.Archivo == nil || !strings.Contains(*creado.Archivo, "/files/") {
+			t.Fatalf("POST /grupos = %s; want an active group with the link of its file", res.body)
+		}
+		ruta := fmt.Sprintf("/grupos/%d", creado.ID)
+
+		res = a.json(http.MethodGet, ruta, "", nil)
+		if res.status != http.StatusOK {
+			t.Fatalf("GET %s = %d %s", ruta, res.status, res.body)
+		}
+		var leido models.Grupo
+		res.decodificar(t, &leido)
+		if leido.Nombre != creado.Nombre || leido.FechaRegistro.String() != "2026-02-10" {
+			t.Errorf("GET %s = %s; want the created group", ruta, res.body)
+		}
+
+		ct, body = formularioGrupo(t, "Grupo de Topología Aplicada", "", nil)
+		if res := a.hacer(http.MethodPut, ruta, admin, ct, body); res.status != http.StatusOK {
+			t.Fatalf("PUT %s = %d %s", ruta, res.status, res.body)
+		}
+		res = a.json(http.MethodGet, ruta, "", nil)
+		res.decodificar(t, &leido)
+		if leido.Nombre != "Grupo de Topología Aplicada" {
+			t.Errorf("nombre after PUT = %q", leido.Nombre)
+		}
+
+		// Soft delete: gone for everyone, except for an admin who asks for deleted groups
+		if res := a.json(http.MethodDelete, ruta, admin, nil); res.status != http.StatusNoContent {
+			t.Fatalf("DELETE %s = %d %s", ruta, res.status, res.body)
+		}
+		res = a.json(http.MethodGet, ruta, "", nil)
+		if res.status != http.StatusNotFound {
+			t.Errorf("GET %s of a deleted group = %d; want 404", ruta, res.status)
+		}
+		var e errorBody
+		res.decodificar(t, &e)
+		if e.Code != "not_found" {
+			t.Errorf("error code = %q; want not_found", e.Code)
+		}
+		if res := a.json(http.MethodGet, ruta+"?includeDeleted=true", admin, nil); res.status != http.StatusOK {
+			t.Errorf("GET %s?includeDeleted=true as admin = %d; want 200", ruta, res.status)
+		}
+		if res := a.json(http.MethodPost, ruta+"/restore", admin, nil); res.status >= 300 {
+			t.Fatalf("POST %s/restore = %d %s", ruta, res.status, res.body)
+		}
+		if res := a.json(http.MethodGet, ruta, "", nil); res.status != http.StatusOK {
+			t.Errorf("GET %s after the restore = %d; want 200", ruta, res.status)
+		}
+	})
+}
+
+func TestCreateGrupoRejectsHTMLFile(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		a := nuevaAPI(t, db)
+
+		// HTML served from the API's origin would run there (stored XSS), whatever its extension
+		for _, nombre := range []string{"pagina.html", "resolucion.pdf", "archivo"} {
+			t.Run(nombre, func(t *testing.T) {
+				ct, body := formularioGrupo(t, "Grupo "+nombre, nombre, []byte("<html><script>alert(1)</script></html>"))
+				res := a.hacer(http.MethodPost, "/grupos", a.login(adminEmail, adminPassword), ct, body)
+				if res.status != http.StatusUnprocessableEntity {
+					t.Fatalf("POST /grupos with HTML as %s = %d %s; want 422", nombre, res.status, res.body)
+				}
+				var e errorBody
+				res.decodificar(t, &e)
+				if len(e.Details) != 1 || e.Details[0].Campo != "archivo" {
+					t.Errorf("error details = %s; want one for archivo", res.body)
+				}
+			})
+		}
+	})
+}
+
+// TestGrupoPendienteVisibility checks that a group awaiting approval, and its memberships, are
+// hidden from anonymous callers everywhere they could show up.
+func TestGrupoPendienteVisibility(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		a := nuevaAPI(t, db)
+		usuario := a.registrar("luis@test.local")
+
+		// Registered by a user without the admin role, so it awaits approval
+		res := a.json(http.MethodPost, "/grupos/with-details", usuario, map[string]interface{}{
+			"grupo": map[string]string{
+				"nombre":             "Grupo Pendiente de Aprobación",
+				"numeroResolucion":   "RS-77",
+				"lineaInvestigacion": "Ciencias de la Salud",
+				"tipoInvestigacion":  "Aplicada",
+				"fechaRegistro":      "2026-01-05",
+			},
+			"investigadores": []map[string]interface{}{{"idInvestigador": 1, "tipoRelacion": "Coordinador"}},
+		})
+		if res.status != http.StatusCreated {
+			t.Fatalf("POST /grupos/with-details = %d %s", res.status, res.body)
+		}
+		var creado struct {
+			ID     int    `json:"idGrupo"`
+			Estado string `json:"estado"`
+		}
+		res.decodificar(t, &creado)
+		if creado.Estado != models.EstadoGrupoPendiente {
+			t.Fatalf("estado = %q; want %q", creado.Estado, models.EstadoGrupoPendiente)
+		}
+
+		incluye := func(t *testing.T, res respuesta) bool {
+			t.Helper()
+			return bytes.Contains(res.body, []byte(fmt.Sprintf(`"idGrupo":%d,`, creado.ID)))
+		}
+		tests := []struct {
+			path string
+			// For anonymous callers: 404, or 200 without the group
+			anonimo404 bool
+		}{
+			{path: fmt.Sprintf("/grupos/%d", creado.ID), anonimo404: true},
+			{path: fmt.Sprintf("/grupos/%d/details", creado.ID), anonimo404: true},
+			{path: fmt.Sprintf("/grupos/%d/detalles", creado.ID), anonimo404: true},
+			{path: "/grupos"},
+			{path: "/detalles?limit=50"},
+			{path: "/investigadores/1/detalles"},
+			{path: "/grupos/1/relacionados"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.path, func(t *testing.T) {
+				res := a.json(http.MethodGet, tt.path, "", nil)
+				switch {
+				case tt.anonimo404 && res.status != http.StatusNotFound:
+					t.Errorf("anonymous GET %s = %d %s; want 404", tt.path, res.status, res.body)
+				case !tt.anonimo404 && (res.status != http.StatusOK || incluye(t, res)):
+					t.Errorf("anonymous GET %s = %d %s; want 200 without group %d", tt.path, res.status, res.body, creado.ID)
+				}
+				if tt.path == "/grupos" || tt.path == "/grupos/1/relacionados" {
+					return // Listed only with ?estado=pendiente, and never related
+				}
+				res = a.json(http.MethodGet, tt.path, usuario, nil)
+				if res.status != http.StatusOK || !incluye(t, res) {
+					t.Errorf("authenticated GET %s = %d %s; want 200 with group %d", tt.path, res.status, res.body, creado.ID)
+				}
+			})
+		}
+	})
+}
+
+func TestDeletedGrupoDetalles(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		a := nuevaAPI(t, db)
+		admin := a.login(adminEmail, adminPassword)
+
+		if res := a.json(http.MethodDelete, "/grupos/1", admin, nil); res.status != http.StatusNoContent {
+			t.Fatalf("DELETE /grupos/1 = %d %s", res.status, res.body)
+		}
+		for _, token := range []string{"", admin} {
+			if res := a.json(http.MethodGet, "/grupos/1/detalles", token, nil); res.status != http.StatusNotFound {
+				t.Errorf("GET /grupos/1/detalles of a deleted group = %d; want 404", res.status)
+			}
+			res := a.json(http.MethodGet, "/detalles?limit=50", token, nil)
+			var page struct {
+				Data []models.DetalleGrupoInvestigador `json:"data"`
+			}
+			res.decodificar(t, &page)
+			for _, d := range page.Data {
+				if d.IDGrupo == 1 {
+					t.Errorf("GET /detalles lists membership %d of the deleted group", d.ID)
+				}
+			}
+			if len(page.Data) != 4 {
+				t.Errorf("GET /detalles = %d memberships; want the 4 of the other groups", len(page.Data))
+			}
+		}
+	})
+}
+
+func TestCreateSolicitudGrupoWithoutCaptcha(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		a := nuevaAPI(t, db)
+		t.Setenv("CAPTCHA_SECRET", "")
+		t.Setenv("CAPTCHA_DISABLED", "")
+
+		res := a.json(http.MethodPost, "/solicitudes-grupo", "", map[string]string{"nombre": "Grupo", "captchaToken": "x"})
+		if res.status != http.StatusServiceUnavailable {
+			t.Errorf("POST /solicitudes-grupo without CAPTCHA_SECRET = %d %s; want 503", res.status, res.body)
+		}
+	})
+}
+
+func TestExplainSearchGrupos(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		a := nuevaAPI(t, db)
+
+		// The query plan is PostgreSQL's; SQLite has no equivalent
+		want := http.StatusOK
+		if database.Driver() == database.DriverSQLite {
+			want = http.StatusNotImplemented
+		}
+		res := a.json(http.MethodGet, "/admin/explain/grupos", a.login(adminEmail, adminPassword), nil)
+		if res.status != want {
+			t.Errorf("GET /admin/explain/grupos = %d %s; want %d", res.status, res.body, want)
+		}
+	})
+}
+
+// TestPasswordResetLinkIgnoresHost checks that the reset link is never built from the Host header,
+// which the caller chooses: a forged one would send the victim's token to another site.
+func TestPasswordResetLinkIgnoresHost(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T, db *sql.DB) {
+		a := nuevaAPI(t, db)
+		a.registrar("ana@test.local")
+		t.Setenv("PASSWORD_RESET_URL", "")
+
+		olvido := func() respuesta {
+			t.Helper()
+			req, err := http.NewRequest(http.MethodPost, a.srv.URL+"/password/forgot", strings.NewReader(`{"email": "ana@test.local"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Host = "atacante.example.com"
+			res, err := a.srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			b, _ := io.ReadAll(res.Body)
+			return respuesta{status: res.StatusCode, header: res.Header, body: b}
+		}
+
+		t.Setenv("PUBLIC_BASE_URL", "")
+		if res := olvido(); res.status != http.StatusServiceUnavailable {
+			t.Errorf("POST /password/forgot without PUBLIC_BASE_URL = %d %s; want 503", res.status, res.body)
+		}
+
+		t.Setenv("PUBLIC_BASE_URL", "https://api.example.edu/")
+		if res := olvido(); res.status != http.StatusAccepted {
+			t.Fatalf("POST /password/forgot = %d %s; want 202", res.status, res.body)
+		}
+		var cuerpo string
+		if err := a.db.QueryRow("SELECT cuerpo FROM notificacion WHERE destino = 'ana@test.local'").Scan(&cuerpo); err != nil {
+			t.Fatalf("reading the queued email: %v", err)
+		}
+		if !strings.Contains(cuerpo, "https://api.example.edu/password/reset?token=") || strings.Contains(cuerpo, "atacante") {
+			t.Errorf("reset email = %q; want a link under PUBLIC_BASE_URL", cuerpo)
+		}
+	})
+}
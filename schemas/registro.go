@@ -0,0 +1,176 @@
+package schemas
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/alerts"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// Tipos de esquema publicados.
+const (
+	TipoEvento   = "evento"   // Payload of a webhook
+	TipoModelo   = "modelo"   // Resource of the API, as in its responses
+	TipoPeticion = "peticion" // Body of a request
+)
+
+// Definicion describes a published schema, as listed by GET /schemas.
+type Definicion struct {
+	Nombre      string `json:"nombre"` // The schema is served at /schemas/{nombre}.json
+	Tipo        string `json:"tipo"`
+	Descripcion string `json:"descripcion"`
+	URL         string `json:"url"` // Set by the handler, which knows the base URL
+}
+
+// entrada is a published schema: its definition and the Go value it is generated from.
+type entrada struct {
+	Definicion
+	valor  any
+	evento string // Value of the "evento" property in the payloads of an event
+}
+
+// evento registers the payload of a webhook event. Events sharing a payload type get a schema each,
+// with their own value of "evento".
+func evento(nombre, descripcion string, valor any) entrada {
+	return entrada{Definicion: Definicion{Nombre: nombre, Tipo: TipoEvento, Descripcion: descripcion}, valor: valor, evento: nombre}
+}
+
+// modelo registers an API model under the kebab-case of its Go name ("GrupoWithInvestigadores" is
+// "grupo-with-investigadores").
+func modelo(descripcion string, valor any) entrada {
+	return entrada{Definicion: Definicion{Nombre: NombreDe(valor), Tipo: TipoModelo, Descripcion: descripcion}, valor: valor}
+}
+
+// peticion registers a request body like modelo.
+func peticion(descripcion string, valor any) entrada {
+	return entrada{Definicion: Definicion{Nombre: NombreDe(valor), Tipo: TipoPeticion, Descripcion: descripcion}, valor: valor}
+}
+
+// registro lists every published schema. Events come first, then resources and request bodies; a
+// model added to the API should be added here too.
+var registro = []entrada{
+	evento(models.EventoMembresiaCreada, "Membresía creada (webhook NOTIFICATIONS_WEBHOOK_URL)", models.CambioMembresia{}),
+	evento(models.EventoMembresiaActualizada, "Membresía actualizada (webhook NOTIFICATIONS_WEBHOOK_URL)", models.CambioMembresia{}),
+	evento(models.EventoMembresiaEliminada, "Membresía eliminada (webhook NOTIFICATIONS_WEBHOOK_URL)", models.CambioMembresia{}),
+	evento("alerta", "Alerta operativa para los administradores (webhook ALERT_WEBHOOK_URL)", alerts.Alert{}),
+
+	modelo("Grupo de investigación", models.Grupo{}),
+	modelo("Grupo con sus integrantes (GET /grupos, GET /grupos/{id}/details)", models.GrupoWithInvestigadores{}),
+	modelo("Grupo de un investigador con todos sus integrantes", models.GrupoDeInvestigador{}),
+	modelo("Grupo con sus subgrupos (GET /grupos/{id}/subgrupos)", models.GrupoNodo{}),
+	modelo("Grupo similar a otro (GET /grupos/{id}/relacionados)", models.GrupoRelacionado{}),
+	modelo("Resolución de un grupo", models.Resolucion{}),
+	modelo("Documento adjunto a un grupo", models.GrupoArchivo{}),
+	modelo("Comentario de un grupo", models.GrupoComentario{}),
+	modelo("Investigador", models.Investigador{}),
+	modelo("Investigador con su rol en un grupo", models.InvestigadorConRol{}),
+	modelo("Investigador con el resumen de sus grupos (?include=grupos)", models.InvestigadorConResumen{}),
+	modelo("Membresía de un investigador en un grupo", models.DetalleGrupoInvestigador{}),
+	modelo("Membresía con el nombre de su grupo", models.DetalleConGrupo{}),
+	modelo("Publicación", models.Publicacion{}),
+	modelo("Resultado de la importación BibTeX de publicaciones", models.ResultadoImportPublicaciones{}),
+	modelo("Proyecto de investigación", models.Proyecto{}),
+	modelo("Convocatoria", models.Convocatoria{}),
+	modelo("Postulación de un grupo a una convocatoria", models.Postulacion{}),
+	modelo("Renovación de la vigencia de un grupo", models.Renovacion{}),
+	modelo("Solicitud pública de registro de un grupo", models.SolicitudGrupo{}),
+	modelo("Facultad con sus escuelas profesionales", models.FacultadConEscuelas{}),
+	modelo("Tipo de investigación del catálogo", models.TipoInvestigacion{}),
+	modelo("Búsqueda guardada (/me/busquedas)", models.BusquedaGuardada{}),
+	modelo("Estadísticas de los grupos", models.EstadisticasGrupos{}),
+	modelo("Reporte institucional de grupos (GET /reportes/grupos)", models.ReporteGrupos{}),
+	modelo("Exportación asíncrona (/exports)", models.ExportJob{}),
+	modelo("Vínculo de un investigador con CTI Vitae", models.CtiVitae{}),
+	modelo("Resultado de una sincronización con CTI Vitae", models.ResultadoSyncCtiVitae{}),
+	modelo("Usuario", models.Usuario{}),
+	modelo("Notificación encolada (email o webhook)", models.Notificacion{}),
+	modelo("Entrada de la auditoría", models.AuditLog{}),
+	modelo("Cambio de una entidad del directorio", models.Cambio{}),
+	modelo("Página del feed de cambios (GET /changes)", models.FeedCambios{}),
+	modelo("Uso de la API por cliente y ruta (GET /admin/usage)", models.ReporteUso{}),
+
+	peticion("Cuerpo de la creación de un grupo con sus integrantes", models.CreateGrupoWithDetailsRequest{}),
+	peticion("Cuerpo de POST /grupos/{id}/estado", models.CambiarEstadoGrupoRequest{}),
+	peticion("Cuerpo de la aprobación o el rechazo de un grupo pendiente", models.RevisarGrupoRequest{}),
+	peticion("Cuerpo de PUT /grupos/{id}/padre", models.CambiarGrupoPadreRequest{}),
+	peticion("Cuerpo de POST y PUT /grupos/{id}/investigadores", models.IntegranteRequest{}),
+	peticion("Cuerpo de POST /grupos/{id}/coordinador", models.CambiarCoordinadorRequest{}),
+	peticion("Cuerpo de POST /convocatorias/{id}/postulaciones", models.CrearPostulacionRequest{}),
+	peticion("Cuerpo de PUT /postulaciones/{id}/estado", models.CambiarEstadoPostulacionRequest{}),
+	peticion("Cuerpo de POST /grupos/{id}/renovaciones", models.SolicitarRenovacionRequest{}),
+	peticion("Cuerpo de POST /solicitudes-grupo", models.CreateSolicitudGrupoRequest{}),
+	peticion("Cuerpo de POST /exports", models.ParametrosExport{}),
+	peticion("Cuerpo de POST /login y POST /register", models.Credentials{}),
+	peticion("Cuerpo de POST /password/forgot", models.SolicitudRestablecimientoPassword{}),
+	peticion("Cuerpo de POST /password/reset", models.RestablecimientoPassword{}),
+}
+
+// NombreDe returns the kebab-case of the Go name of the type of v: "CtiVitae" is "cti-vitae" and
+// "GrupoOAI" is "grupo-oai".
+func NombreDe(v any) string {
+	runas := []rune(reflect.TypeOf(v).Name())
+	var b strings.Builder
+	for i, r := range runas {
+		if unicode.IsUpper(r) && i > 0 {
+			siguienteMinuscula := i+1 < len(runas) && unicode.IsLower(runas[i+1])
+			if unicode.IsLower(runas[i-1]) || (unicode.IsUpper(runas[i-1]) && siguienteMinuscula) {
+				b.WriteByte('-')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+var (
+	esquemasOnce sync.Once
+	esquemas     map[string]*Schema
+)
+
+// getEsquemas generates the schemas once: they only change with the binary and its configuration.
+func getEsquemas() map[string]*Schema {
+	esquemasOnce.Do(func() {
+		esquemas = make(map[string]*Schema, len(registro))
+		for _, e := range registro {
+			var s *Schema
+			switch e.Tipo {
+			case TipoPeticion:
+				s = GenerarPeticion(e.valor)
+			case TipoModelo:
+				s = GenerarRespuesta(e.valor)
+			default:
+				s = Generar(e.valor)
+			}
+			s.Title = e.Nombre
+			s.Description = e.Descripcion
+			if p := s.Properties.Get("evento"); p != nil && e.evento != "" {
+				p.Const = e.evento
+			}
+			esquemas[e.Nombre] = s
+		}
+	})
+	return esquemas
+}
+
+// Definiciones returns the published schemas in the order of registro.
+func Definiciones() []Definicion {
+	defs := make([]Definicion, len(registro))
+	for i, e := range registro {
+		defs[i] = e.Definicion
+	}
+	return defs
+}
+
+// Get returns the schema nombre with $id set to id, or false if there is no such schema.
+func Get(nombre, id string) (*Schema, bool) {
+	s, ok := getEsquemas()[nombre]
+	if !ok {
+		return nil, false
+	}
+	copia := *s
+	copia.ID = id
+	return &copia, true
+}
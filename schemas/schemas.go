@@ -0,0 +1,392 @@
+// Package schemas generates JSON Schemas (draft 2020-12) of the webhook events and the API models
+// from their Go structs, so integrators can validate payloads and generate clients from them.
+//
+// A struct becomes an object whose properties follow its json tags: pointers are nullable and
+// embedded structs without a tag are flattened, as encoding/json does. In the schema of a response
+// or event, fields without omitempty are required, as they are always present; in the schema of a
+// request body (GenerarPeticion), those the API requires (validate required or notblank). The `validate` tags of request bodies become constraints (notblank and min
+// as minLength, max as maxLength/maximum/maxItems, oneof as enum, email and datetime as formats...),
+// so a body that passes the schema passes the API's validation too, except for checks across fields.
+// Nested structs go to $defs and are referenced by their Go name.
+package schemas
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+)
+
+// Draft is the JSON Schema dialect of the generated schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema, limited to the keywords the generator uses.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 any                `json:"type,omitempty"` // A type name, or a list of them
+	Format               string             `json:"format,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Properties           Propiedades        `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// Propiedad is a property of an object schema.
+type Propiedad struct {
+	Nombre string
+	Schema *Schema
+}
+
+// Propiedades are the properties of an object schema, in the order of the struct fields (a map
+// would be written in alphabetical order).
+type Propiedades []Propiedad
+
+// MarshalJSON writes the properties as a JSON object.
+func (p Propiedades) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, prop := range p {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		nombre, err := json.Marshal(prop.Nombre)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := json.Marshal(prop.Schema)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(nombre)
+		b.WriteByte(':')
+		b.Write(schema)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// Get returns the schema of the property nombre, or nil.
+func (p Propiedades) Get(nombre string) *Schema {
+	for _, prop := range p {
+		if prop.Nombre == nombre {
+			return prop.Schema
+		}
+	}
+	return nil
+}
+
+var (
+	tipoTime  = reflect.TypeOf(time.Time{})
+	tipoFecha = reflect.TypeOf(calendario.Fecha{})
+	tipoRaw   = reflect.TypeOf(json.RawMessage{})
+)
+
+// Generar returns the schema of the Go type of v, a struct or a pointer to one, as written in
+// webhook events.
+func Generar(v any) *Schema {
+	return generar(v, false, false)
+}
+
+// GenerarRespuesta returns the schema of the Go type of v as written in the API's responses, which
+// follow the configured utils.Serialization: dates as "date" with JSON_DATE_FORMAT=date and, with
+// JSON_OMIT_NULL, nullable fields no longer required.
+func GenerarRespuesta(v any) *Schema {
+	return generar(v, false, true)
+}
+
+// GenerarPeticion returns the schema of the Go type of v as a request body.
+func GenerarPeticion(v any) *Schema {
+	return generar(v, true, false)
+}
+
+func generar(v any, peticion, respuesta bool) *Schema {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	g := generador{raiz: t, defs: map[string]*Schema{}, peticion: peticion, respuesta: respuesta}
+	s := g.objeto(t)
+	s.Schema = Draft
+	if len(g.defs) > 0 {
+		s.Defs = g.defs
+	}
+	return s
+}
+
+// generador collects the $defs of the structs nested in raiz.
+type generador struct {
+	raiz      reflect.Type
+	defs      map[string]*Schema
+	peticion  bool // Generating a request body
+	respuesta bool // Generating an API response (see GenerarRespuesta)
+}
+
+// tipo returns the schema of a value of type t.
+func (g *generador) tipo(t reflect.Type) *Schema {
+	switch t {
+	case tipoTime:
+		return &Schema{Type: "string", Format: "date-time"}
+	case tipoFecha:
+		if g.peticion {
+			return &Schema{Type: "string", Format: "date"} // Requests send 2006-01-02
+		}
+		return &Schema{Type: "string", Format: "date-time"}
+	case tipoRaw:
+		return &Schema{} // Any JSON value
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.tipo(t.Elem()))
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", ContentEncoding: "base64"}
+		}
+		return &Schema{Type: "array", Items: g.tipo(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.tipo(t.Elem())}
+	case reflect.Struct:
+		if t == g.raiz {
+			return &Schema{Ref: "#"}
+		}
+		if t.Name() == "" {
+			return g.objeto(t)
+		}
+		if _, ok := g.defs[t.Name()]; !ok {
+			// Reserved before generating it, so a struct that contains itself refers to its definition
+			g.defs[t.Name()] = &Schema{}
+			*g.defs[t.Name()] = *g.objeto(t)
+		}
+		return &Schema{Ref: "#/$defs/" + t.Name()}
+	}
+	return &Schema{} // interface{}: any JSON value
+}
+
+// nullable makes s also accept null.
+func nullable(s *Schema) *Schema {
+	switch tipo := s.Type.(type) {
+	case string:
+		s.Type = []string{tipo, "null"}
+		return s
+	case nil:
+		if s.Ref == "" {
+			return s // Already any value
+		}
+	}
+	return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+}
+
+// objeto returns the schema of the struct type t.
+func (g *generador) objeto(t reflect.Type) *Schema {
+	s := &Schema{Type: "object"}
+	g.campos(s, t)
+	return s
+}
+
+// campos adds the fields of the struct type t to the object s.
+func (g *generador) campos(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		nombre, opciones, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if nombre == "-" && opciones == "" {
+			continue
+		}
+		base := f.Type
+		if base.Kind() == reflect.Pointer {
+			base = base.Elem()
+		}
+		if f.Anonymous && nombre == "" && base.Kind() == reflect.Struct {
+			g.campos(s, base) // Flattened, as encoding/json does
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if nombre == "" {
+			nombre = f.Name
+		}
+		if s.Properties.Get(nombre) != nil {
+			continue // Shadowed by a field already added
+		}
+
+		prop := g.tipo(base)
+		if _, fecha := f.Tag.Lookup(utils.FechaTag); fecha && (base == tipoTime || base == tipoFecha) && g.respuesta &&
+			utils.CurrentSerialization().FormatoFecha == utils.FormatoFechaDia {
+			prop.Format = "date"
+		}
+		validate := f.Tag.Get("validate")
+		restricciones(prop, base, validate)
+		if f.Type.Kind() == reflect.Pointer {
+			prop = nullable(prop)
+		}
+		s.Properties = append(s.Properties, Propiedad{nombre, prop})
+		requerido := !contiene(opciones, "omitempty")
+		if g.respuesta && utils.CurrentSerialization().OmitirNulos {
+			switch f.Type.Kind() {
+			case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+				requerido = false // Left out when null
+			}
+		}
+		if g.peticion {
+			requerido = obligatorio(validate) || f.Type.Kind() == reflect.Struct && base != tipoTime && base != tipoFecha && g.conObligatorios(prop)
+		}
+		if requerido {
+			s.Required = append(s.Required, nombre)
+		}
+	}
+}
+
+// obligatorio reports whether a validate tag makes the field required in a request body: the zero
+// value fails it.
+func obligatorio(validate string) bool {
+	antes, _, _ := strings.Cut(validate, ",dive") // Rules after dive are the elements'
+	if contiene(antes, "omitempty") {
+		return false
+	}
+	for _, regla := range strings.Split(antes, ",") {
+		nombre, _, _ := strings.Cut(regla, "=")
+		if nombre == "required" || nombre == "notblank" || nombre == "oneof" {
+			return true
+		}
+	}
+	return false
+}
+
+// conObligatorios reports whether the object s, or the definition it refers to, has required
+// properties. The validator checks nested structs, so a struct field with them is required too.
+func (g *generador) conObligatorios(s *Schema) bool {
+	if s.Ref == "#" {
+		return false // The root is still being generated; a struct can't contain itself anyway
+	}
+	if def, ok := g.defs[strings.TrimPrefix(s.Ref, "#/$defs/")]; ok && s.Ref != "" {
+		s = def
+	}
+	return len(s.Required) > 0
+}
+
+// contiene reports whether the comma-separated list of a tag has opcion.
+func contiene(lista, opcion string) bool {
+	return strings.Contains(","+lista+",", ","+opcion+",")
+}
+
+// restricciones adds to s, the schema of a value of type t, the constraints of its validate tag.
+// Those after dive apply to the elements. With omitempty the zero value is also allowed, so the
+// minimums are left out.
+func restricciones(s *Schema, t reflect.Type, tag string) {
+	if tag == "" || s.Ref != "" {
+		return
+	}
+	reglas := strings.Split(tag, ",")
+	omitempty := false
+	for i, regla := range reglas {
+		nombre, param, _ := strings.Cut(regla, "=")
+		switch nombre {
+		case "dive":
+			if s.Items != nil {
+				restricciones(s.Items, t.Elem(), strings.Join(reglas[i+1:], ","))
+			}
+			return
+		case "omitempty":
+			omitempty = true
+		case "notblank":
+			if !omitempty {
+				s.MinLength = entero(1)
+				s.Pattern = `\S`
+			}
+		case "required":
+			if t.Kind() == reflect.String {
+				s.MinLength = entero(1)
+			}
+		case "min", "gte":
+			if !omitempty {
+				limite(s, t, param, true)
+			}
+		case "max", "lte":
+			limite(s, t, param, false)
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				if n, err := strconv.ParseFloat(v, 64); err == nil && t.Kind() != reflect.String {
+					s.Enum = append(s.Enum, n)
+				} else {
+					s.Enum = append(s.Enum, v)
+				}
+			}
+			if omitempty && t.Kind() == reflect.String {
+				s.Enum = append(s.Enum, "")
+			}
+		case "email":
+			s.Format = "email"
+		case "datetime":
+			if param == "2006-01-02" {
+				s.Format = "date"
+			}
+		case "numeric":
+			s.Pattern = `^[0-9]+$`
+		case "anio":
+			if !omitempty {
+				s.Minimum = decimal(1900)
+			}
+		}
+	}
+}
+
+// limite sets the minimum (or maximum) of a length, number of elements or value, by the kind of t.
+func limite(s *Schema, t reflect.Type, param string, minimo bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch t.Kind() {
+	case reflect.String:
+		if minimo {
+			s.MinLength = entero(int(n))
+		} else {
+			s.MaxLength = entero(int(n))
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if minimo {
+			s.MinItems = entero(int(n))
+		} else {
+			s.MaxItems = entero(int(n))
+		}
+	default:
+		if minimo {
+			s.Minimum = decimal(n)
+		} else {
+			s.Maximum = decimal(n)
+		}
+	}
+}
+
+func entero(n int) *int { return &n }
+
+func decimal(n float64) *float64 { return &n }
@@ -0,0 +1,62 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	secretmanager "google.golang.org/api/secretmanager/v1"
+)
+
+// secretManagerProvider reads secrets from Google Secret Manager with the application default
+// credentials (the service account of the Cloud Run service, which needs the Secret Manager
+// Secret Accessor role). A reference is the resource name of a version,
+// "sm://projects/<project>/secrets/<secret>/versions/<version>", or just "sm://<secret>" for the
+// latest version of a secret of GOOGLE_CLOUD_PROJECT.
+type secretManagerProvider struct {
+	once    sync.Once
+	service *secretmanager.Service
+	err     error
+}
+
+func (*secretManagerProvider) Scheme() string { return "sm" }
+
+func (p *secretManagerProvider) Get(ctx context.Context, ref string) (string, error) {
+	name, err := secretVersionName(ref)
+	if err != nil {
+		return "", err
+	}
+	p.once.Do(func() {
+		p.service, p.err = secretmanager.NewService(ctx)
+	})
+	if p.err != nil {
+		return "", fmt.Errorf("creating Secret Manager client: %w", p.err)
+	}
+	version, err := p.service.Projects.Secrets.Versions.Access(name).Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(version.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("decoding %s: %w", name, err)
+	}
+	return string(data), nil
+}
+
+// secretVersionName expands a reference to the resource name of a secret version.
+func secretVersionName(ref string) (string, error) {
+	if !strings.HasPrefix(ref, "projects/") {
+		project := os.Getenv("GOOGLE_CLOUD_PROJECT")
+		if project == "" {
+			return "", fmt.Errorf("GOOGLE_CLOUD_PROJECT must be set to refer to the secret %q by name", ref)
+		}
+		ref = "projects/" + project + "/secrets/" + ref
+	}
+	if !strings.Contains(ref, "/versions/") {
+		ref += "/versions/latest"
+	}
+	return ref, nil
+}
@@ -0,0 +1,99 @@
+// Package secrets resolves the secrets of the configuration (JWT_SECRET, DB_PASSWORD, the Drive
+// credentials...) at startup, so that they need not be kept as plain environment variables.
+//
+// The variable of a secret holds either the secret itself or a reference to where it is kept:
+// "<scheme>://<ref>", resolved by the provider registered for the scheme. The built-in providers
+// are "sm" (Google Secret Manager) and "file" (e.g. a secret mounted as a volume by Cloud Run).
+// Load replaces each reference with the secret, so the rest of the code keeps reading the
+// environment.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Names are the variables that may hold a reference to a secret.
+var Names = []string{
+	"JWT_SECRET",
+	"DB_PASSWORD",
+	"GOOGLE_CREDENTIALS_JSON",
+	"SMTP_PASSWORD",
+	"SENDGRID_API_KEY",
+	"CAPTCHA_SECRET",
+	"REDIS_URL",
+}
+
+// Provider fetches secrets from a store.
+type Provider interface {
+	// Scheme identifies the provider in references ("sm", "file"...).
+	Scheme() string
+	// Get returns the secret a reference (without "<scheme>://") points to.
+	Get(ctx context.Context, ref string) (string, error)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{}
+)
+
+func init() {
+	Register(fileProvider{})
+	Register(&secretManagerProvider{})
+}
+
+// Register makes a provider available for its scheme.
+func Register(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[p.Scheme()] = p
+}
+
+// provider returns the provider of a reference, or nil if the value is not one (a plain secret).
+func provider(value string) (Provider, string) {
+	scheme, ref, ok := strings.Cut(value, "://")
+	if !ok {
+		return nil, ""
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	return providers[scheme], ref
+}
+
+// Load resolves the references held by the variables in Names and sets each variable to its
+// secret. Plain values are left as they are.
+func Load(ctx context.Context) error {
+	for _, name := range Names {
+		p, ref := provider(os.Getenv(name))
+		if p == nil {
+			continue
+		}
+		secret, err := p.Get(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("resolving %s from %s: %w", name, p.Scheme(), err)
+		}
+		if err := os.Setenv(name, secret); err != nil {
+			return err
+		}
+		slog.Info("Secret loaded", "name", name, "provider", p.Scheme())
+	}
+	return nil
+}
+
+// fileProvider reads a secret from a file: "file:///secrets/jwt". The trailing newline editors
+// and `echo` add is dropped.
+type fileProvider struct{}
+
+func (fileProvider) Scheme() string { return "file" }
+
+func (fileProvider) Get(_ context.Context, path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
@@ -0,0 +1,389 @@
+// Package snapshot serves public GET endpoints from periodically refreshed copies of their
+// responses, so the public directory stays fast and available while the database is busy with
+// heavy admin imports or down for maintenance.
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultInterval   = 5 * time.Minute
+	defaultMaxEntries = 2000
+	defaultMaxBody    = 2 << 20 // 2 MiB
+	// idleIntervals is how many refresh intervals an entry is kept without being requested.
+	idleIntervals = 12
+)
+
+// defaultQueryParams are the query parameters of the public GET endpoints: filters, pagination,
+// sorting and response shape.
+var defaultQueryParams = []string{
+	"q", "page", "limit", "sort", "ids", "fields", "expand", "include", "format", "grupo", "investigador",
+	"nombre", "name", "numeroResolucion", "rol", "tipo", "estado", "estadoVigencia", "anio", "idGrupo",
+	"idInvestigador", "entidad", "facultad", "lineaInvestigacion", "tipoInvestigacion", "activosEn", "desde",
+	"hasta", "since", "agrupar", "groupBy", "umbral", "excluir",
+}
+
+// Config controls the snapshot store.
+type Config struct {
+	Interval    time.Duration `json:"interval"`    // How often every entry is re-rendered
+	MaxEntries  int           `json:"maxEntries"`  // Distinct URLs kept; beyond it requests are served live
+	MaxBody     int           `json:"maxBody"`     // Larger responses are served live, in bytes
+	QueryParams []string      `json:"queryParams"` // The only query parameters a stored URL may have; others are served live
+}
+
+// MarshalJSON writes the interval as a string ("5m0s") instead of nanoseconds.
+func (c Config) MarshalJSON() ([]byte, error) {
+	type plain Config
+	return json.Marshal(struct {
+		plain
+		Interval string `json:"interval"`
+	}{plain(c), c.Interval.String()})
+}
+
+// ConfigFromEnv reads PUBLIC_SNAPSHOT_INTERVAL, PUBLIC_SNAPSHOT_MAX_ENTRIES, PUBLIC_SNAPSHOT_MAX_BODY
+// and PUBLIC_SNAPSHOT_QUERY_PARAMS (comma-separated), using the defaults for unset or invalid
+// values. It reports false unless PUBLIC_SNAPSHOT=true.
+func ConfigFromEnv() (Config, bool) {
+	c := Config{Interval: defaultInterval, MaxEntries: defaultMaxEntries, MaxBody: defaultMaxBody, QueryParams: defaultQueryParams}
+	if v, err := time.ParseDuration(os.Getenv("PUBLIC_SNAPSHOT_INTERVAL")); err == nil && v > 0 {
+		c.Interval = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("PUBLIC_SNAPSHOT_MAX_ENTRIES")); err == nil && v > 0 {
+		c.MaxEntries = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("PUBLIC_SNAPSHOT_MAX_BODY")); err == nil && v > 0 {
+		c.MaxBody = v
+	}
+	if v := os.Getenv("PUBLIC_SNAPSHOT_QUERY_PARAMS"); v != "" {
+		c.QueryParams = nil
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				c.QueryParams = append(c.QueryParams, p)
+			}
+		}
+	}
+	return c, os.Getenv("PUBLIC_SNAPSHOT") == "true"
+}
+
+// entry is the stored response for one URL, plus what is needed to render it again.
+type entry struct {
+	handler  http.Handler
+	uri      string
+	host     string
+	header   http.Header // Request headers replayed on refresh (no credentials)
+	vars     map[string]string
+	status   int
+	respHdr  http.Header
+	body     []byte
+	rendered time.Time
+	lastHit  time.Time
+}
+
+// Status describes the store, for GET /admin/snapshot.
+type Status struct {
+	Config        Config    `json:"config"`
+	Entries       int       `json:"entries"`
+	Bytes         int       `json:"bytes"`
+	Hits          int64     `json:"hits"`
+	Misses        int64     `json:"misses"`
+	LastRefresh   time.Time `json:"lastRefresh"`
+	RefreshErrors int       `json:"refreshErrors"` // Entries whose last re-render failed and are served stale
+}
+
+// Store keeps the snapshot of every public URL requested recently.
+type Store struct {
+	cfg         Config
+	queryParams map[string]bool // cfg.QueryParams
+
+	mu            sync.Mutex
+	entries       map[string]*entry
+	hits, misses  int64
+	lastRefresh   time.Time
+	refreshErrors int
+}
+
+// New creates an empty store.
+func New(cfg Config) *Store {
+	s := &Store{cfg: cfg, queryParams: map[string]bool{}, entries: map[string]*entry{}}
+	for _, p := range cfg.QueryParams {
+		s.queryParams[p] = true
+	}
+	return s
+}
+
+// key returns the key of the stored response for r: the host, the path, the query in canonical
+// order and the negotiated format and language, which handlers may vary on. It reports false if the
+// query has a parameter outside cfg.QueryParams, so that made-up parameters can neither fill the
+// store nor be served a response rendered without them.
+func (s *Store) key(r *http.Request) (string, bool) {
+	query, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		return "", false
+	}
+	for p := range query {
+		if !s.queryParams[p] {
+			return "", false
+		}
+	}
+	return r.Host + r.URL.Path + "?" + query.Encode() + "|" + r.Header.Get("Accept") + "|" + r.Header.Get("Accept-Language"), true
+}
+
+// Middleware serves anonymous GET requests to next from the snapshot, rendering and storing the
+// response on the first request. Requests with credentials are always served live, since public
+// routes may offer extra options to authenticated users, and so are requests with a query parameter
+// outside cfg.QueryParams. A response that turns out larger than cfg.MaxBody, or not a 200, is
+// streamed to the client as it is written instead of kept.
+func (s *Store) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.Header.Get("Authorization") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key, ok := s.key(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		s.mu.Lock()
+		e, ok := s.entries[key]
+		if ok {
+			e.lastHit = time.Now()
+			s.hits++
+			status, hdr, body, rendered := e.status, e.respHdr, e.body, e.rendered
+			s.mu.Unlock()
+			writeResponse(w, r, status, hdr, body, rendered, "hit")
+			return
+		}
+		s.misses++
+		s.mu.Unlock()
+
+		e = &entry{
+			handler: next,
+			uri:     r.URL.RequestURI(),
+			host:    r.Host,
+			header:  replayHeader(r.Header),
+			vars:    mux.Vars(r),
+		}
+		c := &captura{w: w, max: s.cfg.MaxBody, recorder: recorder{header: http.Header{}}}
+		next.ServeHTTP(c, r)
+		if c.directo {
+			return // Already written to the client
+		}
+		if c.status == 0 {
+			c.status = http.StatusOK
+		}
+		e.status, e.respHdr, e.body, e.rendered, e.lastHit = c.status, c.header, c.body.Bytes(), time.Now(), time.Now()
+		s.mu.Lock()
+		if len(s.entries) < s.cfg.MaxEntries {
+			s.entries[key] = e
+		}
+		s.mu.Unlock()
+		writeResponse(w, r, c.status, c.header, c.body.Bytes(), time.Now(), "miss")
+	})
+}
+
+// captura buffers a response that may be stored, up to max bytes of body. Once the response is
+// known not to be storable (not a 200, or a larger body) it writes what it has to w and passes the
+// rest straight through, so a large response is neither held in memory nor delayed.
+type captura struct {
+	recorder
+	w       http.ResponseWriter
+	max     int
+	directo bool // Passing through to w
+}
+
+func (c *captura) WriteHeader(status int) {
+	if c.status != 0 {
+		return
+	}
+	c.status = status
+	if status != http.StatusOK {
+		c.pasar()
+	}
+}
+
+func (c *captura) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	if !c.directo && c.body.Len()+len(b) > c.max {
+		c.pasar()
+	}
+	if c.directo {
+		return c.w.Write(b)
+	}
+	return c.body.Write(b)
+}
+
+// pasar writes the headers and the body buffered so far to w and switches to passing through.
+func (c *captura) pasar() {
+	for k, v := range c.header {
+		c.w.Header()[k] = v
+	}
+	c.w.Header().Set("X-Snapshot", "miss")
+	c.w.WriteHeader(c.status)
+	c.w.Write(c.body.Bytes())
+	c.body = bytes.Buffer{}
+	c.directo = true
+}
+
+// Run refreshes the snapshot every interval until ctx is done.
+func (s *Store) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Refresh(ctx)
+		}
+	}
+}
+
+// Refresh re-renders every entry. An entry whose re-render fails keeps its previous response, so
+// the public endpoints stay available while the database is unavailable; entries not requested
+// for idleIntervals intervals are dropped.
+func (s *Store) Refresh(ctx context.Context) {
+	s.mu.Lock()
+	pending := make(map[string]*entry, len(s.entries))
+	for key, e := range s.entries {
+		if time.Since(e.lastHit) > idleIntervals*s.cfg.Interval {
+			delete(s.entries, key)
+			continue
+		}
+		pending[key] = e
+	}
+	s.mu.Unlock()
+
+	errores := 0
+	for key, e := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+		rec := render(ctx, e, s.cfg.MaxBody)
+		if rec.status != http.StatusOK || rec.excedido {
+			errores++
+			logging.FromContext(ctx).Warn("Snapshot refresh failed, keeping the previous response", "key", key, "status", rec.status)
+			continue
+		}
+		s.mu.Lock()
+		if cur, ok := s.entries[key]; ok && cur == e {
+			e.status, e.respHdr, e.body, e.rendered = rec.status, rec.header, rec.body.Bytes(), time.Now()
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	s.lastRefresh = time.Now()
+	s.refreshErrors = errores
+	s.mu.Unlock()
+}
+
+// Status returns the store's counters and configuration.
+func (s *Store) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := Status{
+		Config:        s.cfg,
+		Entries:       len(s.entries),
+		Hits:          s.hits,
+		Misses:        s.misses,
+		LastRefresh:   s.lastRefresh,
+		RefreshErrors: s.refreshErrors,
+	}
+	for _, e := range s.entries {
+		st.Bytes += len(e.body)
+	}
+	return st
+}
+
+// replayHeader keeps the request headers that can affect a public response (forwarded host and
+// scheme, used to build links, and the ones in the entry's key), dropping credentials.
+func replayHeader(h http.Header) http.Header {
+	out := http.Header{}
+	for _, k := range []string{"X-Forwarded-Proto", "X-Forwarded-Host", "Accept", "Accept-Language"} {
+		if v := h.Values(k); len(v) > 0 {
+			out[k] = append([]string(nil), v...)
+		}
+	}
+	return out
+}
+
+// recorder captures a handler's response in memory. With max set it keeps at most max bytes of
+// body and records that the rest was dropped.
+type recorder struct {
+	status   int
+	header   http.Header
+	body     bytes.Buffer
+	max      int
+	excedido bool // The body was larger than max
+}
+
+func (r *recorder) Header() http.Header { return r.header }
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if r.max > 0 && (r.excedido || r.body.Len()+len(b) > r.max) {
+		r.excedido = true
+		return len(b), nil
+	}
+	return r.body.Write(b)
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+// render runs an entry's handler against a copy of its original request, keeping at most max bytes
+// of the response body.
+func render(ctx context.Context, e *entry, max int) *recorder {
+	rec := &recorder{header: http.Header{}, max: max}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.uri, nil)
+	if err != nil {
+		rec.status = http.StatusInternalServerError
+		return rec
+	}
+	req.Host = e.host
+	req.Header = e.header.Clone()
+	req = mux.SetURLVars(req, e.vars)
+	e.handler.ServeHTTP(rec, req)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec
+}
+
+// writeResponse writes a stored response, telling the client how old it is. A stored 200 with an
+// ETag the client already has is answered with 304.
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, hdr http.Header, body []byte, rendered time.Time, resultado string) {
+	for k, v := range hdr {
+		w.Header()[k] = v
+	}
+	w.Header().Set("X-Snapshot", resultado)
+	w.Header().Set("Age", strconv.Itoa(int(time.Since(rendered).Seconds())))
+	if etag := hdr.Get("ETag"); status == http.StatusOK && etag != "" && utils.NotModified(w, r, etag) {
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// ChecksumReader wraps a reader and computes the SHA-256 and size of everything read through it,
+// so a file's checksum can be stored while it is uploaded.
+type ChecksumReader struct {
+	r    io.Reader
+	h    hash.Hash
+	size int64
+}
+
+// NewChecksumReader returns a ChecksumReader reading from r.
+func NewChecksumReader(r io.Reader) *ChecksumReader {
+	return &ChecksumReader{r: r, h: sha256.New()}
+}
+
+// Read implements io.Reader.
+func (c *ChecksumReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.h.Write(p[:n])
+	c.size += int64(n)
+	return n, err
+}
+
+// Sum returns the hex SHA-256 and size of the data read so far.
+func (c *ChecksumReader) Sum() (string, int64) {
+	return hex.EncodeToString(c.h.Sum(nil)), c.size
+}
+
+// Checksum reads r to the end and returns its hex SHA-256 and size.
+func Checksum(r io.Reader) (string, int64, error) {
+	c := NewChecksumReader(r)
+	if _, err := io.Copy(io.Discard, c); err != nil {
+		return "", 0, err
+	}
+	sum, size := c.Sum()
+	return sum, size, nil
+}
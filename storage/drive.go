@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// DriveName is the name of the Google Drive backend.
+const DriveName = "drive"
+
+// DriveBackend stores files in a Google Drive folder. Keys are Drive file IDs.
+type DriveBackend struct {
+	service  *drive.Service
+	folderID string
+	counters driveCounters
+}
+
+// NewDriveBackend creates a backend that uploads into the given folder.
+func NewDriveBackend(service *drive.Service, folderID string) *DriveBackend {
+	return &DriveBackend{service: service, folderID: folderID}
+}
+
+// Name implements Backend.
+func (d *DriveBackend) Name() string { return DriveName }
+
+// Service returns the underlying Drive client.
+func (d *DriveBackend) Service() *drive.Service { return d.service }
+
+// FolderID returns the folder new files are uploaded to.
+func (d *DriveBackend) FolderID() string { return d.folderID }
+
+// Put implements Backend.
+func (d *DriveBackend) Put(ctx context.Context, name string, r io.Reader) (id string, err error) {
+	ctx, end := startDriveSpan(ctx, "drive.Put", attribute.String("drive.file_name", name))
+	defer func() { end(err) }()
+
+	f := &drive.File{
+		Name:    fmt.Sprintf("%d_%s", time.Now().UnixNano(), name),
+		Parents: []string{d.folderID},
+	}
+	created, err := d.service.Files.Create(f).Media(r).Context(ctx).Do()
+	if err = d.observe(err); err != nil {
+		return "", fmt.Errorf("no se pudo crear el archivo en Google Drive: %w", err)
+	}
+	return created.Id, nil
+}
+
+// Open implements Backend.
+func (d *DriveBackend) Open(ctx context.Context, key string) (obj *Object, err error) {
+	ctx, end := startDriveSpan(ctx, "drive.Open", attribute.String("drive.file_id", key))
+	defer func() { end(err) }()
+
+	meta, err := d.service.Files.Get(key).Fields("name", "mimeType", "trashed").Context(ctx).Do()
+	if err = d.observe(err); err != nil {
+		if isDriveNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("error obteniendo metadatos de '%s' en Google Drive: %w", key, err)
+	}
+	if meta.Trashed {
+		return nil, ErrNotFound
+	}
+	resp, err := d.service.Files.Get(key).Context(ctx).Download()
+	if err = d.observe(err); err != nil {
+		return nil, fmt.Errorf("error descargando '%s' de Google Drive: %w", key, err)
+	}
+	return &Object{Body: resp.Body, Name: meta.Name, ContentType: meta.MimeType}, nil
+}
+
+// Delete implements Backend.
+func (d *DriveBackend) Delete(ctx context.Context, key string) (err error) {
+	ctx, end := startDriveSpan(ctx, "drive.Delete", attribute.String("drive.file_id", key))
+	defer func() { end(err) }()
+
+	if err := d.observe(d.service.Files.Delete(key).Context(ctx).Do()); err != nil && !isDriveNotFound(err) {
+		return fmt.Errorf("error eliminando archivo '%s' de Google Drive: %w", key, err)
+	}
+	return nil
+}
+
+// Exists implements Backend.
+func (d *DriveBackend) Exists(ctx context.Context, key string) (exists bool, err error) {
+	ctx, end := startDriveSpan(ctx, "drive.Exists", attribute.String("drive.file_id", key))
+	defer func() { end(err) }()
+
+	f, err := d.service.Files.Get(key).Fields("id", "trashed").Context(ctx).Do()
+	if err = d.observe(err); err != nil {
+		if isDriveNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error verificando archivo '%s' en Google Drive: %w", key, err)
+	}
+	return !f.Trashed, nil
+}
+
+// startDriveSpan starts the span of a Drive operation; the HTTP calls it makes are its children.
+// The returned function ends it, recording err.
+func startDriveSpan(ctx context.Context, nombre string, attrs ...attribute.KeyValue) (context.Context, func(error)) {
+	ctx, span := telemetry.Tracer().Start(ctx, nombre, trace.WithAttributes(attrs...))
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// URL implements Backend.
+func (d *DriveBackend) URL(key string) string {
+	return fmt.Sprintf("https://drive.google.com/file/d/%s/view", key)
+}
+
+func isDriveNotFound(err error) bool {
+	googleErr, ok := err.(*googleapi.Error)
+	return ok && googleErr.Code == http.StatusNotFound
+}
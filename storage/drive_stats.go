@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"google.golang.org/api/googleapi"
+)
+
+// DriveStats are the cumulative Drive API counters of a DriveBackend since the process started.
+type DriveStats struct {
+	Requests      int64 `json:"requests"`
+	Errors        int64 `json:"errors"`        // Failed calls, rate limits and quota errors included (404 excluded)
+	RateLimited   int64 `json:"rateLimited"`   // 429 and 403 rate limit responses
+	QuotaExceeded int64 `json:"quotaExceeded"` // 403 storageQuotaExceeded responses
+}
+
+// Sub returns the counters accumulated between prev and s.
+func (s DriveStats) Sub(prev DriveStats) DriveStats {
+	return DriveStats{
+		Requests:      s.Requests - prev.Requests,
+		Errors:        s.Errors - prev.Errors,
+		RateLimited:   s.RateLimited - prev.RateLimited,
+		QuotaExceeded: s.QuotaExceeded - prev.QuotaExceeded,
+	}
+}
+
+// DriveQuota is the storage quota of the account owning the uploads. Limit is 0 when the
+// account has unlimited storage.
+type DriveQuota struct {
+	Limit int64 `json:"limit"`
+	Usage int64 `json:"usage"`
+}
+
+// UsageRatio returns Usage/Limit, or 0 when the storage is unlimited.
+func (q DriveQuota) UsageRatio() float64 {
+	if q.Limit <= 0 {
+		return 0
+	}
+	return float64(q.Usage) / float64(q.Limit)
+}
+
+type driveCounters struct {
+	requests, errors, rateLimited, quotaExceeded atomic.Int64
+}
+
+// Stats returns the backend's Drive API counters.
+func (d *DriveBackend) Stats() DriveStats {
+	return DriveStats{
+		Requests:      d.counters.requests.Load(),
+		Errors:        d.counters.errors.Load(),
+		RateLimited:   d.counters.rateLimited.Load(),
+		QuotaExceeded: d.counters.quotaExceeded.Load(),
+	}
+}
+
+// Quota returns the storage quota of the service account.
+func (d *DriveBackend) Quota(ctx context.Context) (DriveQuota, error) {
+	about, err := d.service.About.Get().Fields("storageQuota").Context(ctx).Do()
+	if err = d.observe(err); err != nil {
+		return DriveQuota{}, fmt.Errorf("error obteniendo la cuota de Google Drive: %w", err)
+	}
+	if about.StorageQuota == nil {
+		return DriveQuota{}, nil
+	}
+	return DriveQuota{Limit: about.StorageQuota.Limit, Usage: about.StorageQuota.Usage}, nil
+}
+
+// observe counts one Drive API call and its outcome, and returns err unchanged.
+func (d *DriveBackend) observe(err error) error {
+	d.counters.requests.Add(1)
+	if err == nil || isDriveNotFound(err) {
+		return err
+	}
+	d.counters.errors.Add(1)
+	var googleErr *googleapi.Error
+	if !errors.As(err, &googleErr) {
+		return err
+	}
+	if googleErr.Code == http.StatusTooManyRequests {
+		d.counters.rateLimited.Add(1)
+		return err
+	}
+	if googleErr.Code != http.StatusForbidden {
+		return err
+	}
+	for _, item := range googleErr.Errors {
+		switch item.Reason {
+		case "rateLimitExceeded", "userRateLimitExceeded", "dailyLimitExceeded":
+			d.counters.rateLimited.Add(1)
+			return err
+		case "storageQuotaExceeded", "quotaExceeded":
+			d.counters.quotaExceeded.Add(1)
+			return err
+		}
+	}
+	return err
+}
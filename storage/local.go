@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalName is the name of the local disk backend.
+const LocalName = "local"
+
+// LocalBackend stores files in a directory. They are served by the API through the storage layer
+// (see the /files/ route), never straight from the directory. Keys are slash-separated paths
+// relative to the directory.
+type LocalBackend struct {
+	dir     string
+	urlBase string
+}
+
+// NewLocalBackend creates a backend rooted at dir whose files are served under urlBase (e.g.
+// "https://api.example.com/files/"), followed by the file's ref.
+func NewLocalBackend(dir, urlBase string) *LocalBackend {
+	return &LocalBackend{dir: dir, urlBase: strings.TrimRight(urlBase, "/") + "/"}
+}
+
+// Name implements Backend.
+func (l *LocalBackend) Name() string { return LocalName }
+
+// path returns the file path for a key, refusing keys that escape the directory.
+func (l *LocalBackend) path(key string) (string, error) {
+	clean := path.Clean("/" + key)[1:]
+	if clean == "" || clean != key {
+		return "", fmt.Errorf("clave de archivo local inválida: %q", key)
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(clean)), nil
+}
+
+// Put implements Backend. Files are grouped by month: YYYY/MM/<timestamp>_<name>.
+func (l *LocalBackend) Put(ctx context.Context, name string, r io.Reader) (string, error) {
+	now := time.Now()
+	base := strings.ReplaceAll(filepath.Base(name), " ", "_")
+	key := fmt.Sprintf("%04d/%02d/%d_%s", now.Year(), now.Month(), now.UnixNano(), base)
+	p, err := l.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return "", fmt.Errorf("error creando directorio de almacenamiento: %w", err)
+	}
+	f, err := os.Create(p)
+	if err != nil {
+		return "", fmt.Errorf("error creando archivo local: %w", err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(p)
+		return "", fmt.Errorf("error escribiendo archivo local: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(p)
+		return "", fmt.Errorf("error cerrando archivo local: %w", err)
+	}
+	return key, nil
+}
+
+// Open implements Backend.
+func (l *LocalBackend) Open(ctx context.Context, key string) (*Object, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &Object{Body: f, Name: path.Base(key), ContentType: mime.TypeByExtension(path.Ext(key))}, nil
+}
+
+// Delete implements Backend.
+func (l *LocalBackend) Delete(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error eliminando archivo local '%s': %w", key, err)
+	}
+	return nil
+}
+
+// Exists implements Backend.
+func (l *LocalBackend) Exists(ctx context.Context, key string) (bool, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(p); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// URL implements Backend. The link is urlBase followed by the escaped ref ("local:<key>").
+func (l *LocalBackend) URL(key string) string {
+	segmentos := strings.Split(FormatRef(LocalName, key), "/")
+	for i, s := range segmentos {
+		segmentos[i] = url.PathEscape(s)
+	}
+	return l.urlBase + strings.Join(segmentos, "/")
+}
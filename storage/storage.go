@@ -0,0 +1,114 @@
+// Package storage abstracts where uploaded files live (Google Drive, local disk...).
+//
+// Files are referenced in the database by a "ref": "<backend>:<key>". Refs without a known
+// prefix are legacy Google Drive file IDs, stored before backends existed.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned when a file does not exist in its backend.
+var ErrNotFound = errors.New("archivo no encontrado")
+
+// Object is an opened file.
+type Object struct {
+	Body        io.ReadCloser
+	Name        string
+	ContentType string
+}
+
+// Backend stores files under opaque keys.
+type Backend interface {
+	// Name identifies the backend in refs ("drive", "local"...).
+	Name() string
+	// Put stores the content under a new key derived from name and returns the key.
+	Put(ctx context.Context, name string, r io.Reader) (string, error)
+	// Open returns the file's content; the caller must close Body.
+	Open(ctx context.Context, key string) (*Object, error)
+	// Delete removes a file. Deleting a missing file is not an error.
+	Delete(ctx context.Context, key string) error
+	// Exists reports whether the file is still available.
+	Exists(ctx context.Context, key string) (bool, error)
+	// URL returns the link given to clients for a key.
+	URL(key string) string
+}
+
+var (
+	mu       sync.RWMutex
+	backends = map[string]Backend{}
+)
+
+// Register makes a backend available by name.
+func Register(b Backend) {
+	mu.Lock()
+	defer mu.Unlock()
+	backends[b.Name()] = b
+}
+
+// Get returns a registered backend.
+func Get(name string) (Backend, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	b, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("backend de almacenamiento %q no registrado", name)
+	}
+	return b, nil
+}
+
+// DefaultName is the backend new uploads go to: STORAGE_BACKEND, or "drive".
+func DefaultName() string {
+	if name := os.Getenv("STORAGE_BACKEND"); name != "" {
+		return name
+	}
+	return DriveName
+}
+
+// Default returns the backend new uploads go to.
+func Default() (Backend, error) {
+	return Get(DefaultName())
+}
+
+// FormatRef builds the ref stored in the database for a key in a backend.
+func FormatRef(backend, key string) string {
+	return backend + ":" + key
+}
+
+// ParseRef splits a ref into backend name and key. Legacy refs (bare Drive IDs) map to "drive".
+func ParseRef(ref string) (backend, key string) {
+	if name, key, ok := strings.Cut(ref, ":"); ok {
+		mu.RLock()
+		_, known := backends[name]
+		mu.RUnlock()
+		if known || name == DriveName || name == LocalName {
+			return name, key
+		}
+	}
+	return DriveName, ref
+}
+
+// Resolve returns the backend and key of a ref.
+func Resolve(ref string) (Backend, string, error) {
+	name, key := ParseRef(ref)
+	b, err := Get(name)
+	if err != nil {
+		return nil, "", err
+	}
+	return b, key, nil
+}
+
+// URL returns the client-facing link for a ref, or "" if its backend is not available.
+func URL(ref string) string {
+	b, key, err := Resolve(ref)
+	if err != nil {
+		return ""
+	}
+	return b.URL(key)
+}
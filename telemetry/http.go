@@ -0,0 +1,38 @@
+package telemetry
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Handler wraps the whole HTTP stack (CORS included) with a server span per request, continuing
+// the caller's trace.
+func Handler(h http.Handler) http.Handler {
+	return otelhttp.NewHandler(h, "HTTP", otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method // Renamed after routing by RouteMiddleware
+	}))
+}
+
+// RouteMiddleware names the request span after the matched route template ("GET /grupos/{id}"),
+// so spans of the same endpoint are grouped regardless of IDs in the path.
+func RouteMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			if tmpl, err := route.GetPathTemplate(); err == nil {
+				span := trace.SpanFromContext(r.Context())
+				span.SetName(r.Method + " " + tmpl)
+				span.SetAttributes(semconv.HTTPRoute(tmpl))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Transport instruments outgoing HTTP calls (e.g. to the Drive API) with client spans.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base)
+}
@@ -0,0 +1,73 @@
+// Package telemetry sets up OpenTelemetry tracing. Incoming requests, SQL queries and Drive calls
+// are recorded as spans of the same trace, continuing the trace of the caller (W3C traceparent, as
+// sent by Cloud Run's load balancer and by other instrumented services).
+//
+// Spans are exported with OTLP over HTTP when OTEL_EXPORTER_OTLP_ENDPOINT (or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) is set, e.g. to an OpenTelemetry Collector that forwards them
+// to Cloud Trace. The standard OTEL_* variables (headers, OTEL_TRACES_SAMPLER,
+// OTEL_SERVICE_NAME...) are honored. Without an endpoint tracing is disabled and costs nothing.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/logging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by this service's own code.
+const instrumentationName = "github.com/GoogleCloudPlatform/golang-samples/run/helloworld"
+
+// defaultServiceName is used unless OTEL_SERVICE_NAME is set.
+const defaultServiceName = "apiGrupos"
+
+// Setup installs the global tracer provider and propagator. The returned function flushes pending
+// spans and must be called before the process exits.
+func Setup(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating OTLP trace exporter: %w", err)
+	}
+	nombre := os.Getenv("OTEL_SERVICE_NAME")
+	if nombre == "" {
+		nombre = defaultServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(nombre),
+		semconv.ServiceVersion(version.String()),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("error creating trace resource: %w", err)
+	}
+
+	// The sampler comes from OTEL_TRACES_SAMPLER (parent-based, always on by default)
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	logging.FromContext(ctx).Info("OpenTelemetry tracing enabled", "service", nombre)
+	return tp.Shutdown, nil
+}
+
+// Enabled reports whether an OTLP endpoint is configured.
+func Enabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Tracer returns the tracer for spans created by this service's own code.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
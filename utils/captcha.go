@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// defaultCaptchaVerifyURL is the reCAPTCHA verification endpoint. hCaptcha and Turnstile
+// expose a compatible API and can be used by setting CAPTCHA_VERIFY_URL.
+const defaultCaptchaVerifyURL = "https://www.google.com/recaptcha/api/siteverify"
+
+var captchaClient = &http.Client{Timeout: 10 * time.Second}
+
+// ErrCaptchaNoConfigurado is returned by VerifyCaptcha when CAPTCHA_SECRET is not set and
+// verification was not disabled: the captcha can't be checked, so nothing is accepted.
+var ErrCaptchaNoConfigurado = errors.New("CAPTCHA_SECRET is not set")
+
+// captchaDesactivado reports whether CAPTCHA_DISABLED=true turns verification off, for local
+// development and demo mode.
+func captchaDesactivado() bool {
+	desactivado, _ := strconv.ParseBool(os.Getenv("CAPTCHA_DISABLED"))
+	return desactivado
+}
+
+// LogCaptchaConfig warns at startup when captcha tokens are not verified: with CAPTCHA_DISABLED
+// every token is accepted, and without CAPTCHA_SECRET every one is rejected.
+func LogCaptchaConfig() {
+	switch {
+	case captchaDesactivado():
+		slog.Warn("Captcha verification disabled (CAPTCHA_DISABLED); do not use in production")
+	case os.Getenv("CAPTCHA_SECRET") == "":
+		slog.Warn("CAPTCHA_SECRET not set; public group requests will be answered with 503")
+	}
+}
+
+// VerifyCaptcha validates a captcha token against the configured provider. It fails closed: without
+// CAPTCHA_SECRET it returns ErrCaptchaNoConfigurado, unless CAPTCHA_DISABLED=true skips the check.
+func VerifyCaptcha(token, remoteIP string) (bool, error) {
+	if captchaDesactivado() {
+		return true, nil
+	}
+	secret := os.Getenv("CAPTCHA_SECRET")
+	if secret == "" {
+		return false, ErrCaptchaNoConfigurado
+	}
+	if token == "" {
+		return false, nil
+	}
+
+	verifyURL := os.Getenv("CAPTCHA_VERIFY_URL")
+	if verifyURL == "" {
+		verifyURL = defaultCaptchaVerifyURL
+	}
+
+	form := url.Values{}
+	form.Set("secret", secret)
+	form.Set("response", token)
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	resp, err := captchaClient.PostForm(verifyURL, form)
+	if err != nil {
+		return false, fmt.Errorf("error contacting captcha provider: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("error decoding captcha response: %w", err)
+	}
+	return result.Success, nil
+}
@@ -0,0 +1,22 @@
+package utils
+
+import "strings"
+
+// NormalizarDOI reduces a DOI given as a URL (https://doi.org/...) or with a "doi:" prefix to its
+// bare lowercase form, reporting whether it looks like a DOI (10.<registrant>/<suffix>).
+func NormalizarDOI(s string) (string, bool) {
+	doi := strings.TrimSpace(s)
+	lower := strings.ToLower(doi)
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		if strings.HasPrefix(lower, prefix) {
+			doi = strings.TrimSpace(doi[len(prefix):])
+			break
+		}
+	}
+	doi = strings.ToLower(doi)
+	registrant, suffix, ok := strings.Cut(doi, "/")
+	if !ok || !strings.HasPrefix(registrant, "10.") || len(registrant) < 4 || suffix == "" || strings.ContainsAny(doi, " \t\n") {
+		return "", false
+	}
+	return doi, true
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// NormalizeEmail validates a bare email address ("user@example.com", no display name) and returns it
+// trimmed with its domain lowercased. ok is false if the address is not valid.
+func NormalizeEmail(s string) (email string, ok bool) {
+	s = strings.TrimSpace(s)
+	if len(s) > 254 {
+		return "", false
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return "", false
+	}
+	local, domain, found := strings.Cut(s, "@")
+	if !found || local == "" || !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return "", false
+	}
+	return local + "@" + strings.ToLower(domain), true
+}
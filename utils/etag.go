@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WeakETag builds the ETag of a list from its number of rows and their latest modification, plus
+// anything else the body depends on (query string, caller's role...). It is weak because equal
+// versions may still be serialized differently (e.g. links to files).
+func WeakETag(count int, lastModified time.Time, vary ...string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d|%d", count, lastModified.UnixNano())
+	for _, v := range vary {
+		fmt.Fprintf(h, "|%s", v)
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil))[:20] + `"`
+}
+
+// StrongETag builds the ETag of a representation from its bytes.
+func StrongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:])[:20] + `"`
+}
+
+// NotModified sets the ETag header and, when the request's If-None-Match lists it (weak
+// comparison, as RFC 9110 requires for If-None-Match), answers 304 and reports true.
+func NotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	if !etagMatch(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+func etagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// RespondJSONWithETag writes v like RespondJSON with status 200 and a strong ETag of the body, or
+// 304 without body if the client already has it.
+func RespondJSONWithETag(w http.ResponseWriter, r *http.Request, v interface{}) {
+	body, err := codificarRespuesta(w, v, true)
+	if err != nil {
+		RespondError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+	if NotModified(w, r, StrongETag(body)) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
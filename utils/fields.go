@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// JSONFieldNames returns the JSON names of the fields of a struct type (or pointer to struct),
+// including those of embedded structs. Fields tagged json:"-" are left out.
+func JSONFieldNames(t reflect.Type) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	names := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			names = append(names, JSONFieldNames(field.Type)...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// CheckFields reports the first of fields that is not a JSON field of v's struct type.
+func CheckFields(v interface{}, fields []string) error {
+	known := map[string]bool{}
+	for _, name := range JSONFieldNames(reflect.TypeOf(v)) {
+		known[name] = true
+	}
+	for _, f := range fields {
+		if !known[f] {
+			return fmt.Errorf("unknown field %q", f)
+		}
+	}
+	return nil
+}
+
+// SelectFields returns v as a JSON object, with its links rewritten and its dates in the configured
+// format, keeping only the given fields (every field when fields is empty). Like RespondJSON, it
+// rewrites a pointer's target in place; pass a value to leave it untouched.
+func SelectFields(v interface{}, fields []string) (map[string]json.RawMessage, error) {
+	raw, err := marshalRespuesta(withLinks(v))
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &all); err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return all, nil
+	}
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, f := range fields {
+		if value, ok := all[f]; ok {
+			selected[f] = value
+		}
+	}
+	return selected, nil
+}
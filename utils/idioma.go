@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/i18n"
+)
+
+// languageWriter carries the language negotiated for a response (see middleware.Language).
+type languageWriter struct {
+	http.ResponseWriter
+	lang string
+}
+
+// NewLanguageWriter sets lang as the language of the error messages written through w.
+func NewLanguageWriter(w http.ResponseWriter, lang string) http.ResponseWriter {
+	return &languageWriter{ResponseWriter: w, lang: lang}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (l *languageWriter) Unwrap() http.ResponseWriter {
+	return l.ResponseWriter
+}
+
+// Language returns the language of the error messages written through w, i18n.Default if none
+// was negotiated.
+func Language(w http.ResponseWriter) string {
+	for w != nil {
+		if l, ok := w.(*languageWriter); ok {
+			return l.lang
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	return i18n.Default
+}
+
+// Localize sets the message of e, and of the fields in its details, in the language of w when
+// they are in the i18n catalog.
+func (e *ErrorResponse) Localize(w http.ResponseWriter) {
+	lang := Language(w)
+	w.Header().Set("Content-Language", lang)
+	if m, ok := i18n.Mensaje(lang, e.MessageCode, e.args...); ok {
+		e.Message, e.Error = m, m
+	}
+	if errs, ok := e.Details.([]FieldError); ok {
+		e.Details = localizeFields(lang, errs)
+	}
+}
+
+// localizeFields returns a copy of errs with the messages in the catalog in lang.
+func localizeFields(lang string, errs []FieldError) []FieldError {
+	out := make([]FieldError, len(errs))
+	for i, fe := range errs {
+		clave := fe.clave
+		if clave == "" {
+			clave = i18n.Codigo(fe.Mensaje)
+		}
+		if m, ok := i18n.Mensaje(lang, clave, fe.args...); ok {
+			fe.Mensaje = m
+		}
+		out[i] = fe
+	}
+	return out
+}
@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"reflect"
+	"sync"
+)
+
+// LinkTag is the struct tag marking fields whose stored value must be turned into a client-facing
+// link before the value is sent, e.g. `link:"file"` on a storage ref.
+const LinkTag = "link"
+
+// Link kinds understood by RewriteLinks.
+const (
+	LinkFile = "file" // Storage ref of an uploaded file
+)
+
+var (
+	linkMu        sync.RWMutex
+	linkRewriters = map[string]func(string) string{}
+)
+
+// RegisterLinkRewriter sets the function turning a stored value of the given kind into a link.
+// It returns "" when no link can be built (the field is then cleared).
+func RegisterLinkRewriter(kind string, fn func(string) string) {
+	linkMu.Lock()
+	defer linkMu.Unlock()
+	linkRewriters[kind] = fn
+}
+
+func linkRewriter(kind string) func(string) string {
+	linkMu.RLock()
+	defer linkMu.RUnlock()
+	return linkRewriters[kind]
+}
+
+// RewriteLinks replaces, anywhere inside v, the string and *string fields tagged with LinkTag by the
+// link built by the rewriter registered for their kind. v must be a pointer (or contain pointers,
+// slices or maps) for the changes to be visible; values inside maps and interfaces are copied,
+// rewritten and stored back. *string fields are set to a new pointer (nil when there is no link),
+// so the original string is never modified.
+func RewriteLinks(v interface{}) {
+	rewriteValue(reflect.ValueOf(v), map[uintptr]bool{})
+}
+
+// withLinks returns v with its links rewritten. Values that are not pointers are copied first so
+// their fields can be set.
+func withLinks(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		RewriteLinks(v)
+		return v
+	}
+	if !needsRewrite(rv.Type(), map[reflect.Type]bool{}) {
+		return v
+	}
+	copia := reflect.New(rv.Type())
+	copia.Elem().Set(rv)
+	RewriteLinks(copia.Interface())
+	return copia.Elem().Interface()
+}
+
+func rewriteValue(v reflect.Value, seen map[uintptr]bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		// Guard against cycles and pointers shared between several fields
+		if seen[v.Pointer()] {
+			return
+		}
+		seen[v.Pointer()] = true
+		rewriteValue(v.Elem(), seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		elem := v.Elem()
+		switch elem.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map:
+			// Reference types: their contents can be rewritten in place
+			rewriteValue(elem, seen)
+			return
+		}
+		if !v.CanSet() || !needsRewrite(elem.Type(), map[reflect.Type]bool{}) {
+			return
+		}
+		copia := reflect.New(elem.Type()).Elem()
+		copia.Set(elem)
+		rewriteValue(copia, seen)
+		v.Set(copia)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if kind, ok := field.Tag.Lookup(LinkTag); ok {
+				rewriteField(v.Field(i), kind)
+				continue
+			}
+			rewriteValue(v.Field(i), seen)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return
+		}
+		if !needsRewrite(v.Type().Elem(), map[reflect.Type]bool{}) {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			rewriteValue(v.Index(i), seen)
+		}
+	case reflect.Map:
+		if v.IsNil() || !needsRewrite(v.Type().Elem(), map[reflect.Type]bool{}) {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			copia := reflect.New(v.Type().Elem()).Elem()
+			copia.Set(iter.Value())
+			rewriteValue(copia, seen)
+			v.SetMapIndex(iter.Key(), copia)
+		}
+	}
+}
+
+// rewriteField rewrites one tagged string or *string field.
+func rewriteField(f reflect.Value, kind string) {
+	fn := linkRewriter(kind)
+	if fn == nil || !f.CanSet() {
+		return
+	}
+	switch {
+	case f.Kind() == reflect.String:
+		if f.String() != "" {
+			f.SetString(fn(f.String()))
+		}
+	case f.Kind() == reflect.Ptr && f.Type().Elem().Kind() == reflect.String:
+		if f.IsNil() || f.Elem().String() == "" {
+			f.Set(reflect.Zero(f.Type()))
+			return
+		}
+		link := fn(f.Elem().String())
+		if link == "" {
+			f.Set(reflect.Zero(f.Type()))
+			return
+		}
+		f.Set(reflect.ValueOf(&link).Convert(f.Type()))
+	}
+}
+
+// needsRewrite reports whether values of type t may contain tagged fields, so slices and maps of
+// plain values are not walked element by element.
+func needsRewrite(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[t] {
+		return false // Recursive type: decided by the other fields
+	}
+	visiting[t] = true
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return needsRewrite(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if _, ok := field.Tag.Lookup(LinkTag); ok || needsRewrite(field.Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+)
+
+// MarshalRespuesta encodes v as RespondJSON encodes a response body, without the envelope: links
+// rewritten, private fields left out for anonymous callers, and the configured date format and
+// null fields. Writers that build the body piece by piece use it for each piece.
+func MarshalRespuesta(w http.ResponseWriter, v interface{}) ([]byte, error) {
+	return codificarRespuesta(w, v, false)
+}
+
+// codificarRespuesta is the encoding every successful JSON response goes through, whole
+// (RespondJSON, RespondJSONWithETag) or piece by piece (MarshalRespuesta, ListaJSON), so the
+// configured Serialization applies to all of them. conEnvoltura applies the envelope to v.
+func codificarRespuesta(w http.ResponseWriter, v interface{}, conEnvoltura bool) ([]byte, error) {
+	v = withLinks(paraRespuesta(w, v))
+	if conEnvoltura {
+		v = envolver(v)
+	}
+	return marshalRespuesta(v)
+}
+
+// ListaJSON writes a paginated list one record at a time, in the shape RespondJSON gives a
+// models.PaginatedResponse under the configured envelope: {"data": [...], "pagination": {...}},
+// or the bare array with EnvolturaNinguna (the pagination then only goes in the headers).
+type ListaJSON struct {
+	w        http.ResponseWriter
+	envuelta bool
+	n        int
+}
+
+// NewListaJSON sends status 200 and the opening of the list. Headers must be set before.
+func NewListaJSON(w http.ResponseWriter) (*ListaJSON, error) {
+	l := &ListaJSON{w: w, envuelta: CurrentSerialization().Envoltura != EnvolturaNinguna}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	apertura := "["
+	if l.envuelta {
+		apertura = `{"data":[`
+	}
+	_, err := io.WriteString(w, apertura)
+	return l, err
+}
+
+// Escribir writes the next record.
+func (l *ListaJSON) Escribir(v interface{}) error {
+	b, err := MarshalRespuesta(l.w, v)
+	if err != nil {
+		return err
+	}
+	if l.n > 0 {
+		b = append([]byte{','}, b...)
+	}
+	l.n++
+	_, err = l.w.Write(b)
+	return err
+}
+
+// Cerrar writes the pagination, when the envelope has it, and the end of the list.
+func (l *ListaJSON) Cerrar(pagination interface{}) error {
+	if !l.envuelta {
+		_, err := io.WriteString(l.w, "]\n")
+		return err
+	}
+	b, err := MarshalRespuesta(l.w, pagination)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(l.w, `],"pagination":`); err != nil {
+		return err
+	}
+	if _, err := l.w.Write(b); err != nil {
+		return err
+	}
+	_, err = io.WriteString(l.w, "}\n")
+	return err
+}
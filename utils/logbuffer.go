@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"strings"
+	"sync"
+)
+
+// LogBuffer keeps the most recent log lines in memory so they can be included in support bundles.
+// It implements io.Writer and is meant to be combined with os.Stderr via io.MultiWriter.
+type LogBuffer struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+// NewLogBuffer creates a buffer that retains up to size lines.
+func NewLogBuffer(size int) *LogBuffer {
+	return &LogBuffer{lines: make([]string, size)}
+}
+
+// RecentLogs is the process-wide buffer the logger set up by main also writes to.
+var RecentLogs = NewLogBuffer(500)
+
+// Write stores each line written by the logger.
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		b.lines[b.next] = line
+		b.next = (b.next + 1) % len(b.lines)
+		if b.next == 0 {
+			b.full = true
+		}
+	}
+	return len(p), nil
+}
+
+// Lines returns the retained lines, oldest first.
+func (b *LogBuffer) Lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.full {
+		return append([]string(nil), b.lines[:b.next]...)
+	}
+	return append(append([]string(nil), b.lines[b.next:]...), b.lines[:b.next]...)
+}
+
+// ErrorLines returns the retained lines that look like errors or warnings, oldest first.
+func (b *LogBuffer) ErrorLines() []string {
+	var result []string
+	for _, line := range b.Lines() {
+		lower := strings.ToLower(line)
+		if strings.Contains(lower, "error") || strings.Contains(lower, "fatal") ||
+			strings.Contains(lower, "warning") || strings.Contains(lower, "advertencia") {
+			result = append(result, line)
+		}
+	}
+	return result
+}
@@ -8,16 +8,19 @@ import (
 // GetPaginationParams parses page and limit query parameters from a request.
 // Returns page (default 1) and limit (default 6, max 100).
 func GetPaginationParams(r *http.Request) (page, limit int) {
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
+	// Atoi returns 0 for missing or invalid values, which NormalizePagination replaces by the defaults
+	page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+	return NormalizePagination(page, limit)
+}
 
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
+// NormalizePagination applies the defaults and bounds of GetPaginationParams to page and limit
+// received some other way (e.g. in a gRPC request).
+func NormalizePagination(page, limit int) (int, int) {
+	if page < 1 {
 		page = 1 // Default to page 1
 	}
-
-	limit, err = strconv.Atoi(limitStr)
-	if err != nil || limit < 1 {
+	if limit < 1 {
 		limit = 6 // Default to 6 items per page
 	}
 	if limit > 100 { // Optional: Max limit
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// PublicoTag is the struct tag marking fields left out of the responses to anonymous callers (the
+// public tier), e.g. `publico:"omitir"` on an investigator's email. Handlers pass the same values
+// to everyone and RespondJSON shapes them for the tier of the response.
+const PublicoTag = "publico"
+
+// publicWriter marks a response as going to an anonymous caller. It sets the Cache-Control of
+// successful responses that the handler did not set itself, so shared caches may keep them.
+type publicWriter struct {
+	http.ResponseWriter
+	maxAge int
+	wrote  bool
+}
+
+// NewPublicWriter marks w as the response to an anonymous caller (see EsRespuestaPublica).
+// Successful responses may be cached by shared caches for maxAge seconds (none if 0).
+func NewPublicWriter(w http.ResponseWriter, maxAge int) http.ResponseWriter {
+	return &publicWriter{ResponseWriter: w, maxAge: maxAge}
+}
+
+func (p *publicWriter) WriteHeader(status int) {
+	if !p.wrote {
+		p.wrote = true
+		if p.Header().Get("Cache-Control") == "" {
+			if (status < 300 || status == http.StatusNotModified) && p.maxAge > 0 {
+				p.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", p.maxAge))
+			} else {
+				p.Header().Set("Cache-Control", "no-store")
+			}
+		}
+	}
+	p.ResponseWriter.WriteHeader(status)
+}
+
+func (p *publicWriter) Write(b []byte) (int, error) {
+	if !p.wrote {
+		p.WriteHeader(http.StatusOK)
+	}
+	return p.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (p *publicWriter) Unwrap() http.ResponseWriter {
+	return p.ResponseWriter
+}
+
+// EsRespuestaPublica reports whether w is the response to an anonymous caller, looking through
+// writers that wrap it.
+func EsRespuestaPublica(w http.ResponseWriter) bool {
+	for w != nil {
+		if _, ok := w.(*publicWriter); ok {
+			return true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = u.Unwrap()
+	}
+	return false
+}
+
+// paraRespuesta returns v as it must be sent through w: a copy without the fields tagged with
+// PublicoTag for anonymous callers, v itself otherwise.
+func paraRespuesta(w http.ResponseWriter, v interface{}) interface{} {
+	if v == nil || !EsRespuestaPublica(w) {
+		return v
+	}
+	return SinCamposPrivados(v)
+}
+
+// SinCamposPrivados returns a copy of v with every field tagged with PublicoTag set to its zero
+// value. Only the parts of v that contain such fields are copied, so v itself, which may be shared
+// (e.g. by the directory cache), is never modified.
+func SinCamposPrivados(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if !tienePrivados(rv.Type(), map[reflect.Type]bool{}) {
+		return v
+	}
+	return sinPrivados(rv).Interface()
+}
+
+func sinPrivados(v reflect.Value) reflect.Value {
+	if !tienePrivados(v.Type(), map[reflect.Type]bool{}) {
+		return v
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		copia := reflect.New(v.Type().Elem())
+		copia.Elem().Set(sinPrivados(v.Elem()))
+		return copia
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		copia := reflect.New(v.Type()).Elem()
+		copia.Set(sinPrivados(v.Elem()))
+		return copia
+	case reflect.Struct:
+		copia := reflect.New(v.Type()).Elem()
+		copia.Set(v)
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if _, ok := field.Tag.Lookup(PublicoTag); ok {
+				copia.Field(i).Set(reflect.Zero(field.Type))
+				continue
+			}
+			copia.Field(i).Set(sinPrivados(v.Field(i)))
+		}
+		return copia
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		copia := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copia.Index(i).Set(sinPrivados(v.Index(i)))
+		}
+		return copia
+	case reflect.Array:
+		copia := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			copia.Index(i).Set(sinPrivados(v.Index(i)))
+		}
+		return copia
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		copia := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copia.SetMapIndex(iter.Key(), sinPrivados(iter.Value()))
+		}
+		return copia
+	}
+	return v
+}
+
+// tienePrivados reports whether values of type t may contain fields tagged with PublicoTag.
+func tienePrivados(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[t] {
+		return false // Recursive type: decided by the other fields
+	}
+	visiting[t] = true
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return tienePrivados(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if _, ok := field.Tag.Lookup(PublicoTag); ok || tienePrivados(field.Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
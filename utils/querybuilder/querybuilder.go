@@ -0,0 +1,146 @@
+// Package querybuilder builds the dynamic parts of SQL queries from request parameters: WHERE
+// clauses whose placeholders are numbered as conditions are added, and sort and filter keys mapped
+// to columns through a whitelist, so no SQL text ever comes from the request.
+//
+// Queries use PostgreSQL placeholders ($1, $2...); the SQLite dialect translates them as any
+// other query of the repository.
+package querybuilder
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrClaveNoPermitida is returned for a sort or filter key that is not in the Columnas.
+var ErrClaveNoPermitida = errors.New("sort or filter key not allowed")
+
+// Columnas maps the keys a request may sort or filter by to the SQL expression of each (e.g.
+// "fechaRegistro" to "g.fechaRegistro"). Keys are case-sensitive.
+type Columnas map[string]string
+
+// Columna returns the SQL expression of key, or ErrClaveNoPermitida.
+func (c Columnas) Columna(clave string) (string, error) {
+	col, ok := c[clave]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrClaveNoPermitida, clave)
+	}
+	return col, nil
+}
+
+// Orden returns the ORDER BY list (without the keywords) for a sort parameter: keys separated by
+// commas, each descending with a leading "-" (e.g. "-fechaRegistro,nombre"). An empty parameter
+// is an empty list.
+func (c Columnas) Orden(param string) (string, error) {
+	var partes []string
+	for _, clave := range strings.Split(param, ",") {
+		clave = strings.TrimSpace(clave)
+		if clave == "" {
+			continue
+		}
+		dir := ""
+		if desc, ok := strings.CutPrefix(clave, "-"); ok {
+			clave, dir = desc, " DESC"
+		}
+		col, err := c.Columna(clave)
+		if err != nil {
+			return "", err
+		}
+		partes = append(partes, col+dir)
+	}
+	return strings.Join(partes, ", "), nil
+}
+
+// Where collects the conditions of a WHERE clause and their arguments. The zero value has no
+// conditions and numbers placeholders from $1.
+type Where struct {
+	condiciones []string
+	args        []interface{}
+}
+
+// Add adds a condition taking args. cond marks with ? the placeholder of each argument, in order,
+// or with ?n that of the n-th argument of the call, to use it more than once:
+//
+//	w.Add(`g.estado = ?`, estado)
+//	w.Add(`(nombre ILIKE ?1 OR apellido ILIKE ?1)`, patron)
+//
+// Each mark becomes the $ placeholder of its argument among all of w's. The rest of cond is kept as
+// is, ? inside quoted literals included; a mark without its argument is a bug and panics.
+func (w *Where) Add(cond string, args ...interface{}) {
+	w.condiciones = append(w.condiciones, numerar(cond, len(w.args), len(args)))
+	w.args = append(w.args, args...)
+}
+
+// numerar replaces the marks of cond (see Add) with the placeholders $base+1 to $base+n.
+func numerar(cond string, base, n int) string {
+	var b strings.Builder
+	siguiente := 0
+	enLiteral := false
+	for i := 0; i < len(cond); i++ {
+		c := cond[i]
+		if c == '\'' {
+			enLiteral = !enLiteral
+		}
+		if c != '?' || enLiteral {
+			b.WriteByte(c)
+			continue
+		}
+		j := i + 1
+		for j < len(cond) && cond[j] >= '0' && cond[j] <= '9' {
+			j++
+		}
+		var k int
+		if j > i+1 {
+			k, _ = strconv.Atoi(cond[i+1 : j])
+		} else {
+			siguiente++
+			k = siguiente
+		}
+		if k < 1 || k > n {
+			panic(fmt.Sprintf("querybuilder: %q has no argument %d (got %d)", cond, k, n))
+		}
+		b.WriteString("$" + strconv.Itoa(base+k))
+		i = j - 1
+	}
+	return b.String()
+}
+
+// Arg adds an argument used outside the conditions (e.g. in the select list or LIMIT) and returns
+// its placeholder.
+func (w *Where) Arg(v interface{}) string {
+	w.args = append(w.args, v)
+	return fmt.Sprintf("$%d", len(w.args))
+}
+
+// Next returns the placeholder of the i-th argument (from 1) that the caller will append after
+// Args, such as LIMIT and OFFSET values known only when the query runs.
+func (w *Where) Next(i int) string {
+	return fmt.Sprintf("$%d", len(w.args)+i)
+}
+
+// Empty reports whether no condition was added.
+func (w *Where) Empty() bool {
+	return len(w.condiciones) == 0
+}
+
+// SQL returns " WHERE " and the conditions joined with AND, or "" without conditions.
+func (w *Where) SQL() string {
+	if w.Empty() {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.condiciones, " AND ")
+}
+
+// And returns the conditions each preceded by " AND ", to follow a fixed condition of the query.
+func (w *Where) And() string {
+	if w.Empty() {
+		return ""
+	}
+	return " AND " + strings.Join(w.condiciones, " AND ")
+}
+
+// Args returns the arguments added so far. Adding more later does not change the returned slice.
+func (w *Where) Args() []interface{} {
+	return w.args[:len(w.args):len(w.args)]
+}
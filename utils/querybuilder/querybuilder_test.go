@@ -0,0 +1,176 @@
+package querybuilder
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWhereAdd(t *testing.T) {
+	type condicion struct {
+		cond string
+		args []interface{}
+	}
+	tests := []struct {
+		name        string
+		condiciones []condicion
+		wantSQL     string
+		wantArgs    []interface{}
+	}{
+		{
+			name:    "no conditions",
+			wantSQL: "",
+		},
+		{
+			name:        "no arguments",
+			condiciones: []condicion{{cond: "g.deletedAt IS NULL"}},
+			wantSQL:     " WHERE g.deletedAt IS NULL",
+		},
+		{
+			name: "numbered across conditions",
+			condiciones: []condicion{
+				{cond: "g.deletedAt IS NULL"},
+				{cond: "g.estado = ?", args: []interface{}{"activo"}},
+				{cond: "g.fechaVencimiento >= ? AND g.fechaVencimiento < ?", args: []interface{}{"2026-01-01", "2026-03-01"}},
+				{cond: "g.idFacultad = ANY(?)", args: []interface{}{[]int{1, 2}}},
+			},
+			wantSQL:  " WHERE g.deletedAt IS NULL AND g.estado = $1 AND g.fechaVencimiento >= $2 AND g.fechaVencimiento < $3 AND g.idFacultad = ANY($4)",
+			wantArgs: []interface{}{"activo", "2026-01-01", "2026-03-01", []int{1, 2}},
+		},
+		{
+			name: "argument used twice",
+			condiciones: []condicion{
+				{cond: "tipo = ?", args: []interface{}{"docente"}},
+				{cond: "(nombre ILIKE ?1 OR apellido ILIKE ?1)", args: []interface{}{"%ana%"}},
+			},
+			wantSQL:  " WHERE tipo = $1 AND (nombre ILIKE $2 OR apellido ILIKE $2)",
+			wantArgs: []interface{}{"docente", "%ana%"},
+		},
+		{
+			name: "numbered mark followed by a cast",
+			condiciones: []condicion{
+				{cond: "gi.idGrupo = ?", args: []interface{}{7}},
+				{cond: "(gi.fechaInicio IS NULL OR gi.fechaInicio <= ?1::date) AND (gi.fechaFin IS NULL OR gi.fechaFin >= ?1::date)", args: []interface{}{"2026-10-18"}},
+			},
+			wantSQL:  " WHERE gi.idGrupo = $1 AND (gi.fechaInicio IS NULL OR gi.fechaInicio <= $2::date) AND (gi.fechaFin IS NULL OR gi.fechaFin >= $2::date)",
+			wantArgs: []interface{}{7, "2026-10-18"},
+		},
+		{
+			name: "percent signs are kept",
+			condiciones: []condicion{
+				{cond: "g.nombre NOT LIKE '%prueba%'"},
+				{cond: "to_char(g.fechaRegistro, 'YYYY') = ? AND strftime('%d', g.fechaRegistro) <> '01'", args: []interface{}{"2024"}},
+			},
+			wantSQL:  " WHERE g.nombre NOT LIKE '%prueba%' AND to_char(g.fechaRegistro, 'YYYY') = $1 AND strftime('%d', g.fechaRegistro) <> '01'",
+			wantArgs: []interface{}{"2024"},
+		},
+		{
+			name: "question marks in literals are kept",
+			condiciones: []condicion{
+				{cond: "g.descripcion <> '¿?' AND g.estado = ?", args: []interface{}{"activo"}},
+			},
+			wantSQL:  " WHERE g.descripcion <> '¿?' AND g.estado = $1",
+			wantArgs: []interface{}{"activo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w Where
+			for _, c := range tt.condiciones {
+				w.Add(c.cond, c.args...)
+			}
+			if got := w.SQL(); got != tt.wantSQL {
+				t.Errorf("SQL() = %q, want %q", got, tt.wantSQL)
+			}
+			if got := w.Args(); !reflect.DeepEqual(got, tt.wantArgs) {
+				t.Errorf("Args() = %v, want %v", got, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestWhereArgAndNext(t *testing.T) {
+	var w Where
+	w.Add("g.deletedAt IS NULL")
+	consulta := w.Arg("salud")
+	w.Add("g.busqueda @@ websearch_to_tsquery('es_unaccent', " + consulta + ")")
+	w.Add("g.estado = ?", "activo")
+
+	if consulta != "$1" {
+		t.Errorf("Arg() = %q, want $1", consulta)
+	}
+	want := " AND g.deletedAt IS NULL AND g.busqueda @@ websearch_to_tsquery('es_unaccent', $1) AND g.estado = $2"
+	if got := w.And(); got != want {
+		t.Errorf("And() = %q, want %q", got, want)
+	}
+	if got, want := w.Next(1)+" "+w.Next(2), "$3 $4"; got != want {
+		t.Errorf("Next(1), Next(2) = %q, want %q", got, want)
+	}
+}
+
+func TestWhereAddMissingArgument(t *testing.T) {
+	tests := []struct {
+		name string
+		cond string
+		args []interface{}
+	}{
+		{"more marks than arguments", "a = ? AND b = ?", []interface{}{1}},
+		{"numbered mark out of range", "a = ?2", []interface{}{1}},
+		{"numbered mark zero", "a = ?0", []interface{}{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Add(%q) did not panic", tt.cond)
+				}
+			}()
+			var w Where
+			w.Add(tt.cond, tt.args...)
+		})
+	}
+}
+
+func TestColumnasOrden(t *testing.T) {
+	columnas := Columnas{
+		"nombre":        "g.nombre",
+		"fechaRegistro": "g.fechaRegistro",
+	}
+	tests := []struct {
+		param   string
+		want    string
+		wantErr bool
+	}{
+		{param: "", want: ""},
+		{param: "nombre", want: "g.nombre"},
+		{param: "-fechaRegistro", want: "g.fechaRegistro DESC"},
+		{param: "-fechaRegistro, nombre", want: "g.fechaRegistro DESC, g.nombre"},
+		{param: "nombre,,", want: "g.nombre"},
+		{param: "Nombre", wantErr: true},
+		{param: "createdAt", wantErr: true},
+		{param: "nombre,idGrupo", wantErr: true},
+		{param: "nombre; DROP TABLE grupo", wantErr: true},
+		{param: "g.nombre", wantErr: true},
+		{param: "--nombre", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.param, func(t *testing.T) {
+			got, err := columnas.Orden(tt.param)
+			if tt.wantErr {
+				if !errors.Is(err, ErrClaveNoPermitida) {
+					t.Errorf("Orden(%q) error = %v, want ErrClaveNoPermitida", tt.param, err)
+				}
+				if got != "" {
+					t.Errorf("Orden(%q) = %q, want no SQL with an error", tt.param, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Orden(%q) error = %v", tt.param, err)
+			}
+			if got != tt.want {
+				t.Errorf("Orden(%q) = %q, want %q", tt.param, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ClientIP returns the caller's IP address, without the port.
+func ClientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// PublicBaseURL returns PUBLIC_BASE_URL without a trailing slash, or "" if it is not set. Links
+// sent by email are built only from it: the Host header is chosen by the caller, and a forged one
+// would send the link's token to another site.
+func PublicBaseURL() string {
+	return strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/")
+}
+
+// BaseURL returns the public base URL of the API (PUBLIC_BASE_URL, or derived from the request),
+// without a trailing slash. It is used to build absolute links returned to clients in the response;
+// emailed links use PublicBaseURL.
+func BaseURL(r *http.Request) string {
+	if base := PublicBaseURL(); base != "" {
+		return base
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// QueryValues returns all values of a query parameter, accepting both repeated parameters
+// (?a=1&a=2) and comma-separated lists (?a=1,2). Blank values are dropped.
+func QueryValues(r *http.Request, key string) []string {
+	var values []string
+	for _, raw := range r.URL.Query()[key] {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
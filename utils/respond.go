@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/i18n"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/version"
+)
+
+// ErrorResponse is the JSON envelope returned for every API error: {code, message, messageCode,
+// details}. The HTTP status is the one of the response, and the build that produced the error goes
+// in the X-API-Version header.
+type ErrorResponse struct {
+	Code        string      `json:"code"` // Stable code clients can switch on, see ErrorCode
+	Message     string      `json:"message"`
+	MessageCode string      `json:"messageCode,omitempty"` // Stable code of the message in the i18n catalog, if it is there
+	Details     interface{} `json:"details,omitempty"`     // E.g. the offending fields, as []FieldError
+
+	// Deprecated: same as Message, kept for clients written before the code field. It will be
+	// removed; read message instead.
+	Error string `json:"error"`
+
+	args []interface{} // Arguments of the catalog message
+}
+
+// NewErrorResponse builds the envelope of an error with the given status. A message in the i18n
+// catalog, in any of its wordings, gets its code and is localized when written.
+func NewErrorResponse(message string, status int, details interface{}) ErrorResponse {
+	return ErrorResponse{
+		Code:        ErrorCode(status),
+		Message:     message,
+		MessageCode: i18n.Codigo(message),
+		Details:     details,
+		Error:       message,
+	}
+}
+
+// NewErrorResponseCode builds the envelope of an error whose message is the one of code in the
+// i18n catalog, formatted with args.
+func NewErrorResponseCode(code string, status int, details interface{}, args ...interface{}) ErrorResponse {
+	message, _ := i18n.Mensaje(i18n.Default, code, args...)
+	e := NewErrorResponse(message, status, details)
+	e.MessageCode, e.args = code, args
+	return e
+}
+
+// ErrorCode returns the code of an error status: "not_found" (404), "conflict" (409),
+// "validation" (422), "internal" (500)... and the snake_case status text for the rest.
+func ErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "bad_request"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusUnprocessableEntity:
+		return "validation"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	case http.StatusInternalServerError:
+		return "internal"
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// RespondError writes a JSON error envelope. It mirrors http.Error's signature.
+func RespondError(w http.ResponseWriter, message string, status int) {
+	e := NewErrorResponse(message, status, nil)
+	writeError(w, status, &e)
+}
+
+// RespondErrorCode writes a JSON error envelope with the message of code in the i18n catalog,
+// formatted with args.
+func RespondErrorCode(w http.ResponseWriter, code string, status int, args ...interface{}) {
+	e := NewErrorResponseCode(code, status, nil, args...)
+	writeError(w, status, &e)
+}
+
+// RespondErrorDetails writes a JSON error envelope with details.
+func RespondErrorDetails(w http.ResponseWriter, message string, status int, details interface{}) {
+	e := NewErrorResponse(message, status, details)
+	writeError(w, status, &e)
+}
+
+// RespondBodyTooLarge answers 413 with the body limit, in bytes, in the details.
+func RespondBodyTooLarge(w http.ResponseWriter, limit int64) {
+	e := NewErrorResponseCode("cuerpo_demasiado_grande", http.StatusRequestEntityTooLarge, map[string]int64{"limiteBytes": limit}, limit)
+	writeError(w, http.StatusRequestEntityTooLarge, &e)
+}
+
+// BodyTooLarge reports whether err comes from reading past the limit of a body capped with
+// http.MaxBytesReader, and returns that limit.
+func BodyTooLarge(err error) (int64, bool) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return tooLarge.Limit, true
+	}
+	return 0, false
+}
+
+// RespondDecodeError answers a request whose body could not be decoded: 413 if the body went past
+// its size limit, 400 with message otherwise.
+func RespondDecodeError(w http.ResponseWriter, err error, message string) {
+	if limit, ok := BodyTooLarge(err); ok {
+		RespondBodyTooLarge(w, limit)
+		return
+	}
+	RespondError(w, message, http.StatusBadRequest)
+}
+
+// Localizable is an error envelope: ErrorResponse, or a struct embedding it with more fields (e.g.
+// the records a conflict is about).
+type Localizable interface {
+	Localize(http.ResponseWriter)
+}
+
+// RespondErrorEnvelope writes an error envelope built by the caller, localized for w. Unlike
+// RespondJSON it ignores the Serialization of successful responses.
+func RespondErrorEnvelope(w http.ResponseWriter, status int, e Localizable) {
+	writeError(w, status, e)
+}
+
+// writeError writes an error envelope localized for w, with the build in X-API-Version.
+func writeError(w http.ResponseWriter, status int, e Localizable) {
+	e.Localize(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-API-Version", version.String())
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(e)
+}
+
+// RespondJSON writes v as JSON with the given status. Every field tagged with LinkTag is rewritten
+// to its client-facing link first, and anonymous callers get v without the fields tagged with
+// PublicoTag, so handlers pass the values as loaded from the database. The envelope, the date
+// format and the null fields follow the configured Serialization.
+func RespondJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := codificarRespuesta(w, v, true)
+	if err != nil {
+		RespondError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+// FieldError describes a problem with one field of the request body. A Mensaje in the i18n
+// catalog is localized when written.
+type FieldError struct {
+	Campo   string `json:"campo"`
+	Codigo  string `json:"codigo"` // Stable code clients can switch on, e.g. "email_duplicado"
+	Mensaje string `json:"mensaje"`
+
+	clave string        // Code of Mensaje in the i18n catalog, when built with NewFieldError
+	args  []interface{} // Arguments of that message
+}
+
+// NewFieldError builds the error of a field with the message clave of the i18n catalog, formatted
+// with args.
+func NewFieldError(campo, codigo, clave string, args ...interface{}) FieldError {
+	mensaje, _ := i18n.Mensaje(i18n.Default, clave, args...)
+	return FieldError{Campo: campo, Codigo: codigo, Mensaje: mensaje, clave: clave, args: args}
+}
+
+// RespondFieldErrors writes a JSON error envelope with the offending fields in details.
+func RespondFieldErrors(w http.ResponseWriter, message string, status int, errs ...FieldError) {
+	e := NewErrorResponse(message, status, errs)
+	writeError(w, status, &e)
+}
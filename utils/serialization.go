@@ -0,0 +1,350 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+)
+
+// FechaTag is the struct tag marking time.Time and calendario.Fecha fields (or pointers to them)
+// that hold a calendar date (a DATE column), e.g. `fecha:"dia"`. They are written as
+// Serialization.FormatoFecha says.
+const FechaTag = "fecha"
+
+// Formats of the date fields (see FechaTag) in responses, JSON_DATE_FORMAT.
+const (
+	FormatoFechaRFC3339 = "rfc3339" // 2024-03-15T00:00:00Z, like every timestamp (default)
+	FormatoFechaDia     = "date"    // 2024-03-15, as requests send them
+)
+
+// Envelopes of successful responses, JSON_ENVELOPE.
+const (
+	EnvolturaMixta   = "mixed" // Paginated lists in {"data", "pagination"}, everything else bare (default)
+	EnvolturaData    = "data"  // Every response in {"data": ...}
+	EnvolturaNinguna = "none"  // Nothing wrapped: paginated lists send the array, pagination goes in headers
+)
+
+// Serialization is how successful JSON responses are written: by RespondJSON and
+// RespondJSONWithETag, and by the streaming writers through MarshalRespuesta and ListaJSON. Error
+// responses keep their format.
+type Serialization struct {
+	FormatoFecha string // FormatoFechaRFC3339 or FormatoFechaDia
+	OmitirNulos  bool   // Leave out object fields whose value is null
+	Envoltura    string // EnvolturaMixta, EnvolturaData or EnvolturaNinguna
+}
+
+// Envuelta is implemented by responses that already carry their records in a "data" field
+// (paginated lists, batches, the changes feed): EnvolturaData does not wrap them again.
+type Envuelta interface {
+	// SinEnvoltura returns what EnvolturaNinguna sends instead, and false when the other fields
+	// of the response can't be left out (e.g. a cursor).
+	SinEnvoltura() (interface{}, bool)
+}
+
+var serializacion atomic.Pointer[Serialization]
+
+// SerializationFromEnv reads JSON_DATE_FORMAT (rfc3339 or date), JSON_OMIT_NULL (true or false)
+// and JSON_ENVELOPE (mixed, data or none). Unset variables keep the defaults, which are the
+// API's original format; invalid values are an error.
+func SerializationFromEnv() (Serialization, error) {
+	s := Serialization{FormatoFecha: FormatoFechaRFC3339, Envoltura: EnvolturaMixta}
+	switch v := strings.ToLower(os.Getenv("JSON_DATE_FORMAT")); v {
+	case "", FormatoFechaRFC3339:
+	case FormatoFechaDia:
+		s.FormatoFecha = v
+	default:
+		return s, fmt.Errorf("JSON_DATE_FORMAT must be %s or %s, not %q", FormatoFechaRFC3339, FormatoFechaDia, v)
+	}
+	if v := os.Getenv("JSON_OMIT_NULL"); v != "" {
+		omitir, err := strconv.ParseBool(v)
+		if err != nil {
+			return s, fmt.Errorf("JSON_OMIT_NULL must be true or false, not %q", v)
+		}
+		s.OmitirNulos = omitir
+	}
+	switch v := strings.ToLower(os.Getenv("JSON_ENVELOPE")); v {
+	case "", EnvolturaMixta:
+	case EnvolturaData, EnvolturaNinguna:
+		s.Envoltura = v
+	default:
+		return s, fmt.Errorf("JSON_ENVELOPE must be %s, %s or %s, not %q", EnvolturaMixta, EnvolturaData, EnvolturaNinguna, v)
+	}
+	return s, nil
+}
+
+// SetSerialization sets how responses are written from now on.
+func SetSerialization(s Serialization) {
+	serializacion.Store(&s)
+}
+
+// CurrentSerialization returns how responses are written.
+func CurrentSerialization() Serialization {
+	if s := serializacion.Load(); s != nil {
+		return *s
+	}
+	return Serialization{FormatoFecha: FormatoFechaRFC3339, Envoltura: EnvolturaMixta}
+}
+
+// envolver applies the configured envelope to the body of a successful response.
+func envolver(v interface{}) interface{} {
+	switch CurrentSerialization().Envoltura {
+	case EnvolturaData:
+		if _, ok := v.(Envuelta); !ok {
+			return struct {
+				Data interface{} `json:"data"`
+			}{v}
+		}
+	case EnvolturaNinguna:
+		if e, ok := v.(Envuelta); ok {
+			if datos, ok := e.SinEnvoltura(); ok {
+				return datos
+			}
+		}
+	}
+	return v
+}
+
+// marshalRespuesta encodes v as JSON with the configured date format and null fields. With the
+// defaults it is json.Marshal; otherwise the encoded value is decoded into a tree (keeping the
+// order of the fields), adjusted walking v alongside it, and encoded again.
+func marshalRespuesta(v interface{}) ([]byte, error) {
+	s := CurrentSerialization()
+	b, err := json.Marshal(v)
+	if err != nil || (s.FormatoFecha == FormatoFechaRFC3339 && !s.OmitirNulos) {
+		return b, err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	nodo, err := leerNodo(d)
+	if err != nil {
+		return nil, err
+	}
+	if s.FormatoFecha == FormatoFechaDia {
+		nodo = conFechas(reflect.ValueOf(v), nodo)
+	}
+	if s.OmitirNulos {
+		nodo = sinNulos(nodo)
+	}
+	return json.Marshal(nodo)
+}
+
+// objetoJSON is a decoded JSON object that keeps the order of its fields.
+type objetoJSON []campoJSON
+
+type campoJSON struct {
+	clave string
+	valor interface{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o objetoJSON) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, c := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		clave, err := json.Marshal(c.clave)
+		if err != nil {
+			return nil, err
+		}
+		valor, err := json.Marshal(c.valor)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(clave)
+		buf.WriteByte(':')
+		buf.Write(valor)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// leerNodo decodes the next JSON value: objetoJSON, []interface{}, string, json.Number, bool or nil.
+func leerNodo(d *json.Decoder) (interface{}, error) {
+	t, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := t.(json.Delim)
+	if !ok {
+		return t, nil
+	}
+	if delim == '[' {
+		arreglo := []interface{}{}
+		for d.More() {
+			v, err := leerNodo(d)
+			if err != nil {
+				return nil, err
+			}
+			arreglo = append(arreglo, v)
+		}
+		_, err := d.Token()
+		return arreglo, err
+	}
+	objeto := objetoJSON{}
+	for d.More() {
+		clave, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		v, err := leerNodo(d)
+		if err != nil {
+			return nil, err
+		}
+		objeto = append(objeto, campoJSON{clave: clave.(string), valor: v})
+	}
+	_, err = d.Token()
+	return objeto, err
+}
+
+// conFechas rewrites, in the tree decoded from v, the fields tagged with FechaTag as 2006-01-02.
+func conFechas(v reflect.Value, nodo interface{}) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nodo
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		objeto, ok := nodo.(objetoJSON)
+		if !ok {
+			return nodo
+		}
+		campos := camposJSON(v.Type())
+		for i, c := range objeto {
+			campo, ok := campos[c.clave]
+			if !ok {
+				continue
+			}
+			f, err := v.FieldByIndexErr(campo.indice)
+			if err != nil {
+				continue
+			}
+			if campo.fecha {
+				objeto[i].valor = fechaDia(f, c.valor)
+			} else {
+				objeto[i].valor = conFechas(f, c.valor)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		arreglo, ok := nodo.([]interface{})
+		if !ok || len(arreglo) != v.Len() {
+			return nodo
+		}
+		for i := range arreglo {
+			arreglo[i] = conFechas(v.Index(i), arreglo[i])
+		}
+	case reflect.Map:
+		objeto, ok := nodo.(objetoJSON)
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return nodo
+		}
+		for i, c := range objeto {
+			if mv := v.MapIndex(reflect.ValueOf(c.clave).Convert(v.Type().Key())); mv.IsValid() {
+				objeto[i].valor = conFechas(mv, c.valor)
+			}
+		}
+	}
+	return nodo
+}
+
+// fechaDia returns the date of a time.Time or calendario.Fecha field (or a pointer to one) as
+// 2006-01-02, or nodo unchanged.
+func fechaDia(f reflect.Value, nodo interface{}) interface{} {
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return nodo
+		}
+		f = f.Elem()
+	}
+	switch t := f.Interface().(type) {
+	case time.Time:
+		return t.Format(time.DateOnly)
+	case calendario.Fecha:
+		return t.Format(calendario.Formato)
+	}
+	return nodo
+}
+
+// sinNulos drops the null fields of every object in the tree. Nulls inside arrays are kept.
+func sinNulos(nodo interface{}) interface{} {
+	switch n := nodo.(type) {
+	case objetoJSON:
+		objeto := objetoJSON{}
+		for _, c := range n {
+			if c.valor != nil {
+				objeto = append(objeto, campoJSON{clave: c.clave, valor: sinNulos(c.valor)})
+			}
+		}
+		return objeto
+	case []interface{}:
+		for i := range n {
+			n[i] = sinNulos(n[i])
+		}
+	}
+	return nodo
+}
+
+// campoStruct locates the struct field behind a JSON name.
+type campoStruct struct {
+	indice []int
+	fecha  bool // Tagged with FechaTag
+}
+
+var camposPorTipo sync.Map // reflect.Type -> map[string]campoStruct
+
+// camposJSON maps the JSON names of a struct type to its fields, including those promoted from
+// embedded structs (a field of the struct itself wins over a promoted one).
+func camposJSON(t reflect.Type) map[string]campoStruct {
+	if campos, ok := camposPorTipo.Load(t); ok {
+		return campos.(map[string]campoStruct)
+	}
+	campos := map[string]campoStruct{}
+	embebidos := []reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embebidos = append(embebidos, field)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		_, fecha := field.Tag.Lookup(FechaTag)
+		campos[name] = campoStruct{indice: field.Index, fecha: fecha}
+	}
+	for _, field := range embebidos {
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct {
+			continue
+		}
+		for name, campo := range camposJSON(ft) {
+			if _, ok := campos[name]; !ok {
+				campos[name] = campoStruct{indice: append([]int{field.Index[0]}, campo.indice...), fecha: campo.fecha}
+			}
+		}
+	}
+	camposPorTipo.Store(t, campos)
+	return campos
+}
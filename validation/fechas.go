@@ -0,0 +1,76 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+)
+
+var tipoFecha = reflect.TypeOf(calendario.Fecha{})
+
+// CampoFecha returns the path of the first date (calendario.Fecha) of v, the value body failed to
+// decode into, whose value in body is not a date: "fechaInicio", "grupo.fechaRegistro",
+// "[2].fechaFin". It is "" if there is none. encoding/json does not name the field of such an error
+// (see calendario.EsFechaInvalida), so it is found by decoding the dates of body one by one.
+func CampoFecha(body []byte, v interface{}) string {
+	return campoFecha(body, reflect.TypeOf(v), "")
+}
+
+func campoFecha(raw json.RawMessage, t reflect.Type, ruta string) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == tipoFecha:
+		var f calendario.Fecha
+		if err := json.Unmarshal(raw, &f); err != nil {
+			return ruta
+		}
+	case t.Kind() == reflect.Slice:
+		var items []json.RawMessage
+		if json.Unmarshal(raw, &items) != nil {
+			return ""
+		}
+		for i, item := range items {
+			if c := campoFecha(item, t.Elem(), fmt.Sprintf("%s[%d]", ruta, i)); c != "" {
+				return c
+			}
+		}
+	case t.Kind() == reflect.Struct:
+		var campos map[string]json.RawMessage
+		if json.Unmarshal(raw, &campos) != nil {
+			return ""
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			nombre, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || nombre == "-" {
+				continue
+			}
+			if nombre == "" && f.Anonymous {
+				// Flattened in JSON, like the embedded structs of the field errors
+				if c := campoFecha(raw, f.Type, ruta); c != "" {
+					return c
+				}
+				continue
+			}
+			if nombre == "" {
+				nombre = f.Name
+			}
+			valor, ok := campos[nombre]
+			if !ok {
+				continue
+			}
+			if ruta != "" {
+				nombre = ruta + "." + nombre
+			}
+			if c := campoFecha(valor, f.Type, nombre); c != "" {
+				return c
+			}
+		}
+	}
+	return ""
+}
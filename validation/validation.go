@@ -0,0 +1,156 @@
+// Package validation checks request bodies against the `validate` tags of their models
+// (go-playground/validator) and reports every problem as a field error, so handlers answer a 422
+// listing all the offending fields at once instead of stopping at the first ad-hoc check.
+//
+// Besides the standard tags, it provides:
+//
+//	notblank  the string is not empty or only whitespace
+//	anio      a year between 1900 and next year
+//
+// and checks that memberships (models.DetalleGrupoInvestigador) do not end before they start.
+//
+// Fields are named as in the JSON body (json tag, or form tag for multipart fields), with the path
+// of nested fields: "integrantes[0].rol".
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/calendario"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/utils"
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(fieldName)
+	// Dates are checked (required, gtefield...) as the time.Time of their midnight
+	v.RegisterCustomTypeFunc(func(f reflect.Value) interface{} {
+		return f.Interface().(calendario.Fecha).Time()
+	}, calendario.Fecha{})
+	v.RegisterValidation("notblank", func(fl validator.FieldLevel) bool {
+		return strings.TrimSpace(fl.Field().String()) != ""
+	})
+	v.RegisterValidation("anio", func(fl validator.FieldLevel) bool {
+		y := fl.Field().Int()
+		return y >= 1900 && y <= int64(time.Now().Year()+1)
+	})
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		d := sl.Current().Interface().(models.DetalleGrupoInvestigador)
+		if !d.PeriodoValido() {
+			sl.ReportError(d.FechaFin, "fechaFin", "FechaFin", "periodo", "")
+		}
+	}, models.DetalleGrupoInvestigador{})
+	return v
+}
+
+// fieldName names a field after its json (or form) tag. Embedded structs without a tag are
+// flattened in JSON; they keep their Go name here and are dropped from the path by campo.
+func fieldName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+// Struct validates s and returns its field errors, or nil if it is valid.
+func Struct(s interface{}) []utils.FieldError {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		// Only a programming error (e.g. s is not a struct) gets here
+		return []utils.FieldError{{Codigo: "invalido", Mensaje: err.Error()}}
+	}
+	campos := make([]utils.FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		campos = append(campos, fieldError(fe))
+	}
+	return campos
+}
+
+// FechaInvalida is the error for a date field that could not be parsed before validation, such
+// as a multipart field. It matches the one of the datetime tag.
+func FechaInvalida(campo string) utils.FieldError {
+	return utils.NewFieldError(campo, "fecha_invalida", "campo_fecha_invalida", campo)
+}
+
+// TipoArchivoInvalido is the error for an uploaded file whose content is not allowed (HTML) or
+// does not match the type its name claims.
+func TipoArchivoInvalido(campo string) utils.FieldError {
+	return utils.NewFieldError(campo, "tipo_archivo_invalido", "campo_tipo_archivo_invalido", campo)
+}
+
+// NumeroInvalido is the error for an integer field that could not be parsed before validation,
+// such as a multipart field.
+func NumeroInvalido(campo string) utils.FieldError {
+	return utils.NewFieldError(campo, "valor_invalido", "campo_entero_invalido", campo)
+}
+
+// campo returns the path of the field in the request body: the namespace without the root struct
+// and the Go names of flattened embedded structs (JSON names start with a lowercase letter).
+func campo(fe validator.FieldError) string {
+	parts := strings.Split(fe.Namespace(), ".")[1:]
+	kept := parts[:0]
+	for i, p := range parts {
+		if i < len(parts)-1 && p != "" && p[0] >= 'A' && p[0] <= 'Z' && !strings.Contains(p, "[") {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return strings.Join(kept, ".")
+}
+
+func fieldError(fe validator.FieldError) utils.FieldError {
+	c := campo(fe)
+	param := fe.Param()
+	kind := fe.Kind()
+	switch fe.Tag() {
+	case "required", "notblank", "required_without", "required_if":
+		return utils.NewFieldError(c, "obligatorio", "campo_obligatorio", c)
+	case "email":
+		return utils.NewFieldError(c, "email_invalido", "campo_email_invalido", c)
+	case "oneof":
+		return utils.NewFieldError(c, "valor_invalido", "campo_valor_no_permitido", c, strings.Join(strings.Fields(param), ", "))
+	case "datetime":
+		return FechaInvalida(c)
+	case "anio":
+		return utils.NewFieldError(c, "fuera_de_rango", "campo_anio_fuera_de_rango", c, time.Now().Year()+1)
+	case "periodo", "gtefield":
+		return utils.NewFieldError(c, "periodo_invalido", "campo_periodo_invalido", c)
+	case "max", "lte":
+		switch kind {
+		case reflect.String:
+			return utils.NewFieldError(c, "demasiado_largo", "campo_demasiado_largo", c, param)
+		case reflect.Slice, reflect.Map:
+			return utils.NewFieldError(c, "demasiados_elementos", "campo_demasiados_elementos", c, param)
+		default:
+			return utils.NewFieldError(c, "fuera_de_rango", "campo_maximo", c, param)
+		}
+	case "min", "gte":
+		switch kind {
+		case reflect.String:
+			return utils.NewFieldError(c, "demasiado_corto", "campo_demasiado_corto", c, param)
+		case reflect.Slice, reflect.Map:
+			return utils.NewFieldError(c, "muy_pocos_elementos", "campo_muy_pocos_elementos", c, param)
+		default:
+			return utils.NewFieldError(c, "fuera_de_rango", "campo_minimo", c, param)
+		}
+	default:
+		return utils.NewFieldError(c, "invalido", "campo_invalido", c)
+	}
+}
@@ -0,0 +1,78 @@
+// Package version exposes build information injected at build time with -ldflags:
+//
+//	go build -ldflags "-X github.com/GoogleCloudPlatform/golang-samples/run/helloworld/version.Version=1.4.0 \
+//	  -X github.com/GoogleCloudPlatform/golang-samples/run/helloworld/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/GoogleCloudPlatform/golang-samples/run/helloworld/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// When the values are not injected, Commit and BuildTime fall back to the VCS data embedded by the Go toolchain.
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set via -ldflags "-X ...".
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+func init() {
+	if Commit != "" && BuildTime != "" {
+		return
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if Commit == "" {
+				Commit = s.Value
+				if len(Commit) > 12 {
+					Commit = Commit[:12]
+				}
+			}
+		case "vcs.time":
+			if BuildTime == "" {
+				BuildTime = s.Value
+			}
+		}
+	}
+}
+
+// Info is the build information returned by GET /version.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the current build information.
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    valueOrUnknown(Commit),
+		BuildTime: valueOrUnknown(BuildTime),
+		GoVersion: runtime.Version(),
+	}
+}
+
+// String returns a short identifier such as "1.4.0+abc123" for log lines and error bodies.
+func String() string {
+	if Commit == "" {
+		return Version
+	}
+	return Version + "+" + Commit
+}
+
+func valueOrUnknown(v string) string {
+	if v == "" {
+		return "unknown"
+	}
+	return v
+}